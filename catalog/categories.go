@@ -0,0 +1,89 @@
+package catalog
+
+// DefaultCategory is the predefined category of products whose categories are not recognized
+const DefaultCategory = "Other"
+
+// categoryRule maps an Open Food Facts category to a predefined pantry category and the
+// typical shelf life of an unopened package, in days
+type categoryRule struct {
+	Category      string
+	ShelfLifeDays int
+}
+
+// storageRules are categories that describe how a product is kept. They win over what the
+// product is, so frozen peas are Frozen Foods rather than Vegetables.
+var storageRules = map[string]categoryRule{
+	"en:frozen-foods": {"Frozen Foods", 180},
+	"en:canned-foods": {"Canned Goods", 730},
+}
+
+// categoryRules are matched against a product's categories from the most specific one up
+var categoryRules = map[string]categoryRule{
+	"en:milks":                      {"Dairy", 7},
+	"en:uht-milks":                  {"Dairy", 90},
+	"en:plant-based-milks":          {"Beverages", 180},
+	"en:yogurts":                    {"Dairy", 14},
+	"en:cheeses":                    {"Dairy", 30},
+	"en:butters":                    {"Dairy", 60},
+	"en:creams":                     {"Dairy", 10},
+	"en:eggs":                       {"Dairy", 28},
+	"en:dairies":                    {"Dairy", 10},
+	"en:fresh-fruits":               {"Fruits", 7},
+	"en:dried-fruits":               {"Fruits", 180},
+	"en:fruits":                     {"Fruits", 7},
+	"en:fresh-vegetables":           {"Vegetables", 7},
+	"en:vegetables":                 {"Vegetables", 7},
+	"en:breakfast-cereals":          {"Grains & Cereals", 180},
+	"en:cereals-and-their-products": {"Grains & Cereals", 365},
+	"en:poultries":                  {"Meat & Poultry", 2},
+	"en:meats":                      {"Meat & Poultry", 3},
+	"en:hams":                       {"Meat & Poultry", 7},
+	"en:sausages":                   {"Meat & Poultry", 14},
+	"en:fishes":                     {"Seafood", 2},
+	"en:seafood":                    {"Seafood", 2},
+	"en:waters":                     {"Beverages", 365},
+	"en:sodas":                      {"Beverages", 270},
+	"en:fruit-juices":               {"Beverages", 180},
+	"en:coffees":                    {"Beverages", 365},
+	"en:teas":                       {"Beverages", 540},
+	"en:beverages":                  {"Beverages", 180},
+	"en:chips-and-fries":            {"Snacks", 90},
+	"en:biscuits-and-cakes":         {"Snacks", 120},
+	"en:chocolates":                 {"Snacks", 365},
+	"en:snacks":                     {"Snacks", 90},
+	"en:sauces":                     {"Condiments & Sauces", 180},
+	"en:condiments":                 {"Condiments & Sauces", 180},
+	"en:spreads":                    {"Condiments & Sauces", 180},
+	"en:spices":                     {"Spices & Seasonings", 730},
+	"en:salts":                      {"Spices & Seasonings", 1825},
+	"en:flours":                     {"Baking Supplies", 240},
+	"en:sugars":                     {"Baking Supplies", 730},
+	"en:baking-decorations":         {"Baking Supplies", 365},
+	"en:vegetable-oils":             {"Oils & Vinegars", 365},
+	"en:olive-oils":                 {"Oils & Vinegars", 540},
+	"en:vinegars":                   {"Oils & Vinegars", 730},
+	"en:nuts":                       {"Nuts & Seeds", 180},
+	"en:seeds":                      {"Nuts & Seeds", 180},
+	"en:breads":                     {"Bread & Bakery", 5},
+	"en:pastries":                   {"Bread & Bakery", 3},
+	"en:pastas":                     {"Pasta & Rice", 730},
+	"en:rices":                      {"Pasta & Rice", 730},
+	"en:noodles":                    {"Pasta & Rice", 365},
+}
+
+// categoryFor picks the predefined category and typical shelf life of a product from its
+// Open Food Facts category tags, which run from the broadest to the most specific. The shelf
+// life is nil if no category is recognized.
+func categoryFor(tags []string) (string, *int) {
+	for _, tag := range tags {
+		if rule, ok := storageRules[tag]; ok {
+			return rule.Category, &rule.ShelfLifeDays
+		}
+	}
+	for i := len(tags) - 1; i >= 0; i-- {
+		if rule, ok := categoryRules[tags[i]]; ok {
+			return rule.Category, &rule.ShelfLifeDays
+		}
+	}
+	return DefaultCategory, nil
+}
@@ -0,0 +1,56 @@
+// Package catalog reads product data keyed by barcode from Open Food Facts dumps.
+//
+// Barcodes are GTINs: EAN-8, UPC-A, EAN-13 and GTIN-14 numbers ending in a check digit. They
+// are stored as 14 digits with leading zeros, so a UPC-A and the EAN-13 printed for the same
+// product are the same key.
+package catalog
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidGTIN is returned for a barcode that is not a valid GTIN
+var ErrInvalidGTIN = errors.New("barcode must be a valid EAN-8, UPC-A, EAN-13 or GTIN-14")
+
+// NormalizeGTIN validates a barcode as scanned or typed, ignoring spaces and dashes, and
+// returns it as 14 digits
+func NormalizeGTIN(raw string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+
+	switch len(digits) {
+	case 8, 12, 13, 14:
+	default:
+		return "", ErrInvalidGTIN
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", ErrInvalidGTIN
+		}
+	}
+
+	gtin := strings.Repeat("0", 14-len(digits)) + digits
+	if checkDigit(gtin[:13]) != gtin[13] {
+		return "", ErrInvalidGTIN
+	}
+	return gtin, nil
+}
+
+// checkDigit computes the GS1 check digit: digits are weighted 3 and 1 alternately from the
+// right, and the check digit brings the sum up to a multiple of 10
+func checkDigit(digits string) byte {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,81 @@
+package catalog
+
+import (
+	"compress/gzip"
+	"context"
+	"cribb-backend/models"
+	"io"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// importBatchSize is how many products are written to the database at once
+const importBatchSize = 1000
+
+// ImportStats summarizes a catalog import
+type ImportStats struct {
+	Read     int   // Products with a valid barcode and name
+	Skipped  int   // Records without either
+	Inserted int64 // Products new to the catalog
+	Updated  int64 // Products whose details changed
+}
+
+// ImportFile imports an Open Food Facts dump into the products collection, replacing the
+// details of products already in it. Gzipped dumps are read as they are.
+func ImportFile(ctx context.Context, db *mongo.Database, path string) (ImportStats, error) {
+	var stats ImportStats
+
+	format, err := FormatForPath(path)
+	if err != nil {
+		return stats, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return stats, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(strings.ToLower(path), ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return stats, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	// A barcode listed twice in a batch keeps its last entry, as upserting both at once could
+	// insert it twice
+	batch := make([]models.Product, 0, importBatchSize)
+	positions := make(map[string]int, importBatchSize)
+	flush := func() error {
+		inserted, updated, err := models.UpsertProducts(ctx, db, batch)
+		stats.Inserted += inserted
+		stats.Updated += updated
+		batch = batch[:0]
+		clear(positions)
+		return err
+	}
+
+	stats.Skipped, err = ReadProducts(reader, format, func(product models.Product) error {
+		stats.Read++
+		if i, ok := positions[product.GTIN]; ok {
+			batch[i] = product
+			return nil
+		}
+		positions[product.GTIN] = len(batch)
+		batch = append(batch, product)
+		if len(batch) == importBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	return stats, flush()
+}
@@ -0,0 +1,264 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"cribb-backend/models"
+	"cribb-backend/units"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SourceOpenFoodFacts marks products imported from Open Food Facts
+const SourceOpenFoodFacts = "openfoodfacts"
+
+// Format is the layout of an Open Food Facts dump
+type Format string
+
+const (
+	FormatCSV  Format = "csv"  // The products export, tab-separated despite its name
+	FormatJSON Format = "json" // The JSONL products export, or a JSON array of products
+)
+
+// FormatForPath picks the format of a dump from its file name, ignoring a .gz suffix
+func FormatForPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(strings.TrimSuffix(strings.ToLower(path), ".gz"))) {
+	case ".csv", ".tsv":
+		return FormatCSV, nil
+	case ".json", ".jsonl":
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("cannot tell the format of %s; expected a .csv, .tsv, .json or .jsonl file", path)
+}
+
+// record is the part of an Open Food Facts product the catalog uses
+type record struct {
+	Code           looseString `json:"code"`
+	ProductName    string      `json:"product_name"`
+	ProductNameEN  string      `json:"product_name_en"`
+	Brands         string      `json:"brands"`
+	Quantity       string      `json:"quantity"`
+	CategoriesTags []string    `json:"categories_tags"`
+}
+
+// looseString accepts a JSON string or number, as barcodes appear as both in the dumps. A
+// number has lost its leading zeros, so it is padded back to the 13 digits of an EAN-13.
+type looseString string
+
+func (s *looseString) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		digits := string(bytes.TrimSpace(data))
+		if len(digits) < 13 {
+			digits = strings.Repeat("0", 13-len(digits)) + digits
+		}
+		*s = looseString(digits)
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*s = looseString(value)
+	return nil
+}
+
+// product turns a record into a catalog product, reporting false for records without a valid
+// barcode or a name
+func (r *record) product() (models.Product, bool) {
+	gtin, err := NormalizeGTIN(string(r.Code))
+	if err != nil {
+		return models.Product{}, false
+	}
+	name := strings.TrimSpace(r.ProductName)
+	if name == "" {
+		name = strings.TrimSpace(r.ProductNameEN)
+	}
+	if name == "" {
+		return models.Product{}, false
+	}
+
+	brand, _, _ := strings.Cut(r.Brands, ",")
+	category, shelfLife := categoryFor(r.CategoriesTags)
+	return models.Product{
+		GTIN:            gtin,
+		Name:            name,
+		Brand:           strings.TrimSpace(brand),
+		PackageQuantity: strings.TrimSpace(r.Quantity),
+		DefaultUnit:     defaultUnit(r.Quantity),
+		DefaultCategory: category,
+		ShelfLifeDays:   shelfLife,
+		Source:          SourceOpenFoodFacts,
+	}, true
+}
+
+// packageAmount matches an amount and unit in a package quantity such as "6 x 330 ml"
+var packageAmount = regexp.MustCompile(`(?i)[\d.,]+\s*([a-z][a-z .]*)`)
+
+// defaultUnit picks the unit to count a product in: the mass or volume its package is
+// labelled with, or pieces
+func defaultUnit(quantity string) string {
+	matches := packageAmount.FindAllStringSubmatch(quantity, -1)
+	if len(matches) > 0 {
+		unit, err := units.Parse(matches[len(matches)-1][1])
+		if err == nil && (unit.Dimension == units.Mass || unit.Dimension == units.Volume) {
+			return unit.Symbol
+		}
+	}
+	return "pc"
+}
+
+// ReadProducts reads an Open Food Facts dump, calling fn with each product that has a valid
+// barcode and a name. It returns how many records were skipped.
+func ReadProducts(r io.Reader, format Format, fn func(models.Product) error) (int, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(r, fn)
+	case FormatJSON:
+		return readJSON(r, fn)
+	}
+	return 0, fmt.Errorf("unknown catalog format %q", format)
+}
+
+func readCSV(r io.Reader, fn func(models.Product) error) (int, error) {
+	reader := bufio.NewReaderSize(r, 1<<20)
+	header, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+
+	// The official export separates fields with tabs and does not quote them, so it is split
+	// by line; anything else is read as a regular comma-separated file
+	var next func() ([]string, error)
+	var columns []string
+	if strings.Contains(header, "\t") {
+		columns = strings.Split(strings.TrimRight(header, "\r\n"), "\t")
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 0, 1<<20), 16<<20)
+		next = func() ([]string, error) {
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return nil, err
+				}
+				return nil, io.EOF
+			}
+			return strings.Split(scanner.Text(), "\t"), nil
+		}
+	} else {
+		csvReader := csv.NewReader(io.MultiReader(strings.NewReader(header), reader))
+		csvReader.FieldsPerRecord = -1
+		csvReader.LazyQuotes = true
+		if columns, err = csvReader.Read(); err != nil {
+			return 0, err
+		}
+		next = csvReader.Read
+	}
+
+	index := make(map[string]int, len(columns))
+	for i, column := range columns {
+		index[strings.TrimSpace(column)] = i
+	}
+	if _, ok := index["code"]; !ok {
+		return 0, errors.New("catalog dump has no code column")
+	}
+	field := func(fields []string, name string) string {
+		if i, ok := index[name]; ok && i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+
+	skipped := 0
+	for {
+		fields, err := next()
+		if errors.Is(err, io.EOF) {
+			return skipped, nil
+		}
+		if err != nil {
+			return skipped, err
+		}
+
+		rec := record{
+			Code:          looseString(field(fields, "code")),
+			ProductName:   field(fields, "product_name"),
+			ProductNameEN: field(fields, "product_name_en"),
+			Brands:        field(fields, "brands"),
+			Quantity:      field(fields, "quantity"),
+		}
+		if tags := field(fields, "categories_tags"); tags != "" {
+			rec.CategoriesTags = strings.Split(tags, ",")
+		}
+
+		product, ok := rec.product()
+		if !ok {
+			skipped++
+			continue
+		}
+		if err := fn(product); err != nil {
+			return skipped, err
+		}
+	}
+}
+
+func readJSON(r io.Reader, fn func(models.Product) error) (int, error) {
+	reader := bufio.NewReader(r)
+	decoder := json.NewDecoder(reader)
+
+	// A JSON array is read element by element; otherwise the dump is a stream of objects
+	first, err := firstByte(reader)
+	if err != nil {
+		return 0, err
+	}
+	isArray := first == '['
+	if isArray {
+		if _, err := decoder.Token(); err != nil {
+			return 0, err
+		}
+	}
+
+	skipped := 0
+	for line := 1; ; line++ {
+		if isArray && !decoder.More() {
+			return skipped, nil
+		}
+
+		var rec record
+		err := decoder.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			return skipped, nil
+		}
+		if err != nil {
+			return skipped, fmt.Errorf("product %d: %w", line, err)
+		}
+
+		product, ok := rec.product()
+		if !ok {
+			skipped++
+			continue
+		}
+		if err := fn(product); err != nil {
+			return skipped, err
+		}
+	}
+}
+
+// firstByte peeks at the first byte that is not whitespace, or 0 for an empty input
+func firstByte(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, reader.UnreadByte()
+		}
+	}
+}
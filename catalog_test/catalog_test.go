@@ -0,0 +1,160 @@
+package catalog_test
+
+import (
+	"cribb-backend/catalog"
+	"cribb-backend/models"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeGTIN(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+	}{
+		{"4006381333931", "04006381333931"},
+		{"036000291452", "00036000291452"},
+		{"0036000291452", "00036000291452"},
+		{"96385074", "00000096385074"},
+		{" 5449-0000-00996 ", "05449000000996"},
+		{"10036000291459", "10036000291459"},
+	}
+
+	for _, tt := range tests {
+		got, err := catalog.NormalizeGTIN(tt.raw)
+		if err != nil {
+			t.Errorf("Expected %q to be valid, got %v", tt.raw, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("Expected %q to normalize to %s, got %s", tt.raw, tt.expected, got)
+		}
+	}
+
+	for _, raw := range []string{"", "4006381333932", "12345", "40063813339AB", "0000000000000000"} {
+		if _, err := catalog.NormalizeGTIN(raw); !errors.Is(err, catalog.ErrInvalidGTIN) {
+			t.Errorf("Expected ErrInvalidGTIN for %q, got %v", raw, err)
+		}
+	}
+}
+
+func readAll(t *testing.T, dump string, format catalog.Format) ([]models.Product, int) {
+	t.Helper()
+	var products []models.Product
+	skipped, err := catalog.ReadProducts(strings.NewReader(dump), format, func(product models.Product) error {
+		products = append(products, product)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected the dump to be read, got %v", err)
+	}
+	return products, skipped
+}
+
+func checkProducts(t *testing.T, products []models.Product) {
+	t.Helper()
+	expected := []struct {
+		gtin      string
+		name      string
+		brand     string
+		unit      string
+		category  string
+		shelfLife int
+	}{
+		{"05449000000996", "Coca-Cola", "Coca-Cola", "ml", "Beverages", 270},
+		{"04006381333931", "Whole Milk", "Farm Co", "l", "Dairy", 7},
+		{"00036000291452", "Garden Peas", "", "g", "Frozen Foods", 180},
+		{"00000096385074", "Mystery Snack", "", "pc", "Other", 0},
+	}
+
+	if len(products) != len(expected) {
+		t.Fatalf("Expected %d products, got %d", len(expected), len(products))
+	}
+	for i, want := range expected {
+		got := products[i]
+		if got.GTIN != want.gtin || got.Name != want.name || got.Brand != want.brand ||
+			got.DefaultUnit != want.unit || got.DefaultCategory != want.category {
+			t.Errorf("Expected %+v, got %+v", want, got)
+		}
+		shelfLife := 0
+		if got.ShelfLifeDays != nil {
+			shelfLife = *got.ShelfLifeDays
+		}
+		if shelfLife != want.shelfLife {
+			t.Errorf("Expected %s to keep %d days, got %d", want.name, want.shelfLife, shelfLife)
+		}
+		if got.Source != catalog.SourceOpenFoodFacts {
+			t.Errorf("Expected source %s, got %s", catalog.SourceOpenFoodFacts, got.Source)
+		}
+	}
+}
+
+func TestReadProductsCSV(t *testing.T) {
+	dump := "code\tproduct_name\tbrands\tquantity\tcategories_tags\n" +
+		"5449000000996\tCoca-Cola\tCoca-Cola,The Coca-Cola Company\t6 x 330 ml\ten:beverages,en:carbonated-drinks,en:sodas\n" +
+		"4006381333931\tWhole Milk\tFarm Co\t1 L\ten:dairies,en:milks\n" +
+		"036000291452\tGarden Peas\t\t500 g\ten:plant-based-foods,en:frozen-foods,en:vegetables,en:peas\n" +
+		"123\tInternal code\t\t\t\n" +
+		"4006381333931\t\t\t\t\n" +
+		"96385074\tMystery Snack\t\t\t\n"
+
+	products, skipped := readAll(t, dump, catalog.FormatCSV)
+	checkProducts(t, products)
+	if skipped != 2 {
+		t.Errorf("Expected 2 records skipped, got %d", skipped)
+	}
+}
+
+func TestReadProductsCommaCSV(t *testing.T) {
+	dump := "code,product_name,brands,quantity,categories_tags\n" +
+		"5449000000996,Coca-Cola,\"Coca-Cola,The Coca-Cola Company\",6 x 330 ml,\"en:beverages,en:sodas\"\n" +
+		"4006381333931,Whole Milk,Farm Co,1 L,\"en:dairies,en:milks\"\n" +
+		"036000291452,Garden Peas,,500 g,\"en:frozen-foods,en:vegetables\"\n" +
+		"96385074,Mystery Snack,,,\n"
+
+	products, _ := readAll(t, dump, catalog.FormatCSV)
+	checkProducts(t, products)
+}
+
+func TestReadProductsJSON(t *testing.T) {
+	lines := `{"code":"5449000000996","product_name":"Coca-Cola","brands":"Coca-Cola","quantity":"6 x 330 ml","categories_tags":["en:beverages","en:sodas"]}
+{"code":"4006381333931","product_name":"","product_name_en":"Whole Milk","brands":"Farm Co","quantity":"1 L","categories_tags":["en:dairies","en:milks"]}
+{"code":36000291452,"product_name":"Garden Peas","quantity":"500 g","categories_tags":["en:frozen-foods","en:vegetables"]}
+{"code":"not-a-barcode","product_name":"Skipped"}
+{"code":"96385074","product_name":"Mystery Snack"}
+`
+
+	products, skipped := readAll(t, lines, catalog.FormatJSON)
+	checkProducts(t, products)
+	if skipped != 1 {
+		t.Errorf("Expected 1 record skipped, got %d", skipped)
+	}
+
+	array := "[" + strings.Join(strings.Split(strings.TrimSpace(lines), "\n"), ",") + "]"
+	products, _ = readAll(t, array, catalog.FormatJSON)
+	checkProducts(t, products)
+}
+
+func TestFormatForPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected catalog.Format
+	}{
+		{"en.openfoodfacts.org.products.csv", catalog.FormatCSV},
+		{"en.openfoodfacts.org.products.csv.gz", catalog.FormatCSV},
+		{"openfoodfacts-products.jsonl.gz", catalog.FormatJSON},
+		{"products.JSON", catalog.FormatJSON},
+	}
+
+	for _, tt := range tests {
+		got, err := catalog.FormatForPath(tt.path)
+		if err != nil || got != tt.expected {
+			t.Errorf("Expected %s for %s, got %s (%v)", tt.expected, tt.path, got, err)
+		}
+	}
+
+	if _, err := catalog.FormatForPath("products.xml"); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
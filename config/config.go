@@ -7,6 +7,7 @@ import (
 	"log"
 	"math/rand"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,8 +20,29 @@ import (
 var (
 	DB        *mongo.Database
 	JWTSecret []byte
+
+	// Web Push is enabled when the VAPID keys are set. Generate a pair with
+	// `go run . -generate-vapid-keys`.
+	VAPIDPublicKey  string
+	VAPIDPrivateKey string
+	VAPIDSubject    string
+
+	// RealtimeBackend carries group events between instances: "memory" for a single
+	// instance, "mongo" to share them through the database
+	RealtimeBackend string
+
+	// WebhookAllowPrivateNetworks lets webhooks reach loopback and private addresses, for
+	// development against local receivers
+	WebhookAllowPrivateNetworks bool
+
+	// ChoreArchiveAfterDays is how long completed chores stay in active lists before they
+	// are archived; zero or a negative value turns automatic archival off
+	ChoreArchiveAfterDays int
 )
 
+// DefaultChoreArchiveAfterDays is used when CHORE_ARCHIVE_AFTER_DAYS is not set
+const DefaultChoreArchiveAfterDays = 30
+
 func init() {
 	// Initialize random seed
 	rand.Seed(time.Now().UnixNano())
@@ -55,6 +77,38 @@ func ConnectDB() {
 	// Set JWT secret
 	JWTSecret = []byte(jwtSecret)
 
+	// Web Push is optional, but needs both keys and a contact subject once enabled
+	VAPIDPublicKey = strings.TrimSpace(os.Getenv("VAPID_PUBLIC_KEY"))
+	VAPIDPrivateKey = strings.TrimSpace(os.Getenv("VAPID_PRIVATE_KEY"))
+	VAPIDSubject = strings.TrimSpace(os.Getenv("VAPID_SUBJECT"))
+
+	if (VAPIDPublicKey == "") != (VAPIDPrivateKey == "") {
+		log.Fatal("VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY must be set together")
+	}
+
+	if WebPushEnabled() && VAPIDSubject == "" {
+		log.Fatal("VAPID_SUBJECT is required when Web Push is enabled")
+	}
+
+	RealtimeBackend = strings.TrimSpace(os.Getenv("REALTIME_BACKEND"))
+	if RealtimeBackend == "" {
+		RealtimeBackend = "memory"
+	}
+	if RealtimeBackend != "memory" && RealtimeBackend != "mongo" {
+		log.Fatal("REALTIME_BACKEND must be memory or mongo")
+	}
+
+	WebhookAllowPrivateNetworks = strings.TrimSpace(os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS")) == "true"
+
+	ChoreArchiveAfterDays = DefaultChoreArchiveAfterDays
+	if value := strings.TrimSpace(os.Getenv("CHORE_ARCHIVE_AFTER_DAYS")); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatal("CHORE_ARCHIVE_AFTER_DAYS must be a whole number of days")
+		}
+		ChoreArchiveAfterDays = days
+	}
+
 	log.Printf("Attempting to connect to MongoDB...")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -83,6 +137,11 @@ func ConnectDB() {
 	log.Printf("Successfully connected to MongoDB database: %s", dbName)
 }
 
+// WebPushEnabled reports whether VAPID keys were configured
+func WebPushEnabled() bool {
+	return VAPIDPublicKey != ""
+}
+
 // Helper function to check if a collection exists
 func collectionExists(ctx context.Context, db *mongo.Database, collectionName string) bool {
 	collections, err := db.ListCollectionNames(ctx, bson.M{"name": collectionName})
@@ -122,6 +181,10 @@ func initializeDatabase() error {
 		{
 			Keys: bson.D{{Key: "room_number", Value: 1}},
 		},
+		{
+			Keys:    bson.D{{Key: "calendar_feed_token", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
 	}
 	_, err := usersCollection.Indexes().CreateMany(ctx, usersIndexes)
 	if err != nil {
@@ -183,12 +246,28 @@ func initializeDatabase() error {
 		{
 			Keys: bson.D{{Key: "recurring_id", Value: 1}},
 		},
+		{
+			// Supports the chore history and the archival job
+			Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "status", Value: 1}, {Key: "completed_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "archived_at", Value: 1}},
+		},
+		{
+			// Supports paging through a group's chores by due date
+			Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "due_date", Value: 1}, {Key: "_id", Value: 1}},
+		},
 	}
 	_, err = choresCollection.Indexes().CreateMany(ctx, choresIndexes)
 	if err != nil {
 		return fmt.Errorf("failed to create chore indexes: %v", err)
 	}
 
+	// Backfill completed_at on chores completed before it was stored
+	if err := models.MigrateChoreCompletedAt(DB); err != nil {
+		log.Printf("Warning: Could not migrate chore completion times: %v", err)
+	}
+
 	// Create recurring_chores collection with indexes
 	recurringChoresCollection := DB.Collection("recurring_chores")
 	recurringChoresIndexes := []mongo.IndexModel{
@@ -219,12 +298,89 @@ func initializeDatabase() error {
 		{
 			Keys: bson.D{{Key: "completed_at", Value: -1}},
 		},
+		{
+			// Supports the time-windowed leaderboard aggregations
+			Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "completed_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "completed_at", Value: -1}},
+		},
 	}
 	_, err = completionsCollection.Indexes().CreateMany(ctx, completionsIndexes)
 	if err != nil {
 		return fmt.Errorf("failed to create chore completion indexes: %v", err)
 	}
 
+	// Backfill group_id on completions recorded before it was stored
+	if err := models.MigrateChoreCompletionGroups(DB); err != nil {
+		log.Printf("Warning: Could not migrate chore completion groups: %v", err)
+	}
+
+	// Create leaderboard_snapshots collection with indexes
+	snapshotsCollection := DB.Collection("leaderboard_snapshots")
+	snapshotsIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "group_id", Value: 1},
+				{Key: "period", Value: 1},
+				{Key: "period_start", Value: -1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	_, err = snapshotsCollection.Indexes().CreateMany(ctx, snapshotsIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create leaderboard snapshot indexes: %v", err)
+	}
+
+	// Create member_progress collection with indexes
+	memberProgressCollection := DB.Collection("member_progress")
+	memberProgressIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "group_id", Value: 1}},
+		},
+	}
+	_, err = memberProgressCollection.Indexes().CreateMany(ctx, memberProgressIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create member progress indexes: %v", err)
+	}
+
+	// Create earned_achievements collection with indexes
+	achievementsCollection := DB.Collection("earned_achievements")
+	achievementsIndexes := []mongo.IndexModel{
+		{
+			// A member earns each achievement at most once per period
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "achievement_id", Value: 1},
+				{Key: "period_key", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// Only one member per group can be first to finish a given week
+			Keys: bson.D{
+				{Key: "group_id", Value: 1},
+				{Key: "achievement_id", Value: 1},
+				{Key: "period_key", Value: 1},
+			},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"achievement_id": models.AchievementFirstToFinishWeek}),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "earned_at", Value: -1}},
+		},
+	}
+	_, err = achievementsCollection.Indexes().CreateMany(ctx, achievementsIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create earned achievement indexes: %v", err)
+	}
+
 	shoppingCartCollection := DB.Collection("shopping_cart")
 	shoppingCartIndexes := []mongo.IndexModel{
 		{
@@ -250,6 +406,50 @@ func initializeDatabase() error {
 		return fmt.Errorf("failed to create shopping cart indexes: %v", err)
 	}
 
+	// Create pantry_history and shopping_cart_activity indexes for paging newest first
+	for _, name := range []string{"pantry_history", "shopping_cart_activity"} {
+		_, err = DB.Collection(name).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create %s indexes: %v", name, err)
+		}
+	}
+
+	// Create the pantry_history index the consumption forecasts read recent uses through
+	_, err = DB.Collection("pantry_history").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "action", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create pantry_history indexes: %v", err)
+	}
+
+	// Create the pantry_history index the waste report reads a group's discards through
+	_, err = DB.Collection("pantry_history").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "action", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create pantry_history indexes: %v", err)
+	}
+
+	// Create pantry_forecasts collection; each item has one stored consumption rate
+	_, err = DB.Collection("pantry_forecasts").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "item_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create pantry forecasts indexes: %v", err)
+	}
+
+	// Create analytics_versions collection; each group has one analytics version
+	_, err = DB.Collection("analytics_versions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "group_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create analytics versions indexes: %v", err)
+	}
+
 	// Create pantry_categories collection with indexes
 	categoriesCollection := DB.Collection("pantry_categories")
 	categoriesIndexes := []mongo.IndexModel{
@@ -280,6 +480,263 @@ func initializeDatabase() error {
 		// Continue anyway, as this might not be critical
 	}
 
+	// Create shelf_life_rules collection with indexes. A group has one rule per category or
+	// product; seeded rules have no group.
+	_, err = DB.Collection("shelf_life_rules").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "group_id", Value: 1}, {Key: "category_id", Value: 1}, {Key: "product_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create shelf-life rules indexes: %v", err)
+	}
+
+	// Seed default shelf lives for the predefined categories
+	if err := seedShelfLifeRules(); err != nil {
+		log.Printf("Warning: Could not seed shelf-life rules: %v", err)
+	}
+
+	// Create chore_templates collection with indexes
+	templatesCollection := DB.Collection("chore_templates")
+	templatesIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "title", Value: 1}, {Key: "group_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"group_id": bson.M{"$exists": true},
+			}),
+		},
+		{
+			Keys: bson.D{{Key: "type", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "group_id", Value: 1}},
+		},
+	}
+	_, err = templatesCollection.Indexes().CreateMany(ctx, templatesIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create chore templates indexes: %v", err)
+	}
+
+	// Seed predefined chore templates if they don't exist
+	if err := seedPredefinedChoreTemplates(); err != nil {
+		log.Printf("Warning: Could not seed predefined chore templates: %v", err)
+	}
+
+	// Create chore_comments collection with indexes
+	commentsCollection := DB.Collection("chore_comments")
+	commentsIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "chore_id", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "recurring_chore_id", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "group_id", Value: 1}},
+		},
+	}
+	_, err = commentsCollection.Indexes().CreateMany(ctx, commentsIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create chore comments indexes: %v", err)
+	}
+
+	// Move pantry and group notifications into the unified notifications collection
+	if err := models.MigrateLegacyNotifications(DB); err != nil {
+		log.Printf("Warning: Could not migrate legacy notifications: %v", err)
+	}
+
+	// Store pantry units under their canonical symbols so quantities can be converted
+	if err := models.MigratePantryUnits(DB); err != nil {
+		log.Printf("Warning: Could not normalize pantry units: %v", err)
+	}
+
+	// Give pantry items stored before lots existed a single lot holding their stock
+	if err := models.MigratePantryLots(DB); err != nil {
+		log.Printf("Warning: Could not migrate pantry lots: %v", err)
+	}
+	if err := models.MigratePantryOwnership(DB); err != nil {
+		log.Printf("Warning: Could not migrate pantry ownership: %v", err)
+	}
+
+	// Index lot expiration dates for the expiry job, and lot locations and owners for filtering
+	_, err = DB.Collection("pantry_items").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "lots.expiration_date", Value: 1}}},
+		{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "lots.location_id", Value: 1}}},
+		{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "owner_ids", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create pantry item indexes: %v", err)
+	}
+
+	// Create storage_locations collection; location names are unique within a group
+	_, err = DB.Collection("storage_locations").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "group_id", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create storage locations indexes: %v", err)
+	}
+
+	// Create recipes collection; recipe names are unique within a group
+	_, err = DB.Collection("recipes").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "group_id", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create recipes indexes: %v", err)
+	}
+
+	// Create meal_plan collection with indexes for loading a group's week and a recipe's meals
+	_, err = DB.Collection("meal_plan").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "date", Value: 1}}},
+		{Keys: bson.D{{Key: "recipe_id", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create meal plan indexes: %v", err)
+	}
+
+	// Create products collection with a unique barcode index
+	_, err = DB.Collection("products").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "gtin", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create products indexes: %v", err)
+	}
+
+	// Create notifications collection with indexes. Notifications expire at expires_at.
+	notificationsCollection := DB.Collection("notifications")
+	notificationsIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "recipient_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "pantry.item_id", Value: 1}, {Key: "type", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "chore.comment_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "cart.activity_id", Value: 1}},
+		},
+		{
+			// Supports the Web Push job finding notifications it has not pushed yet
+			Keys: bson.D{{Key: "pushed_at", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	_, err = notificationsCollection.Indexes().CreateMany(ctx, notificationsIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create notifications indexes: %v", err)
+	}
+
+	// Create notification_receipts collection with indexes. Receipts expire with their notification.
+	receiptsCollection := DB.Collection("notification_receipts")
+	receiptsIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "notification_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "notification_created_at", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	_, err = receiptsCollection.Indexes().CreateMany(ctx, receiptsIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create notification receipts indexes: %v", err)
+	}
+
+	// Create push_subscriptions collection with indexes. The endpoint identifies a device.
+	pushSubscriptionsCollection := DB.Collection("push_subscriptions")
+	pushSubscriptionsIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "endpoint", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+	}
+	_, err = pushSubscriptionsCollection.Indexes().CreateMany(ctx, pushSubscriptionsIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create push subscription indexes: %v", err)
+	}
+
+	// Create webhooks collection with indexes, matched against each published event
+	webhooksCollection := DB.Collection("webhooks")
+	webhooksIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "events", Value: 1}},
+		},
+	}
+	_, err = webhooksCollection.Indexes().CreateMany(ctx, webhooksIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create webhooks indexes: %v", err)
+	}
+
+	// Create webhook_deliveries collection with indexes. The worker claims due deliveries
+	// and the delivery log expires after its retention period.
+	webhookDeliveriesCollection := DB.Collection("webhook_deliveries")
+	webhookDeliveriesIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	_, err = webhookDeliveriesCollection.Indexes().CreateMany(ctx, webhookDeliveriesIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook deliveries indexes: %v", err)
+	}
+
+	// Create chore_imports collection with indexes. Previews expire if never confirmed.
+	importsCollection := DB.Collection("chore_imports")
+	importsIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys: bson.D{{Key: "group_id", Value: 1}},
+		},
+	}
+	_, err = importsCollection.Indexes().CreateMany(ctx, importsIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create chore import indexes: %v", err)
+	}
+
+	// Create reminder_deliveries collection with indexes. The unique key is what keeps a
+	// reminder from being sent twice; records are dropped once they can no longer repeat.
+	deliveriesCollection := DB.Collection("reminder_deliveries")
+	deliveriesIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(models.ReminderDeliveryRetention / time.Second)),
+		},
+	}
+	_, err = deliveriesCollection.Indexes().CreateMany(ctx, deliveriesIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create reminder delivery indexes: %v", err)
+	}
+
 	log.Println("Successfully initialized database collections and indexes")
 	return nil
 }
@@ -348,3 +805,134 @@ func seedPredefinedCategories() error {
 	log.Printf("Successfully seeded %d predefined categories", len(result.InsertedIDs))
 	return nil
 }
+
+// seedShelfLifeRules seeds default shelf lives for the predefined categories, leaving rules
+// already seeded as they are
+func seedShelfLifeRules() error {
+	if DB == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Days in the fridge or cupboard, and how much longer the food keeps frozen
+	defaults := map[string]models.ShelfLife{
+		"Dairy":               {Days: 7, FreezerMultiplier: 4},
+		"Fruits":              {Days: 7, FreezerMultiplier: 26},
+		"Vegetables":          {Days: 7, FreezerMultiplier: 26},
+		"Grains & Cereals":    {Days: 365, FreezerMultiplier: 1},
+		"Meat & Poultry":      {Days: 3, FreezerMultiplier: 40},
+		"Seafood":             {Days: 2, FreezerMultiplier: 45},
+		"Beverages":           {Days: 180, FreezerMultiplier: 1},
+		"Snacks":              {Days: 90, FreezerMultiplier: 1},
+		"Condiments & Sauces": {Days: 180, FreezerMultiplier: 1},
+		"Spices & Seasonings": {Days: 730, FreezerMultiplier: 1},
+		"Baking Supplies":     {Days: 365, FreezerMultiplier: 1},
+		"Frozen Foods":        {Days: 180, FreezerMultiplier: 1},
+		"Canned Goods":        {Days: 730, FreezerMultiplier: 1},
+		"Oils & Vinegars":     {Days: 365, FreezerMultiplier: 1},
+		"Nuts & Seeds":        {Days: 180, FreezerMultiplier: 2},
+		"Bread & Bakery":      {Days: 4, FreezerMultiplier: 20},
+		"Pasta & Rice":        {Days: 730, FreezerMultiplier: 1},
+	}
+
+	cursor, err := DB.Collection("pantry_categories").Find(ctx, bson.M{"type": "predefined"})
+	if err != nil {
+		return fmt.Errorf("failed to fetch predefined categories: %v", err)
+	}
+	var categories []models.PantryCategory
+	if err := cursor.All(ctx, &categories); err != nil {
+		return fmt.Errorf("failed to decode predefined categories: %v", err)
+	}
+
+	now := time.Now()
+	var writes []mongo.WriteModel
+	for _, category := range categories {
+		shelfLife, ok := defaults[category.Name]
+		if !ok {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{
+				"group_id":    bson.M{"$exists": false},
+				"category_id": category.ID,
+				"product_id":  bson.M{"$exists": false},
+			}).
+			SetUpdate(bson.M{"$setOnInsert": bson.M{
+				"days":               shelfLife.Days,
+				"freezer_multiplier": shelfLife.FreezerMultiplier,
+				"created_at":         now,
+				"updated_at":         now,
+			}}).
+			SetUpsert(true))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+
+	result, err := DB.Collection("shelf_life_rules").BulkWrite(ctx, writes)
+	if err != nil {
+		return fmt.Errorf("failed to seed shelf-life rules: %v", err)
+	}
+	if result.UpsertedCount > 0 {
+		log.Printf("Successfully seeded %d shelf-life rules", result.UpsertedCount)
+	}
+	return nil
+}
+
+// seedPredefinedChoreTemplates seeds the database with predefined chore templates
+func seedPredefinedChoreTemplates() error {
+	if DB == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Check if predefined templates already exist
+	count, err := DB.Collection("chore_templates").CountDocuments(
+		ctx,
+		bson.M{"type": models.ChoreTemplateTypePredefined},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to check existing predefined chore templates: %v", err)
+	}
+
+	// If templates already exist, skip seeding
+	if count > 0 {
+		log.Printf("Predefined chore templates already exist (%d found), skipping seeding", count)
+		return nil
+	}
+
+	// Define predefined templates
+	predefinedTemplates := []*models.ChoreTemplate{
+		models.CreatePredefinedChoreTemplate("Take Out Trash", "Empty all bins and take bags to the outside bins", "weekly", 5),
+		models.CreatePredefinedChoreTemplate("Take Out Recycling", "Sort and take recycling to the outside bins", "weekly", 5),
+		models.CreatePredefinedChoreTemplate("Wash Dishes", "Wash, dry and put away dishes left in the sink", "daily", 3),
+		models.CreatePredefinedChoreTemplate("Clean Kitchen", "Wipe counters and stovetop, clean the sink", "weekly", 10),
+		models.CreatePredefinedChoreTemplate("Clean Bathroom", "Scrub toilet, sink, shower and mirror", "weekly", 15),
+		models.CreatePredefinedChoreTemplate("Vacuum Common Areas", "Vacuum the living room, hallway and stairs", "weekly", 10),
+		models.CreatePredefinedChoreTemplate("Mop Floors", "Mop kitchen and bathroom floors", "biweekly", 10),
+		models.CreatePredefinedChoreTemplate("Clean Fridge", "Throw out expired food and wipe the shelves", "monthly", 15),
+		models.CreatePredefinedChoreTemplate("Water Plants", "Water all shared plants", "weekly", 2),
+		models.CreatePredefinedChoreTemplate("Restock Supplies", "Check and restock toilet paper, soap and cleaning supplies", "biweekly", 5),
+		models.CreatePredefinedChoreTemplate("Change Towels", "Replace bathroom and kitchen towels and run a wash", "weekly", 5),
+		models.CreatePredefinedChoreTemplate("Dust Surfaces", "Dust shelves, tables and window sills in common areas", "biweekly", 5),
+	}
+
+	// Create template documents
+	var templates []interface{}
+	for _, template := range predefinedTemplates {
+		templates = append(templates, template)
+	}
+
+	// Insert all predefined templates
+	result, err := DB.Collection("chore_templates").InsertMany(ctx, templates)
+	if err != nil {
+		return fmt.Errorf("failed to insert predefined chore templates: %v", err)
+	}
+
+	log.Printf("Successfully seeded %d predefined chore templates", len(result.InsertedIDs))
+	return nil
+}
@@ -0,0 +1,140 @@
+// handlers/analytics.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/jobs"
+	"cribb-backend/models"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// UpdateGroupTimezoneRequest defines the request structure for setting a group's time zone
+type UpdateGroupTimezoneRequest struct {
+	GroupName string `json:"group_name" validate:"required"`
+	Timezone  string `json:"timezone" validate:"required"` // IANA name, e.g. Europe/London
+}
+
+// ChoreAnalyticsResponse is an analytics report and whether it came from the cache
+type ChoreAnalyticsResponse struct {
+	*models.ChoreAnalytics
+	Cached bool `json:"cached"`
+}
+
+// GetChoreAnalyticsHandler reports how a group is doing with its chores: completion rate,
+// on-time percentage and average lateness per member, the chores trend, the points
+// distribution and the most skipped recurring chores. Periods are bucketed by day, week
+// (default) or month in the group's time zone; from and to narrow the window.
+func GetChoreAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	groupName := query.Get("group_name")
+	if groupName == "" {
+		http.Error(w, "Group name is required", http.StatusBadRequest)
+		return
+	}
+
+	bucket := models.AnalyticsBucket(query.Get("bucket"))
+	if bucket == "" {
+		bucket = models.AnalyticsBucketWeek
+	}
+	if !bucket.IsValid() {
+		http.Error(w, "Invalid bucket. Must be day, week or month", http.StatusBadRequest)
+		return
+	}
+
+	var from time.Time
+	if value := query.Get("from"); value != "" {
+		parsed, err := parseCalendarDate(value)
+		if err != nil {
+			http.Error(w, "Invalid from date. Use RFC3339 or YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	to := time.Now()
+	if value := query.Get("to"); value != "" {
+		parsed, err := parseCalendarDate(value)
+		if err != nil {
+			http.Error(w, "Invalid to date. Use RFC3339 or YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+
+	_, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	loc, err := models.LoadGroupLocation(group.Timezone)
+	if err != nil {
+		log.Printf("Group %s has an invalid time zone %q, using UTC: %v", group.ID.Hex(), group.Timezone, err)
+		loc = time.UTC
+	}
+
+	start, end, err := models.AnalyticsWindow(bucket, from, to, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, cached, err := jobs.GetChoreAnalytics(group.ID, bucket, start, end, loc)
+	if err != nil {
+		log.Printf("Failed to compute chore analytics for group %s: %v", group.ID.Hex(), err)
+		http.Error(w, "Failed to compute analytics", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ChoreAnalyticsResponse{ChoreAnalytics: report, Cached: cached})
+}
+
+// UpdateGroupTimezoneHandler sets the time zone a group's analytics are bucketed in
+func UpdateGroupTimezoneHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request UpdateGroupTimezoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := time.LoadLocation(request.Timezone); err != nil || request.Timezone == "Local" {
+		http.Error(w, "Unknown time zone. Use an IANA name such as Europe/London", http.StatusBadRequest)
+		return
+	}
+
+	_, group, ok := findGroupForMember(w, r, request.GroupName)
+	if !ok {
+		return
+	}
+
+	_, err := config.DB.Collection("groups").UpdateOne(
+		context.Background(),
+		bson.M{"_id": group.ID},
+		bson.M{"$set": bson.M{"timezone": request.Timezone, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		log.Printf("Failed to update group time zone: %v", err)
+		http.Error(w, "Failed to update time zone", http.StatusInternalServerError)
+		return
+	}
+	jobs.InvalidateChoreAnalytics(group.ID)
+
+	group.Timezone = request.Timezone
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
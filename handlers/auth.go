@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"cribb-backend/config"
+	"cribb-backend/jobs"
 	"cribb-backend/middleware"
 	"cribb-backend/models"
 
@@ -232,6 +234,8 @@ type UserData struct {
 	Score      int    `json:"score"`
 	GroupCode  string `json:"groupCode,omitempty"`
 	GroupName  string `json:"groupName,omitempty"`
+
+	Achievements *jobs.MemberAchievements `json:"achievements,omitempty"` // Streaks and badges, only on the profile endpoint
 }
 
 type LoginResponse struct {
@@ -374,6 +378,14 @@ func GetUserProfileHandler(w http.ResponseWriter, r *http.Request) {
 		GroupName:  user.Group, // Add the existing group name field
 	}
 
+	// Attach streaks and badges; the profile is still useful without them
+	achievements, err := jobs.GetMemberAchievements([]primitive.ObjectID{user.ID})
+	if err != nil {
+		log.Printf("GetUserProfileHandler achievements error: %v", err)
+	} else {
+		response.Achievements = achievements[user.ID]
+	}
+
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
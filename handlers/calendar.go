@@ -0,0 +1,277 @@
+// handlers/calendar.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultCalendarDays = 30  // Range returned when no end date is given
+	maxCalendarDays     = 366 // Longest range a single calendar request may cover
+	feedPastDays        = 30  // How far back the ICS feed reaches
+	feedFutureDays      = 90  // How far ahead the ICS feed projects recurring chores
+)
+
+// CalendarResponse is the response of the calendar endpoint
+type CalendarResponse struct {
+	GroupName string                 `json:"group_name"`
+	Start     time.Time              `json:"start"`
+	End       time.Time              `json:"end"`
+	Events    []models.CalendarEvent `json:"events"`
+}
+
+// parseCalendarDate accepts either an RFC3339 timestamp or a plain YYYY-MM-DD date (UTC midnight)
+func parseCalendarDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// buildCalendarEvents returns the chore instances due in [start, end) together with the projected
+// occurrences of the group's recurring chores, sorted by due date. When assignee is set only
+// that member's chores are returned.
+func buildCalendarEvents(groupID primitive.ObjectID, assignee *primitive.ObjectID, start, end, now time.Time) ([]models.CalendarEvent, error) {
+	ctx := context.Background()
+
+	filter := bson.M{
+		"group_id": groupID,
+		"due_date": bson.M{"$gte": start, "$lt": end},
+	}
+	if assignee != nil {
+		filter["assigned_to"] = *assignee
+	}
+
+	cursor, err := config.DB.Collection("chores").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var chores []models.Chore
+	if err = cursor.All(ctx, &chores); err != nil {
+		return nil, err
+	}
+
+	events := make([]models.CalendarEvent, 0, len(chores))
+	created := make(map[string]bool, len(chores))
+	for i := range chores {
+		event := models.ChoreCalendarEvent(&chores[i])
+		created[event.UID] = true
+		events = append(events, event)
+	}
+
+	cursor, err = config.DB.Collection("recurring_chores").Find(ctx, bson.M{
+		"group_id":  groupID,
+		"is_active": true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var recurringChores []models.RecurringChore
+	if err = cursor.All(ctx, &recurringChores); err != nil {
+		return nil, err
+	}
+
+	for _, rc := range recurringChores {
+		for _, event := range models.ProjectRecurringChore(rc, start, end, now) {
+			// An occurrence already created as a chore is listed once, as that chore
+			if created[event.UID] || (assignee != nil && event.AssignedTo != *assignee) {
+				continue
+			}
+			events = append(events, event)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].DueDate.Before(events[j].DueDate)
+	})
+	return events, nil
+}
+
+// GetCalendarHandler returns a group's chores due within a date range, including projected
+// occurrences of recurring chores that have not been created yet
+func GetCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	groupName := query.Get("group_name")
+	if groupName == "" {
+		http.Error(w, "Group name is required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	year, month, day := now.UTC().Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if value := query.Get("start"); value != "" {
+		parsed, err := parseCalendarDate(value)
+		if err != nil {
+			http.Error(w, "Invalid start date. Use RFC3339 or YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		start = parsed
+	}
+
+	end := start.AddDate(0, 0, defaultCalendarDays)
+	if value := query.Get("end"); value != "" {
+		parsed, err := parseCalendarDate(value)
+		if err != nil {
+			http.Error(w, "Invalid end date. Use RFC3339 or YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		end = parsed
+	}
+
+	if !end.After(start) {
+		http.Error(w, "End date must be after start date", http.StatusBadRequest)
+		return
+	}
+	if end.Sub(start) > maxCalendarDays*24*time.Hour {
+		http.Error(w, fmt.Sprintf("Date range cannot exceed %d days", maxCalendarDays), http.StatusBadRequest)
+		return
+	}
+
+	var assignee *primitive.ObjectID
+	if value := query.Get("assigned_to"); value != "" {
+		assigneeID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			http.Error(w, "Invalid assigned_to ID format", http.StatusBadRequest)
+			return
+		}
+		assignee = &assigneeID
+	}
+
+	_, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	events, err := buildCalendarEvents(group.ID, assignee, start, end, now)
+	if err != nil {
+		log.Printf("Failed to build calendar for group %s: %v", group.ID.Hex(), err)
+		http.Error(w, "Failed to fetch calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CalendarResponse{
+		GroupName: group.Name,
+		Start:     start,
+		End:       end,
+		Events:    events,
+	})
+}
+
+// RotateCalendarFeedTokenHandler issues a new secret token for the current user's ICS feed.
+// Any previously issued feed URL stops working.
+func RotateCalendarFeedTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		http.Error(w, "Failed to generate feed token", http.StatusInternalServerError)
+		return
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	result, err := config.DB.Collection("users").UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"calendar_feed_token": token, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		log.Printf("Failed to store calendar feed token: %v", err)
+		http.Error(w, "Failed to create feed token", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"token":    token,
+		"feed_url": fmt.Sprintf("%s://%s/api/calendar/feed.ics?token=%s", scheme, r.Host, token),
+	})
+}
+
+// CalendarFeedHandler serves a user's chores as an iCalendar feed. Calendar apps cannot send
+// a JWT, so the feed is authenticated by the secret token in the URL instead.
+func CalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Feed token is required", http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := config.DB.Collection("users").FindOne(
+		context.Background(),
+		bson.M{"calendar_feed_token": token},
+	).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Feed not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch feed", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	now := time.Now()
+	events := make([]models.CalendarEvent, 0)
+	if !user.GroupID.IsZero() {
+		events, err = buildCalendarEvents(user.GroupID, &user.ID, now.AddDate(0, 0, -feedPastDays), now.AddDate(0, 0, feedFutureDays), now)
+		if err != nil {
+			log.Printf("Failed to build calendar feed for user %s: %v", user.ID.Hex(), err)
+			http.Error(w, "Failed to build feed", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	calendarName := "Cribb chores"
+	if user.Group != "" {
+		calendarName = fmt.Sprintf("Cribb chores (%s)", user.Group)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="cribb-chores.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	w.Write([]byte(models.MarshalICalendar(calendarName, events, now)))
+}
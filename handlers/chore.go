@@ -4,7 +4,9 @@ package handlers
 import (
 	"context"
 	"cribb-backend/config"
+	"cribb-backend/jobs"
 	"cribb-backend/models"
+	"cribb-backend/realtime"
 	"encoding/json"
 	"errors"
 	"log"
@@ -30,6 +32,10 @@ func CreateIndividualChoreHandler(w http.ResponseWriter, r *http.Request) {
 		AssignedTo  string    `json:"assigned_to"` // Username of user to assign
 		DueDate     time.Time `json:"due_date"`
 		Points      int       `json:"points"`
+
+		Subtasks      []models.SubtaskSpec `json:"subtasks"`       // Optional ordered checklist
+		SubtaskPolicy models.SubtaskPolicy `json:"subtask_policy"` // block (default) or warn
+		PartialPoints bool                 `json:"partial_points"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -47,9 +53,19 @@ func CreateIndividualChoreHandler(w http.ResponseWriter, r *http.Request) {
 		request.Points = 1 // Default points if not provided or invalid
 	}
 
+	if request.SubtaskPolicy != "" && !request.SubtaskPolicy.IsValid() {
+		http.Error(w, "Invalid subtask policy. Must be block or warn", http.StatusBadRequest)
+		return
+	}
+	subtasks, err := models.BuildSubtasks(request.Subtasks, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Find the group
 	var group models.Group
-	err := config.DB.Collection("groups").FindOne(
+	err = config.DB.Collection("groups").FindOne(
 		context.Background(),
 		bson.M{"name": request.GroupName},
 	).Decode(&group)
@@ -94,6 +110,9 @@ func CreateIndividualChoreHandler(w http.ResponseWriter, r *http.Request) {
 		request.DueDate,
 		request.Points,
 	)
+	chore.Subtasks = subtasks
+	chore.SubtaskPolicy = request.SubtaskPolicy
+	chore.PartialPoints = request.PartialPoints
 
 	// Insert the chore
 	result, err := config.DB.Collection("chores").InsertOne(context.Background(), chore)
@@ -106,6 +125,9 @@ func CreateIndividualChoreHandler(w http.ResponseWriter, r *http.Request) {
 	// Set the inserted ID
 	chore.ID = result.InsertedID.(primitive.ObjectID)
 
+	jobs.InvalidateChoreAnalytics(group.ID)
+	publishGroupEvent(r, group.ID, realtime.EventChoreCreated, chore)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(chore)
@@ -127,6 +149,10 @@ func CreateRecurringChoreHandler(w http.ResponseWriter, r *http.Request) {
 		Points          int      `json:"points"`
 		MemberUsernames []string `json:"member_usernames"`
 		FirstDueDate    string   `json:"first_due_date"`
+
+		Subtasks      []models.SubtaskSpec `json:"subtasks"`       // Optional checklist copied into every instance
+		SubtaskPolicy models.SubtaskPolicy `json:"subtask_policy"` // block (default) or warn
+		PartialPoints bool                 `json:"partial_points"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -157,9 +183,19 @@ func CreateRecurringChoreHandler(w http.ResponseWriter, r *http.Request) {
 		request.Points = 1 // Default points if not provided or invalid
 	}
 
+	if request.SubtaskPolicy != "" && !request.SubtaskPolicy.IsValid() {
+		http.Error(w, "Invalid subtask policy. Must be block or warn", http.StatusBadRequest)
+		return
+	}
+	subtasks, err := models.BuildSubtasks(request.Subtasks, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Find the group
 	var group models.Group
-	err := config.DB.Collection("groups").FindOne(
+	err = config.DB.Collection("groups").FindOne(
 		context.Background(),
 		bson.M{"name": request.GroupName},
 	).Decode(&group)
@@ -239,6 +275,9 @@ func CreateRecurringChoreHandler(w http.ResponseWriter, r *http.Request) {
 		request.Frequency,
 		request.Points,
 	)
+	recurringChore.Subtasks = subtasks
+	recurringChore.SubtaskPolicy = request.SubtaskPolicy
+	recurringChore.PartialPoints = request.PartialPoints
 
 	// Calculate next assignment time based on frequency
 	var nextAssignment time.Time
@@ -289,10 +328,14 @@ func CreateRecurringChoreHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Insert the first chore instance
-	_, err = config.DB.Collection("chores").InsertOne(context.Background(), firstChore)
+	result, err = config.DB.Collection("chores").InsertOne(context.Background(), firstChore)
 	if err != nil {
 		log.Printf("Failed to create first chore instance: %v", err)
 		// Continue anyway since the recurring definition was created successfully
+	} else {
+		firstChore.ID = result.InsertedID.(primitive.ObjectID)
+		jobs.InvalidateChoreAnalytics(group.ID)
+		publishGroupEvent(r, group.ID, realtime.EventChoreCreated, firstChore)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -354,12 +397,8 @@ func GetUserChoresHandler(w http.ResponseWriter, r *http.Request) {
 		if chore.Status != models.ChoreStatusOverdue && !chore.DueDate.IsZero() && chore.DueDate.Before(now) {
 			chores[i].Status = models.ChoreStatusOverdue
 
-			// Update in database
-			_, _ = config.DB.Collection("chores").UpdateOne(
-				context.Background(),
-				bson.M{"_id": chore.ID},
-				bson.M{"$set": bson.M{"status": models.ChoreStatusOverdue}},
-			)
+			// Update in database and record the missed chore against the member's streaks
+			_, _ = jobs.MarkChoreOverdue(chore)
 		}
 	}
 
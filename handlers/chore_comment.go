@@ -0,0 +1,453 @@
+// handlers/chore_comment.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateChoreCommentRequest defines the request structure for commenting on a chore.
+// Exactly one of ChoreID or RecurringChoreID must be given.
+type CreateChoreCommentRequest struct {
+	ChoreID          string `json:"chore_id"`
+	RecurringChoreID string `json:"recurring_chore_id"`
+	Body             string `json:"body" validate:"required"`
+}
+
+// UpdateChoreCommentRequest defines the request structure for editing a comment
+type UpdateChoreCommentRequest struct {
+	CommentID string `json:"comment_id" validate:"required"`
+	Body      string `json:"body" validate:"required"`
+}
+
+// ChoreCommentsResponse is a page of comments, oldest first
+type ChoreCommentsResponse struct {
+	Comments []models.ChoreComment `json:"comments"`
+	Page     int                   `json:"page"`
+	Limit    int                   `json:"limit"`
+	Total    int64                 `json:"total"`
+	HasMore  bool                  `json:"has_more"`
+}
+
+// validateCommentBody trims the body and checks its length
+func validateCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", errors.New("comment body is required")
+	}
+	if len([]rune(body)) > models.MaxCommentLength {
+		return "", errors.New("comment body must be at most " + strconv.Itoa(models.MaxCommentLength) + " characters")
+	}
+	return body, nil
+}
+
+// resolveMentions maps the @usernames in a comment body to members of the group.
+// Mentions of people outside the group are ignored.
+func resolveMentions(body string, groupID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	usernames := models.ParseMentions(body)
+	mentions := make([]primitive.ObjectID, 0, len(usernames))
+	if len(usernames) == 0 {
+		return mentions, nil
+	}
+
+	cursor, err := config.DB.Collection("users").Find(
+		context.Background(),
+		bson.M{"group_id": groupID},
+		options.Find().SetProjection(bson.M{"_id": 1, "username": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var members []models.User
+	if err = cursor.All(context.Background(), &members); err != nil {
+		return nil, err
+	}
+
+	membersByUsername := make(map[string]primitive.ObjectID, len(members))
+	for _, member := range members {
+		membersByUsername[strings.ToLower(member.Username)] = member.ID
+	}
+	for _, username := range usernames {
+		if id, ok := membersByUsername[username]; ok {
+			mentions = append(mentions, id)
+		}
+	}
+	return mentions, nil
+}
+
+// publishCommentNotifications adds the comment to the group's notification stream
+func publishCommentNotifications(comment *models.ChoreComment) {
+	notifications := models.CreateCommentNotifications(comment)
+	documents := make([]interface{}, 0, len(notifications))
+	for _, notification := range notifications {
+		documents = append(documents, notification)
+	}
+
+	if _, err := config.DB.Collection("notifications").InsertMany(context.Background(), documents); err != nil {
+		log.Printf("Failed to create comment notifications: %v", err)
+	}
+}
+
+// CreateChoreCommentHandler adds a comment to a chore or recurring chore
+func CreateChoreCommentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request CreateChoreCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if (request.ChoreID == "") == (request.RecurringChoreID == "") {
+		http.Error(w, "Exactly one of chore_id or recurring_chore_id is required", http.StatusBadRequest)
+		return
+	}
+
+	body, err := validateCommentBody(request.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var chore models.Chore
+	var recurringChore models.RecurringChore
+	var groupID primitive.ObjectID
+
+	if request.ChoreID != "" {
+		choreID, err := primitive.ObjectIDFromHex(request.ChoreID)
+		if err != nil {
+			http.Error(w, "Invalid chore ID format", http.StatusBadRequest)
+			return
+		}
+		err = config.DB.Collection("chores").FindOne(context.Background(), bson.M{"_id": choreID}).Decode(&chore)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				http.Error(w, "Chore not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to fetch chore", http.StatusInternalServerError)
+			}
+			return
+		}
+		groupID = chore.GroupID
+	} else {
+		recurringChoreID, err := primitive.ObjectIDFromHex(request.RecurringChoreID)
+		if err != nil {
+			http.Error(w, "Invalid recurring chore ID format", http.StatusBadRequest)
+			return
+		}
+		err = config.DB.Collection("recurring_chores").FindOne(context.Background(), bson.M{"_id": recurringChoreID}).Decode(&recurringChore)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				http.Error(w, "Recurring chore not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to fetch recurring chore", http.StatusInternalServerError)
+			}
+			return
+		}
+		groupID = recurringChore.GroupID
+	}
+
+	user, ok := verifyGroupMember(w, userID, groupID)
+	if !ok {
+		return
+	}
+
+	mentions, err := resolveMentions(body, groupID)
+	if err != nil {
+		log.Printf("Failed to resolve comment mentions: %v", err)
+		http.Error(w, "Failed to resolve mentions", http.StatusInternalServerError)
+		return
+	}
+
+	var comment *models.ChoreComment
+	if request.ChoreID != "" {
+		comment = models.CreateChoreComment(&chore, user.ID, user.Name, body, mentions)
+	} else {
+		comment = models.CreateRecurringChoreComment(&recurringChore, user.ID, user.Name, body, mentions)
+	}
+
+	result, err := config.DB.Collection("chore_comments").InsertOne(context.Background(), comment)
+	if err != nil {
+		log.Printf("Failed to create comment: %v", err)
+		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+		return
+	}
+	comment.ID = result.InsertedID.(primitive.ObjectID)
+
+	publishCommentNotifications(comment)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// GetChoreCommentsHandler lists the comments on a chore or recurring chore, oldest first.
+// Comments on chore instances remain available after the chore has been cleared.
+func GetChoreCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	choreIDStr := r.URL.Query().Get("chore_id")
+	recurringChoreIDStr := r.URL.Query().Get("recurring_chore_id")
+	if (choreIDStr == "") == (recurringChoreIDStr == "") {
+		http.Error(w, "Exactly one of chore_id or recurring_chore_id is required", http.StatusBadRequest)
+		return
+	}
+
+	page := 1
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		parsed, err := strconv.Atoi(pageStr)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Page must be a positive number", http.StatusBadRequest)
+			return
+		}
+		page = parsed
+	}
+
+	limit := 20 // Default page size
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > 100 {
+			http.Error(w, "Limit must be between 1 and 100", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{}
+	if choreIDStr != "" {
+		choreID, err := primitive.ObjectIDFromHex(choreIDStr)
+		if err != nil {
+			http.Error(w, "Invalid chore ID format", http.StatusBadRequest)
+			return
+		}
+		filter["chore_id"] = choreID
+	} else {
+		recurringChoreID, err := primitive.ObjectIDFromHex(recurringChoreIDStr)
+		if err != nil {
+			http.Error(w, "Invalid recurring chore ID format", http.StatusBadRequest)
+			return
+		}
+		filter["recurring_chore_id"] = recurringChoreID
+	}
+
+	// Only members of the user's group can read its comments
+	var user models.User
+	err := config.DB.Collection("users").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		}
+		return
+	}
+	filter["group_id"] = user.GroupID
+
+	total, err := config.DB.Collection("chore_comments").CountDocuments(context.Background(), filter)
+	if err != nil {
+		http.Error(w, "Failed to count comments", http.StatusInternalServerError)
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := config.DB.Collection("chore_comments").Find(context.Background(), filter, opts)
+	if err != nil {
+		http.Error(w, "Failed to fetch comments", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	comments := make([]models.ChoreComment, 0)
+	if err = cursor.All(context.Background(), &comments); err != nil {
+		http.Error(w, "Failed to decode comments", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ChoreCommentsResponse{
+		Comments: comments,
+		Page:     page,
+		Limit:    limit,
+		Total:    total,
+		HasMore:  int64(page*limit) < total,
+	})
+}
+
+// findOwnComment loads a comment and checks that the current user wrote it
+func findOwnComment(w http.ResponseWriter, r *http.Request, commentIDStr string) (models.ChoreComment, bool) {
+	var comment models.ChoreComment
+
+	commentID, err := primitive.ObjectIDFromHex(commentIDStr)
+	if err != nil {
+		http.Error(w, "Invalid comment ID format", http.StatusBadRequest)
+		return comment, false
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return comment, false
+	}
+
+	err = config.DB.Collection("chore_comments").FindOne(context.Background(), bson.M{"_id": commentID}).Decode(&comment)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch comment", http.StatusInternalServerError)
+		}
+		return comment, false
+	}
+
+	if !comment.CanBeEditedBy(userID) {
+		http.Error(w, "You can only change your own comments", http.StatusForbidden)
+		return comment, false
+	}
+	return comment, true
+}
+
+// UpdateChoreCommentHandler edits a comment. Only the author can edit it.
+func UpdateChoreCommentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request UpdateChoreCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	body, err := validateCommentBody(request.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comment, ok := findOwnComment(w, r, request.CommentID)
+	if !ok {
+		return
+	}
+
+	mentions, err := resolveMentions(body, comment.GroupID)
+	if err != nil {
+		log.Printf("Failed to resolve comment mentions: %v", err)
+		http.Error(w, "Failed to resolve mentions", http.StatusInternalServerError)
+		return
+	}
+
+	// Only notify members who were not already mentioned before the edit
+	previouslyMentioned := make(map[primitive.ObjectID]bool, len(comment.Mentions))
+	for _, id := range comment.Mentions {
+		previouslyMentioned[id] = true
+	}
+	newMentions := make([]primitive.ObjectID, 0)
+	for _, id := range mentions {
+		if !previouslyMentioned[id] {
+			newMentions = append(newMentions, id)
+		}
+	}
+
+	now := time.Now()
+	var updated models.ChoreComment
+	err = config.DB.Collection("chore_comments").FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": comment.ID},
+		bson.M{"$set": bson.M{
+			"body":       body,
+			"mentions":   mentions,
+			"updated_at": now,
+			"edited_at":  now,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		log.Printf("Failed to update comment: %v", err)
+		http.Error(w, "Failed to update comment", http.StatusInternalServerError)
+		return
+	}
+
+	if len(newMentions) > 0 {
+		notifyOnly := updated
+		notifyOnly.Mentions = newMentions
+		notifications := models.CreateCommentNotifications(&notifyOnly)
+		// Skip the group-wide entry; the comment itself is not new
+		documents := make([]interface{}, 0, len(notifications)-1)
+		for _, notification := range notifications[1:] {
+			documents = append(documents, notification)
+		}
+		if len(documents) > 0 {
+			if _, err := config.DB.Collection("notifications").InsertMany(context.Background(), documents); err != nil {
+				log.Printf("Failed to create mention notifications: %v", err)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteChoreCommentHandler deletes a comment. Only the author can delete it.
+func DeleteChoreCommentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	comment, ok := findOwnComment(w, r, r.URL.Query().Get("comment_id"))
+	if !ok {
+		return
+	}
+
+	_, err := config.DB.Collection("chore_comments").DeleteOne(context.Background(), bson.M{"_id": comment.ID})
+	if err != nil {
+		log.Printf("Failed to delete comment: %v", err)
+		http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
+		return
+	}
+
+	// Remove the stream entries that point at the deleted comment
+	_, err = config.DB.Collection("notifications").DeleteMany(context.Background(), bson.M{"chore.comment_id": comment.ID})
+	if err != nil {
+		log.Printf("Failed to delete comment notifications: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Comment deleted successfully",
+	})
+}
@@ -4,7 +4,10 @@ package handlers
 import (
 	"context"
 	"cribb-backend/config"
+	"cribb-backend/jobs"
 	"cribb-backend/models"
+	"cribb-backend/query"
+	"cribb-backend/realtime"
 	"encoding/json"
 	"errors"
 	"log"
@@ -14,7 +17,6 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // CompleteChoreHandler handles the completion of a chore by a user
@@ -63,6 +65,10 @@ func CompleteChoreHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer session.EndSession(context.Background())
 
+	// Completed chore and completion time, captured for the achievements engine
+	var completedChore models.Chore
+	var completedAt time.Time
+
 	// Define the transaction
 	result, err := session.WithTransaction(context.Background(), func(sessionContext mongo.SessionContext) (interface{}, error) {
 		// 1. Get the user by ID
@@ -103,14 +109,22 @@ func CompleteChoreHandler(w http.ResponseWriter, r *http.Request) {
 			return nil, errors.New("chore is already completed")
 		}
 
+		// Required subtasks block completion unless the chore only warns about them
+		openSubtasks := chore.OpenRequiredSubtasks()
+		if len(openSubtasks) > 0 && chore.EffectiveSubtaskPolicy() == models.SubtaskPolicyBlock {
+			return nil, errors.New("chore has open required subtasks")
+		}
+		points := chore.EarnedPoints()
+
 		now := time.Now()
 
 		// 5. Create chore completion record
 		choreCompletion := models.ChoreCompletion{
 			ChoreID:     chore.ID,
 			UserID:      user.ID,
+			GroupID:     chore.GroupID,
 			CompletedAt: now,
-			Points:      chore.Points,
+			Points:      points,
 		}
 
 		_, err = config.DB.Collection("chore_completions").InsertOne(
@@ -127,8 +141,9 @@ func CompleteChoreHandler(w http.ResponseWriter, r *http.Request) {
 			bson.M{"_id": chore.ID},
 			bson.M{
 				"$set": bson.M{
-					"status":     models.ChoreStatusCompleted,
-					"updated_at": now,
+					"status":       models.ChoreStatusCompleted,
+					"completed_at": now,
+					"updated_at":   now,
 				},
 			},
 		)
@@ -141,7 +156,7 @@ func CompleteChoreHandler(w http.ResponseWriter, r *http.Request) {
 			sessionContext,
 			bson.M{"_id": user.ID},
 			bson.M{
-				"$inc": bson.M{"score": chore.Points},
+				"$inc": bson.M{"score": points},
 				"$set": bson.M{"updated_at": now},
 			},
 		)
@@ -213,10 +228,19 @@ func CompleteChoreHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		return map[string]interface{}{
-			"points_earned": chore.Points,
-			"new_score":     user.Score + chore.Points,
-		}, nil
+		completedChore = chore
+		completedChore.Points = points
+		completedAt = now
+
+		response := map[string]interface{}{
+			"points_earned": points,
+			"new_score":     user.Score + points,
+		}
+		if len(openSubtasks) > 0 {
+			response["open_subtasks"] = openSubtasks
+			response["warning"] = "Chore completed with required subtasks still open"
+		}
+		return response, nil
 	})
 
 	if err != nil {
@@ -225,13 +249,39 @@ func CompleteChoreHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Completion changes every analytics figure of the group
+	jobs.InvalidateChoreAnalytics(completedChore.GroupID)
+
+	// Update streaks and award achievements; failures here must not fail the completion
+	response := result.(map[string]interface{})
+	earned, err := jobs.ProcessAchievementEvent(models.AchievementEvent{
+		Type:        models.AchievementEventChoreCompleted,
+		UserID:      userID,
+		GroupID:     completedChore.GroupID,
+		ChoreID:     completedChore.ID,
+		RecurringID: completedChore.RecurringID,
+		Points:      completedChore.Points,
+		DueDate:     completedChore.DueDate,
+		OccurredAt:  completedAt,
+	})
+	if err != nil {
+		log.Printf("Failed to process achievements for chore %s: %v", completedChore.ID.Hex(), err)
+	}
+	if earned == nil {
+		earned = []models.EarnedAchievement{}
+	}
+	response["achievements_earned"] = earned
+
+	publishGroupEvent(r, completedChore.GroupID, realtime.EventChoreCompleted, completedChore)
+
 	// Return success response
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(response)
 }
 
-// GetGroupChoresHandler retrieves all active chores for a group
-// GetGroupChoresHandler retrieves all active chores for a group
+// GetGroupChoresHandler retrieves a page of a group's active chores. Chores can be filtered
+// by status, assigned_to, recurring_id, points and due_date or created_at ranges, e.g.
+// status=pending,overdue&due_date[lt]=2025-04-01&sort=-points
 func GetGroupChoresHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -244,6 +294,11 @@ func GetGroupChoresHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	q, ok := parseListQuery(w, r, choreListSpec)
+	if !ok {
+		return
+	}
+
 	// Find the group by name
 	var group models.Group
 	err := config.DB.Collection("groups").FindOne(
@@ -260,12 +315,10 @@ func GetGroupChoresHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get all chores for the group, sorted by due date
-	opts := options.Find().SetSort(bson.D{{Key: "due_date", Value: 1}})
 	cursor, err := config.DB.Collection("chores").Find(
 		context.Background(),
-		bson.M{"group_id": group.ID},
-		opts,
+		q.Filter(models.NotArchived(bson.M{"group_id": group.ID})),
+		q.FindOptions(),
 	)
 
 	if err != nil {
@@ -280,6 +333,13 @@ func GetGroupChoresHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	chores, nextCursor, err := query.Paginate(chores, q)
+	if err != nil {
+		log.Printf("Failed to build chores cursor: %v", err)
+		http.Error(w, "Failed to paginate chores", http.StatusInternalServerError)
+		return
+	}
+
 	// Check for overdue chores and update their status
 	// now := time.Now()
 	// Calculate the start of today in UTC
@@ -305,16 +365,11 @@ func GetGroupChoresHandler(w http.ResponseWriter, r *http.Request) {
 				chores[i].Status = models.ChoreStatusOverdue
 
 				// Update in database (don't wait for the result)
-				go func(choreID primitive.ObjectID) {
-					_, err := config.DB.Collection("chores").UpdateOne(
-						context.Background(),
-						bson.M{"_id": choreID},
-						bson.M{"$set": bson.M{"status": models.ChoreStatusOverdue}},
-					)
-					if err != nil {
+				go func(chore models.Chore) {
+					if _, err := jobs.MarkChoreOverdue(chore); err != nil {
 						log.Printf("Failed to update chore status to overdue: %v", err)
 					}
-				}(chore.ID)
+				}(chore)
 			}
 		}
 	}
@@ -349,7 +404,7 @@ func GetGroupChoresHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(choresWithAssignees)
+	json.NewEncoder(w).Encode(query.NewPage(choresWithAssignees, nextCursor, q.Limit))
 }
 
 // GetGroupRecurringChoresHandler retrieves all recurring chores for a group
@@ -0,0 +1,232 @@
+// handlers/chore_history.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/jobs"
+	"cribb-backend/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RestoreChoreRequest defines the request structure for bringing a chore back from the archive
+type RestoreChoreRequest struct {
+	ChoreID string `json:"chore_id" validate:"required"`
+}
+
+// ChoreHistoryResponse is a page of completed chores, most recently completed first
+type ChoreHistoryResponse struct {
+	Chores  []models.Chore `json:"chores"`
+	Page    int            `json:"page"`
+	Limit   int            `json:"limit"`
+	Total   int64          `json:"total"`
+	HasMore bool           `json:"has_more"`
+}
+
+// GetChoreHistoryHandler lists a group's completed chores, archived or not. Results can be
+// filtered by member (user_id), recurring chore (recurring_id), completion date range
+// (from, to) and archive state (archived=true|false).
+func GetChoreHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	groupName := query.Get("group_name")
+	if groupName == "" {
+		http.Error(w, "Group name is required", http.StatusBadRequest)
+		return
+	}
+
+	page := 1
+	if pageStr := query.Get("page"); pageStr != "" {
+		parsed, err := strconv.Atoi(pageStr)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Page must be a positive number", http.StatusBadRequest)
+			return
+		}
+		page = parsed
+	}
+
+	limit := 20 // Default page size
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > 100 {
+			http.Error(w, "Limit must be between 1 and 100", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	filter := bson.M{"status": models.ChoreStatusCompleted}
+
+	if value := query.Get("user_id"); value != "" {
+		memberID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+			return
+		}
+		filter["assigned_to"] = memberID
+	}
+
+	if value := query.Get("recurring_id"); value != "" {
+		recurringID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			http.Error(w, "Invalid recurring chore ID format", http.StatusBadRequest)
+			return
+		}
+		filter["recurring_id"] = recurringID
+	}
+
+	completedAt := bson.M{}
+	if value := query.Get("from"); value != "" {
+		from, err := parseCalendarDate(value)
+		if err != nil {
+			http.Error(w, "Invalid from date. Use RFC3339 or YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		completedAt["$gte"] = from
+	}
+	if value := query.Get("to"); value != "" {
+		to, err := parseCalendarDate(value)
+		if err != nil {
+			http.Error(w, "Invalid to date. Use RFC3339 or YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		completedAt["$lt"] = to
+	}
+	if len(completedAt) > 0 {
+		filter["completed_at"] = completedAt
+	}
+
+	switch query.Get("archived") {
+	case "":
+	case "true":
+		filter["archived_at"] = bson.M{"$ne": nil}
+	case "false":
+		models.NotArchived(filter)
+	default:
+		http.Error(w, "Archived must be true or false", http.StatusBadRequest)
+		return
+	}
+
+	_, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+	filter["group_id"] = group.ID
+
+	total, err := config.DB.Collection("chores").CountDocuments(context.Background(), filter)
+	if err != nil {
+		http.Error(w, "Failed to count chores", http.StatusInternalServerError)
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "completed_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := config.DB.Collection("chores").Find(context.Background(), filter, opts)
+	if err != nil {
+		http.Error(w, "Failed to fetch chore history", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	chores := make([]models.Chore, 0)
+	if err = cursor.All(context.Background(), &chores); err != nil {
+		http.Error(w, "Failed to decode chores", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ChoreHistoryResponse{
+		Chores:  chores,
+		Page:    page,
+		Limit:   limit,
+		Total:   total,
+		HasMore: int64(page*limit) < total,
+	})
+}
+
+// RestoreChoreHandler brings an archived chore back into the group's chore list
+func RestoreChoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request RestoreChoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	choreID, err := primitive.ObjectIDFromHex(request.ChoreID)
+	if err != nil {
+		http.Error(w, "Invalid chore ID format", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var chore models.Chore
+	err = config.DB.Collection("chores").FindOne(context.Background(), bson.M{"_id": choreID}).Decode(&chore)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Chore not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch chore", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if _, ok := verifyGroupMember(w, userID, chore.GroupID); !ok {
+		return
+	}
+
+	if !chore.IsArchived() {
+		http.Error(w, "Chore is not archived", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	var restored models.Chore
+	err = config.DB.Collection("chores").FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": choreID, "archived_at": bson.M{"$ne": nil}},
+		bson.M{
+			"$set":   bson.M{"restored_at": now, "updated_at": now},
+			"$unset": bson.M{"archived_at": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&restored)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Chore is not archived", http.StatusConflict)
+		} else {
+			log.Printf("Failed to restore chore: %v", err)
+			http.Error(w, "Failed to restore chore", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	jobs.InvalidateChoreAnalytics(restored.GroupID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restored)
+}
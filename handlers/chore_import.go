@@ -0,0 +1,364 @@
+// handlers/chore_import.go
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/jobs"
+	"cribb-backend/models"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxImportFileSize is the largest iCalendar file accepted for import
+const maxImportFileSize = 2 << 20 // 2 MB
+
+// errImportNotPending is returned when an import was already committed or has expired
+var errImportNotPending = errors.New("import is no longer pending")
+
+// CommitChoreImportRequest defines the request structure for confirming a previewed import
+type CommitChoreImportRequest struct {
+	ImportID    string            `json:"import_id" validate:"required"`
+	SkipUIDs    []string          `json:"skip_uids"`   // Events to leave out
+	Assignments map[string]string `json:"assignments"` // Event UID -> username, overrides the previewed assignee
+}
+
+// ICalValidationResponse lists the line-numbered problems found in an uploaded file
+type ICalValidationResponse struct {
+	Status  string                  `json:"status"`
+	Message string                  `json:"message"`
+	Errors  []models.ICalParseError `json:"errors"`
+}
+
+// CommittedChoreImport is the response of a successful import
+type CommittedChoreImport struct {
+	ImportID        primitive.ObjectID      `json:"import_id"`
+	Chores          []models.Chore          `json:"chores"`
+	RecurringChores []models.RecurringChore `json:"recurring_chores"`
+	Skipped         int                     `json:"skipped"`
+}
+
+// readImportFile returns the uploaded calendar and its file name. Files can be sent as a
+// multipart "file" field or as a raw text/calendar body.
+func readImportFile(w http.ResponseWriter, r *http.Request) ([]byte, string, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize+1024)
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+			http.Error(w, "Invalid upload or file too large", http.StatusBadRequest)
+			return nil, "", false
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "A .ics file is required in the file field", http.StatusBadRequest)
+			return nil, "", false
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, "Failed to read uploaded file", http.StatusBadRequest)
+			return nil, "", false
+		}
+		return data, header.Filename, true
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+		return nil, "", false
+	}
+	if len(data) == 0 {
+		http.Error(w, "Calendar file is required", http.StatusBadRequest)
+		return nil, "", false
+	}
+	return data, "upload.ics", true
+}
+
+// fetchGroupMembers returns the members of a group in the order they joined
+func fetchGroupMembers(ctx context.Context, groupID primitive.ObjectID) ([]models.User, error) {
+	cursor, err := config.DB.Collection("users").Find(
+		ctx,
+		bson.M{"group_id": groupID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var members []models.User
+	if err = cursor.All(ctx, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// PreviewChoreImportHandler parses an uploaded iCalendar file and shows which chores it would
+// create. Nothing is created until the preview is committed.
+func PreviewChoreImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, fileName, ok := readImportFile(w, r)
+	if !ok {
+		return
+	}
+
+	// Form values take precedence so multipart uploads can carry their own options
+	groupName := r.FormValue("group_name")
+	if groupName == "" {
+		http.Error(w, "Group name is required", http.StatusBadRequest)
+		return
+	}
+	points := 1
+	if value := r.FormValue("points"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Points must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		points = parsed
+	}
+
+	user, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	events, parseErrors := models.ParseICalendar(bytes.NewReader(data))
+	if len(parseErrors) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ICalValidationResponse{
+			Status:  "error",
+			Message: "Invalid iCalendar file",
+			Errors:  parseErrors,
+		})
+		return
+	}
+	if len(events) == 0 {
+		http.Error(w, "Calendar file contains no events", http.StatusBadRequest)
+		return
+	}
+
+	members, err := fetchGroupMembers(context.Background(), group.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch group members", http.StatusInternalServerError)
+		return
+	}
+
+	items := models.BuildChoreImportItems(events, members, user, points, time.Now())
+	choreImport := models.CreateChoreImport(group.ID, user.ID, fileName, items)
+
+	result, err := config.DB.Collection("chore_imports").InsertOne(context.Background(), choreImport)
+	if err != nil {
+		log.Printf("Failed to store chore import: %v", err)
+		http.Error(w, "Failed to save import preview", http.StatusInternalServerError)
+		return
+	}
+	choreImport.ID = result.InsertedID.(primitive.ObjectID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(choreImport)
+}
+
+// CommitChoreImportHandler creates the chores of a previewed import in a single transaction
+func CommitChoreImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request CommitChoreImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	importID, err := primitive.ObjectIDFromHex(request.ImportID)
+	if err != nil {
+		http.Error(w, "Invalid import ID format", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var choreImport models.ChoreImport
+	err = config.DB.Collection("chore_imports").FindOne(context.Background(), bson.M{"_id": importID}).Decode(&choreImport)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Import not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch import", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	user, ok := verifyGroupMember(w, userID, choreImport.GroupID)
+	if !ok {
+		return
+	}
+	if !choreImport.IsPending(time.Now()) {
+		http.Error(w, "Import was already committed or has expired", http.StatusConflict)
+		return
+	}
+
+	// Apply the user's corrections to the preview
+	skip := make(map[string]bool, len(request.SkipUIDs))
+	for _, uid := range request.SkipUIDs {
+		skip[uid] = true
+	}
+	if len(request.Assignments) > 0 {
+		members, err := fetchGroupMembers(context.Background(), choreImport.GroupID)
+		if err != nil {
+			http.Error(w, "Failed to fetch group members", http.StatusInternalServerError)
+			return
+		}
+		membersByUsername := make(map[string]models.User, len(members))
+		for _, member := range members {
+			membersByUsername[strings.ToLower(member.Username)] = member
+		}
+
+		for i := range choreImport.Items {
+			item := &choreImport.Items[i]
+			username, exists := request.Assignments[item.UID]
+			if !exists {
+				continue
+			}
+			member, isMember := membersByUsername[strings.ToLower(username)]
+			if !isMember {
+				http.Error(w, "User "+username+" is not a member of this group", http.StatusBadRequest)
+				return
+			}
+			item.AssignTo([]models.User{member}, models.ChoreImportAssignedByMapping, user)
+		}
+	}
+
+	session, err := config.DB.Client().StartSession()
+	if err != nil {
+		log.Printf("Failed to start MongoDB session: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer session.EndSession(context.Background())
+
+	result, err := session.WithTransaction(context.Background(), func(sessionContext mongo.SessionContext) (interface{}, error) {
+		now := time.Now()
+
+		// Claim the import first so concurrent confirmations cannot create the chores twice
+		claim, err := config.DB.Collection("chore_imports").UpdateOne(
+			sessionContext,
+			bson.M{
+				"_id":        choreImport.ID,
+				"status":     models.ChoreImportStatusPending,
+				"expires_at": bson.M{"$gt": now},
+			},
+			bson.M{"$set": bson.M{
+				"status":       models.ChoreImportStatusCommitted,
+				"committed_at": now,
+				"items":        choreImport.Items,
+			}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if claim.MatchedCount == 0 {
+			return nil, errImportNotPending
+		}
+
+		committed := CommittedChoreImport{
+			ImportID:        choreImport.ID,
+			Chores:          make([]models.Chore, 0),
+			RecurringChores: make([]models.RecurringChore, 0),
+		}
+
+		for _, item := range choreImport.Items {
+			if item.Skipped || skip[item.UID] {
+				committed.Skipped++
+				continue
+			}
+
+			if item.Kind == models.ChoreImportKindIndividual {
+				chore := models.CreateChore(item.Title, item.Description, choreImport.GroupID, item.AssignedTo, item.DueDate, item.Points)
+				insertResult, err := config.DB.Collection("chores").InsertOne(sessionContext, chore)
+				if err != nil {
+					return nil, err
+				}
+				chore.ID = insertResult.InsertedID.(primitive.ObjectID)
+				committed.Chores = append(committed.Chores, *chore)
+				continue
+			}
+
+			recurringChore := models.CreateRecurringChore(
+				item.Title,
+				item.Description,
+				choreImport.GroupID,
+				item.MemberRotation,
+				item.Frequency,
+				item.Points,
+			)
+			recurringChore.NextAssignment = item.NextAssignment
+
+			insertResult, err := config.DB.Collection("recurring_chores").InsertOne(sessionContext, recurringChore)
+			if err != nil {
+				return nil, err
+			}
+			recurringChore.ID = insertResult.InsertedID.(primitive.ObjectID)
+
+			firstChore := models.CreateChoreFromRecurringWithBaseDate(recurringChore, item.DueDate)
+
+			// Persist the rotation position after the first assignment
+			_, err = config.DB.Collection("recurring_chores").UpdateOne(
+				sessionContext,
+				bson.M{"_id": recurringChore.ID},
+				bson.M{"$set": bson.M{"current_index": recurringChore.CurrentIndex}},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			choreResult, err := config.DB.Collection("chores").InsertOne(sessionContext, firstChore)
+			if err != nil {
+				return nil, err
+			}
+			firstChore.ID = choreResult.InsertedID.(primitive.ObjectID)
+
+			committed.RecurringChores = append(committed.RecurringChores, *recurringChore)
+			committed.Chores = append(committed.Chores, *firstChore)
+		}
+
+		return committed, nil
+	})
+	if err != nil {
+		if errors.Is(err, errImportNotPending) {
+			http.Error(w, "Import was already committed or has expired", http.StatusConflict)
+			return
+		}
+		log.Printf("Failed to commit chore import %s: %v", choreImport.ID.Hex(), err)
+		http.Error(w, "Failed to import chores", http.StatusInternalServerError)
+		return
+	}
+
+	jobs.InvalidateChoreAnalytics(choreImport.GroupID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
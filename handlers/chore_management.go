@@ -4,7 +4,9 @@ package handlers
 import (
 	"context"
 	"cribb-backend/config"
+	"cribb-backend/jobs"
 	"cribb-backend/models"
+	"cribb-backend/realtime"
 	"encoding/json"
 	"errors"
 	"log"
@@ -146,6 +148,7 @@ func UpdateChoreHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "No changes were made", http.StatusOK)
 		return
 	}
+	jobs.InvalidateChoreAnalytics(chore.GroupID)
 
 	// Get updated chore
 	var updatedChore models.Chore
@@ -165,6 +168,8 @@ func UpdateChoreHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	publishGroupEvent(r, updatedChore.GroupID, realtime.EventChoreUpdated, updatedChore)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(updatedChore)
@@ -223,6 +228,18 @@ func DeleteChoreHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Comments on a deleted chore have nothing left to refer to
+	_, err = config.DB.Collection("chore_comments").DeleteMany(
+		context.Background(),
+		bson.M{"chore_id": objectID},
+	)
+	if err != nil {
+		log.Printf("Failed to delete comments for chore %s: %v", objectID.Hex(), err)
+	}
+
+	jobs.InvalidateChoreAnalytics(chore.GroupID)
+	publishGroupEvent(r, chore.GroupID, realtime.EventChoreDeleted, map[string]primitive.ObjectID{"chore_id": objectID})
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
@@ -420,21 +437,21 @@ func DeleteRecurringChoreHandler(w http.ResponseWriter, r *http.Request) {
 	defer session.EndSession(context.Background())
 
 	// Define the transaction
+	var deleted models.RecurringChore
 	_, err = session.WithTransaction(context.Background(), func(sessionContext mongo.SessionContext) (interface{}, error) {
 		// Delete the recurring chore
-		result, err := config.DB.Collection("recurring_chores").DeleteOne(
+		err := config.DB.Collection("recurring_chores").FindOneAndDelete(
 			sessionContext,
 			bson.M{"_id": objectID},
-		)
+		).Decode(&deleted)
 
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("recurring chore not found")
+		}
 		if err != nil {
 			return nil, err
 		}
 
-		if result.DeletedCount == 0 {
-			return nil, errors.New("recurring chore not found")
-		}
-
 		// Delete any pending instances of this recurring chore
 		_, err = config.DB.Collection("chores").DeleteMany(
 			sessionContext,
@@ -461,6 +478,8 @@ func DeleteRecurringChoreHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	jobs.InvalidateChoreAnalytics(deleted.GroupID)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
@@ -468,7 +487,9 @@ func DeleteRecurringChoreHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// ClearCompletedChoresHandler deletes all completed chores for a given group
+// ClearCompletedChoresHandler archives all completed chores for a given group. Archived chores
+// disappear from the group's chore list but stay available in the chore history, so completions
+// and comments keep pointing at a real chore.
 func ClearCompletedChoresHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -496,23 +517,28 @@ func ClearCompletedChoresHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete all completed chores for this group
-	result, err := config.DB.Collection("chores").DeleteMany(
+	// Archive all completed chores for this group
+	result, err := config.DB.Collection("chores").UpdateMany(
 		context.Background(),
-		bson.M{
+		models.NotArchived(bson.M{
 			"group_id": group.ID,
 			"status":   models.ChoreStatusCompleted,
-		},
+		}),
+		bson.M{"$set": bson.M{"archived_at": time.Now()}},
 	)
 	if err != nil {
-		log.Printf("Failed to delete completed chores: %v", err)
+		log.Printf("Failed to archive completed chores: %v", err)
 		http.Error(w, "Failed to clear completed chores", http.StatusInternalServerError)
 		return
 	}
+	if result.ModifiedCount > 0 {
+		jobs.InvalidateChoreAnalytics(group.ID)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"deleted_count": result.DeletedCount,
-		"message":       "Completed chores cleared successfully",
+		"archived_count": result.ModifiedCount,
+		"deleted_count":  result.ModifiedCount, // Kept for clients written before chores were archived
+		"message":        "Completed chores cleared successfully",
 	})
 }
@@ -0,0 +1,238 @@
+// handlers/chore_subtask.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/jobs"
+	"cribb-backend/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TickSubtaskRequest defines the request structure for ticking or unticking a subtask
+type TickSubtaskRequest struct {
+	ChoreID   string `json:"chore_id" validate:"required"`
+	SubtaskID string `json:"subtask_id" validate:"required"`
+	Done      *bool  `json:"done"` // Defaults to true
+}
+
+// UpdateSubtasksRequest defines the request structure for replacing a checklist.
+// Exactly one of ChoreID or RecurringChoreID must be given.
+type UpdateSubtasksRequest struct {
+	ChoreID          string               `json:"chore_id"`
+	RecurringChoreID string               `json:"recurring_chore_id"`
+	Subtasks         []models.SubtaskSpec `json:"subtasks"`
+	SubtaskPolicy    models.SubtaskPolicy `json:"subtask_policy"`
+	PartialPoints    *bool                `json:"partial_points"`
+}
+
+// TickSubtaskHandler marks a subtask of a chore as done or not done
+func TickSubtaskHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request TickSubtaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	choreID, err := primitive.ObjectIDFromHex(request.ChoreID)
+	if err != nil {
+		http.Error(w, "Invalid chore ID format", http.StatusBadRequest)
+		return
+	}
+	subtaskID, err := primitive.ObjectIDFromHex(request.SubtaskID)
+	if err != nil {
+		http.Error(w, "Invalid subtask ID format", http.StatusBadRequest)
+		return
+	}
+	done := true
+	if request.Done != nil {
+		done = *request.Done
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var chore models.Chore
+	err = config.DB.Collection("chores").FindOne(context.Background(), bson.M{"_id": choreID}).Decode(&chore)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Chore not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch chore", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if _, ok := verifyGroupMember(w, userID, chore.GroupID); !ok {
+		return
+	}
+
+	if chore.Status == models.ChoreStatusCompleted {
+		http.Error(w, "Cannot change subtasks of a completed chore", http.StatusBadRequest)
+		return
+	}
+	if chore.FindSubtask(subtaskID) < 0 {
+		http.Error(w, "Subtask not found", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"subtasks.$.done": done,
+			"updated_at":      now,
+		},
+	}
+	if done {
+		update["$set"].(bson.M)["subtasks.$.done_by"] = userID
+		update["$set"].(bson.M)["subtasks.$.done_at"] = now
+	} else {
+		update["$unset"] = bson.M{
+			"subtasks.$.done_by": "",
+			"subtasks.$.done_at": "",
+		}
+	}
+
+	var updated models.Chore
+	err = config.DB.Collection("chores").FindOneAndUpdate(
+		context.Background(),
+		bson.M{
+			"_id":         choreID,
+			"subtasks.id": subtaskID,
+			"status":      bson.M{"$ne": models.ChoreStatusCompleted},
+		},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Chore was completed or changed, please refresh", http.StatusConflict)
+		} else {
+			log.Printf("Failed to update subtask: %v", err)
+			http.Error(w, "Failed to update subtask", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	jobs.InvalidateChoreAnalytics(updated.GroupID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// UpdateSubtasksHandler replaces the checklist of a chore or recurring chore. Subtasks that
+// reference an existing ID keep their progress; changes to a recurring chore apply to future instances.
+func UpdateSubtasksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request UpdateSubtasksRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if (request.ChoreID == "") == (request.RecurringChoreID == "") {
+		http.Error(w, "Exactly one of chore_id or recurring_chore_id is required", http.StatusBadRequest)
+		return
+	}
+	if request.SubtaskPolicy != "" && !request.SubtaskPolicy.IsValid() {
+		http.Error(w, "Invalid subtask policy. Must be block or warn", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	collection := "chores"
+	idStr := request.ChoreID
+	if request.RecurringChoreID != "" {
+		collection = "recurring_chores"
+		idStr = request.RecurringChoreID
+	}
+
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		http.Error(w, "Invalid chore ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Both chores and recurring chores share the fields needed here
+	var current struct {
+		GroupID  primitive.ObjectID `bson:"group_id"`
+		Status   models.ChoreStatus `bson:"status"`
+		Subtasks []models.Subtask   `bson:"subtasks"`
+	}
+	err = config.DB.Collection(collection).FindOne(context.Background(), bson.M{"_id": id}).Decode(&current)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Chore not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch chore", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if _, ok := verifyGroupMember(w, userID, current.GroupID); !ok {
+		return
+	}
+
+	if current.Status == models.ChoreStatusCompleted {
+		http.Error(w, "Cannot change subtasks of a completed chore", http.StatusBadRequest)
+		return
+	}
+
+	subtasks, err := models.BuildSubtasks(request.Subtasks, current.Subtasks)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	set := bson.M{
+		"subtasks":   subtasks,
+		"updated_at": time.Now(),
+	}
+	if request.SubtaskPolicy != "" {
+		set["subtask_policy"] = request.SubtaskPolicy
+	}
+	if request.PartialPoints != nil {
+		set["partial_points"] = *request.PartialPoints
+	}
+
+	_, err = config.DB.Collection(collection).UpdateOne(
+		context.Background(),
+		bson.M{"_id": id},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		log.Printf("Failed to update subtasks: %v", err)
+		http.Error(w, "Failed to update subtasks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Subtasks updated successfully",
+		"subtasks": subtasks,
+	})
+}
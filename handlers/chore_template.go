@@ -0,0 +1,432 @@
+// handlers/chore_template.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/jobs"
+	"cribb-backend/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateChoreTemplateRequest defines the request structure for creating a custom chore template
+type CreateChoreTemplateRequest struct {
+	GroupName   string `json:"group_name" validate:"required"`
+	Title       string `json:"title" validate:"required"`
+	Description string `json:"description"`
+	Frequency   string `json:"frequency" validate:"required"` // daily, weekly, biweekly, monthly
+	Points      int    `json:"points" validate:"required,min=1"`
+}
+
+// ApplyChoreTemplatesRequest defines the request structure for bulk-creating chores from templates
+type ApplyChoreTemplatesRequest struct {
+	GroupName    string   `json:"group_name" validate:"required"`
+	TemplateIDs  []string `json:"template_ids" validate:"required"`
+	FirstDueDate string   `json:"first_due_date"` // Optional RFC3339 due date for the first instances
+}
+
+// ChoreTemplatesResponse groups the templates available to a group by type
+type ChoreTemplatesResponse struct {
+	GroupName  string                 `json:"group_name"`
+	GroupID    string                 `json:"group_id"`
+	Predefined []models.ChoreTemplate `json:"predefined"`
+	Custom     []models.ChoreTemplate `json:"custom"`
+}
+
+// AppliedChoreTemplate pairs a template with the recurring chore and first instance created from it
+type AppliedChoreTemplate struct {
+	TemplateID     primitive.ObjectID    `json:"template_id"`
+	RecurringChore models.RecurringChore `json:"recurring_chore"`
+	FirstChore     models.Chore          `json:"first_chore"`
+}
+
+// GetChoreTemplatesHandler lists the predefined templates and the group's custom templates
+func GetChoreTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupName := r.URL.Query().Get("group_name")
+	if groupName == "" {
+		http.Error(w, "group_name parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	_, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	// Query for templates: predefined OR custom templates for the group
+	filter := bson.M{
+		"is_active": true,
+		"$or": []bson.M{
+			{"type": models.ChoreTemplateTypePredefined},
+			{
+				"type":     models.ChoreTemplateTypeCustom,
+				"group_id": group.ID,
+			},
+		},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "title", Value: 1}})
+
+	cursor, err := config.DB.Collection("chore_templates").Find(context.Background(), filter, opts)
+	if err != nil {
+		log.Printf("Failed to fetch chore templates: %v", err)
+		http.Error(w, "Failed to fetch chore templates", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	var templates []models.ChoreTemplate
+	if err = cursor.All(context.Background(), &templates); err != nil {
+		log.Printf("Failed to decode chore templates: %v", err)
+		http.Error(w, "Failed to decode chore templates", http.StatusInternalServerError)
+		return
+	}
+
+	response := ChoreTemplatesResponse{
+		GroupName:  group.Name,
+		GroupID:    group.ID.Hex(),
+		Predefined: []models.ChoreTemplate{},
+		Custom:     []models.ChoreTemplate{},
+	}
+	for _, template := range templates {
+		if template.IsPredefined() {
+			response.Predefined = append(response.Predefined, template)
+		} else {
+			response.Custom = append(response.Custom, template)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateChoreTemplateHandler creates a custom chore template for the group
+func CreateChoreTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request CreateChoreTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	title := strings.TrimSpace(request.Title)
+	if title == "" {
+		http.Error(w, "Template title is required", http.StatusBadRequest)
+		return
+	}
+	if !models.IsValidChoreFrequency(request.Frequency) {
+		http.Error(w, "Invalid frequency. Must be daily, weekly, biweekly, or monthly", http.StatusBadRequest)
+		return
+	}
+	if request.Points < 1 {
+		http.Error(w, "Points must be at least 1", http.StatusBadRequest)
+		return
+	}
+
+	user, group, ok := findGroupForMember(w, r, request.GroupName)
+	if !ok {
+		return
+	}
+
+	// Reject titles that clash with a predefined or existing group template
+	escapedTitle := regexp.QuoteMeta(title)
+	existingFilter := bson.M{
+		"title":     bson.M{"$regex": primitive.Regex{Pattern: "^" + escapedTitle + "$", Options: "i"}},
+		"is_active": true,
+		"$or": []bson.M{
+			{"type": models.ChoreTemplateTypePredefined},
+			{
+				"type":     models.ChoreTemplateTypeCustom,
+				"group_id": group.ID,
+			},
+		},
+	}
+	count, err := config.DB.Collection("chore_templates").CountDocuments(context.Background(), existingFilter)
+	if err != nil {
+		log.Printf("Error checking existing chore template: %v", err)
+		http.Error(w, "Failed to check existing templates", http.StatusInternalServerError)
+		return
+	}
+	if count > 0 {
+		http.Error(w, "A template with this title already exists", http.StatusConflict)
+		return
+	}
+
+	template := models.CreateCustomChoreTemplate(
+		title,
+		request.Description,
+		request.Frequency,
+		request.Points,
+		group.ID,
+		user.ID,
+	)
+
+	result, err := config.DB.Collection("chore_templates").InsertOne(context.Background(), template)
+	if err != nil {
+		log.Printf("Failed to create chore template: %v", err)
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "A template with this title already exists", http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to create chore template", http.StatusInternalServerError)
+		}
+		return
+	}
+	template.ID = result.InsertedID.(primitive.ObjectID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(template)
+}
+
+// DeleteChoreTemplateHandler deletes one of the group's custom chore templates
+func DeleteChoreTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	templateID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("template_id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID format", http.StatusBadRequest)
+		return
+	}
+
+	groupName := r.URL.Query().Get("group_name")
+	if groupName == "" {
+		http.Error(w, "group_name parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	user, _, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	var template models.ChoreTemplate
+	err = config.DB.Collection("chore_templates").FindOne(
+		context.Background(),
+		bson.M{"_id": templateID},
+	).Decode(&template)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Template not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch template", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if !template.CanBeDeletedBy(user.ID, user.GroupID) {
+		if template.IsPredefined() {
+			http.Error(w, "Predefined templates cannot be deleted", http.StatusForbidden)
+		} else {
+			http.Error(w, "You can only delete custom templates from your group", http.StatusForbidden)
+		}
+		return
+	}
+
+	_, err = config.DB.Collection("chore_templates").DeleteOne(context.Background(), bson.M{"_id": templateID})
+	if err != nil {
+		log.Printf("Failed to delete chore template: %v", err)
+		http.Error(w, "Failed to delete chore template", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Template deleted successfully",
+	})
+}
+
+// ApplyChoreTemplatesHandler creates a recurring chore for each selected template, rotating over
+// the group's current members. All chores are created in a single transaction.
+func ApplyChoreTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request ApplyChoreTemplatesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(request.TemplateIDs) == 0 {
+		http.Error(w, "At least one template ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var firstDueDate time.Time
+	if request.FirstDueDate != "" {
+		parsed, err := time.Parse(time.RFC3339, request.FirstDueDate)
+		if err != nil {
+			http.Error(w, "Invalid first_due_date format, expected RFC3339", http.StatusBadRequest)
+			return
+		}
+		firstDueDate = parsed
+	}
+
+	// Convert template IDs, ignoring duplicates
+	templateIDs := make([]primitive.ObjectID, 0, len(request.TemplateIDs))
+	seen := make(map[primitive.ObjectID]bool, len(request.TemplateIDs))
+	for _, idStr := range request.TemplateIDs {
+		id, err := primitive.ObjectIDFromHex(idStr)
+		if err != nil {
+			http.Error(w, "Invalid template ID format: "+idStr, http.StatusBadRequest)
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			templateIDs = append(templateIDs, id)
+		}
+	}
+
+	_, group, ok := findGroupForMember(w, r, request.GroupName)
+	if !ok {
+		return
+	}
+
+	// Fetch the selected templates and make sure the group may use all of them
+	cursor, err := config.DB.Collection("chore_templates").Find(
+		context.Background(),
+		bson.M{"_id": bson.M{"$in": templateIDs}, "is_active": true},
+	)
+	if err != nil {
+		http.Error(w, "Failed to fetch templates", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	var found []models.ChoreTemplate
+	if err = cursor.All(context.Background(), &found); err != nil {
+		http.Error(w, "Failed to decode templates", http.StatusInternalServerError)
+		return
+	}
+
+	templatesByID := make(map[primitive.ObjectID]models.ChoreTemplate, len(found))
+	for _, template := range found {
+		templatesByID[template.ID] = template
+	}
+	for _, id := range templateIDs {
+		template, exists := templatesByID[id]
+		if !exists || !template.BelongsToGroup(group.ID) {
+			http.Error(w, "Template not found: "+id.Hex(), http.StatusNotFound)
+			return
+		}
+	}
+
+	// The group's current members form the rotation
+	membersCursor, err := config.DB.Collection("users").Find(
+		context.Background(),
+		bson.M{"group_id": group.ID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		http.Error(w, "Failed to fetch group members", http.StatusInternalServerError)
+		return
+	}
+	defer membersCursor.Close(context.Background())
+
+	var members []models.User
+	if err = membersCursor.All(context.Background(), &members); err != nil {
+		http.Error(w, "Failed to decode users", http.StatusInternalServerError)
+		return
+	}
+	if len(members) == 0 {
+		http.Error(w, "Group has no members to assign chores to", http.StatusBadRequest)
+		return
+	}
+
+	memberRotation := make([]primitive.ObjectID, 0, len(members))
+	for _, member := range members {
+		memberRotation = append(memberRotation, member.ID)
+	}
+
+	session, err := config.DB.Client().StartSession()
+	if err != nil {
+		log.Printf("Failed to start MongoDB session: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer session.EndSession(context.Background())
+
+	result, err := session.WithTransaction(context.Background(), func(sessionContext mongo.SessionContext) (interface{}, error) {
+		applied := make([]AppliedChoreTemplate, 0, len(templateIDs))
+
+		for i, id := range templateIDs {
+			template := templatesByID[id]
+
+			recurringChore := template.ToRecurringChore(group.ID, memberRotation)
+			// Stagger the starting member so the first instances are spread across the group
+			recurringChore.CurrentIndex = i % len(memberRotation)
+
+			insertResult, err := config.DB.Collection("recurring_chores").InsertOne(sessionContext, recurringChore)
+			if err != nil {
+				return nil, err
+			}
+			recurringChore.ID = insertResult.InsertedID.(primitive.ObjectID)
+
+			var firstChore *models.Chore
+			if !firstDueDate.IsZero() {
+				firstChore = models.CreateChoreFromRecurringWithBaseDate(recurringChore, firstDueDate)
+			} else {
+				firstChore = models.CreateChoreFromRecurring(recurringChore)
+			}
+
+			// Persist the rotation position after the first assignment
+			_, err = config.DB.Collection("recurring_chores").UpdateOne(
+				sessionContext,
+				bson.M{"_id": recurringChore.ID},
+				bson.M{"$set": bson.M{"current_index": recurringChore.CurrentIndex}},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			choreResult, err := config.DB.Collection("chores").InsertOne(sessionContext, firstChore)
+			if err != nil {
+				return nil, err
+			}
+			firstChore.ID = choreResult.InsertedID.(primitive.ObjectID)
+
+			applied = append(applied, AppliedChoreTemplate{
+				TemplateID:     template.ID,
+				RecurringChore: *recurringChore,
+				FirstChore:     *firstChore,
+			})
+		}
+
+		return applied, nil
+	})
+	if err != nil {
+		log.Printf("Failed to apply chore templates: %v", err)
+		http.Error(w, "Failed to create chores from templates", http.StatusInternalServerError)
+		return
+	}
+
+	jobs.InvalidateChoreAnalytics(group.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"cribb-backend/config"
+	"cribb-backend/jobs"
 	"cribb-backend/models"
 	"encoding/json"
 	"errors"
@@ -263,6 +264,8 @@ type GroupDetailsResponse struct {
 	UpdatedAt   time.Time          `json:"updated_at"`
 	MemberCount int64              `json:"member_count"`
 	TotalPoints int64              `json:"total_points"`
+
+	Achievements []jobs.MemberAchievements `json:"achievements"` // Streaks and badges per member
 }
 
 // GetGroupDetailsHandler returns high-level metadata about a group (name, code, member count, total points, etc.)
@@ -333,21 +336,56 @@ func GetGroupDetailsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Collect streaks and badges for every current member
+	memberCursor, err := config.DB.Collection("users").Find(
+		ctx,
+		bson.M{"group_id": group.ID},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		log.Printf("GetGroupDetailsHandler find members error: %v", err)
+		http.Error(w, "Failed to fetch members", http.StatusInternalServerError)
+		return
+	}
+	var members []models.User
+	if err := memberCursor.All(ctx, &members); err != nil {
+		log.Printf("GetGroupDetailsHandler members decode error: %v", err)
+		http.Error(w, "Failed to fetch members", http.StatusInternalServerError)
+		return
+	}
+	memberIDs := make([]primitive.ObjectID, 0, len(members))
+	for _, member := range members {
+		memberIDs = append(memberIDs, member.ID)
+	}
+	// Achievements are extra; the group details are still returned without them
+	achievements := make([]jobs.MemberAchievements, 0, len(memberIDs))
+	summaries, err := jobs.GetMemberAchievements(memberIDs)
+	if err != nil {
+		log.Printf("GetGroupDetailsHandler achievements error: %v", err)
+	} else {
+		for _, memberID := range memberIDs {
+			achievements = append(achievements, *summaries[memberID])
+		}
+	}
+
 	response := GroupDetailsResponse{
-		ID:          group.ID,
-		Name:        group.Name,
-		GroupCode:   group.GroupCode,
-		CreatedAt:   group.CreatedAt,
-		UpdatedAt:   group.UpdatedAt,
-		MemberCount: memberCount,
-		TotalPoints: totalPoints,
+		ID:           group.ID,
+		Name:         group.Name,
+		GroupCode:    group.GroupCode,
+		CreatedAt:    group.CreatedAt,
+		UpdatedAt:    group.UpdatedAt,
+		MemberCount:  memberCount,
+		TotalPoints:  totalPoints,
+		Achievements: achievements,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetGroupLeaderboardHandler returns members of a group sorted by score in descending order
+// GetGroupLeaderboardHandler returns members of a group sorted by score in descending order.
+// With a period query parameter (week, month, custom, all_time) it instead returns a ranked
+// leaderboard computed from chore completions in that window.
 func GetGroupLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -380,6 +418,26 @@ func GetGroupLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// When a period is requested, rank by points earned from completions in that window
+	if r.URL.Query().Get("period") != "" {
+		period, start, end, err := parseLeaderboardWindow(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response, err := buildLeaderboardResponse(&group.ID, period, start, end)
+		if err != nil {
+			log.Printf("GetGroupLeaderboardHandler compute leaderboard error: %v", err)
+			http.Error(w, "Failed to compute leaderboard", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	// Retrieve users for the group sorted by score DESC
 	opts := options.Find().SetSort(bson.D{{Key: "score", Value: -1}})
 	cursor, err := config.DB.Collection("users").Find(ctx, bson.M{"group_id": group.ID}, opts)
@@ -0,0 +1,218 @@
+// handlers/leaderboard.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/jobs"
+	"cribb-backend/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LeaderboardResponse defines the response structure for time-windowed leaderboards
+type LeaderboardResponse struct {
+	Period              models.LeaderboardPeriod  `json:"period"`
+	PeriodStart         *time.Time                `json:"period_start,omitempty"`
+	PeriodEnd           *time.Time                `json:"period_end,omitempty"`
+	PreviousPeriodStart *time.Time                `json:"previous_period_start,omitempty"`
+	PreviousPeriodEnd   *time.Time                `json:"previous_period_end,omitempty"`
+	Entries             []models.LeaderboardEntry `json:"entries"`
+}
+
+// LeaderboardHistoryResponse defines the response structure for archived leaderboards
+type LeaderboardHistoryResponse struct {
+	Period    models.LeaderboardPeriod     `json:"period"`
+	Snapshots []models.LeaderboardSnapshot `json:"snapshots"`
+	Winners   []LeaderboardWinner          `json:"winners"`
+}
+
+// LeaderboardWinner summarises who won an archived period
+type LeaderboardWinner struct {
+	PeriodStart time.Time           `json:"period_start"`
+	PeriodEnd   time.Time           `json:"period_end"`
+	WinnerID    *primitive.ObjectID `json:"winner_id,omitempty"`
+	WinnerName  string              `json:"winner_name,omitempty"`
+	Points      int                 `json:"points"`
+}
+
+// parseLeaderboardWindow reads period, start and end query parameters.
+// Custom periods require RFC3339 start and end; all other periods ignore them.
+func parseLeaderboardWindow(query url.Values) (models.LeaderboardPeriod, time.Time, time.Time, error) {
+	period := models.LeaderboardPeriod(query.Get("period"))
+	if !period.IsValid() {
+		return "", time.Time{}, time.Time{}, errors.New("invalid period. Must be week, month, custom, or all_time")
+	}
+
+	if period != models.LeaderboardPeriodCustom {
+		start, end, _ := models.PeriodRange(period, time.Now())
+		return period, start, end, nil
+	}
+
+	start, err := time.Parse(time.RFC3339, query.Get("start"))
+	if err != nil {
+		return "", time.Time{}, time.Time{}, errors.New("custom period requires start in RFC3339 format")
+	}
+	end, err := time.Parse(time.RFC3339, query.Get("end"))
+	if err != nil {
+		return "", time.Time{}, time.Time{}, errors.New("custom period requires end in RFC3339 format")
+	}
+	if !end.After(start) {
+		return "", time.Time{}, time.Time{}, errors.New("end must be after start")
+	}
+
+	return period, start.UTC(), end.UTC(), nil
+}
+
+// buildLeaderboardResponse computes the leaderboard for the window along with rank changes
+// against the previous window. A nil groupID produces a global leaderboard.
+func buildLeaderboardResponse(groupID *primitive.ObjectID, period models.LeaderboardPeriod, start, end time.Time) (*LeaderboardResponse, error) {
+	entries, err := jobs.ComputeLeaderboard(groupID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &LeaderboardResponse{
+		Period:  period,
+		Entries: entries,
+	}
+
+	// All-time leaderboards have no previous period to compare against
+	if period == models.LeaderboardPeriodAllTime {
+		return response, nil
+	}
+
+	prevStart, prevEnd := models.PreviousPeriodRange(period, start, end)
+	response.PeriodStart = &start
+	response.PeriodEnd = &end
+	response.PreviousPeriodStart = &prevStart
+	response.PreviousPeriodEnd = &prevEnd
+
+	// Prefer the archived snapshot so rank changes match what members saw at the time
+	var previousEntries []models.LeaderboardEntry
+	if groupID != nil && period.IsArchivable() {
+		snapshot, err := jobs.FindLeaderboardSnapshot(*groupID, period, prevStart)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot != nil {
+			previousEntries = snapshot.Entries
+		}
+	}
+	if previousEntries == nil {
+		previousEntries, err = jobs.ComputeLeaderboard(groupID, prevStart, prevEnd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	models.ApplyRankChanges(response.Entries, previousEntries)
+	return response, nil
+}
+
+// GetLeaderboardHistoryHandler returns archived weekly or monthly leaderboards for a group,
+// newest first, together with the winner of each period
+func GetLeaderboardHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := context.Background()
+	groupName := r.URL.Query().Get("group_name")
+	groupCode := r.URL.Query().Get("group_code")
+
+	var filter bson.M
+	if groupName != "" {
+		filter = bson.M{"name": groupName}
+	} else if groupCode != "" {
+		filter = bson.M{"group_code": groupCode}
+	} else {
+		http.Error(w, "Either group_name or group_code is required", http.StatusBadRequest)
+		return
+	}
+
+	period := models.LeaderboardPeriod(r.URL.Query().Get("period"))
+	if period == "" {
+		period = models.LeaderboardPeriodMonth
+	}
+	if !period.IsArchivable() {
+		http.Error(w, "Invalid period. Must be week or month", http.StatusBadRequest)
+		return
+	}
+
+	limit := 12 // Default to a year of monthly winners
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > 100 {
+			http.Error(w, "Limit must be between 1 and 100", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	// Fetch group document to obtain its ID
+	var group models.Group
+	if err := config.DB.Collection("groups").FindOne(ctx, filter).Decode(&group); err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Group not found", http.StatusNotFound)
+		} else {
+			log.Printf("GetLeaderboardHistoryHandler find error: %v", err)
+			http.Error(w, "Failed to fetch group", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "period_start", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := config.DB.Collection("leaderboard_snapshots").Find(
+		ctx,
+		bson.M{"group_id": group.ID, "period": period},
+		opts,
+	)
+	if err != nil {
+		log.Printf("GetLeaderboardHistoryHandler find snapshots error: %v", err)
+		http.Error(w, "Failed to fetch leaderboard history", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	snapshots := make([]models.LeaderboardSnapshot, 0)
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		log.Printf("GetLeaderboardHistoryHandler cursor decode error: %v", err)
+		http.Error(w, "Failed to decode leaderboard history", http.StatusInternalServerError)
+		return
+	}
+
+	winners := make([]LeaderboardWinner, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		winner := LeaderboardWinner{
+			PeriodStart: snapshot.PeriodStart,
+			PeriodEnd:   snapshot.PeriodEnd,
+			WinnerID:    snapshot.WinnerID,
+			WinnerName:  snapshot.WinnerName,
+		}
+		if top := models.LeaderboardWinner(snapshot.Entries); top != nil {
+			winner.Points = top.Points
+		}
+		winners = append(winners, winner)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LeaderboardHistoryResponse{
+		Period:    period,
+		Snapshots: snapshots,
+		Winners:   winners,
+	})
+}
@@ -0,0 +1,165 @@
+// handlers/list_query.go
+package handlers
+
+import (
+	"cribb-backend/middleware"
+	"cribb-backend/models"
+	"cribb-backend/query"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Filter and sort whitelists of the list endpoints
+var (
+	choreListSpec = query.Spec{
+		Fields: []query.Field{
+			{Name: "status", Type: query.String, Filter: true, Sort: true, Values: []string{
+				string(models.ChoreStatusPending), string(models.ChoreStatusCompleted), string(models.ChoreStatusOverdue),
+			}},
+			{Name: "assigned_to", Type: query.ObjectID, Filter: true},
+			{Name: "recurring_id", Type: query.ObjectID, Filter: true},
+			{Name: "due_date", Type: query.Time, Filter: true, Sort: true},
+			{Name: "created_at", Type: query.Time, Filter: true, Sort: true},
+			{Name: "points", Type: query.Number, Filter: true, Sort: true},
+			{Name: "title", Type: query.String, Sort: true},
+		},
+		DefaultSort:  "due_date",
+		DefaultLimit: 50,
+		MaxLimit:     100,
+	}
+
+	pantryItemListSpec = query.Spec{
+		Fields: []query.Field{
+			{Name: "category_id", Type: query.ObjectID, Filter: true},
+			{Name: "added_by", Type: query.ObjectID, Filter: true},
+			{Name: "location_id", Path: "lots.location_id", Type: query.ObjectID, Filter: true},
+			{Name: "owner_id", Path: "owner_ids", Type: query.ObjectID, Filter: true},
+			{Name: "ownership", Type: query.String, Filter: true, Values: []string{
+				string(models.OwnershipShared), string(models.OwnershipPersonal), string(models.OwnershipSplit),
+			}},
+			{Name: "expiration_date", Type: query.Time, Filter: true, Sort: true},
+			{Name: "created_at", Type: query.Time, Filter: true, Sort: true},
+			{Name: "quantity", Type: query.Number, Filter: true, Sort: true},
+			{Name: "name", Type: query.String, Sort: true},
+		},
+		DefaultSort:  "name",
+		DefaultLimit: 50,
+		MaxLimit:     100,
+	}
+
+	pantryHistoryListSpec = query.Spec{
+		Fields: []query.Field{
+			{Name: "item_id", Type: query.ObjectID, Filter: true},
+			{Name: "lot_id", Type: query.ObjectID, Filter: true},
+			{Name: "location_id", Type: query.ObjectID, Filter: true},
+			{Name: "user_id", Type: query.ObjectID, Filter: true},
+			{Name: "action", Type: query.String, Filter: true, Values: []string{
+				string(models.ActionTypeAdd), string(models.ActionTypeUpdate), string(models.ActionTypeUse), string(models.ActionTypeRemove),
+				string(models.ActionTypeMove), string(models.ActionTypeDiscard),
+			}},
+			{Name: "reason", Type: query.String, Filter: true, Values: []string{
+				string(models.DiscardReasonExpired), string(models.DiscardReasonSpoiled), string(models.DiscardReasonOther),
+			}},
+			{Name: "created_at", Type: query.Time, Filter: true, Sort: true},
+		},
+		DefaultSort:  "-created_at",
+		DefaultLimit: 50,
+		MaxLimit:     100,
+	}
+
+	shoppingCartListSpec = query.Spec{
+		Fields: []query.Field{
+			{Name: "user_id", Type: query.ObjectID, Filter: true},
+			{Name: "category", Type: query.String, Filter: true, Sort: true},
+			{Name: "added_at", Type: query.Time, Filter: true, Sort: true},
+			{Name: "item_name", Type: query.String, Sort: true},
+		},
+		DefaultSort:  "-added_at",
+		DefaultLimit: 50,
+		MaxLimit:     100,
+	}
+
+	shoppingCartActivityListSpec = query.Spec{
+		Fields: []query.Field{
+			{Name: "action", Type: query.String, Filter: true, Values: []string{
+				string(models.CartActivityTypeAdd), string(models.CartActivityTypeUpdate), string(models.CartActivityTypeDelete),
+			}},
+			{Name: "user_id", Type: query.ObjectID, Filter: true},
+			{Name: "item_id", Type: query.ObjectID, Filter: true},
+			{Name: "created_at", Type: query.Time, Filter: true, Sort: true},
+		},
+		DefaultSort:  "-created_at",
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	}
+
+	notificationListSpec = query.Spec{
+		Fields: []query.Field{
+			{Name: "type", Type: query.String, Filter: true, Values: []string{
+				string(models.NotificationTypeChoreComment), string(models.NotificationTypeChoreMention),
+				string(models.NotificationTypeChoreDueSoon), string(models.NotificationTypeChoreOverdue),
+				string(models.NotificationTypeChoreDigest), string(models.NotificationTypeLowStock),
+				string(models.NotificationTypeExpiringSoon), string(models.NotificationTypeExpired),
+				string(models.NotificationTypeOutOfStock), string(models.NotificationTypeRunningOutSoon),
+				string(models.NotificationTypePantryItemUsed),
+				string(models.NotificationTypePantryUseRequested), string(models.NotificationTypePantryUseAllowed),
+				string(models.NotificationTypeCartItemAdded),
+				string(models.NotificationTypeCartItemUpdated), string(models.NotificationTypeCartItemRemoved),
+			}},
+			{Name: "domain", Type: query.String, Filter: true, Values: []string{
+				string(models.NotificationDomainChore), string(models.NotificationDomainPantry), string(models.NotificationDomainCart),
+			}},
+			{Name: "created_at", Type: query.Time, Filter: true, Sort: true},
+		},
+		DefaultSort:  "-created_at",
+		DefaultLimit: 50,
+		MaxLimit:     200,
+	}
+
+	webhookDeliveryListSpec = query.Spec{
+		Fields: []query.Field{
+			{Name: "status", Type: query.String, Filter: true, Values: []string{
+				string(models.WebhookDeliveryPending), string(models.WebhookDeliveryDelivering),
+				string(models.WebhookDeliverySucceeded), string(models.WebhookDeliveryDead),
+			}},
+			{Name: "event_type", Type: query.String, Filter: true},
+			{Name: "created_at", Type: query.Time, Filter: true, Sort: true},
+		},
+		DefaultSort:  "-created_at",
+		DefaultLimit: 50,
+		MaxLimit:     100,
+	}
+)
+
+// parseListQuery validates the filter, sort and pagination parameters of a list request,
+// answering with the invalid parameters if there are any
+func parseListQuery(w http.ResponseWriter, r *http.Request, spec query.Spec) (*query.Query, bool) {
+	q, err := query.Parse(r.URL.Query(), spec)
+	if err == nil {
+		return q, true
+	}
+
+	var queryErr *query.Error
+	if !errors.As(err, &queryErr) {
+		http.Error(w, "Invalid query parameters", http.StatusBadRequest)
+		return nil, false
+	}
+
+	response := middleware.ValidationResponse{
+		Status:  "error",
+		Message: "Invalid query parameters",
+		Errors:  make([]middleware.ValidationError, 0, len(queryErr.Errors)),
+	}
+	for _, fieldErr := range queryErr.Errors {
+		response.Errors = append(response.Errors, middleware.ValidationError{
+			Field:   fieldErr.Field,
+			Message: fieldErr.Message,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(response)
+	return nil, false
+}
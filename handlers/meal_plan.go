@@ -0,0 +1,329 @@
+// handlers/meal_plan.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// errMealAlreadyCooked is returned when a planned meal is cooked twice
+var errMealAlreadyCooked = errors.New("meal already cooked")
+
+// AddMealPlanEntryRequest defines the request structure for planning a recipe for a meal
+type AddMealPlanEntryRequest struct {
+	GroupName string `json:"group_name" validate:"required"`
+	RecipeID  string `json:"recipe_id" validate:"required"`
+	Date      string `json:"date" validate:"required"` // YYYY-MM-DD
+	Meal      string `json:"meal" validate:"required"`
+	Servings  int    `json:"servings,omitempty"` // Left out, the recipe's own servings
+}
+
+// MealPlanWeekRequest defines the request structure for acting on a week of a group's meal
+// plan
+type MealPlanWeekRequest struct {
+	GroupName string `json:"group_name" validate:"required"`
+	Week      string `json:"week,omitempty"` // Any day of the week, YYYY-MM-DD; left out, the current week
+}
+
+// mealPlanWeek returns the Monday starting the week containing day, a YYYY-MM-DD date in the
+// group's time zone, or the current week if day is empty
+func mealPlanWeek(group *models.Group, day string) (time.Time, error) {
+	loc, err := models.LoadGroupLocation(group.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	t := time.Now()
+	if day != "" {
+		if t, err = time.ParseInLocation(models.MealPlanDateLayout, day, loc); err != nil {
+			return time.Time{}, errors.New("invalid week. Use YYYY-MM-DD")
+		}
+	}
+	return models.BucketStart(models.AnalyticsBucketWeek, t, loc), nil
+}
+
+// loadMealPlanWeek loads the entries of a week of a group's meal plan and their recipes
+func loadMealPlanWeek(ctx context.Context, groupID primitive.ObjectID, start time.Time) ([]models.MealPlanEntry, map[primitive.ObjectID]models.Recipe, error) {
+	dates := models.WeekDates(start)
+	cursor, err := config.DB.Collection("meal_plan").Find(ctx,
+		bson.M{"group_id": groupID, "date": bson.M{"$in": dates}},
+		options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := []models.MealPlanEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, nil, err
+	}
+
+	recipeIDs := make([]primitive.ObjectID, 0, len(entries))
+	for _, entry := range entries {
+		recipeIDs = append(recipeIDs, entry.RecipeID)
+	}
+	recipes := make(map[primitive.ObjectID]models.Recipe)
+	if len(recipeIDs) == 0 {
+		return entries, recipes, nil
+	}
+
+	cursor, err = config.DB.Collection("recipes").Find(ctx, bson.M{"_id": bson.M{"$in": recipeIDs}, "group_id": groupID})
+	if err != nil {
+		return nil, nil, err
+	}
+	var found []models.Recipe
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, nil, err
+	}
+	for _, recipe := range found {
+		recipes[recipe.ID] = recipe
+	}
+	return entries, recipes, nil
+}
+
+// checkMealPlanCoverage works out how much of what the uncooked meals of a week need the
+// pantry holds for the user
+func checkMealPlanCoverage(ctx context.Context, user *models.User, start time.Time) ([]models.IngredientCoverage, error) {
+	entries, recipes, err := loadMealPlanWeek(ctx, user.GroupID, start)
+	if err != nil {
+		return nil, err
+	}
+	items, err := usablePantryItems(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return models.CheckCoverage(models.PlannedIngredients(entries, recipes), items), nil
+}
+
+// GetMealPlanHandler returns a week of a group's meal plan
+func GetMealPlanHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupName := r.URL.Query().Get("group_name")
+	if groupName == "" {
+		http.Error(w, "group_name parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	_, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	start, err := mealPlanWeek(&group, r.URL.Query().Get("week"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, _, err := loadMealPlanWeek(context.Background(), group.ID, start)
+	if err != nil {
+		http.Error(w, "Failed to fetch meal plan", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"week_start": start.Format(models.MealPlanDateLayout),
+		"days":       models.WeekDates(start),
+		"entries":    entries,
+	})
+}
+
+// AddMealPlanEntryHandler plans one of the group's recipes for a meal on a day
+func AddMealPlanEntryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request AddMealPlanEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := time.Parse(models.MealPlanDateLayout, request.Date); err != nil {
+		http.Error(w, "Invalid date. Use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	meal := models.MealType(request.Meal)
+	if !models.IsValidMealType(meal) {
+		http.Error(w, "Invalid meal. Must be one of: breakfast, lunch, dinner, snack", http.StatusBadRequest)
+		return
+	}
+	if request.Servings < 0 {
+		http.Error(w, "servings must be a positive number", http.StatusBadRequest)
+		return
+	}
+	recipeID, err := primitive.ObjectIDFromHex(request.RecipeID)
+	if err != nil {
+		http.Error(w, "Invalid recipe ID format", http.StatusBadRequest)
+		return
+	}
+
+	user, group, ok := findGroupForMember(w, r, request.GroupName)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	recipe, err := findRecipe(ctx, group.ID, recipeID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Recipe not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch recipe", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	entry := models.CreateMealPlanEntry(recipe, request.Date, meal, request.Servings, user.ID)
+	result, err := config.DB.Collection("meal_plan").InsertOne(ctx, entry)
+	if err != nil {
+		log.Printf("Failed to add meal plan entry: %v", err)
+		http.Error(w, "Failed to add meal plan entry", http.StatusInternalServerError)
+		return
+	}
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// DeleteMealPlanEntryHandler removes a meal from the plan
+func DeleteMealPlanEntryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	entry, ok := loadMealPlanEntryForMember(w, r, r.URL.Query().Get("entry_id"))
+	if !ok {
+		return
+	}
+
+	if _, err := config.DB.Collection("meal_plan").DeleteOne(context.Background(), bson.M{"_id": entry.ID}); err != nil {
+		log.Printf("Failed to delete meal plan entry: %v", err)
+		http.Error(w, "Failed to delete meal plan entry", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Meal plan entry deleted successfully"})
+}
+
+// GetMealPlanCoverageHandler checks how much of what the uncooked meals of a week need the
+// pantry holds for the user, and lists the missing ingredients
+func GetMealPlanCoverageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupName := r.URL.Query().Get("group_name")
+	if groupName == "" {
+		http.Error(w, "group_name parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	user, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	start, err := mealPlanWeek(&group, r.URL.Query().Get("week"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	coverage, err := checkMealPlanCoverage(context.Background(), &user, start)
+	if err != nil {
+		log.Printf("Failed to check meal plan coverage: %v", err)
+		http.Error(w, "Failed to check meal plan coverage", http.StatusInternalServerError)
+		return
+	}
+	missing := models.MissingIngredients(coverage)
+	if missing == nil {
+		missing = []models.RecipeIngredient{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"week_start":  start.Format(models.MealPlanDateLayout),
+		"covered":     len(missing) == 0,
+		"ingredients": coverage,
+		"missing":     missing,
+	})
+}
+
+// AddMealPlanToCartHandler adds the ingredients the pantry is missing for the uncooked meals
+// of a week to the user's shopping cart
+func AddMealPlanToCartHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request MealPlanWeekRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, group, ok := findGroupForMember(w, r, request.GroupName)
+	if !ok {
+		return
+	}
+
+	start, err := mealPlanWeek(&group, request.Week)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	coverage, err := checkMealPlanCoverage(ctx, &user, start)
+	if err != nil {
+		log.Printf("Failed to check meal plan coverage: %v", err)
+		http.Error(w, "Failed to check meal plan coverage", http.StatusInternalServerError)
+		return
+	}
+
+	added := []models.ShoppingCartItem{}
+	for _, ingredient := range models.MissingIngredients(coverage) {
+		cartItem, err := addToShoppingCart(ctx, &user, ingredient.Name, ingredient.Quantity, ingredient.Unit, "")
+		if err != nil {
+			var unitErr *cartUnitError
+			if errors.As(err, &unitErr) {
+				http.Error(w, "Cannot add to "+ingredient.Name+": "+unitErr.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Printf("Failed to add shopping cart item: %v", err)
+			http.Error(w, "Failed to add item to shopping cart", http.StatusInternalServerError)
+			return
+		}
+		added = append(added, cartItem)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ShoppingCartResponse{
+		Status:  "success",
+		Message: "Missing ingredients added to shopping cart",
+		Data:    added,
+	})
+}
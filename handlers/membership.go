@@ -0,0 +1,223 @@
+// handlers/membership.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/middleware"
+	"cribb-backend/models"
+	"errors"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// currentUserID returns the authenticated user's ID, writing an error response on failure
+func currentUserID(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	userClaims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return primitive.NilObjectID, false
+	}
+
+	userID, err := primitive.ObjectIDFromHex(userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+// findGroupForMember loads the user from the request context and the named group,
+// writing an error response and returning false if the user is not a member
+func findGroupForMember(w http.ResponseWriter, r *http.Request, groupName string) (models.User, models.Group, bool) {
+	var user models.User
+	var group models.Group
+
+	userClaims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return user, group, false
+	}
+
+	userID, err := primitive.ObjectIDFromHex(userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return user, group, false
+	}
+
+	err = config.DB.Collection("users").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		}
+		return user, group, false
+	}
+
+	err = config.DB.Collection("groups").FindOne(context.Background(), bson.M{"name": groupName}).Decode(&group)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Group not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch group", http.StatusInternalServerError)
+		}
+		return user, group, false
+	}
+
+	if user.GroupID != group.ID {
+		http.Error(w, "User is not a member of this group", http.StatusForbidden)
+		return user, group, false
+	}
+
+	return user, group, true
+}
+
+// verifyGroupMember loads the user and checks that they belong to the group,
+// writing an error response if not
+func verifyGroupMember(w http.ResponseWriter, userID, groupID primitive.ObjectID) (models.User, bool) {
+	var user models.User
+	err := config.DB.Collection("users").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		}
+		return user, false
+	}
+
+	if user.GroupID != groupID {
+		http.Error(w, "User is not a member of this group", http.StatusForbidden)
+		return user, false
+	}
+	return user, true
+}
+
+// loadPantryItemForMember loads a pantry item and the user, checking the user is a member of
+// the item's group
+func loadPantryItemForMember(w http.ResponseWriter, r *http.Request, itemIDStr string) (models.PantryItem, models.User, bool) {
+	var pantryItem models.PantryItem
+
+	itemID, err := primitive.ObjectIDFromHex(itemIDStr)
+	if err != nil {
+		http.Error(w, "Invalid item ID format", http.StatusBadRequest)
+		return pantryItem, models.User{}, false
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return pantryItem, models.User{}, false
+	}
+
+	err = config.DB.Collection("pantry_items").FindOne(context.Background(), bson.M{"_id": itemID}).Decode(&pantryItem)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Pantry item not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch pantry item", http.StatusInternalServerError)
+		}
+		return pantryItem, models.User{}, false
+	}
+
+	user, ok := verifyGroupMember(w, userID, pantryItem.GroupID)
+	return pantryItem, user, ok
+}
+
+// loadStorageLocationForEdit loads a storage location and checks the user may change it: a
+// member of its group, and its owner if it is personal
+func loadStorageLocationForEdit(w http.ResponseWriter, r *http.Request, locationIDStr string) (*models.StorageLocation, bool) {
+	locationID, err := primitive.ObjectIDFromHex(locationIDStr)
+	if err != nil {
+		http.Error(w, "Invalid location ID format", http.StatusBadRequest)
+		return nil, false
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	var location models.StorageLocation
+	err = config.DB.Collection("storage_locations").FindOne(context.Background(), bson.M{"_id": locationID}).Decode(&location)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Storage location not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch storage location", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	if _, ok := verifyGroupMember(w, userID, location.GroupID); !ok {
+		return nil, false
+	}
+	if location.OwnerID != nil && *location.OwnerID != userID {
+		http.Error(w, "Only the owner can change a personal storage location", http.StatusForbidden)
+		return nil, false
+	}
+	return &location, true
+}
+
+// loadRecipeForMember loads a recipe and the user, checking the user is a member of the
+// recipe's group
+func loadRecipeForMember(w http.ResponseWriter, r *http.Request, recipeIDStr string) (*models.Recipe, models.User, bool) {
+	recipeID, err := primitive.ObjectIDFromHex(recipeIDStr)
+	if err != nil {
+		http.Error(w, "Invalid recipe ID format", http.StatusBadRequest)
+		return nil, models.User{}, false
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return nil, models.User{}, false
+	}
+
+	var recipe models.Recipe
+	err = config.DB.Collection("recipes").FindOne(context.Background(), bson.M{"_id": recipeID}).Decode(&recipe)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Recipe not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch recipe", http.StatusInternalServerError)
+		}
+		return nil, models.User{}, false
+	}
+
+	user, ok := verifyGroupMember(w, userID, recipe.GroupID)
+	return &recipe, user, ok
+}
+
+// loadMealPlanEntryForMember loads a meal plan entry, checking the user is a member of its
+// group
+func loadMealPlanEntryForMember(w http.ResponseWriter, r *http.Request, entryIDStr string) (*models.MealPlanEntry, bool) {
+	entryID, err := primitive.ObjectIDFromHex(entryIDStr)
+	if err != nil {
+		http.Error(w, "Invalid entry ID format", http.StatusBadRequest)
+		return nil, false
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	var entry models.MealPlanEntry
+	err = config.DB.Collection("meal_plan").FindOne(context.Background(), bson.M{"_id": entryID}).Decode(&entry)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Meal plan entry not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch meal plan entry", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	if _, ok := verifyGroupMember(w, userID, entry.GroupID); !ok {
+		return nil, false
+	}
+	return &entry, true
+}
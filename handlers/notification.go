@@ -0,0 +1,469 @@
+// handlers/notification.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"cribb-backend/query"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MarkAllNotificationsReadRequest defines the request structure for marking a group's notifications read
+type MarkAllNotificationsReadRequest struct {
+	GroupName string `json:"group_name" validate:"required"`
+}
+
+// NotificationView is a notification with the current user's read state
+type NotificationView struct {
+	models.Notification
+	IsRead bool `json:"is_read"`
+}
+
+// NotificationsResponse is a page of notifications and the user's unread count
+type NotificationsResponse struct {
+	query.Page
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// UnreadNotificationsResponse holds a user's unread counts, in total and per domain
+type UnreadNotificationsResponse struct {
+	UnreadCount int64                               `json:"unread_count"`
+	ByDomain    map[models.NotificationDomain]int64 `json:"by_domain"`
+}
+
+// unreadNotificationsFilter returns the filter for the notifications of a group the user has
+// not read. Receipts are only kept for notifications newer than the read-all watermark, so
+// the list of IDs to leave out stays short.
+func unreadNotificationsFilter(ctx context.Context, groupID primitive.ObjectID, user *models.User) (bson.M, error) {
+	receiptFilter := bson.M{"user_id": user.ID}
+	if user.Notifications.ReadAllAt != nil {
+		receiptFilter["notification_created_at"] = bson.M{"$gt": *user.Notifications.ReadAllAt}
+	}
+	readIDs, err := config.DB.Collection("notification_receipts").Distinct(ctx, "notification_id", receiptFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := models.UnreadNotifications(groupID, user)
+	if len(readIDs) > 0 {
+		filter["_id"] = bson.M{"$nin": readIDs}
+	}
+	return filter, nil
+}
+
+// notificationViews adds the user's read state to a page of notifications
+func notificationViews(ctx context.Context, user *models.User, notifications []models.Notification) ([]NotificationView, error) {
+	ids := make([]primitive.ObjectID, 0, len(notifications))
+	for _, notification := range notifications {
+		ids = append(ids, notification.ID)
+	}
+
+	receipts := make(map[primitive.ObjectID]bool)
+	if len(ids) > 0 {
+		readIDs, err := config.DB.Collection("notification_receipts").Distinct(ctx, "notification_id", bson.M{
+			"user_id":         user.ID,
+			"notification_id": bson.M{"$in": ids},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range readIDs {
+			if oid, ok := id.(primitive.ObjectID); ok {
+				receipts[oid] = true
+			}
+		}
+	}
+
+	views := make([]NotificationView, 0, len(notifications))
+	for _, notification := range notifications {
+		views = append(views, NotificationView{
+			Notification: notification,
+			IsRead:       notification.IsReadBy(user.ID, user.Notifications, receipts[notification.ID]),
+		})
+	}
+	return views, nil
+}
+
+// markNotificationsRead records receipts for the notifications the user has not read yet
+func markNotificationsRead(ctx context.Context, user *models.User, notifications []models.Notification) (int, error) {
+	now := time.Now()
+	marked := 0
+	for i := range notifications {
+		notification := &notifications[i]
+		if notification.IsReadBy(user.ID, user.Notifications, false) {
+			continue
+		}
+
+		receipt := models.NewNotificationReceipt(notification, user.ID, now)
+		result, err := config.DB.Collection("notification_receipts").UpdateOne(ctx,
+			bson.M{"notification_id": notification.ID, "user_id": user.ID},
+			bson.M{"$setOnInsert": receipt},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return marked, err
+		}
+		if result.UpsertedCount > 0 {
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+// markLinkedNotificationsRead marks the notifications matching filter as read, for screens
+// that show the underlying records rather than the notification stream
+func markLinkedNotificationsRead(ctx context.Context, user *models.User, filter bson.M) error {
+	filter["group_id"] = user.GroupID
+	cursor, err := config.DB.Collection("notifications").Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	var notifications []models.Notification
+	if err = cursor.All(ctx, &notifications); err != nil {
+		return err
+	}
+	_, err = markNotificationsRead(ctx, user, notifications)
+	return err
+}
+
+// GetNotificationsHandler returns a page of the group's notifications as seen by the current
+// user, newest first, leaving out the types they muted. Notifications can be filtered by type,
+// domain and created_at, and unread=true lists only those not read yet.
+func GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupName := r.URL.Query().Get("group_name")
+	if groupName == "" {
+		http.Error(w, "group_name parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	q, ok := parseListQuery(w, r, notificationListSpec)
+	if !ok {
+		return
+	}
+
+	user, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	unreadFilter, err := unreadNotificationsFilter(ctx, group.ID, &user)
+	if err != nil {
+		log.Printf("Failed to load notification receipts: %v", err)
+		http.Error(w, "Failed to fetch notifications", http.StatusInternalServerError)
+		return
+	}
+
+	base := models.VisibleNotifications(group.ID, &user)
+	if r.URL.Query().Get("unread") == "true" {
+		base = unreadFilter
+	}
+
+	cursor, err := config.DB.Collection("notifications").Find(ctx, q.Filter(base), q.FindOptions())
+	if err != nil {
+		http.Error(w, "Failed to fetch notifications", http.StatusInternalServerError)
+		return
+	}
+	var notifications []models.Notification
+	if err = cursor.All(ctx, &notifications); err != nil {
+		http.Error(w, "Failed to decode notifications", http.StatusInternalServerError)
+		return
+	}
+
+	notifications, nextCursor, err := query.Paginate(notifications, q)
+	if err != nil {
+		log.Printf("Failed to build notifications cursor: %v", err)
+		http.Error(w, "Failed to paginate notifications", http.StatusInternalServerError)
+		return
+	}
+
+	views, err := notificationViews(ctx, &user, notifications)
+	if err != nil {
+		log.Printf("Failed to load notification receipts: %v", err)
+		http.Error(w, "Failed to fetch notifications", http.StatusInternalServerError)
+		return
+	}
+
+	unreadCount, err := config.DB.Collection("notifications").CountDocuments(ctx, unreadFilter)
+	if err != nil {
+		log.Printf("Failed to count unread notifications: %v", err)
+		http.Error(w, "Failed to count unread notifications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(NotificationsResponse{
+		Page:        query.NewPage(views, nextCursor, q.Limit),
+		UnreadCount: unreadCount,
+	})
+}
+
+// GetUnreadNotificationCountHandler returns how many notifications the current user has not read
+func GetUnreadNotificationCountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupName := r.URL.Query().Get("group_name")
+	if groupName == "" {
+		http.Error(w, "group_name parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	user, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	filter, err := unreadNotificationsFilter(ctx, group.ID, &user)
+	if err != nil {
+		log.Printf("Failed to load notification receipts: %v", err)
+		http.Error(w, "Failed to count unread notifications", http.StatusInternalServerError)
+		return
+	}
+
+	cursor, err := config.DB.Collection("notifications").Aggregate(ctx, mongo.Pipeline{
+		bson.D{{Key: "$match", Value: filter}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": "$domain", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		log.Printf("Failed to count unread notifications: %v", err)
+		http.Error(w, "Failed to count unread notifications", http.StatusInternalServerError)
+		return
+	}
+	var counts []struct {
+		Domain models.NotificationDomain `bson:"_id"`
+		Count  int64                     `bson:"count"`
+	}
+	if err = cursor.All(ctx, &counts); err != nil {
+		http.Error(w, "Failed to decode unread counts", http.StatusInternalServerError)
+		return
+	}
+
+	response := UnreadNotificationsResponse{ByDomain: make(map[models.NotificationDomain]int64)}
+	for _, count := range counts {
+		response.ByDomain[count.Domain] = count.Count
+		response.UnreadCount += count.Count
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// MarkNotificationsReadHandler marks one notification (notification_id) or several
+// (notification_ids) as read by the current user
+func MarkNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		NotificationID  string   `json:"notification_id"`
+		NotificationIDs []string `json:"notification_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.NotificationID != "" {
+		request.NotificationIDs = append(request.NotificationIDs, request.NotificationID)
+	}
+	if len(request.NotificationIDs) == 0 {
+		http.Error(w, "Notification ID is required", http.StatusBadRequest)
+		return
+	}
+	if len(request.NotificationIDs) > notificationListSpec.MaxLimit {
+		http.Error(w, fmt.Sprintf("At most %d notifications can be marked at once", notificationListSpec.MaxLimit), http.StatusBadRequest)
+		return
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(request.NotificationIDs))
+	for _, hex := range request.NotificationIDs {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			http.Error(w, "Invalid notification ID format", http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	var user models.User
+	err := config.DB.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Muted notifications can still be marked read
+	filter := models.AccessibleNotifications(user.GroupID, &user)
+	filter["_id"] = bson.M{"$in": ids}
+	cursor, err := config.DB.Collection("notifications").Find(ctx, filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch notifications", http.StatusInternalServerError)
+		return
+	}
+	var notifications []models.Notification
+	if err = cursor.All(ctx, &notifications); err != nil {
+		http.Error(w, "Failed to decode notifications", http.StatusInternalServerError)
+		return
+	}
+	if len(notifications) == 0 {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+
+	marked, err := markNotificationsRead(ctx, &user, notifications)
+	if err != nil {
+		log.Printf("Failed to mark notifications as read: %v", err)
+		http.Error(w, "Failed to mark notification as read", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Notification marked as read",
+		"marked":  marked,
+	})
+}
+
+// MarkAllNotificationsReadHandler marks every notification of the group created so far as read
+// by moving the user's watermark, then drops the receipts it makes redundant
+func MarkAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request MarkAllNotificationsReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, _, ok := findGroupForMember(w, r, request.GroupName)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	_, err := config.DB.Collection("users").UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"notifications.read_all_at": now}},
+	)
+	if err != nil {
+		log.Printf("Failed to mark all notifications as read: %v", err)
+		http.Error(w, "Failed to mark notifications as read", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = config.DB.Collection("notification_receipts").DeleteMany(ctx, bson.M{
+		"user_id":                 user.ID,
+		"notification_created_at": bson.M{"$lte": now},
+	})
+	if err != nil {
+		log.Printf("Failed to clean up notification receipts: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "All notifications marked as read",
+		"read_all_at": now,
+	})
+}
+
+// NotificationSettingsHandler returns the notification types the current user muted on GET
+// and replaces them on POST
+func NotificationSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	var user models.User
+	err := config.DB.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	settings := user.Notifications
+	if r.Method == http.MethodPost {
+		var request struct {
+			Muted []models.NotificationType `json:"muted"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		muted := make([]models.NotificationType, 0, len(request.Muted))
+		seen := make(map[models.NotificationType]bool)
+		for _, notificationType := range request.Muted {
+			if !notificationType.IsValid() {
+				http.Error(w, fmt.Sprintf("Unknown notification type %q", notificationType), http.StatusBadRequest)
+				return
+			}
+			if !seen[notificationType] {
+				seen[notificationType] = true
+				muted = append(muted, notificationType)
+			}
+		}
+
+		_, err = config.DB.Collection("users").UpdateOne(ctx,
+			bson.M{"_id": userID},
+			bson.M{"$set": bson.M{"notifications.muted": muted, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			log.Printf("Failed to update notification settings: %v", err)
+			http.Error(w, "Failed to update notification settings", http.StatusInternalServerError)
+			return
+		}
+		settings.Muted = muted
+	}
+	if settings.Muted == nil {
+		settings.Muted = []models.NotificationType{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
@@ -3,12 +3,18 @@ package handlers
 import (
 	"context"
 	"cribb-backend/config"
+	"cribb-backend/jobs"
 	"cribb-backend/middleware"
 	"cribb-backend/models"
+	"cribb-backend/query"
+	"cribb-backend/realtime"
+	"cribb-backend/units"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
@@ -18,14 +24,29 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// AddPantryItemRequest defines the request structure for adding a pantry item
+// AddPantryItemRequest defines the request structure for adding a pantry item. With a barcode,
+// the name, unit, category and expiration date default to the catalog product's.
 type AddPantryItemRequest struct {
-	Name           string  `json:"name" validate:"required"`
+	Name           string  `json:"name"`
 	Quantity       float64 `json:"quantity" validate:"required,min=0"`
-	Unit           string  `json:"unit" validate:"required"`
-	CategoryID     string  `json:"category_id" validate:"required"` // Now required, no fallbacks
+	Unit           string  `json:"unit"`
+	CategoryID     string  `json:"category_id"` // Required unless a barcode is given
 	ExpirationDate *string `json:"expiration_date,omitempty"`
 	GroupName      string  `json:"group_name" validate:"required"`
+	Barcode        string  `json:"barcode,omitempty"`
+	LocationID     string  `json:"location_id,omitempty"` // Left out, an existing item's lots stay together
+
+	// Optional ownership, shared by default. A personal item without owner_ids belongs to the
+	// member adding it, and stock only joins an existing item with the same owners.
+	Ownership string   `json:"ownership,omitempty"`
+	OwnerIDs  []string `json:"owner_ids,omitempty"`
+
+	// Optional stock levels; left out, the item keeps its own or falls back to its category's
+	LowStockThreshold *float64 `json:"low_stock_threshold,omitempty"`
+	ParLevel          *float64 `json:"par_level,omitempty"`
+
+	// Optional density in grams per millilitre, for converting between mass and volume
+	Density *float64 `json:"density,omitempty"`
 }
 
 // UpdatePantryItemRequest defines the request structure for updating a pantry item
@@ -36,21 +57,40 @@ type UpdatePantryItemRequest struct {
 	CategoryID     string  `json:"category_id" validate:"required"` // Now required, no fallbacks
 	ExpirationDate *string `json:"expiration_date,omitempty"`
 	GroupName      string  `json:"group_name" validate:"required"`
+
+	// Optional stock levels; left out, the item keeps its own or falls back to its category's
+	LowStockThreshold *float64 `json:"low_stock_threshold,omitempty"`
+	ParLevel          *float64 `json:"par_level,omitempty"`
+
+	// Optional density in grams per millilitre, for converting between mass and volume
+	Density *float64 `json:"density,omitempty"`
 }
 
-// UsePantryItemRequest defines the request structure for using a pantry item
+// UsePantryItemRequest defines the request structure for using a pantry item. The quantity is
+// in the item's own unit unless another unit is given.
 type UsePantryItemRequest struct {
 	ItemID   string  `json:"item_id" validate:"required"`
 	Quantity float64 `json:"quantity" validate:"required,min=0.1"`
+	Unit     string  `json:"unit,omitempty"`
+}
+
+// SetPantryStockLevelsRequest defines the request structure for setting an item's stock
+// levels. A value left out falls back to the category's default.
+type SetPantryStockLevelsRequest struct {
+	ItemID            string   `json:"item_id" validate:"required"`
+	LowStockThreshold *float64 `json:"low_stock_threshold"`
+	ParLevel          *float64 `json:"par_level"`
 }
 
 // PantryItemWithCategory represents a pantry item with resolved category information
 type PantryItemWithCategory struct {
 	models.PantryItem
-	CategoryInfo   CategoryInfo `json:"category_info"`
-	IsExpiringSoon bool         `json:"is_expiring_soon"`
-	IsExpired      bool         `json:"is_expired"`
-	AddedByName    string       `json:"added_by_name"`
+	CategoryInfo   CategoryInfo       `json:"category_info"`
+	StockLevels    models.StockLevels `json:"stock_levels"`
+	IsLowStock     bool               `json:"is_low_stock"`
+	IsExpiringSoon bool               `json:"is_expiring_soon"`
+	IsExpired      bool               `json:"is_expired"`
+	AddedByName    string             `json:"added_by_name"`
 }
 
 // CategoryInfo represents resolved category information
@@ -95,7 +135,62 @@ func validateCategoryID(categoryIDStr string, groupID primitive.ObjectID) (*mode
 	return &category, nil
 }
 
-// AddPantryItemHandler creates or updates a pantry item
+// setStockLevels applies the stock levels given in a request, keeping the item's current
+// values for those left out
+func setStockLevels(item *models.PantryItem, threshold, par *float64) {
+	if threshold != nil {
+		item.LowStockThreshold = threshold
+	}
+	if par != nil {
+		item.ParLevel = par
+	}
+}
+
+// setStockLevels fills in the item's resolved stock levels
+func (p *PantryItemWithCategory) setStockLevels(category *models.PantryCategory) {
+	p.StockLevels = models.ResolveStockLevels(&p.PantryItem, category)
+	p.IsLowStock = p.StockLevels.IsLowStock(p.Quantity)
+}
+
+// newPantryItemResponse builds the response for an item changed in place, looking up its
+// category for the category information and stock levels
+func newPantryItemResponse(pantryItem models.PantryItem) PantryItemWithCategory {
+	responseItem := PantryItemWithCategory{
+		PantryItem:     pantryItem,
+		IsExpiringSoon: pantryItem.IsExpiringSoon(3),
+		IsExpired:      pantryItem.IsExpired(),
+	}
+	var category models.PantryCategory
+	err := config.DB.Collection("pantry_categories").FindOne(context.Background(), bson.M{"_id": pantryItem.CategoryID}).Decode(&category)
+	if err == nil {
+		responseItem.CategoryInfo = CategoryInfo{
+			ID:   category.ID,
+			Name: category.Name,
+			Type: string(category.Type),
+		}
+		responseItem.setStockLevels(&category)
+	} else {
+		responseItem.setStockLevels(nil)
+	}
+	return responseItem
+}
+
+// createPantryNotifications saves notifications about a pantry change once the change is
+// committed. They are not critical, so a failure is only logged.
+func createPantryNotifications(notifications ...*models.Notification) {
+	for _, notification := range notifications {
+		if notification == nil {
+			continue
+		}
+		_, err := config.DB.Collection("notifications").InsertOne(context.Background(), notification)
+		if err != nil {
+			log.Printf("Failed to create %s notification: %v", notification.Type, err)
+		}
+	}
+}
+
+// AddPantryItemHandler creates a pantry item, or adds a new lot to an existing item with the
+// same name and category
 func AddPantryItemHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -115,15 +210,45 @@ func AddPantryItemHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Fill in what the request leaves out from the catalog product with the barcode
+	var product *models.Product
+	if request.Barcode != "" {
+		var err error
+		product, err = findProductByBarcode(context.Background(), request.Barcode)
+		if err != nil {
+			writeProductLookupError(w, err)
+			return
+		}
+		if request.Name == "" {
+			request.Name = product.Name
+		}
+		if request.Unit == "" {
+			request.Unit = product.DefaultUnit
+		}
+	}
+
 	// Validate required fields
-	if request.Name == "" || request.Quantity < 0 || request.Unit == "" || request.GroupName == "" || request.CategoryID == "" {
+	if request.Name == "" || request.Quantity < 0 || request.Unit == "" || request.GroupName == "" || (request.CategoryID == "" && product == nil) {
 		http.Error(w, "Name, quantity, unit, category_id, and group name are required", http.StatusBadRequest)
 		return
 	}
+	if err := models.ValidateStockLevels(request.LowStockThreshold, request.ParLevel); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	unit, err := units.Normalize(request.Unit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.Density != nil && *request.Density <= 0 {
+		http.Error(w, "density must be positive", http.StatusBadRequest)
+		return
+	}
 
 	// Find the group
 	var group models.Group
-	err := config.DB.Collection("groups").FindOne(
+	err = config.DB.Collection("groups").FindOne(
 		context.Background(),
 		bson.M{"name": request.GroupName},
 	).Decode(&group)
@@ -166,6 +291,17 @@ func AddPantryItemHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Default the category of a product added by barcode
+	if request.CategoryID == "" {
+		productCategory, err := resolveProductCategory(context.Background(), product, group.ID)
+		if err != nil {
+			log.Printf("Failed to resolve product category: %v", err)
+			http.Error(w, "Failed to resolve the product's category", http.StatusInternalServerError)
+			return
+		}
+		request.CategoryID = productCategory.ID.Hex()
+	}
+
 	// Validate category ID
 	category, err := validateCategoryID(request.CategoryID, group.ID)
 	if err != nil {
@@ -176,7 +312,7 @@ func AddPantryItemHandler(w http.ResponseWriter, r *http.Request) {
 	// Convert category ID to ObjectID
 	categoryID, _ := primitive.ObjectIDFromHex(request.CategoryID)
 
-	// Parse expiration date if provided
+	// Parse expiration date if provided; left out, it is estimated from the shelf-life rules
 	var expirationDate time.Time
 	if request.ExpirationDate != nil && *request.ExpirationDate != "" {
 		expirationDate, err = time.Parse(time.RFC3339, *request.ExpirationDate)
@@ -186,6 +322,35 @@ func AddPantryItemHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Find the storage location if one is given
+	var location *models.StorageLocation
+	if request.LocationID != "" {
+		location, err = parseStorageLocation(context.Background(), group.ID, request.LocationID)
+		if err != nil {
+			if errors.Is(err, models.ErrStorageLocationNotFound) {
+				http.Error(w, "Storage location not found", http.StatusBadRequest)
+			} else {
+				http.Error(w, "Failed to fetch storage location", http.StatusInternalServerError)
+			}
+			return
+		}
+		if !location.CanPlace(userID) {
+			http.Error(w, models.ErrNotLocationOwner.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	// Resolve who the item belongs to
+	ownership := models.OwnershipShared
+	if request.Ownership != "" {
+		ownership = models.OwnershipMode(request.Ownership)
+	}
+	ownerIDs, err := parseOwners(context.Background(), group.ID, userID, ownership, request.OwnerIDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Start a transaction
 	session, err := config.DB.Client().StartSession()
 	if err != nil {
@@ -197,35 +362,67 @@ func AddPantryItemHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Start transaction
 	var pantryItem models.PantryItem
-	var isNewItem bool = true
-	var oldQuantity float64 = 0
-
-	err = mongo.WithSession(context.Background(), session, func(sc mongo.SessionContext) error {
-		// Check if item already exists in this group with same name and category
-		existingItem := config.DB.Collection("pantry_items").FindOne(
-			sc,
-			bson.M{
-				"group_id":    group.ID,
-				"name":        bson.M{"$regex": primitive.Regex{Pattern: "^" + strings.TrimSpace(request.Name) + "$", Options: "i"}},
-				"category_id": categoryID,
+	var addedLot *models.PantryLot
+	var expiryNotice *models.Notification
+
+	// An item already in the group has the same name and category, or the same product, and
+	// the same owners
+	escapedName := regexp.QuoteMeta(strings.TrimSpace(request.Name))
+	existingFilter := bson.M{
+		"group_id":    group.ID,
+		"name":        bson.M{"$regex": primitive.Regex{Pattern: "^" + escapedName + "$", Options: "i"}},
+		"category_id": categoryID,
+	}
+	if product != nil {
+		existingFilter = bson.M{
+			"group_id": group.ID,
+			"$or": []bson.M{
+				{"product_id": product.ID},
+				{"name": existingFilter["name"], "category_id": categoryID},
 			},
-		)
+		}
+	}
+	for key, value := range models.OwnershipFilter(ownership, ownerIDs) {
+		existingFilter[key] = value
+	}
+
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		pantryItem, addedLot, expiryNotice = models.PantryItem{}, nil, nil
+
+		// Check if item already exists in this group
+		existingItem := config.DB.Collection("pantry_items").FindOne(sc, existingFilter)
 
 		if existingItem.Err() == nil {
 			// Item exists, update it
 			if err := existingItem.Decode(&pantryItem); err != nil {
-				return err
+				return nil, err
 			}
 
-			isNewItem = false
-			oldQuantity = pantryItem.Quantity
-
-			// Update the item
-			pantryItem.Quantity = request.Quantity
-			pantryItem.Unit = request.Unit
-			if !expirationDate.IsZero() {
-				pantryItem.ExpirationDate = expirationDate
+			// Add the new quantity as a lot of its own, converted into the item's unit, so
+			// each batch keeps its expiration date
+			if request.Density != nil {
+				pantryItem.Density = request.Density
 			}
+			quantity, err := pantryItem.ToItemUnit(request.Quantity, unit)
+			if err != nil {
+				return nil, err
+			}
+			pantryItem.Unit, _ = units.Normalize(pantryItem.Unit)
+			if product != nil && pantryItem.ProductID == nil {
+				pantryItem.ProductID = &product.ID
+				pantryItem.GTIN = product.GTIN
+			}
+			if quantity > 0 {
+				lot := models.NewPantryLot(quantity, expirationDate, userID)
+				lotLocation := location
+				if lotLocation == nil {
+					lotLocation = itemLocation(sc, &pantryItem)
+				}
+				placeNewLot(sc, &pantryItem, &lot, lotLocation)
+				pantryItem.AddLot(lot)
+				addedLot = &lot
+			}
+			setStockLevels(&pantryItem, request.LowStockThreshold, request.ParLevel)
 			pantryItem.UpdatedAt = time.Now()
 
 			_, err = config.DB.Collection("pantry_items").UpdateOne(
@@ -234,7 +431,7 @@ func AddPantryItemHandler(w http.ResponseWriter, r *http.Request) {
 				bson.M{"$set": pantryItem},
 			)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		} else if errors.Is(existingItem.Err(), mongo.ErrNoDocuments) {
 			// Item doesn't exist, create new one
@@ -242,69 +439,68 @@ func AddPantryItemHandler(w http.ResponseWriter, r *http.Request) {
 				group.ID,
 				request.Name,
 				request.Quantity,
-				request.Unit,
+				unit,
 				categoryID,
 				expirationDate,
 				userID,
 			)
+			setStockLevels(&pantryItem, request.LowStockThreshold, request.ParLevel)
+			pantryItem.Density = request.Density
+			if err := pantryItem.SetOwnership(ownership, ownerIDs); err != nil {
+				return nil, err
+			}
+			if product != nil {
+				pantryItem.ProductID = &product.ID
+				pantryItem.GTIN = product.GTIN
+			}
+			if len(pantryItem.Lots) > 0 {
+				placeNewLot(sc, &pantryItem, &pantryItem.Lots[0], location)
+				pantryItem.SyncLots()
+				lot := pantryItem.Lots[0]
+				addedLot = &lot
+			}
 
 			result, err := config.DB.Collection("pantry_items").InsertOne(sc, pantryItem)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			pantryItem.ID = result.InsertedID.(primitive.ObjectID)
 		} else {
 			// Some other error occurred
-			return existingItem.Err()
+			return nil, existingItem.Err()
 		}
 
-		// Check if we need to create expiration notification
-		if !expirationDate.IsZero() && pantryItem.IsExpiringSoon(3) {
-			notification := models.CreatePantryNotification(
-				group.ID,
-				pantryItem.ID,
-				pantryItem.Name,
-				models.NotificationTypeExpiringSoon,
-				"Item will expire in 3 days or less",
-			)
-			_, err = config.DB.Collection("pantry_notifications").InsertOne(sc, notification)
-			if err != nil {
-				log.Printf("Failed to create expiration notification: %v", err)
-				// Continue anyway, as this is not critical
-			}
+		// Check if we need to create expiration notification for the new lot
+		if addedLot != nil && addedLot.IsExpiringSoon(3) {
+			expiryNotice = models.CreateLotExpiryNotification(&pantryItem, addedLot, models.NotificationTypeExpiringSoon)
 		}
 
-		return nil
+		return nil, nil
 	})
 
 	if err != nil {
+		var conversionErr *units.ConversionError
+		if errors.As(err, &conversionErr) {
+			http.Error(w, "Cannot add to "+pantryItem.Name+": "+conversionErr.Error(), http.StatusBadRequest)
+			return
+		}
 		log.Printf("Transaction failed: %v", err)
 		http.Error(w, "Failed to add/update pantry item", http.StatusInternalServerError)
 		return
 	}
+	createPantryNotifications(expiryNotice)
 
-	// Create history record for adding a new item or updating an existing one
-	if isNewItem {
+	// Create history record for the lot added
+	if addedLot != nil {
 		UpdatePantryHistoryForAdd(
 			group.ID,
 			pantryItem.ID,
 			pantryItem.Name,
 			userID,
 			user.Name,
-			request.Quantity,
+			addedLot.Quantity,
+			&addedLot.ID,
 		)
-	} else {
-		// If quantity changed, record it as an update
-		if oldQuantity != request.Quantity {
-			UpdatePantryHistoryForAdd(
-				group.ID,
-				pantryItem.ID,
-				pantryItem.Name,
-				userID,
-				user.Name,
-				request.Quantity-oldQuantity,
-			)
-		}
 	}
 
 	// Build response with category information
@@ -319,6 +515,9 @@ func AddPantryItemHandler(w http.ResponseWriter, r *http.Request) {
 		IsExpired:      pantryItem.IsExpired(),
 		AddedByName:    user.Name,
 	}
+	responseItem.setStockLevels(category)
+
+	publishGroupEvent(r, group.ID, realtime.EventPantryItemAdded, responseItem)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -364,6 +563,19 @@ func UpdatePantryItemHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Name, quantity, unit, category_id, and group name are required", http.StatusBadRequest)
 		return
 	}
+	if err := models.ValidateStockLevels(request.LowStockThreshold, request.ParLevel); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	unit, err := units.Normalize(request.Unit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.Density != nil && *request.Density <= 0 {
+		http.Error(w, "density must be positive", http.StatusBadRequest)
+		return
+	}
 
 	// Find the group
 	var group models.Group
@@ -441,9 +653,13 @@ func UpdatePantryItemHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Start transaction
 	var pantryItem models.PantryItem
-	var oldQuantity float64 = 0
+	var addedLot *models.PantryLot
+	var usedLots []models.LotUsage
+	var expiryNotice *models.Notification
+
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		pantryItem, addedLot, usedLots, expiryNotice = models.PantryItem{}, nil, nil, nil
 
-	err = mongo.WithSession(context.Background(), session, func(sc mongo.SessionContext) error {
 		// Find the existing item by ID
 		err := config.DB.Collection("pantry_items").FindOne(
 			sc,
@@ -452,26 +668,81 @@ func UpdatePantryItemHandler(w http.ResponseWriter, r *http.Request) {
 
 		if err != nil {
 			if errors.Is(err, mongo.ErrNoDocuments) {
-				return errors.New("pantry item not found")
+				return nil, errors.New("pantry item not found")
 			}
-			return err
+			return nil, err
 		}
 
 		// Verify the item belongs to the user's group
 		if pantryItem.GroupID != user.GroupID {
-			return errors.New("pantry item does not belong to user's group")
+			return nil, errors.New("pantry item does not belong to user's group")
+		}
+
+		// Changing someone else's item needs the same permission as using it
+		if !pantryItem.CanUse(userID) {
+			return nil, models.ErrUseNotPermitted
 		}
 
-		oldQuantity = pantryItem.Quantity
+		if request.Density != nil {
+			pantryItem.Density = request.Density
+		}
+
+		// A unit change converts the lots and stock levels into the new unit so the history
+		// and thresholds stay comparable; an incompatible unit is refused
+		if unit != pantryItem.Unit {
+			converted := pantryItem
+			converted.Name = request.Name
+			converted.Unit = unit
+
+			// Check the units convert before changing anything, as an item without lots or
+			// stock levels would otherwise take any unit
+			if _, err := converted.ToItemUnit(1, pantryItem.Unit); err != nil {
+				return nil, fmt.Errorf("cannot change the unit of %s: %w", pantryItem.Name, err)
+			}
+			err := pantryItem.ScaleLots(func(quantity float64) (float64, error) {
+				return converted.ToItemUnit(quantity, pantryItem.Unit)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("cannot change the unit of %s: %w", pantryItem.Name, err)
+			}
+			if pantryItem.LowStockThreshold != nil {
+				threshold, err := converted.ToItemUnit(*pantryItem.LowStockThreshold, pantryItem.Unit)
+				if err != nil {
+					return nil, fmt.Errorf("cannot change the unit of %s: %w", pantryItem.Name, err)
+				}
+				pantryItem.LowStockThreshold = &threshold
+			}
+			if pantryItem.ParLevel != nil {
+				par, err := converted.ToItemUnit(*pantryItem.ParLevel, pantryItem.Unit)
+				if err != nil {
+					return nil, fmt.Errorf("cannot change the unit of %s: %w", pantryItem.Name, err)
+				}
+				pantryItem.ParLevel = &par
+			}
+		}
 
 		// Update the item fields
 		pantryItem.Name = request.Name
-		pantryItem.Quantity = request.Quantity
-		pantryItem.Unit = request.Unit
+		pantryItem.Unit = unit
 		pantryItem.CategoryID = categoryID
-		if !expirationDate.IsZero() {
-			pantryItem.ExpirationDate = expirationDate
+
+		// A quantity change adds a lot or uses up the first-expiring lots. The expiration
+		// date goes on the new lot, estimated if none is given, or on the item if it is held
+		// in a single lot. A new lot joins the others in their storage location.
+		newLot := models.NewPantryLot(0, expirationDate, userID)
+		if request.Quantity > pantryItem.Quantity {
+			placeNewLot(sc, &pantryItem, &newLot, itemLocation(sc, &pantryItem))
+		}
+		addedLot, usedLots, err = pantryItem.SetQuantity(request.Quantity, newLot)
+		if err != nil {
+			return nil, err
 		}
+		if addedLot == nil && !expirationDate.IsZero() {
+			if err := pantryItem.SetExpirationDate(expirationDate); err != nil {
+				return nil, err
+			}
+		}
+		setStockLevels(&pantryItem, request.LowStockThreshold, request.ParLevel)
 		pantryItem.UpdatedAt = time.Now()
 
 		// Update the item in the database
@@ -481,43 +752,53 @@ func UpdatePantryItemHandler(w http.ResponseWriter, r *http.Request) {
 			bson.M{"$set": pantryItem},
 		)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		// Check if we need to create expiration notification
-		if !expirationDate.IsZero() && pantryItem.IsExpiringSoon(3) {
-			notification := models.CreatePantryNotification(
-				group.ID,
-				pantryItem.ID,
-				pantryItem.Name,
-				models.NotificationTypeExpiringSoon,
-				"Item will expire in 3 days or less",
-			)
-			_, err = config.DB.Collection("pantry_notifications").InsertOne(sc, notification)
-			if err != nil {
-				log.Printf("Failed to create expiration notification: %v", err)
-				// Continue anyway, as this is not critical
+		// Check if we need to create expiration notification for the lot with the new date
+		for _, lot := range pantryItem.Lots {
+			if expirationDate.IsZero() || !lot.ExpirationDate.Equal(expirationDate) || !lot.IsExpiringSoon(3) {
+				continue
 			}
+			expiryNotice = models.CreateLotExpiryNotification(&pantryItem, &lot, models.NotificationTypeExpiringSoon)
+			break
 		}
 
-		return nil
+		return nil, nil
 	})
 
 	if err != nil {
 		log.Printf("Transaction failed: %v", err)
+		if errors.Is(err, models.ErrUseNotPermitted) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	createPantryNotifications(expiryNotice)
 
-	// Create history record for updating the item
-	if oldQuantity != request.Quantity {
+	// Create history records for the lot added or the lots taken down
+	if addedLot != nil {
 		UpdatePantryHistoryForAdd(
 			group.ID,
 			pantryItem.ID,
 			pantryItem.Name,
 			userID,
 			user.Name,
-			request.Quantity-oldQuantity,
+			addedLot.Quantity,
+			&addedLot.ID,
+		)
+	}
+	for _, usage := range usedLots {
+		UpdatePantryHistoryForAdd(
+			group.ID,
+			pantryItem.ID,
+			pantryItem.Name,
+			userID,
+			user.Name,
+			-usage.Quantity,
+			&usage.LotID,
 		)
 	}
 
@@ -533,13 +814,18 @@ func UpdatePantryItemHandler(w http.ResponseWriter, r *http.Request) {
 		IsExpired:      pantryItem.IsExpired(),
 		AddedByName:    user.Name,
 	}
+	responseItem.setStockLevels(category)
+
+	publishGroupEvent(r, group.ID, realtime.EventPantryItemUpdated, responseItem)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(responseItem)
 }
 
-// GetPantryItemsHandler retrieves all pantry items for a group with resolved category information
+// GetPantryItemsHandler retrieves a page of a group's pantry items with resolved category information.
+// Items can be filtered by category_id, added_by, location_id, owner_id, ownership, quantity and
+// expiration_date or created_at ranges. An item matches a location if any of its lots is stored there.
 func GetPantryItemsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -555,7 +841,6 @@ func GetPantryItemsHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Get query parameters
 	groupName := r.URL.Query().Get("group_name")
-	categoryFilter := r.URL.Query().Get("category_id")
 
 	// Verify group name is provided
 	if groupName == "" {
@@ -563,6 +848,11 @@ func GetPantryItemsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	q, ok := parseListQuery(w, r, pantryItemListSpec)
+	if !ok {
+		return
+	}
+
 	// Find the group
 	var group models.Group
 	err := config.DB.Collection("groups").FindOne(
@@ -608,27 +898,11 @@ func GetPantryItemsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Build query filter
-	filter := bson.M{"group_id": group.ID}
-	if categoryFilter != "" {
-		categoryID, err := primitive.ObjectIDFromHex(categoryFilter)
-		if err != nil {
-			http.Error(w, "Invalid category ID format", http.StatusBadRequest)
-			return
-		}
-		filter["category_id"] = categoryID
-	}
-
 	// Find pantry items
-	opts := options.Find().SetSort(bson.D{
-		{Key: "category_id", Value: 1},
-		{Key: "name", Value: 1},
-	})
-
 	cursor, err := config.DB.Collection("pantry_items").Find(
 		context.Background(),
-		filter,
-		opts,
+		q.Filter(bson.M{"group_id": group.ID}),
+		q.FindOptions(),
 	)
 	if err != nil {
 		http.Error(w, "Failed to fetch pantry items", http.StatusInternalServerError)
@@ -642,6 +916,13 @@ func GetPantryItemsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	pantryItems, nextCursor, err := query.Paginate(pantryItems, q)
+	if err != nil {
+		log.Printf("Failed to build pantry items cursor: %v", err)
+		http.Error(w, "Failed to paginate pantry items", http.StatusInternalServerError)
+		return
+	}
+
 	// Build response with category information and user names
 	response := make([]PantryItemWithCategory, 0, len(pantryItems))
 	categoryCache := make(map[string]*models.PantryCategory)
@@ -677,6 +958,7 @@ func GetPantryItemsHandler(w http.ResponseWriter, r *http.Request) {
 				Type: string(category.Type),
 			}
 		}
+		extendedItem.setStockLevels(category)
 
 		// Get the name of the user who added the item
 		userIDStr := item.AddedBy.Hex()
@@ -698,7 +980,152 @@ func GetPantryItemsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(query.NewPage(response, nextCursor, q.Limit))
+}
+
+// pantryUse is what came of using some of a pantry item
+type pantryUse struct {
+	Item       models.PantryItem
+	Quantity   float64 // In the item's unit
+	Lots       []models.LotUsage
+	LowStock   bool
+	OutOfStock bool
+}
+
+// usePantryItem uses quantity of a pantry item, given in unit or the item's own unit if unit
+// is empty, within the caller's transaction. It takes the quantity from the item's
+// first-expiring lots and works out whether the item is now low or out of stock. The
+// notifications, history and events are left to recordPantryUse once the transaction commits.
+func usePantryItem(sc mongo.SessionContext, itemID primitive.ObjectID, user *models.User, quantity float64, unit string) (*pantryUse, error) {
+	// Find the pantry item
+	var pantryItem models.PantryItem
+	err := config.DB.Collection("pantry_items").FindOne(
+		sc,
+		bson.M{"_id": itemID},
+	).Decode(&pantryItem)
+
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("pantry item not found")
+		}
+		return nil, err
+	}
+
+	// Verify the item belongs to the user's group
+	if pantryItem.GroupID != user.GroupID {
+		return nil, errors.New("pantry item does not belong to user's group")
+	}
+
+	// Someone else's item needs their permission
+	if !pantryItem.CanUse(user.ID) {
+		return nil, models.ErrUseNotPermitted
+	}
+
+	// Convert the quantity used into the item's unit
+	usedQuantity, err := pantryItem.ToItemUnit(quantity, unit)
+	if err != nil {
+		return nil, fmt.Errorf("cannot use %s: %w", pantryItem.Name, err)
+	}
+
+	// Take the quantity from the first-expiring lots
+	usedLots, err := pantryItem.ConsumeLots(usedQuantity)
+	if err != nil {
+		return nil, err
+	}
+	newQuantity := pantryItem.Quantity
+
+	_, err = config.DB.Collection("pantry_items").UpdateOne(
+		sc,
+		bson.M{"_id": pantryItem.ID},
+		bson.M{"$set": bson.M{
+			"quantity":             newQuantity,
+			"lots":                 pantryItem.Lots,
+			"expiration_date":      pantryItem.ExpirationDate,
+			"expiration_estimated": pantryItem.ExpirationEstimated,
+			"location_id":          pantryItem.LocationID,
+			"updated_at":           pantryItem.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	use := &pantryUse{Item: pantryItem, Quantity: usedQuantity, Lots: usedLots}
+
+	// Check if the item is now below its low-stock threshold or used up
+	levels, err := jobs.LoadStockLevels(sc, []models.PantryItem{pantryItem})
+	if err != nil {
+		return nil, err
+	}
+	use.LowStock = levels[pantryItem.ID].IsLowStock(newQuantity)
+	use.OutOfStock = newQuantity == 0
+
+	return use, nil
+}
+
+// recordPantryUse writes a history record for each lot used, raises the owner and stock
+// notifications and tells the group, once the transaction that used the item has committed.
+// The notifications are not critical, so failures are only logged.
+func recordPantryUse(r *http.Request, user *models.User, use *pantryUse, details string) {
+	ctx := context.Background()
+	item := &use.Item
+
+	// Let the owners know when someone else used their item
+	if err := notifyOwnersOfUse(ctx, item, user, use.Quantity); err != nil {
+		log.Printf("Failed to notify item owners: %v", err)
+	}
+
+	if use.LowStock && !use.OutOfStock {
+		createPantryNotifications(models.CreatePantryNotification(
+			item.GroupID,
+			item.ID,
+			item.Name,
+			models.NotificationTypeLowStock,
+			"Item is running low",
+		))
+	}
+	if use.OutOfStock {
+		// An out of stock item replaces its low_stock notifications
+		_, err := config.DB.Collection("notifications").DeleteMany(
+			ctx,
+			bson.M{
+				"pantry.item_id": item.ID,
+				"type":           models.NotificationTypeLowStock,
+			},
+		)
+		if err != nil {
+			log.Printf("Failed to delete low_stock notifications: %v", err)
+		}
+		createPantryNotifications(models.CreatePantryNotification(
+			item.GroupID,
+			item.ID,
+			item.Name,
+			models.NotificationTypeOutOfStock,
+			"Item is out of stock",
+		))
+	}
+
+	for _, usage := range use.Lots {
+		UpdatePantryHistoryForUse(
+			user.GroupID,
+			use.Item.ID,
+			use.Item.Name,
+			user.ID,
+			user.Name,
+			usage.Quantity,
+			&usage.LotID,
+			details,
+		)
+	}
+
+	publishGroupEvent(r, user.GroupID, realtime.EventPantryItemUsed, map[string]interface{}{
+		"item_id":            use.Item.ID,
+		"remaining_quantity": use.Item.Quantity,
+		"unit":               use.Item.Unit,
+	})
+	if use.LowStock {
+		publishGroupEvent(r, user.GroupID, realtime.EventPantryLowStock, use.Item)
+	}
 }
 
 // UsePantryItemHandler handles consuming an item from the pantry
@@ -766,140 +1193,108 @@ func UsePantryItemHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer session.EndSession(context.Background())
 
-	// Response data structure
-	type UsePantryItemResponse struct {
-		Success      bool    `json:"success"`
-		Message      string  `json:"message"`
-		RemainingQty float64 `json:"remaining_quantity"`
-		Unit         string  `json:"unit"`
-	}
-	var response UsePantryItemResponse
-
-	// Start transaction
-	err = mongo.WithSession(context.Background(), session, func(sc mongo.SessionContext) error {
-		// Find the pantry item
-		var pantryItem models.PantryItem
-		err := config.DB.Collection("pantry_items").FindOne(
-			sc,
-			bson.M{"_id": itemID},
-		).Decode(&pantryItem)
-
-		if err != nil {
-			if errors.Is(err, mongo.ErrNoDocuments) {
-				return errors.New("pantry item not found")
-			}
-			return err
-		}
-
-		// Verify the item belongs to the user's group
-		if pantryItem.GroupID != user.GroupID {
-			return errors.New("pantry item does not belong to user's group")
-		}
+	var use *pantryUse
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		var err error
+		use, err = usePantryItem(sc, itemID, &user, request.Quantity, request.Unit)
+		return nil, err
+	})
 
-		// Check if there's enough quantity
-		if pantryItem.Quantity < request.Quantity {
-			return errors.New("not enough quantity available")
+	if err != nil {
+		log.Printf("Transaction failed: %v", err)
+		if errors.Is(err, models.ErrUseNotPermitted) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
 		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		// Update the quantity
-		newQuantity := pantryItem.Quantity - request.Quantity
-		pantryItem.UpdateQuantity(newQuantity)
-
-		_, err = config.DB.Collection("pantry_items").UpdateOne(
-			sc,
-			bson.M{"_id": pantryItem.ID},
-			bson.M{"$set": bson.M{
-				"quantity":   newQuantity,
-				"updated_at": pantryItem.UpdatedAt,
-			}},
-		)
-		if err != nil {
-			return err
-		}
+	recordPantryUse(r, &user, use, "Item used from pantry")
 
-		// Set response values
-		response.Success = true
-		response.Message = "Item used successfully"
-		response.RemainingQty = newQuantity
-		response.Unit = pantryItem.Unit
-
-		// Check if low-stock notification is needed (if quantity is below threshold)
-		if newQuantity > 0 && newQuantity <= 1 {
-			notification := models.CreatePantryNotification(
-				pantryItem.GroupID,
-				pantryItem.ID,
-				pantryItem.Name,
-				models.NotificationTypeLowStock,
-				"Item is running low",
-			)
-			_, err = config.DB.Collection("pantry_notifications").InsertOne(sc, notification)
-			if err != nil {
-				log.Printf("Failed to create low-stock notification: %v", err)
-				// Continue anyway, as this is not critical
-			}
-		}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Success      bool              `json:"success"`
+		Message      string            `json:"message"`
+		UsedQty      float64           `json:"used_quantity"` // In the item's unit
+		RemainingQty float64           `json:"remaining_quantity"`
+		Unit         string            `json:"unit"`
+		Lots         []models.LotUsage `json:"lots"` // How much came out of each lot
+	}{
+		Success:      true,
+		Message:      "Item used successfully",
+		UsedQty:      use.Quantity,
+		RemainingQty: use.Item.Quantity,
+		Unit:         use.Item.Unit,
+		Lots:         use.Lots,
+	})
+}
 
-		if newQuantity == 0 {
-			// Remove any existing low_stock notifications
-			_, err = config.DB.Collection("pantry_notifications").DeleteMany(
-				sc,
-				bson.M{
-					"item_id": pantryItem.ID,
-					"type":    models.NotificationTypeLowStock,
-				},
-			)
-			if err != nil {
-				log.Printf("Failed to delete low_stock notifications: %v", err)
-				// Continue anyway as this is not critical
-			}
+// SetPantryStockLevelsHandler sets the low-stock threshold and par level of a pantry item.
+// Both are replaced, so a value left out goes back to the category's default.
+func SetPantryStockLevelsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-			// Create out_of_stock notification
-			notification := models.CreatePantryNotification(
-				pantryItem.GroupID,
-				pantryItem.ID,
-				pantryItem.Name,
-				models.NotificationTypeOutOfStock,
-				"Item is out of stock",
-			)
+	var request SetPantryStockLevelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
 
-			_, err = config.DB.Collection("pantry_notifications").InsertOne(sc, notification)
-			if err != nil {
-				log.Printf("Failed to create out_of_stock notification: %v", err)
-				// Continue anyway as this is not critical
-			}
-		}
+	if err := models.ValidateStockLevels(request.LowStockThreshold, request.ParLevel); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		return nil
-	})
+	pantryItem, user, ok := loadPantryItemForMember(w, r, request.ItemID)
+	if !ok {
+		return
+	}
 
-	if err != nil {
-		log.Printf("Transaction failed: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	// Changing someone else's item needs the same permission as using it
+	if !pantryItem.CanUse(user.ID) {
+		http.Error(w, models.ErrUseNotPermitted.Error(), http.StatusForbidden)
 		return
 	}
 
-	// Create history record for using an item
-	itemID, _ = primitive.ObjectIDFromHex(request.ItemID)
-	var pantryItem models.PantryItem
-	err = config.DB.Collection("pantry_items").FindOne(
+	set := bson.M{"updated_at": time.Now()}
+	unset := bson.M{}
+	if request.LowStockThreshold != nil {
+		set["low_stock_threshold"] = *request.LowStockThreshold
+	} else {
+		unset["low_stock_threshold"] = ""
+	}
+	if request.ParLevel != nil {
+		set["par_level"] = *request.ParLevel
+	} else {
+		unset["par_level"] = ""
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	err := config.DB.Collection("pantry_items").FindOneAndUpdate(
 		context.Background(),
-		bson.M{"_id": itemID},
+		bson.M{"_id": pantryItem.ID},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
 	).Decode(&pantryItem)
-
-	if err == nil {
-		UpdatePantryHistoryForUse(
-			user.GroupID,
-			itemID,
-			pantryItem.Name,
-			userID,
-			user.Name,
-			request.Quantity,
-		)
+	if err != nil {
+		log.Printf("Failed to update stock levels: %v", err)
+		http.Error(w, "Failed to update stock levels", http.StatusInternalServerError)
+		return
 	}
 
+	responseItem := newPantryItemResponse(pantryItem)
+	publishGroupEvent(r, pantryItem.GroupID, realtime.EventPantryItemUpdated, responseItem)
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(responseItem)
 }
 
 // DeletePantryItemHandler handles deleting a pantry item
@@ -1000,6 +1395,11 @@ func DeletePantryItemHandler(w http.ResponseWriter, r *http.Request) {
 			return errors.New("pantry item does not belong to user's group")
 		}
 
+		// Only the owners can delete a personal or split item
+		if !pantryItem.CanDelete(userID) {
+			return models.ErrNotOwner
+		}
+
 		// Delete the pantry item
 		_, err = config.DB.Collection("pantry_items").DeleteOne(
 			sc,
@@ -1010,20 +1410,34 @@ func DeletePantryItemHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Delete any notifications related to this item
-		_, err = config.DB.Collection("pantry_notifications").DeleteMany(
+		_, err = config.DB.Collection("notifications").DeleteMany(
 			sc,
-			bson.M{"item_id": itemID},
+			bson.M{"pantry.item_id": itemID},
 		)
 		if err != nil {
 			log.Printf("Failed to delete related notifications: %v", err)
 			// Continue anyway, as this is not critical
 		}
 
+		// Delete the item's consumption forecast
+		_, err = config.DB.Collection("pantry_forecasts").DeleteOne(
+			sc,
+			bson.M{"item_id": itemID},
+		)
+		if err != nil {
+			log.Printf("Failed to delete pantry forecast: %v", err)
+			// Continue anyway, as this is not critical
+		}
+
 		return nil
 	})
 
 	if err != nil {
 		log.Printf("Transaction failed: %v", err)
+		if errors.Is(err, models.ErrNotOwner) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -1040,6 +1454,8 @@ func DeletePantryItemHandler(w http.ResponseWriter, r *http.Request) {
 		)
 	}
 
+	publishGroupEvent(r, groupID, realtime.EventPantryItemDeleted, map[string]primitive.ObjectID{"item_id": itemID})
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
@@ -21,6 +21,10 @@ import (
 // CreateCategoryRequest defines the request structure for creating a custom category
 type CreateCategoryRequest struct {
 	Name string `json:"name" validate:"required,min=1"`
+
+	// Optional default stock levels for the category's items
+	LowStockThreshold *float64 `json:"low_stock_threshold,omitempty"`
+	ParLevel          *float64 `json:"par_level,omitempty"`
 }
 
 // UpdateCategoryRequest defines the request structure for updating a custom category
@@ -28,6 +32,14 @@ type UpdateCategoryRequest struct {
 	Name string `json:"name" validate:"required,min=1"`
 }
 
+// SetCategoryStockLevelsRequest defines the request structure for setting a custom category's
+// default stock levels. A value left out goes back to the global default.
+type SetCategoryStockLevelsRequest struct {
+	CategoryID        string   `json:"category_id" validate:"required"`
+	LowStockThreshold *float64 `json:"low_stock_threshold"`
+	ParLevel          *float64 `json:"par_level"`
+}
+
 // CategoryWithCreator represents a category with creator information
 type CategoryWithCreator struct {
 	ID          primitive.ObjectID `json:"id"`
@@ -37,6 +49,9 @@ type CategoryWithCreator struct {
 	GroupName   *string            `json:"group_name,omitempty"`
 	CreatedBy   *string            `json:"created_by,omitempty"`
 	CreatedByID *string            `json:"created_by_id,omitempty"`
+
+	LowStockThreshold *float64 `json:"low_stock_threshold,omitempty"`
+	ParLevel          *float64 `json:"par_level,omitempty"`
 }
 
 // StructuredCategoryResponse represents the new structured response format
@@ -168,9 +183,11 @@ func GetPantryCategoriesHandler(w http.ResponseWriter, r *http.Request) {
 
 	for _, category := range categories {
 		categoryWithCreator := CategoryWithCreator{
-			ID:   category.ID,
-			Name: category.Name,
-			Type: string(category.Type),
+			ID:                category.ID,
+			Name:              category.Name,
+			Type:              string(category.Type),
+			LowStockThreshold: category.LowStockThreshold,
+			ParLevel:          category.ParLevel,
 		}
 
 		if category.IsPredefined() {
@@ -240,6 +257,11 @@ func CreatePantryCategoryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := models.ValidateStockLevels(request.LowStockThreshold, request.ParLevel); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Sanitize the category name
 	categoryName := strings.TrimSpace(request.Name)
 
@@ -303,6 +325,8 @@ func CreatePantryCategoryHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Create new custom category
 	newCategory := models.CreateCustomCategory(categoryName, user.GroupID, userID)
+	newCategory.LowStockThreshold = request.LowStockThreshold
+	newCategory.ParLevel = request.ParLevel
 
 	// Insert the category
 	result, err := config.DB.Collection("pantry_categories").InsertOne(
@@ -494,6 +518,118 @@ func UpdatePantryCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// SetCategoryStockLevelsHandler sets the default low-stock threshold and par level of a
+// custom category, used by its items that do not set their own
+func SetCategoryStockLevelsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request SetCategoryStockLevelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := models.ValidateStockLevels(request.LowStockThreshold, request.ParLevel); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	categoryID, err := primitive.ObjectIDFromHex(request.CategoryID)
+	if err != nil {
+		http.Error(w, "Invalid category ID format", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Find user to get their group
+	var user models.User
+	err = config.DB.Collection("users").FindOne(
+		context.Background(),
+		bson.M{"_id": userID},
+	).Decode(&user)
+
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var category models.PantryCategory
+	err = config.DB.Collection("pantry_categories").FindOne(
+		context.Background(),
+		bson.M{"_id": categoryID},
+	).Decode(&category)
+
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Category not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch category", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if !category.CanBeEditedBy(userID, user.GroupID) {
+		if category.IsPredefined() {
+			http.Error(w, "Predefined categories cannot be edited", http.StatusForbidden)
+		} else {
+			http.Error(w, "You can only edit custom categories from your group", http.StatusForbidden)
+		}
+		return
+	}
+
+	set := bson.M{}
+	unset := bson.M{}
+	if request.LowStockThreshold != nil {
+		set["low_stock_threshold"] = *request.LowStockThreshold
+	} else {
+		unset["low_stock_threshold"] = ""
+	}
+	if request.ParLevel != nil {
+		set["par_level"] = *request.ParLevel
+	} else {
+		unset["par_level"] = ""
+	}
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var updatedCategory models.PantryCategory
+	err = config.DB.Collection("pantry_categories").FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": categoryID},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updatedCategory)
+
+	if err != nil {
+		log.Printf("Failed to update category stock levels: %v", err)
+		http.Error(w, "Failed to update category", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CategoryResponse{
+		Status:  "success",
+		Message: "Category stock levels updated successfully",
+		Data:    updatedCategory,
+	})
+}
+
 // DeletePantryCategoryHandler deletes a custom category (only if no items use it)
 func DeletePantryCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
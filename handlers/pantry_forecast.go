@@ -0,0 +1,94 @@
+// handlers/pantry_forecast.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/jobs"
+	"cribb-backend/models"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetPantryForecastsHandler returns when each of a group's pantry items is expected to run
+// out at the rate the group uses it, soonest first, with how much to buy to cover a week.
+// Items without enough use history to forecast come last.
+func GetPantryForecastsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupName := r.URL.Query().Get("group_name")
+	if groupName == "" {
+		http.Error(w, "group_name parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	_, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{"group_id": group.ID}
+	if itemIDStr := r.URL.Query().Get("item_id"); itemIDStr != "" {
+		itemID, err := primitive.ObjectIDFromHex(itemIDStr)
+		if err != nil {
+			http.Error(w, "Invalid item ID format", http.StatusBadRequest)
+			return
+		}
+		filter["_id"] = itemID
+	}
+
+	ctx := context.Background()
+	cursor, err := config.DB.Collection("pantry_items").Find(ctx, filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch pantry items", http.StatusInternalServerError)
+		return
+	}
+	var items []models.PantryItem
+	if err := cursor.All(ctx, &items); err != nil {
+		http.Error(w, "Failed to decode pantry items", http.StatusInternalServerError)
+		return
+	}
+
+	levels, err := jobs.LoadStockLevels(ctx, items)
+	if err != nil {
+		log.Printf("Failed to load stock levels: %v", err)
+		http.Error(w, "Failed to fetch pantry forecasts", http.StatusInternalServerError)
+		return
+	}
+	rates, err := jobs.LoadConsumptionRates(ctx, items)
+	if err != nil {
+		log.Printf("Failed to load consumption rates: %v", err)
+		http.Error(w, "Failed to fetch pantry forecasts", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	forecasts := make([]models.PantryForecast, 0, len(items))
+	for i := range items {
+		rate := rates[items[i].ID]
+		forecasts = append(forecasts, rate.Forecast(&items[i], levels[items[i].ID], now))
+	}
+	sort.SliceStable(forecasts, func(i, j int) bool {
+		a, b := forecasts[i].RunOutDate, forecasts[j].RunOutDate
+		if (a == nil) != (b == nil) {
+			return a != nil
+		}
+		if a != nil && !a.Equal(*b) {
+			return a.Before(*b)
+		}
+		return strings.ToLower(forecasts[i].ItemName) < strings.ToLower(forecasts[j].ItemName)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(forecasts)
+}
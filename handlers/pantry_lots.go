@@ -0,0 +1,150 @@
+// handlers/pantry_lots.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"cribb-backend/realtime"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UpdatePantryLotRequest defines the request structure for correcting a lot of a pantry item.
+// The quantity is in the item's unit, and 0 removes the lot. An expiration date confirms an
+// estimated one; an empty one clears it.
+type UpdatePantryLotRequest struct {
+	ItemID         string   `json:"item_id" validate:"required"`
+	LotID          string   `json:"lot_id" validate:"required"`
+	Quantity       *float64 `json:"quantity,omitempty"`
+	ExpirationDate *string  `json:"expiration_date,omitempty"`
+}
+
+// UpdatePantryLotHandler corrects the quantity or expiration date of one lot of a pantry item
+func UpdatePantryLotHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request UpdatePantryLotRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.Quantity == nil && request.ExpirationDate == nil {
+		http.Error(w, "Quantity or expiration date is required", http.StatusBadRequest)
+		return
+	}
+	if request.Quantity != nil && *request.Quantity < 0 {
+		http.Error(w, "Quantity cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	lotID, err := primitive.ObjectIDFromHex(request.LotID)
+	if err != nil {
+		http.Error(w, "Invalid lot ID format", http.StatusBadRequest)
+		return
+	}
+
+	var expirationDate time.Time
+	if request.ExpirationDate != nil && *request.ExpirationDate != "" {
+		expirationDate, err = time.Parse(time.RFC3339, *request.ExpirationDate)
+		if err != nil {
+			http.Error(w, "Invalid expiration date format. Use ISO 8601/RFC3339 format (YYYY-MM-DDTHH:MM:SSZ)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	pantryItem, user, ok := loadPantryItemForMember(w, r, request.ItemID)
+	if !ok {
+		return
+	}
+	if !pantryItem.CanUse(user.ID) {
+		http.Error(w, models.ErrUseNotPermitted.Error(), http.StatusForbidden)
+		return
+	}
+
+	session, err := config.DB.Client().StartSession()
+	if err != nil {
+		log.Printf("Failed to start MongoDB session: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer session.EndSession(context.Background())
+
+	// Run the read and rewrite of the lots in a transaction so a concurrent change to the
+	// item's stock is not overwritten
+	var delta float64
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		// Re-read the item so the change applies to its current lots
+		err := config.DB.Collection("pantry_items").FindOne(sc, bson.M{"_id": pantryItem.ID}).Decode(&pantryItem)
+		if err != nil {
+			return nil, err
+		}
+
+		lot, err := pantryItem.Lot(lotID)
+		if err != nil {
+			return nil, err
+		}
+		delta = 0
+		if request.Quantity != nil {
+			delta = *request.Quantity - lot.Quantity
+			lot.Quantity = *request.Quantity
+		}
+		if request.ExpirationDate != nil {
+			lot.ExpirationDate = expirationDate
+			lot.ExpirationEstimated = false
+		}
+		pantryItem.SyncLots()
+		pantryItem.UpdatedAt = time.Now()
+
+		_, err = config.DB.Collection("pantry_items").UpdateOne(
+			sc,
+			bson.M{"_id": pantryItem.ID},
+			bson.M{"$set": bson.M{
+				"quantity":             pantryItem.Quantity,
+				"lots":                 pantryItem.Lots,
+				"expiration_date":      pantryItem.ExpirationDate,
+				"expiration_estimated": pantryItem.ExpirationEstimated,
+				"location_id":          pantryItem.LocationID,
+				"updated_at":           pantryItem.UpdatedAt,
+			}},
+		)
+		return nil, err
+	})
+
+	if err != nil {
+		if errors.Is(err, models.ErrLotNotFound) {
+			http.Error(w, "Lot not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to update pantry lot: %v", err)
+		http.Error(w, "Failed to update pantry lot", http.StatusInternalServerError)
+		return
+	}
+
+	UpdatePantryHistoryForLot(
+		pantryItem.GroupID,
+		pantryItem.ID,
+		pantryItem.Name,
+		user.ID,
+		user.Name,
+		delta,
+		lotID,
+	)
+
+	responseItem := newPantryItemResponse(pantryItem)
+	publishGroupEvent(r, pantryItem.GroupID, realtime.EventPantryItemUpdated, responseItem)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responseItem)
+}
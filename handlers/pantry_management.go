@@ -7,6 +7,7 @@ import (
 	"cribb-backend/jobs"
 	"cribb-backend/middleware"
 	"cribb-backend/models"
+	"cribb-backend/query"
 	"encoding/json"
 	"errors"
 	"log"
@@ -19,8 +20,8 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// GetPantryWarningsHandler retrieves low-stock warnings for a group
-// GetPantryWarningsHandler retrieves low-stock warnings for a group
+// GetPantryWarningsHandler retrieves low-stock warnings for a group, with each item's
+// stock levels and how much to buy to reach its par level
 func GetPantryWarningsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -98,7 +99,7 @@ func GetPantryWarningsHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Find all low-stock and out-of-stock notifications for this group
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(50)
-	cursor, err := config.DB.Collection("pantry_notifications").Find(
+	cursor, err := config.DB.Collection("notifications").Find(
 		context.Background(),
 		bson.M{
 			"group_id": group.ID,
@@ -118,7 +119,7 @@ func GetPantryWarningsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer cursor.Close(context.Background())
 
-	var notifications []models.PantryNotification
+	var notifications []models.Notification
 	if err = cursor.All(context.Background(), &notifications); err != nil {
 		http.Error(w, "Failed to decode pantry warnings", http.StatusInternalServerError)
 		return
@@ -126,31 +127,79 @@ func GetPantryWarningsHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Now fetch the items to get current quantities
 	type WarningResponse struct {
-		models.PantryNotification
-		CurrentQuantity float64 `json:"current_quantity"`
-		Unit            string  `json:"unit"`
-		IsRead          bool    `json:"is_read"`
+		models.Notification
+		ItemID            primitive.ObjectID `json:"item_id"`
+		ItemName          string             `json:"item_name"`
+		CurrentQuantity   float64            `json:"current_quantity"`
+		Unit              string             `json:"unit"`
+		LowStockThreshold float64            `json:"low_stock_threshold"`
+		ParLevel          float64            `json:"par_level"`
+		SuggestedQuantity float64            `json:"suggested_quantity"`
+		IsRead            bool               `json:"is_read"`
+	}
+
+	views, err := notificationViews(context.Background(), &user, notifications)
+	if err != nil {
+		log.Printf("Failed to load notification receipts: %v", err)
+		http.Error(w, "Failed to fetch pantry warnings", http.StatusInternalServerError)
+		return
 	}
 
-	response := make([]WarningResponse, 0, len(notifications))
+	itemIDs := make([]primitive.ObjectID, 0, len(notifications))
 	for _, notification := range notifications {
-		warningResponse := WarningResponse{
-			PantryNotification: notification,
-			CurrentQuantity:    0,
-			Unit:               "",
-			IsRead:             notification.HasBeenReadBy(userID),
+		if notification.Pantry != nil {
+			itemIDs = append(itemIDs, notification.Pantry.ItemID)
 		}
+	}
+	itemCursor, err := config.DB.Collection("pantry_items").Find(
+		context.Background(),
+		bson.M{"_id": bson.M{"$in": itemIDs}},
+	)
+	if err != nil {
+		http.Error(w, "Failed to fetch pantry items", http.StatusInternalServerError)
+		return
+	}
+	var items []models.PantryItem
+	if err = itemCursor.All(context.Background(), &items); err != nil {
+		http.Error(w, "Failed to decode pantry items", http.StatusInternalServerError)
+		return
+	}
+	itemsByID := make(map[primitive.ObjectID]models.PantryItem, len(items))
+	for _, item := range items {
+		itemsByID[item.ID] = item
+	}
 
-		// Try to get the current item information
-		var item models.PantryItem
-		err := config.DB.Collection("pantry_items").FindOne(
-			context.Background(),
-			bson.M{"_id": notification.ItemID},
-		).Decode(&item)
+	levels, err := jobs.LoadStockLevels(context.Background(), items)
+	if err != nil {
+		log.Printf("Failed to load stock levels: %v", err)
+		http.Error(w, "Failed to fetch pantry warnings", http.StatusInternalServerError)
+		return
+	}
 
-		if err == nil {
+	response := make([]WarningResponse, 0, len(views))
+	for _, view := range views {
+		notification := view.Notification
+		warningResponse := WarningResponse{
+			Notification:    notification,
+			ItemID:          notification.Pantry.ItemID,
+			ItemName:        notification.Pantry.ItemName,
+			CurrentQuantity: 0,
+			Unit:            "",
+			IsRead:          view.IsRead,
+		}
+
+		// Fill in the current item information, leaving out warnings for items that have
+		// since been restocked above their threshold
+		if item, found := itemsByID[notification.Pantry.ItemID]; found {
+			itemLevels := levels[item.ID]
+			if !itemLevels.NeedsRestock(item.Quantity) {
+				continue
+			}
 			warningResponse.CurrentQuantity = item.Quantity
 			warningResponse.Unit = item.Unit
+			warningResponse.LowStockThreshold = itemLevels.LowStockThreshold
+			warningResponse.ParLevel = itemLevels.ParLevel
+			warningResponse.SuggestedQuantity = itemLevels.SuggestedQuantity(item.Quantity)
 		}
 
 		response = append(response, warningResponse)
@@ -160,7 +209,7 @@ func GetPantryWarningsHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetPantryExpiringHandler retrieves items that are expiring soon
+// GetPantryExpiringHandler retrieves the lots of items that are expiring soon or have expired
 func GetPantryExpiringHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -239,7 +288,7 @@ func GetPantryExpiringHandler(w http.ResponseWriter, r *http.Request) {
 	// Find all expiration notifications for this group
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(50)
 
-	cursor, err := config.DB.Collection("pantry_notifications").Find(
+	cursor, err := config.DB.Collection("notifications").Find(
 		context.Background(),
 		bson.M{
 			"group_id": group.ID,
@@ -259,7 +308,7 @@ func GetPantryExpiringHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer cursor.Close(context.Background())
 
-	var notifications []models.PantryNotification
+	var notifications []models.Notification
 	if err = cursor.All(context.Background(), &notifications); err != nil {
 		http.Error(w, "Failed to decode expiration notifications", http.StatusInternalServerError)
 		return
@@ -267,26 +316,41 @@ func GetPantryExpiringHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Now fetch the items to get current expiration dates
 	type ExpiringResponse struct {
-		models.PantryNotification
-		ExpirationDate time.Time `json:"expiration_date"`
-		Quantity       float64   `json:"quantity"`
-		Unit           string    `json:"unit"`
-		IsExpired      bool      `json:"is_expired"`
-		IsRead         bool      `json:"is_read"`
+		models.Notification
+		ItemID         primitive.ObjectID  `json:"item_id"`
+		ItemName       string              `json:"item_name"`
+		LotID          *primitive.ObjectID `json:"lot_id,omitempty"`
+		ExpirationDate time.Time           `json:"expiration_date"`
+		Quantity       float64             `json:"quantity"`
+		Unit           string              `json:"unit"`
+		IsExpired      bool                `json:"is_expired"`
+		IsRead         bool                `json:"is_read"`
+
+		ExpirationEstimated bool `json:"expiration_estimated"`
 	}
 
-	response := make([]ExpiringResponse, 0, len(notifications))
-	for _, notification := range notifications {
+	views, err := notificationViews(context.Background(), &user, notifications)
+	if err != nil {
+		log.Printf("Failed to load notification receipts: %v", err)
+		http.Error(w, "Failed to fetch expiration notifications", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]ExpiringResponse, 0, len(views))
+	for _, view := range views {
+		notification := view.Notification
 		expiringResponse := ExpiringResponse{
-			PantryNotification: notification,
-			IsRead:             notification.HasBeenReadBy(userID),
+			Notification: notification,
+			ItemID:       notification.Pantry.ItemID,
+			ItemName:     notification.Pantry.ItemName,
+			IsRead:       view.IsRead,
 		}
 
 		// Try to get the current item information
 		var item models.PantryItem
 		err := config.DB.Collection("pantry_items").FindOne(
 			context.Background(),
-			bson.M{"_id": notification.ItemID},
+			bson.M{"_id": notification.Pantry.ItemID},
 		).Decode(&item)
 
 		if err == nil {
@@ -294,6 +358,20 @@ func GetPantryExpiringHandler(w http.ResponseWriter, r *http.Request) {
 			expiringResponse.Quantity = item.Quantity
 			expiringResponse.Unit = item.Unit
 			expiringResponse.IsExpired = item.IsExpired()
+			expiringResponse.ExpirationEstimated = item.ExpirationEstimated
+
+			// Report the lot the notification is about, leaving it out once the lot is used up
+			if lotID := notification.Pantry.LotID; lotID != nil {
+				lot, err := item.Lot(*lotID)
+				if err != nil {
+					continue
+				}
+				expiringResponse.LotID = lotID
+				expiringResponse.ExpirationDate = lot.ExpirationDate
+				expiringResponse.Quantity = lot.Quantity
+				expiringResponse.IsExpired = lot.IsExpired()
+				expiringResponse.ExpirationEstimated = lot.ExpirationEstimated
+			}
 		}
 
 		response = append(response, expiringResponse)
@@ -303,106 +381,6 @@ func GetPantryExpiringHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// MarkNotificationReadHandler marks a notification as read by the current user
-func MarkNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Get user from context (set by AuthMiddleware)
-	userClaims, ok := middleware.GetUserFromContext(r.Context())
-	if !ok {
-		http.Error(w, "User not authenticated", http.StatusUnauthorized)
-		return
-	}
-
-	var request struct {
-		NotificationID string `json:"notification_id"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	// Validate required fields
-	if request.NotificationID == "" {
-		http.Error(w, "Notification ID is required", http.StatusBadRequest)
-		return
-	}
-
-	// Convert notification ID to ObjectID
-	notificationID, err := primitive.ObjectIDFromHex(request.NotificationID)
-	if err != nil {
-		http.Error(w, "Invalid notification ID format", http.StatusBadRequest)
-		return
-	}
-
-	// Get user ID
-	userID, err := primitive.ObjectIDFromHex(userClaims.ID)
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
-		return
-	}
-
-	// Find notification
-	var notification models.PantryNotification
-	err = config.DB.Collection("pantry_notifications").FindOne(
-		context.Background(),
-		bson.M{"_id": notificationID},
-	).Decode(&notification)
-
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			http.Error(w, "Notification not found", http.StatusNotFound)
-		} else {
-			http.Error(w, "Failed to fetch notification", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	// Find user to verify group membership
-	var user models.User
-	err = config.DB.Collection("users").FindOne(
-		context.Background(),
-		bson.M{"_id": userID},
-	).Decode(&user)
-
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			http.Error(w, "User not found", http.StatusNotFound)
-		} else {
-			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	// Verify user belongs to the notification's group
-	if user.GroupID != notification.GroupID {
-		http.Error(w, "User is not a member of this notification's group", http.StatusForbidden)
-		return
-	}
-
-	// Mark notification as read
-	_, err = config.DB.Collection("pantry_notifications").UpdateOne(
-		context.Background(),
-		bson.M{"_id": notificationID},
-		bson.M{"$addToSet": bson.M{"read_by": userID}},
-	)
-
-	if err != nil {
-		http.Error(w, "Failed to mark notification as read", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Notification marked as read",
-	})
-}
-
 // GetPantryShoppingListHandler generates a shopping list based on low stock items
 func GetPantryShoppingListHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -492,7 +470,8 @@ func GetPantryShoppingListHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GetPantryHistoryHandler retrieves the history of pantry actions
+// GetPantryHistoryHandler retrieves a page of pantry actions, newest first by default. History can be
+// filtered by item_id, lot_id, location_id, user_id, action and created_at range.
 func GetPantryHistoryHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -509,8 +488,6 @@ func GetPantryHistoryHandler(w http.ResponseWriter, r *http.Request) {
 	// Get query parameters
 	groupName := r.URL.Query().Get("group_name")
 	groupCode := r.URL.Query().Get("group_code")
-	itemID := r.URL.Query().Get("item_id")
-	limit := 50 // Default limit
 
 	// Need either group name or group code
 	if groupName == "" && groupCode == "" {
@@ -518,6 +495,11 @@ func GetPantryHistoryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	q, ok := parseListQuery(w, r, pantryHistoryListSpec)
+	if !ok {
+		return
+	}
+
 	// Get user ID
 	userID, err := primitive.ObjectIDFromHex(userClaims.ID)
 	if err != nil {
@@ -570,29 +552,11 @@ func GetPantryHistoryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set up the query filter
-	filter := bson.M{"group_id": group.ID}
-
-	// Add item ID filter if provided
-	if itemID != "" {
-		itemObjID, err := primitive.ObjectIDFromHex(itemID)
-		if err != nil {
-			http.Error(w, "Invalid item ID format", http.StatusBadRequest)
-			return
-		}
-		filter["item_id"] = itemObjID
-	}
-
-	// Set up options for sorting and limiting results
-	opts := options.Find().
-		SetSort(bson.D{{Key: "created_at", Value: -1}}). // Sort by newest first
-		SetLimit(int64(limit))
-
 	// Query the history collection
 	cursor, err := config.DB.Collection("pantry_history").Find(
 		context.Background(),
-		filter,
-		opts,
+		q.Filter(bson.M{"group_id": group.ID}),
+		q.FindOptions(),
 	)
 
 	if err != nil {
@@ -607,14 +571,18 @@ func GetPantryHistoryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	history, nextCursor, err := query.Paginate(history, q)
+	if err != nil {
+		log.Printf("Failed to build pantry history cursor: %v", err)
+		http.Error(w, "Failed to paginate pantry history", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"history": history,
-		"count":   len(history),
-	})
+	json.NewEncoder(w).Encode(query.NewPage(history, nextCursor, q.Limit))
 }
 
-// DeleteNotificationHandler handles deleting a pantry notification
+// DeleteNotificationHandler deletes a notification for the whole group, along with its read receipts
 func DeleteNotificationHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -666,8 +634,8 @@ func DeleteNotificationHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Find notification to verify ownership
-	var notification models.PantryNotification
-	err = config.DB.Collection("pantry_notifications").FindOne(
+	var notification models.Notification
+	err = config.DB.Collection("notifications").FindOne(
 		context.Background(),
 		bson.M{"_id": notificationID},
 	).Decode(&notification)
@@ -686,9 +654,13 @@ func DeleteNotificationHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "User is not a member of this notification's group", http.StatusForbidden)
 		return
 	}
+	if !notification.IsVisibleTo(userID) {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
 
 	// Delete the notification
-	result, err := config.DB.Collection("pantry_notifications").DeleteOne(
+	result, err := config.DB.Collection("notifications").DeleteOne(
 		context.Background(),
 		bson.M{"_id": notificationID},
 	)
@@ -703,6 +675,14 @@ func DeleteNotificationHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	_, err = config.DB.Collection("notification_receipts").DeleteMany(
+		context.Background(),
+		bson.M{"notification_id": notificationID},
+	)
+	if err != nil {
+		log.Printf("Failed to delete notification receipts: %v", err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
@@ -710,8 +690,9 @@ func DeleteNotificationHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// UpdatePantryHistoryForAdd creates a history record for adding an item
-func UpdatePantryHistoryForAdd(groupID, itemID primitive.ObjectID, itemName string, userID primitive.ObjectID, userName string, quantity float64) {
+// UpdatePantryHistoryForAdd creates a history record for adding an item, referencing the
+// lot when one is given
+func UpdatePantryHistoryForAdd(groupID, itemID primitive.ObjectID, itemName string, userID primitive.ObjectID, userName string, quantity float64, lotID *primitive.ObjectID) {
 	history := models.CreatePantryHistory(
 		groupID,
 		itemID,
@@ -722,6 +703,7 @@ func UpdatePantryHistoryForAdd(groupID, itemID primitive.ObjectID, itemName stri
 		quantity,
 		"Item added to pantry",
 	)
+	history.LotID = lotID
 
 	_, err := config.DB.Collection("pantry_history").InsertOne(
 		context.Background(),
@@ -733,8 +715,9 @@ func UpdatePantryHistoryForAdd(groupID, itemID primitive.ObjectID, itemName stri
 	}
 }
 
-// UpdatePantryHistoryForUse creates a history record for using an item
-func UpdatePantryHistoryForUse(groupID, itemID primitive.ObjectID, itemName string, userID primitive.ObjectID, userName string, quantity float64) {
+// UpdatePantryHistoryForUse creates a history record for using an item, referencing the
+// lot when one is given
+func UpdatePantryHistoryForUse(groupID, itemID primitive.ObjectID, itemName string, userID primitive.ObjectID, userName string, quantity float64, lotID *primitive.ObjectID, details string) {
 	history := models.CreatePantryHistory(
 		groupID,
 		itemID,
@@ -743,8 +726,9 @@ func UpdatePantryHistoryForUse(groupID, itemID primitive.ObjectID, itemName stri
 		userName,
 		models.ActionTypeUse,
 		quantity,
-		"Item used from pantry",
+		details,
 	)
+	history.LotID = lotID
 
 	_, err := config.DB.Collection("pantry_history").InsertOne(
 		context.Background(),
@@ -756,6 +740,98 @@ func UpdatePantryHistoryForUse(groupID, itemID primitive.ObjectID, itemName stri
 	}
 }
 
+// UpdatePantryHistoryForLot creates a history record for correcting a lot of an item
+func UpdatePantryHistoryForLot(groupID, itemID primitive.ObjectID, itemName string, userID primitive.ObjectID, userName string, quantity float64, lotID primitive.ObjectID) {
+	history := models.CreatePantryHistory(
+		groupID,
+		itemID,
+		itemName,
+		userID,
+		userName,
+		models.ActionTypeUpdate,
+		quantity,
+		"Item lot updated",
+	)
+	history.LotID = &lotID
+
+	_, err := config.DB.Collection("pantry_history").InsertOne(
+		context.Background(),
+		history,
+	)
+
+	if err != nil {
+		log.Printf("Failed to create pantry history record: %v", err)
+	}
+}
+
+// UpdatePantryHistoryForMove creates a history record for moving a lot of an item to another
+// storage location
+func UpdatePantryHistoryForMove(groupID, itemID primitive.ObjectID, itemName string, userID primitive.ObjectID, userName string, quantity float64, lotID primitive.ObjectID, location *models.StorageLocation) {
+	history := models.CreatePantryHistory(
+		groupID,
+		itemID,
+		itemName,
+		userID,
+		userName,
+		models.ActionTypeMove,
+		quantity,
+		"Item moved to "+location.Name,
+	)
+	history.LotID = &lotID
+	history.LocationID = &location.ID
+
+	_, err := config.DB.Collection("pantry_history").InsertOne(
+		context.Background(),
+		history,
+	)
+
+	if err != nil {
+		log.Printf("Failed to create pantry history record: %v", err)
+	}
+}
+
+// UpdatePantryHistoryForDiscard creates a history record for each lot stock was thrown away
+// from, sharing out the discard's value among them. The records share a discard ID so the
+// waste report counts them as one discard. It writes within ctx so the records are saved
+// in the same transaction as the discard itself.
+func UpdatePantryHistoryForDiscard(ctx context.Context, item *models.PantryItem, userID primitive.ObjectID, userName string, usages []models.LotUsage, reason models.DiscardReason, value *float64, details string) error {
+	if len(usages) == 0 {
+		return nil
+	}
+
+	discardID := primitive.NewObjectID()
+	createdAt := time.Now()
+	values := models.SplitValue(value, usages)
+	records := make([]interface{}, 0, len(usages))
+	for i, usage := range usages {
+		history := models.CreatePantryHistory(
+			item.GroupID,
+			item.ID,
+			item.Name,
+			userID,
+			userName,
+			models.ActionTypeDiscard,
+			usage.Quantity,
+			details,
+		)
+		lotID := usage.LotID
+		history.LotID = &lotID
+		history.CreatedAt = createdAt
+		history.DiscardID = &discardID
+		history.Reason = reason
+		history.Value = values[i]
+		history.Unit = item.Unit
+		if !item.CategoryID.IsZero() {
+			categoryID := item.CategoryID
+			history.CategoryID = &categoryID
+		}
+		records = append(records, history)
+	}
+
+	_, err := config.DB.Collection("pantry_history").InsertMany(ctx, records)
+	return err
+}
+
 // UpdatePantryHistoryForRemove creates a history record for removing an item
 func UpdatePantryHistoryForRemove(groupID, itemID primitive.ObjectID, itemName string, userID primitive.ObjectID, userName string, quantity float64) {
 	history := models.CreatePantryHistory(
@@ -0,0 +1,273 @@
+// handlers/pantry_ownership.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"cribb-backend/realtime"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SetPantryOwnershipRequest defines the request structure for changing who a pantry item
+// belongs to. A personal item without owner_ids belongs to the member making the request.
+type SetPantryOwnershipRequest struct {
+	ItemID    string   `json:"item_id" validate:"required"`
+	Ownership string   `json:"ownership" validate:"required"`
+	OwnerIDs  []string `json:"owner_ids,omitempty"`
+}
+
+// RequestPantryUseRequest defines the request structure for asking the owners of a pantry item
+// for permission to use it
+type RequestPantryUseRequest struct {
+	ItemID  string `json:"item_id" validate:"required"`
+	Message string `json:"message,omitempty"`
+}
+
+// AllowPantryUseRequest defines the request structure for giving or taking away a member's
+// permission to use a pantry item
+type AllowPantryUseRequest struct {
+	ItemID  string `json:"item_id" validate:"required"`
+	UserID  string `json:"user_id" validate:"required"`
+	Allowed bool   `json:"allowed"`
+}
+
+// parseOwners resolves the owners given for an ownership mode, checking they are members of
+// the group. A personal item without owners belongs to userID.
+func parseOwners(ctx context.Context, groupID, userID primitive.ObjectID, mode models.OwnershipMode, ownerIDStrs []string) ([]primitive.ObjectID, error) {
+	if !models.IsValidOwnershipMode(mode) {
+		return nil, errors.New("invalid ownership. Must be one of: shared, personal, split")
+	}
+	if mode == models.OwnershipPersonal && len(ownerIDStrs) == 0 {
+		return []primitive.ObjectID{userID}, nil
+	}
+
+	ownerIDs := make([]primitive.ObjectID, 0, len(ownerIDStrs))
+	for _, idStr := range ownerIDStrs {
+		id, err := primitive.ObjectIDFromHex(idStr)
+		if err != nil {
+			return nil, errors.New("invalid owner ID format")
+		}
+		ownerIDs = append(ownerIDs, id)
+	}
+	ownerIDs = models.SortOwners(ownerIDs)
+	if err := models.ValidateOwnership(mode, ownerIDs); err != nil {
+		return nil, err
+	}
+	if len(ownerIDs) == 0 {
+		return ownerIDs, nil
+	}
+
+	members, err := config.DB.Collection("users").CountDocuments(ctx, bson.M{"_id": bson.M{"$in": ownerIDs}, "group_id": groupID})
+	if err != nil {
+		return nil, err
+	}
+	if int(members) != len(ownerIDs) {
+		return nil, errors.New("owners must be members of the group")
+	}
+	return ownerIDs, nil
+}
+
+// notifyOwnersOfUse tells the owners of a personal or split item that another member used
+// some of it
+func notifyOwnersOfUse(ctx context.Context, item *models.PantryItem, user *models.User, quantity float64) error {
+	if item.IsShared() || item.IsOwner(user.ID) {
+		return nil
+	}
+
+	message := user.Name + " used " + strconv.FormatFloat(quantity, 'f', -1, 64) + " " + item.Unit + " of your " + item.Name
+	notifications := make([]interface{}, 0, len(item.OwnerIDs))
+	for _, ownerID := range item.OwnerIDs {
+		notifications = append(notifications, models.CreatePantryMemberNotification(
+			item, ownerID, user, models.NotificationTypePantryItemUsed, message,
+		))
+	}
+	_, err := config.DB.Collection("notifications").InsertMany(ctx, notifications)
+	return err
+}
+
+// SetPantryOwnershipHandler changes who a pantry item belongs to. Only the item's owners may
+// do so, or for a shared item the member who added it.
+func SetPantryOwnershipHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request SetPantryOwnershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	pantryItem, user, ok := loadPantryItemForMember(w, r, request.ItemID)
+	if !ok {
+		return
+	}
+	if !pantryItem.CanManageOwnership(user.ID) {
+		http.Error(w, models.ErrNotOwner.Error(), http.StatusForbidden)
+		return
+	}
+
+	mode := models.OwnershipMode(request.Ownership)
+	ownerIDs, err := parseOwners(context.Background(), pantryItem.GroupID, user.ID, mode, request.OwnerIDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := pantryItem.SetOwnership(mode, ownerIDs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pantryItem.UpdatedAt = time.Now()
+
+	_, err = config.DB.Collection("pantry_items").UpdateOne(
+		context.Background(),
+		bson.M{"_id": pantryItem.ID},
+		bson.M{"$set": bson.M{
+			"ownership":        pantryItem.Ownership,
+			"owner_ids":        pantryItem.OwnerIDs,
+			"allowed_user_ids": pantryItem.AllowedUserIDs,
+			"updated_at":       pantryItem.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		log.Printf("Failed to update pantry item ownership: %v", err)
+		http.Error(w, "Failed to update pantry item ownership", http.StatusInternalServerError)
+		return
+	}
+
+	responseItem := newPantryItemResponse(pantryItem)
+	publishGroupEvent(r, pantryItem.GroupID, realtime.EventPantryItemUpdated, responseItem)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responseItem)
+}
+
+// RequestPantryUseHandler asks the owners of a personal or split pantry item for permission
+// to use it
+func RequestPantryUseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request RequestPantryUseRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	pantryItem, user, ok := loadPantryItemForMember(w, r, request.ItemID)
+	if !ok {
+		return
+	}
+	if pantryItem.CanUse(user.ID) {
+		http.Error(w, "You can already use this item", http.StatusBadRequest)
+		return
+	}
+
+	message := user.Name + " asks to use your " + pantryItem.Name
+	if request.Message != "" {
+		message += ": " + request.Message
+	}
+	notifications := make([]interface{}, 0, len(pantryItem.OwnerIDs))
+	for _, ownerID := range pantryItem.OwnerIDs {
+		notifications = append(notifications, models.CreatePantryMemberNotification(
+			&pantryItem, ownerID, &user, models.NotificationTypePantryUseRequested, message,
+		))
+	}
+	if _, err := config.DB.Collection("notifications").InsertMany(context.Background(), notifications); err != nil {
+		log.Printf("Failed to create permission request notifications: %v", err)
+		http.Error(w, "Failed to request permission", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Permission requested successfully"})
+}
+
+// AllowPantryUseHandler gives or takes away a member's permission to use a personal or split
+// pantry item. Only the item's owners may do so.
+func AllowPantryUseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request AllowPantryUseRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	memberID, err := primitive.ObjectIDFromHex(request.UserID)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	pantryItem, user, ok := loadPantryItemForMember(w, r, request.ItemID)
+	if !ok {
+		return
+	}
+	if pantryItem.IsShared() {
+		http.Error(w, "Everyone in the group can use a shared item", http.StatusBadRequest)
+		return
+	}
+	if !pantryItem.IsOwner(user.ID) {
+		http.Error(w, models.ErrNotOwner.Error(), http.StatusForbidden)
+		return
+	}
+
+	ctx := context.Background()
+	members, err := config.DB.Collection("users").CountDocuments(ctx, bson.M{"_id": memberID, "group_id": pantryItem.GroupID})
+	if err != nil {
+		http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		return
+	}
+	if members == 0 {
+		http.Error(w, "User is not a member of this group", http.StatusBadRequest)
+		return
+	}
+
+	pantryItem.AllowUser(memberID, request.Allowed)
+	pantryItem.UpdatedAt = time.Now()
+	_, err = config.DB.Collection("pantry_items").UpdateOne(
+		ctx,
+		bson.M{"_id": pantryItem.ID},
+		bson.M{"$set": bson.M{
+			"allowed_user_ids": pantryItem.AllowedUserIDs,
+			"updated_at":       pantryItem.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		log.Printf("Failed to update pantry item permissions: %v", err)
+		http.Error(w, "Failed to update pantry item permissions", http.StatusInternalServerError)
+		return
+	}
+
+	if request.Allowed {
+		notification := models.CreatePantryMemberNotification(
+			&pantryItem, memberID, &user, models.NotificationTypePantryUseAllowed,
+			user.Name+" allowed you to use their "+pantryItem.Name,
+		)
+		if _, err := config.DB.Collection("notifications").InsertOne(ctx, notification); err != nil {
+			log.Printf("Failed to create permission notification: %v", err)
+		}
+	}
+
+	responseItem := newPantryItemResponse(pantryItem)
+	publishGroupEvent(r, pantryItem.GroupID, realtime.EventPantryItemUpdated, responseItem)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responseItem)
+}
@@ -0,0 +1,429 @@
+// handlers/pantry_waste.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"cribb-backend/realtime"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DiscardPantryItemRequest defines the request structure for throwing pantry stock away
+type DiscardPantryItemRequest struct {
+	ItemID   string   `json:"item_id" validate:"required"`
+	LotID    string   `json:"lot_id,omitempty"`   // Left out, stock is taken from the first-expiring lots
+	Quantity float64  `json:"quantity,omitempty"` // Left out, the whole lot, or every expired lot
+	Unit     string   `json:"unit,omitempty"`     // Left out, the item's own unit
+	Reason   string   `json:"reason" validate:"required"`
+	Value    *float64 `json:"value,omitempty"` // Left out, estimated from earlier discards of the item
+	Notes    string   `json:"notes,omitempty"`
+}
+
+// DiscardPrompt is an expired lot a member can throw away
+type DiscardPrompt struct {
+	ItemID              primitive.ObjectID   `json:"item_id"`
+	ItemName            string               `json:"item_name"`
+	LotID               primitive.ObjectID   `json:"lot_id"`
+	Quantity            float64              `json:"quantity"`
+	Unit                string               `json:"unit"`
+	ExpirationDate      time.Time            `json:"expiration_date"`
+	ExpirationEstimated bool                 `json:"expiration_estimated"`
+	EstimatedValue      *float64             `json:"estimated_value,omitempty"`
+	Reason              models.DiscardReason `json:"reason"` // The reason suggested for the discard
+}
+
+// DiscardPantryItemHandler throws pantry stock away, recording why and what it was worth so
+// the group can see how much it wastes. Unlike removing an item, the item is kept, with its
+// remaining lots.
+func DiscardPantryItemHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request DiscardPantryItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	reason := models.DiscardReason(request.Reason)
+	if !models.IsValidDiscardReason(reason) {
+		http.Error(w, "Invalid reason. Must be expired, spoiled or other", http.StatusBadRequest)
+		return
+	}
+	if request.Quantity < 0 {
+		http.Error(w, "Quantity cannot be negative", http.StatusBadRequest)
+		return
+	}
+	if request.Value != nil && *request.Value < 0 {
+		http.Error(w, "Value cannot be negative", http.StatusBadRequest)
+		return
+	}
+	var lotID *primitive.ObjectID
+	if request.LotID != "" {
+		id, err := primitive.ObjectIDFromHex(request.LotID)
+		if err != nil {
+			http.Error(w, "Invalid lot ID format", http.StatusBadRequest)
+			return
+		}
+		lotID = &id
+	}
+
+	pantryItem, user, ok := loadPantryItemForMember(w, r, request.ItemID)
+	if !ok {
+		return
+	}
+
+	// Only the owners can throw away a personal or split item
+	if !pantryItem.CanDelete(user.ID) {
+		http.Error(w, models.ErrNotOwner.Error(), http.StatusForbidden)
+		return
+	}
+
+	// Convert the quantity thrown away into the item's unit
+	discardQuantity, err := pantryItem.ToItemUnit(request.Quantity, request.Unit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot discard %s: %v", pantryItem.Name, err), http.StatusBadRequest)
+		return
+	}
+
+	session, err := config.DB.Client().StartSession()
+	if err != nil {
+		log.Printf("Failed to start MongoDB session: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer session.EndSession(context.Background())
+
+	details := request.Notes
+	if details == "" {
+		details = "Item discarded"
+	}
+
+	// Take the stock out, remember its value and record the waste in one transaction, so a
+	// concurrent change to the item is not overwritten and no discard goes unrecorded
+	var discarded []models.LotUsage
+	var quantity float64
+	var value *float64
+	var emptied []primitive.ObjectID
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		// Re-read the item so the discard applies to its current lots
+		err := config.DB.Collection("pantry_items").FindOne(sc, bson.M{"_id": pantryItem.ID}).Decode(&pantryItem)
+		if err != nil {
+			return nil, err
+		}
+		discarded, err = pantryItem.DiscardLots(lotID, discardQuantity, time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		quantity = models.DiscardedQuantity(discarded)
+		if request.Value != nil {
+			value = request.Value
+			pantryItem.RememberValue(quantity, *request.Value)
+		} else {
+			value = pantryItem.EstimateValue(quantity)
+		}
+
+		_, err = config.DB.Collection("pantry_items").UpdateOne(
+			sc,
+			bson.M{"_id": pantryItem.ID},
+			bson.M{"$set": bson.M{
+				"quantity":             pantryItem.Quantity,
+				"lots":                 pantryItem.Lots,
+				"expiration_date":      pantryItem.ExpirationDate,
+				"expiration_estimated": pantryItem.ExpirationEstimated,
+				"location_id":          pantryItem.LocationID,
+				"unit_value":           pantryItem.UnitValue,
+				"updated_at":           pantryItem.UpdatedAt,
+			}},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := UpdatePantryHistoryForDiscard(sc, &pantryItem, user.ID, user.Name, discarded, reason, value, details); err != nil {
+			return nil, err
+		}
+
+		// Lots thrown away no longer need expiry reminders
+		emptied = emptied[:0]
+		for _, usage := range discarded {
+			if lot, err := pantryItem.Lot(usage.LotID); err != nil || lot.Quantity == 0 {
+				emptied = append(emptied, usage.LotID)
+			}
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrLotNotFound):
+			http.Error(w, "Lot not found", http.StatusNotFound)
+		case errors.Is(err, models.ErrNothingToDiscard), errors.Is(err, models.ErrNotEnoughQuantity):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			log.Printf("Failed to discard pantry item: %v", err)
+			http.Error(w, "Failed to discard pantry item", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// The expiry reminders are cleared once the discard is committed; they are not critical
+	if len(emptied) > 0 {
+		_, err = config.DB.Collection("notifications").DeleteMany(
+			context.Background(),
+			bson.M{
+				"pantry.item_id": pantryItem.ID,
+				"pantry.lot_id":  bson.M{"$in": emptied},
+				"type": bson.M{"$in": []models.NotificationType{
+					models.NotificationTypeExpiringSoon,
+					models.NotificationTypeExpired,
+				}},
+			},
+		)
+		if err != nil {
+			log.Printf("Failed to delete expiry notifications: %v", err)
+		}
+	}
+
+	publishGroupEvent(r, pantryItem.GroupID, realtime.EventPantryItemDiscarded, map[string]interface{}{
+		"item_id":            pantryItem.ID,
+		"discarded_quantity": quantity,
+		"remaining_quantity": pantryItem.Quantity,
+		"unit":               pantryItem.Unit,
+		"reason":             reason,
+		"value":              value,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Success      bool                 `json:"success"`
+		Message      string               `json:"message"`
+		Discarded    float64              `json:"discarded_quantity"` // In the item's unit
+		RemainingQty float64              `json:"remaining_quantity"`
+		Unit         string               `json:"unit"`
+		Reason       models.DiscardReason `json:"reason"`
+		Value        *float64             `json:"value,omitempty"`
+		Lots         []models.LotUsage    `json:"lots"` // How much came out of each lot
+	}{
+		Success:      true,
+		Message:      "Item discarded successfully",
+		Discarded:    quantity,
+		RemainingQty: pantryItem.Quantity,
+		Unit:         pantryItem.Unit,
+		Reason:       reason,
+		Value:        value,
+		Lots:         discarded,
+	})
+}
+
+// GetWasteReportHandler reports how much of a group's pantry stock was thrown away: the
+// quantity, value and number of discards by item, category, member and reason, and over time.
+// Periods are bucketed by day, week (default) or month in the group's time zone; from and to
+// narrow the window.
+func GetWasteReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	groupName := query.Get("group_name")
+	if groupName == "" {
+		http.Error(w, "group_name parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	bucket := models.AnalyticsBucket(query.Get("bucket"))
+	if bucket == "" {
+		bucket = models.AnalyticsBucketWeek
+	}
+	if !bucket.IsValid() {
+		http.Error(w, "Invalid bucket. Must be day, week or month", http.StatusBadRequest)
+		return
+	}
+
+	var from time.Time
+	if value := query.Get("from"); value != "" {
+		parsed, err := parseCalendarDate(value)
+		if err != nil {
+			http.Error(w, "Invalid from date. Use RFC3339 or YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	to := time.Now()
+	if value := query.Get("to"); value != "" {
+		parsed, err := parseCalendarDate(value)
+		if err != nil {
+			http.Error(w, "Invalid to date. Use RFC3339 or YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+
+	_, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	loc, err := models.LoadGroupLocation(group.Timezone)
+	if err != nil {
+		log.Printf("Group %s has an invalid time zone %q, using UTC: %v", group.ID.Hex(), group.Timezone, err)
+		loc = time.UTC
+	}
+
+	start, end, err := models.AnalyticsWindow(bucket, from, to, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	cursor, err := config.DB.Collection("pantry_history").Find(ctx, bson.M{
+		"group_id":   group.ID,
+		"action":     models.ActionTypeDiscard,
+		"created_at": bson.M{"$gte": start, "$lt": end},
+	})
+	if err != nil {
+		http.Error(w, "Failed to fetch pantry history", http.StatusInternalServerError)
+		return
+	}
+	var records []models.PantryHistory
+	if err := cursor.All(ctx, &records); err != nil {
+		http.Error(w, "Failed to decode pantry history", http.StatusInternalServerError)
+		return
+	}
+
+	categoryNames, err := loadCategoryNames(ctx, records)
+	if err != nil {
+		log.Printf("Failed to load pantry categories: %v", err)
+		http.Error(w, "Failed to compute waste report", http.StatusInternalServerError)
+		return
+	}
+
+	report := models.BuildWasteReport(records, categoryNames, bucket, start, end, loc)
+	report.GroupID = group.ID
+	report.GeneratedAt = time.Now()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// loadCategoryNames loads the names of the categories discarded items were in
+func loadCategoryNames(ctx context.Context, records []models.PantryHistory) (map[primitive.ObjectID]string, error) {
+	names := make(map[primitive.ObjectID]string)
+	var categoryIDs []primitive.ObjectID
+	for _, record := range records {
+		if record.CategoryID == nil {
+			continue
+		}
+		if _, seen := names[*record.CategoryID]; !seen {
+			names[*record.CategoryID] = ""
+			categoryIDs = append(categoryIDs, *record.CategoryID)
+		}
+	}
+	if len(categoryIDs) == 0 {
+		return names, nil
+	}
+
+	cursor, err := config.DB.Collection("pantry_categories").Find(ctx, bson.M{"_id": bson.M{"$in": categoryIDs}})
+	if err != nil {
+		return nil, err
+	}
+	var categories []models.PantryCategory
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, err
+	}
+
+	// Categories deleted since count as uncategorized
+	names = make(map[primitive.ObjectID]string, len(categories))
+	for _, category := range categories {
+		names[category.ID] = category.Name
+	}
+	return names, nil
+}
+
+// GetDiscardPromptsHandler lists the expired lots of a group's pantry the member may throw
+// away, longest expired first, with what each is estimated to be worth
+func GetDiscardPromptsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupName := r.URL.Query().Get("group_name")
+	if groupName == "" {
+		http.Error(w, "group_name parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	user, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	ctx := context.Background()
+	cursor, err := config.DB.Collection("pantry_items").Find(ctx, bson.M{
+		"group_id":             group.ID,
+		"lots.expiration_date": bson.M{"$lt": now},
+	})
+	if err != nil {
+		http.Error(w, "Failed to fetch pantry items", http.StatusInternalServerError)
+		return
+	}
+	var items []models.PantryItem
+	if err := cursor.All(ctx, &items); err != nil {
+		http.Error(w, "Failed to decode pantry items", http.StatusInternalServerError)
+		return
+	}
+
+	prompts := make([]DiscardPrompt, 0)
+	for i := range items {
+		item := &items[i]
+		if !item.CanDelete(user.ID) {
+			continue
+		}
+		for _, lot := range item.Lots {
+			if lot.Quantity <= 0 || lot.ExpirationDate.IsZero() || !lot.ExpirationDate.Before(now) {
+				continue
+			}
+			prompts = append(prompts, DiscardPrompt{
+				ItemID:              item.ID,
+				ItemName:            item.Name,
+				LotID:               lot.ID,
+				Quantity:            lot.Quantity,
+				Unit:                item.Unit,
+				ExpirationDate:      lot.ExpirationDate,
+				ExpirationEstimated: lot.ExpirationEstimated,
+				EstimatedValue:      item.EstimateValue(lot.Quantity),
+				Reason:              models.DiscardReasonExpired,
+			})
+		}
+	}
+	sort.SliceStable(prompts, func(i, j int) bool {
+		if !prompts[i].ExpirationDate.Equal(prompts[j].ExpirationDate) {
+			return prompts[i].ExpirationDate.Before(prompts[j].ExpirationDate)
+		}
+		return strings.ToLower(prompts[i].ItemName) < strings.ToLower(prompts[j].ItemName)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prompts)
+}
@@ -0,0 +1,143 @@
+// handlers/product.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/catalog"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrProductNotFound is returned when no catalog product has a barcode
+var ErrProductNotFound = errors.New("no product found for this barcode")
+
+// ProductLookupResponse is a catalog product with its category resolved for the user's group
+// and the expiration date a package bought now would get
+type ProductLookupResponse struct {
+	models.Product
+	CategoryID          primitive.ObjectID `json:"category_id"`
+	EstimatedExpiration *time.Time         `json:"estimated_expiration,omitempty"`
+}
+
+// findProductByBarcode looks up a catalog product by any form of its barcode
+func findProductByBarcode(ctx context.Context, barcode string) (*models.Product, error) {
+	gtin, err := catalog.NormalizeGTIN(barcode)
+	if err != nil {
+		return nil, err
+	}
+
+	var product models.Product
+	err = config.DB.Collection("products").FindOne(ctx, bson.M{"gtin": gtin}).Decode(&product)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+	return &product, nil
+}
+
+// writeProductLookupError writes the response for a failed barcode lookup
+func writeProductLookupError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, catalog.ErrInvalidGTIN):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrProductNotFound):
+		http.Error(w, "No product found for this barcode", http.StatusNotFound)
+	default:
+		log.Printf("Failed to look up product: %v", err)
+		http.Error(w, "Failed to look up product", http.StatusInternalServerError)
+	}
+}
+
+// resolveProductCategory finds the pantry category for a product in a group: the group's own
+// category with the product's category name, else the predefined one, else Other
+func resolveProductCategory(ctx context.Context, product *models.Product, groupID primitive.ObjectID) (*models.PantryCategory, error) {
+	for _, name := range []string{product.DefaultCategory, catalog.DefaultCategory} {
+		var categories []models.PantryCategory
+		cursor, err := config.DB.Collection("pantry_categories").Find(ctx, bson.M{
+			"name":      name,
+			"is_active": true,
+			"$or": []bson.M{
+				{"type": models.CategoryTypePredefined},
+				{"type": models.CategoryTypeCustom, "group_id": groupID},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := cursor.All(ctx, &categories); err != nil {
+			return nil, err
+		}
+
+		for i := range categories {
+			if !categories[i].IsPredefined() {
+				return &categories[i], nil
+			}
+		}
+		if len(categories) > 0 {
+			return &categories[0], nil
+		}
+	}
+	return nil, errors.New("no category found for this product")
+}
+
+// GetProductByBarcodeHandler looks up a catalog product by barcode
+func GetProductByBarcodeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	barcode := r.URL.Query().Get("barcode")
+	if barcode == "" {
+		http.Error(w, "barcode is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	err := config.DB.Collection("users").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	product, err := findProductByBarcode(context.Background(), barcode)
+	if err != nil {
+		writeProductLookupError(w, err)
+		return
+	}
+
+	response := ProductLookupResponse{Product: *product}
+	category, err := resolveProductCategory(context.Background(), product, user.GroupID)
+	if err != nil {
+		log.Printf("Failed to resolve product category: %v", err)
+	} else {
+		response.CategoryID = category.ID
+		expiration := estimateExpiration(context.Background(), user.GroupID, category.ID, &product.ID, false)
+		if !expiration.IsZero() {
+			response.EstimatedExpiration = &expiration
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,187 @@
+// handlers/push.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/jobs"
+	"cribb-backend/models"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PushSubscribeRequest is a browser PushSubscription as serialized by toJSON()
+type PushSubscribeRequest struct {
+	Endpoint       string                      `json:"endpoint" validate:"required"`
+	ExpirationTime *int64                      `json:"expirationTime"` // Milliseconds since the epoch
+	Keys           models.PushSubscriptionKeys `json:"keys" validate:"required"`
+}
+
+// PushUnsubscribeRequest identifies the device to stop pushing to
+type PushUnsubscribeRequest struct {
+	Endpoint string `json:"endpoint" validate:"required"`
+}
+
+// VAPIDPublicKeyResponse tells the frontend whether push is available and which key to
+// subscribe with
+type VAPIDPublicKeyResponse struct {
+	Enabled   bool   `json:"enabled"`
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+// GetVAPIDPublicKeyHandler returns the application server key browsers subscribe with
+func GetVAPIDPublicKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := VAPIDPublicKeyResponse{}
+	if sender, err := jobs.WebPush(); err == nil && sender != nil {
+		response.Enabled = true
+		response.PublicKey = sender.Keys().PublicKey
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// SubscribePushHandler registers the current device for Web Push
+func SubscribePushHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !config.WebPushEnabled() {
+		http.Error(w, "Web Push is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var request PushSubscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := models.ValidatePushEndpoint(request.Endpoint); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, _, err := request.Keys.Decode(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	var expiresAt *time.Time
+	if request.ExpirationTime != nil {
+		expiry := time.UnixMilli(*request.ExpirationTime)
+		expiresAt = &expiry
+	}
+
+	var subscription models.PushSubscription
+	err := config.DB.Collection("push_subscriptions").FindOneAndUpdate(
+		context.Background(),
+		bson.M{"endpoint": request.Endpoint},
+		bson.M{
+			"$set": bson.M{
+				"user_id":       userID,
+				"keys":          request.Keys,
+				"user_agent":    r.UserAgent(),
+				"expires_at":    expiresAt,
+				"failure_count": 0,
+				"updated_at":    now,
+			},
+			"$setOnInsert": bson.M{"created_at": now},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&subscription)
+	if err != nil {
+		log.Printf("Failed to save push subscription: %v", err)
+		http.Error(w, "Failed to save push subscription", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// UnsubscribePushHandler stops pushing to one of the current user's devices
+func UnsubscribePushHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var request PushUnsubscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := config.DB.Collection("push_subscriptions").DeleteOne(
+		context.Background(),
+		bson.M{"endpoint": request.Endpoint, "user_id": userID},
+	)
+	if err != nil {
+		log.Printf("Failed to delete push subscription: %v", err)
+		http.Error(w, "Failed to delete push subscription", http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		http.Error(w, "Push subscription not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Push subscription removed"})
+}
+
+// GetPushSubscriptionsHandler lists the devices the current user receives pushes on
+func GetPushSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	cursor, err := config.DB.Collection("push_subscriptions").Find(
+		context.Background(),
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		log.Printf("Failed to fetch push subscriptions: %v", err)
+		http.Error(w, "Failed to fetch push subscriptions", http.StatusInternalServerError)
+		return
+	}
+	subscriptions := []models.PushSubscription{}
+	if err = cursor.All(context.Background(), &subscriptions); err != nil {
+		log.Printf("Failed to decode push subscriptions: %v", err)
+		http.Error(w, "Failed to decode push subscriptions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscriptions)
+}
@@ -0,0 +1,44 @@
+// handlers/realtime.go
+package handlers
+
+import (
+	"cribb-backend/jobs"
+	"cribb-backend/middleware"
+	"cribb-backend/realtime"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GroupEventsHandler streams a group's chore, pantry and cart changes as Server-Sent
+// Events. EventSource clients pass their token as access_token; see StreamAuthMiddleware.
+func GroupEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupName := r.URL.Query().Get("group_name")
+	if groupName == "" {
+		http.Error(w, "Group name is required", http.StatusBadRequest)
+		return
+	}
+
+	_, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	jobs.RealtimeHub().ServeGroup(w, r, group.ID, realtime.HeartbeatInterval)
+}
+
+// publishGroupEvent broadcasts a change made by the requesting user to their group
+func publishGroupEvent(r *http.Request, groupID primitive.ObjectID, eventType realtime.EventType, data interface{}) {
+	var actorID *primitive.ObjectID
+	if claims, ok := middleware.GetUserFromContext(r.Context()); ok {
+		if id, err := primitive.ObjectIDFromHex(claims.ID); err == nil {
+			actorID = &id
+		}
+	}
+	jobs.PublishGroupEvent(groupID, eventType, actorID, data)
+}
@@ -0,0 +1,510 @@
+// handlers/recipe.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"cribb-backend/units"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RecipeIngredientRequest defines an ingredient of a recipe. An ingredient linked to a
+// catalog product may leave out its name and unit to take the product's.
+type RecipeIngredientRequest struct {
+	Name      string  `json:"name,omitempty"`
+	Quantity  float64 `json:"quantity"`
+	Unit      string  `json:"unit,omitempty"`
+	ProductID string  `json:"product_id,omitempty"`
+}
+
+// CreateRecipeRequest defines the request structure for creating a recipe
+type CreateRecipeRequest struct {
+	GroupName    string                    `json:"group_name" validate:"required"`
+	Name         string                    `json:"name" validate:"required"`
+	Servings     int                       `json:"servings" validate:"required,min=1"`
+	Ingredients  []RecipeIngredientRequest `json:"ingredients" validate:"required"`
+	Instructions string                    `json:"instructions,omitempty"`
+}
+
+// UpdateRecipeRequest defines the request structure for updating a recipe. The ingredients
+// given replace the recipe's.
+type UpdateRecipeRequest struct {
+	RecipeID     string                    `json:"recipe_id" validate:"required"`
+	Name         string                    `json:"name" validate:"required"`
+	Servings     int                       `json:"servings" validate:"required,min=1"`
+	Ingredients  []RecipeIngredientRequest `json:"ingredients" validate:"required"`
+	Instructions string                    `json:"instructions,omitempty"`
+}
+
+// CookRecipeRequest defines the request structure for cooking a recipe. Cooking a planned
+// meal takes the recipe and servings from the meal plan entry.
+type CookRecipeRequest struct {
+	RecipeID string `json:"recipe_id,omitempty"`
+	Servings int    `json:"servings,omitempty"` // Left out, the recipe's own servings
+	EntryID  string `json:"entry_id,omitempty"`
+}
+
+// parseIngredients checks the ingredients of a recipe, normalizing their units and filling in
+// the name and unit of those linked to a catalog product
+func parseIngredients(ctx context.Context, requests []RecipeIngredientRequest) ([]models.RecipeIngredient, error) {
+	ingredients := make([]models.RecipeIngredient, 0, len(requests))
+	for _, request := range requests {
+		ingredient := models.RecipeIngredient{
+			Name:     strings.TrimSpace(request.Name),
+			Quantity: request.Quantity,
+		}
+
+		if request.ProductID != "" {
+			productID, err := primitive.ObjectIDFromHex(request.ProductID)
+			if err != nil {
+				return nil, errors.New("invalid product ID format")
+			}
+			var product models.Product
+			err = config.DB.Collection("products").FindOne(ctx, bson.M{"_id": productID}).Decode(&product)
+			if err != nil {
+				if errors.Is(err, mongo.ErrNoDocuments) {
+					return nil, errors.New("product not found")
+				}
+				return nil, err
+			}
+			ingredient.ProductID = &product.ID
+			if ingredient.Name == "" {
+				ingredient.Name = product.Name
+			}
+			if request.Unit == "" {
+				request.Unit = product.DefaultUnit
+			}
+		}
+
+		if request.Unit != "" {
+			unit, err := units.Normalize(request.Unit)
+			if err != nil {
+				return nil, err
+			}
+			ingredient.Unit = unit
+		}
+		ingredients = append(ingredients, ingredient)
+	}
+	return ingredients, nil
+}
+
+// findRecipe loads one of the group's recipes
+func findRecipe(ctx context.Context, groupID, recipeID primitive.ObjectID) (*models.Recipe, error) {
+	var recipe models.Recipe
+	err := config.DB.Collection("recipes").FindOne(ctx, bson.M{"_id": recipeID, "group_id": groupID}).Decode(&recipe)
+	if err != nil {
+		return nil, err
+	}
+	return &recipe, nil
+}
+
+// usablePantryItems loads the group's pantry items in stock that the user can use
+func usablePantryItems(ctx context.Context, user *models.User) ([]models.PantryItem, error) {
+	cursor, err := config.DB.Collection("pantry_items").Find(ctx, bson.M{
+		"group_id": user.GroupID,
+		"quantity": bson.M{"$gt": 0},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var items []models.PantryItem
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+
+	usable := make([]models.PantryItem, 0, len(items))
+	for _, item := range items {
+		if item.CanUse(user.ID) {
+			usable = append(usable, item)
+		}
+	}
+	return usable, nil
+}
+
+// parseServings reads the optional servings query parameter
+func parseServings(r *http.Request) (int, error) {
+	servingsStr := r.URL.Query().Get("servings")
+	if servingsStr == "" {
+		return 0, nil
+	}
+	servings, err := strconv.Atoi(servingsStr)
+	if err != nil || servings < 1 {
+		return 0, errors.New("servings must be a positive number")
+	}
+	return servings, nil
+}
+
+// GetRecipesHandler lists a group's recipes
+func GetRecipesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupName := r.URL.Query().Get("group_name")
+	if groupName == "" {
+		http.Error(w, "group_name parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	_, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	cursor, err := config.DB.Collection("recipes").Find(ctx,
+		bson.M{"group_id": group.ID},
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}}),
+	)
+	if err != nil {
+		http.Error(w, "Failed to fetch recipes", http.StatusInternalServerError)
+		return
+	}
+	recipes := []models.Recipe{}
+	if err := cursor.All(ctx, &recipes); err != nil {
+		http.Error(w, "Failed to decode recipes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recipes)
+}
+
+// CreateRecipeHandler creates a recipe for a group
+func CreateRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request CreateRecipeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, group, ok := findGroupForMember(w, r, request.GroupName)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	ingredients, err := parseIngredients(ctx, request.Ingredients)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(request.Name)
+	if err := models.ValidateRecipe(name, request.Servings, ingredients); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recipe := models.CreateRecipe(group.ID, name, request.Servings, ingredients, request.Instructions, user.ID)
+	result, err := config.DB.Collection("recipes").InsertOne(ctx, recipe)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "A recipe with this name already exists", http.StatusConflict)
+			return
+		}
+		log.Printf("Failed to create recipe: %v", err)
+		http.Error(w, "Failed to create recipe", http.StatusInternalServerError)
+		return
+	}
+	recipe.ID = result.InsertedID.(primitive.ObjectID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(recipe)
+}
+
+// UpdateRecipeHandler updates a recipe's name, servings, ingredients and instructions
+func UpdateRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request UpdateRecipeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	recipe, _, ok := loadRecipeForMember(w, r, request.RecipeID)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	ingredients, err := parseIngredients(ctx, request.Ingredients)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(request.Name)
+	if err := models.ValidateRecipe(name, request.Servings, ingredients); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recipe.Name = name
+	recipe.Servings = request.Servings
+	recipe.Ingredients = ingredients
+	recipe.Instructions = request.Instructions
+	recipe.UpdatedAt = time.Now()
+
+	_, err = config.DB.Collection("recipes").UpdateOne(
+		ctx,
+		bson.M{"_id": recipe.ID},
+		bson.M{"$set": bson.M{
+			"name":         recipe.Name,
+			"servings":     recipe.Servings,
+			"ingredients":  recipe.Ingredients,
+			"instructions": recipe.Instructions,
+			"updated_at":   recipe.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "A recipe with this name already exists", http.StatusConflict)
+			return
+		}
+		log.Printf("Failed to update recipe: %v", err)
+		http.Error(w, "Failed to update recipe", http.StatusInternalServerError)
+		return
+	}
+
+	// Keep the name shown on planned meals current
+	_, err = config.DB.Collection("meal_plan").UpdateMany(
+		ctx,
+		bson.M{"recipe_id": recipe.ID},
+		bson.M{"$set": bson.M{"recipe_name": recipe.Name}},
+	)
+	if err != nil {
+		log.Printf("Failed to update meal plan entries: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recipe)
+}
+
+// DeleteRecipeHandler deletes a recipe along with its meals that are planned but not cooked
+func DeleteRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	recipe, _, ok := loadRecipeForMember(w, r, r.URL.Query().Get("recipe_id"))
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	if _, err := config.DB.Collection("recipes").DeleteOne(ctx, bson.M{"_id": recipe.ID}); err != nil {
+		log.Printf("Failed to delete recipe: %v", err)
+		http.Error(w, "Failed to delete recipe", http.StatusInternalServerError)
+		return
+	}
+
+	_, err := config.DB.Collection("meal_plan").DeleteMany(ctx, bson.M{
+		"recipe_id": recipe.ID,
+		"cooked_at": bson.M{"$exists": false},
+	})
+	if err != nil {
+		log.Printf("Failed to delete planned meals of recipe: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Recipe deleted successfully"})
+}
+
+// GetRecipeCoverageHandler checks how much of a recipe's ingredients the pantry holds for the
+// user, for the recipe's servings or those given
+func GetRecipeCoverageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	servings, err := parseServings(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recipe, user, ok := loadRecipeForMember(w, r, r.URL.Query().Get("recipe_id"))
+	if !ok {
+		return
+	}
+
+	items, err := usablePantryItems(context.Background(), &user)
+	if err != nil {
+		http.Error(w, "Failed to fetch pantry items", http.StatusInternalServerError)
+		return
+	}
+	coverage := models.CheckCoverage(models.CombineIngredients(recipe.ScaledIngredients(servings)), items)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"recipe_id":   recipe.ID,
+		"covered":     len(models.MissingIngredients(coverage)) == 0,
+		"ingredients": coverage,
+	})
+}
+
+// CookRecipeHandler cooks a recipe, or a planned meal, taking every ingredient from the
+// pantry in a single transaction through the same path as using an item, so each ingredient
+// is recorded in the pantry history. Nothing is taken unless the pantry holds all of them.
+func CookRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request CookRecipeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.Servings < 0 {
+		http.Error(w, "servings must be a positive number", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	var entry *models.MealPlanEntry
+	if request.EntryID != "" {
+		var ok bool
+		entry, ok = loadMealPlanEntryForMember(w, r, request.EntryID)
+		if !ok {
+			return
+		}
+		if entry.IsCooked() {
+			http.Error(w, "This meal has already been cooked", http.StatusConflict)
+			return
+		}
+		request.RecipeID = entry.RecipeID.Hex()
+		request.Servings = entry.Servings
+	}
+	if request.RecipeID == "" {
+		http.Error(w, "recipe_id or entry_id is required", http.StatusBadRequest)
+		return
+	}
+
+	recipe, user, ok := loadRecipeForMember(w, r, request.RecipeID)
+	if !ok {
+		return
+	}
+
+	items, err := usablePantryItems(ctx, &user)
+	if err != nil {
+		http.Error(w, "Failed to fetch pantry items", http.StatusInternalServerError)
+		return
+	}
+	coverage := models.CheckCoverage(models.CombineIngredients(recipe.ScaledIngredients(request.Servings)), items)
+	if len(models.MissingIngredients(coverage)) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":     "The pantry does not hold all of the ingredients",
+			"ingredients": coverage,
+		})
+		return
+	}
+
+	// Start a transaction
+	session, err := config.DB.Client().StartSession()
+	if err != nil {
+		log.Printf("Failed to start MongoDB session: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer session.EndSession(ctx)
+
+	var uses []*pantryUse
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		uses = nil
+		for _, covered := range coverage {
+			for _, allocation := range covered.Items {
+				use, err := usePantryItem(sc, allocation.ItemID, &user, allocation.Quantity, "")
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", covered.Name, err)
+				}
+				uses = append(uses, use)
+			}
+		}
+
+		if entry != nil {
+			now := time.Now()
+			result, err := config.DB.Collection("meal_plan").UpdateOne(
+				sc,
+				bson.M{"_id": entry.ID, "cooked_at": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"cooked_at": now, "cooked_by": user.ID}},
+			)
+			if err != nil {
+				return nil, err
+			}
+			if result.MatchedCount == 0 {
+				return nil, errMealAlreadyCooked
+			}
+			entry.CookedAt = &now
+			entry.CookedBy = &user.ID
+		}
+		return nil, nil
+	})
+
+	if err != nil {
+		log.Printf("Transaction failed: %v", err)
+		switch {
+		case errors.Is(err, errMealAlreadyCooked):
+			http.Error(w, "This meal has already been cooked", http.StatusConflict)
+		case errors.Is(err, models.ErrUseNotPermitted):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	details := "Used for " + recipe.Name
+	for _, use := range uses {
+		recordPantryUse(r, &user, use, details)
+	}
+
+	used := make([]map[string]interface{}, 0, len(uses))
+	for _, use := range uses {
+		used = append(used, map[string]interface{}{
+			"item_id":            use.Item.ID,
+			"item_name":          use.Item.Name,
+			"used_quantity":      use.Quantity,
+			"remaining_quantity": use.Item.Quantity,
+			"unit":               use.Item.Unit,
+			"lots":               use.Lots,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Recipe cooked successfully",
+		"recipe":  recipe.Name,
+		"used":    used,
+		"entry":   entry,
+	})
+}
@@ -0,0 +1,72 @@
+// handlers/reminder.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/jobs"
+	"cribb-backend/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ReminderPreferencesHandler returns the current user's chore reminder preferences on GET
+// and updates them on POST. Fields left out of the body keep their current values.
+func ReminderPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	err := config.DB.Collection("users").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			log.Printf("Failed to fetch user: %v", err)
+			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	prefs := user.ReminderSettings()
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if prefs.Channels == nil {
+			prefs.Channels = []models.ReminderChannel{}
+		}
+		if err := prefs.Validate(jobs.ReminderDispatcher().Channels()); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		_, err = config.DB.Collection("users").UpdateOne(
+			context.Background(),
+			bson.M{"_id": userID},
+			bson.M{"$set": bson.M{"reminder_preferences": prefs, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			log.Printf("Failed to update reminder preferences: %v", err)
+			http.Error(w, "Failed to update reminder preferences", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
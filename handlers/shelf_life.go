@@ -0,0 +1,319 @@
+// handlers/shelf_life.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SetShelfLifeRuleRequest defines the request structure for setting a group's own shelf life
+// for a category or a catalog product. Exactly one of category_id and product_id is given.
+type SetShelfLifeRuleRequest struct {
+	GroupName         string  `json:"group_name" validate:"required"`
+	CategoryID        string  `json:"category_id,omitempty"`
+	ProductID         string  `json:"product_id,omitempty"`
+	Days              int     `json:"days" validate:"required,min=1"`
+	FreezerMultiplier float64 `json:"freezer_multiplier,omitempty"`
+}
+
+// ShelfLifeRuleView is a shelf-life rule with the names of what it applies to
+type ShelfLifeRuleView struct {
+	models.ShelfLifeRule
+	CategoryName string `json:"category_name,omitempty"`
+	ProductName  string `json:"product_name,omitempty"`
+	Overridden   bool   `json:"overridden"` // A seeded rule the group has its own rule for
+}
+
+// findShelfLife resolves the shelf life of an item of a category, and of a catalog product if
+// productID is set, from the seeded rules and the group's own
+func findShelfLife(ctx context.Context, groupID, categoryID primitive.ObjectID, productID *primitive.ObjectID) (models.ShelfLife, bool, error) {
+	var product *models.Product
+	targets := []bson.M{{"category_id": categoryID}}
+	if productID != nil {
+		var found models.Product
+		err := config.DB.Collection("products").FindOne(ctx, bson.M{"_id": *productID}).Decode(&found)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return models.ShelfLife{}, false, err
+		}
+		if err == nil {
+			product = &found
+		}
+		targets = append(targets, bson.M{"product_id": *productID})
+	}
+
+	cursor, err := config.DB.Collection("shelf_life_rules").Find(ctx, bson.M{
+		"$and": []bson.M{
+			{"$or": []bson.M{{"group_id": groupID}, {"group_id": bson.M{"$exists": false}}}},
+			{"$or": targets},
+		},
+	})
+	if err != nil {
+		return models.ShelfLife{}, false, err
+	}
+	var rules []models.ShelfLifeRule
+	if err := cursor.All(ctx, &rules); err != nil {
+		return models.ShelfLife{}, false, err
+	}
+
+	if product == nil && productID != nil {
+		product = &models.Product{ID: *productID}
+	}
+	shelfLife, found := models.ResolveShelfLife(rules, groupID, categoryID, product)
+	return shelfLife, found, nil
+}
+
+// estimateExpiration estimates when an item stored now, frozen or not, expires from its shelf
+// life. It returns the zero time if the shelf life is unknown or cannot be loaded.
+func estimateExpiration(ctx context.Context, groupID, categoryID primitive.ObjectID, productID *primitive.ObjectID, frozen bool) time.Time {
+	shelfLife, found, err := findShelfLife(ctx, groupID, categoryID, productID)
+	if err != nil {
+		log.Printf("Failed to load shelf-life rules: %v", err)
+		return time.Time{}
+	}
+	if !found {
+		return time.Time{}
+	}
+	if frozen {
+		return time.Now().AddDate(0, 0, shelfLife.FrozenDays())
+	}
+	return shelfLife.ExpirationFrom(time.Now())
+}
+
+// GetShelfLifeRulesHandler lists the shelf-life rules that apply to a group: the seeded rules,
+// marked when overridden, and the group's own
+func GetShelfLifeRulesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupName := r.URL.Query().Get("group_name")
+	if groupName == "" {
+		http.Error(w, "group_name parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	_, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	cursor, err := config.DB.Collection("shelf_life_rules").Find(ctx,
+		bson.M{"$or": []bson.M{{"group_id": group.ID}, {"group_id": bson.M{"$exists": false}}}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		http.Error(w, "Failed to fetch shelf-life rules", http.StatusInternalServerError)
+		return
+	}
+	var rules []models.ShelfLifeRule
+	if err := cursor.All(ctx, &rules); err != nil {
+		http.Error(w, "Failed to decode shelf-life rules", http.StatusInternalServerError)
+		return
+	}
+
+	// Look up the names of the categories and products the rules apply to, and what the
+	// group has overridden
+	var categoryIDs, productIDs []primitive.ObjectID
+	overrides := make(map[primitive.ObjectID]bool)
+	for _, rule := range rules {
+		if rule.CategoryID != nil {
+			categoryIDs = append(categoryIDs, *rule.CategoryID)
+			if rule.GroupID != nil {
+				overrides[*rule.CategoryID] = true
+			}
+		}
+		if rule.ProductID != nil {
+			productIDs = append(productIDs, *rule.ProductID)
+			if rule.GroupID != nil {
+				overrides[*rule.ProductID] = true
+			}
+		}
+	}
+
+	categoryNames := make(map[primitive.ObjectID]string)
+	var categories []models.PantryCategory
+	cursor, err = config.DB.Collection("pantry_categories").Find(ctx, bson.M{"_id": bson.M{"$in": categoryIDs}})
+	if err == nil {
+		err = cursor.All(ctx, &categories)
+	}
+	if err != nil {
+		log.Printf("Failed to load shelf-life rule categories: %v", err)
+	}
+	for _, category := range categories {
+		categoryNames[category.ID] = category.Name
+	}
+
+	productNames := make(map[primitive.ObjectID]string)
+	if len(productIDs) > 0 {
+		var products []models.Product
+		cursor, err = config.DB.Collection("products").Find(ctx, bson.M{"_id": bson.M{"$in": productIDs}})
+		if err == nil {
+			err = cursor.All(ctx, &products)
+		}
+		if err != nil {
+			log.Printf("Failed to load shelf-life rule products: %v", err)
+		}
+		for _, product := range products {
+			productNames[product.ID] = product.Name
+		}
+	}
+
+	response := make([]ShelfLifeRuleView, 0, len(rules))
+	for _, rule := range rules {
+		view := ShelfLifeRuleView{ShelfLifeRule: rule}
+		if rule.CategoryID != nil {
+			view.CategoryName = categoryNames[*rule.CategoryID]
+			view.Overridden = rule.GroupID == nil && overrides[*rule.CategoryID]
+		}
+		if rule.ProductID != nil {
+			view.ProductName = productNames[*rule.ProductID]
+			view.Overridden = rule.GroupID == nil && overrides[*rule.ProductID]
+		}
+		response = append(response, view)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// SetShelfLifeRuleHandler sets the group's own shelf life for a category or a catalog product,
+// overriding the seeded rule
+func SetShelfLifeRuleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request SetShelfLifeRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if (request.CategoryID == "") == (request.ProductID == "") {
+		http.Error(w, "Exactly one of category_id and product_id is required", http.StatusBadRequest)
+		return
+	}
+	if err := models.ValidateShelfLifeRule(request.Days, request.FreezerMultiplier); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, group, ok := findGroupForMember(w, r, request.GroupName)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	filter := bson.M{"group_id": group.ID}
+	if request.CategoryID != "" {
+		category, err := validateCategoryID(request.CategoryID, group.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter["category_id"] = category.ID
+	} else {
+		productID, err := primitive.ObjectIDFromHex(request.ProductID)
+		if err != nil {
+			http.Error(w, "Invalid product ID format", http.StatusBadRequest)
+			return
+		}
+		count, err := config.DB.Collection("products").CountDocuments(ctx, bson.M{"_id": productID})
+		if err != nil {
+			http.Error(w, "Failed to fetch product", http.StatusInternalServerError)
+			return
+		}
+		if count == 0 {
+			http.Error(w, "Product not found", http.StatusNotFound)
+			return
+		}
+		filter["product_id"] = productID
+	}
+
+	now := time.Now()
+	set := bson.M{"days": request.Days, "updated_at": now}
+	update := bson.M{
+		"$set":         set,
+		"$setOnInsert": bson.M{"created_by": user.ID, "created_at": now},
+	}
+	if request.FreezerMultiplier != 0 {
+		set["freezer_multiplier"] = request.FreezerMultiplier
+	} else {
+		update["$unset"] = bson.M{"freezer_multiplier": ""}
+	}
+
+	var rule models.ShelfLifeRule
+	err := config.DB.Collection("shelf_life_rules").FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&rule)
+	if err != nil {
+		log.Printf("Failed to set shelf-life rule: %v", err)
+		http.Error(w, "Failed to set shelf-life rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// DeleteShelfLifeRuleHandler removes one of the group's own shelf-life rules, going back to
+// the seeded rule
+func DeleteShelfLifeRuleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ruleID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("rule_id"))
+	if err != nil {
+		http.Error(w, "Invalid rule ID format", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	var rule models.ShelfLifeRule
+	err = config.DB.Collection("shelf_life_rules").FindOne(ctx, bson.M{"_id": ruleID}).Decode(&rule)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Shelf-life rule not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch shelf-life rule", http.StatusInternalServerError)
+		}
+		return
+	}
+	if rule.GroupID == nil {
+		http.Error(w, "Seeded shelf-life rules cannot be deleted; set the group's own rule instead", http.StatusForbidden)
+		return
+	}
+	if _, ok := verifyGroupMember(w, userID, *rule.GroupID); !ok {
+		return
+	}
+
+	if _, err := config.DB.Collection("shelf_life_rules").DeleteOne(ctx, bson.M{"_id": rule.ID}); err != nil {
+		log.Printf("Failed to delete shelf-life rule: %v", err)
+		http.Error(w, "Failed to delete shelf-life rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Shelf-life rule deleted successfully"})
+}
@@ -4,8 +4,12 @@ package handlers
 import (
 	"context"
 	"cribb-backend/config"
+	"cribb-backend/jobs"
 	"cribb-backend/middleware"
 	"cribb-backend/models"
+	"cribb-backend/query"
+	"cribb-backend/realtime"
+	"cribb-backend/units"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,13 +21,13 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // AddShoppingCartItemRequest defines the request structure for adding a shopping cart item
 type AddShoppingCartItemRequest struct {
 	ItemName string  `json:"item_name" validate:"required,min=1"`
 	Quantity float64 `json:"quantity" validate:"required,min=0.1"`
+	Unit     string  `json:"unit,omitempty"`
 	Category string  `json:"category"`
 }
 
@@ -32,6 +36,7 @@ type UpdateShoppingCartItemRequest struct {
 	ItemID   string  `json:"item_id" validate:"required"`
 	ItemName string  `json:"item_name,omitempty" validate:"min=1"`
 	Quantity float64 `json:"quantity,omitempty" validate:"min=0.1"`
+	Unit     string  `json:"unit,omitempty"`
 	Category string  `json:"category,omitempty"`
 }
 
@@ -42,60 +47,25 @@ type ShoppingCartResponse struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
-// AddShoppingCartItemHandler handles adding an item to the shopping cart
-func AddShoppingCartItemHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Get user from context (set by AuthMiddleware)
-	userClaims, ok := middleware.GetUserFromContext(r.Context())
-	if !ok {
-		http.Error(w, "User not authenticated", http.StatusUnauthorized)
-		return
-	}
-
-	var request AddShoppingCartItemRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	// Validate required fields
-	if request.ItemName == "" || request.Quantity <= 0 {
-		http.Error(w, "Item name and quantity are required. Quantity must be positive.", http.StatusBadRequest)
-		return
-	}
-
-	// Get user ID
-	userID, err := primitive.ObjectIDFromHex(userClaims.ID)
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
-		return
-	}
-
-	// Find user to get their group
-	var user models.User
-	err = config.DB.Collection("users").FindOne(
-		context.Background(),
-		bson.M{"_id": userID},
-	).Decode(&user)
+// cartUnitError is returned when a quantity cannot be converted into the unit of the cart
+// item it is added to
+type cartUnitError struct {
+	err error
+}
 
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			http.Error(w, "User not found", http.StatusNotFound)
-		} else {
-			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
-		}
-		return
-	}
+func (e *cartUnitError) Error() string {
+	return e.err.Error()
+}
 
+// addToShoppingCart adds quantity of an item to the user's shopping cart, merging it into the
+// cart item with the same name in that item's unit, and logs the activity. A quantity without
+// a unit is taken to be in the cart item's unit. It returns the cart item as it now stands.
+func addToShoppingCart(ctx context.Context, user *models.User, itemName string, quantity float64, unit, category string) (models.ShoppingCartItem, error) {
 	// Define filter to find the item
 	filter := bson.M{
-		"user_id":   userID,
+		"user_id":   user.ID,
 		"group_id":  user.GroupID,
-		"item_name": request.ItemName,
+		"item_name": itemName,
 	}
 
 	// Variable to hold the final item state
@@ -104,66 +74,67 @@ func AddShoppingCartItemHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Attempt to find the existing item first
 	var existingItem models.ShoppingCartItem
-	err = config.DB.Collection("shopping_cart").FindOne(context.Background(), filter).Decode(&existingItem)
+	err := config.DB.Collection("shopping_cart").FindOne(ctx, filter).Decode(&existingItem)
+
+	// The quantity added, in the cart item's unit when merging into an existing item
+	added := quantity
 
 	if err == nil {
 		// Item found - Increment quantity and update timestamp/category
 		itemWasUpdated = true
+
+		// Convert into the unit already in the cart; a request without a unit is taken to be in it
+		if unit != "" && existingItem.Unit != "" {
+			density, _ := units.DensityFor(existingItem.ItemName)
+			added, err = units.ConvertString(quantity, unit, existingItem.Unit, density)
+			if err != nil {
+				return finalShoppingCartItem, &cartUnitError{err: err}
+			}
+		}
+
 		update := bson.M{
-			"$inc": bson.M{"quantity": request.Quantity}, // Increment quantity
+			"$inc": bson.M{"quantity": added}, // Increment quantity
 			"$set": bson.M{
 				"added_at": time.Now(), // Update timestamp
-				// Optionally update category if provided?
-				// "category": request.Category, // Keep existing category or update? Decide policy.
 			},
 		}
 		// If category is provided in the request, update it as well
-		if request.Category != "" {
-			update["$set"].(bson.M)["category"] = request.Category
+		if category != "" {
+			update["$set"].(bson.M)["category"] = category
+		}
+		// Older items without a unit take the request's
+		if unit != "" && existingItem.Unit == "" {
+			update["$set"].(bson.M)["unit"] = unit
 		}
 
-		_, updateErr := config.DB.Collection("shopping_cart").UpdateOne(
-			context.Background(),
-			filter,
-			update,
-		)
-		if updateErr != nil {
-			log.Printf("Failed to increment shopping cart item quantity: %v", updateErr)
-			http.Error(w, "Failed to update item quantity in shopping cart", http.StatusInternalServerError)
-			return
+		if _, err := config.DB.Collection("shopping_cart").UpdateOne(ctx, filter, update); err != nil {
+			return finalShoppingCartItem, fmt.Errorf("failed to increment shopping cart item quantity: %w", err)
 		}
 		// Fetch the updated item to return it
-		fetchErr := config.DB.Collection("shopping_cart").FindOne(context.Background(), filter).Decode(&finalShoppingCartItem)
-		if fetchErr != nil {
-			log.Printf("Failed to fetch updated shopping cart item: %v", fetchErr)
-			http.Error(w, "Failed to fetch updated item", http.StatusInternalServerError)
-			return
+		if err := config.DB.Collection("shopping_cart").FindOne(ctx, filter).Decode(&finalShoppingCartItem); err != nil {
+			return finalShoppingCartItem, fmt.Errorf("failed to fetch updated shopping cart item: %w", err)
 		}
 
 	} else if errors.Is(err, mongo.ErrNoDocuments) {
 		// Item not found - Insert new item
-		itemWasUpdated = false
 		newItem := models.CreateShoppingCartItem(
-			userID,
+			user.ID,
 			user.GroupID,
-			request.ItemName,
-			request.Quantity,
-			request.Category,
+			itemName,
+			quantity,
+			category,
 		)
-		insertResult, insertErr := config.DB.Collection("shopping_cart").InsertOne(context.Background(), newItem)
-		if insertErr != nil {
-			log.Printf("Failed to insert new shopping cart item: %v", insertErr)
-			http.Error(w, "Failed to add item to shopping cart", http.StatusInternalServerError)
-			return
+		newItem.Unit = unit
+		insertResult, err := config.DB.Collection("shopping_cart").InsertOne(ctx, newItem)
+		if err != nil {
+			return finalShoppingCartItem, fmt.Errorf("failed to insert new shopping cart item: %w", err)
 		}
 		newItem.ID = insertResult.InsertedID.(primitive.ObjectID)
 		finalShoppingCartItem = *newItem // Use the newly inserted item data (Dereference the pointer)
 
 	} else {
 		// Other database error during FindOne
-		log.Printf("Error checking for existing shopping cart item: %v", err)
-		http.Error(w, "Database error checking for item", http.StatusInternalServerError)
-		return
+		return finalShoppingCartItem, fmt.Errorf("failed to check for existing shopping cart item: %w", err)
 	}
 
 	// Log the activity
@@ -172,29 +143,95 @@ func AddShoppingCartItemHandler(w http.ResponseWriter, r *http.Request) {
 		activityDetails := "Added item to shopping cart"
 		if itemWasUpdated {
 			activityAction = models.CartActivityTypeUpdate // Using Update type for increment as well
-			activityDetails = fmt.Sprintf("Increased quantity of %s by %.2f (New total: %.2f)", finalShoppingCartItem.ItemName, request.Quantity, finalShoppingCartItem.Quantity)
+			activityDetails = fmt.Sprintf("Increased quantity of %s by %.2f (New total: %.2f)", finalShoppingCartItem.ItemName, added, finalShoppingCartItem.Quantity)
 		}
 
 		activity := models.CreateShoppingCartActivity(
 			user.GroupID,
 			finalShoppingCartItem.ID, // Use the ID from the final item state
 			finalShoppingCartItem.ItemName,
-			userID,
+			user.ID,
 			user.Name,
 			activityAction,                 // Use the determined action
 			finalShoppingCartItem.Quantity, // Log the *new* total quantity
 			activityDetails,                // Use the determined details
 		)
 
-		_, insertErr := config.DB.Collection("shopping_cart_activity").InsertOne(
-			context.Background(),
-			activity,
-		)
+		recordCartActivity(activity)
+	}()
+
+	return finalShoppingCartItem, nil
+}
 
-		if insertErr != nil {
-			log.Printf("Failed to create shopping cart activity record: %v", insertErr)
+// AddShoppingCartItemHandler handles adding an item to the shopping cart
+func AddShoppingCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user from context (set by AuthMiddleware)
+	userClaims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request AddShoppingCartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate required fields
+	if request.ItemName == "" || request.Quantity <= 0 {
+		http.Error(w, "Item name and quantity are required. Quantity must be positive.", http.StatusBadRequest)
+		return
+	}
+
+	var unit string
+	if request.Unit != "" {
+		var err error
+		if unit, err = units.Normalize(request.Unit); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-	}()
+	}
+
+	// Get user ID
+	userID, err := primitive.ObjectIDFromHex(userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	// Find user to get their group
+	var user models.User
+	err = config.DB.Collection("users").FindOne(
+		context.Background(),
+		bson.M{"_id": userID},
+	).Decode(&user)
+
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	finalShoppingCartItem, err := addToShoppingCart(context.Background(), &user, request.ItemName, request.Quantity, unit, request.Category)
+	if err != nil {
+		var unitErr *cartUnitError
+		if errors.As(err, &unitErr) {
+			http.Error(w, "Cannot add to "+request.ItemName+": "+unitErr.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Failed to add shopping cart item: %v", err)
+		http.Error(w, "Failed to add item to shopping cart", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK) // 200 OK for both add and increment
@@ -296,6 +333,25 @@ func UpdateShoppingCartItemHandler(w http.ResponseWriter, r *http.Request) {
 		updateFields["category"] = request.Category
 	}
 
+	// A new unit without a new quantity converts the quantity already in the cart
+	if request.Unit != "" {
+		unit, err := units.Normalize(request.Unit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if request.Quantity <= 0 && shoppingCartItem.Unit != "" && unit != shoppingCartItem.Unit {
+			density, _ := units.DensityFor(shoppingCartItem.ItemName)
+			converted, err := units.ConvertString(shoppingCartItem.Quantity, shoppingCartItem.Unit, unit, density)
+			if err != nil {
+				http.Error(w, "Cannot change the unit of "+shoppingCartItem.ItemName+": "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			updateFields["quantity"] = converted
+		}
+		updateFields["unit"] = unit
+	}
+
 	// If no fields to update, return early
 	if len(updateFields) == 0 {
 		http.Error(w, "No valid fields to update", http.StatusBadRequest)
@@ -359,14 +415,7 @@ func UpdateShoppingCartItemHandler(w http.ResponseWriter, r *http.Request) {
 			details,
 		)
 
-		_, err := config.DB.Collection("shopping_cart_activity").InsertOne(
-			context.Background(),
-			activity,
-		)
-
-		if err != nil {
-			log.Printf("Failed to create shopping cart activity record: %v", err)
-		}
+		recordCartActivity(activity)
 	}()
 
 	w.Header().Set("Content-Type", "application/json")
@@ -482,14 +531,7 @@ func DeleteShoppingCartItemHandler(w http.ResponseWriter, r *http.Request) {
 			"Removed item from shopping cart",
 		)
 
-		_, err := config.DB.Collection("shopping_cart_activity").InsertOne(
-			context.Background(),
-			activity,
-		)
-
-		if err != nil {
-			log.Printf("Failed to create shopping cart activity record: %v", err)
-		}
+		recordCartActivity(activity)
 	}()
 
 	w.Header().Set("Content-Type", "application/json")
@@ -500,7 +542,8 @@ func DeleteShoppingCartItemHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// ListShoppingCartItemsHandler handles retrieving all items in the shopping cart for a group
+// ListShoppingCartItemsHandler retrieves a page of the shopping cart for the user's group. Items can
+// be filtered by user_id, category and added_at range.
 func ListShoppingCartItemsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -537,41 +580,16 @@ func ListShoppingCartItemsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check for filter by user
-	filterByUser := r.URL.Query().Get("user_id")
-
-	// Build the query filter
-	filter := bson.M{"group_id": user.GroupID}
-
-	// If filtering by user, add user_id to filter
-	if filterByUser != "" {
-		filterUserID, err := primitive.ObjectIDFromHex(filterByUser)
-		if err != nil {
-			http.Error(w, "Invalid filter user ID format", http.StatusBadRequest)
-			return
-		}
-
-		// Verify the filter user belongs to the same group
-		var filterUser models.User
-		err = config.DB.Collection("users").FindOne(
-			context.Background(),
-			bson.M{"_id": filterUserID},
-		).Decode(&filterUser)
-
-		if err != nil || filterUser.GroupID != user.GroupID {
-			http.Error(w, "User not found or not in your group", http.StatusForbidden)
-			return
-		}
-
-		filter["user_id"] = filterUserID
+	q, ok := parseListQuery(w, r, shoppingCartListSpec)
+	if !ok {
+		return
 	}
 
-	// Get all items in the shopping cart for the group
-	opts := options.Find().SetSort(bson.D{{Key: "added_at", Value: -1}})
+	// Get the requested page of the group's shopping cart
 	cursor, err := config.DB.Collection("shopping_cart").Find(
 		context.Background(),
-		filter,
-		opts,
+		q.Filter(bson.M{"group_id": user.GroupID}),
+		q.FindOptions(),
 	)
 
 	if err != nil {
@@ -588,6 +606,13 @@ func ListShoppingCartItemsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	shoppingCartItems, nextCursor, err := query.Paginate(shoppingCartItems, q)
+	if err != nil {
+		log.Printf("Failed to build shopping cart cursor: %v", err)
+		http.Error(w, "Failed to paginate shopping cart items", http.StatusInternalServerError)
+		return
+	}
+
 	// Return items with additional user info
 	type ShoppingCartItemWithUser struct {
 		models.ShoppingCartItem
@@ -628,14 +653,11 @@ func ListShoppingCartItemsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(ShoppingCartResponse{
-		Status:  "success",
-		Message: "Shopping cart items retrieved successfully",
-		Data:    itemsWithUsers,
-	})
+	json.NewEncoder(w).Encode(query.NewPage(itemsWithUsers, nextCursor, q.Limit))
 }
 
-// GetShoppingCartActivityHandler retrieves recent activity for a group's shopping cart
+// GetShoppingCartActivityHandler retrieves a page of a group's shopping cart activity, newest first by
+// default, and marks it as read. Activity can be filtered by action, user_id, item_id and created_at range.
 func GetShoppingCartActivityHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -652,7 +674,6 @@ func GetShoppingCartActivityHandler(w http.ResponseWriter, r *http.Request) {
 	// Get query parameters
 	groupName := r.URL.Query().Get("group_name")
 	groupCode := r.URL.Query().Get("group_code")
-	limit := 20 // Default limit
 
 	// Need either group name or group code
 	if groupName == "" && groupCode == "" {
@@ -660,6 +681,11 @@ func GetShoppingCartActivityHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	q, ok := parseListQuery(w, r, shoppingCartActivityListSpec)
+	if !ok {
+		return
+	}
+
 	// Get user ID
 	userID, err := primitive.ObjectIDFromHex(userClaims.ID)
 	if err != nil {
@@ -712,16 +738,11 @@ func GetShoppingCartActivityHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set up options for sorting and limiting results
-	opts := options.Find().
-		SetSort(bson.D{{Key: "created_at", Value: -1}}). // Sort by newest first
-		SetLimit(int64(limit))
-
-	// Find all activity for this group
+	// Find the requested page of activity for this group
 	cursor, err := config.DB.Collection("shopping_cart_activity").Find(
 		context.Background(),
-		bson.M{"group_id": group.ID},
-		opts,
+		q.Filter(bson.M{"group_id": group.ID}),
+		q.FindOptions(),
 	)
 
 	if err != nil {
@@ -736,29 +757,27 @@ func GetShoppingCartActivityHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Update read status for the current user
+	activities, nextCursor, err := query.Paginate(activities, q)
+	if err != nil {
+		log.Printf("Failed to build shopping cart activity cursor: %v", err)
+		http.Error(w, "Failed to paginate shopping cart activity", http.StatusInternalServerError)
+		return
+	}
+
+	// Mark the notifications of the activity shown as read for the current user
+	activityIDs := make([]primitive.ObjectID, 0, len(activities))
+	for _, activity := range activities {
+		activityIDs = append(activityIDs, activity.ID)
+	}
 	go func() {
-		for _, activity := range activities {
-			if !activity.HasBeenReadBy(userID) {
-				_, err := config.DB.Collection("shopping_cart_activity").UpdateOne(
-					context.Background(),
-					bson.M{"_id": activity.ID},
-					bson.M{"$addToSet": bson.M{"read_by": userID}},
-				)
-
-				if err != nil {
-					log.Printf("Failed to update activity read status: %v", err)
-				}
-			}
+		err := markLinkedNotificationsRead(context.Background(), &user, bson.M{"cart.activity_id": bson.M{"$in": activityIDs}})
+		if err != nil {
+			log.Printf("Failed to update activity read status: %v", err)
 		}
 	}()
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(ShoppingCartResponse{
-		Status:  "success",
-		Message: "Shopping cart activity retrieved successfully",
-		Data:    activities,
-	})
+	json.NewEncoder(w).Encode(query.NewPage(activities, nextCursor, q.Limit))
 }
 
 // MarkActivityReadHandler marks a shopping cart activity as read
@@ -842,17 +861,10 @@ func MarkActivityReadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Mark activity as read - update both the read_by array and the is_read flag
-	_, err = config.DB.Collection("shopping_cart_activity").UpdateOne(
-		context.Background(),
-		bson.M{"_id": activityID},
-		bson.M{
-			"$addToSet": bson.M{"read_by": userID},
-			"$set":      bson.M{"is_read": true},
-		},
-	)
-
+	// Mark the activity's notification as read
+	err = markLinkedNotificationsRead(context.Background(), &user, bson.M{"cart.activity_id": activityID})
 	if err != nil {
+		log.Printf("Failed to mark activity as read: %v", err)
 		http.Error(w, "Failed to mark activity as read", http.StatusInternalServerError)
 		return
 	}
@@ -863,3 +875,28 @@ func MarkActivityReadHandler(w http.ResponseWriter, r *http.Request) {
 		"message": "Activity marked as read",
 	})
 }
+
+// recordCartActivity logs a shopping cart change, notifies the group about it and
+// broadcasts it to connected members
+func recordCartActivity(activity *models.ShoppingCartActivity) {
+	result, err := config.DB.Collection("shopping_cart_activity").InsertOne(context.Background(), activity)
+	if err != nil {
+		log.Printf("Failed to create shopping cart activity record: %v", err)
+		return
+	}
+	activity.ID = result.InsertedID.(primitive.ObjectID)
+
+	if _, err := config.DB.Collection("notifications").InsertOne(context.Background(), models.CreateCartNotification(activity)); err != nil {
+		log.Printf("Failed to create shopping cart notification: %v", err)
+	}
+
+	eventType := realtime.EventCartItemUpdated
+	switch activity.Action {
+	case models.CartActivityTypeAdd:
+		eventType = realtime.EventCartItemAdded
+	case models.CartActivityTypeDelete:
+		eventType = realtime.EventCartItemRemoved
+	}
+	actorID := activity.UserID
+	jobs.PublishGroupEvent(activity.GroupID, eventType, &actorID, activity)
+}
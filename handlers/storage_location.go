@@ -0,0 +1,379 @@
+// handlers/storage_location.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"cribb-backend/realtime"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateStorageLocationRequest defines the request structure for creating a storage location.
+// A personal location belongs to the member creating it.
+type CreateStorageLocationRequest struct {
+	GroupName string `json:"group_name" validate:"required"`
+	Name      string `json:"name" validate:"required"`
+	Kind      string `json:"kind" validate:"required"`
+	Personal  bool   `json:"personal,omitempty"`
+}
+
+// UpdateStorageLocationRequest defines the request structure for renaming a storage location
+type UpdateStorageLocationRequest struct {
+	LocationID string `json:"location_id" validate:"required"`
+	Name       string `json:"name" validate:"required"`
+}
+
+// MovePantryItemRequest defines the request structure for moving a pantry item, or one of its
+// lots, to another storage location
+type MovePantryItemRequest struct {
+	ItemID     string `json:"item_id" validate:"required"`
+	LotID      string `json:"lot_id,omitempty"` // Left out, every lot of the item is moved
+	LocationID string `json:"location_id" validate:"required"`
+}
+
+// findStorageLocation loads one of the group's storage locations
+func findStorageLocation(ctx context.Context, groupID, locationID primitive.ObjectID) (*models.StorageLocation, error) {
+	var location models.StorageLocation
+	err := config.DB.Collection("storage_locations").FindOne(ctx, bson.M{"_id": locationID, "group_id": groupID}).Decode(&location)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrStorageLocationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+// parseStorageLocation loads the group's storage location with the given hex ID
+func parseStorageLocation(ctx context.Context, groupID primitive.ObjectID, locationIDStr string) (*models.StorageLocation, error) {
+	locationID, err := primitive.ObjectIDFromHex(locationIDStr)
+	if err != nil {
+		return nil, models.ErrStorageLocationNotFound
+	}
+	return findStorageLocation(ctx, groupID, locationID)
+}
+
+// placeNewLot puts a lot being added to an item in a storage location, if one is given, and
+// estimates its expiration date from the shelf-life rules if it has none
+func placeNewLot(ctx context.Context, item *models.PantryItem, lot *models.PantryLot, location *models.StorageLocation) {
+	if location != nil {
+		lot.PlaceIn(location, time.Now())
+	}
+	if lot.ExpirationDate.IsZero() {
+		lot.ExpirationDate = estimateExpiration(ctx, item.GroupID, item.CategoryID, item.ProductID, lot.FrozenAt != nil)
+		lot.ExpirationEstimated = !lot.ExpirationDate.IsZero()
+	}
+}
+
+// itemLocation loads the storage location all of an item's lots are in, if they share one
+func itemLocation(ctx context.Context, item *models.PantryItem) *models.StorageLocation {
+	if item.LocationID == nil {
+		return nil
+	}
+	location, err := findStorageLocation(ctx, item.GroupID, *item.LocationID)
+	if err != nil {
+		if !errors.Is(err, models.ErrStorageLocationNotFound) {
+			log.Printf("Failed to load storage location: %v", err)
+		}
+		return nil
+	}
+	return location
+}
+
+// GetStorageLocationsHandler lists a group's storage locations
+func GetStorageLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupName := r.URL.Query().Get("group_name")
+	if groupName == "" {
+		http.Error(w, "group_name parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	_, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	cursor, err := config.DB.Collection("storage_locations").Find(ctx,
+		bson.M{"group_id": group.ID},
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}}),
+	)
+	if err != nil {
+		http.Error(w, "Failed to fetch storage locations", http.StatusInternalServerError)
+		return
+	}
+	locations := []models.StorageLocation{}
+	if err := cursor.All(ctx, &locations); err != nil {
+		http.Error(w, "Failed to decode storage locations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(locations)
+}
+
+// CreateStorageLocationHandler creates a storage location for a group
+func CreateStorageLocationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request CreateStorageLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		http.Error(w, "Location name is required", http.StatusBadRequest)
+		return
+	}
+	kind := models.StorageKind(request.Kind)
+	if !models.IsValidStorageKind(kind) {
+		http.Error(w, "Invalid kind. Must be one of: fridge, freezer, cupboard, shelf", http.StatusBadRequest)
+		return
+	}
+
+	user, group, ok := findGroupForMember(w, r, request.GroupName)
+	if !ok {
+		return
+	}
+
+	location := models.CreateStorageLocation(group.ID, name, kind, user.ID)
+	if request.Personal {
+		location.OwnerID = &user.ID
+	}
+
+	result, err := config.DB.Collection("storage_locations").InsertOne(context.Background(), location)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "A storage location with this name already exists", http.StatusConflict)
+			return
+		}
+		log.Printf("Failed to create storage location: %v", err)
+		http.Error(w, "Failed to create storage location", http.StatusInternalServerError)
+		return
+	}
+	location.ID = result.InsertedID.(primitive.ObjectID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(location)
+}
+
+// UpdateStorageLocationHandler renames a storage location
+func UpdateStorageLocationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request UpdateStorageLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		http.Error(w, "Location name is required", http.StatusBadRequest)
+		return
+	}
+
+	location, ok := loadStorageLocationForEdit(w, r, request.LocationID)
+	if !ok {
+		return
+	}
+
+	location.Name = name
+	location.UpdatedAt = time.Now()
+	_, err := config.DB.Collection("storage_locations").UpdateOne(
+		context.Background(),
+		bson.M{"_id": location.ID},
+		bson.M{"$set": bson.M{"name": location.Name, "updated_at": location.UpdatedAt}},
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "A storage location with this name already exists", http.StatusConflict)
+			return
+		}
+		log.Printf("Failed to update storage location: %v", err)
+		http.Error(w, "Failed to update storage location", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(location)
+}
+
+// DeleteStorageLocationHandler deletes a storage location no pantry item is stored in
+func DeleteStorageLocationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	location, ok := loadStorageLocationForEdit(w, r, r.URL.Query().Get("location_id"))
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	itemCount, err := config.DB.Collection("pantry_items").CountDocuments(ctx, bson.M{"lots.location_id": location.ID})
+	if err != nil {
+		http.Error(w, "Failed to check storage location usage", http.StatusInternalServerError)
+		return
+	}
+	if itemCount > 0 {
+		http.Error(w, "Cannot delete storage location: pantry items are stored in it", http.StatusConflict)
+		return
+	}
+
+	if _, err := config.DB.Collection("storage_locations").DeleteOne(ctx, bson.M{"_id": location.ID}); err != nil {
+		log.Printf("Failed to delete storage location: %v", err)
+		http.Error(w, "Failed to delete storage location", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Storage location deleted successfully"})
+}
+
+// MovePantryItemHandler moves a pantry item, or one of its lots, to another storage location.
+// Lots moved into a freezer keep longer, and lots taken out of one expire sooner.
+func MovePantryItemHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request MovePantryItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var lotID *primitive.ObjectID
+	if request.LotID != "" {
+		id, err := primitive.ObjectIDFromHex(request.LotID)
+		if err != nil {
+			http.Error(w, "Invalid lot ID format", http.StatusBadRequest)
+			return
+		}
+		lotID = &id
+	}
+
+	pantryItem, user, ok := loadPantryItemForMember(w, r, request.ItemID)
+	if !ok {
+		return
+	}
+
+	// Moving someone else's item needs the same permission as using it
+	if !pantryItem.CanUse(user.ID) {
+		http.Error(w, models.ErrUseNotPermitted.Error(), http.StatusForbidden)
+		return
+	}
+
+	location, err := parseStorageLocation(context.Background(), pantryItem.GroupID, request.LocationID)
+	if err != nil {
+		if errors.Is(err, models.ErrStorageLocationNotFound) {
+			http.Error(w, "Storage location not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch storage location", http.StatusInternalServerError)
+		}
+		return
+	}
+	if !location.CanPlace(user.ID) {
+		http.Error(w, models.ErrNotLocationOwner.Error(), http.StatusForbidden)
+		return
+	}
+
+	session, err := config.DB.Client().StartSession()
+	if err != nil {
+		log.Printf("Failed to start MongoDB session: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer session.EndSession(context.Background())
+
+	// Run the read and rewrite of the lots in a transaction so a concurrent change to the
+	// item's stock is not overwritten
+	var moved []models.PantryLot
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		// Re-read the item so the move applies to its current lots
+		err := config.DB.Collection("pantry_items").FindOne(sc, bson.M{"_id": pantryItem.ID}).Decode(&pantryItem)
+		if err != nil {
+			return nil, err
+		}
+
+		// An item without a known shelf life keeps its dates
+		shelfLife, _, err := findShelfLife(sc, pantryItem.GroupID, pantryItem.CategoryID, pantryItem.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		moved, err = pantryItem.MoveLots(lotID, location, shelfLife, time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = config.DB.Collection("pantry_items").UpdateOne(
+			sc,
+			bson.M{"_id": pantryItem.ID},
+			bson.M{"$set": bson.M{
+				"lots":                 pantryItem.Lots,
+				"location_id":          pantryItem.LocationID,
+				"expiration_date":      pantryItem.ExpirationDate,
+				"expiration_estimated": pantryItem.ExpirationEstimated,
+				"updated_at":           pantryItem.UpdatedAt,
+			}},
+		)
+		return nil, err
+	})
+
+	if err != nil {
+		if errors.Is(err, models.ErrLotNotFound) {
+			http.Error(w, "Lot not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to move pantry item: %v", err)
+		http.Error(w, "Failed to move pantry item", http.StatusInternalServerError)
+		return
+	}
+
+	for _, lot := range moved {
+		UpdatePantryHistoryForMove(
+			pantryItem.GroupID,
+			pantryItem.ID,
+			pantryItem.Name,
+			user.ID,
+			user.Name,
+			lot.Quantity,
+			lot.ID,
+			location,
+		)
+	}
+
+	responseItem := newPantryItemResponse(pantryItem)
+	publishGroupEvent(r, pantryItem.GroupID, realtime.EventPantryItemMoved, responseItem)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responseItem)
+}
@@ -0,0 +1,25 @@
+// handlers/units.go
+package handlers
+
+import (
+	"cribb-backend/units"
+	"encoding/json"
+	"net/http"
+)
+
+// UnitsResponse lists the units quantities can be converted between
+type UnitsResponse struct {
+	Units []units.Unit `json:"units"`
+}
+
+// GetUnitsHandler lists the canonical units of measure. Other units are accepted as custom
+// units that only combine with themselves.
+func GetUnitsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UnitsResponse{Units: units.Known})
+}
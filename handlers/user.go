@@ -4,6 +4,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"log"
 	"net/http"
 
 	"cribb-backend/config"
@@ -68,6 +69,26 @@ func GetUsersByScoreHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// When a period is requested, rank every user by points earned in that window
+	if r.URL.Query().Get("period") != "" {
+		period, start, end, err := parseLeaderboardWindow(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response, err := buildLeaderboardResponse(nil, period, start, end)
+		if err != nil {
+			log.Printf("GetUsersByScoreHandler compute leaderboard error: %v", err)
+			http.Error(w, "Failed to compute leaderboard", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	// Set up options for sorting by score in descending order
 	opts := options.Find().SetSort(bson.D{{Key: "score", Value: -1}})
 
@@ -0,0 +1,436 @@
+// handlers/webhook.go
+package handlers
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/jobs"
+	"cribb-backend/models"
+	"cribb-backend/query"
+	"cribb-backend/realtime"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateWebhookRequest defines the request structure for subscribing a URL to group events.
+// A secret is generated when none is given.
+type CreateWebhookRequest struct {
+	GroupName   string   `json:"group_name" validate:"required"`
+	URL         string   `json:"url" validate:"required"`
+	Events      []string `json:"events" validate:"required"`
+	Secret      string   `json:"secret"`
+	Description string   `json:"description"`
+}
+
+// UpdateWebhookRequest defines the request structure for changing a webhook. Fields left
+// out are not changed.
+type UpdateWebhookRequest struct {
+	WebhookID    string    `json:"webhook_id" validate:"required"`
+	URL          *string   `json:"url"`
+	Events       *[]string `json:"events"`
+	Active       *bool     `json:"active"`
+	Description  *string   `json:"description"`
+	RotateSecret bool      `json:"rotate_secret"`
+}
+
+// RedeliverWebhookRequest defines the request structure for sending a delivery again
+type RedeliverWebhookRequest struct {
+	DeliveryID string `json:"delivery_id" validate:"required"`
+}
+
+// WebhookSecretResponse is a webhook along with its signing secret, which is only shown
+// when it is created or rotated
+type WebhookSecretResponse struct {
+	models.Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookEventsResponse lists the event types a webhook can subscribe to
+type WebhookEventsResponse struct {
+	Events []realtime.EventType `json:"events"`
+}
+
+// validateWebhookEvents checks a webhook's event types, dropping duplicates
+func validateWebhookEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, errors.New("at least one event is required")
+	}
+	seen := make(map[string]bool, len(events))
+	valid := make([]string, 0, len(events))
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !realtime.EventType(event).IsValid() {
+			return nil, fmt.Errorf("unknown event %q", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			valid = append(valid, event)
+		}
+	}
+	return valid, nil
+}
+
+// findGroupWebhook loads a webhook and checks that the current user belongs to its group,
+// writing an error response if not
+func findGroupWebhook(w http.ResponseWriter, r *http.Request, webhookIDHex string) (models.Webhook, bool) {
+	var hook models.Webhook
+
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return hook, false
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(webhookIDHex)
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return hook, false
+	}
+
+	err = config.DB.Collection("webhooks").FindOne(context.Background(), bson.M{"_id": webhookID}).Decode(&hook)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch webhook", http.StatusInternalServerError)
+		}
+		return hook, false
+	}
+
+	if _, ok := verifyGroupMember(w, userID, hook.GroupID); !ok {
+		return hook, false
+	}
+	return hook, true
+}
+
+// GetWebhookEventsHandler lists the event types webhooks can subscribe to
+func GetWebhookEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WebhookEventsResponse{Events: realtime.EventTypes})
+}
+
+// GetWebhooksHandler lists a group's webhooks
+func GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupName := r.URL.Query().Get("group_name")
+	if groupName == "" {
+		http.Error(w, "group_name parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	_, group, ok := findGroupForMember(w, r, groupName)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	cursor, err := config.DB.Collection("webhooks").Find(ctx,
+		bson.M{"group_id": group.ID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		http.Error(w, "Failed to fetch webhooks", http.StatusInternalServerError)
+		return
+	}
+	webhooks := make([]models.Webhook, 0)
+	if err = cursor.All(ctx, &webhooks); err != nil {
+		http.Error(w, "Failed to decode webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// CreateWebhookHandler subscribes a URL to a group's events. The signing secret is only
+// returned in this response.
+func CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	request.URL = strings.TrimSpace(request.URL)
+	if err := models.ValidateWebhookURL(request.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events, err := validateWebhookEvents(request.Events)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, group, ok := findGroupForMember(w, r, request.GroupName)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	count, err := config.DB.Collection("webhooks").CountDocuments(ctx, bson.M{"group_id": group.ID})
+	if err != nil {
+		http.Error(w, "Failed to count webhooks", http.StatusInternalServerError)
+		return
+	}
+	if count >= models.MaxWebhooksPerGroup {
+		http.Error(w, fmt.Sprintf("A group can have at most %d webhooks", models.MaxWebhooksPerGroup), http.StatusConflict)
+		return
+	}
+
+	secret := strings.TrimSpace(request.Secret)
+	if secret == "" {
+		secret, err = models.NewWebhookSecret()
+		if err != nil {
+			log.Printf("Failed to generate webhook secret: %v", err)
+			http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	now := time.Now()
+	hook := models.Webhook{
+		ID:          primitive.NewObjectID(),
+		GroupID:     group.ID,
+		URL:         request.URL,
+		Secret:      secret,
+		Events:      events,
+		Description: strings.TrimSpace(request.Description),
+		Active:      true,
+		CreatedBy:   user.ID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if _, err := config.DB.Collection("webhooks").InsertOne(ctx, hook); err != nil {
+		log.Printf("Failed to create webhook: %v", err)
+		http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(WebhookSecretResponse{Webhook: hook, Secret: secret})
+}
+
+// UpdateWebhookHandler changes a webhook's URL, events, description or state, and can
+// rotate its secret
+func UpdateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	update := bson.M{"updated_at": time.Now()}
+	if request.URL != nil {
+		url := strings.TrimSpace(*request.URL)
+		if err := models.ValidateWebhookURL(url); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		update["url"] = url
+	}
+	if request.Events != nil {
+		events, err := validateWebhookEvents(*request.Events)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		update["events"] = events
+	}
+	if request.Active != nil {
+		update["active"] = *request.Active
+	}
+	if request.Description != nil {
+		update["description"] = strings.TrimSpace(*request.Description)
+	}
+	var secret string
+	if request.RotateSecret {
+		var err error
+		secret, err = models.NewWebhookSecret()
+		if err != nil {
+			log.Printf("Failed to generate webhook secret: %v", err)
+			http.Error(w, "Failed to update webhook", http.StatusInternalServerError)
+			return
+		}
+		update["secret"] = secret
+	}
+
+	hook, ok := findGroupWebhook(w, r, request.WebhookID)
+	if !ok {
+		return
+	}
+
+	var updated models.Webhook
+	err := config.DB.Collection("webhooks").FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": hook.ID},
+		bson.M{"$set": update},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		log.Printf("Failed to update webhook: %v", err)
+		http.Error(w, "Failed to update webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if request.RotateSecret {
+		json.NewEncoder(w).Encode(WebhookSecretResponse{Webhook: updated, Secret: secret})
+		return
+	}
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteWebhookHandler removes a webhook along with its delivery log
+func DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hook, ok := findGroupWebhook(w, r, r.URL.Query().Get("webhook_id"))
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	if _, err := config.DB.Collection("webhooks").DeleteOne(ctx, bson.M{"_id": hook.ID}); err != nil {
+		log.Printf("Failed to delete webhook: %v", err)
+		http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+
+	// Deliveries still queued are dead-lettered by the worker if this fails
+	if _, err := config.DB.Collection("webhook_deliveries").DeleteMany(ctx, bson.M{"webhook_id": hook.ID}); err != nil {
+		log.Printf("Failed to delete webhook deliveries: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Webhook deleted successfully",
+	})
+}
+
+// GetWebhookDeliveriesHandler lists a webhook's deliveries with their attempts, newest first
+func GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q, ok := parseListQuery(w, r, webhookDeliveryListSpec)
+	if !ok {
+		return
+	}
+
+	hook, ok := findGroupWebhook(w, r, r.URL.Query().Get("webhook_id"))
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	cursor, err := config.DB.Collection("webhook_deliveries").Find(ctx,
+		q.Filter(bson.M{"webhook_id": hook.ID}),
+		q.FindOptions(),
+	)
+	if err != nil {
+		http.Error(w, "Failed to fetch webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+	var deliveries []models.WebhookDelivery
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		http.Error(w, "Failed to decode webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	deliveries, nextCursor, err := query.Paginate(deliveries, q)
+	if err != nil {
+		log.Printf("Failed to build webhook deliveries cursor: %v", err)
+		http.Error(w, "Failed to paginate webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(query.NewPage(deliveries, nextCursor, q.Limit))
+}
+
+// RedeliverWebhookHandler queues a past delivery's payload to be sent again
+func RedeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request RedeliverWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	deliveryID, err := primitive.ObjectIDFromHex(request.DeliveryID)
+	if err != nil {
+		http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	var delivery models.WebhookDelivery
+	err = config.DB.Collection("webhook_deliveries").FindOne(ctx, bson.M{"_id": deliveryID}).Decode(&delivery)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Delivery not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch delivery", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	hook, ok := findGroupWebhook(w, r, delivery.WebhookID.Hex())
+	if !ok {
+		return
+	}
+	if !hook.Active {
+		http.Error(w, "Webhook is disabled", http.StatusConflict)
+		return
+	}
+
+	redelivery := delivery.Redeliver(time.Now())
+	if err := jobs.EnqueueWebhookDelivery(ctx, redelivery); err != nil {
+		log.Printf("Failed to queue webhook redelivery: %v", err)
+		http.Error(w, "Failed to queue redelivery", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(redelivery)
+}
@@ -0,0 +1,250 @@
+// jobs/achievements.go
+package jobs
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"cribb-backend/realtime"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MemberAchievements is the streak and badge summary shown on profiles and group details
+type MemberAchievements struct {
+	UserID           primitive.ObjectID         `json:"user_id"`
+	OnTimeStreak     int                        `json:"on_time_streak"`
+	BestOnTimeStreak int                        `json:"best_on_time_streak"`
+	DailyStreak      int                        `json:"daily_streak"`
+	BestDailyStreak  int                        `json:"best_daily_streak"`
+	Badges           []models.EarnedAchievement `json:"badges"`
+}
+
+// ProcessAchievementEvent applies a chore event to the member's streaks, evaluates every
+// achievement rule and persists anything newly earned. It returns the new achievements.
+func ProcessAchievementEvent(event models.AchievementEvent) ([]models.EarnedAchievement, error) {
+	ctx := context.Background()
+
+	if event.UserID.IsZero() {
+		return nil, nil
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	// Apply the event in one atomic update so concurrent events each count, and evaluate the
+	// achievements on the progress as that update left it
+	update, onTime := models.AchievementProgressUpdate(event, time.Now())
+	var progress models.MemberProgress
+	err := updateMemberProgress(ctx, event.UserID, update, &progress)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent event created the member's progress first; apply this one on top
+		err = updateMemberProgress(ctx, event.UserID, update, &progress)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	achievementCtx := models.AchievementContext{
+		Event:    event,
+		Progress: progress,
+		OnTime:   onTime,
+	}
+	if event.Type == models.AchievementEventChoreCompleted {
+		achievementCtx.FirstToFinishWeek, err = isFirstToFinishWeek(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	earned, err := earnedAchievementKeys(ctx, event.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	awarded := models.EvaluateAchievements(models.AchievementRules, achievementCtx, earned)
+	saved := make([]models.EarnedAchievement, 0, len(awarded))
+	for _, achievement := range awarded {
+		result, err := config.DB.Collection("earned_achievements").InsertOne(ctx, achievement)
+		if err != nil {
+			// Already awarded by a concurrent event
+			if mongo.IsDuplicateKeyError(err) {
+				continue
+			}
+			return saved, err
+		}
+		achievement.ID = result.InsertedID.(primitive.ObjectID)
+		saved = append(saved, achievement)
+		log.Printf("User %s earned achievement %s", event.UserID.Hex(), achievement.AchievementID)
+	}
+
+	return saved, nil
+}
+
+// MarkChoreOverdue marks a pending chore as overdue and emits the overdue achievement event.
+// It returns false if the chore was no longer pending.
+func MarkChoreOverdue(chore models.Chore) (bool, error) {
+	now := time.Now()
+	result, err := config.DB.Collection("chores").UpdateOne(
+		context.Background(),
+		bson.M{"_id": chore.ID, "status": models.ChoreStatusPending},
+		bson.M{"$set": bson.M{
+			"status":     models.ChoreStatusOverdue,
+			"updated_at": now,
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	if result.ModifiedCount == 0 {
+		return false, nil
+	}
+	InvalidateChoreAnalytics(chore.GroupID)
+
+	_, err = ProcessAchievementEvent(models.AchievementEvent{
+		Type:        models.AchievementEventChoreOverdue,
+		UserID:      chore.AssignedTo,
+		GroupID:     chore.GroupID,
+		ChoreID:     chore.ID,
+		RecurringID: chore.RecurringID,
+		Points:      chore.Points,
+		DueDate:     chore.DueDate,
+		OccurredAt:  now,
+	})
+	if err != nil {
+		log.Printf("Failed to process overdue achievement event for chore %s: %v", chore.ID.Hex(), err)
+	}
+
+	chore.Status = models.ChoreStatusOverdue
+	chore.UpdatedAt = now
+	PublishGroupEvent(chore.GroupID, realtime.EventChoreOverdue, nil, chore)
+	return true, nil
+}
+
+// GetMemberAchievements returns streaks and badges for each of the given users
+func GetMemberAchievements(userIDs []primitive.ObjectID) (map[primitive.ObjectID]*MemberAchievements, error) {
+	ctx := context.Background()
+	summaries := make(map[primitive.ObjectID]*MemberAchievements, len(userIDs))
+	for _, userID := range userIDs {
+		summaries[userID] = &MemberAchievements{
+			UserID: userID,
+			Badges: make([]models.EarnedAchievement, 0),
+		}
+	}
+	if len(userIDs) == 0 {
+		return summaries, nil
+	}
+
+	progressCursor, err := config.DB.Collection("member_progress").Find(ctx, bson.M{"user_id": bson.M{"$in": userIDs}})
+	if err != nil {
+		return nil, err
+	}
+	var progress []models.MemberProgress
+	if err = progressCursor.All(ctx, &progress); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, p := range progress {
+		summary, ok := summaries[p.UserID]
+		if !ok {
+			continue
+		}
+		summary.OnTimeStreak = p.OnTimeStreak
+		summary.BestOnTimeStreak = p.BestOnTimeStreak
+		summary.DailyStreak = p.CurrentDailyStreak(now)
+		summary.BestDailyStreak = p.BestDailyStreak
+	}
+
+	badgeCursor, err := config.DB.Collection("earned_achievements").Find(
+		ctx,
+		bson.M{"user_id": bson.M{"$in": userIDs}},
+		options.Find().SetSort(bson.D{{Key: "earned_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var badges []models.EarnedAchievement
+	if err = badgeCursor.All(ctx, &badges); err != nil {
+		return nil, err
+	}
+	for _, badge := range badges {
+		if summary, ok := summaries[badge.UserID]; ok {
+			summary.Badges = append(summary.Badges, badge)
+		}
+	}
+
+	return summaries, nil
+}
+
+// updateMemberProgress applies an update to a member's progress, creating it if needed, and
+// decodes the progress as the update left it
+func updateMemberProgress(ctx context.Context, userID primitive.ObjectID, update interface{}, progress *models.MemberProgress) error {
+	return config.DB.Collection("member_progress").FindOneAndUpdate(
+		ctx,
+		bson.M{"user_id": userID},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(progress)
+}
+
+// earnedAchievementKeys returns the keys of all achievements a user has already earned
+func earnedAchievementKeys(ctx context.Context, userID primitive.ObjectID) (map[string]bool, error) {
+	cursor, err := config.DB.Collection("earned_achievements").Find(
+		ctx,
+		bson.M{"user_id": userID},
+		options.Find().SetProjection(bson.M{"achievement_id": 1, "period_key": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var achievements []models.EarnedAchievement
+	if err = cursor.All(ctx, &achievements); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool, len(achievements))
+	for _, achievement := range achievements {
+		keys[models.AchievementKey(achievement.AchievementID, achievement.PeriodKey)] = true
+	}
+	return keys, nil
+}
+
+// isFirstToFinishWeek reports whether the completion left the member with no open chores due
+// this week, and no other group member has already finished theirs
+func isFirstToFinishWeek(ctx context.Context, event models.AchievementEvent) (bool, error) {
+	weekStart, weekEnd, _ := models.PeriodRange(models.LeaderboardPeriodWeek, event.OccurredAt)
+	dueThisWeek := bson.M{"$gte": weekStart, "$lt": weekEnd}
+
+	// Only chores due this week count towards finishing the week
+	if event.DueDate.Before(weekStart) || !event.DueDate.Before(weekEnd) {
+		return false, nil
+	}
+
+	open, err := config.DB.Collection("chores").CountDocuments(ctx, bson.M{
+		"group_id":    event.GroupID,
+		"assigned_to": event.UserID,
+		"due_date":    dueThisWeek,
+		"status":      bson.M{"$ne": models.ChoreStatusCompleted},
+	})
+	if err != nil || open > 0 {
+		return false, err
+	}
+
+	claimed, err := config.DB.Collection("earned_achievements").CountDocuments(ctx, bson.M{
+		"group_id":       event.GroupID,
+		"achievement_id": models.AchievementFirstToFinishWeek,
+		"period_key":     models.AchievementPeriodKey(models.AchievementScopeWeekly, event.OccurredAt),
+	})
+	if err != nil {
+		return false, err
+	}
+	return claimed == 0, nil
+}
@@ -0,0 +1,329 @@
+// jobs/analytics.go
+package jobs
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ChoreAnalyticsCacheTTL is how long a computed analytics report is served from memory.
+// Any write to a group's chores invalidates the group's reports straight away.
+const ChoreAnalyticsCacheTTL = 15 * time.Minute
+
+// mostSkippedLimit is the number of recurring chores reported as most skipped
+const mostSkippedLimit = 5
+
+type analyticsCacheEntry struct {
+	report    *models.ChoreAnalytics
+	version   int64
+	expiresAt time.Time
+}
+
+// analyticsCache holds computed reports per group, keyed by bucket, window and time zone.
+// Each report remembers the group's analytics version it was computed at, so a change made
+// through any instance invalidates it.
+var analyticsCache = struct {
+	sync.Mutex
+	groups map[primitive.ObjectID]map[string]analyticsCacheEntry
+}{groups: make(map[primitive.ObjectID]map[string]analyticsCacheEntry)}
+
+// analyticsVersion is a group's analytics version, stored in the database so every instance
+// sees the same one
+type analyticsVersion struct {
+	GroupID primitive.ObjectID `bson:"group_id"`
+	Version int64              `bson:"version"`
+}
+
+// InvalidateChoreAnalytics drops every cached analytics report of a group, on every instance,
+// by moving the group's analytics version on. It is called after any write to the group's
+// chores.
+func InvalidateChoreAnalytics(groupID primitive.ObjectID) {
+	_, err := config.DB.Collection("analytics_versions").UpdateOne(
+		context.Background(),
+		bson.M{"group_id": groupID},
+		bson.M{"$inc": bson.M{"version": 1}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		log.Printf("Failed to invalidate chore analytics of group %s: %v", groupID.Hex(), err)
+	}
+
+	analyticsCache.Lock()
+	defer analyticsCache.Unlock()
+	delete(analyticsCache.groups, groupID)
+}
+
+// choreAnalyticsVersion returns a group's current analytics version
+func choreAnalyticsVersion(ctx context.Context, groupID primitive.ObjectID) (int64, error) {
+	var version analyticsVersion
+	err := config.DB.Collection("analytics_versions").FindOne(ctx, bson.M{"group_id": groupID}).Decode(&version)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	return version.Version, err
+}
+
+// GetChoreAnalytics returns the analytics report of a group over [from, to), computing it
+// when there is no fresh cached copy at the group's current version. The boolean reports
+// whether the cache was used.
+func GetChoreAnalytics(groupID primitive.ObjectID, bucket models.AnalyticsBucket, from, to time.Time, loc *time.Location) (*models.ChoreAnalytics, bool, error) {
+	key := fmt.Sprintf("%s|%d|%d|%s", bucket, from.Unix(), to.Unix(), loc.String())
+	now := time.Now()
+
+	version, err := choreAnalyticsVersion(context.Background(), groupID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	analyticsCache.Lock()
+	entry, found := analyticsCache.groups[groupID][key]
+	analyticsCache.Unlock()
+	if found && entry.version == version && now.Before(entry.expiresAt) {
+		return entry.report, true, nil
+	}
+
+	report, err := ComputeChoreAnalytics(groupID, bucket, from, to, loc, now)
+	if err != nil {
+		return nil, false, err
+	}
+
+	analyticsCache.Lock()
+	defer analyticsCache.Unlock()
+	reports, ok := analyticsCache.groups[groupID]
+	if !ok {
+		reports = make(map[string]analyticsCacheEntry)
+		analyticsCache.groups[groupID] = reports
+	}
+	for cachedKey, cached := range reports {
+		if cached.version != version || !now.Before(cached.expiresAt) {
+			delete(reports, cachedKey)
+		}
+	}
+	reports[key] = analyticsCacheEntry{report: report, version: version, expiresAt: now.Add(ChoreAnalyticsCacheTTL)}
+
+	return report, false, nil
+}
+
+// ComputeChoreAnalytics aggregates the chores due and the completions recorded in [from, to).
+// Trends are bucketed in loc so that days, weeks and months match the household's calendar.
+func ComputeChoreAnalytics(groupID primitive.ObjectID, bucket models.AnalyticsBucket, from, to time.Time, loc *time.Location, now time.Time) (*models.ChoreAnalytics, error) {
+	ctx := context.Background()
+
+	isCompleted := bson.M{"$eq": bson.A{"$status", models.ChoreStatusCompleted}}
+	isOnTime := bson.M{"$and": bson.A{isCompleted, bson.M{"$lte": bson.A{"$completed_at", "$due_date"}}}}
+	isLate := bson.M{"$and": bson.A{isCompleted, bson.M{"$gt": bson.A{"$completed_at", "$due_date"}}}}
+	isMissed := bson.M{"$and": bson.A{bson.M{"$not": bson.A{isCompleted}}, bson.M{"$lt": bson.A{"$due_date", now}}}}
+	count := func(condition bson.M) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{condition, 1, 0}}}
+	}
+
+	choresPipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{
+			"group_id": groupID,
+			"due_date": bson.M{"$gte": from, "$lt": to},
+		}}},
+		bson.D{{Key: "$facet", Value: bson.M{
+			"members": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":               "$assigned_to",
+					"assigned":          bson.M{"$sum": 1},
+					"completed":         count(isCompleted),
+					"completed_on_time": count(isOnTime),
+					"missed":            count(isMissed),
+					"lateness_total_ms": bson.M{"$sum": bson.M{"$cond": bson.A{
+						isLate,
+						bson.M{"$subtract": bson.A{"$completed_at", "$due_date"}},
+						0,
+					}}},
+				}},
+				bson.M{"$lookup": bson.M{
+					"from":         "users",
+					"localField":   "_id",
+					"foreignField": "_id",
+					"as":           "user",
+				}},
+				bson.M{"$unwind": bson.M{"path": "$user", "preserveNullAndEmptyArrays": true}},
+				bson.M{"$addFields": bson.M{
+					"username": bson.M{"$ifNull": bson.A{"$user.username", ""}},
+					"name":     bson.M{"$ifNull": bson.A{"$user.name", ""}},
+				}},
+				bson.M{"$project": bson.M{"user": 0}},
+			},
+			"trend": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":               dateTrunc("$due_date", bucket, loc),
+					"due":               bson.M{"$sum": 1},
+					"completed_on_time": count(isOnTime),
+				}},
+			},
+			"skipped": bson.A{
+				bson.M{"$match": bson.M{"recurring_id": bson.M{"$exists": true, "$ne": nil}}},
+				bson.M{"$group": bson.M{
+					"_id":       "$recurring_id",
+					"title":     bson.M{"$last": "$title"},
+					"instances": bson.M{"$sum": 1},
+					"skipped":   count(isMissed),
+				}},
+				bson.M{"$match": bson.M{"skipped": bson.M{"$gt": 0}}},
+				bson.M{"$lookup": bson.M{
+					"from":         "recurring_chores",
+					"localField":   "_id",
+					"foreignField": "_id",
+					"as":           "recurring",
+				}},
+				bson.M{"$addFields": bson.M{
+					"title": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$recurring.title", 0}}, "$title"}},
+				}},
+				bson.M{"$project": bson.M{"recurring": 0}},
+				bson.M{"$sort": bson.D{{Key: "skipped", Value: -1}}},
+				bson.M{"$limit": mostSkippedLimit * 4},
+			},
+		}}},
+	}
+
+	var choreFacets []struct {
+		Members []models.MemberAnalytics       `bson:"members"`
+		Trend   []models.AnalyticsTrendPoint   `bson:"trend"`
+		Skipped []models.SkippedRecurringChore `bson:"skipped"`
+	}
+	if err := aggregateAll(ctx, "chores", choresPipeline, &choreFacets); err != nil {
+		return nil, err
+	}
+
+	completionsPipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{
+			"group_id":     groupID,
+			"completed_at": bson.M{"$gte": from, "$lt": to},
+		}}},
+		bson.D{{Key: "$facet", Value: bson.M{
+			"points": bson.A{
+				bson.M{"$group": bson.M{"_id": "$user_id", "points": bson.M{"$sum": "$points"}}},
+			},
+			"trend": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":       dateTrunc("$completed_at", bucket, loc),
+					"completed": bson.M{"$sum": 1},
+					"points":    bson.M{"$sum": "$points"},
+				}},
+			},
+		}}},
+	}
+
+	var completionFacets []struct {
+		Points []struct {
+			UserID primitive.ObjectID `bson:"_id"`
+			Points int                `bson:"points"`
+		} `bson:"points"`
+		Trend []models.AnalyticsTrendPoint `bson:"trend"`
+	}
+	if err := aggregateAll(ctx, "chore_completions", completionsPipeline, &completionFacets); err != nil {
+		return nil, err
+	}
+
+	report := &models.ChoreAnalytics{
+		GroupID:     groupID,
+		Timezone:    loc.String(),
+		Bucket:      bucket,
+		From:        from,
+		To:          to,
+		GeneratedAt: now,
+	}
+
+	var members []models.MemberAnalytics
+	var dueTrend, completedTrend []models.AnalyticsTrendPoint
+	var skipped []models.SkippedRecurringChore
+	if len(choreFacets) > 0 {
+		members = choreFacets[0].Members
+		dueTrend = choreFacets[0].Trend
+		skipped = choreFacets[0].Skipped
+	}
+	points := make(map[primitive.ObjectID]int)
+	if len(completionFacets) > 0 {
+		for _, entry := range completionFacets[0].Points {
+			points[entry.UserID] = entry.Points
+		}
+		completedTrend = completionFacets[0].Trend
+	}
+
+	members, err := withGroupMembers(ctx, groupID, members, points)
+	if err != nil {
+		return nil, err
+	}
+	report.Members, report.Summary = models.FinalizeMemberAnalytics(members, points)
+	report.Trend = models.FillTrend(bucket, from, to, dueTrend, completedTrend)
+	report.MostSkipped = models.FinalizeSkippedChores(skipped, mostSkippedLimit)
+
+	return report, nil
+}
+
+// withGroupMembers adds current members without any chores in the window, and fills in the
+// names of members who only appear in the points aggregation
+func withGroupMembers(ctx context.Context, groupID primitive.ObjectID, members []models.MemberAnalytics, points map[primitive.ObjectID]int) ([]models.MemberAnalytics, error) {
+	cursor, err := config.DB.Collection("users").Find(ctx, bson.M{"group_id": groupID})
+	if err != nil {
+		return nil, err
+	}
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[primitive.ObjectID]bool, len(members))
+	for _, member := range members {
+		seen[member.UserID] = true
+	}
+	for _, user := range users {
+		if !seen[user.ID] {
+			seen[user.ID] = true
+			members = append(members, models.MemberAnalytics{UserID: user.ID, Username: user.Username, Name: user.Name})
+		}
+	}
+
+	// Former members who still earned points in the window
+	for userID := range points {
+		if seen[userID] {
+			continue
+		}
+		member := models.MemberAnalytics{UserID: userID}
+		var user models.User
+		if err := config.DB.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err == nil {
+			member.Username = user.Username
+			member.Name = user.Name
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// dateTrunc truncates a date field to the start of its bucket in the given time zone
+func dateTrunc(field string, bucket models.AnalyticsBucket, loc *time.Location) bson.M {
+	trunc := bson.M{
+		"date":     field,
+		"unit":     string(bucket),
+		"timezone": loc.String(),
+	}
+	if bucket == models.AnalyticsBucketWeek {
+		trunc["startOfWeek"] = "monday"
+	}
+	return bson.M{"$dateTrunc": trunc}
+}
+
+func aggregateAll(ctx context.Context, collection string, pipeline mongo.Pipeline, results interface{}) error {
+	cursor, err := config.DB.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	return cursor.All(ctx, results)
+}
@@ -0,0 +1,207 @@
+// jobs/chore_reminders.go
+package jobs
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"cribb-backend/notify"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReminderInterval is how often the reminder scheduler looks for chores to remind about.
+// Reminders are sent up to one interval after they become due.
+const ReminderInterval = 15 * time.Minute
+
+// OverdueReminderWindow bounds how long after the due date an overdue reminder is still
+// sent, so that enabling reminders does not flood a user with old chores
+const OverdueReminderWindow = 48 * time.Hour
+
+// reminderLookback is how far back overdue chores are listed in the daily digest
+const reminderLookback = 7 * 24 * time.Hour
+
+// maxDueSoonLead matches the longest lead time ReminderPreferences accepts
+const maxDueSoonLead = 168 * time.Hour
+
+var (
+	reminderDispatcher     *notify.Dispatcher
+	reminderDispatcherOnce sync.Once
+)
+
+// ReminderDispatcher returns the dispatcher used for chore reminders, with every channel
+// the server is configured for
+func ReminderDispatcher() *notify.Dispatcher {
+	reminderDispatcherOnce.Do(func() {
+		reminderDispatcher = notify.NewDispatcher(
+			notify.NewMongoDeliveryLog(config.DB),
+			notify.NewInAppNotifier(config.DB),
+		)
+	})
+	return reminderDispatcher
+}
+
+// StartReminderScheduler starts sending due-soon, overdue and daily digest reminders
+func StartReminderScheduler() {
+	log.Println("Starting reminder scheduler...")
+
+	ticker := time.NewTicker(ReminderInterval)
+
+	go sendChoreReminders()
+
+	go func() {
+		for range ticker.C {
+			sendChoreReminders()
+		}
+	}()
+}
+
+// sendChoreReminders dispatches the reminders every assignee is due at this point in time
+func sendChoreReminders() {
+	ctx := context.Background()
+	now := time.Now()
+
+	cursor, err := config.DB.Collection("chores").Find(ctx, models.NotArchived(bson.M{
+		"status":      bson.M{"$in": []models.ChoreStatus{models.ChoreStatusPending, models.ChoreStatusOverdue}},
+		"assigned_to": bson.M{"$exists": true, "$ne": primitive.NilObjectID},
+		"due_date":    bson.M{"$gte": now.Add(-reminderLookback), "$lte": now.Add(maxDueSoonLead)},
+	}))
+	if err != nil {
+		log.Printf("Error finding chores for reminders: %v", err)
+		return
+	}
+	var chores []models.Chore
+	if err = cursor.All(ctx, &chores); err != nil {
+		log.Printf("Error decoding chores for reminders: %v", err)
+		return
+	}
+	if len(chores) == 0 {
+		return
+	}
+
+	choresByUser := make(map[primitive.ObjectID][]models.Chore)
+	userIDs := make([]primitive.ObjectID, 0)
+	groupIDs := make([]primitive.ObjectID, 0)
+	seenGroups := make(map[primitive.ObjectID]bool)
+	for _, chore := range chores {
+		if _, ok := choresByUser[chore.AssignedTo]; !ok {
+			userIDs = append(userIDs, chore.AssignedTo)
+		}
+		choresByUser[chore.AssignedTo] = append(choresByUser[chore.AssignedTo], chore)
+		if !seenGroups[chore.GroupID] {
+			seenGroups[chore.GroupID] = true
+			groupIDs = append(groupIDs, chore.GroupID)
+		}
+	}
+
+	var users []models.User
+	cursor, err = config.DB.Collection("users").Find(ctx, bson.M{"_id": bson.M{"$in": userIDs}})
+	if err == nil {
+		err = cursor.All(ctx, &users)
+	}
+	if err != nil {
+		log.Printf("Error loading users for reminders: %v", err)
+		return
+	}
+
+	var groups []models.Group
+	cursor, err = config.DB.Collection("groups").Find(ctx, bson.M{"_id": bson.M{"$in": groupIDs}})
+	if err == nil {
+		err = cursor.All(ctx, &groups)
+	}
+	if err != nil {
+		log.Printf("Error loading groups for reminders: %v", err)
+		return
+	}
+	timezones := make(map[primitive.ObjectID]string, len(groups))
+	for _, group := range groups {
+		timezones[group.ID] = group.Timezone
+	}
+
+	dispatcher := ReminderDispatcher()
+	sent := 0
+	for _, user := range users {
+		prefs := user.ReminderSettings()
+		loc := prefs.Location(timezones[user.GroupID])
+
+		for _, message := range planChoreReminders(user, choresByUser[user.ID], prefs, loc, now) {
+			outcome, err := dispatcher.Dispatch(ctx, message, prefs, loc, now)
+			if err != nil {
+				log.Printf("Error sending reminder %s: %v", message.Key, err)
+			}
+			if outcome == notify.OutcomeSent {
+				sent++
+			}
+		}
+	}
+
+	if sent > 0 {
+		log.Printf("Sent %d chore reminders", sent)
+	}
+}
+
+// planChoreReminders lists the reminders a user is due for their chores at now. Keys are
+// stable, so the same reminder planned on a later run is recognised as a duplicate.
+func planChoreReminders(user models.User, chores []models.Chore, prefs models.ReminderPreferences, loc *time.Location, now time.Time) []notify.Message {
+	var messages []notify.Message
+	lead := time.Duration(prefs.DueSoonHours) * time.Hour
+
+	localNow := now.In(loc)
+	year, month, day := localNow.Date()
+	startOfDay := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	endOfDay := startOfDay.AddDate(0, 0, 1)
+
+	var dueToday, overdue []models.Chore
+	for _, chore := range chores {
+		choreID := chore.ID
+		due := chore.DueDate
+
+		switch {
+		case due.After(now) && !due.After(now.Add(lead)) && prefs.Enabled(models.ReminderKindDueSoon):
+			messages = append(messages, notify.Message{
+				Kind:    models.ReminderKindDueSoon,
+				Key:     models.DueSoonReminderKey(chore.ID, user.ID, due),
+				UserID:  user.ID,
+				GroupID: chore.GroupID,
+				ChoreID: &choreID,
+				Title:   "Chore due soon",
+				Body:    chore.Title + " is due " + due.In(loc).Format("Mon Jan 2 15:04"),
+			})
+		case !due.After(now) && now.Sub(due) <= OverdueReminderWindow && prefs.Enabled(models.ReminderKindOverdue):
+			messages = append(messages, notify.Message{
+				Kind:    models.ReminderKindOverdue,
+				Key:     models.OverdueReminderKey(chore.ID, user.ID, due),
+				UserID:  user.ID,
+				GroupID: chore.GroupID,
+				ChoreID: &choreID,
+				Title:   "Chore overdue",
+				Body:    chore.Title + " was due " + due.In(loc).Format("Mon Jan 2 15:04"),
+			})
+		}
+
+		if !due.After(now) {
+			overdue = append(overdue, chore)
+		} else if due.Before(endOfDay) {
+			dueToday = append(dueToday, chore)
+		}
+	}
+
+	if prefs.Enabled(models.ReminderKindDailyDigest) && localNow.Hour() >= prefs.DigestHour {
+		if summary := models.DigestSummary(dueToday, overdue); summary != "" {
+			messages = append(messages, notify.Message{
+				Kind:    models.ReminderKindDailyDigest,
+				Key:     models.DailyDigestReminderKey(user.ID, startOfDay),
+				UserID:  user.ID,
+				GroupID: user.GroupID,
+				Title:   "Your chores today",
+				Body:    summary,
+			})
+		}
+	}
+
+	return messages
+}
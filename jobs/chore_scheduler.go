@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -22,12 +23,14 @@ func StartChoreScheduler() {
 	// Run immediately once at startup
 	go processRecurringChores()
 	go detectOverdueChores()
+	go archiveCompletedChores()
 
 	// Then run on the schedule
 	go func() {
 		for range ticker.C {
 			processRecurringChores()
 			detectOverdueChores()
+			archiveCompletedChores()
 		}
 	}()
 }
@@ -71,7 +74,9 @@ func processRecurringChores() {
 			defer s.EndSession(context.Background())
 
 			// Execute in a transaction
+			created := false
 			_, err := s.WithTransaction(context.Background(), func(ctx mongo.SessionContext) (interface{}, error) {
+				created = false
 				// Get fresh copy of recurring chore to avoid race conditions
 				var freshRC models.RecurringChore
 				err := config.DB.Collection("recurring_chores").FindOne(
@@ -128,11 +133,14 @@ func processRecurringChores() {
 				}
 
 				log.Printf("Created new chore instance from recurring chore %s", freshRC.ID.Hex())
+				created = true
 				return nil, nil
 			})
 
 			if err != nil {
 				log.Printf("Error processing recurring chore %s: %v", rc.ID.Hex(), err)
+			} else if created {
+				InvalidateChoreAnalytics(rc.GroupID)
 			}
 		}(session, recurringChore)
 	}
@@ -150,29 +158,105 @@ func detectOverdueChores() {
 
 	// Any pending chore whose due date is strictly before the start of today UTC
 	// has had its entire due day pass and should now be considered overdue.
-
-	result, err := config.DB.Collection("chores").UpdateMany(
+	// Chores are marked one at a time so that each one emits an achievement event.
+	cursor, err := config.DB.Collection("chores").Find(
 		context.Background(),
 		bson.M{
 			"status":   models.ChoreStatusPending,
 			"due_date": bson.M{"$lt": startOfTodayUTC},
 		},
-		bson.M{
-			"$set": bson.M{
-				"status":     models.ChoreStatusOverdue,
-				"updated_at": time.Now(),
-			},
-		},
 	)
-
 	if err != nil {
-		log.Printf("Error updating overdue chores: %v", err)
+		log.Printf("Error finding overdue chores: %v", err)
 		return
 	}
+	defer cursor.Close(context.Background())
 
-	if result.ModifiedCount > 0 {
-		log.Printf("Marked %d chores as overdue", result.ModifiedCount)
+	var chores []models.Chore
+	if err = cursor.All(context.Background(), &chores); err != nil {
+		log.Printf("Error decoding overdue chores: %v", err)
+		return
+	}
+
+	marked := 0
+	for _, chore := range chores {
+		updated, err := MarkChoreOverdue(chore)
+		if err != nil {
+			log.Printf("Error updating overdue chore %s: %v", chore.ID.Hex(), err)
+			continue
+		}
+		if updated {
+			marked++
+		}
+	}
+
+	if marked > 0 {
+		log.Printf("Marked %d chores as overdue", marked)
 	} else {
 		log.Printf("No overdue chores found")
 	}
 }
+
+// archiveCompletedChores archives chores that were completed more than the configured number of days ago.
+// Chores that were restored from the archive get the same grace period again.
+func archiveCompletedChores() {
+	days := config.ChoreArchiveAfterDays
+	if days <= 0 {
+		return
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -days)
+
+	// Narrow the search to completed chores old enough, then let the chore decide
+	cursor, err := config.DB.Collection("chores").Find(ctx, models.NotArchived(bson.M{
+		"status": models.ChoreStatusCompleted,
+		"$or": []bson.M{
+			{"completed_at": bson.M{"$lt": cutoff}},
+			{"completed_at": bson.M{"$exists": false}, "updated_at": bson.M{"$lt": cutoff}},
+		},
+	}))
+	if err != nil {
+		log.Printf("Error finding completed chores: %v", err)
+		return
+	}
+	var chores []models.Chore
+	if err := cursor.All(ctx, &chores); err != nil {
+		log.Printf("Error decoding completed chores: %v", err)
+		return
+	}
+
+	choreIDs := make([]primitive.ObjectID, 0, len(chores))
+	groupIDs := make(map[primitive.ObjectID]bool)
+	for i := range chores {
+		if chores[i].CanBeArchived(cutoff) {
+			choreIDs = append(choreIDs, chores[i].ID)
+			groupIDs[chores[i].GroupID] = true
+		}
+	}
+	if len(choreIDs) == 0 {
+		return
+	}
+
+	// The status is checked again in case a chore was reopened in the meantime
+	result, err := config.DB.Collection("chores").UpdateMany(
+		ctx,
+		models.NotArchived(bson.M{
+			"_id":    bson.M{"$in": choreIDs},
+			"status": models.ChoreStatusCompleted,
+		}),
+		bson.M{"$set": bson.M{"archived_at": now}},
+	)
+	if err != nil {
+		log.Printf("Error archiving completed chores: %v", err)
+		return
+	}
+
+	if result.ModifiedCount > 0 {
+		log.Printf("Archived %d completed chores", result.ModifiedCount)
+		for groupID := range groupIDs {
+			InvalidateChoreAnalytics(groupID)
+		}
+	}
+}
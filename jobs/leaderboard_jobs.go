@@ -0,0 +1,248 @@
+// jobs/leaderboard_jobs.go
+package jobs
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StartLeaderboardJobs initializes and starts the leaderboard archival job
+func StartLeaderboardJobs() {
+	log.Println("Starting leaderboard jobs...")
+
+	// Run the archival every 6 hours; snapshots are idempotent so extra runs are harmless
+	ticker := time.NewTicker(6 * time.Hour)
+
+	// Run immediately once at startup
+	go archiveLeaderboards()
+
+	// Then run on the schedule
+	go func() {
+		for range ticker.C {
+			archiveLeaderboards()
+		}
+	}()
+}
+
+// archiveLeaderboards snapshots the most recently finished week and month for every group
+func archiveLeaderboards() {
+	log.Println("Archiving leaderboards...")
+
+	cursor, err := config.DB.Collection("groups").Find(
+		context.Background(),
+		bson.M{},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		log.Printf("Error finding groups for leaderboard archival: %v", err)
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	var groups []models.Group
+	if err = cursor.All(context.Background(), &groups); err != nil {
+		log.Printf("Error decoding groups for leaderboard archival: %v", err)
+		return
+	}
+
+	now := time.Now()
+	archived := 0
+	for _, group := range groups {
+		for _, period := range []models.LeaderboardPeriod{models.LeaderboardPeriodWeek, models.LeaderboardPeriodMonth} {
+			currentStart, currentEnd, _ := models.PeriodRange(period, now)
+			start, end := models.PreviousPeriodRange(period, currentStart, currentEnd)
+
+			created, err := archiveLeaderboardPeriod(group.ID, period, start, end)
+			if err != nil {
+				log.Printf("Error archiving %s leaderboard for group %s: %v", period, group.ID.Hex(), err)
+				continue
+			}
+			if created {
+				archived++
+			}
+		}
+	}
+
+	log.Printf("Completed leaderboard archival, created %d snapshots", archived)
+}
+
+// archiveLeaderboardPeriod stores a snapshot for the given period unless one already exists
+func archiveLeaderboardPeriod(groupID primitive.ObjectID, period models.LeaderboardPeriod, start, end time.Time) (bool, error) {
+	count, err := config.DB.Collection("leaderboard_snapshots").CountDocuments(
+		context.Background(),
+		bson.M{
+			"group_id":     groupID,
+			"period":       period,
+			"period_start": start,
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	entries, err := ComputeLeaderboard(&groupID, start, end)
+	if err != nil {
+		return false, err
+	}
+
+	// Compare against the previous archived snapshot when there is one,
+	// otherwise compute the previous period on the fly
+	previous, err := FindLeaderboardSnapshot(groupID, period, start.Add(-time.Nanosecond))
+	if err != nil {
+		return false, err
+	}
+	if previous != nil {
+		models.ApplyRankChanges(entries, previous.Entries)
+	} else {
+		prevStart, prevEnd := models.PreviousPeriodRange(period, start, end)
+		previousEntries, err := ComputeLeaderboard(&groupID, prevStart, prevEnd)
+		if err != nil {
+			return false, err
+		}
+		models.ApplyRankChanges(entries, previousEntries)
+	}
+
+	snapshot := models.CreateLeaderboardSnapshot(groupID, period, start, end, entries)
+	_, err = config.DB.Collection("leaderboard_snapshots").InsertOne(context.Background(), snapshot)
+	if err != nil {
+		// Another instance archived the same period first
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// FindLeaderboardSnapshot returns the archived snapshot of the period containing ref, or nil if none exists
+func FindLeaderboardSnapshot(groupID primitive.ObjectID, period models.LeaderboardPeriod, ref time.Time) (*models.LeaderboardSnapshot, error) {
+	start, _, err := models.PeriodRange(period, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot models.LeaderboardSnapshot
+	err = config.DB.Collection("leaderboard_snapshots").FindOne(
+		context.Background(),
+		bson.M{
+			"group_id":     groupID,
+			"period":       period,
+			"period_start": start,
+		},
+	).Decode(&snapshot)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// ComputeLeaderboard ranks members by the points they earned from chore completions in [start, end).
+// A nil groupID ranks every user; zero start/end times leave that side of the window open.
+// When a group is given, members without completions are included with zero points.
+func ComputeLeaderboard(groupID *primitive.ObjectID, start, end time.Time) ([]models.LeaderboardEntry, error) {
+	ctx := context.Background()
+
+	match := bson.M{}
+	if groupID != nil {
+		match["group_id"] = *groupID
+	}
+	completedAt := bson.M{}
+	if !start.IsZero() {
+		completedAt["$gte"] = start
+	}
+	if !end.IsZero() {
+		completedAt["$lt"] = end
+	}
+	if len(completedAt) > 0 {
+		match["completed_at"] = completedAt
+	}
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":              "$user_id",
+			"points":           bson.M{"$sum": "$points"},
+			"chores_completed": bson.M{"$sum": 1},
+		}}},
+		bson.D{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		bson.D{{Key: "$unwind", Value: bson.M{"path": "$user", "preserveNullAndEmptyArrays": true}}},
+		bson.D{{Key: "$project", Value: bson.M{
+			"_id":              0,
+			"user_id":          "$_id",
+			"username":         bson.M{"$ifNull": bson.A{"$user.username", ""}},
+			"name":             bson.M{"$ifNull": bson.A{"$user.name", ""}},
+			"points":           1,
+			"chores_completed": 1,
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "points", Value: -1}, {Key: "chores_completed", Value: -1}}}},
+	}
+
+	cursor, err := config.DB.Collection("chore_completions").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := make([]models.LeaderboardEntry, 0)
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	if groupID != nil {
+		// Include current members that have not completed anything in the window
+		seen := make(map[primitive.ObjectID]bool, len(entries))
+		for _, entry := range entries {
+			seen[entry.UserID] = true
+		}
+
+		membersCursor, err := config.DB.Collection("users").Find(
+			ctx,
+			bson.M{"group_id": *groupID},
+			options.Find().SetProjection(bson.M{"_id": 1, "username": 1, "name": 1}),
+		)
+		if err != nil {
+			return nil, err
+		}
+		defer membersCursor.Close(ctx)
+
+		var members []models.User
+		if err = membersCursor.All(ctx, &members); err != nil {
+			return nil, err
+		}
+
+		for _, member := range members {
+			if !seen[member.ID] {
+				entries = append(entries, models.LeaderboardEntry{
+					UserID:   member.ID,
+					Username: member.Username,
+					Name:     member.Name,
+				})
+			}
+		}
+	}
+
+	models.RankLeaderboard(entries)
+	return entries, nil
+}
@@ -0,0 +1,180 @@
+// jobs/pantry_forecast.go
+package jobs
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"cribb-backend/realtime"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// updateConsumptionForecasts learns how fast each pantry item is used from its recent use
+// history, stores the rates, and warns groups about items that will run out within
+// ForecastWarningDays
+func updateConsumptionForecasts() {
+	log.Println("Updating pantry consumption forecasts...")
+	ctx := context.Background()
+	now := time.Now()
+
+	cursor, err := config.DB.Collection("pantry_items").Find(ctx, bson.M{})
+	if err != nil {
+		log.Printf("Error finding pantry items: %v", err)
+		return
+	}
+	var items []models.PantryItem
+	if err = cursor.All(ctx, &items); err != nil {
+		log.Printf("Error decoding pantry items: %v", err)
+		return
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	rates, err := computeConsumptionRates(ctx, items, now)
+	if err != nil {
+		log.Printf("Error computing consumption rates: %v", err)
+		return
+	}
+	if err := storeConsumptionRates(ctx, rates); err != nil {
+		log.Printf("Error storing consumption rates: %v", err)
+		return
+	}
+
+	levels, err := LoadStockLevels(ctx, items)
+	if err != nil {
+		log.Printf("Error loading stock levels: %v", err)
+		return
+	}
+
+	runningOut := 0
+	for i := range items {
+		item := &items[i]
+		rate := rates[item.ID]
+		// Items already low have their own warning
+		if !rate.RunsOutWithin(item.Quantity, models.ForecastWarningDays) || levels[item.ID].NeedsRestock(item.Quantity) {
+			continue
+		}
+		runningOut++
+		forecast := rate.Forecast(item, levels[item.ID], now)
+		if notifyRunningOut(ctx, item, &forecast, now) {
+			PublishGroupEvent(item.GroupID, realtime.EventPantryRunningOut, nil, forecast)
+		}
+	}
+
+	log.Printf("Completed consumption forecasts for %d items, %d running out soon", len(rates), runningOut)
+}
+
+// computeConsumptionRates learns the consumption rate of each item from its use history over
+// the last ForecastWindowDays
+func computeConsumptionRates(ctx context.Context, items []models.PantryItem, now time.Time) (map[primitive.ObjectID]models.ConsumptionRate, error) {
+	cursor, err := config.DB.Collection("pantry_history").Find(ctx, bson.M{
+		"action":     models.ActionTypeUse,
+		"created_at": bson.M{"$gte": now.AddDate(0, 0, -models.ForecastWindowDays)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var history []models.PantryHistory
+	if err = cursor.All(ctx, &history); err != nil {
+		return nil, err
+	}
+
+	historyByItem := make(map[primitive.ObjectID][]models.PantryHistory)
+	for _, record := range history {
+		historyByItem[record.ItemID] = append(historyByItem[record.ItemID], record)
+	}
+
+	rates := make(map[primitive.ObjectID]models.ConsumptionRate, len(items))
+	for _, item := range items {
+		rate := models.ComputeConsumptionRate(historyByItem[item.ID], now)
+		rate.ItemID = item.ID
+		rate.GroupID = item.GroupID
+		rates[item.ID] = rate
+	}
+	return rates, nil
+}
+
+// storeConsumptionRates saves the rates, one per item
+func storeConsumptionRates(ctx context.Context, rates map[primitive.ObjectID]models.ConsumptionRate) error {
+	writes := make([]mongo.WriteModel, 0, len(rates))
+	for itemID, rate := range rates {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"item_id": itemID}).
+			SetUpdate(bson.M{"$set": bson.M{
+				"group_id":     rate.GroupID,
+				"daily_rate":   rate.DailyRate,
+				"days":         rate.Days,
+				"uses":         rate.Uses,
+				"last_used_at": rate.LastUsedAt,
+				"computed_at":  rate.ComputedAt,
+			}}).
+			SetUpsert(true))
+	}
+	_, err := config.DB.Collection("pantry_forecasts").BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// notifyRunningOut warns the group that an item will run out soon unless it was warned in
+// the last ForecastWarningDays, and reports whether it did
+func notifyRunningOut(ctx context.Context, item *models.PantryItem, forecast *models.PantryForecast, now time.Time) bool {
+	count, err := config.DB.Collection("notifications").CountDocuments(ctx, bson.M{
+		"pantry.item_id": item.ID,
+		"type":           models.NotificationTypeRunningOutSoon,
+		"created_at":     bson.M{"$gte": now.AddDate(0, 0, -models.ForecastWarningDays)},
+	})
+	if err != nil {
+		log.Printf("Error checking existing notifications: %v", err)
+		return false
+	}
+	if count > 0 {
+		return false
+	}
+
+	notification := models.CreatePantryNotification(
+		item.GroupID,
+		item.ID,
+		item.Name,
+		models.NotificationTypeRunningOutSoon,
+		models.RunOutMessage(*forecast.DaysLeft),
+	)
+	if _, err := config.DB.Collection("notifications").InsertOne(ctx, notification); err != nil {
+		log.Printf("Error creating running out notification: %v", err)
+		return false
+	}
+	log.Printf("Created running out notification for item: %s", item.Name)
+	return true
+}
+
+// LoadConsumptionRates loads the stored consumption rates of items, keyed by item ID. Items
+// the forecast job has not reached yet have no rate.
+func LoadConsumptionRates(ctx context.Context, items []models.PantryItem) (map[primitive.ObjectID]models.ConsumptionRate, error) {
+	itemIDs := make([]primitive.ObjectID, 0, len(items))
+	for _, item := range items {
+		itemIDs = append(itemIDs, item.ID)
+	}
+
+	rates := make(map[primitive.ObjectID]models.ConsumptionRate, len(items))
+	if len(itemIDs) == 0 {
+		return rates, nil
+	}
+
+	cursor, err := config.DB.Collection("pantry_forecasts").Find(ctx, bson.M{"item_id": bson.M{"$in": itemIDs}})
+	if err != nil {
+		return nil, err
+	}
+	var found []models.ConsumptionRate
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	for _, rate := range found {
+		rates[rate.ItemID] = rate
+	}
+	return rates, nil
+}
@@ -5,14 +5,18 @@ import (
 	"context"
 	"cribb-backend/config"
 	"cribb-backend/models"
+	"cribb-backend/realtime"
 	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// expiredNoticeDays is how long after a lot expires the job still looks at it. Lots expired
+// longer ago are left to the discard prompts.
+const expiredNoticeDays = 7
+
 // StartPantryJobs initializes and starts the pantry background jobs
 func StartPantryJobs() {
 	log.Println("Starting pantry background jobs...")
@@ -23,33 +27,38 @@ func StartPantryJobs() {
 	// Run immediately once at startup
 	go checkExpiringItems()
 	go checkLowStockItems()
+	go updateConsumptionForecasts()
 
 	// Then run on the schedule
 	go func() {
 		for range ticker.C {
 			checkExpiringItems()
 			checkLowStockItems()
+			updateConsumptionForecasts()
 		}
 	}()
 }
 
-// checkExpiringItems looks for items that will expire soon and creates notifications
+// checkExpiringItems looks for lots that will expire soon or have recently expired and creates
+// notifications for them. An expired lot is notified once.
 func checkExpiringItems() {
 	log.Println("Checking for expiring pantry items...")
 
-	// Find items that will expire in the next 3 days
+	// Find lots that will expire in the next 3 days
 	now := time.Now()
 	expirationThreshold := now.AddDate(0, 0, 3)
+	expiredSince := now.AddDate(0, 0, -expiredNoticeDays)
 
-	// Find items that will expire soon but haven't been marked yet
-	// (no existing notification of type expiring_soon)
+	// Find items holding a lot that expires soon or expired within expiredNoticeDays
 	cursor, err := config.DB.Collection("pantry_items").Find(
 		context.Background(),
 		bson.M{
-			"expiration_date": bson.M{
-				"$gte": now,
-				"$lte": expirationThreshold,
-			},
+			"lots": bson.M{"$elemMatch": bson.M{
+				"expiration_date": bson.M{
+					"$gte": expiredSince,
+					"$lte": expirationThreshold,
+				},
+			}},
 		},
 	)
 
@@ -59,120 +68,71 @@ func checkExpiringItems() {
 	}
 	defer cursor.Close(context.Background())
 
-	var expiringItems []models.PantryItem
-	if err = cursor.All(context.Background(), &expiringItems); err != nil {
+	var items []models.PantryItem
+	if err = cursor.All(context.Background(), &items); err != nil {
 		log.Printf("Error decoding expiring items: %v", err)
 		return
 	}
 
-	// Process each item and create notifications if needed
-	for _, item := range expiringItems {
-		// Check if a notification already exists for this item
-		count, err := config.DB.Collection("pantry_notifications").CountDocuments(
-			context.Background(),
-			bson.M{
-				"item_id": item.ID,
-				"type":    models.NotificationTypeExpiringSoon,
-				"created_at": bson.M{
-					"$gte": now.AddDate(0, 0, -3), // Only check for notifications in the last 3 days
-				},
-			},
-		)
-
-		if err != nil {
-			log.Printf("Error checking existing notifications: %v", err)
-			continue
+	// Process each lot and create notifications if needed
+	expiringLots, expiredLots := 0, 0
+	for _, item := range items {
+		item.SyncLots()
+		for _, lot := range item.Lots {
+			switch {
+			case lot.IsExpiringSoon(3):
+				expiringLots++
+				if notifyLotExpiry(item, lot, models.NotificationTypeExpiringSoon, now) {
+					PublishGroupEvent(item.GroupID, realtime.EventPantryExpiring, nil, item)
+				}
+			case lot.IsExpired() && !lot.ExpirationDate.Before(expiredSince):
+				expiredLots++
+				notifyLotExpiry(item, lot, models.NotificationTypeExpired, now)
+			}
 		}
+	}
 
-		// If no notification exists, create one
-		if count == 0 {
-			notification := models.CreatePantryNotification(
-				item.GroupID,
-				item.ID,
-				item.Name,
-				models.NotificationTypeExpiringSoon,
-				"Item will expire in 3 days or less",
-			)
-
-			_, err = config.DB.Collection("pantry_notifications").InsertOne(
-				context.Background(),
-				notification,
-			)
+	log.Printf("Completed expiring items check, found %d expiring and %d expired lots",
+		expiringLots, expiredLots)
+}
 
-			if err != nil {
-				log.Printf("Error creating expiration notification: %v", err)
-			} else {
-				log.Printf("Created expiration notification for item: %s", item.Name)
-			}
+// notifyLotExpiry creates an expiry notification for a lot and reports whether it did. A lot
+// expiring soon is reminded about again after 3 days; an expired lot is notified only once.
+func notifyLotExpiry(item models.PantryItem, lot models.PantryLot, notificationType models.NotificationType, now time.Time) bool {
+	// Check if a notification already exists for this lot
+	filter := bson.M{
+		"pantry.item_id": item.ID,
+		"pantry.lot_id":  lot.ID,
+		"type":           notificationType,
+	}
+	if notificationType != models.NotificationTypeExpired {
+		filter["created_at"] = bson.M{
+			"$gte": now.AddDate(0, 0, -3), // Only check for notifications in the last 3 days
 		}
 	}
-
-	// Also check for already expired items
-	cursor, err = config.DB.Collection("pantry_items").Find(
-		context.Background(),
-		bson.M{
-			"expiration_date": bson.M{
-				"$lt": now,
-			},
-		},
-	)
+	count, err := config.DB.Collection("notifications").CountDocuments(context.Background(), filter)
 
 	if err != nil {
-		log.Printf("Error finding expired items: %v", err)
-		return
+		log.Printf("Error checking existing notifications: %v", err)
+		return false
 	}
-	defer cursor.Close(context.Background())
-
-	var expiredItems []models.PantryItem
-	if err = cursor.All(context.Background(), &expiredItems); err != nil {
-		log.Printf("Error decoding expired items: %v", err)
-		return
+	if count > 0 {
+		return false
 	}
 
-	// Process each expired item
-	for _, item := range expiredItems {
-		// Check if a notification already exists for this item
-		count, err := config.DB.Collection("pantry_notifications").CountDocuments(
-			context.Background(),
-			bson.M{
-				"item_id": item.ID,
-				"type":    models.NotificationTypeExpired,
-				"created_at": bson.M{
-					"$gte": now.AddDate(0, 0, -3), // Only check for notifications in the last 3 days
-				},
-			},
-		)
-
-		if err != nil {
-			log.Printf("Error checking existing notifications: %v", err)
-			continue
-		}
+	notification := models.CreateLotExpiryNotification(&item, &lot, notificationType)
 
-		// If no notification exists, create one
-		if count == 0 {
-			notification := models.CreatePantryNotification(
-				item.GroupID,
-				item.ID,
-				item.Name,
-				models.NotificationTypeExpired,
-				"Item has expired",
-			)
-
-			_, err = config.DB.Collection("pantry_notifications").InsertOne(
-				context.Background(),
-				notification,
-			)
+	_, err = config.DB.Collection("notifications").InsertOne(
+		context.Background(),
+		notification,
+	)
 
-			if err != nil {
-				log.Printf("Error creating expired notification: %v", err)
-			} else {
-				log.Printf("Created expired notification for item: %s", item.Name)
-			}
-		}
+	if err != nil {
+		log.Printf("Error creating %s notification: %v", notificationType, err)
+		return false
 	}
-
-	log.Printf("Completed expiring items check, found %d expiring and %d expired items",
-		len(expiringItems), len(expiredItems))
+	log.Printf("Created %s notification for item: %s", notificationType, item.Name)
+	return true
 }
 
 // checkLowStockItems looks for items that are running low and creates notifications
@@ -200,11 +160,11 @@ func checkLowStockItems() {
 			// Process each out of stock item
 			for _, item := range outOfStockItems {
 				// Check if a notification already exists for this item
-				count, err := config.DB.Collection("pantry_notifications").CountDocuments(
+				count, err := config.DB.Collection("notifications").CountDocuments(
 					context.Background(),
 					bson.M{
-						"item_id": item.ID,
-						"type":    models.NotificationTypeOutOfStock,
+						"pantry.item_id": item.ID,
+						"type":           models.NotificationTypeOutOfStock,
 						"created_at": bson.M{
 							"$gte": now.AddDate(0, 0, -3), // Only check for notifications in the last 3 days
 						},
@@ -219,11 +179,11 @@ func checkLowStockItems() {
 				// If no notification exists, create one
 				if count == 0 {
 					// First delete any existing low stock notifications for this item
-					_, err := config.DB.Collection("pantry_notifications").DeleteMany(
+					_, err := config.DB.Collection("notifications").DeleteMany(
 						context.Background(),
 						bson.M{
-							"item_id": item.ID,
-							"type":    models.NotificationTypeLowStock,
+							"pantry.item_id": item.ID,
+							"type":           models.NotificationTypeLowStock,
 						},
 					)
 
@@ -240,7 +200,7 @@ func checkLowStockItems() {
 						"Item is out of stock",
 					)
 
-					_, err = config.DB.Collection("pantry_notifications").InsertOne(
+					_, err = config.DB.Collection("notifications").InsertOne(
 						context.Background(),
 						notification,
 					)
@@ -257,16 +217,12 @@ func checkLowStockItems() {
 		}
 	}
 
-	// Then handle low stock items (but exclude items with quantity 0)
-	lowStockThreshold := 1.0 // Setting a fixed threshold for simplicity
-
+	// Then handle low stock items (but exclude items with quantity 0). Each item has its own
+	// threshold, so the comparison happens after resolving its stock levels.
 	cursor, err = config.DB.Collection("pantry_items").Find(
 		context.Background(),
 		bson.M{
-			"quantity": bson.M{
-				"$gt":  0,
-				"$lte": lowStockThreshold,
-			},
+			"quantity": bson.M{"$gt": 0},
 		},
 	)
 
@@ -276,20 +232,33 @@ func checkLowStockItems() {
 	}
 	defer cursor.Close(context.Background())
 
-	var lowStockItems []models.PantryItem
-	if err = cursor.All(context.Background(), &lowStockItems); err != nil {
+	var inStockItems []models.PantryItem
+	if err = cursor.All(context.Background(), &inStockItems); err != nil {
 		log.Printf("Error decoding low stock items: %v", err)
 		return
 	}
 
+	levels, err := LoadStockLevels(context.Background(), inStockItems)
+	if err != nil {
+		log.Printf("Error loading stock levels: %v", err)
+		return
+	}
+
+	var lowStockItems []models.PantryItem
+	for _, item := range inStockItems {
+		if levels[item.ID].IsLowStock(item.Quantity) {
+			lowStockItems = append(lowStockItems, item)
+		}
+	}
+
 	// Process each low stock item
 	for _, item := range lowStockItems {
 		// Check if a notification already exists for this item
-		count, err := config.DB.Collection("pantry_notifications").CountDocuments(
+		count, err := config.DB.Collection("notifications").CountDocuments(
 			context.Background(),
 			bson.M{
-				"item_id": item.ID,
-				"type":    models.NotificationTypeLowStock,
+				"pantry.item_id": item.ID,
+				"type":           models.NotificationTypeLowStock,
 				"created_at": bson.M{
 					"$gte": now.AddDate(0, 0, -3), // Only check for notifications in the last 3 days
 				},
@@ -311,7 +280,7 @@ func checkLowStockItems() {
 				"Item is running low",
 			)
 
-			_, err = config.DB.Collection("pantry_notifications").InsertOne(
+			_, err = config.DB.Collection("notifications").InsertOne(
 				context.Background(),
 				notification,
 			)
@@ -320,6 +289,7 @@ func checkLowStockItems() {
 				log.Printf("Error creating low stock notification: %v", err)
 			} else {
 				log.Printf("Created low stock notification for item: %s", item.Name)
+				PublishGroupEvent(item.GroupID, realtime.EventPantryLowStock, nil, item)
 			}
 		}
 	}
@@ -327,17 +297,49 @@ func checkLowStockItems() {
 	log.Printf("Completed low stock check, found %d items", len(lowStockItems))
 }
 
-// GenerateShoppingList automatically creates a shopping list based on low stock items
+// LoadStockLevels resolves the stock levels of items, keyed by item ID, loading the
+// categories they fall back to
+func LoadStockLevels(ctx context.Context, items []models.PantryItem) (map[primitive.ObjectID]models.StockLevels, error) {
+	categoryIDs := make([]primitive.ObjectID, 0)
+	seen := make(map[primitive.ObjectID]bool)
+	for _, item := range items {
+		if !item.CategoryID.IsZero() && !seen[item.CategoryID] {
+			seen[item.CategoryID] = true
+			categoryIDs = append(categoryIDs, item.CategoryID)
+		}
+	}
+
+	categories := make(map[primitive.ObjectID]*models.PantryCategory, len(categoryIDs))
+	if len(categoryIDs) > 0 {
+		cursor, err := config.DB.Collection("pantry_categories").Find(ctx, bson.M{"_id": bson.M{"$in": categoryIDs}})
+		if err != nil {
+			return nil, err
+		}
+		var found []models.PantryCategory
+		if err = cursor.All(ctx, &found); err != nil {
+			return nil, err
+		}
+		for i := range found {
+			categories[found[i].ID] = &found[i]
+		}
+	}
+
+	levels := make(map[primitive.ObjectID]models.StockLevels, len(items))
+	for i := range items {
+		levels[items[i].ID] = models.ResolveStockLevels(&items[i], categories[items[i].CategoryID])
+	}
+	return levels, nil
+}
+
+// GenerateShoppingList automatically creates a shopping list based on low stock items and
+// items forecast to run out within ForecastWarningDays. Each item's suggested quantity covers
+// ForecastCoverDays of use at its consumption rate, and at least tops it back up to its par
+// level.
 func GenerateShoppingList(groupID primitive.ObjectID) ([]map[string]interface{}, error) {
-	// Find all low stock items
+	// Find the group's items; which of them are low depends on their own thresholds
 	cursor, err := config.DB.Collection("pantry_items").Find(
 		context.Background(),
-		bson.M{
-			"group_id": groupID,
-			"quantity": bson.M{
-				"$lte": 1.0, // Low stock threshold
-			},
-		},
+		bson.M{"group_id": groupID},
 	)
 
 	if err != nil {
@@ -350,8 +352,17 @@ func GenerateShoppingList(groupID primitive.ObjectID) ([]map[string]interface{},
 		return nil, err
 	}
 
+	levels, err := LoadStockLevels(context.Background(), items)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := LoadConsumptionRates(context.Background(), items)
+	if err != nil {
+		return nil, err
+	}
+
 	// Also include items with notifications of type low_stock
-	notifCursor, err := config.DB.Collection("pantry_notifications").Find(
+	notifCursor, err := config.DB.Collection("notifications").Find(
 		context.Background(),
 		bson.M{
 			"group_id": groupID,
@@ -367,70 +378,102 @@ func GenerateShoppingList(groupID primitive.ObjectID) ([]map[string]interface{},
 	}
 	defer notifCursor.Close(context.Background())
 
-	var notifications []models.PantryNotification
+	var notifications []models.Notification
 	if err = notifCursor.All(context.Background(), &notifications); err != nil {
 		return nil, err
 	}
 
 	// Create a map to track items already in the shopping list
 	itemMap := make(map[string]bool)
+	itemsByID := make(map[primitive.ObjectID]models.PantryItem, len(items))
 	shoppingList := make([]map[string]interface{}, 0)
 
 	// Add low stock items to the shopping list
 	for _, item := range items {
-		if !itemMap[item.ID.Hex()] {
-			itemMap[item.ID.Hex()] = true
+		itemsByID[item.ID] = item
+		itemLevels := levels[item.ID]
+		rate := rates[item.ID]
+		if !itemLevels.NeedsRestock(item.Quantity) || itemMap[item.ID.Hex()] {
+			continue
+		}
+		itemMap[item.ID.Hex()] = true
 
-			// Suggest a quantity to buy based on typical usage
-			suggestedQuantity := 1.0
-			if item.Quantity <= 0 {
-				suggestedQuantity = 2.0 // If completely out, suggest buying 2
-			}
+		reason := "Low stock"
+		if item.Quantity <= 0 {
+			reason = "Out of stock"
+		}
 
-			shoppingList = append(shoppingList, map[string]interface{}{
-				"item_id":            item.ID.Hex(),
-				"name":               item.Name,
-				"category":           item.Category,
-				"current_quantity":   item.Quantity,
-				"unit":               item.Unit,
-				"suggested_quantity": suggestedQuantity,
-				"reason":             "Low stock",
-			})
+		shoppingList = append(shoppingList, map[string]interface{}{
+			"item_id":             item.ID.Hex(),
+			"name":                item.Name,
+			"category":            item.Category,
+			"current_quantity":    item.Quantity,
+			"unit":                item.Unit,
+			"low_stock_threshold": itemLevels.LowStockThreshold,
+			"par_level":           itemLevels.ParLevel,
+			"suggested_quantity":  rate.SuggestedQuantity(item.Quantity, itemLevels),
+			"daily_rate":          rate.DailyRate,
+			"reason":              reason,
+		})
+	}
+
+	// Add items that are not low yet but will run out soon at the rate they are used
+	now := time.Now()
+	for _, item := range items {
+		rate := rates[item.ID]
+		if itemMap[item.ID.Hex()] || !rate.RunsOutWithin(item.Quantity, models.ForecastWarningDays) {
+			continue
 		}
+		itemMap[item.ID.Hex()] = true
+
+		itemLevels := levels[item.ID]
+		forecast := rate.Forecast(&item, itemLevels, now)
+		shoppingList = append(shoppingList, map[string]interface{}{
+			"item_id":             item.ID.Hex(),
+			"name":                item.Name,
+			"category":            item.Category,
+			"current_quantity":    item.Quantity,
+			"unit":                item.Unit,
+			"low_stock_threshold": itemLevels.LowStockThreshold,
+			"par_level":           itemLevels.ParLevel,
+			"suggested_quantity":  forecast.SuggestedQuantity,
+			"daily_rate":          rate.DailyRate,
+			"run_out_date":        forecast.RunOutDate,
+			"reason":              models.RunOutMessage(*forecast.DaysLeft),
+		})
 	}
 
-	// Add items from low stock notifications if not already in the list
+	// Add items from low stock notifications if not already in the list and still below par
 	for _, notification := range notifications {
-		if !itemMap[notification.ItemID.Hex()] {
-			// Fetch the current item data
-			var item models.PantryItem
-			err := config.DB.Collection("pantry_items").FindOne(
-				context.Background(),
-				bson.M{"_id": notification.ItemID},
-			).Decode(&item)
-
-			if err != nil {
-				if err != mongo.ErrNoDocuments {
-					log.Printf("Error fetching item %s: %v", notification.ItemID.Hex(), err)
-				}
-				continue
-			}
-
-			itemMap[notification.ItemID.Hex()] = true
+		if notification.Pantry == nil || itemMap[notification.Pantry.ItemID.Hex()] {
+			continue
+		}
 
-			// Suggest a quantity based on typical usage
-			suggestedQuantity := 2.0 // Default suggestion
+		item, found := itemsByID[notification.Pantry.ItemID]
+		if !found {
+			continue
+		}
 
-			shoppingList = append(shoppingList, map[string]interface{}{
-				"item_id":            item.ID.Hex(),
-				"name":               item.Name,
-				"category":           item.Category,
-				"current_quantity":   item.Quantity,
-				"unit":               item.Unit,
-				"suggested_quantity": suggestedQuantity,
-				"reason":             notification.Message,
-			})
+		itemLevels := levels[item.ID]
+		rate := rates[item.ID]
+		suggestedQuantity := rate.SuggestedQuantity(item.Quantity, itemLevels)
+		if suggestedQuantity <= 0 {
+			continue
 		}
+		itemMap[item.ID.Hex()] = true
+
+		shoppingList = append(shoppingList, map[string]interface{}{
+			"item_id":             item.ID.Hex(),
+			"name":                item.Name,
+			"category":            item.Category,
+			"current_quantity":    item.Quantity,
+			"unit":                item.Unit,
+			"low_stock_threshold": itemLevels.LowStockThreshold,
+			"par_level":           itemLevels.ParLevel,
+			"suggested_quantity":  suggestedQuantity,
+			"daily_rate":          rate.DailyRate,
+			"reason":              notification.Message,
+		})
 	}
 
 	return shoppingList, nil
@@ -0,0 +1,133 @@
+// jobs/push_notifications.go
+package jobs
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"cribb-backend/notify"
+	"cribb-backend/webhook"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PushInterval is how often new notifications are pushed to subscribed devices
+const PushInterval = 30 * time.Second
+
+// pushWindow bounds how old a notification may be and still get pushed, so that turning
+// Web Push on does not push the whole notification history
+const pushWindow = time.Hour
+
+var (
+	webPush     *notify.WebPush
+	webPushErr  error
+	webPushOnce sync.Once
+)
+
+// WebPush returns the Web Push sender, or nil when no VAPID keys are configured
+func WebPush() (*notify.WebPush, error) {
+	webPushOnce.Do(func() {
+		if !config.WebPushEnabled() {
+			return
+		}
+		keys, err := notify.ParseVAPIDKeys(config.VAPIDPublicKey, config.VAPIDPrivateKey, config.VAPIDSubject)
+		if err != nil {
+			webPushErr = err
+			return
+		}
+		webPush = notify.NewWebPush(keys, notify.NewMongoPushSubscriptionStore(config.DB), webhook.NewClient(false))
+	})
+	return webPush, webPushErr
+}
+
+// StartPushNotifier starts pushing new notifications to subscribed devices, if Web Push is
+// configured
+func StartPushNotifier() {
+	sender, err := WebPush()
+	if err != nil {
+		log.Fatal("Invalid VAPID configuration:", err)
+	}
+	if sender == nil {
+		log.Println("Web Push is not configured; skipping push notifier")
+		return
+	}
+
+	log.Println("Starting push notifier...")
+
+	ticker := time.NewTicker(PushInterval)
+
+	go func() {
+		for range ticker.C {
+			pushPendingNotifications(sender)
+		}
+	}()
+}
+
+// pushPendingNotifications claims and pushes each notification created since the last run.
+// Claiming marks the notification first, so like reminders a push is sent at most once
+// even with several instances running.
+func pushPendingNotifications(sender *notify.WebPush) {
+	ctx := context.Background()
+
+	for {
+		now := time.Now()
+		var notification models.Notification
+		err := config.DB.Collection("notifications").FindOneAndUpdate(ctx,
+			bson.M{
+				"pushed_at":  bson.M{"$exists": false},
+				"created_at": bson.M{"$gt": now.Add(-pushWindow)},
+			},
+			bson.M{"$set": bson.M{"pushed_at": now}},
+			options.FindOneAndUpdate().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+		).Decode(&notification)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return
+		}
+		if err != nil {
+			log.Printf("Error claiming notification to push: %v", err)
+			return
+		}
+
+		if err := pushNotification(ctx, sender, &notification, now); err != nil {
+			log.Printf("Error pushing notification %s: %v", notification.ID.Hex(), err)
+		}
+	}
+}
+
+// pushNotification sends a notification to the devices of every member it is meant for
+func pushNotification(ctx context.Context, sender *notify.WebPush, notification *models.Notification, now time.Time) error {
+	var group models.Group
+	err := config.DB.Collection("groups").FindOne(ctx, bson.M{"_id": notification.GroupID}).Decode(&group)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := config.DB.Collection("users").Find(ctx,
+		bson.M{"_id": bson.M{"$in": group.Members}},
+		options.Find().SetProjection(bson.M{"_id": 1, "notifications": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	var members []models.User
+	if err = cursor.All(ctx, &members); err != nil {
+		return err
+	}
+
+	recipients := models.PushRecipients(notification, members)
+	result, err := sender.Push(ctx, recipients, notify.NewPushPayload(notification, group.Name), now)
+	if err != nil {
+		return err
+	}
+	if result.Failed > 0 {
+		log.Printf("Push for notification %s: %d delivered, %d failed, %d subscriptions removed",
+			notification.ID.Hex(), result.Delivered, result.Failed, result.Removed)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// jobs/realtime.go
+package jobs
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/realtime"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// realtimePublishTimeout bounds how long a request waits to publish an event
+const realtimePublishTimeout = 2 * time.Second
+
+var (
+	realtimeHub     *realtime.Hub
+	realtimeHubOnce sync.Once
+)
+
+// RealtimeHub returns the hub group event streams are served from. Events go through
+// MongoDB when REALTIME_BACKEND is "mongo", so that several instances can share them.
+func RealtimeHub() *realtime.Hub {
+	realtimeHubOnce.Do(func() {
+		var backend realtime.Backend = realtime.NewMemoryBackend()
+		if config.RealtimeBackend == "mongo" {
+			backend = realtime.NewMongoBackend(config.DB)
+		}
+		realtimeHub = realtime.NewHub(backend, realtime.DefaultHistory)
+	})
+	return realtimeHub
+}
+
+// StartRealtimeHub starts receiving events for the group streams
+func StartRealtimeHub() {
+	log.Printf("Starting realtime hub with the %s backend...", config.RealtimeBackend)
+	RealtimeHub().Start(context.Background())
+}
+
+// PublishGroupEvent broadcasts a change to the group's connected members and queues it for
+// the group's webhooks. Failures are only logged: they must not fail the change itself.
+func PublishGroupEvent(groupID primitive.ObjectID, eventType realtime.EventType, actorID *primitive.ObjectID, data interface{}) {
+	event, err := realtime.NewEvent(groupID, eventType, actorID, data)
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v", eventType, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), realtimePublishTimeout)
+	defer cancel()
+	if err := RealtimeHub().Publish(ctx, event); err != nil {
+		log.Printf("Failed to publish %s event: %v", eventType, err)
+	}
+	if err := enqueueWebhookDeliveries(ctx, event); err != nil {
+		log.Printf("Failed to queue webhooks for %s event: %v", eventType, err)
+	}
+}
@@ -0,0 +1,188 @@
+// jobs/webhooks.go
+package jobs
+
+import (
+	"context"
+	"cribb-backend/config"
+	"cribb-backend/models"
+	"cribb-backend/realtime"
+	"cribb-backend/webhook"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WebhookInterval is how often the webhook worker looks for deliveries that are due.
+// New events wake it up straight away.
+const WebhookInterval = 10 * time.Second
+
+// webhookLockTime is how long a claimed delivery is reserved for its worker. A delivery
+// whose worker died is retried once the lock runs out, so receivers may see a delivery
+// twice and should deduplicate on the delivery ID header.
+const webhookLockTime = 2 * time.Minute
+
+var (
+	webhookClient     *http.Client
+	webhookClientOnce sync.Once
+
+	// webhookWake nudges the worker when deliveries are queued
+	webhookWake = make(chan struct{}, 1)
+)
+
+func webhookHTTPClient() *http.Client {
+	webhookClientOnce.Do(func() {
+		webhookClient = webhook.NewClient(config.WebhookAllowPrivateNetworks)
+	})
+	return webhookClient
+}
+
+// StartWebhookWorker starts delivering queued webhook events
+func StartWebhookWorker() {
+	log.Println("Starting webhook worker...")
+
+	ticker := time.NewTicker(WebhookInterval)
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+			case <-webhookWake:
+			}
+			processWebhookDeliveries()
+		}
+	}()
+}
+
+// EnqueueWebhookDelivery saves a delivery and wakes the worker for it
+func EnqueueWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if delivery.ID.IsZero() {
+		delivery.ID = primitive.NewObjectID()
+	}
+	_, err := config.DB.Collection("webhook_deliveries").InsertOne(ctx, delivery)
+	if err != nil {
+		return err
+	}
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// enqueueWebhookDeliveries queues an event for every active webhook of its group that
+// subscribes to its type
+func enqueueWebhookDeliveries(ctx context.Context, event realtime.Event) error {
+	cursor, err := config.DB.Collection("webhooks").Find(ctx, bson.M{
+		"group_id": event.GroupID,
+		"active":   true,
+		"events":   string(event.Type),
+	})
+	if err != nil {
+		return err
+	}
+	var webhooks []models.Webhook
+	if err = cursor.All(ctx, &webhooks); err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range webhooks {
+		delivery := models.NewWebhookDelivery(&webhooks[i], event.ID, string(event.Type), string(payload), now)
+		if err := EnqueueWebhookDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processWebhookDeliveries claims and attempts every delivery that is due
+func processWebhookDeliveries() {
+	ctx := context.Background()
+
+	for {
+		now := time.Now()
+		lockedUntil := now.Add(webhookLockTime)
+
+		var delivery models.WebhookDelivery
+		err := config.DB.Collection("webhook_deliveries").FindOneAndUpdate(ctx,
+			bson.M{"$or": []bson.M{
+				{"status": models.WebhookDeliveryPending, "next_attempt_at": bson.M{"$lte": now}},
+				{"status": models.WebhookDeliveryDelivering, "locked_until": bson.M{"$lt": now}},
+			}},
+			bson.M{"$set": bson.M{
+				"status":       models.WebhookDeliveryDelivering,
+				"locked_until": lockedUntil,
+				"updated_at":   now,
+			}},
+			options.FindOneAndUpdate().
+				SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+				SetReturnDocument(options.After),
+		).Decode(&delivery)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return
+		}
+		if err != nil {
+			log.Printf("Error claiming webhook delivery: %v", err)
+			return
+		}
+
+		attemptWebhookDelivery(ctx, &delivery, now)
+	}
+}
+
+// attemptWebhookDelivery sends a claimed delivery once and records the outcome
+func attemptWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery, now time.Time) {
+	var hook models.Webhook
+	err := config.DB.Collection("webhooks").FindOne(ctx, bson.M{"_id": delivery.WebhookID}).Decode(&hook)
+
+	var attempt models.WebhookAttempt
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		attempt = models.WebhookAttempt{At: now, Error: "webhook was deleted"}
+		delivery.DeadLetter(attempt)
+	case err != nil:
+		log.Printf("Error fetching webhook %s: %v", delivery.WebhookID.Hex(), err)
+		return
+	case !hook.Active:
+		// Nothing is sent while a webhook is disabled; dead-letter so it can be redelivered
+		attempt = models.WebhookAttempt{At: now, Error: "webhook is disabled"}
+		delivery.DeadLetter(attempt)
+	default:
+		attempt = webhook.Send(ctx, webhookHTTPClient(), &hook, delivery, now)
+		delivery.RecordAttempt(attempt)
+	}
+
+	_, err = config.DB.Collection("webhook_deliveries").UpdateOne(ctx,
+		bson.M{"_id": delivery.ID, "status": models.WebhookDeliveryDelivering},
+		bson.M{
+			"$set": bson.M{
+				"status":          delivery.Status,
+				"next_attempt_at": delivery.NextAttemptAt,
+				"updated_at":      delivery.UpdatedAt,
+			},
+			"$unset": bson.M{"locked_until": ""},
+			"$push":  bson.M{"attempts": attempt},
+		},
+	)
+	if err != nil {
+		log.Printf("Error recording webhook delivery %s: %v", delivery.ID.Hex(), err)
+	}
+	if delivery.Status == models.WebhookDeliveryDead {
+		log.Printf("Webhook delivery %s dead-lettered: %s", delivery.ID.Hex(), attempt.Error)
+	}
+}
@@ -1,22 +1,54 @@
 package main
 
 import (
+	"context"
+	"cribb-backend/catalog"
 	"cribb-backend/config"
 	"cribb-backend/handlers"
 	"cribb-backend/jobs"
 	"cribb-backend/middleware"
+	"cribb-backend/notify"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 )
 
 func main() {
+	generateVAPIDKeys := flag.Bool("generate-vapid-keys", false, "print a new VAPID key pair for Web Push and exit")
+	importCatalog := flag.String("import-catalog", "", "import products from an Open Food Facts CSV or JSON dump at this path and exit")
+	flag.Parse()
+
+	if *generateVAPIDKeys {
+		publicKey, privateKey, err := notify.GenerateVAPIDKeys()
+		if err != nil {
+			log.Fatal("Failed to generate VAPID keys:", err)
+		}
+		fmt.Printf("VAPID_PUBLIC_KEY=%s\nVAPID_PRIVATE_KEY=%s\n", publicKey, privateKey)
+		return
+	}
+
 	// Connect to MongoDB and initialize collections
 	config.ConnectDB()
 
+	if *importCatalog != "" {
+		stats, err := catalog.ImportFile(context.Background(), config.DB, *importCatalog)
+		if err != nil {
+			log.Fatal("Failed to import product catalog:", err)
+		}
+		fmt.Printf("Imported %d products (%d new, %d updated, %d records skipped)\n",
+			stats.Read, stats.Inserted, stats.Updated, stats.Skipped)
+		return
+	}
+
 	// Start the background jobs
 	jobs.StartChoreScheduler()
 	jobs.StartPantryJobs() // Start the pantry background jobs
+	jobs.StartLeaderboardJobs()
+	jobs.StartReminderScheduler()
+	jobs.StartPushNotifier()
+	jobs.StartRealtimeHub()
+	jobs.StartWebhookWorker()
 
 	// Register routes
 	http.HandleFunc("/health", middleware.CORSMiddleware(func(w http.ResponseWriter, r *http.Request) {
@@ -32,6 +64,7 @@ func main() {
 	http.HandleFunc("/api/users", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetUsersHandler)))
 	http.HandleFunc("/api/users/by-username", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetUserByUsernameHandler)))
 	http.HandleFunc("/api/users/by-score", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetUsersByScoreHandler)))
+	http.HandleFunc("/api/users/reminder-preferences", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.ReminderPreferencesHandler)))
 
 	// Group routes - wrap existing middleware with CORS middleware
 	http.HandleFunc("/api/groups", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.CreateGroupHandler)))
@@ -40,6 +73,7 @@ func main() {
 	http.HandleFunc("/api/groups/members", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetGroupMembersHandler)))
 	http.HandleFunc("/api/groups/details", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetGroupDetailsHandler)))
 	http.HandleFunc("/api/groups/leaderboard", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetGroupLeaderboardHandler)))
+	http.HandleFunc("/api/groups/leaderboard/history", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetLeaderboardHistoryHandler)))
 
 	// Chore routes - existing - wrap with CORS middleware
 	http.HandleFunc("/api/chores/individual", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.CreateIndividualChoreHandler)))
@@ -56,6 +90,80 @@ func main() {
 	http.HandleFunc("/api/chores/recurring/delete", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.DeleteRecurringChoreHandler)))
 	http.HandleFunc("/api/chores/clear-completed", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.ClearCompletedChoresHandler)))
 
+	// Chore analytics routes
+	updateTimezoneValidation := middleware.ValidateRequest(handlers.UpdateGroupTimezoneHandler, handlers.UpdateGroupTimezoneRequest{})
+	http.HandleFunc("/api/chores/analytics", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetChoreAnalyticsHandler)))
+	http.HandleFunc("/api/groups/timezone", middleware.CORSMiddleware(middleware.AuthMiddleware(updateTimezoneValidation)))
+
+	// Chore history and archive routes
+	restoreChoreValidation := middleware.ValidateRequest(handlers.RestoreChoreHandler, handlers.RestoreChoreRequest{})
+	http.HandleFunc("/api/chores/history", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetChoreHistoryHandler)))
+	http.HandleFunc("/api/chores/restore", middleware.CORSMiddleware(middleware.AuthMiddleware(restoreChoreValidation)))
+
+	// Chore subtask routes
+	tickSubtaskValidation := middleware.ValidateRequest(handlers.TickSubtaskHandler, handlers.TickSubtaskRequest{})
+	http.HandleFunc("/api/chores/subtasks", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.UpdateSubtasksHandler)))
+	http.HandleFunc("/api/chores/subtasks/tick", middleware.CORSMiddleware(middleware.AuthMiddleware(tickSubtaskValidation)))
+
+	// Chore comment routes
+	createCommentValidation := middleware.ValidateRequest(handlers.CreateChoreCommentHandler, handlers.CreateChoreCommentRequest{})
+	updateCommentValidation := middleware.ValidateRequest(handlers.UpdateChoreCommentHandler, handlers.UpdateChoreCommentRequest{})
+	http.HandleFunc("/api/chores/comments", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetChoreCommentsHandler)))
+	http.HandleFunc("/api/chores/comments/create", middleware.CORSMiddleware(middleware.AuthMiddleware(createCommentValidation)))
+	http.HandleFunc("/api/chores/comments/update", middleware.CORSMiddleware(middleware.AuthMiddleware(updateCommentValidation)))
+	http.HandleFunc("/api/chores/comments/delete", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.DeleteChoreCommentHandler)))
+
+	// Notification routes, shared by chores, pantry and shopping cart. The group routes are kept for older clients.
+	markAllNotificationsReadValidation := middleware.ValidateRequest(handlers.MarkAllNotificationsReadHandler, handlers.MarkAllNotificationsReadRequest{})
+	http.HandleFunc("/api/notifications", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetNotificationsHandler)))
+	http.HandleFunc("/api/notifications/unread-count", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetUnreadNotificationCountHandler)))
+	http.HandleFunc("/api/notifications/read", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.MarkNotificationsReadHandler)))
+	http.HandleFunc("/api/notifications/read-all", middleware.CORSMiddleware(middleware.AuthMiddleware(markAllNotificationsReadValidation)))
+	http.HandleFunc("/api/notifications/settings", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.NotificationSettingsHandler)))
+	http.HandleFunc("/api/groups/notifications", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetNotificationsHandler)))
+	http.HandleFunc("/api/groups/notifications/read", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.MarkNotificationsReadHandler)))
+
+	// Realtime group events, streamed as Server-Sent Events
+	http.HandleFunc("/api/groups/events", middleware.CORSMiddleware(middleware.StreamAuthMiddleware(handlers.GroupEventsHandler)))
+
+	// Web Push routes: one subscription per browser or device
+	pushSubscribeValidation := middleware.ValidateRequest(handlers.SubscribePushHandler, handlers.PushSubscribeRequest{})
+	pushUnsubscribeValidation := middleware.ValidateRequest(handlers.UnsubscribePushHandler, handlers.PushUnsubscribeRequest{})
+	http.HandleFunc("/api/push/vapid-public-key", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetVAPIDPublicKeyHandler)))
+	http.HandleFunc("/api/push/subscriptions", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetPushSubscriptionsHandler)))
+	http.HandleFunc("/api/push/subscribe", middleware.CORSMiddleware(middleware.AuthMiddleware(pushSubscribeValidation)))
+	http.HandleFunc("/api/push/unsubscribe", middleware.CORSMiddleware(middleware.AuthMiddleware(pushUnsubscribeValidation)))
+
+	// Outbound webhook routes: signed deliveries of group events to external URLs
+	createWebhookValidation := middleware.ValidateRequest(handlers.CreateWebhookHandler, handlers.CreateWebhookRequest{})
+	updateWebhookValidation := middleware.ValidateRequest(handlers.UpdateWebhookHandler, handlers.UpdateWebhookRequest{})
+	redeliverWebhookValidation := middleware.ValidateRequest(handlers.RedeliverWebhookHandler, handlers.RedeliverWebhookRequest{})
+	http.HandleFunc("/api/webhooks", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetWebhooksHandler)))
+	http.HandleFunc("/api/webhooks/events", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetWebhookEventsHandler)))
+	http.HandleFunc("/api/webhooks/create", middleware.CORSMiddleware(middleware.AuthMiddleware(createWebhookValidation)))
+	http.HandleFunc("/api/webhooks/update", middleware.CORSMiddleware(middleware.AuthMiddleware(updateWebhookValidation)))
+	http.HandleFunc("/api/webhooks/delete", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.DeleteWebhookHandler)))
+	http.HandleFunc("/api/webhooks/deliveries", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetWebhookDeliveriesHandler)))
+	http.HandleFunc("/api/webhooks/redeliver", middleware.CORSMiddleware(middleware.AuthMiddleware(redeliverWebhookValidation)))
+
+	// iCalendar import routes: preview an uploaded file, then commit it
+	commitImportValidation := middleware.ValidateRequest(handlers.CommitChoreImportHandler, handlers.CommitChoreImportRequest{})
+	http.HandleFunc("/api/chores/import/preview", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.PreviewChoreImportHandler)))
+	http.HandleFunc("/api/chores/import/commit", middleware.CORSMiddleware(middleware.AuthMiddleware(commitImportValidation)))
+
+	// Calendar routes. The ICS feed is authenticated by its token, not a JWT, so calendar apps can subscribe.
+	http.HandleFunc("/api/calendar", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetCalendarHandler)))
+	http.HandleFunc("/api/calendar/feed-token", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.RotateCalendarFeedTokenHandler)))
+	http.HandleFunc("/api/calendar/feed.ics", middleware.CORSMiddleware(handlers.CalendarFeedHandler))
+
+	// Chore template routes
+	createTemplateValidation := middleware.ValidateRequest(handlers.CreateChoreTemplateHandler, handlers.CreateChoreTemplateRequest{})
+	applyTemplatesValidation := middleware.ValidateRequest(handlers.ApplyChoreTemplatesHandler, handlers.ApplyChoreTemplatesRequest{})
+	http.HandleFunc("/api/chores/templates", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetChoreTemplatesHandler)))
+	http.HandleFunc("/api/chores/templates/create", middleware.CORSMiddleware(middleware.AuthMiddleware(createTemplateValidation)))
+	http.HandleFunc("/api/chores/templates/delete", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.DeleteChoreTemplateHandler)))
+	http.HandleFunc("/api/chores/templates/apply", middleware.CORSMiddleware(middleware.AuthMiddleware(applyTemplatesValidation)))
+
 	// Pantry Category routes - NEW STRUCTURED ENDPOINT
 	// GET /api/pantry/categories?group_name={group_name} - Returns structured response with predefined and user_defined categories
 	http.HandleFunc("/api/pantry/categories", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetPantryCategoriesHandler)))
@@ -64,6 +172,10 @@ func main() {
 	createCategoryValidation := middleware.ValidateRequest(handlers.CreatePantryCategoryHandler, handlers.CreateCategoryRequest{})
 	http.HandleFunc("/api/pantry/categories/create", middleware.CORSMiddleware(middleware.AuthMiddleware(createCategoryValidation)))
 
+	// Default stock levels of a custom category
+	categoryStockLevelsValidation := middleware.ValidateRequest(handlers.SetCategoryStockLevelsHandler, handlers.SetCategoryStockLevelsRequest{})
+	http.HandleFunc("/api/pantry/categories/stock-levels", middleware.CORSMiddleware(middleware.AuthMiddleware(categoryStockLevelsValidation)))
+
 	// Update/Delete category routes (dynamic based on method)
 	http.HandleFunc("/api/pantry/categories/", middleware.CORSMiddleware(middleware.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -91,6 +203,67 @@ func main() {
 	usePantryValidation := middleware.ValidateRequest(handlers.UsePantryItemHandler, handlers.UsePantryItemRequest{})
 	http.HandleFunc("/api/pantry/use", middleware.CORSMiddleware(middleware.AuthMiddleware(usePantryValidation)))
 
+	// Low-stock threshold and par level of a pantry item
+	pantryStockLevelsValidation := middleware.ValidateRequest(handlers.SetPantryStockLevelsHandler, handlers.SetPantryStockLevelsRequest{})
+	http.HandleFunc("/api/pantry/stock-levels", middleware.CORSMiddleware(middleware.AuthMiddleware(pantryStockLevelsValidation)))
+
+	// Correct the quantity or expiration date of one lot of a pantry item
+	pantryLotValidation := middleware.ValidateRequest(handlers.UpdatePantryLotHandler, handlers.UpdatePantryLotRequest{})
+	http.HandleFunc("/api/pantry/lots/update", middleware.CORSMiddleware(middleware.AuthMiddleware(pantryLotValidation)))
+
+	// Storage locations and moving pantry items between them
+	createLocationValidation := middleware.ValidateRequest(handlers.CreateStorageLocationHandler, handlers.CreateStorageLocationRequest{})
+	updateLocationValidation := middleware.ValidateRequest(handlers.UpdateStorageLocationHandler, handlers.UpdateStorageLocationRequest{})
+	movePantryValidation := middleware.ValidateRequest(handlers.MovePantryItemHandler, handlers.MovePantryItemRequest{})
+	http.HandleFunc("/api/pantry/locations", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetStorageLocationsHandler)))
+	http.HandleFunc("/api/pantry/locations/create", middleware.CORSMiddleware(middleware.AuthMiddleware(createLocationValidation)))
+	http.HandleFunc("/api/pantry/locations/update", middleware.CORSMiddleware(middleware.AuthMiddleware(updateLocationValidation)))
+	http.HandleFunc("/api/pantry/locations/delete", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.DeleteStorageLocationHandler)))
+	http.HandleFunc("/api/pantry/move", middleware.CORSMiddleware(middleware.AuthMiddleware(movePantryValidation)))
+
+	// Throwing pantry stock away, and how much the group wastes
+	discardPantryValidation := middleware.ValidateRequest(handlers.DiscardPantryItemHandler, handlers.DiscardPantryItemRequest{})
+	http.HandleFunc("/api/pantry/discard", middleware.CORSMiddleware(middleware.AuthMiddleware(discardPantryValidation)))
+	http.HandleFunc("/api/pantry/discard/prompts", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetDiscardPromptsHandler)))
+	http.HandleFunc("/api/pantry/waste", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetWasteReportHandler)))
+
+	// Who pantry items belong to, and permission to use other members' items
+	pantryOwnershipValidation := middleware.ValidateRequest(handlers.SetPantryOwnershipHandler, handlers.SetPantryOwnershipRequest{})
+	requestPantryUseValidation := middleware.ValidateRequest(handlers.RequestPantryUseHandler, handlers.RequestPantryUseRequest{})
+	allowPantryUseValidation := middleware.ValidateRequest(handlers.AllowPantryUseHandler, handlers.AllowPantryUseRequest{})
+	http.HandleFunc("/api/pantry/ownership", middleware.CORSMiddleware(middleware.AuthMiddleware(pantryOwnershipValidation)))
+	http.HandleFunc("/api/pantry/permission/request", middleware.CORSMiddleware(middleware.AuthMiddleware(requestPantryUseValidation)))
+	http.HandleFunc("/api/pantry/permission", middleware.CORSMiddleware(middleware.AuthMiddleware(allowPantryUseValidation)))
+
+	// Shelf-life rules used to estimate expiration dates
+	shelfLifeValidation := middleware.ValidateRequest(handlers.SetShelfLifeRuleHandler, handlers.SetShelfLifeRuleRequest{})
+	http.HandleFunc("/api/pantry/shelf-life", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetShelfLifeRulesHandler)))
+	http.HandleFunc("/api/pantry/shelf-life/set", middleware.CORSMiddleware(middleware.AuthMiddleware(shelfLifeValidation)))
+	http.HandleFunc("/api/pantry/shelf-life/delete", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.DeleteShelfLifeRuleHandler)))
+
+	// Recipes, the weekly meal plan and cooking from the pantry
+	createRecipeValidation := middleware.ValidateRequest(handlers.CreateRecipeHandler, handlers.CreateRecipeRequest{})
+	updateRecipeValidation := middleware.ValidateRequest(handlers.UpdateRecipeHandler, handlers.UpdateRecipeRequest{})
+	addMealPlanValidation := middleware.ValidateRequest(handlers.AddMealPlanEntryHandler, handlers.AddMealPlanEntryRequest{})
+	mealPlanCartValidation := middleware.ValidateRequest(handlers.AddMealPlanToCartHandler, handlers.MealPlanWeekRequest{})
+	http.HandleFunc("/api/recipes", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetRecipesHandler)))
+	http.HandleFunc("/api/recipes/create", middleware.CORSMiddleware(middleware.AuthMiddleware(createRecipeValidation)))
+	http.HandleFunc("/api/recipes/update", middleware.CORSMiddleware(middleware.AuthMiddleware(updateRecipeValidation)))
+	http.HandleFunc("/api/recipes/delete", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.DeleteRecipeHandler)))
+	http.HandleFunc("/api/recipes/coverage", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetRecipeCoverageHandler)))
+	http.HandleFunc("/api/recipes/cook", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.CookRecipeHandler)))
+	http.HandleFunc("/api/meal-plan", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetMealPlanHandler)))
+	http.HandleFunc("/api/meal-plan/add", middleware.CORSMiddleware(middleware.AuthMiddleware(addMealPlanValidation)))
+	http.HandleFunc("/api/meal-plan/remove", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.DeleteMealPlanEntryHandler)))
+	http.HandleFunc("/api/meal-plan/coverage", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetMealPlanCoverageHandler)))
+	http.HandleFunc("/api/meal-plan/cart", middleware.CORSMiddleware(middleware.AuthMiddleware(mealPlanCartValidation)))
+
+	// Product catalog lookup by barcode
+	http.HandleFunc("/api/products/lookup", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetProductByBarcodeHandler)))
+
+	// Canonical units of measure for pantry and shopping quantities
+	http.HandleFunc("/api/units", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetUnitsHandler)))
+
 	// Get pantry items - now includes resolved category info and supports category_id filter
 	http.HandleFunc("/api/pantry/list", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetPantryItemsHandler)))
 
@@ -101,8 +274,9 @@ func main() {
 	http.HandleFunc("/api/pantry/warnings", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetPantryWarningsHandler)))
 	http.HandleFunc("/api/pantry/expiring", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetPantryExpiringHandler)))
 	http.HandleFunc("/api/pantry/shopping-list", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetPantryShoppingListHandler)))
+	http.HandleFunc("/api/pantry/forecasts", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetPantryForecastsHandler)))
 	http.HandleFunc("/api/pantry/history", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.GetPantryHistoryHandler)))
-	http.HandleFunc("/api/pantry/notify/read", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.MarkNotificationReadHandler)))
+	http.HandleFunc("/api/pantry/notify/read", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.MarkNotificationsReadHandler)))
 	http.HandleFunc("/api/pantry/notify/delete", middleware.CORSMiddleware(middleware.AuthMiddleware(handlers.DeleteNotificationHandler)))
 
 	// Shopping cart routes - apply CORS and Auth middleware with validation
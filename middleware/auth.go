@@ -3,6 +3,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"os"
 	"strings"
@@ -40,40 +41,14 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		// Extract token
-		tokenString := parts[1]
-
-		// Parse and validate the token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate the signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return config.JWTSecret, nil
-		})
-
+		// Validate the token and extract the user
+		userClaims, err := ValidateToken(parts[1])
 		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		if !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		// Extract claims
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 
 		// Store user info in context
-		userClaims := UserClaims{
-			ID:       claims["id"].(string),
-			Username: claims["username"].(string),
-		}
 		ctx := context.WithValue(r.Context(), UserContextKey, userClaims)
 
 		// Call next handler with updated context
@@ -81,6 +56,48 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// StreamAuthMiddleware authenticates long-lived streaming requests. Browsers cannot set
+// headers on an EventSource, so the token may also be passed as the access_token query
+// parameter; it is validated exactly as in AuthMiddleware.
+func StreamAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	authenticated := AuthMiddleware(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			if token := r.URL.Query().Get("access_token"); token != "" {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		authenticated(w, r)
+	}
+}
+
+// ValidateToken parses and validates a JWT, returning the user it was issued to
+func ValidateToken(tokenString string) (UserClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Validate the signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return config.JWTSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return UserClaims{}, errors.New("Invalid token")
+	}
+
+	// Extract claims
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return UserClaims{}, errors.New("Invalid token claims")
+	}
+	id, idOK := claims["id"].(string)
+	username, usernameOK := claims["username"].(string)
+	if !idOK || !usernameOK {
+		return UserClaims{}, errors.New("Invalid token claims")
+	}
+
+	return UserClaims{ID: id, Username: username}, nil
+}
+
 // GetUserFromContext extracts user claims from the request context
 func GetUserFromContext(ctx context.Context) (UserClaims, bool) {
 	user, ok := ctx.Value(UserContextKey).(UserClaims)
@@ -116,6 +116,76 @@ func TestAuthMiddlewareInvalidToken(t *testing.T) {
 	}
 }
 
+func TestStreamAuthMiddlewareQueryToken(t *testing.T) {
+	// Create a test handler that checks the user was authenticated
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.GetUserFromContext(r.Context())
+		if !ok || claims.ID != "test-id" {
+			t.Errorf("Expected user test-id in context, got %+v", claims)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Create a JWT token
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":       "test-id",
+		"username": "testuser",
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	})
+	tokenString, err := token.SignedString(config.JWTSecret)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	// EventSource cannot set headers, so the token comes in the query string
+	req, err := http.NewRequest("GET", "/events?access_token="+tokenString, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	middleware.StreamAuthMiddleware(testHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+}
+
+func TestStreamAuthMiddlewareInvalidToken(t *testing.T) {
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called when token is invalid")
+	})
+
+	for _, target := range []string{"/events", "/events?access_token=invalid-token"} {
+		req, err := http.NewRequest("GET", target, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		middleware.StreamAuthMiddleware(testHandler).ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusUnauthorized {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", target, status, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestValidateTokenMissingClaims(t *testing.T) {
+	// A validly signed token without the user claims must not panic
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	tokenString, err := token.SignedString(config.JWTSecret)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	if _, err := middleware.ValidateToken(tokenString); err == nil {
+		t.Error("Expected an error for a token without user claims")
+	}
+}
+
 func TestGetUserFromContext(t *testing.T) {
 	// Create user claims
 	expectedClaims := middleware.UserClaims{
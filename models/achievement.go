@@ -0,0 +1,289 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AchievementEventType represents the kind of chore event the achievements engine reacts to
+type AchievementEventType string
+
+const (
+	AchievementEventChoreCompleted AchievementEventType = "chore_completed" // Emitted by CompleteChoreHandler
+	AchievementEventChoreOverdue   AchievementEventType = "chore_overdue"   // Emitted when a pending chore is marked overdue
+)
+
+// AchievementScope controls how often a member can earn an achievement
+type AchievementScope string
+
+const (
+	AchievementScopeOnce   AchievementScope = "once"   // Earned at most once per member
+	AchievementScopeWeekly AchievementScope = "weekly" // Can be earned again every week
+)
+
+// AchievementEvent describes something that happened to one of a member's chores
+type AchievementEvent struct {
+	Type        AchievementEventType
+	UserID      primitive.ObjectID
+	GroupID     primitive.ObjectID
+	ChoreID     primitive.ObjectID
+	RecurringID primitive.ObjectID
+	Points      int
+	DueDate     time.Time
+	OccurredAt  time.Time
+}
+
+// MemberProgress stores the running streak counters for a member
+type MemberProgress struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID           primitive.ObjectID `bson:"user_id" json:"user_id"`
+	GroupID          primitive.ObjectID `bson:"group_id" json:"group_id"`
+	ChoresCompleted  int                `bson:"chores_completed" json:"chores_completed"`
+	OnTimeStreak     int                `bson:"on_time_streak" json:"on_time_streak"`                   // Consecutive chores completed on time
+	BestOnTimeStreak int                `bson:"best_on_time_streak" json:"best_on_time_streak"`         // Longest on-time streak ever reached
+	DailyStreak      int                `bson:"daily_streak" json:"daily_streak"`                       // Consecutive days with at least one completion
+	BestDailyStreak  int                `bson:"best_daily_streak" json:"best_daily_streak"`             // Longest daily streak ever reached
+	LastActiveDay    time.Time          `bson:"last_active_day,omitempty" json:"last_active_day"`       // UTC midnight of the last day with a completion
+	WeekStart        time.Time          `bson:"week_start,omitempty" json:"week_start"`                 // Monday of the week WeekActiveDays refers to
+	WeekActiveDays   int                `bson:"week_active_days" json:"week_active_days"`               // Distinct days with a completion in that week
+	LastEventAt      time.Time          `bson:"last_event_at,omitempty" json:"last_event_at,omitempty"` // Time of the most recently applied event
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// EarnedAchievement is a badge awarded to a member
+type EarnedAchievement struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"user_id"`
+	GroupID       primitive.ObjectID `bson:"group_id" json:"group_id"`
+	AchievementID string             `bson:"achievement_id" json:"achievement_id"`
+	Name          string             `bson:"name" json:"name"`
+	Description   string             `bson:"description" json:"description"`
+	PeriodKey     string             `bson:"period_key" json:"period_key,omitempty"` // Week start (YYYY-MM-DD) for weekly achievements, empty otherwise
+	EarnedAt      time.Time          `bson:"earned_at" json:"earned_at"`
+}
+
+// AchievementContext is everything a rule can look at when deciding whether an achievement is earned
+type AchievementContext struct {
+	Event    AchievementEvent
+	Progress MemberProgress // Progress after the event has been applied
+	OnTime   bool           // Whether a completion event was on time
+
+	// FirstToFinishWeek is set when the member just finished every chore due this week
+	// and nobody else in the group has done so yet
+	FirstToFinishWeek bool
+}
+
+// AchievementRule declares an achievement and the condition under which it is earned
+type AchievementRule struct {
+	ID          string
+	Name        string
+	Description string
+	Scope       AchievementScope
+	Check       func(ctx AchievementContext) bool
+}
+
+// Achievement IDs, referenced by the engine for rules that need extra context
+const (
+	AchievementFirstChore        = "first_chore"
+	AchievementOnTimeStreak10    = "on_time_streak_10"
+	AchievementChores50          = "chores_50"
+	AchievementPerfectWeek       = "perfect_week"
+	AchievementFirstToFinishWeek = "first_to_finish_week"
+)
+
+// AchievementRules is the list of achievements evaluated after every chore event
+var AchievementRules = []AchievementRule{
+	{
+		ID:          AchievementFirstChore,
+		Name:        "Getting Started",
+		Description: "Complete your first chore",
+		Scope:       AchievementScopeOnce,
+		Check: func(ctx AchievementContext) bool {
+			return ctx.Event.Type == AchievementEventChoreCompleted && ctx.Progress.ChoresCompleted >= 1
+		},
+	},
+	{
+		ID:          AchievementOnTimeStreak10,
+		Name:        "Like Clockwork",
+		Description: "Complete 10 chores on time in a row",
+		Scope:       AchievementScopeOnce,
+		Check: func(ctx AchievementContext) bool {
+			return ctx.Event.Type == AchievementEventChoreCompleted && ctx.Progress.OnTimeStreak >= 10
+		},
+	},
+	{
+		ID:          AchievementChores50,
+		Name:        "House Hero",
+		Description: "Complete 50 chores",
+		Scope:       AchievementScopeOnce,
+		Check: func(ctx AchievementContext) bool {
+			return ctx.Event.Type == AchievementEventChoreCompleted && ctx.Progress.ChoresCompleted >= 50
+		},
+	},
+	{
+		ID:          AchievementPerfectWeek,
+		Name:        "Perfect Week",
+		Description: "Complete a chore every day of the week",
+		Scope:       AchievementScopeWeekly,
+		Check: func(ctx AchievementContext) bool {
+			return ctx.Event.Type == AchievementEventChoreCompleted && ctx.Progress.WeekActiveDays >= 7
+		},
+	},
+	{
+		ID:          AchievementFirstToFinishWeek,
+		Name:        "Early Bird",
+		Description: "Be the first in your group to finish your chores for the week",
+		Scope:       AchievementScopeWeekly,
+		Check: func(ctx AchievementContext) bool {
+			return ctx.Event.Type == AchievementEventChoreCompleted && ctx.FirstToFinishWeek
+		},
+	},
+}
+
+// CreateMemberProgress creates empty progress for a member
+func CreateMemberProgress(userID, groupID primitive.ObjectID) *MemberProgress {
+	return &MemberProgress{
+		UserID:    userID,
+		GroupID:   groupID,
+		UpdatedAt: time.Now(),
+	}
+}
+
+// IsCompletionOnTime reports whether a chore completed at completedAt meets its due date.
+// Chores are due by the end of their due day (UTC); chores without a due date are always on time.
+func IsCompletionOnTime(dueDate, completedAt time.Time) bool {
+	if dueDate.IsZero() {
+		return true
+	}
+	return completedAt.UTC().Before(startOfDayUTC(dueDate).AddDate(0, 0, 1))
+}
+
+// startOfDayUTC returns midnight UTC of the date portion of the supplied time
+func startOfDayUTC(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// AchievementPeriodKey returns the key under which an achievement with the given scope is stored
+func AchievementPeriodKey(scope AchievementScope, at time.Time) string {
+	if scope != AchievementScopeWeekly {
+		return ""
+	}
+	weekStart, _, _ := PeriodRange(LeaderboardPeriodWeek, at)
+	return weekStart.Format("2006-01-02")
+}
+
+// AchievementProgressUpdate is the update pipeline that applies the event to a member's stored
+// progress in a single atomic write, so events processed at the same time do not overwrite
+// each other's counters. It also returns whether a completion was on time.
+//
+// A completion counts towards the total and extends the on-time streak if it met the due date,
+// or breaks it if not. The first completion of a day extends the daily streak if the previous
+// one was the day before, or starts it again, and counts as an active day of its week.
+// Overdue chores break the on-time streak.
+func AchievementProgressUpdate(event AchievementEvent, now time.Time) (bson.A, bool) {
+	stored := func(field string, fallback interface{}) bson.M {
+		return bson.M{"$ifNull": bson.A{"$" + field, fallback}}
+	}
+	touched := bson.M{
+		"group_id":      event.GroupID,
+		"last_event_at": event.OccurredAt,
+		"updated_at":    now,
+	}
+
+	if event.Type == AchievementEventChoreOverdue {
+		touched["on_time_streak"] = 0
+		return bson.A{bson.M{"$set": touched}}, false
+	}
+
+	onTime := IsCompletionOnTime(event.DueDate, event.OccurredAt)
+	touched["chores_completed"] = bson.M{"$add": bson.A{stored("chores_completed", 0), 1}}
+	if onTime {
+		touched["on_time_streak"] = bson.M{"$add": bson.A{stored("on_time_streak", 0), 1}}
+	} else {
+		touched["on_time_streak"] = 0
+	}
+
+	// Only the first completion of a later day moves the daily and weekly counters
+	today := startOfDayUTC(event.OccurredAt)
+	weekStart, _, _ := PeriodRange(LeaderboardPeriodWeek, today)
+	newDay := bson.M{"$gt": bson.A{today, stored("last_active_day", nil)}}
+	onNewDay := func(changed, unchanged interface{}) bson.M {
+		return bson.M{"$cond": bson.A{newDay, changed, unchanged}}
+	}
+	days := bson.M{
+		"daily_streak": onNewDay(
+			bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{stored("last_active_day", nil), today.AddDate(0, 0, -1)}},
+				bson.M{"$add": bson.A{stored("daily_streak", 0), 1}},
+				1,
+			}},
+			stored("daily_streak", 0),
+		),
+		"week_active_days": onNewDay(
+			bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{stored("week_start", nil), weekStart}},
+				bson.M{"$add": bson.A{stored("week_active_days", 0), 1}},
+				1,
+			}},
+			stored("week_active_days", 0),
+		),
+		"week_start":      onNewDay(weekStart, "$week_start"),
+		"last_active_day": onNewDay(today, "$last_active_day"),
+	}
+	best := bson.M{
+		"best_on_time_streak": bson.M{"$max": bson.A{stored("best_on_time_streak", 0), "$on_time_streak"}},
+		"best_daily_streak":   bson.M{"$max": bson.A{stored("best_daily_streak", 0), "$daily_streak"}},
+	}
+
+	// Each stage sees the fields the one before it set
+	return bson.A{bson.M{"$set": touched}, bson.M{"$set": days}, bson.M{"$set": best}}, onTime
+}
+
+// CurrentDailyStreak returns the daily streak as of now. A streak stays alive until a full
+// day passes without any completions.
+func (p *MemberProgress) CurrentDailyStreak(now time.Time) int {
+	if p.LastActiveDay.IsZero() {
+		return 0
+	}
+	if startOfDayUTC(now).After(p.LastActiveDay.AddDate(0, 0, 1)) {
+		return 0
+	}
+	return p.DailyStreak
+}
+
+// EvaluateAchievements returns the achievements newly earned in ctx. earned holds the keys
+// (see AchievementKey) of achievements the member already has, so nothing is awarded twice.
+func EvaluateAchievements(rules []AchievementRule, ctx AchievementContext, earned map[string]bool) []EarnedAchievement {
+	awarded := make([]EarnedAchievement, 0)
+	for _, rule := range rules {
+		periodKey := AchievementPeriodKey(rule.Scope, ctx.Event.OccurredAt)
+		if earned[AchievementKey(rule.ID, periodKey)] {
+			continue
+		}
+		if !rule.Check(ctx) {
+			continue
+		}
+
+		awarded = append(awarded, EarnedAchievement{
+			UserID:        ctx.Event.UserID,
+			GroupID:       ctx.Event.GroupID,
+			AchievementID: rule.ID,
+			Name:          rule.Name,
+			Description:   rule.Description,
+			PeriodKey:     periodKey,
+			EarnedAt:      ctx.Event.OccurredAt,
+		})
+	}
+	return awarded
+}
+
+// AchievementKey identifies a single award of an achievement
+func AchievementKey(achievementID, periodKey string) string {
+	if periodKey == "" {
+		return achievementID
+	}
+	return achievementID + ":" + periodKey
+}
@@ -0,0 +1,267 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AnalyticsBucket is the size of the periods analytics trends are grouped into
+type AnalyticsBucket string
+
+const (
+	AnalyticsBucketDay   AnalyticsBucket = "day"
+	AnalyticsBucketWeek  AnalyticsBucket = "week" // Weeks start on Monday
+	AnalyticsBucketMonth AnalyticsBucket = "month"
+)
+
+// MaxAnalyticsBuckets bounds the number of periods a single analytics request can cover
+const MaxAnalyticsBuckets = 366
+
+// IsValid checks if the bucket is one of the supported sizes
+func (b AnalyticsBucket) IsValid() bool {
+	switch b {
+	case AnalyticsBucketDay, AnalyticsBucketWeek, AnalyticsBucketMonth:
+		return true
+	}
+	return false
+}
+
+// DefaultWindow returns how many buckets are reported when no range is requested
+func (b AnalyticsBucket) DefaultWindow() int {
+	if b == AnalyticsBucketDay {
+		return 30
+	}
+	return 12
+}
+
+// MemberAnalytics summarises how a member is doing with the chores assigned to them
+type MemberAnalytics struct {
+	UserID               primitive.ObjectID `bson:"_id" json:"user_id"`
+	Username             string             `bson:"username" json:"username"`
+	Name                 string             `bson:"name" json:"name"`
+	Assigned             int                `bson:"assigned" json:"assigned"`                   // Chores due in the window
+	Completed            int                `bson:"completed" json:"completed"`                 // Of those, how many were completed
+	CompletedOnTime      int                `bson:"completed_on_time" json:"completed_on_time"` // Completed by the end of the due day
+	Missed               int                `bson:"missed" json:"missed"`                       // Past due and still not completed
+	LatenessTotalMs      int64              `bson:"lateness_total_ms" json:"-"`
+	CompletionRate       float64            `bson:"-" json:"completion_rate"`        // Completed / assigned, 0-1
+	OnTimeRate           float64            `bson:"-" json:"on_time_rate"`           // Completed on time / completed, 0-1
+	AverageLatenessHours float64            `bson:"-" json:"average_lateness_hours"` // Mean delay of the chores completed late
+	Points               int                `bson:"-" json:"points"`                 // Points earned in the window
+	PointsShare          float64            `bson:"-" json:"points_share"`           // Share of the group's points, 0-1
+}
+
+// AnalyticsTrendPoint is one period of the chores trend
+type AnalyticsTrendPoint struct {
+	PeriodStart     time.Time `bson:"_id" json:"period_start"`
+	Due             int       `bson:"due" json:"due"`
+	CompletedOnTime int       `bson:"completed_on_time" json:"completed_on_time"`
+	Completed       int       `bson:"completed" json:"completed"` // Completions recorded in the period
+	Points          int       `bson:"points" json:"points"`
+}
+
+// SkippedRecurringChore counts the instances of a recurring chore nobody completed
+type SkippedRecurringChore struct {
+	RecurringID primitive.ObjectID `bson:"_id" json:"recurring_id"`
+	Title       string             `bson:"title" json:"title"`
+	Instances   int                `bson:"instances" json:"instances"` // Instances due in the window
+	Skipped     int                `bson:"skipped" json:"skipped"`     // Instances past due and not completed
+	SkipRate    float64            `bson:"-" json:"skip_rate"`
+}
+
+// AnalyticsSummary holds the group-wide totals
+type AnalyticsSummary struct {
+	Assigned             int     `json:"assigned"`
+	Completed            int     `json:"completed"`
+	CompletedOnTime      int     `json:"completed_on_time"`
+	Missed               int     `json:"missed"`
+	CompletionRate       float64 `json:"completion_rate"`
+	OnTimeRate           float64 `json:"on_time_rate"`
+	AverageLatenessHours float64 `json:"average_lateness_hours"`
+	Points               int     `json:"points"`
+}
+
+// ChoreAnalytics is the analytics report of a group over a window
+type ChoreAnalytics struct {
+	GroupID     primitive.ObjectID      `json:"group_id"`
+	Timezone    string                  `json:"timezone"`
+	Bucket      AnalyticsBucket         `json:"bucket"`
+	From        time.Time               `json:"from"`
+	To          time.Time               `json:"to"`
+	Summary     AnalyticsSummary        `json:"summary"`
+	Members     []MemberAnalytics       `json:"members"`
+	Trend       []AnalyticsTrendPoint   `json:"trend"`
+	MostSkipped []SkippedRecurringChore `json:"most_skipped"`
+	GeneratedAt time.Time               `json:"generated_at"`
+}
+
+// LoadGroupLocation returns the time zone of a group, UTC when none is set
+func LoadGroupLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(timezone)
+}
+
+// BucketStart returns the start of the bucket containing t, in the given time zone
+func BucketStart(bucket AnalyticsBucket, t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	year, month, day := local.Date()
+
+	switch bucket {
+	case AnalyticsBucketWeek:
+		offset := (int(local.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, loc)
+	case AnalyticsBucketMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// NextBucket returns the start of the bucket following the one starting at start
+func NextBucket(bucket AnalyticsBucket, start time.Time) time.Time {
+	return addBuckets(bucket, start, 1)
+}
+
+func addBuckets(bucket AnalyticsBucket, start time.Time, n int) time.Time {
+	switch bucket {
+	case AnalyticsBucketWeek:
+		return start.AddDate(0, 0, 7*n)
+	case AnalyticsBucketMonth:
+		return start.AddDate(0, n, 0)
+	}
+	return start.AddDate(0, 0, n)
+}
+
+// AnalyticsWindow aligns [from, to) to whole buckets in the group's time zone. A zero from
+// defaults to the bucket's default window ending with the bucket containing to.
+func AnalyticsWindow(bucket AnalyticsBucket, from, to time.Time, loc *time.Location) (time.Time, time.Time, error) {
+	end := BucketStart(bucket, to, loc)
+	if !end.Equal(to.In(loc)) {
+		end = NextBucket(bucket, end)
+	}
+
+	start := addBuckets(bucket, end, -bucket.DefaultWindow())
+	if !from.IsZero() {
+		start = BucketStart(bucket, from, loc)
+	}
+
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, errors.New("to must be after from")
+	}
+	count := 0
+	for t := start; t.Before(end); t = NextBucket(bucket, t) {
+		count++
+		if count > MaxAnalyticsBuckets {
+			return time.Time{}, time.Time{}, errors.New("range covers too many periods; use a larger bucket")
+		}
+	}
+	return start, end, nil
+}
+
+// FillTrend returns one point per bucket in [from, to), using zero values for periods
+// without any activity, merged from the due-date and completion aggregations
+func FillTrend(bucket AnalyticsBucket, from, to time.Time, due, completed []AnalyticsTrendPoint) []AnalyticsTrendPoint {
+	byStart := make(map[int64]*AnalyticsTrendPoint)
+	trend := make([]AnalyticsTrendPoint, 0)
+	for start := from; start.Before(to); start = NextBucket(bucket, start) {
+		trend = append(trend, AnalyticsTrendPoint{PeriodStart: start})
+	}
+	for i := range trend {
+		byStart[trend[i].PeriodStart.Unix()] = &trend[i]
+	}
+
+	for _, point := range due {
+		if target, ok := byStart[point.PeriodStart.Unix()]; ok {
+			target.Due += point.Due
+			target.CompletedOnTime += point.CompletedOnTime
+		}
+	}
+	for _, point := range completed {
+		if target, ok := byStart[point.PeriodStart.Unix()]; ok {
+			target.Completed += point.Completed
+			target.Points += point.Points
+		}
+	}
+	return trend
+}
+
+// FinalizeMemberAnalytics merges the points earned by each member into their chore stats,
+// computes the rates and totals, and orders members by completion rate. members must
+// include everyone who earned points in the window.
+func FinalizeMemberAnalytics(members []MemberAnalytics, points map[primitive.ObjectID]int) ([]MemberAnalytics, AnalyticsSummary) {
+	summary := AnalyticsSummary{}
+	var latenessTotal int64
+
+	for i := range members {
+		member := &members[i]
+		member.Points = points[member.UserID]
+		member.CompletionRate = ratio(member.Completed, member.Assigned)
+		member.OnTimeRate = ratio(member.CompletedOnTime, member.Completed)
+		if late := member.Completed - member.CompletedOnTime; late > 0 {
+			member.AverageLatenessHours = roundHours(time.Duration(member.LatenessTotalMs/int64(late)) * time.Millisecond)
+		}
+
+		summary.Assigned += member.Assigned
+		summary.Completed += member.Completed
+		summary.CompletedOnTime += member.CompletedOnTime
+		summary.Missed += member.Missed
+		summary.Points += member.Points
+		latenessTotal += member.LatenessTotalMs
+	}
+
+	for i := range members {
+		members[i].PointsShare = ratio(members[i].Points, summary.Points)
+	}
+	summary.CompletionRate = ratio(summary.Completed, summary.Assigned)
+	summary.OnTimeRate = ratio(summary.CompletedOnTime, summary.Completed)
+	if late := summary.Completed - summary.CompletedOnTime; late > 0 {
+		summary.AverageLatenessHours = roundHours(time.Duration(latenessTotal/int64(late)) * time.Millisecond)
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		if members[i].CompletionRate != members[j].CompletionRate {
+			return members[i].CompletionRate > members[j].CompletionRate
+		}
+		return members[i].Points > members[j].Points
+	})
+	return members, summary
+}
+
+// FinalizeSkippedChores computes skip rates and orders chores from most to least skipped
+func FinalizeSkippedChores(chores []SkippedRecurringChore, limit int) []SkippedRecurringChore {
+	result := make([]SkippedRecurringChore, 0, len(chores))
+	for _, chore := range chores {
+		if chore.Skipped == 0 {
+			continue
+		}
+		chore.SkipRate = ratio(chore.Skipped, chore.Instances)
+		result = append(result, chore)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Skipped != result[j].Skipped {
+			return result[i].Skipped > result[j].Skipped
+		}
+		return result[i].SkipRate > result[j].SkipRate
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// ratio returns part/whole rounded to three decimals, 0 when whole is 0
+func ratio(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(int(float64(part)/float64(whole)*1000+0.5)) / 1000
+}
+
+func roundHours(d time.Duration) float64 {
+	return float64(int(d.Hours()*10+0.5)) / 10
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxProjectedOccurrences guards against runaway projections over very long ranges
+const maxProjectedOccurrences = 400
+
+// CalendarEvent is a chore shown on the calendar, either an existing chore instance or a
+// projected future occurrence of a recurring chore that has not been created yet
+type CalendarEvent struct {
+	UID              string              `json:"uid"` // Stable iCalendar UID so edits update the same event
+	Title            string              `json:"title"`
+	Description      string              `json:"description"`
+	ChoreID          *primitive.ObjectID `json:"chore_id,omitempty"`
+	RecurringChoreID *primitive.ObjectID `json:"recurring_chore_id,omitempty"`
+	AssignedTo       primitive.ObjectID  `json:"assigned_to,omitempty"`
+	Status           ChoreStatus         `json:"status,omitempty"` // Empty for projected occurrences
+	Points           int                 `json:"points"`
+	DueDate          time.Time           `json:"due_date"`
+	Projected        bool                `json:"projected"`
+	UpdatedAt        time.Time           `json:"updated_at,omitempty"`
+}
+
+// ChoreCalendarUID returns the iCalendar UID of a chore instance
+func ChoreCalendarUID(choreID primitive.ObjectID) string {
+	return fmt.Sprintf("chore-%s@cribb", choreID.Hex())
+}
+
+// RecurringCalendarUID returns the iCalendar UID of a projected occurrence of a recurring chore,
+// keyed by the UTC date the occurrence is due
+func RecurringCalendarUID(recurringChoreID primitive.ObjectID, dueDate time.Time) string {
+	return fmt.Sprintf("recurring-%s-%s@cribb", recurringChoreID.Hex(), dueDate.UTC().Format("20060102"))
+}
+
+// choreUID returns the iCalendar UID of a chore instance. A chore created from a recurring
+// chore keeps the UID its occurrence was projected with, so it stays the same event.
+func choreUID(chore *Chore) string {
+	switch {
+	case chore.CalendarUID != "":
+		return chore.CalendarUID
+	case !chore.RecurringID.IsZero():
+		return RecurringCalendarUID(chore.RecurringID, chore.DueDate)
+	}
+	return ChoreCalendarUID(chore.ID)
+}
+
+// ChoreCalendarEvent converts a chore instance into a calendar event
+func ChoreCalendarEvent(chore *Chore) CalendarEvent {
+	choreID := chore.ID
+	event := CalendarEvent{
+		UID:         choreUID(chore),
+		Title:       chore.Title,
+		Description: chore.Description,
+		ChoreID:     &choreID,
+		AssignedTo:  chore.AssignedTo,
+		Status:      chore.Status,
+		Points:      chore.Points,
+		DueDate:     chore.DueDate,
+		UpdatedAt:   chore.UpdatedAt,
+	}
+	if !chore.RecurringID.IsZero() {
+		recurringID := chore.RecurringID
+		event.RecurringChoreID = &recurringID
+	}
+	return event
+}
+
+// ProjectedDueDate returns the due date of an instance assigned at the given time.
+// It mirrors the due date calculation in CreateChoreFromRecurring.
+func ProjectedDueDate(frequency string, assignedAt time.Time) time.Time {
+	switch frequency {
+	case "weekly":
+		assignedAt = assignedAt.AddDate(0, 0, 7)
+	case "biweekly":
+		assignedAt = assignedAt.AddDate(0, 0, 14)
+	case "monthly":
+		assignedAt = assignedAt.AddDate(0, 1, 0)
+	}
+	return endOfDayUTC(assignedAt)
+}
+
+// AssignmentForDueDate is the inverse of ProjectedDueDate: the time a recurring chore must be
+// assigned for the resulting instance to fall due on the given day
+func AssignmentForDueDate(frequency string, dueDate time.Time) time.Time {
+	year, month, day := dueDate.Date()
+	assignAt := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	switch frequency {
+	case "weekly":
+		return assignAt.AddDate(0, 0, -7)
+	case "biweekly":
+		return assignAt.AddDate(0, 0, -14)
+	case "monthly":
+		return assignAt.AddDate(0, -1, 0)
+	}
+	return assignAt
+}
+
+// ProjectRecurringChore returns the occurrences of a recurring chore that the scheduler has not
+// created yet and that fall due within [from, to). Nothing is persisted; the rotation is walked
+// on a copy so the recurring chore itself is left untouched.
+func ProjectRecurringChore(rc RecurringChore, from, to, now time.Time) []CalendarEvent {
+	events := make([]CalendarEvent, 0)
+	if !rc.IsActive {
+		return events
+	}
+
+	recurringID := rc.ID
+	index := rc.CurrentIndex
+
+	// An assignment that is already due is picked up by the scheduler on its next run
+	assignAt := rc.NextAssignment
+	if assignAt.Before(now) {
+		assignAt = now
+	}
+
+	for i := 0; i < maxProjectedOccurrences; i++ {
+		dueDate := ProjectedDueDate(rc.Frequency, assignAt)
+		if !dueDate.Before(to) {
+			break
+		}
+
+		var assignee primitive.ObjectID
+		if len(rc.MemberRotation) > 0 {
+			assignee = rc.MemberRotation[index%len(rc.MemberRotation)]
+		}
+		index++
+
+		if !dueDate.Before(from) {
+			events = append(events, CalendarEvent{
+				UID:              RecurringCalendarUID(rc.ID, dueDate),
+				Title:            rc.Title,
+				Description:      rc.Description,
+				RecurringChoreID: &recurringID,
+				AssignedTo:       assignee,
+				Points:           rc.Points,
+				DueDate:          dueDate,
+				Projected:        true,
+				UpdatedAt:        rc.UpdatedAt,
+			})
+		}
+
+		assignAt = NextAssignmentAfter(rc.Frequency, assignAt)
+	}
+
+	return events
+}
@@ -1,9 +1,12 @@
 package models
 
 import (
+	"context"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // ChoreType represents the type of chore
@@ -38,6 +41,18 @@ type Chore struct {
 	RecurringID primitive.ObjectID `bson:"recurring_id,omitempty" json:"recurring_id,omitempty"`
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+
+	// CalendarUID is the UID the occurrence had on the calendar while it was projected, kept so
+	// calendar clients see the same event once the chore is created, even if it is rescheduled
+	CalendarUID string `bson:"calendar_uid,omitempty" json:"-"`
+
+	Subtasks      []Subtask     `bson:"subtasks,omitempty" json:"subtasks,omitempty"`             // Ordered checklist
+	SubtaskPolicy SubtaskPolicy `bson:"subtask_policy,omitempty" json:"subtask_policy,omitempty"` // Defaults to block
+	PartialPoints bool          `bson:"partial_points,omitempty" json:"partial_points,omitempty"` // Scale points by subtasks done
+
+	CompletedAt *time.Time `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	ArchivedAt  *time.Time `bson:"archived_at,omitempty" json:"archived_at,omitempty"` // Archived chores are hidden from active lists but kept for history
+	RestoredAt  *time.Time `bson:"restored_at,omitempty" json:"restored_at,omitempty"` // Last time the chore was brought back from the archive
 }
 
 // RecurringChore represents a template for chores that rotate among group members
@@ -54,6 +69,10 @@ type RecurringChore struct {
 	IsActive       bool                 `bson:"is_active" json:"is_active"`
 	CreatedAt      time.Time            `bson:"created_at" json:"created_at"`
 	UpdatedAt      time.Time            `bson:"updated_at" json:"updated_at"`
+
+	Subtasks      []Subtask     `bson:"subtasks,omitempty" json:"subtasks,omitempty"` // Checklist copied into each instance
+	SubtaskPolicy SubtaskPolicy `bson:"subtask_policy,omitempty" json:"subtask_policy,omitempty"`
+	PartialPoints bool          `bson:"partial_points,omitempty" json:"partial_points,omitempty"`
 }
 
 // ChoreCompletion represents a record of a completed chore
@@ -61,10 +80,94 @@ type ChoreCompletion struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	ChoreID     primitive.ObjectID `bson:"chore_id" json:"chore_id"`
 	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	GroupID     primitive.ObjectID `bson:"group_id,omitempty" json:"group_id,omitempty"` // Lets leaderboards aggregate completions per group
 	CompletedAt time.Time          `bson:"completed_at" json:"completed_at"`
 	Points      int                `bson:"points" json:"points"`
 }
 
+// MigrateChoreCompletionGroups backfills group_id on completions recorded before it was stored,
+// using the current group of the user who completed the chore
+func MigrateChoreCompletionGroups(db *mongo.Database) error {
+	ctx := context.Background()
+	userIDs, err := db.Collection("chore_completions").Distinct(
+		ctx,
+		"user_id",
+		bson.M{"group_id": bson.M{"$exists": false}},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, rawID := range userIDs {
+		userID, ok := rawID.(primitive.ObjectID)
+		if !ok {
+			continue
+		}
+
+		var user struct {
+			GroupID primitive.ObjectID `bson:"group_id"`
+		}
+		err := db.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+		if err != nil || user.GroupID.IsZero() {
+			// User no longer exists or has left their group; leave the completion as is
+			continue
+		}
+
+		_, err = db.Collection("chore_completions").UpdateMany(
+			ctx,
+			bson.M{"user_id": userID, "group_id": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"group_id": user.GroupID}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateChoreCompletedAt backfills completed_at on chores completed before it was stored,
+// using the time the chore was last updated
+func MigrateChoreCompletedAt(db *mongo.Database) error {
+	_, err := db.Collection("chores").UpdateMany(
+		context.Background(),
+		bson.M{
+			"status":       ChoreStatusCompleted,
+			"completed_at": bson.M{"$exists": false},
+		},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"completed_at": "$updated_at"}}},
+		},
+	)
+	return err
+}
+
+// NotArchived adds the condition that leaves out archived chores to a chore filter
+func NotArchived(filter bson.M) bson.M {
+	filter["archived_at"] = nil // Matches chores that were never archived or have been restored
+	return filter
+}
+
+// IsArchived checks if the chore has been archived
+func (c *Chore) IsArchived() bool {
+	return c.ArchivedAt != nil
+}
+
+// CanBeArchived checks if a chore may be archived at the given cutoff. Only completed chores are
+// archived, and a chore restored after the cutoff is left alone until it ages again.
+func (c *Chore) CanBeArchived(cutoff time.Time) bool {
+	if c.Status != ChoreStatusCompleted || c.IsArchived() {
+		return false
+	}
+	completedAt := c.UpdatedAt
+	if c.CompletedAt != nil {
+		completedAt = *c.CompletedAt
+	}
+	if !completedAt.Before(cutoff) {
+		return false
+	}
+	return c.RestoredAt == nil || c.RestoredAt.Before(cutoff)
+}
+
 // endOfDayUTC returns a time at 23:59:00 UTC for the date portion of the supplied time
 func endOfDayUTC(t time.Time) time.Time {
 	// Work in UTC so that comparisons on the backend remain consistent
@@ -128,7 +231,6 @@ func CreateChoreFromRecurring(recurringChore *RecurringChore) *Chore {
 	switch recurringChore.Frequency {
 	case "daily":
 		// Due at the end of the current day
-		now = now
 	case "weekly":
 		now = now.AddDate(0, 0, 7)
 	case "biweekly":
@@ -152,6 +254,11 @@ func CreateChoreFromRecurring(recurringChore *RecurringChore) *Chore {
 		RecurringID: recurringChore.ID,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
+		CalendarUID: RecurringCalendarUID(recurringChore.ID, dueDate),
+
+		Subtasks:      copySubtasksForInstance(recurringChore.Subtasks),
+		SubtaskPolicy: recurringChore.SubtaskPolicy,
+		PartialPoints: recurringChore.PartialPoints,
 	}
 }
 
@@ -179,5 +286,10 @@ func CreateChoreFromRecurringWithBaseDate(recurringChore *RecurringChore, baseDa
 		RecurringID: recurringChore.ID,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
+		CalendarUID: RecurringCalendarUID(recurringChore.ID, dueDate),
+
+		Subtasks:      copySubtasksForInstance(recurringChore.Subtasks),
+		SubtaskPolicy: recurringChore.SubtaskPolicy,
+		PartialPoints: recurringChore.PartialPoints,
 	}
 }
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxCommentLength is the maximum number of characters allowed in a comment body
+const MaxCommentLength = 2000
+
+// ChoreComment is a note left on a chore instance or a recurring chore.
+// Comments are stored separately from chores so that they outlive cleared chore instances.
+type ChoreComment struct {
+	ID               primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	GroupID          primitive.ObjectID   `bson:"group_id" json:"group_id"`
+	ChoreID          *primitive.ObjectID  `bson:"chore_id,omitempty" json:"chore_id,omitempty"`                     // Set for comments on a chore instance
+	RecurringChoreID *primitive.ObjectID  `bson:"recurring_chore_id,omitempty" json:"recurring_chore_id,omitempty"` // Set for comments on a recurring chore
+	ChoreTitle       string               `bson:"chore_title" json:"chore_title"`                                   // Kept so the comment still makes sense once the chore is cleared
+	AuthorID         primitive.ObjectID   `bson:"author_id" json:"author_id"`
+	AuthorName       string               `bson:"author_name" json:"author_name"`
+	Body             string               `bson:"body" json:"body"`
+	Mentions         []primitive.ObjectID `bson:"mentions" json:"mentions"`
+	CreatedAt        time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time            `bson:"updated_at" json:"updated_at"`
+	EditedAt         *time.Time           `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+}
+
+// CreateChoreComment creates a new comment on a chore instance
+func CreateChoreComment(chore *Chore, authorID primitive.ObjectID, authorName, body string, mentions []primitive.ObjectID) *ChoreComment {
+	choreID := chore.ID
+	comment := newChoreComment(chore.GroupID, chore.Title, authorID, authorName, body, mentions)
+	comment.ChoreID = &choreID
+	return comment
+}
+
+// CreateRecurringChoreComment creates a new comment on a recurring chore
+func CreateRecurringChoreComment(recurringChore *RecurringChore, authorID primitive.ObjectID, authorName, body string, mentions []primitive.ObjectID) *ChoreComment {
+	recurringChoreID := recurringChore.ID
+	comment := newChoreComment(recurringChore.GroupID, recurringChore.Title, authorID, authorName, body, mentions)
+	comment.RecurringChoreID = &recurringChoreID
+	return comment
+}
+
+func newChoreComment(groupID primitive.ObjectID, choreTitle string, authorID primitive.ObjectID, authorName, body string, mentions []primitive.ObjectID) *ChoreComment {
+	if mentions == nil {
+		mentions = make([]primitive.ObjectID, 0)
+	}
+	return &ChoreComment{
+		GroupID:    groupID,
+		ChoreTitle: choreTitle,
+		AuthorID:   authorID,
+		AuthorName: authorName,
+		Body:       body,
+		Mentions:   mentions,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+}
+
+// CanBeEditedBy checks if a user can edit or delete the comment. Only the author can.
+func (c *ChoreComment) CanBeEditedBy(userID primitive.ObjectID) bool {
+	return c.AuthorID == userID
+}
+
+// mentionPattern matches @username where usernames may themselves be email addresses
+var mentionPattern = regexp.MustCompile(`(?:^|[\s(])@([A-Za-z0-9._%+\-]+(?:@[A-Za-z0-9.\-]+)?)`)
+
+// ParseMentions returns the distinct usernames mentioned with @ in a comment body, lowercased
+// and in order of first appearance
+func ParseMentions(body string) []string {
+	matches := mentionPattern.FindAllStringSubmatch(body, -1)
+	usernames := make([]string, 0, len(matches))
+	seen := make(map[string]bool, len(matches))
+	for _, match := range matches {
+		// Drop punctuation that ends a sentence rather than the username
+		username := strings.ToLower(strings.TrimRight(match[1], ".,-"))
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
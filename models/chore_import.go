@@ -0,0 +1,261 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChoreImportTTL is how long a previewed import can be confirmed before it expires
+const ChoreImportTTL = 24 * time.Hour
+
+// ChoreImportStatus represents the state of an iCalendar import
+type ChoreImportStatus string
+
+const (
+	ChoreImportStatusPending   ChoreImportStatus = "pending"   // Previewed, waiting for confirmation
+	ChoreImportStatusCommitted ChoreImportStatus = "committed" // Chores have been created
+)
+
+// ChoreImportKind is what an imported event becomes
+type ChoreImportKind string
+
+const (
+	ChoreImportKindIndividual ChoreImportKind = "individual" // One-off event, becomes a Chore
+	ChoreImportKindRecurring  ChoreImportKind = "recurring"  // Event with an RRULE, becomes a RecurringChore
+)
+
+// ChoreImportAssignmentSource records how the assignee of an imported event was chosen
+type ChoreImportAssignmentSource string
+
+const (
+	ChoreImportAssignedByAttendee ChoreImportAssignmentSource = "attendee" // Event attendee matched a member
+	ChoreImportAssignedBySummary  ChoreImportAssignmentSource = "summary"  // Member's name appears in the summary
+	ChoreImportAssignedByMapping  ChoreImportAssignmentSource = "mapping"  // Chosen explicitly by the importing user
+	ChoreImportAssignedByRotation ChoreImportAssignmentSource = "rotation" // No match; rotates over the whole group
+	ChoreImportAssignedByDefault  ChoreImportAssignmentSource = "default"  // No match; assigned to the importing user
+)
+
+// ChoreImportItem is the preview of a single imported event
+type ChoreImportItem struct {
+	UID              string                      `bson:"uid" json:"uid"`
+	Line             int                         `bson:"line" json:"line"`
+	Kind             ChoreImportKind             `bson:"kind" json:"kind"`
+	Title            string                      `bson:"title" json:"title"`
+	Description      string                      `bson:"description" json:"description"`
+	Frequency        string                      `bson:"frequency,omitempty" json:"frequency,omitempty"`
+	DueDate          time.Time                   `bson:"due_date" json:"due_date"`                                   // Due date of the first chore
+	NextAssignment   time.Time                   `bson:"next_assignment,omitempty" json:"next_assignment,omitempty"` // When the scheduler creates the following instance
+	AssignedTo       primitive.ObjectID          `bson:"assigned_to,omitempty" json:"assigned_to,omitempty"`
+	MemberRotation   []primitive.ObjectID        `bson:"member_rotation,omitempty" json:"member_rotation,omitempty"`
+	Assignees        []string                    `bson:"assignees" json:"assignees"` // Usernames, for display
+	AssignmentSource ChoreImportAssignmentSource `bson:"assignment_source" json:"assignment_source"`
+	Points           int                         `bson:"points" json:"points"`
+	Skipped          bool                        `bson:"skipped" json:"skipped"`
+	Warnings         []string                    `bson:"warnings" json:"warnings"`
+}
+
+// ChoreImport is a previewed iCalendar import waiting to be confirmed
+type ChoreImport struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GroupID     primitive.ObjectID `bson:"group_id" json:"group_id"`
+	CreatedBy   primitive.ObjectID `bson:"created_by" json:"created_by"`
+	FileName    string             `bson:"file_name" json:"file_name"`
+	Status      ChoreImportStatus  `bson:"status" json:"status"`
+	Items       []ChoreImportItem  `bson:"items" json:"items"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt   time.Time          `bson:"expires_at" json:"expires_at"`
+	CommittedAt *time.Time         `bson:"committed_at,omitempty" json:"committed_at,omitempty"`
+}
+
+// CreateChoreImport creates a pending import for a group
+func CreateChoreImport(groupID, createdBy primitive.ObjectID, fileName string, items []ChoreImportItem) *ChoreImport {
+	now := time.Now()
+	return &ChoreImport{
+		GroupID:   groupID,
+		CreatedBy: createdBy,
+		FileName:  fileName,
+		Status:    ChoreImportStatusPending,
+		Items:     items,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ChoreImportTTL),
+	}
+}
+
+// IsPending checks if the import can still be committed
+func (ci *ChoreImport) IsPending(now time.Time) bool {
+	return ci.Status == ChoreImportStatusPending && now.Before(ci.ExpiresAt)
+}
+
+// BuildChoreImportItems maps parsed events to chores. Events with an RRULE become recurring chores,
+// other events become individual chores. Members are matched from attendees first, then from
+// names in the summary; events that cannot be imported are kept in the preview as skipped.
+func BuildChoreImportItems(events []ICalEvent, members []User, importer User, points int, now time.Time) []ChoreImportItem {
+	if points < 1 {
+		points = 1
+	}
+	today := startOfDayUTC(now)
+
+	items := make([]ChoreImportItem, 0, len(events))
+	for _, event := range events {
+		item := ChoreImportItem{
+			UID:         event.UID,
+			Line:        event.Line,
+			Kind:        ChoreImportKindIndividual,
+			Title:       strings.TrimSpace(event.Summary),
+			Description: event.Description,
+			Points:      points,
+			Assignees:   make([]string, 0),
+			Warnings:    make([]string, 0),
+		}
+		if item.UID == "" {
+			item.UID = fmt.Sprintf("line-%d", event.Line)
+		}
+
+		switch {
+		case event.RecurrenceID:
+			item.skip("changes to a single occurrence of a repeating event are not imported")
+		case event.Status == "CANCELLED":
+			item.skip("event is cancelled")
+		case event.RRule != nil:
+			item.mapRecurrence(event, today)
+		default:
+			if event.Start.Before(today) {
+				item.skip("event is in the past")
+			} else {
+				item.DueDate = importDueDate(event.Start)
+			}
+		}
+
+		if !item.Skipped {
+			if matched := matchAttendees(event.Attendees, members); len(matched) > 0 {
+				item.AssignTo(matched, ChoreImportAssignedByAttendee, importer)
+			} else if matched := matchSummary(event.Summary, members); len(matched) > 0 {
+				item.AssignTo(matched, ChoreImportAssignedBySummary, importer)
+			} else if item.Kind == ChoreImportKindRecurring {
+				item.AssignTo(members, ChoreImportAssignedByRotation, importer)
+			} else {
+				item.AssignTo(nil, ChoreImportAssignedByDefault, importer)
+			}
+		}
+
+		items = append(items, item)
+	}
+	return items
+}
+
+// mapRecurrence fills in the recurring chore schedule from the event's RRULE
+func (item *ChoreImportItem) mapRecurrence(event ICalEvent, today time.Time) {
+	occurrences := event.NextOccurrences(today, 2)
+	if len(occurrences) == 0 {
+		item.skip("repeating event has no future occurrences")
+		return
+	}
+
+	item.DueDate = importDueDate(occurrences[0])
+	if len(occurrences) == 1 {
+		// Only one occurrence left, so there is nothing to repeat
+		item.Warnings = append(item.Warnings, "repeating event ends after its next occurrence; imported as a single chore")
+		return
+	}
+
+	frequency, exact := event.RRule.ChoreFrequency()
+	item.Kind = ChoreImportKindRecurring
+	item.Frequency = frequency
+	item.NextAssignment = AssignmentForDueDate(frequency, importDueDate(occurrences[1]))
+
+	if !exact {
+		item.Warnings = append(item.Warnings, fmt.Sprintf("repeat rule FREQ=%s;INTERVAL=%d is not supported exactly; imported as %s", event.RRule.Freq, event.RRule.Interval, frequency))
+	}
+	if event.RRule.Count > 0 || !event.RRule.Until.IsZero() {
+		item.Warnings = append(item.Warnings, "recurring chores do not end; the chore will repeat past the event's last occurrence")
+	}
+}
+
+// AssignTo sets who the imported chore is assigned to. Individual chores go to the first member,
+// recurring chores rotate over all of them. With no members the chore goes to the importer.
+func (item *ChoreImportItem) AssignTo(members []User, source ChoreImportAssignmentSource, importer User) {
+	item.AssignedTo = primitive.NilObjectID
+	item.MemberRotation = nil
+	item.Assignees = make([]string, 0, len(members))
+
+	if len(members) == 0 {
+		members = []User{importer}
+		source = ChoreImportAssignedByDefault
+		item.Warnings = append(item.Warnings, "no group member matched; assigned to you")
+	}
+	item.AssignmentSource = source
+
+	if item.Kind == ChoreImportKindRecurring {
+		item.MemberRotation = make([]primitive.ObjectID, 0, len(members))
+		for _, member := range members {
+			item.MemberRotation = append(item.MemberRotation, member.ID)
+			item.Assignees = append(item.Assignees, member.Username)
+		}
+		return
+	}
+
+	if len(members) > 1 {
+		item.Warnings = append(item.Warnings, fmt.Sprintf("several members matched; assigned to %s", members[0].Username))
+	}
+	item.AssignedTo = members[0].ID
+	item.Assignees = append(item.Assignees, members[0].Username)
+}
+
+func (item *ChoreImportItem) skip(reason string) {
+	item.Skipped = true
+	item.Warnings = append(item.Warnings, reason)
+}
+
+// importDueDate keeps the calendar date of an occurrence and makes it due at the end of that day,
+// the same convention chores created in the app use
+func importDueDate(occurrence time.Time) time.Time {
+	year, month, day := occurrence.Date()
+	return time.Date(year, month, day, 23, 59, 0, 0, time.UTC)
+}
+
+// matchAttendees finds the members an event's attendees refer to, by email (against usernames)
+// or by display name
+func matchAttendees(attendees []ICalAttendee, members []User) []User {
+	matched := make([]User, 0)
+	seen := make(map[primitive.ObjectID]bool)
+	for _, attendee := range attendees {
+		localPart, _, _ := strings.Cut(attendee.Email, "@")
+		for _, member := range members {
+			if seen[member.ID] {
+				continue
+			}
+			byEmail := attendee.Email != "" &&
+				(strings.EqualFold(member.Username, attendee.Email) || strings.EqualFold(member.Username, localPart))
+			byName := attendee.Name != "" &&
+				(strings.EqualFold(member.Name, attendee.Name) || strings.EqualFold(member.Username, attendee.Name))
+			if byEmail || byName {
+				seen[member.ID] = true
+				matched = append(matched, member)
+			}
+		}
+	}
+	return matched
+}
+
+// matchSummary finds members whose name or username appears as a whole word in the summary,
+// for rotas written like "Bins - Alice"
+func matchSummary(summary string, members []User) []User {
+	matched := make([]User, 0)
+	for _, member := range members {
+		for _, name := range []string{member.Name, member.Username} {
+			if strings.TrimSpace(name) == "" {
+				continue
+			}
+			pattern := regexp.MustCompile(`(?i)(^|\W)` + regexp.QuoteMeta(name) + `($|\W)`)
+			if pattern.MatchString(summary) {
+				matched = append(matched, member)
+				break
+			}
+		}
+	}
+	return matched
+}
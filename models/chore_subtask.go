@@ -0,0 +1,154 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SubtaskPolicy controls what happens when a chore is completed with required subtasks still open
+type SubtaskPolicy string
+
+const (
+	SubtaskPolicyBlock SubtaskPolicy = "block" // Completion is rejected until required subtasks are done
+	SubtaskPolicyWarn  SubtaskPolicy = "warn"  // Completion is allowed but the response lists the open subtasks
+)
+
+// Subtask is a single step of a chore checklist
+type Subtask struct {
+	ID       primitive.ObjectID  `bson:"id" json:"id"`
+	Title    string              `bson:"title" json:"title"`
+	Required bool                `bson:"required" json:"required"`
+	Position int                 `bson:"position" json:"position"` // Zero-based order within the checklist
+	Done     bool                `bson:"done" json:"done"`
+	DoneBy   *primitive.ObjectID `bson:"done_by,omitempty" json:"done_by,omitempty"`
+	DoneAt   *time.Time          `bson:"done_at,omitempty" json:"done_at,omitempty"`
+}
+
+// SubtaskSpec describes a subtask supplied by a client. An ID refers to an existing subtask.
+type SubtaskSpec struct {
+	ID       string `json:"id,omitempty"`
+	Title    string `json:"title"`
+	Required bool   `json:"required"`
+}
+
+// IsValid checks if the policy is one of the supported subtask policies
+func (p SubtaskPolicy) IsValid() bool {
+	return p == SubtaskPolicyBlock || p == SubtaskPolicyWarn
+}
+
+// BuildSubtasks turns client specs into an ordered checklist. Specs that reference an
+// existing subtask keep its ID and completion state so that reordering does not reset progress.
+func BuildSubtasks(specs []SubtaskSpec, existing []Subtask) ([]Subtask, error) {
+	existingByID := make(map[primitive.ObjectID]Subtask, len(existing))
+	for _, subtask := range existing {
+		existingByID[subtask.ID] = subtask
+	}
+
+	subtasks := make([]Subtask, 0, len(specs))
+	seen := make(map[primitive.ObjectID]bool, len(specs))
+	for i, spec := range specs {
+		title := strings.TrimSpace(spec.Title)
+		if title == "" {
+			return nil, errors.New("subtask title is required")
+		}
+
+		subtask := Subtask{
+			ID:       primitive.NewObjectID(),
+			Title:    title,
+			Required: spec.Required,
+			Position: i,
+		}
+
+		if spec.ID != "" {
+			id, err := primitive.ObjectIDFromHex(spec.ID)
+			if err != nil {
+				return nil, errors.New("invalid subtask ID format")
+			}
+			previous, found := existingByID[id]
+			if !found {
+				return nil, errors.New("subtask not found: " + spec.ID)
+			}
+			if seen[id] {
+				return nil, errors.New("duplicate subtask: " + spec.ID)
+			}
+			subtask.ID = id
+			subtask.Done = previous.Done
+			subtask.DoneBy = previous.DoneBy
+			subtask.DoneAt = previous.DoneAt
+		}
+
+		seen[subtask.ID] = true
+		subtasks = append(subtasks, subtask)
+	}
+
+	return subtasks, nil
+}
+
+// copySubtasksForInstance returns the checklist for a new chore instance with every subtask open
+func copySubtasksForInstance(subtasks []Subtask) []Subtask {
+	if len(subtasks) == 0 {
+		return nil
+	}
+
+	copied := make([]Subtask, len(subtasks))
+	for i, subtask := range subtasks {
+		copied[i] = Subtask{
+			ID:       subtask.ID,
+			Title:    subtask.Title,
+			Required: subtask.Required,
+			Position: subtask.Position,
+		}
+	}
+	return copied
+}
+
+// EffectiveSubtaskPolicy returns the chore's subtask policy, defaulting to block
+func (c *Chore) EffectiveSubtaskPolicy() SubtaskPolicy {
+	if c.SubtaskPolicy.IsValid() {
+		return c.SubtaskPolicy
+	}
+	return SubtaskPolicyBlock
+}
+
+// OpenRequiredSubtasks returns the required subtasks that have not been ticked yet
+func (c *Chore) OpenRequiredSubtasks() []Subtask {
+	open := make([]Subtask, 0)
+	for _, subtask := range c.Subtasks {
+		if subtask.Required && !subtask.Done {
+			open = append(open, subtask)
+		}
+	}
+	return open
+}
+
+// EarnedPoints returns the points awarded for completing the chore. With partial points
+// enabled the chore's points are scaled by the fraction of subtasks done, rounded to the
+// nearest point; otherwise the full points are awarded.
+func (c *Chore) EarnedPoints() int {
+	if !c.PartialPoints || len(c.Subtasks) == 0 {
+		return c.Points
+	}
+
+	done := 0
+	for _, subtask := range c.Subtasks {
+		if subtask.Done {
+			done++
+		}
+	}
+
+	total := len(c.Subtasks)
+	return (c.Points*done*2 + total) / (total * 2)
+}
+
+// FindSubtask returns the index of the subtask with the given ID, or -1 if the chore has none
+func (c *Chore) FindSubtask(id primitive.ObjectID) int {
+	for i, subtask := range c.Subtasks {
+		if subtask.ID == id {
+			return i
+		}
+	}
+	return -1
+}
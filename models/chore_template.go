@@ -0,0 +1,126 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChoreTemplateType defines the type of chore template
+type ChoreTemplateType string
+
+const (
+	// ChoreTemplateTypePredefined indicates a system-wide predefined template
+	ChoreTemplateTypePredefined ChoreTemplateType = "predefined"
+
+	// ChoreTemplateTypeCustom indicates a group-specific custom template
+	ChoreTemplateTypeCustom ChoreTemplateType = "custom"
+)
+
+// ChoreTemplate is a reusable description of a recurring chore
+type ChoreTemplate struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Title       string              `bson:"title" json:"title" validate:"required"`
+	Description string              `bson:"description" json:"description"`
+	Points      int                 `bson:"points" json:"points" validate:"required,min=1"` // Suggested points
+	Frequency   string              `bson:"frequency" json:"frequency" validate:"required"` // daily, weekly, biweekly, monthly
+	Type        ChoreTemplateType   `bson:"type" json:"type"`
+	GroupID     *primitive.ObjectID `bson:"group_id,omitempty" json:"group_id,omitempty"`     // null for predefined, group_id for custom
+	CreatedBy   *primitive.ObjectID `bson:"created_by,omitempty" json:"created_by,omitempty"` // null for predefined, user_id for custom
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	IsActive    bool                `bson:"is_active" json:"is_active"`
+}
+
+// IsValidChoreFrequency checks if the frequency is one supported by recurring chores
+func IsValidChoreFrequency(frequency string) bool {
+	switch frequency {
+	case "daily", "weekly", "biweekly", "monthly":
+		return true
+	}
+	return false
+}
+
+// NextAssignmentAfter returns when a recurring chore with the given frequency is next assigned
+func NextAssignmentAfter(frequency string, from time.Time) time.Time {
+	switch frequency {
+	case "daily":
+		return from.Add(24 * time.Hour)
+	case "biweekly":
+		return from.Add(14 * 24 * time.Hour)
+	case "monthly":
+		return from.AddDate(0, 1, 0)
+	default:
+		return from.Add(7 * 24 * time.Hour) // Default to weekly
+	}
+}
+
+// CreatePredefinedChoreTemplate creates a new predefined chore template
+func CreatePredefinedChoreTemplate(title, description, frequency string, points int) *ChoreTemplate {
+	return &ChoreTemplate{
+		Title:       title,
+		Description: description,
+		Points:      points,
+		Frequency:   frequency,
+		Type:        ChoreTemplateTypePredefined,
+		GroupID:     nil,
+		CreatedBy:   nil,
+		CreatedAt:   time.Now(),
+		IsActive:    true,
+	}
+}
+
+// CreateCustomChoreTemplate creates a new custom chore template for a group
+func CreateCustomChoreTemplate(title, description, frequency string, points int, groupID primitive.ObjectID, createdBy primitive.ObjectID) *ChoreTemplate {
+	return &ChoreTemplate{
+		Title:       title,
+		Description: description,
+		Points:      points,
+		Frequency:   frequency,
+		Type:        ChoreTemplateTypeCustom,
+		GroupID:     &groupID,
+		CreatedBy:   &createdBy,
+		CreatedAt:   time.Now(),
+		IsActive:    true,
+	}
+}
+
+// IsPredefined checks if the template is a predefined template
+func (ct *ChoreTemplate) IsPredefined() bool {
+	return ct.Type == ChoreTemplateTypePredefined
+}
+
+// IsCustom checks if the template is a custom template
+func (ct *ChoreTemplate) IsCustom() bool {
+	return ct.Type == ChoreTemplateTypeCustom
+}
+
+// BelongsToGroup checks if the template is available to a specific group
+func (ct *ChoreTemplate) BelongsToGroup(groupID primitive.ObjectID) bool {
+	if ct.IsPredefined() {
+		return true // Predefined templates belong to all groups
+	}
+	return ct.GroupID != nil && *ct.GroupID == groupID
+}
+
+// CanBeDeletedBy checks if a user can delete this template
+func (ct *ChoreTemplate) CanBeDeletedBy(userID primitive.ObjectID, userGroupID primitive.ObjectID) bool {
+	if ct.IsPredefined() {
+		return false // Predefined templates cannot be deleted by users
+	}
+	// Custom templates can be deleted by members of the same group
+	return ct.GroupID != nil && *ct.GroupID == userGroupID
+}
+
+// ToRecurringChore instantiates the template as a recurring chore rotating over the given members
+func (ct *ChoreTemplate) ToRecurringChore(groupID primitive.ObjectID, memberRotation []primitive.ObjectID) *RecurringChore {
+	recurringChore := CreateRecurringChore(
+		ct.Title,
+		ct.Description,
+		groupID,
+		memberRotation,
+		ct.Frequency,
+		ct.Points,
+	)
+	recurringChore.NextAssignment = NextAssignmentAfter(ct.Frequency, time.Now())
+	return recurringChore
+}
@@ -15,6 +15,7 @@ type Group struct {
 	Name      string               `bson:"name" json:"name" validate:"required,min=3"`
 	GroupCode string               `bson:"group_code" json:"group_code"`
 	Members   []primitive.ObjectID `bson:"members" json:"members"`
+	Timezone  string               `bson:"timezone,omitempty" json:"timezone,omitempty"` // IANA zone analytics are bucketed in, UTC when unset
 	CreatedAt time.Time            `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time            `bson:"updated_at" json:"updated_at"`
 }
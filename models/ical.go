@@ -0,0 +1,101 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// iCalendar (RFC 5545) formatting constants
+const (
+	icalLineLimit    = 75 // Maximum octets per content line, excluding the CRLF
+	icalDateFormat   = "20060102"
+	icalStampFormat  = "20060102T150405Z"
+	icalProductID    = "-//Cribb//Chores//EN"
+	icalRefreshHours = 1
+)
+
+// MarshalICalendar serializes calendar events as an RFC 5545 VCALENDAR. Chores are due by the
+// end of their due day, so each one is written as an all-day event on its due date.
+func MarshalICalendar(calendarName string, events []CalendarEvent, stamp time.Time) string {
+	var b strings.Builder
+
+	writeICalLine(&b, "BEGIN:VCALENDAR")
+	writeICalLine(&b, "VERSION:2.0")
+	writeICalLine(&b, "PRODID:"+icalProductID)
+	writeICalLine(&b, "CALSCALE:GREGORIAN")
+	writeICalLine(&b, "METHOD:PUBLISH")
+	writeICalLine(&b, "X-WR-CALNAME:"+EscapeICalText(calendarName))
+	writeICalLine(&b, fmt.Sprintf("REFRESH-INTERVAL;VALUE=DURATION:PT%dH", icalRefreshHours))
+	writeICalLine(&b, fmt.Sprintf("X-PUBLISHED-TTL:PT%dH", icalRefreshHours))
+
+	dtstamp := stamp.UTC().Format(icalStampFormat)
+	for _, event := range events {
+		due := event.DueDate.UTC()
+
+		summary := event.Title
+		if event.Status == ChoreStatusCompleted {
+			summary = "✓ " + summary
+		}
+
+		description := event.Description
+		if description != "" {
+			description += "\n"
+		}
+		description += fmt.Sprintf("Points: %d", event.Points)
+
+		status := "CONFIRMED"
+		if event.Projected {
+			status = "TENTATIVE"
+		}
+
+		writeICalLine(&b, "BEGIN:VEVENT")
+		writeICalLine(&b, "UID:"+event.UID)
+		writeICalLine(&b, "DTSTAMP:"+dtstamp)
+		writeICalLine(&b, "DTSTART;VALUE=DATE:"+due.Format(icalDateFormat))
+		writeICalLine(&b, "DTEND;VALUE=DATE:"+due.AddDate(0, 0, 1).Format(icalDateFormat))
+		writeICalLine(&b, "SUMMARY:"+EscapeICalText(summary))
+		writeICalLine(&b, "DESCRIPTION:"+EscapeICalText(description))
+		writeICalLine(&b, "STATUS:"+status)
+		writeICalLine(&b, "TRANSP:TRANSPARENT")
+		if !event.UpdatedAt.IsZero() {
+			writeICalLine(&b, "LAST-MODIFIED:"+event.UpdatedAt.UTC().Format(icalStampFormat))
+		}
+		writeICalLine(&b, "END:VEVENT")
+	}
+
+	writeICalLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// EscapeICalText escapes a value of the TEXT type (RFC 5545 section 3.3.11)
+func EscapeICalText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return replacer.Replace(s)
+}
+
+// writeICalLine writes a content line terminated by CRLF, folding it so that no physical line
+// exceeds 75 octets. Continuation lines start with a single space and never split a UTF-8 character.
+func writeICalLine(b *strings.Builder, line string) {
+	limit := icalLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icalLineLimit - 1 // Account for the leading space
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
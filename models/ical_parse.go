@@ -0,0 +1,444 @@
+package models
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRecurrenceSteps bounds how far a recurrence rule is walked when looking for an occurrence
+const maxRecurrenceSteps = 10000
+
+// ICalParseError is a validation error tied to a line of an uploaded iCalendar file
+type ICalParseError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e ICalParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// ICalAttendee is an ATTENDEE of an event
+type ICalAttendee struct {
+	Email string `json:"email"`
+	Name  string `json:"name"` // CN parameter
+}
+
+// ICalRecurrence is the subset of an RRULE needed to map an event to a recurring chore
+type ICalRecurrence struct {
+	Freq     string    `json:"freq"` // DAILY, WEEKLY, MONTHLY, YEARLY, ...
+	Interval int       `json:"interval"`
+	Count    int       `json:"count,omitempty"`
+	Until    time.Time `json:"until,omitempty"`
+	ByDay    []string  `json:"by_day,omitempty"`
+}
+
+// ICalEvent is a VEVENT read from an iCalendar file
+type ICalEvent struct {
+	Line         int // Line of BEGIN:VEVENT
+	UID          string
+	Summary      string
+	Description  string
+	Start        time.Time
+	AllDay       bool
+	Status       string
+	Attendees    []ICalAttendee
+	RRule        *ICalRecurrence
+	RecurrenceID bool // Set on events that override a single occurrence of another event
+}
+
+// icalContentLine is an unfolded content line with the physical line it started on
+type icalContentLine struct {
+	line   int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// ParseICalendar reads the VEVENTs of an iCalendar (RFC 5545) stream. All validation errors are
+// collected with their line numbers rather than stopping at the first one.
+func ParseICalendar(r io.Reader) ([]ICalEvent, []ICalParseError) {
+	var errs []ICalParseError
+	addError := func(line int, format string, args ...interface{}) {
+		errs = append(errs, ICalParseError{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, []ICalParseError{{Line: 0, Message: "could not read file: " + err.Error()}}
+	}
+	if len(lines) == 0 {
+		return nil, []ICalParseError{{Line: 1, Message: "file is empty"}}
+	}
+
+	type openComponent struct {
+		name string
+		line int
+	}
+	var stack []openComponent
+	var events []ICalEvent
+	var current *ICalEvent
+	seenCalendar := false
+
+	for _, raw := range lines {
+		cl, ok := parseICalContentLine(raw.line, raw.text)
+		if !ok {
+			addError(raw.line, "expected NAME:VALUE, got %q", truncateICal(raw.text))
+			continue
+		}
+
+		switch cl.name {
+		case "BEGIN":
+			component := strings.ToUpper(cl.value)
+			if len(stack) == 0 && component != "VCALENDAR" {
+				addError(cl.line, "expected BEGIN:VCALENDAR, got BEGIN:%s", component)
+			}
+			if component == "VCALENDAR" {
+				seenCalendar = true
+			}
+			if component == "VEVENT" && len(stack) > 0 && stack[len(stack)-1].name == "VCALENDAR" {
+				current = &ICalEvent{Line: cl.line}
+			}
+			stack = append(stack, openComponent{name: component, line: cl.line})
+			continue
+
+		case "END":
+			component := strings.ToUpper(cl.value)
+			if len(stack) == 0 {
+				addError(cl.line, "END:%s without matching BEGIN", component)
+				continue
+			}
+			open := stack[len(stack)-1]
+			if open.name != component {
+				addError(cl.line, "END:%s does not match BEGIN:%s on line %d", component, open.name, open.line)
+				continue
+			}
+			stack = stack[:len(stack)-1]
+			if component == "VEVENT" && current != nil {
+				if current.Start.IsZero() {
+					addError(current.Line, "event is missing DTSTART")
+				}
+				if strings.TrimSpace(current.Summary) == "" {
+					addError(current.Line, "event is missing SUMMARY")
+				}
+				events = append(events, *current)
+				current = nil
+			}
+			continue
+		}
+
+		if len(stack) == 0 {
+			addError(cl.line, "property %s is outside of BEGIN:VCALENDAR", cl.name)
+			continue
+		}
+
+		// Only properties directly inside a VEVENT matter; alarms, time zones and so on are skipped
+		if current == nil || stack[len(stack)-1].name != "VEVENT" {
+			continue
+		}
+
+		switch cl.name {
+		case "UID":
+			current.UID = cl.value
+		case "SUMMARY":
+			current.Summary = unescapeICalText(cl.value)
+		case "DESCRIPTION":
+			current.Description = unescapeICalText(cl.value)
+		case "STATUS":
+			current.Status = strings.ToUpper(cl.value)
+		case "RECURRENCE-ID":
+			current.RecurrenceID = true
+		case "DTSTART":
+			start, allDay, err := parseICalTime(cl.value, cl.params)
+			if err != nil {
+				addError(cl.line, "invalid DTSTART: %v", err)
+				continue
+			}
+			current.Start = start
+			current.AllDay = allDay
+		case "RRULE":
+			rule, err := parseICalRRule(cl.value)
+			if err != nil {
+				addError(cl.line, "invalid RRULE: %v", err)
+				continue
+			}
+			current.RRule = rule
+		case "ATTENDEE":
+			attendee := ICalAttendee{Name: cl.params["CN"]}
+			if strings.HasPrefix(strings.ToLower(cl.value), "mailto:") {
+				attendee.Email = strings.ToLower(cl.value[len("mailto:"):])
+			}
+			current.Attendees = append(current.Attendees, attendee)
+		}
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		addError(stack[i].line, "BEGIN:%s is never closed", stack[i].name)
+	}
+	if !seenCalendar && len(errs) == 0 {
+		addError(1, "file does not contain a VCALENDAR")
+	}
+
+	return events, errs
+}
+
+type icalRawLine struct {
+	line int
+	text string
+}
+
+// unfoldICalLines joins folded continuation lines, remembering where each logical line started
+func unfoldICalLines(r io.Reader) ([]icalRawLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []icalRawLine
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if number == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if text == "" {
+			continue
+		}
+		if (text[0] == ' ' || text[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1].text += text[1:]
+			continue
+		}
+		lines = append(lines, icalRawLine{line: number, text: text})
+	}
+	return lines, scanner.Err()
+}
+
+// parseICalContentLine splits "NAME;PARAM=VALUE:value" into its parts
+func parseICalContentLine(line int, text string) (icalContentLine, bool) {
+	cl := icalContentLine{line: line, params: make(map[string]string)}
+
+	// The value starts at the first colon that is not inside a quoted parameter value
+	inQuotes := false
+	colon := -1
+	for i, c := range text {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if c == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return cl, false
+	}
+
+	head := text[:colon]
+	cl.value = text[colon+1:]
+
+	parts := splitICalParams(head)
+	cl.name = strings.ToUpper(strings.TrimSpace(parts[0]))
+	if cl.name == "" {
+		return cl, false
+	}
+	for _, param := range parts[1:] {
+		key, value, found := strings.Cut(param, "=")
+		if !found {
+			return cl, false
+		}
+		cl.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return cl, true
+}
+
+// splitICalParams splits on semicolons outside of quotes
+func splitICalParams(head string) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i, c := range head {
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == ';' && !inQuotes:
+			parts = append(parts, head[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, head[start:])
+}
+
+// parseICalTime parses a DATE or DATE-TIME value. Floating times and unknown time zones are read as UTC.
+func parseICalTime(value string, params map[string]string) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.Parse("20060102", value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("expected YYYYMMDD, got %q", value)
+		}
+		return t, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("expected YYYYMMDDTHHMMSSZ, got %q", value)
+		}
+		return t, false, nil
+	}
+
+	location := time.UTC
+	if tzid := params["TZID"]; tzid != "" {
+		if loaded, err := time.LoadLocation(tzid); err == nil {
+			location = loaded
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, location)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("expected YYYYMMDDTHHMMSS, got %q", value)
+	}
+	return t, false, nil
+}
+
+// parseICalRRule parses the parts of an RRULE used for mapping to chore frequencies
+func parseICalRRule(value string) (*ICalRecurrence, error) {
+	rule := &ICalRecurrence{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, found := strings.Cut(part, "=")
+		if !found {
+			return nil, fmt.Errorf("expected KEY=VALUE, got %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			switch strings.ToUpper(val) {
+			case "SECONDLY", "MINUTELY", "HOURLY", "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				rule.Freq = strings.ToUpper(val)
+			default:
+				return nil, fmt.Errorf("unknown FREQ %q", val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("INTERVAL must be a positive integer, got %q", val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive integer, got %q", val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, _, err := parseICalTime(val, map[string]string{})
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL: %v", err)
+			}
+			rule.Until = until
+		case "BYDAY":
+			rule.ByDay = strings.Split(strings.ToUpper(val), ",")
+		}
+	}
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	return rule, nil
+}
+
+// unescapeICalText reverses EscapeICalText
+func unescapeICalText(s string) string {
+	replacer := strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+	return replacer.Replace(s)
+}
+
+func truncateICal(s string) string {
+	if len(s) > 40 {
+		return s[:40] + "..."
+	}
+	return s
+}
+
+// occurrence returns the n-th (zero-based) occurrence of a recurrence starting at start
+func (rule *ICalRecurrence) occurrence(start time.Time, n int) time.Time {
+	step := n * rule.Interval
+	switch rule.Freq {
+	case "DAILY":
+		return start.AddDate(0, 0, step)
+	case "WEEKLY":
+		return start.AddDate(0, 0, 7*step)
+	case "MONTHLY":
+		return start.AddDate(0, step, 0)
+	case "YEARLY":
+		return start.AddDate(step, 0, 0)
+	default:
+		return start.Add(time.Duration(step) * time.Hour)
+	}
+}
+
+// NextOccurrences returns up to limit occurrences of the event on or after from, honouring
+// the rule's COUNT and UNTIL. Non-recurring events have at most one occurrence.
+func (e *ICalEvent) NextOccurrences(from time.Time, limit int) []time.Time {
+	var occurrences []time.Time
+	if e.RRule == nil {
+		if !e.Start.Before(from) {
+			occurrences = append(occurrences, e.Start)
+		}
+		return occurrences
+	}
+
+	for n := 0; n < maxRecurrenceSteps && len(occurrences) < limit; n++ {
+		if e.RRule.Count > 0 && n >= e.RRule.Count {
+			break
+		}
+		occurrence := e.RRule.occurrence(e.Start, n)
+		if !e.RRule.Until.IsZero() && occurrence.After(e.RRule.Until) {
+			break
+		}
+		if !occurrence.Before(from) {
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+	return occurrences
+}
+
+// ChoreFrequency maps the rule to the closest supported recurring chore frequency. exact is
+// false when the mapping changes the schedule, for example for every-3-days or multi-day weekly rules.
+func (rule *ICalRecurrence) ChoreFrequency() (frequency string, exact bool) {
+	switch {
+	case rule.Freq == "DAILY" && rule.Interval == 1:
+		return "daily", true
+	case rule.Freq == "WEEKLY" && rule.Interval == 1 && len(rule.ByDay) <= 1:
+		return "weekly", true
+	case rule.Freq == "WEEKLY" && rule.Interval == 2 && len(rule.ByDay) <= 1:
+		return "biweekly", true
+	case rule.Freq == "MONTHLY" && rule.Interval == 1:
+		return "monthly", true
+	}
+
+	var days int
+	switch rule.Freq {
+	case "DAILY":
+		days = rule.Interval
+	case "WEEKLY":
+		days = 7 * rule.Interval
+	case "MONTHLY":
+		days = 30 * rule.Interval
+	case "YEARLY":
+		days = 365 * rule.Interval
+	}
+
+	switch {
+	case days <= 3:
+		return "daily", false
+	case days <= 10:
+		return "weekly", false
+	case days <= 21:
+		return "biweekly", false
+	default:
+		return "monthly", false
+	}
+}
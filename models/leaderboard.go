@@ -0,0 +1,162 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LeaderboardPeriod represents the time window a leaderboard is computed over
+type LeaderboardPeriod string
+
+const (
+	LeaderboardPeriodWeek    LeaderboardPeriod = "week"     // Monday 00:00 UTC to the following Monday
+	LeaderboardPeriodMonth   LeaderboardPeriod = "month"    // First day of the month to the first day of the next month
+	LeaderboardPeriodCustom  LeaderboardPeriod = "custom"   // Caller supplied start and end
+	LeaderboardPeriodAllTime LeaderboardPeriod = "all_time" // Every completion ever recorded
+)
+
+// IsValid checks if the period is one of the supported leaderboard periods
+func (p LeaderboardPeriod) IsValid() bool {
+	switch p {
+	case LeaderboardPeriodWeek, LeaderboardPeriodMonth, LeaderboardPeriodCustom, LeaderboardPeriodAllTime:
+		return true
+	}
+	return false
+}
+
+// IsArchivable checks if snapshots of the period are archived by the leaderboard job
+func (p LeaderboardPeriod) IsArchivable() bool {
+	return p == LeaderboardPeriodWeek || p == LeaderboardPeriodMonth
+}
+
+// LeaderboardEntry represents a single member's standing within a leaderboard
+type LeaderboardEntry struct {
+	UserID          primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Username        string             `bson:"username" json:"username"`
+	Name            string             `bson:"name" json:"name"`
+	Points          int                `bson:"points" json:"points"`
+	ChoresCompleted int                `bson:"chores_completed" json:"chores_completed"`
+	Rank            int                `bson:"rank" json:"rank"`
+	PreviousRank    int                `bson:"previous_rank,omitempty" json:"previous_rank,omitempty"` // 0 when the member was not ranked in the previous period
+	RankChange      int                `bson:"rank_change" json:"rank_change"`                         // Positive when the member moved up
+}
+
+// LeaderboardSnapshot is an archived leaderboard for a completed week or month
+type LeaderboardSnapshot struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	GroupID     primitive.ObjectID  `bson:"group_id" json:"group_id"`
+	Period      LeaderboardPeriod   `bson:"period" json:"period"`
+	PeriodStart time.Time           `bson:"period_start" json:"period_start"`
+	PeriodEnd   time.Time           `bson:"period_end" json:"period_end"`
+	Entries     []LeaderboardEntry  `bson:"entries" json:"entries"`
+	WinnerID    *primitive.ObjectID `bson:"winner_id,omitempty" json:"winner_id,omitempty"` // nil when nobody scored in the period
+	WinnerName  string              `bson:"winner_name,omitempty" json:"winner_name,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// PeriodRange returns the [start, end) window of the period containing ref.
+// All periods are calculated in UTC so that they line up with due dates.
+func PeriodRange(period LeaderboardPeriod, ref time.Time) (time.Time, time.Time, error) {
+	utc := ref.UTC()
+	year, month, day := utc.Date()
+
+	switch period {
+	case LeaderboardPeriodWeek:
+		// Weeks start on Monday
+		offset := (int(utc.Weekday()) + 6) % 7
+		start := time.Date(year, month, day-offset, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 7), nil
+	case LeaderboardPeriodMonth:
+		start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0), nil
+	case LeaderboardPeriodAllTime:
+		return time.Time{}, time.Time{}, nil
+	}
+
+	return time.Time{}, time.Time{}, errors.New("period does not have a fixed range")
+}
+
+// PreviousPeriodRange returns the window immediately preceding [start, end).
+// Calendar periods step back by one week/month, custom ranges by their own length.
+func PreviousPeriodRange(period LeaderboardPeriod, start, end time.Time) (time.Time, time.Time) {
+	switch period {
+	case LeaderboardPeriodWeek:
+		return start.AddDate(0, 0, -7), start
+	case LeaderboardPeriodMonth:
+		return start.AddDate(0, -1, 0), start
+	}
+	return start.Add(-end.Sub(start)), start
+}
+
+// RankLeaderboard sorts entries by points (then chores completed, then name) and assigns
+// competition ranks, so members with identical points share a rank ("1, 1, 3").
+func RankLeaderboard(entries []LeaderboardEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Points != entries[j].Points {
+			return entries[i].Points > entries[j].Points
+		}
+		if entries[i].ChoresCompleted != entries[j].ChoresCompleted {
+			return entries[i].ChoresCompleted > entries[j].ChoresCompleted
+		}
+		return entries[i].Name < entries[j].Name
+	})
+
+	for i := range entries {
+		if i > 0 && entries[i].Points == entries[i-1].Points {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+}
+
+// ApplyRankChanges fills PreviousRank and RankChange on current using the ranks in previous.
+// Members absent from previous are treated as new entrants and get a zero change.
+func ApplyRankChanges(current, previous []LeaderboardEntry) {
+	previousRanks := make(map[primitive.ObjectID]int, len(previous))
+	for _, entry := range previous {
+		previousRanks[entry.UserID] = entry.Rank
+	}
+
+	for i := range current {
+		prevRank, found := previousRanks[current[i].UserID]
+		if !found {
+			current[i].PreviousRank = 0
+			current[i].RankChange = 0
+			continue
+		}
+		current[i].PreviousRank = prevRank
+		current[i].RankChange = prevRank - current[i].Rank
+	}
+}
+
+// LeaderboardWinner returns the top entry of a ranked leaderboard, or nil if nobody scored
+func LeaderboardWinner(entries []LeaderboardEntry) *LeaderboardEntry {
+	if len(entries) == 0 || entries[0].Points <= 0 {
+		return nil
+	}
+	return &entries[0]
+}
+
+// CreateLeaderboardSnapshot builds an archived snapshot from ranked entries
+func CreateLeaderboardSnapshot(groupID primitive.ObjectID, period LeaderboardPeriod, start, end time.Time, entries []LeaderboardEntry) *LeaderboardSnapshot {
+	snapshot := &LeaderboardSnapshot{
+		GroupID:     groupID,
+		Period:      period,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Entries:     entries,
+		CreatedAt:   time.Now(),
+	}
+
+	if winner := LeaderboardWinner(entries); winner != nil {
+		winnerID := winner.UserID
+		snapshot.WinnerID = &winnerID
+		snapshot.WinnerName = winner.Name
+	}
+
+	return snapshot
+}
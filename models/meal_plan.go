@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MealType defines which meal of the day a planned recipe is for
+type MealType string
+
+const (
+	MealBreakfast MealType = "breakfast"
+	MealLunch     MealType = "lunch"
+	MealDinner    MealType = "dinner"
+	MealSnack     MealType = "snack"
+)
+
+// MealPlanDateLayout is the layout of the days in a meal plan
+const MealPlanDateLayout = "2006-01-02"
+
+// IsValidMealType checks if the meal type is one of the known meals
+func IsValidMealType(meal MealType) bool {
+	switch meal {
+	case MealBreakfast, MealLunch, MealDinner, MealSnack:
+		return true
+	}
+	return false
+}
+
+// MealPlanEntry is a recipe a group plans to cook for a meal on a day
+type MealPlanEntry struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	GroupID    primitive.ObjectID  `bson:"group_id" json:"group_id"`
+	RecipeID   primitive.ObjectID  `bson:"recipe_id" json:"recipe_id"`
+	RecipeName string              `bson:"recipe_name" json:"recipe_name"`
+	Date       string              `bson:"date" json:"date"` // YYYY-MM-DD in the group's time zone
+	Meal       MealType            `bson:"meal" json:"meal"`
+	Servings   int                 `bson:"servings" json:"servings"`
+	CookedAt   *time.Time          `bson:"cooked_at,omitempty" json:"cooked_at,omitempty"`
+	CookedBy   *primitive.ObjectID `bson:"cooked_by,omitempty" json:"cooked_by,omitempty"`
+	CreatedBy  primitive.ObjectID  `bson:"created_by" json:"created_by"`
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// CreateMealPlanEntry plans a recipe for a meal on a day
+func CreateMealPlanEntry(recipe *Recipe, date string, meal MealType, servings int, createdBy primitive.ObjectID) *MealPlanEntry {
+	if servings <= 0 {
+		servings = recipe.Servings
+	}
+	return &MealPlanEntry{
+		GroupID:    recipe.GroupID,
+		RecipeID:   recipe.ID,
+		RecipeName: recipe.Name,
+		Date:       date,
+		Meal:       meal,
+		Servings:   servings,
+		CreatedBy:  createdBy,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// IsCooked checks if the planned meal has been cooked
+func (e *MealPlanEntry) IsCooked() bool {
+	return e.CookedAt != nil
+}
+
+// WeekDates returns the seven days of the week starting at start, as meal plan dates. Weeks
+// start on Monday, as they do in analytics; see BucketStart.
+func WeekDates(start time.Time) []string {
+	dates := make([]string, 7)
+	for i := range dates {
+		dates[i] = start.AddDate(0, 0, i).Format(MealPlanDateLayout)
+	}
+	return dates
+}
+
+// PlannedIngredients returns what the uncooked entries of a plan need, combined across meals.
+// Entries whose recipe is not in recipes are skipped.
+func PlannedIngredients(entries []MealPlanEntry, recipes map[primitive.ObjectID]Recipe) []RecipeIngredient {
+	var ingredients []RecipeIngredient
+	for _, entry := range entries {
+		if entry.IsCooked() {
+			continue
+		}
+		recipe, ok := recipes[entry.RecipeID]
+		if !ok {
+			continue
+		}
+		ingredients = append(ingredients, recipe.ScaledIngredients(entry.Servings)...)
+	}
+	return CombineIngredients(ingredients)
+}
@@ -0,0 +1,541 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NotificationType defines the type of a notification
+type NotificationType string
+
+const (
+	// NotificationTypeChoreComment indicates someone commented on a chore
+	NotificationTypeChoreComment NotificationType = "chore_comment"
+
+	// NotificationTypeChoreMention indicates a member was mentioned in a chore comment
+	NotificationTypeChoreMention NotificationType = "chore_mention"
+
+	// NotificationTypeChoreDueSoon reminds a member that their chore is due soon
+	NotificationTypeChoreDueSoon NotificationType = "chore_due_soon"
+
+	// NotificationTypeChoreOverdue tells a member that their chore is past its due date
+	NotificationTypeChoreOverdue NotificationType = "chore_overdue"
+
+	// NotificationTypeChoreDigest is a member's daily summary of their chores
+	NotificationTypeChoreDigest NotificationType = "chore_digest"
+
+	// NotificationTypeLowStock indicates an item is running low
+	NotificationTypeLowStock NotificationType = "low_stock"
+
+	// NotificationTypeExpiringSoon indicates an item is expiring soon
+	NotificationTypeExpiringSoon NotificationType = "expiring_soon"
+
+	// NotificationTypeExpired indicates an item has expired
+	NotificationTypeExpired NotificationType = "expired"
+
+	// NotificationTypeOutOfStock indicates an item has run out
+	NotificationTypeOutOfStock NotificationType = "out_of_stock"
+
+	// NotificationTypeRunningOutSoon warns that an item will run out in a few days at the rate
+	// the group uses it
+	NotificationTypeRunningOutSoon NotificationType = "running_out_soon"
+
+	// NotificationTypePantryItemUsed tells a member that someone else used their item
+	NotificationTypePantryItemUsed NotificationType = "pantry_item_used"
+
+	// NotificationTypePantryUseRequested asks a member for permission to use their item
+	NotificationTypePantryUseRequested NotificationType = "pantry_use_requested"
+
+	// NotificationTypePantryUseAllowed tells a member they may now use someone's item
+	NotificationTypePantryUseAllowed NotificationType = "pantry_use_allowed"
+
+	// NotificationTypeCartItemAdded indicates an item was added to the shopping cart
+	NotificationTypeCartItemAdded NotificationType = "cart_item_added"
+
+	// NotificationTypeCartItemUpdated indicates a shopping cart item was changed
+	NotificationTypeCartItemUpdated NotificationType = "cart_item_updated"
+
+	// NotificationTypeCartItemRemoved indicates an item was removed from the shopping cart
+	NotificationTypeCartItemRemoved NotificationType = "cart_item_removed"
+)
+
+// NotificationDomain is the part of the app a notification comes from
+type NotificationDomain string
+
+const (
+	NotificationDomainChore  NotificationDomain = "chore"
+	NotificationDomainPantry NotificationDomain = "pantry"
+	NotificationDomainCart   NotificationDomain = "cart"
+)
+
+// NotificationRetention is how long notifications are kept. Cart notifications expire with
+// the activity they describe.
+const NotificationRetention = 60 * 24 * time.Hour
+
+// Domain returns the domain of the notification type, empty for unknown types
+func (t NotificationType) Domain() NotificationDomain {
+	switch t {
+	case NotificationTypeChoreComment, NotificationTypeChoreMention, NotificationTypeChoreDueSoon,
+		NotificationTypeChoreOverdue, NotificationTypeChoreDigest:
+		return NotificationDomainChore
+	case NotificationTypeLowStock, NotificationTypeExpiringSoon, NotificationTypeExpired, NotificationTypeOutOfStock,
+		NotificationTypeRunningOutSoon, NotificationTypePantryItemUsed, NotificationTypePantryUseRequested, NotificationTypePantryUseAllowed:
+		return NotificationDomainPantry
+	case NotificationTypeCartItemAdded, NotificationTypeCartItemUpdated, NotificationTypeCartItemRemoved:
+		return NotificationDomainCart
+	}
+	return ""
+}
+
+// IsValid checks if the notification type is known
+func (t NotificationType) IsValid() bool {
+	return t.Domain() != ""
+}
+
+// ChoreNotificationPayload is the chore a notification is about
+type ChoreNotificationPayload struct {
+	ChoreID          *primitive.ObjectID `bson:"chore_id,omitempty" json:"chore_id,omitempty"`
+	RecurringChoreID *primitive.ObjectID `bson:"recurring_chore_id,omitempty" json:"recurring_chore_id,omitempty"`
+	CommentID        *primitive.ObjectID `bson:"comment_id,omitempty" json:"comment_id,omitempty"`
+	Title            string              `bson:"title,omitempty" json:"title,omitempty"`
+}
+
+// PantryNotificationPayload is the pantry item a notification is about
+type PantryNotificationPayload struct {
+	ItemID   primitive.ObjectID  `bson:"item_id" json:"item_id"`
+	ItemName string              `bson:"item_name" json:"item_name"`
+	LotID    *primitive.ObjectID `bson:"lot_id,omitempty" json:"lot_id,omitempty"` // Set when about a single lot
+
+	// ExpirationEstimated marks expiry notifications about an estimated expiration date
+	ExpirationEstimated bool `bson:"expiration_estimated,omitempty" json:"expiration_estimated,omitempty"`
+}
+
+// CartNotificationPayload is the shopping cart change a notification is about
+type CartNotificationPayload struct {
+	ActivityID primitive.ObjectID `bson:"activity_id" json:"activity_id"`
+	ItemID     primitive.ObjectID `bson:"item_id" json:"item_id"`
+	ItemName   string             `bson:"item_name" json:"item_name"`
+	Action     CartActivityType   `bson:"action" json:"action"`
+	Quantity   float64            `bson:"quantity" json:"quantity"`
+}
+
+// Notification is an entry in a group's notification stream. Exactly one payload is set,
+// matching the domain of the type. Read state is kept per user in NotificationReceipt
+// documents rather than on the notification.
+type Notification struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	GroupID     primitive.ObjectID  `bson:"group_id" json:"group_id"`
+	Type        NotificationType    `bson:"type" json:"type"`
+	Domain      NotificationDomain  `bson:"domain" json:"domain"`
+	RecipientID *primitive.ObjectID `bson:"recipient_id,omitempty" json:"recipient_id,omitempty"` // nil when meant for the whole group
+	ActorID     *primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id,omitempty"`         // nil for system notifications
+	ActorName   string              `bson:"actor_name,omitempty" json:"actor_name,omitempty"`
+	Message     string              `bson:"message" json:"message"`
+
+	Chore  *ChoreNotificationPayload  `bson:"chore,omitempty" json:"chore,omitempty"`
+	Pantry *PantryNotificationPayload `bson:"pantry,omitempty" json:"pantry,omitempty"`
+	Cart   *CartNotificationPayload   `bson:"cart,omitempty" json:"cart,omitempty"`
+
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	ExpiresAt time.Time  `bson:"expires_at" json:"expires_at"`
+	PushedAt  *time.Time `bson:"pushed_at,omitempty" json:"-"` // When Web Push delivery was claimed
+}
+
+// NotificationReceipt records that a user read a notification. Receipts expire with their
+// notification, and marking everything read replaces the older ones with a watermark.
+type NotificationReceipt struct {
+	ID                    primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	NotificationID        primitive.ObjectID `bson:"notification_id" json:"notification_id"`
+	UserID                primitive.ObjectID `bson:"user_id" json:"user_id"`
+	NotificationCreatedAt time.Time          `bson:"notification_created_at" json:"notification_created_at"`
+	ReadAt                time.Time          `bson:"read_at" json:"read_at"`
+	ExpiresAt             time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+// NotificationSettings holds a user's notification read watermark and muted types
+type NotificationSettings struct {
+	ReadAllAt *time.Time         `bson:"read_all_at,omitempty" json:"read_all_at,omitempty"` // Everything created up to here is read
+	Muted     []NotificationType `bson:"muted,omitempty" json:"muted"`
+}
+
+func newNotification(groupID primitive.ObjectID, notificationType NotificationType, message string) *Notification {
+	now := time.Now()
+	return &Notification{
+		GroupID:   groupID,
+		Type:      notificationType,
+		Domain:    notificationType.Domain(),
+		Message:   message,
+		CreatedAt: now,
+		ExpiresAt: now.Add(NotificationRetention),
+	}
+}
+
+// CreatePantryNotification creates a group-wide notification about a pantry item
+func CreatePantryNotification(
+	groupID primitive.ObjectID,
+	itemID primitive.ObjectID,
+	itemName string,
+	notificationType NotificationType,
+	message string,
+) *Notification {
+	notification := newNotification(groupID, notificationType, message)
+	notification.Pantry = &PantryNotificationPayload{ItemID: itemID, ItemName: itemName}
+	return notification
+}
+
+// CreatePantryMemberNotification creates a notification for a single member about something
+// another member did with a pantry item
+func CreatePantryMemberNotification(
+	item *PantryItem,
+	recipientID primitive.ObjectID,
+	actor *User,
+	notificationType NotificationType,
+	message string,
+) *Notification {
+	notification := CreatePantryNotification(item.GroupID, item.ID, item.Name, notificationType, message)
+	notification.RecipientID = &recipientID
+	notification.ActorID = &actor.ID
+	notification.ActorName = actor.Name
+	return notification
+}
+
+// CreateLotExpiryNotification creates the group-wide notification that a lot of a pantry item
+// is expiring soon or has expired, saying so when its expiration date is only estimated
+func CreateLotExpiryNotification(item *PantryItem, lot *PantryLot, notificationType NotificationType) *Notification {
+	message := "Item will expire in 3 days or less"
+	if notificationType == NotificationTypeExpired {
+		message = "Item has expired; discard it to keep track of waste"
+	}
+	if lot.ExpirationEstimated {
+		message += " (estimated expiration date)"
+	}
+
+	notification := CreatePantryNotification(item.GroupID, item.ID, item.Name, notificationType, message)
+	notification.Pantry.LotID = &lot.ID
+	notification.Pantry.ExpirationEstimated = lot.ExpirationEstimated
+	return notification
+}
+
+// CreateCartNotification creates the group-wide notification for a shopping cart change
+func CreateCartNotification(activity *ShoppingCartActivity) *Notification {
+	notificationType := NotificationTypeCartItemUpdated
+	switch activity.Action {
+	case CartActivityTypeAdd:
+		notificationType = NotificationTypeCartItemAdded
+	case CartActivityTypeDelete:
+		notificationType = NotificationTypeCartItemRemoved
+	}
+
+	message := activity.Details
+	if message == "" {
+		message = activity.UserName + " changed " + activity.ItemName
+	}
+
+	actorID := activity.UserID
+	notification := newNotification(activity.GroupID, notificationType, message)
+	notification.ActorID = &actorID
+	notification.ActorName = activity.UserName
+	notification.Cart = &CartNotificationPayload{
+		ActivityID: activity.ID,
+		ItemID:     activity.ItemID,
+		ItemName:   activity.ItemName,
+		Action:     activity.Action,
+		Quantity:   activity.Quantity,
+	}
+	if !activity.ExpiresAt.IsZero() {
+		notification.ExpiresAt = activity.ExpiresAt
+	}
+	return notification
+}
+
+// CreateChoreNotification creates a notification about a chore for a single member
+func CreateChoreNotification(
+	groupID primitive.ObjectID,
+	recipientID primitive.ObjectID,
+	notificationType NotificationType,
+	payload ChoreNotificationPayload,
+	message string,
+) *Notification {
+	notification := newNotification(groupID, notificationType, message)
+	notification.RecipientID = &recipientID
+	notification.Chore = &payload
+	return notification
+}
+
+// CreateCommentNotifications builds the group-wide notification for a new comment and one
+// mention notification per mentioned member other than the author
+func CreateCommentNotifications(comment *ChoreComment) []*Notification {
+	commentID := comment.ID
+	authorID := comment.AuthorID
+
+	newCommentNotification := func(notificationType NotificationType, message string) *Notification {
+		notification := newNotification(comment.GroupID, notificationType, message)
+		notification.ActorID = &authorID
+		notification.ActorName = comment.AuthorName
+		notification.Chore = &ChoreNotificationPayload{
+			ChoreID:          comment.ChoreID,
+			RecurringChoreID: comment.RecurringChoreID,
+			CommentID:        &commentID,
+			Title:            comment.ChoreTitle,
+		}
+		return notification
+	}
+
+	notifications := []*Notification{
+		newCommentNotification(NotificationTypeChoreComment, comment.AuthorName+" commented on "+comment.ChoreTitle),
+	}
+
+	for _, mentioned := range comment.Mentions {
+		if mentioned == comment.AuthorID {
+			continue
+		}
+		recipientID := mentioned
+		notification := newCommentNotification(NotificationTypeChoreMention, comment.AuthorName+" mentioned you on "+comment.ChoreTitle)
+		notification.RecipientID = &recipientID
+		notifications = append(notifications, notification)
+	}
+
+	return notifications
+}
+
+// IsVisibleTo checks if the notification should be shown to a user
+func (n *Notification) IsVisibleTo(userID primitive.ObjectID) bool {
+	return n.RecipientID == nil || *n.RecipientID == userID
+}
+
+// IsReadBy checks if a user has read the notification, given whether they have a receipt
+// for it. Users never need to read about their own actions.
+func (n *Notification) IsReadBy(userID primitive.ObjectID, settings NotificationSettings, hasReceipt bool) bool {
+	if hasReceipt {
+		return true
+	}
+	if n.ActorID != nil && *n.ActorID == userID {
+		return true
+	}
+	return settings.ReadAllAt != nil && !n.CreatedAt.After(*settings.ReadAllAt)
+}
+
+// IsMuted checks if the user muted a notification type
+func (s NotificationSettings) IsMuted(notificationType NotificationType) bool {
+	for _, muted := range s.Muted {
+		if muted == notificationType {
+			return true
+		}
+	}
+	return false
+}
+
+// PushRecipients returns the members a notification should be pushed to: those it is
+// visible to, other than the member who caused it and members who muted its type
+func PushRecipients(notification *Notification, members []User) []primitive.ObjectID {
+	recipients := make([]primitive.ObjectID, 0, len(members))
+	for _, member := range members {
+		if !notification.IsVisibleTo(member.ID) || member.Notifications.IsMuted(notification.Type) {
+			continue
+		}
+		if notification.ActorID != nil && *notification.ActorID == member.ID {
+			continue
+		}
+		recipients = append(recipients, member.ID)
+	}
+	return recipients
+}
+
+// NewNotificationReceipt records that a user read a notification at now
+func NewNotificationReceipt(notification *Notification, userID primitive.ObjectID, now time.Time) *NotificationReceipt {
+	return &NotificationReceipt{
+		NotificationID:        notification.ID,
+		UserID:                userID,
+		NotificationCreatedAt: notification.CreatedAt,
+		ReadAt:                now,
+		ExpiresAt:             notification.ExpiresAt,
+	}
+}
+
+// AccessibleNotifications returns the filter for the notifications of a group a user may act
+// on: those meant for the whole group or for them, muted types included
+func AccessibleNotifications(groupID primitive.ObjectID, user *User) bson.M {
+	return bson.M{
+		"group_id": groupID,
+		"$or": []bson.M{
+			{"recipient_id": bson.M{"$exists": false}},
+			{"recipient_id": user.ID},
+		},
+	}
+}
+
+// VisibleNotifications narrows AccessibleNotifications to the ones a user sees, leaving out
+// the types they muted
+func VisibleNotifications(groupID primitive.ObjectID, user *User) bson.M {
+	filter := AccessibleNotifications(groupID, user)
+	if len(user.Notifications.Muted) > 0 {
+		filter["type"] = bson.M{"$nin": user.Notifications.Muted}
+	}
+	return filter
+}
+
+// UnreadNotifications narrows VisibleNotifications to those the watermark and authorship do
+// not already mark as read. Receipts still have to be checked.
+func UnreadNotifications(groupID primitive.ObjectID, user *User) bson.M {
+	filter := VisibleNotifications(groupID, user)
+	filter["actor_id"] = bson.M{"$ne": user.ID}
+	if user.Notifications.ReadAllAt != nil {
+		filter["created_at"] = bson.M{"$gt": *user.Notifications.ReadAllAt}
+	}
+	return filter
+}
+
+// MigrateLegacyNotifications moves the pantry_notifications and group_notifications
+// collections into notifications, turning each read_by entry into a receipt. Legacy
+// collections are dropped once copied, so the migration only runs once. The read_by of
+// shopping cart activity becomes receipts on the activity's notification the same way.
+func MigrateLegacyNotifications(db *mongo.Database) error {
+	ctx := context.Background()
+
+	type legacyNotification struct {
+		ID               primitive.ObjectID   `bson:"_id"`
+		GroupID          primitive.ObjectID   `bson:"group_id"`
+		Type             NotificationType     `bson:"type"`
+		RecipientID      *primitive.ObjectID  `bson:"recipient_id"`
+		ActorID          primitive.ObjectID   `bson:"actor_id"`
+		ActorName        string               `bson:"actor_name"`
+		ItemID           primitive.ObjectID   `bson:"item_id"`
+		ItemName         string               `bson:"item_name"`
+		ChoreID          *primitive.ObjectID  `bson:"chore_id"`
+		RecurringChoreID *primitive.ObjectID  `bson:"recurring_chore_id"`
+		CommentID        *primitive.ObjectID  `bson:"comment_id"`
+		Message          string               `bson:"message"`
+		CreatedAt        time.Time            `bson:"created_at"`
+		ReadBy           []primitive.ObjectID `bson:"read_by"`
+	}
+
+	for _, collection := range []string{"pantry_notifications", "group_notifications"} {
+		cursor, err := db.Collection(collection).Find(ctx, bson.M{})
+		if err != nil {
+			return err
+		}
+		var legacy []legacyNotification
+		if err = cursor.All(ctx, &legacy); err != nil {
+			return err
+		}
+		if len(legacy) == 0 {
+			continue
+		}
+
+		notifications := make([]interface{}, 0, len(legacy))
+		receipts := make([]interface{}, 0)
+		for _, old := range legacy {
+			notification := &Notification{
+				ID:          old.ID,
+				GroupID:     old.GroupID,
+				Type:        old.Type,
+				Domain:      old.Type.Domain(),
+				RecipientID: old.RecipientID,
+				ActorName:   old.ActorName,
+				Message:     old.Message,
+				CreatedAt:   old.CreatedAt,
+				ExpiresAt:   old.CreatedAt.Add(NotificationRetention),
+			}
+			if !old.ActorID.IsZero() {
+				actorID := old.ActorID
+				notification.ActorID = &actorID
+			}
+			if notification.Domain == NotificationDomainPantry {
+				notification.Pantry = &PantryNotificationPayload{ItemID: old.ItemID, ItemName: old.ItemName}
+			} else {
+				notification.Chore = &ChoreNotificationPayload{
+					ChoreID:          old.ChoreID,
+					RecurringChoreID: old.RecurringChoreID,
+					CommentID:        old.CommentID,
+				}
+			}
+			notifications = append(notifications, notification)
+
+			for _, userID := range old.ReadBy {
+				receipts = append(receipts, NewNotificationReceipt(notification, userID, old.CreatedAt))
+			}
+		}
+
+		// Unordered inserts skip documents copied by an earlier, interrupted run
+		unordered := options.InsertMany().SetOrdered(false)
+		if _, err := db.Collection("notifications").InsertMany(ctx, notifications, unordered); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		if len(receipts) > 0 {
+			if _, err := db.Collection("notification_receipts").InsertMany(ctx, receipts, unordered); err != nil && !mongo.IsDuplicateKeyError(err) {
+				return err
+			}
+		}
+		if err := db.Collection(collection).Drop(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Cart activity no longer tracks read state; its notification does, so each read_by
+	// entry becomes a receipt on the notification of that activity before it is dropped
+	legacyRead := bson.M{"read_by": bson.M{"$exists": true}}
+	cursor, err := db.Collection("shopping_cart_activity").Find(ctx, legacyRead)
+	if err != nil {
+		return err
+	}
+	var activities []struct {
+		ID        primitive.ObjectID   `bson:"_id"`
+		CreatedAt time.Time            `bson:"created_at"`
+		ReadBy    []primitive.ObjectID `bson:"read_by"`
+	}
+	if err = cursor.All(ctx, &activities); err != nil {
+		return err
+	}
+
+	readBy := make(map[primitive.ObjectID][]primitive.ObjectID, len(activities))
+	readAt := make(map[primitive.ObjectID]time.Time, len(activities))
+	activityIDs := make([]primitive.ObjectID, 0, len(activities))
+	for _, activity := range activities {
+		if len(activity.ReadBy) == 0 {
+			continue
+		}
+		readBy[activity.ID] = activity.ReadBy
+		readAt[activity.ID] = activity.CreatedAt
+		activityIDs = append(activityIDs, activity.ID)
+	}
+
+	if len(activityIDs) > 0 {
+		cursor, err = db.Collection("notifications").Find(ctx, bson.M{"cart.activity_id": bson.M{"$in": activityIDs}})
+		if err != nil {
+			return err
+		}
+		var notifications []Notification
+		if err = cursor.All(ctx, &notifications); err != nil {
+			return err
+		}
+
+		receipts := make([]interface{}, 0)
+		for i := range notifications {
+			notification := &notifications[i]
+			if notification.Cart == nil {
+				continue
+			}
+			activityID := notification.Cart.ActivityID
+			for _, userID := range readBy[activityID] {
+				receipts = append(receipts, NewNotificationReceipt(notification, userID, readAt[activityID]))
+			}
+		}
+		if len(receipts) > 0 {
+			unordered := options.InsertMany().SetOrdered(false)
+			if _, err := db.Collection("notification_receipts").InsertMany(ctx, receipts, unordered); err != nil && !mongo.IsDuplicateKeyError(err) {
+				return err
+			}
+		}
+	}
+
+	_, err = db.Collection("shopping_cart_activity").UpdateMany(ctx,
+		legacyRead,
+		bson.M{"$unset": bson.M{"read_by": "", "is_read": ""}},
+	)
+	return err
+}
@@ -26,6 +26,10 @@ type PantryCategory struct {
 	CreatedBy *primitive.ObjectID `bson:"created_by,omitempty" json:"created_by,omitempty"` // null for predefined, user_id for custom
 	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
 	IsActive  bool                `bson:"is_active" json:"is_active"`
+
+	// Default stock levels for the category's items; nil uses DefaultLowStockThreshold and DefaultParLevel
+	LowStockThreshold *float64 `bson:"low_stock_threshold,omitempty" json:"low_stock_threshold,omitempty"`
+	ParLevel          *float64 `bson:"par_level,omitempty" json:"par_level,omitempty"`
 }
 
 // CreatePredefinedCategory creates a new predefined category
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// ForecastWindowDays is how many days of use history consumption rates are learned from
+	ForecastWindowDays = 60
+
+	// ForecastSmoothing is the weight the exponentially weighted average gives each new day;
+	// higher values follow recent changes in use faster
+	ForecastSmoothing = 0.3
+
+	// ForecastMinDays is how many days of history an item needs before its rate is trusted
+	ForecastMinDays = 3
+
+	// ForecastWarningDays is how close to running out an item is when members are warned
+	ForecastWarningDays = 3
+
+	// ForecastCoverDays is how many days of use suggested shopping quantities cover
+	ForecastCoverDays = 7
+)
+
+// ConsumptionRate is how fast a group uses a pantry item, learned from its use history. It is
+// stored per item and refreshed by the pantry jobs.
+type ConsumptionRate struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	ItemID     primitive.ObjectID `bson:"item_id" json:"item_id"`
+	GroupID    primitive.ObjectID `bson:"group_id" json:"group_id"`
+	DailyRate  float64            `bson:"daily_rate" json:"daily_rate"` // In the item's unit per day
+	Days       int                `bson:"days" json:"days"`             // Days of history the rate was learned from
+	Uses       int                `bson:"uses" json:"uses"`
+	LastUsedAt time.Time          `bson:"last_used_at" json:"last_used_at"`
+	ComputedAt time.Time          `bson:"computed_at" json:"computed_at"`
+}
+
+// ComputeConsumptionRate learns a daily rate from an item's use history as an exponentially
+// weighted average of the quantity used per day, oldest day first, so recent use counts more.
+// Days are counted back in 24 hour windows from now so the latest window is never partial, and
+// days without use count as zero. The history is expected to hold only use records of the
+// item, within ForecastWindowDays; older records are ignored.
+func ComputeConsumptionRate(history []PantryHistory, now time.Time) ConsumptionRate {
+	rate := ConsumptionRate{ComputedAt: now}
+	if len(history) == 0 {
+		return rate
+	}
+
+	daily := make([]float64, ForecastWindowDays)
+	oldest := -1
+	for _, record := range history {
+		if record.Action != ActionTypeUse || record.Quantity <= 0 || record.CreatedAt.After(now) {
+			continue
+		}
+		day := int(now.Sub(record.CreatedAt) / (24 * time.Hour))
+		if day >= ForecastWindowDays {
+			continue
+		}
+		daily[day] += record.Quantity
+		oldest = max(oldest, day)
+		rate.Uses++
+		if record.CreatedAt.After(rate.LastUsedAt) {
+			rate.LastUsedAt = record.CreatedAt
+		}
+	}
+	if oldest < 0 {
+		return rate
+	}
+
+	// Start from the plain average so a short history is not dominated by its first day
+	rate.Days = oldest + 1
+	total := 0.0
+	for day := 0; day <= oldest; day++ {
+		total += daily[day]
+	}
+	average := total / float64(rate.Days)
+	for day := oldest; day >= 0; day-- {
+		average = ForecastSmoothing*daily[day] + (1-ForecastSmoothing)*average
+	}
+	rate.DailyRate = roundQuantity(average)
+	return rate
+}
+
+// IsReliable checks if the rate was learned from enough history to forecast with
+func (c *ConsumptionRate) IsReliable() bool {
+	return c.DailyRate > 0 && c.Days >= ForecastMinDays && c.Uses >= 2
+}
+
+// PantryForecast is when a pantry item is expected to run out at its consumption rate
+type PantryForecast struct {
+	ItemID            primitive.ObjectID `json:"item_id"`
+	ItemName          string             `json:"item_name"`
+	Quantity          float64            `json:"quantity"`
+	Unit              string             `json:"unit"`
+	DailyRate         float64            `json:"daily_rate"`
+	DaysLeft          *float64           `json:"days_left,omitempty"`
+	RunOutDate        *time.Time         `json:"run_out_date,omitempty"`
+	SuggestedQuantity float64            `json:"suggested_quantity"`
+	Reliable          bool               `json:"reliable"`
+	ComputedAt        time.Time          `json:"computed_at"`
+}
+
+// Forecast predicts when the item runs out at the rate, and how much to buy. Without a
+// reliable rate there is no run-out date and the suggestion tops the item up to its par level.
+func (c *ConsumptionRate) Forecast(item *PantryItem, levels StockLevels, now time.Time) PantryForecast {
+	forecast := PantryForecast{
+		ItemID:            item.ID,
+		ItemName:          item.Name,
+		Quantity:          item.Quantity,
+		Unit:              item.Unit,
+		DailyRate:         c.DailyRate,
+		SuggestedQuantity: c.SuggestedQuantity(item.Quantity, levels),
+		Reliable:          c.IsReliable(),
+		ComputedAt:        c.ComputedAt,
+	}
+	if !forecast.Reliable {
+		return forecast
+	}
+
+	daysLeft := math.Round(item.Quantity/c.DailyRate*10) / 10
+	runOut := now.Add(time.Duration(item.Quantity / c.DailyRate * float64(24*time.Hour)))
+	forecast.DaysLeft = &daysLeft
+	forecast.RunOutDate = &runOut
+	return forecast
+}
+
+// RunsOutWithin checks if the item is expected to run out within the given number of days
+func (c *ConsumptionRate) RunsOutWithin(quantity float64, days int) bool {
+	return c.IsReliable() && quantity > 0 && quantity/c.DailyRate <= float64(days)
+}
+
+// SuggestedQuantity is how much to buy so the stock lasts ForecastCoverDays at the rate and
+// still holds the low-stock threshold after, and never less than what reaches the par level
+func (c *ConsumptionRate) SuggestedQuantity(quantity float64, levels StockLevels) float64 {
+	suggested := levels.SuggestedQuantity(quantity)
+	if !c.IsReliable() {
+		return suggested
+	}
+	needed := c.DailyRate*ForecastCoverDays + levels.LowStockThreshold - quantity
+	return roundQuantity(math.Max(suggested, needed))
+}
+
+// RunOutMessage describes how soon an item runs out, for notifications and shopping lists
+func RunOutMessage(daysLeft float64) string {
+	days := int(math.Round(daysLeft))
+	switch {
+	case days < 1:
+		return "Will run out within a day"
+	case days == 1:
+		return "Will run out in about 1 day"
+	}
+	return "Will run out in about " + strconv.Itoa(days) + " days"
+}
@@ -22,20 +22,37 @@ const (
 
 	// ActionTypeRemove indicates an item was removed from the pantry
 	ActionTypeRemove ActionType = "remove"
+
+	// ActionTypeMove indicates an item was moved to another storage location
+	ActionTypeMove ActionType = "move"
+
+	// ActionTypeDiscard indicates stock was thrown away rather than used
+	ActionTypeDiscard ActionType = "discard"
 )
 
 // PantryHistory represents a record of changes to a pantry item
 type PantryHistory struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	GroupID   primitive.ObjectID `bson:"group_id" json:"group_id" validate:"required"`
-	ItemID    primitive.ObjectID `bson:"item_id" json:"item_id" validate:"required"`
-	ItemName  string             `bson:"item_name" json:"item_name" validate:"required"`
-	UserID    primitive.ObjectID `bson:"user_id" json:"user_id" validate:"required"`
-	UserName  string             `bson:"user_name" json:"user_name"`
-	Action    ActionType         `bson:"action" json:"action" validate:"required"`
-	Quantity  float64            `bson:"quantity" json:"quantity"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	Details   string             `bson:"details,omitempty" json:"details,omitempty"`
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	GroupID    primitive.ObjectID  `bson:"group_id" json:"group_id" validate:"required"`
+	ItemID     primitive.ObjectID  `bson:"item_id" json:"item_id" validate:"required"`
+	ItemName   string              `bson:"item_name" json:"item_name" validate:"required"`
+	LotID      *primitive.ObjectID `bson:"lot_id,omitempty" json:"lot_id,omitempty"`           // The lot added to or used from
+	LocationID *primitive.ObjectID `bson:"location_id,omitempty" json:"location_id,omitempty"` // The storage location moved to
+	UserID     primitive.ObjectID  `bson:"user_id" json:"user_id" validate:"required"`
+	UserName   string              `bson:"user_name" json:"user_name"`
+	Action     ActionType          `bson:"action" json:"action" validate:"required"`
+	Quantity   float64             `bson:"quantity" json:"quantity"`
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+	Details    string              `bson:"details,omitempty" json:"details,omitempty"`
+
+	// Why stock was discarded and what it was worth, with the item's unit and category at the
+	// time so waste can be reported after the item is gone. The records a discard writes for
+	// each lot it took from share its DiscardID.
+	DiscardID  *primitive.ObjectID `bson:"discard_id,omitempty" json:"discard_id,omitempty"`
+	Reason     DiscardReason       `bson:"reason,omitempty" json:"reason,omitempty"`
+	Value      *float64            `bson:"value,omitempty" json:"value,omitempty"`
+	Unit       string              `bson:"unit,omitempty" json:"unit,omitempty"`
+	CategoryID *primitive.ObjectID `bson:"category_id,omitempty" json:"category_id,omitempty"`
 }
 
 // CreatePantryHistory creates a new pantry history record
@@ -1,24 +1,55 @@
 package models
 
 import (
+	"context"
+	"cribb-backend/units"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // PantryItem represents an item in a group's shared pantry
 type PantryItem struct {
-	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	GroupID        primitive.ObjectID `bson:"group_id" json:"group_id" validate:"required"`
-	Name           string             `bson:"name" json:"name" validate:"required"`
-	Quantity       float64            `bson:"quantity" json:"quantity" validate:"required,min=0"`
-	Unit           string             `bson:"unit" json:"unit" validate:"required"`
-	CategoryID     primitive.ObjectID `bson:"category_id" json:"category_id" validate:"required"`
-	Category       string             `bson:"category,omitempty" json:"category,omitempty"`
-	ExpirationDate time.Time          `bson:"expiration_date,omitempty" json:"expiration_date,omitempty"`
-	AddedBy        primitive.ObjectID `bson:"added_by" json:"added_by" validate:"required"`
-	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GroupID             primitive.ObjectID `bson:"group_id" json:"group_id" validate:"required"`
+	Name                string             `bson:"name" json:"name" validate:"required"`
+	Quantity            float64            `bson:"quantity" json:"quantity" validate:"required,min=0"`
+	Unit                string             `bson:"unit" json:"unit" validate:"required"`
+	CategoryID          primitive.ObjectID `bson:"category_id" json:"category_id" validate:"required"`
+	Category            string             `bson:"category,omitempty" json:"category,omitempty"`
+	ExpirationDate      time.Time          `bson:"expiration_date,omitempty" json:"expiration_date,omitempty"`
+	ExpirationEstimated bool               `bson:"expiration_estimated" json:"expiration_estimated"`
+	AddedBy             primitive.ObjectID `bson:"added_by" json:"added_by" validate:"required"`
+	CreatedAt           time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt           time.Time          `bson:"updated_at" json:"updated_at"`
+
+	// Stock levels override the category defaults when set
+	LowStockThreshold *float64 `bson:"low_stock_threshold,omitempty" json:"low_stock_threshold,omitempty"`
+	ParLevel          *float64 `bson:"par_level,omitempty" json:"par_level,omitempty"`
+
+	// Density in grams per millilitre, overriding the typical density for the item's name
+	Density *float64 `bson:"density,omitempty" json:"density,omitempty"`
+
+	// Estimated value of one unit of the item, remembered from discards to value later ones
+	UnitValue *float64 `bson:"unit_value,omitempty" json:"unit_value,omitempty"`
+
+	// Who the item belongs to. Personal and split items can only be used by their owners and
+	// the members they allow, and only deleted by their owners.
+	Ownership      OwnershipMode        `bson:"ownership" json:"ownership"`
+	OwnerIDs       []primitive.ObjectID `bson:"owner_ids" json:"owner_ids,omitempty"`
+	AllowedUserIDs []primitive.ObjectID `bson:"allowed_user_ids" json:"allowed_user_ids,omitempty"`
+
+	// The catalog product the item was added by barcode as
+	ProductID *primitive.ObjectID `bson:"product_id,omitempty" json:"product_id,omitempty"`
+	GTIN      string              `bson:"gtin,omitempty" json:"gtin,omitempty"`
+
+	// Lots hold the item's stock by batch, first-expiring first. Quantity is their total,
+	// ExpirationDate the earliest of their expiration dates and LocationID the storage
+	// location they are all in, if they are.
+	Lots       []PantryLot         `bson:"lots" json:"lots"`
+	LocationID *primitive.ObjectID `bson:"location_id" json:"location_id,omitempty"`
 }
 
 // CreatePantryItem creates a new pantry item with category ID
@@ -31,17 +62,18 @@ func CreatePantryItem(
 	expirationDate time.Time,
 	addedBy primitive.ObjectID,
 ) *PantryItem {
-	return &PantryItem{
-		GroupID:        groupID,
-		Name:           name,
-		Quantity:       quantity,
-		Unit:           unit,
-		CategoryID:     categoryID,
-		ExpirationDate: expirationDate,
-		AddedBy:        addedBy,
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+	item := &PantryItem{
+		GroupID:    groupID,
+		Name:       name,
+		Unit:       unit,
+		CategoryID: categoryID,
+		AddedBy:    addedBy,
+		Ownership:  OwnershipShared,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
 	}
+	item.AddLot(NewPantryLot(quantity, expirationDate, addedBy))
+	return item
 }
 
 // CreatePantryItemLegacy creates a new pantry item with legacy category string (for backward compatibility)
@@ -54,17 +86,18 @@ func CreatePantryItemLegacy(
 	expirationDate time.Time,
 	addedBy primitive.ObjectID,
 ) *PantryItem {
-	return &PantryItem{
-		GroupID:        groupID,
-		Name:           name,
-		Quantity:       quantity,
-		Unit:           unit,
-		Category:       category,
-		ExpirationDate: expirationDate,
-		AddedBy:        addedBy,
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+	item := &PantryItem{
+		GroupID:   groupID,
+		Name:      name,
+		Unit:      unit,
+		Category:  category,
+		AddedBy:   addedBy,
+		Ownership: OwnershipShared,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
 	}
+	item.AddLot(NewPantryLot(quantity, expirationDate, addedBy))
+	return item
 }
 
 // IsExpiringSoon checks if the item is expiring within the given number of days
@@ -91,3 +124,48 @@ func (p *PantryItem) UpdateQuantity(newQuantity float64) {
 	p.Quantity = newQuantity
 	p.UpdatedAt = time.Now()
 }
+
+// GramsPerML returns the density used to convert the item between mass and volume: its own,
+// else the typical density of an ingredient with its name, else 0
+func (p *PantryItem) GramsPerML() float64 {
+	if p.Density != nil && *p.Density > 0 {
+		return *p.Density
+	}
+	density, _ := units.DensityFor(p.Name)
+	return density
+}
+
+// ToItemUnit converts a quantity given in unit into the item's own unit. An empty unit
+// means the quantity is already in the item's unit, as does an item without a unit.
+func (p *PantryItem) ToItemUnit(quantity float64, unit string) (float64, error) {
+	if unit == "" || p.Unit == "" {
+		return quantity, nil
+	}
+	return units.ConvertString(quantity, unit, p.Unit, p.GramsPerML())
+}
+
+// MigratePantryUnits rewrites the free-form units stored on pantry items to their canonical
+// symbols, so "Kilos" and "kg" are recognized as the same unit
+func MigratePantryUnits(db *mongo.Database) error {
+	ctx := context.Background()
+	collection := db.Collection("pantry_items")
+
+	stored, err := collection.Distinct(ctx, "unit", bson.M{})
+	if err != nil {
+		return err
+	}
+	for _, value := range stored {
+		raw, ok := value.(string)
+		if !ok {
+			continue
+		}
+		symbol, err := units.Normalize(raw)
+		if err != nil || symbol == raw {
+			continue
+		}
+		if _, err := collection.UpdateMany(ctx, bson.M{"unit": raw}, bson.M{"$set": bson.M{"unit": symbol}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
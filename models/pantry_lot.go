@@ -0,0 +1,259 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrNotEnoughQuantity is returned when more of an item is used than its lots hold
+var ErrNotEnoughQuantity = errors.New("not enough quantity available")
+
+// ErrLotNotFound is returned when a lot is not part of the item
+var ErrLotNotFound = errors.New("lot not found")
+
+// ErrAmbiguousLot is returned when an item-wide expiration date is set on an item holding
+// several lots with different dates
+var ErrAmbiguousLot = errors.New("item has several lots; set the expiration date on a lot")
+
+// PantryLot is a batch of a pantry item added at one time, with its own expiration date.
+// Quantities are in the item's unit.
+type PantryLot struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	Quantity       float64            `bson:"quantity" json:"quantity"`
+	ExpirationDate time.Time          `bson:"expiration_date,omitempty" json:"expiration_date,omitempty"`
+	AddedBy        primitive.ObjectID `bson:"added_by" json:"added_by"`
+	AddedAt        time.Time          `bson:"added_at" json:"added_at"`
+
+	// ExpirationEstimated marks an expiration date estimated from shelf-life rules rather
+	// than read off the package
+	ExpirationEstimated bool `bson:"expiration_estimated,omitempty" json:"expiration_estimated"`
+
+	// Where the lot is stored, and since when it is frozen if it is in a freezer
+	LocationID *primitive.ObjectID `bson:"location_id,omitempty" json:"location_id,omitempty"`
+	FrozenAt   *time.Time          `bson:"frozen_at,omitempty" json:"frozen_at,omitempty"`
+}
+
+// LotUsage is how much was taken from one lot
+type LotUsage struct {
+	LotID          primitive.ObjectID `json:"lot_id"`
+	Quantity       float64            `json:"quantity"`
+	ExpirationDate time.Time          `json:"expiration_date,omitempty"`
+}
+
+// NewPantryLot creates a lot added now
+func NewPantryLot(quantity float64, expirationDate time.Time, addedBy primitive.ObjectID) PantryLot {
+	return PantryLot{
+		ID:             primitive.NewObjectID(),
+		Quantity:       quantity,
+		ExpirationDate: expirationDate,
+		AddedBy:        addedBy,
+		AddedAt:        time.Now(),
+	}
+}
+
+// IsExpiringSoon checks if the lot is expiring within the given number of days
+func (l *PantryLot) IsExpiringSoon(days int) bool {
+	if l.ExpirationDate.IsZero() {
+		return false
+	}
+
+	expirationThreshold := time.Now().AddDate(0, 0, days)
+	return l.ExpirationDate.Before(expirationThreshold) && l.ExpirationDate.After(time.Now())
+}
+
+// IsExpired checks if the lot is already expired
+func (l *PantryLot) IsExpired() bool {
+	if l.ExpirationDate.IsZero() {
+		return false
+	}
+
+	return l.ExpirationDate.Before(time.Now())
+}
+
+// expiresBefore orders lots first-expiring first. Lots without an expiration date come last,
+// and lots expiring together are used oldest first.
+func (l *PantryLot) expiresBefore(other *PantryLot) bool {
+	if l.ExpirationDate.IsZero() != other.ExpirationDate.IsZero() {
+		return !l.ExpirationDate.IsZero()
+	}
+	if !l.ExpirationDate.Equal(other.ExpirationDate) {
+		return l.ExpirationDate.Before(other.ExpirationDate)
+	}
+	return l.AddedAt.Before(other.AddedAt)
+}
+
+// SyncLots sorts the item's lots first-expiring first, drops empty ones, and sets the item's
+// quantity to their total and its expiration date to the earliest one, estimated if that
+// lot's is. The item's location is the one its lots share, if they do. An item stored before
+// lots existed is given a single lot holding its quantity, keyed by the item's ID.
+func (p *PantryItem) SyncLots() {
+	if p.Lots == nil && p.Quantity > 0 {
+		p.Lots = []PantryLot{{
+			ID:             p.ID,
+			Quantity:       p.Quantity,
+			ExpirationDate: p.ExpirationDate,
+			AddedBy:        p.AddedBy,
+			AddedAt:        p.CreatedAt,
+			LocationID:     p.LocationID,
+		}}
+	}
+
+	lots := make([]PantryLot, 0, len(p.Lots))
+	for _, lot := range p.Lots {
+		if lot.Quantity > 0 {
+			lots = append(lots, lot)
+		}
+	}
+	sort.SliceStable(lots, func(i, j int) bool {
+		return lots[i].expiresBefore(&lots[j])
+	})
+
+	p.Lots = lots
+	p.Quantity = 0
+	p.ExpirationDate = time.Time{}
+	p.ExpirationEstimated = false
+	p.LocationID = nil
+	for i, lot := range lots {
+		p.Quantity += lot.Quantity
+		if !lot.ExpirationDate.IsZero() && (p.ExpirationDate.IsZero() || lot.ExpirationDate.Before(p.ExpirationDate)) {
+			p.ExpirationDate = lot.ExpirationDate
+			p.ExpirationEstimated = lot.ExpirationEstimated
+		}
+		if i == 0 {
+			p.LocationID = lot.LocationID
+		} else if p.LocationID != nil && (lot.LocationID == nil || *lot.LocationID != *p.LocationID) {
+			p.LocationID = nil
+		}
+	}
+	p.Quantity = roundQuantity(p.Quantity)
+}
+
+// AddLot adds a lot to the item
+func (p *PantryItem) AddLot(lot PantryLot) {
+	p.SyncLots()
+	p.Lots = append(p.Lots, lot)
+	p.SyncLots()
+	p.UpdatedAt = time.Now()
+}
+
+// ConsumeLots takes quantity from the item's lots, first-expiring first, and returns how much
+// came out of each lot. Nothing is taken if the lots hold less than quantity.
+func (p *PantryItem) ConsumeLots(quantity float64) ([]LotUsage, error) {
+	p.SyncLots()
+	if roundQuantity(quantity) > p.Quantity {
+		return nil, ErrNotEnoughQuantity
+	}
+
+	var usages []LotUsage
+	remaining := quantity
+	for i := range p.Lots {
+		if remaining <= 0 {
+			break
+		}
+		lot := &p.Lots[i]
+		taken := math.Min(lot.Quantity, remaining)
+		lot.Quantity = roundQuantity(lot.Quantity - taken)
+		remaining = roundQuantity(remaining - taken)
+		usages = append(usages, LotUsage{LotID: lot.ID, Quantity: taken, ExpirationDate: lot.ExpirationDate})
+	}
+
+	p.SyncLots()
+	p.UpdatedAt = time.Now()
+	return usages, nil
+}
+
+// SetQuantity brings the item to quantity, adding the difference as newLot or using it up
+// from the first-expiring lots. It returns the lot added, if any, and the lots used.
+func (p *PantryItem) SetQuantity(quantity float64, newLot PantryLot) (*PantryLot, []LotUsage, error) {
+	p.SyncLots()
+	delta := roundQuantity(quantity - p.Quantity)
+	switch {
+	case delta > 0:
+		newLot.Quantity = delta
+		p.AddLot(newLot)
+		return &newLot, nil, nil
+	case delta < 0:
+		usages, err := p.ConsumeLots(-delta)
+		return nil, usages, err
+	}
+	return nil, nil, nil
+}
+
+// SetExpirationDate sets the expiration date of an item held in a single lot, confirming it if
+// it was estimated. An item with several lots only accepts the date it already reports, the
+// earliest of its lots.
+func (p *PantryItem) SetExpirationDate(expirationDate time.Time) error {
+	p.SyncLots()
+	switch {
+	case len(p.Lots) == 0 || (len(p.Lots) > 1 && expirationDate.Equal(p.ExpirationDate)):
+		return nil
+	case len(p.Lots) > 1:
+		return ErrAmbiguousLot
+	}
+	p.Lots[0].ExpirationDate = expirationDate
+	p.Lots[0].ExpirationEstimated = false
+	p.SyncLots()
+	p.UpdatedAt = time.Now()
+	return nil
+}
+
+// Lot returns the item's lot with the given ID
+func (p *PantryItem) Lot(lotID primitive.ObjectID) (*PantryLot, error) {
+	p.SyncLots()
+	for i := range p.Lots {
+		if p.Lots[i].ID == lotID {
+			return &p.Lots[i], nil
+		}
+	}
+	return nil, ErrLotNotFound
+}
+
+// ScaleLots converts every lot with convert, used when the item changes unit
+func (p *PantryItem) ScaleLots(convert func(float64) (float64, error)) error {
+	p.SyncLots()
+	for i := range p.Lots {
+		quantity, err := convert(p.Lots[i].Quantity)
+		if err != nil {
+			return err
+		}
+		p.Lots[i].Quantity = quantity
+	}
+	p.SyncLots()
+	return nil
+}
+
+func roundQuantity(quantity float64) float64 {
+	return math.Round(quantity*1e6) / 1e6
+}
+
+// MigratePantryLots gives every pantry item stored before lots existed a single lot holding
+// its quantity and expiration date
+func MigratePantryLots(db *mongo.Database) error {
+	ctx := context.Background()
+	collection := db.Collection("pantry_items")
+
+	cursor, err := collection.Find(ctx, bson.M{"lots": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var item PantryItem
+		if err := cursor.Decode(&item); err != nil {
+			return err
+		}
+		item.SyncLots()
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": item.ID}, bson.M{"$set": bson.M{"lots": item.Lots}}); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
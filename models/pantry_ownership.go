@@ -0,0 +1,170 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// OwnershipMode defines who a pantry item belongs to
+type OwnershipMode string
+
+const (
+	// OwnershipShared means the item belongs to the whole group
+	OwnershipShared OwnershipMode = "shared"
+
+	// OwnershipPersonal means the item belongs to a single member
+	OwnershipPersonal OwnershipMode = "personal"
+
+	// OwnershipSplit means the item is split among some of the members
+	OwnershipSplit OwnershipMode = "split"
+)
+
+// ErrUseNotPermitted is returned when a member uses an item that is not theirs without the
+// owner's permission
+var ErrUseNotPermitted = errors.New("this item belongs to someone else; ask an owner for permission to use it")
+
+// ErrNotOwner is returned when a member who does not own an item tries to delete it or change
+// who it belongs to
+var ErrNotOwner = errors.New("only the item's owners can do this")
+
+// IsValidOwnershipMode checks if the ownership mode is one of the known modes
+func IsValidOwnershipMode(mode OwnershipMode) bool {
+	switch mode {
+	case OwnershipShared, OwnershipPersonal, OwnershipSplit:
+		return true
+	}
+	return false
+}
+
+// ValidateOwnership checks that the owners fit the ownership mode: none for a shared item,
+// one for a personal item and at least two for a split item
+func ValidateOwnership(mode OwnershipMode, ownerIDs []primitive.ObjectID) error {
+	switch mode {
+	case OwnershipShared:
+		if len(ownerIDs) > 0 {
+			return errors.New("a shared item has no owners")
+		}
+	case OwnershipPersonal:
+		if len(ownerIDs) != 1 {
+			return errors.New("a personal item has exactly one owner")
+		}
+	case OwnershipSplit:
+		if len(ownerIDs) < 2 {
+			return errors.New("a split item has at least two owners")
+		}
+	default:
+		return errors.New("invalid ownership. Must be one of: shared, personal, split")
+	}
+	return nil
+}
+
+// SortOwners orders owner IDs and drops duplicates, so items with the same owners store the
+// same list
+func SortOwners(ownerIDs []primitive.ObjectID) []primitive.ObjectID {
+	sorted := make([]primitive.ObjectID, 0, len(ownerIDs))
+	seen := make(map[primitive.ObjectID]bool)
+	for _, id := range ownerIDs {
+		if !seen[id] {
+			seen[id] = true
+			sorted = append(sorted, id)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Hex() < sorted[j].Hex()
+	})
+	return sorted
+}
+
+// IsShared checks if the item belongs to the whole group. Items stored before ownership
+// existed are shared.
+func (p *PantryItem) IsShared() bool {
+	return p.Ownership == "" || p.Ownership == OwnershipShared
+}
+
+// IsOwner checks if the user owns the item
+func (p *PantryItem) IsOwner(userID primitive.ObjectID) bool {
+	return containsObjectID(p.OwnerIDs, userID)
+}
+
+// CanUse checks if the user may use the item: anyone in the group for a shared item, else
+// its owners and the members they gave permission
+func (p *PantryItem) CanUse(userID primitive.ObjectID) bool {
+	return p.IsShared() || p.IsOwner(userID) || containsObjectID(p.AllowedUserIDs, userID)
+}
+
+// CanDelete checks if the user may delete the item: anyone in the group for a shared item,
+// else only its owners
+func (p *PantryItem) CanDelete(userID primitive.ObjectID) bool {
+	return p.IsShared() || p.IsOwner(userID)
+}
+
+// CanManageOwnership checks if the user may change who the item belongs to and who may use
+// it: its owners, or for a shared item the member who added it
+func (p *PantryItem) CanManageOwnership(userID primitive.ObjectID) bool {
+	if p.IsShared() {
+		return p.AddedBy == userID
+	}
+	return p.IsOwner(userID)
+}
+
+// SetOwnership changes who the item belongs to. Permissions given to other members are kept
+// only while the item is not shared.
+func (p *PantryItem) SetOwnership(mode OwnershipMode, ownerIDs []primitive.ObjectID) error {
+	ownerIDs = SortOwners(ownerIDs)
+	if err := ValidateOwnership(mode, ownerIDs); err != nil {
+		return err
+	}
+	p.Ownership = mode
+	p.OwnerIDs = ownerIDs
+	if mode == OwnershipShared {
+		p.AllowedUserIDs = nil
+	}
+	return nil
+}
+
+// AllowUser gives or takes away a member's permission to use the item
+func (p *PantryItem) AllowUser(userID primitive.ObjectID, allowed bool) {
+	var allowedUserIDs []primitive.ObjectID
+	for _, id := range p.AllowedUserIDs {
+		if id != userID {
+			allowedUserIDs = append(allowedUserIDs, id)
+		}
+	}
+	if allowed {
+		allowedUserIDs = append(allowedUserIDs, userID)
+	}
+	p.AllowedUserIDs = allowedUserIDs
+}
+
+// OwnershipFilter matches the items with the given ownership mode and owners, so stock added
+// for someone only joins their own items
+func OwnershipFilter(mode OwnershipMode, ownerIDs []primitive.ObjectID) bson.M {
+	if mode == OwnershipShared {
+		return bson.M{"ownership": bson.M{"$in": []interface{}{OwnershipShared, nil}}}
+	}
+	return bson.M{"ownership": mode, "owner_ids": SortOwners(ownerIDs)}
+}
+
+func containsObjectID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// MigratePantryOwnership marks every pantry item stored before ownership existed as shared
+func MigratePantryOwnership(db *mongo.Database) error {
+	_, err := db.Collection("pantry_items").UpdateMany(
+		context.Background(),
+		bson.M{"ownership": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"ownership": OwnershipShared}},
+	)
+	return err
+}
@@ -0,0 +1,80 @@
+package models
+
+import "errors"
+
+// DefaultLowStockThreshold and DefaultParLevel apply when neither an item nor its category
+// sets them
+const (
+	DefaultLowStockThreshold = 1.0
+	DefaultParLevel          = 2.0
+)
+
+// StockLevels are the quantities an item is judged against: it is running low at or below
+// LowStockThreshold and is restocked up to ParLevel
+type StockLevels struct {
+	LowStockThreshold float64 `json:"low_stock_threshold"`
+	ParLevel          float64 `json:"par_level"`
+}
+
+// ValidateStockLevels checks a threshold and par level set together. Either may be nil to
+// fall back to the category or default value.
+func ValidateStockLevels(threshold, par *float64) error {
+	if threshold != nil && *threshold < 0 {
+		return errors.New("low_stock_threshold cannot be negative")
+	}
+	if par != nil && *par < 0 {
+		return errors.New("par_level cannot be negative")
+	}
+	if threshold != nil && par != nil && *par < *threshold {
+		return errors.New("par_level cannot be below low_stock_threshold")
+	}
+	return nil
+}
+
+// ResolveStockLevels works out an item's stock levels: its own values first, then its
+// category's defaults, then DefaultLowStockThreshold and DefaultParLevel. category may be nil.
+// When the two values come from different places and the par level ends up below the
+// threshold, it is raised to twice the threshold so a low item always has something to buy.
+func ResolveStockLevels(item *PantryItem, category *PantryCategory) StockLevels {
+	levels := StockLevels{
+		LowStockThreshold: DefaultLowStockThreshold,
+		ParLevel:          DefaultParLevel,
+	}
+	if category != nil {
+		if category.LowStockThreshold != nil {
+			levels.LowStockThreshold = *category.LowStockThreshold
+		}
+		if category.ParLevel != nil {
+			levels.ParLevel = *category.ParLevel
+		}
+	}
+	if item.LowStockThreshold != nil {
+		levels.LowStockThreshold = *item.LowStockThreshold
+	}
+	if item.ParLevel != nil {
+		levels.ParLevel = *item.ParLevel
+	}
+
+	if levels.ParLevel < levels.LowStockThreshold {
+		levels.ParLevel = 2 * levels.LowStockThreshold
+	}
+	return levels
+}
+
+// IsLowStock checks if a quantity is running low but not yet out of stock
+func (l StockLevels) IsLowStock(quantity float64) bool {
+	return quantity > 0 && quantity <= l.LowStockThreshold
+}
+
+// NeedsRestock checks if a quantity is low or out of stock
+func (l StockLevels) NeedsRestock(quantity float64) bool {
+	return quantity <= l.LowStockThreshold
+}
+
+// SuggestedQuantity is how much to buy to bring a quantity back up to the par level
+func (l StockLevels) SuggestedQuantity(quantity float64) float64 {
+	if quantity >= l.ParLevel {
+		return 0
+	}
+	return l.ParLevel - quantity
+}
@@ -0,0 +1,333 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DiscardReason defines why pantry stock was thrown away
+type DiscardReason string
+
+const (
+	DiscardReasonExpired DiscardReason = "expired"
+	DiscardReasonSpoiled DiscardReason = "spoiled"
+	DiscardReasonOther   DiscardReason = "other"
+)
+
+// ErrNothingToDiscard is returned when an item holds no stock to throw away
+var ErrNothingToDiscard = errors.New("nothing to discard")
+
+// IsValidDiscardReason checks if the reason is one of the known reasons
+func IsValidDiscardReason(reason DiscardReason) bool {
+	switch reason {
+	case DiscardReasonExpired, DiscardReasonSpoiled, DiscardReasonOther:
+		return true
+	}
+	return false
+}
+
+// DiscardLots takes stock out of the item to throw it away: quantity from the lot with lotID,
+// or from the first-expiring lots if lotID is nil. A quantity of zero takes the whole lot, or
+// with no lot every expired lot, or everything if none has expired. It returns how much came
+// out of each lot.
+func (p *PantryItem) DiscardLots(lotID *primitive.ObjectID, quantity float64, now time.Time) ([]LotUsage, error) {
+	p.SyncLots()
+	if p.Quantity <= 0 {
+		return nil, ErrNothingToDiscard
+	}
+	if lotID == nil {
+		if quantity > 0 {
+			return p.ConsumeLots(quantity)
+		}
+		return p.discardWhere(func(lot *PantryLot) bool {
+			return !lot.ExpirationDate.IsZero() && lot.ExpirationDate.Before(now)
+		}, true)
+	}
+
+	lot, err := p.Lot(*lotID)
+	if err != nil {
+		return nil, err
+	}
+	if quantity <= 0 {
+		quantity = lot.Quantity
+	}
+	if roundQuantity(quantity) > lot.Quantity {
+		return nil, ErrNotEnoughQuantity
+	}
+	lot.Quantity = roundQuantity(lot.Quantity - quantity)
+	usage := LotUsage{LotID: lot.ID, Quantity: roundQuantity(quantity), ExpirationDate: lot.ExpirationDate}
+
+	p.SyncLots()
+	p.UpdatedAt = time.Now()
+	return []LotUsage{usage}, nil
+}
+
+// discardWhere empties the lots matching discard, or all of them if none match and
+// allIfNone is set
+func (p *PantryItem) discardWhere(discard func(*PantryLot) bool, allIfNone bool) ([]LotUsage, error) {
+	var usages []LotUsage
+	for i := range p.Lots {
+		lot := &p.Lots[i]
+		if !discard(lot) {
+			continue
+		}
+		usages = append(usages, LotUsage{LotID: lot.ID, Quantity: lot.Quantity, ExpirationDate: lot.ExpirationDate})
+		lot.Quantity = 0
+	}
+	if len(usages) == 0 && allIfNone {
+		return p.discardWhere(func(*PantryLot) bool { return true }, false)
+	}
+
+	p.SyncLots()
+	p.UpdatedAt = time.Now()
+	return usages, nil
+}
+
+// DiscardedQuantity is how much a discard took out of the item over all its lots
+func DiscardedQuantity(usages []LotUsage) float64 {
+	total := 0.0
+	for _, usage := range usages {
+		total += usage.Quantity
+	}
+	return roundQuantity(total)
+}
+
+// EstimateValue is what quantity of the item is worth at its remembered value per unit, or
+// nil if none is known
+func (p *PantryItem) EstimateValue(quantity float64) *float64 {
+	if p.UnitValue == nil {
+		return nil
+	}
+	value := roundMoney(*p.UnitValue * quantity)
+	return &value
+}
+
+// RememberValue keeps the value per unit of a discard so later ones can be estimated
+func (p *PantryItem) RememberValue(quantity, value float64) {
+	if quantity <= 0 || value < 0 {
+		return
+	}
+	unitValue := roundQuantity(value / quantity)
+	p.UnitValue = &unitValue
+}
+
+// SplitValue shares a value out over the lots stock was taken from, by quantity, so each
+// history record carries its part
+func SplitValue(value *float64, usages []LotUsage) []*float64 {
+	parts := make([]*float64, len(usages))
+	if value == nil {
+		return parts
+	}
+	total := 0.0
+	for _, usage := range usages {
+		total += usage.Quantity
+	}
+	for i, usage := range usages {
+		part := 0.0
+		if total > 0 {
+			part = roundMoney(*value * usage.Quantity / total)
+		}
+		parts[i] = &part
+	}
+	return parts
+}
+
+func roundMoney(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// WasteTotals is how often stock was thrown away and its estimated value
+type WasteTotals struct {
+	Discards int     `json:"discards"`
+	Value    float64 `json:"value"`
+}
+
+// ItemWaste is what was thrown away of one pantry item
+type ItemWaste struct {
+	ItemID   primitive.ObjectID `json:"item_id"`
+	ItemName string             `json:"item_name"`
+	Quantity float64            `json:"quantity"`
+	Unit     string             `json:"unit"`
+	WasteTotals
+}
+
+// CategoryWaste is what was thrown away of the items in one category
+type CategoryWaste struct {
+	CategoryID *primitive.ObjectID `json:"category_id,omitempty"`
+	Category   string              `json:"category"`
+	WasteTotals
+}
+
+// MemberWaste is what one member threw away
+type MemberWaste struct {
+	UserID   primitive.ObjectID `json:"user_id"`
+	UserName string             `json:"user_name"`
+	WasteTotals
+}
+
+// ReasonWaste is what was thrown away for one reason
+type ReasonWaste struct {
+	Reason DiscardReason `json:"reason"`
+	WasteTotals
+}
+
+// WasteTrendPoint is what was thrown away in one period
+type WasteTrendPoint struct {
+	PeriodStart time.Time `json:"period_start"`
+	WasteTotals
+}
+
+// WasteReport is a group's food waste over a window
+type WasteReport struct {
+	GroupID     primitive.ObjectID `json:"group_id"`
+	Timezone    string             `json:"timezone"`
+	Bucket      AnalyticsBucket    `json:"bucket"`
+	From        time.Time          `json:"from"`
+	To          time.Time          `json:"to"`
+	Summary     WasteTotals        `json:"summary"`
+	Items       []ItemWaste        `json:"items"`
+	Categories  []CategoryWaste    `json:"categories"`
+	Members     []MemberWaste      `json:"members"`
+	Reasons     []ReasonWaste      `json:"reasons"`
+	Trend       []WasteTrendPoint  `json:"trend"`
+	GeneratedAt time.Time          `json:"generated_at"`
+}
+
+func (t *WasteTotals) add(record *PantryHistory) {
+	t.Discards++
+	if record.Value != nil {
+		t.Value = roundMoney(t.Value + *record.Value)
+	}
+}
+
+// BuildWasteReport totals the discard records in [from, to) by item, category, member, reason
+// and period. Records of one discard taken from several lots count as one discard.
+// categoryNames resolves the names of the items' categories. Each breakdown is ordered by
+// value, then by how often.
+func BuildWasteReport(records []PantryHistory, categoryNames map[primitive.ObjectID]string, bucket AnalyticsBucket, from, to time.Time, loc *time.Location) *WasteReport {
+	report := &WasteReport{
+		Timezone: loc.String(),
+		Bucket:   bucket,
+		From:     from,
+		To:       to,
+		Trend:    make([]WasteTrendPoint, 0),
+	}
+	for start := from; start.Before(to); start = NextBucket(bucket, start) {
+		report.Trend = append(report.Trend, WasteTrendPoint{PeriodStart: start})
+	}
+	trend := make(map[int64]*WasteTrendPoint, len(report.Trend))
+	for i := range report.Trend {
+		trend[report.Trend[i].PeriodStart.Unix()] = &report.Trend[i]
+	}
+
+	items := make(map[primitive.ObjectID]*ItemWaste)
+	categories := make(map[primitive.ObjectID]*CategoryWaste)
+	members := make(map[primitive.ObjectID]*MemberWaste)
+	reasons := make(map[DiscardReason]*ReasonWaste)
+
+	for _, record := range mergeDiscards(records) {
+		if record.Action != ActionTypeDiscard || record.CreatedAt.Before(from) || !record.CreatedAt.Before(to) {
+			continue
+		}
+		report.Summary.add(&record)
+
+		item, ok := items[record.ItemID]
+		if !ok {
+			item = &ItemWaste{ItemID: record.ItemID, ItemName: record.ItemName, Unit: record.Unit}
+			items[record.ItemID] = item
+		}
+		item.Quantity = roundQuantity(item.Quantity + record.Quantity)
+		item.add(&record)
+
+		var categoryID primitive.ObjectID
+		if record.CategoryID != nil {
+			categoryID = *record.CategoryID
+		}
+		category, ok := categories[categoryID]
+		if !ok {
+			category = &CategoryWaste{CategoryID: record.CategoryID, Category: "Uncategorized"}
+			if name, found := categoryNames[categoryID]; found {
+				category.Category = name
+			}
+			categories[categoryID] = category
+		}
+		category.add(&record)
+
+		member, ok := members[record.UserID]
+		if !ok {
+			member = &MemberWaste{UserID: record.UserID, UserName: record.UserName}
+			members[record.UserID] = member
+		}
+		member.add(&record)
+
+		reason, ok := reasons[record.Reason]
+		if !ok {
+			reason = &ReasonWaste{Reason: record.Reason}
+			reasons[record.Reason] = reason
+		}
+		reason.add(&record)
+
+		if point, ok := trend[BucketStart(bucket, record.CreatedAt, loc).Unix()]; ok {
+			point.add(&record)
+		}
+	}
+
+	report.Items = sortedWaste(items, func(i *ItemWaste) (WasteTotals, string) { return i.WasteTotals, i.ItemName })
+	report.Categories = sortedWaste(categories, func(c *CategoryWaste) (WasteTotals, string) { return c.WasteTotals, c.Category })
+	report.Members = sortedWaste(members, func(m *MemberWaste) (WasteTotals, string) { return m.WasteTotals, m.UserName })
+	report.Reasons = sortedWaste(reasons, func(r *ReasonWaste) (WasteTotals, string) { return r.WasteTotals, string(r.Reason) })
+	return report
+}
+
+// mergeDiscards folds the records a single discard wrote for each lot it took from into one
+// record, so frequency counts discards rather than lots. Records of one discard share its
+// DiscardID; records without one each count as a discard of their own.
+func mergeDiscards(records []PantryHistory) []PantryHistory {
+	merged := make([]PantryHistory, 0, len(records))
+	index := make(map[primitive.ObjectID]int)
+	for _, record := range records {
+		if record.DiscardID == nil || record.Action != ActionTypeDiscard {
+			merged = append(merged, record)
+			continue
+		}
+		i, seen := index[*record.DiscardID]
+		if !seen {
+			index[*record.DiscardID] = len(merged)
+			merged = append(merged, record)
+			continue
+		}
+		existing := &merged[i]
+		existing.Quantity = roundQuantity(existing.Quantity + record.Quantity)
+		if record.Value != nil {
+			value := *record.Value
+			if existing.Value != nil {
+				value = roundMoney(value + *existing.Value)
+			}
+			existing.Value = &value
+		}
+	}
+	return merged
+}
+
+func sortedWaste[K comparable, V any](byKey map[K]*V, totals func(*V) (WasteTotals, string)) []V {
+	sorted := make([]V, 0, len(byKey))
+	for _, value := range byKey {
+		sorted = append(sorted, *value)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, aName := totals(&sorted[i])
+		b, bName := totals(&sorted[j])
+		if a.Value != b.Value {
+			return a.Value > b.Value
+		}
+		if a.Discards != b.Discards {
+			return a.Discards > b.Discards
+		}
+		return aName < bName
+	})
+	return sorted
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Product is an entry in the product catalog, keyed by barcode. Pantry items added by
+// barcode take their name, unit and category from it, and its shelf life estimates their
+// expiration date unless the group has its own rule for the product.
+type Product struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GTIN            string             `bson:"gtin" json:"gtin"` // 14 digits with leading zeros
+	Name            string             `bson:"name" json:"name"`
+	Brand           string             `bson:"brand,omitempty" json:"brand,omitempty"`
+	PackageQuantity string             `bson:"package_quantity,omitempty" json:"package_quantity,omitempty"` // As printed, e.g. "6 x 330 ml"
+	DefaultUnit     string             `bson:"default_unit" json:"default_unit"`
+	DefaultCategory string             `bson:"default_category" json:"default_category"` // Name of a predefined category
+	ShelfLifeDays   *int               `bson:"shelf_life_days,omitempty" json:"shelf_life_days,omitempty"`
+	Source          string             `bson:"source" json:"source"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// UpsertProducts adds products to the catalog, replacing the details of those already in it
+// by GTIN. It returns how many products were added and how many were updated.
+func UpsertProducts(ctx context.Context, db *mongo.Database, products []Product) (int64, int64, error) {
+	if len(products) == 0 {
+		return 0, 0, nil
+	}
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(products))
+	for _, product := range products {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"gtin": product.GTIN}).
+			SetUpdate(bson.M{
+				"$set": bson.M{
+					"name":             product.Name,
+					"brand":            product.Brand,
+					"package_quantity": product.PackageQuantity,
+					"default_unit":     product.DefaultUnit,
+					"default_category": product.DefaultCategory,
+					"shelf_life_days":  product.ShelfLifeDays,
+					"source":           product.Source,
+					"updated_at":       now,
+				},
+				"$setOnInsert": bson.M{"created_at": now},
+			}).
+			SetUpsert(true))
+	}
+
+	result, err := db.Collection("products").BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, 0, err
+	}
+	return result.UpsertedCount, result.ModifiedCount, nil
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxPushFailures is how many pushes in a row may fail before a subscription is dropped
+const MaxPushFailures = 10
+
+// PushSubscriptionKeys are the keys a browser hands out with a push subscription, base64url
+// encoded as in PushSubscription.toJSON()
+type PushSubscriptionKeys struct {
+	P256dh string `bson:"p256dh" json:"p256dh" validate:"required"`
+	Auth   string `bson:"auth" json:"auth" validate:"required"`
+}
+
+// PushSubscription is one device's Web Push subscription. The endpoint identifies the
+// device, so registering it again under another account moves it to that account.
+type PushSubscription struct {
+	ID           primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID   `bson:"user_id" json:"user_id"`
+	Endpoint     string               `bson:"endpoint" json:"endpoint"`
+	Keys         PushSubscriptionKeys `bson:"keys" json:"-"`
+	UserAgent    string               `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	ExpiresAt    *time.Time           `bson:"expires_at,omitempty" json:"expires_at,omitempty"` // Set when the push service gave one
+	FailureCount int                  `bson:"failure_count" json:"failure_count"`
+	LastPushAt   *time.Time           `bson:"last_push_at,omitempty" json:"last_push_at,omitempty"`
+	CreatedAt    time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// Decode returns the subscription's P-256 public key and authentication secret, checking
+// their lengths
+func (k PushSubscriptionKeys) Decode() (publicKey []byte, authSecret []byte, err error) {
+	publicKey, err = decodeBase64URL(k.P256dh)
+	if err != nil || len(publicKey) != 65 || publicKey[0] != 0x04 {
+		return nil, nil, errors.New("p256dh must be an uncompressed P-256 public key")
+	}
+	authSecret, err = decodeBase64URL(k.Auth)
+	if err != nil || len(authSecret) != 16 {
+		return nil, nil, errors.New("auth must be a 16 byte secret")
+	}
+	return publicKey, authSecret, nil
+}
+
+// IsExpired checks if the push service's expiration time for the subscription has passed
+func (s *PushSubscription) IsExpired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
+}
+
+// ValidatePushEndpoint checks that a push endpoint is an absolute HTTPS URL that does not
+// point at the server's own or a private network. Hosts given by name are checked again when
+// the push is sent, as they may resolve to such an address.
+func ValidatePushEndpoint(endpoint string) error {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" {
+		return errors.New("endpoint must be an absolute URL")
+	}
+	if parsed.Scheme != "https" {
+		return errors.New("endpoint must use https")
+	}
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); strings.EqualFold(host, "localhost") || (ip != nil && IsPrivateAddress(ip)) {
+		return errors.New("endpoint must not be a private or loopback address")
+	}
+	return nil
+}
+
+// decodeBase64URL accepts base64url with or without padding, as browsers differ
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
@@ -0,0 +1,248 @@
+package models
+
+import (
+	"cribb-backend/units"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecipeIngredient is an ingredient of a recipe, in the quantity needed for the recipe's
+// servings
+type RecipeIngredient struct {
+	Name      string              `bson:"name" json:"name"`
+	Quantity  float64             `bson:"quantity" json:"quantity"`
+	Unit      string              `bson:"unit" json:"unit"`
+	ProductID *primitive.ObjectID `bson:"product_id,omitempty" json:"product_id,omitempty"` // The catalog product the ingredient is, if linked
+}
+
+// Recipe is a dish a group cooks, with the ingredients it takes from the pantry
+type Recipe struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GroupID      primitive.ObjectID `bson:"group_id" json:"group_id"`
+	Name         string             `bson:"name" json:"name"`
+	Servings     int                `bson:"servings" json:"servings"`
+	Ingredients  []RecipeIngredient `bson:"ingredients" json:"ingredients"`
+	Instructions string             `bson:"instructions,omitempty" json:"instructions,omitempty"`
+	CreatedBy    primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CreateRecipe creates a new recipe for a group
+func CreateRecipe(groupID primitive.ObjectID, name string, servings int, ingredients []RecipeIngredient, instructions string, createdBy primitive.ObjectID) *Recipe {
+	now := time.Now()
+	return &Recipe{
+		GroupID:      groupID,
+		Name:         name,
+		Servings:     servings,
+		Ingredients:  ingredients,
+		Instructions: instructions,
+		CreatedBy:    createdBy,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
+
+// ValidateRecipe checks that a recipe has a name, at least one serving and at least one
+// ingredient, and that every ingredient has a name and a positive quantity
+func ValidateRecipe(name string, servings int, ingredients []RecipeIngredient) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("recipe name is required")
+	}
+	if servings < 1 {
+		return errors.New("servings must be at least 1")
+	}
+	if len(ingredients) == 0 {
+		return errors.New("a recipe needs at least one ingredient")
+	}
+	for _, ingredient := range ingredients {
+		if strings.TrimSpace(ingredient.Name) == "" {
+			return errors.New("every ingredient needs a name")
+		}
+		if ingredient.Quantity <= 0 {
+			return errors.New("ingredient quantities must be positive")
+		}
+	}
+	return nil
+}
+
+// ScaledIngredients returns the recipe's ingredients for the given number of servings, or for
+// the recipe's own servings if servings is not positive
+func (r *Recipe) ScaledIngredients(servings int) []RecipeIngredient {
+	factor := 1.0
+	if servings > 0 && r.Servings > 0 {
+		factor = float64(servings) / float64(r.Servings)
+	}
+
+	scaled := make([]RecipeIngredient, len(r.Ingredients))
+	for i, ingredient := range r.Ingredients {
+		ingredient.Quantity = roundQuantity(ingredient.Quantity * factor)
+		scaled[i] = ingredient
+	}
+	return scaled
+}
+
+// Matches checks if a pantry item holds the ingredient: the same catalog product when the
+// ingredient is linked to one, else an item with the same name
+func (i *RecipeIngredient) Matches(item *PantryItem) bool {
+	if i.ProductID != nil && item.ProductID != nil {
+		return *i.ProductID == *item.ProductID
+	}
+	return strings.EqualFold(strings.TrimSpace(i.Name), strings.TrimSpace(item.Name))
+}
+
+// sameIngredient checks if two ingredient lines are the same ingredient
+func (i *RecipeIngredient) sameIngredient(other *RecipeIngredient) bool {
+	if i.ProductID != nil && other.ProductID != nil {
+		return *i.ProductID == *other.ProductID
+	}
+	return strings.EqualFold(strings.TrimSpace(i.Name), strings.TrimSpace(other.Name))
+}
+
+// CombineIngredients merges ingredients needed more than once, such as by several meals of a
+// week, into the unit they were first needed in. Lines whose units do not convert are kept
+// apart.
+func CombineIngredients(ingredients []RecipeIngredient) []RecipeIngredient {
+	var combined []RecipeIngredient
+	for _, ingredient := range ingredients {
+		merged := false
+		for i := range combined {
+			existing := &combined[i]
+			if !existing.sameIngredient(&ingredient) {
+				continue
+			}
+			density, _ := units.DensityFor(ingredient.Name)
+			quantity, err := convertIngredient(ingredient.Quantity, ingredient.Unit, existing.Unit, density)
+			if err != nil {
+				continue
+			}
+			existing.Quantity = roundQuantity(existing.Quantity + quantity)
+			if existing.ProductID == nil {
+				existing.ProductID = ingredient.ProductID
+			}
+			merged = true
+			break
+		}
+		if !merged {
+			combined = append(combined, ingredient)
+		}
+	}
+	return combined
+}
+
+// IngredientAllocation is how much of a pantry item an ingredient takes
+type IngredientAllocation struct {
+	ItemID   primitive.ObjectID `json:"item_id"`
+	ItemName string             `json:"item_name"`
+	Quantity float64            `json:"quantity"` // In the item's unit
+	Unit     string             `json:"unit"`
+}
+
+// IngredientCoverage is how much of an ingredient the pantry holds. Available and Missing are
+// in the ingredient's unit, and Items are the pantry items it would be taken from.
+type IngredientCoverage struct {
+	RecipeIngredient
+	Available float64                `json:"available"`
+	Missing   float64                `json:"missing"`
+	Items     []IngredientAllocation `json:"items,omitempty"`
+}
+
+// IsCovered checks if the pantry holds all of the ingredient
+func (c *IngredientCoverage) IsCovered() bool {
+	return c.Missing <= 0
+}
+
+// CheckCoverage works out how much of each ingredient the pantry items hold and which items
+// it would be taken from, first-expiring first. Items whose unit does not convert to the
+// ingredient's are skipped, and stock taken by one ingredient is not available to the next.
+// Only items the member can use should be passed.
+func CheckCoverage(ingredients []RecipeIngredient, items []PantryItem) []IngredientCoverage {
+	sorted := make([]PantryItem, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].ExpirationDate, sorted[j].ExpirationDate
+		if a.IsZero() != b.IsZero() {
+			return !a.IsZero()
+		}
+		return a.Before(b)
+	})
+
+	remaining := make(map[primitive.ObjectID]float64, len(sorted))
+	for _, item := range sorted {
+		remaining[item.ID] = item.Quantity
+	}
+
+	coverage := make([]IngredientCoverage, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		covered := IngredientCoverage{RecipeIngredient: ingredient}
+		needed := ingredient.Quantity
+
+		for i := range sorted {
+			item := &sorted[i]
+			left := remaining[item.ID]
+			if left <= 0 || !ingredient.Matches(item) {
+				continue
+			}
+			available, err := convertIngredient(left, item.Unit, ingredient.Unit, item.GramsPerML())
+			if err != nil || available <= 0 {
+				continue
+			}
+			covered.Available = roundQuantity(covered.Available + available)
+
+			if needed <= 0 {
+				continue
+			}
+			taken := left
+			if available > needed {
+				taken, err = convertIngredient(needed, ingredient.Unit, item.Unit, item.GramsPerML())
+				if err != nil {
+					continue
+				}
+				taken = roundQuantity(math.Min(taken, left))
+				needed = 0
+			} else {
+				needed = roundQuantity(needed - available)
+			}
+			remaining[item.ID] = roundQuantity(left - taken)
+			covered.Items = append(covered.Items, IngredientAllocation{
+				ItemID:   item.ID,
+				ItemName: item.Name,
+				Quantity: taken,
+				Unit:     item.Unit,
+			})
+		}
+
+		covered.Missing = roundQuantity(needed)
+		coverage = append(coverage, covered)
+	}
+	return coverage
+}
+
+// MissingIngredients returns the ingredients the pantry does not hold enough of, in the
+// quantity still needed
+func MissingIngredients(coverage []IngredientCoverage) []RecipeIngredient {
+	var missing []RecipeIngredient
+	for _, covered := range coverage {
+		if covered.IsCovered() {
+			continue
+		}
+		ingredient := covered.RecipeIngredient
+		ingredient.Quantity = covered.Missing
+		missing = append(missing, ingredient)
+	}
+	return missing
+}
+
+// convertIngredient converts a quantity between units. An empty unit on either side means the
+// quantities are counted alike.
+func convertIngredient(quantity float64, from, to string, density float64) (float64, error) {
+	if from == "" || to == "" {
+		return quantity, nil
+	}
+	return units.ConvertString(quantity, from, to, density)
+}
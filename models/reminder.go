@@ -0,0 +1,223 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReminderKind identifies what a reminder is about
+type ReminderKind string
+
+const (
+	ReminderKindDueSoon     ReminderKind = "due_soon"     // Sent a number of hours before a chore is due
+	ReminderKindOverdue     ReminderKind = "overdue"      // Sent once a chore is past its due date
+	ReminderKindDailyDigest ReminderKind = "daily_digest" // Morning summary of the day's chores
+)
+
+// ReminderChannel is a way of reaching a user
+type ReminderChannel string
+
+const (
+	ReminderChannelInApp ReminderChannel = "in_app" // The group notification stream
+)
+
+// ReminderDeliveryRetention is how long delivery records are kept to deduplicate reminders
+const ReminderDeliveryRetention = 30 * 24 * time.Hour
+
+// ReminderPreferences controls which reminders a user gets, how and when
+type ReminderPreferences struct {
+	Channels        []ReminderChannel `bson:"channels" json:"channels"`
+	DueSoonHours    int               `bson:"due_soon_hours" json:"due_soon_hours"` // Lead time before the due date, 0 disables
+	Overdue         bool              `bson:"overdue" json:"overdue"`
+	DailyDigest     bool              `bson:"daily_digest" json:"daily_digest"`
+	DigestHour      int               `bson:"digest_hour" json:"digest_hour"`               // Local hour the digest is sent from
+	QuietHoursStart string            `bson:"quiet_hours_start" json:"quiet_hours_start"`   // HH:MM local time, empty for none
+	QuietHoursEnd   string            `bson:"quiet_hours_end" json:"quiet_hours_end"`       // HH:MM local time
+	Timezone        string            `bson:"timezone,omitempty" json:"timezone,omitempty"` // Defaults to the group's time zone
+}
+
+// DefaultReminderPreferences returns the preferences of users who never changed them
+func DefaultReminderPreferences() ReminderPreferences {
+	return ReminderPreferences{
+		Channels:        []ReminderChannel{ReminderChannelInApp},
+		DueSoonHours:    24,
+		Overdue:         true,
+		DailyDigest:     true,
+		DigestHour:      8,
+		QuietHoursStart: "22:00",
+		QuietHoursEnd:   "07:00",
+	}
+}
+
+// ReminderSettings returns the user's reminder preferences, or the defaults
+func (u *User) ReminderSettings() ReminderPreferences {
+	if u.ReminderPreferences == nil {
+		return DefaultReminderPreferences()
+	}
+	return *u.ReminderPreferences
+}
+
+// Validate checks the preferences against the channels the server can deliver on
+func (p ReminderPreferences) Validate(available []ReminderChannel) error {
+	for _, channel := range p.Channels {
+		known := false
+		for _, candidate := range available {
+			if channel == candidate {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown channel %q", channel)
+		}
+	}
+	if p.DueSoonHours < 0 || p.DueSoonHours > 168 {
+		return errors.New("due_soon_hours must be between 0 and 168")
+	}
+	if p.DigestHour < 0 || p.DigestHour > 23 {
+		return errors.New("digest_hour must be between 0 and 23")
+	}
+	if (p.QuietHoursStart == "") != (p.QuietHoursEnd == "") {
+		return errors.New("quiet hours need both a start and an end")
+	}
+	if p.QuietHoursStart != "" {
+		if _, err := parseClock(p.QuietHoursStart); err != nil {
+			return errors.New("quiet_hours_start must be HH:MM")
+		}
+		if _, err := parseClock(p.QuietHoursEnd); err != nil {
+			return errors.New("quiet_hours_end must be HH:MM")
+		}
+	}
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil || p.Timezone == "Local" {
+			return errors.New("unknown time zone")
+		}
+	}
+	return nil
+}
+
+// Location returns the time zone reminders are scheduled in
+func (p ReminderPreferences) Location(groupTimezone string) *time.Location {
+	for _, name := range []string{p.Timezone, groupTimezone} {
+		if name == "" {
+			continue
+		}
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// Enabled checks if the user wants reminders of a kind
+func (p ReminderPreferences) Enabled(kind ReminderKind) bool {
+	switch kind {
+	case ReminderKindDueSoon:
+		return p.DueSoonHours > 0
+	case ReminderKindOverdue:
+		return p.Overdue
+	case ReminderKindDailyDigest:
+		return p.DailyDigest
+	}
+	return false
+}
+
+// InQuietHours checks if t falls in the user's quiet hours. Quiet hours can span midnight,
+// e.g. 22:00 to 07:00.
+func (p ReminderPreferences) InQuietHours(t time.Time, loc *time.Location) bool {
+	if p.QuietHoursStart == "" || p.QuietHoursEnd == "" {
+		return false
+	}
+	start, err := parseClock(p.QuietHoursStart)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(p.QuietHoursEnd)
+	if err != nil {
+		return false
+	}
+
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// parseClock returns the minutes past midnight of an HH:MM time
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// DueSoonReminderKey identifies the due-soon reminder of a chore. The due date is part of
+// the key so that a rescheduled chore is reminded about again.
+func DueSoonReminderKey(choreID, userID primitive.ObjectID, dueDate time.Time) string {
+	return fmt.Sprintf("%s:%s:%s:%d", ReminderKindDueSoon, choreID.Hex(), userID.Hex(), dueDate.Unix())
+}
+
+// OverdueReminderKey identifies the overdue reminder of a chore
+func OverdueReminderKey(choreID, userID primitive.ObjectID, dueDate time.Time) string {
+	return fmt.Sprintf("%s:%s:%s:%d", ReminderKindOverdue, choreID.Hex(), userID.Hex(), dueDate.Unix())
+}
+
+// DailyDigestReminderKey identifies a user's digest for a local calendar day
+func DailyDigestReminderKey(userID primitive.ObjectID, day time.Time) string {
+	return fmt.Sprintf("%s:%s:%s", ReminderKindDailyDigest, userID.Hex(), day.Format("2006-01-02"))
+}
+
+// ReminderDeliveryStatus is the state of a claimed reminder
+type ReminderDeliveryStatus string
+
+const (
+	ReminderDeliverySending ReminderDeliveryStatus = "sending" // Claimed, channels are being called
+	ReminderDeliverySent    ReminderDeliveryStatus = "sent"
+)
+
+// ReminderDelivery records that a reminder was sent. The unique key guarantees each reminder
+// is claimed once, even by several instances or across restarts.
+type ReminderDelivery struct {
+	ID        primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Key       string                 `bson:"key" json:"key"`
+	Kind      ReminderKind           `bson:"kind" json:"kind"`
+	UserID    primitive.ObjectID     `bson:"user_id" json:"user_id"`
+	ChoreID   *primitive.ObjectID    `bson:"chore_id,omitempty" json:"chore_id,omitempty"`
+	Status    ReminderDeliveryStatus `bson:"status" json:"status"`
+	Channels  []ReminderChannel      `bson:"channels" json:"channels"` // Channels the reminder went out on
+	CreatedAt time.Time              `bson:"created_at" json:"created_at"`
+	SentAt    *time.Time             `bson:"sent_at,omitempty" json:"sent_at,omitempty"`
+}
+
+// DigestSummary builds the text of a daily digest, empty when there is nothing to report
+func DigestSummary(dueToday, overdue []Chore) string {
+	if len(dueToday) == 0 && len(overdue) == 0 {
+		return ""
+	}
+
+	summary := ""
+	switch len(dueToday) {
+	case 0:
+	case 1:
+		summary = "1 chore due today: " + dueToday[0].Title
+	default:
+		summary = fmt.Sprintf("%d chores due today", len(dueToday))
+	}
+	if len(overdue) > 0 {
+		if summary != "" {
+			summary += "; "
+		}
+		if len(overdue) == 1 {
+			summary += "1 overdue: " + overdue[0].Title
+		} else {
+			summary += fmt.Sprintf("%d overdue", len(overdue))
+		}
+	}
+	return summary
+}
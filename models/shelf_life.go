@@ -0,0 +1,109 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultFreezerMultiplier is how much longer items keep frozen when no rule says otherwise
+const DefaultFreezerMultiplier = 1.0
+
+// ShelfLifeRule is how long items of a category or a catalog product typically keep, unopened
+// and stored as usual. Seeded rules apply to every group; a group's own rule for the same
+// category or product overrides them.
+type ShelfLifeRule struct {
+	ID                primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	GroupID           *primitive.ObjectID `bson:"group_id,omitempty" json:"group_id,omitempty"` // nil for seeded rules
+	CategoryID        *primitive.ObjectID `bson:"category_id,omitempty" json:"category_id,omitempty"`
+	ProductID         *primitive.ObjectID `bson:"product_id,omitempty" json:"product_id,omitempty"`
+	Days              int                 `bson:"days" json:"days"`
+	FreezerMultiplier float64             `bson:"freezer_multiplier,omitempty" json:"freezer_multiplier,omitempty"` // How many times longer it keeps frozen
+	CreatedBy         *primitive.ObjectID `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt         time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// ShelfLife is the resolved shelf life of an item
+type ShelfLife struct {
+	Days              int     `json:"days"`
+	FreezerMultiplier float64 `json:"freezer_multiplier"`
+}
+
+// ValidateShelfLifeRule checks the days and freezer multiplier of a rule
+func ValidateShelfLifeRule(days int, freezerMultiplier float64) error {
+	if days <= 0 {
+		return errors.New("days must be positive")
+	}
+	if freezerMultiplier != 0 && freezerMultiplier < 1 {
+		return errors.New("freezer_multiplier must be at least 1")
+	}
+	return nil
+}
+
+// ExpirationFrom returns when an item stored from the given time expires
+func (s ShelfLife) ExpirationFrom(from time.Time) time.Time {
+	return from.AddDate(0, 0, s.Days)
+}
+
+// FrozenDays returns how long the item keeps in the freezer
+func (s ShelfLife) FrozenDays() int {
+	return int(float64(s.Days) * s.FreezerMultiplier)
+}
+
+// ResolveShelfLife picks the shelf life of an item from the rules that may apply to it. The
+// group's product rule wins, then a seeded product rule, then the catalog product's own
+// shelf life, then the group's category rule and last the seeded category rule. A freezer
+// multiplier missing from the rule that wins comes from the category rules. It reports false
+// if nothing is known about the item.
+func ResolveShelfLife(rules []ShelfLifeRule, groupID, categoryID primitive.ObjectID, product *Product) (ShelfLife, bool) {
+	var groupProduct, seededProduct, groupCategory, seededCategory *ShelfLifeRule
+	for i := range rules {
+		rule := &rules[i]
+		switch {
+		case rule.GroupID != nil && *rule.GroupID != groupID:
+		case rule.ProductID != nil && product != nil && *rule.ProductID == product.ID:
+			if rule.GroupID != nil {
+				groupProduct = rule
+			} else {
+				seededProduct = rule
+			}
+		case rule.CategoryID != nil && *rule.CategoryID == categoryID:
+			if rule.GroupID != nil {
+				groupCategory = rule
+			} else {
+				seededCategory = rule
+			}
+		}
+	}
+
+	category := groupCategory
+	if category == nil {
+		category = seededCategory
+	}
+
+	var shelfLife ShelfLife
+	switch {
+	case groupProduct != nil:
+		shelfLife = ShelfLife{Days: groupProduct.Days, FreezerMultiplier: groupProduct.FreezerMultiplier}
+	case seededProduct != nil:
+		shelfLife = ShelfLife{Days: seededProduct.Days, FreezerMultiplier: seededProduct.FreezerMultiplier}
+	case product != nil && product.ShelfLifeDays != nil:
+		shelfLife = ShelfLife{Days: *product.ShelfLifeDays}
+	case category != nil:
+		shelfLife = ShelfLife{Days: category.Days, FreezerMultiplier: category.FreezerMultiplier}
+	default:
+		return ShelfLife{}, false
+	}
+
+	for _, rule := range []*ShelfLifeRule{groupCategory, seededCategory} {
+		if shelfLife.FreezerMultiplier == 0 && rule != nil {
+			shelfLife.FreezerMultiplier = rule.FreezerMultiplier
+		}
+	}
+	if shelfLife.FreezerMultiplier == 0 {
+		shelfLife.FreezerMultiplier = DefaultFreezerMultiplier
+	}
+	return shelfLife, true
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StorageKind defines what kind of place a storage location is
+type StorageKind string
+
+const (
+	// StorageKindFridge is a refrigerator
+	StorageKindFridge StorageKind = "fridge"
+
+	// StorageKindFreezer is a freezer; items moved into one keep longer
+	StorageKindFreezer StorageKind = "freezer"
+
+	// StorageKindCupboard is a cupboard or pantry shelf at room temperature
+	StorageKindCupboard StorageKind = "cupboard"
+
+	// StorageKindShelf is a member's personal shelf
+	StorageKindShelf StorageKind = "shelf"
+)
+
+// ErrStorageLocationNotFound is returned when a storage location is not one of the group's
+var ErrStorageLocationNotFound = errors.New("storage location not found")
+
+// ErrNotLocationOwner is returned when a member uses someone else's personal storage location
+var ErrNotLocationOwner = errors.New("only the owner can use a personal storage location")
+
+// IsValidStorageKind checks if the storage kind is one of the known kinds
+func IsValidStorageKind(kind StorageKind) bool {
+	switch kind {
+	case StorageKindFridge, StorageKindFreezer, StorageKindCupboard, StorageKindShelf:
+		return true
+	}
+	return false
+}
+
+// StorageLocation is a place where a group keeps pantry items, such as the fridge or a
+// member's personal shelf
+type StorageLocation struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	GroupID   primitive.ObjectID  `bson:"group_id" json:"group_id"`
+	Name      string              `bson:"name" json:"name"`
+	Kind      StorageKind         `bson:"kind" json:"kind"`
+	OwnerID   *primitive.ObjectID `bson:"owner_id,omitempty" json:"owner_id,omitempty"` // The member a personal location belongs to
+	CreatedBy primitive.ObjectID  `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// CreateStorageLocation creates a new storage location for a group
+func CreateStorageLocation(groupID primitive.ObjectID, name string, kind StorageKind, createdBy primitive.ObjectID) *StorageLocation {
+	now := time.Now()
+	return &StorageLocation{
+		GroupID:   groupID,
+		Name:      name,
+		Kind:      kind,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// CanPlace checks if a member may put items in the location: anyone for a shared location, and
+// only its owner for a personal one
+func (l *StorageLocation) CanPlace(userID primitive.ObjectID) bool {
+	return l.OwnerID == nil || *l.OwnerID == userID
+}
+
+// IsFreezer checks if items in the location are frozen
+func (l *StorageLocation) IsFreezer() bool {
+	return l.Kind == StorageKindFreezer
+}
+
+// PlaceIn puts a lot that was just added in a storage location, frozen from now if the
+// location is a freezer
+func (l *PantryLot) PlaceIn(location *StorageLocation, now time.Time) {
+	l.LocationID = &location.ID
+	l.FrozenAt = nil
+	if location.IsFreezer() {
+		l.FrozenAt = &now
+	}
+}
+
+// MoveTo moves the lot to a storage location. Going into the freezer, the time the lot has
+// left is stretched by the freezer multiplier of its shelf life, and a lot without a date is
+// given its frozen shelf life; coming out, the time left shrinks by the same factor. A
+// changed date is marked estimated, and expired lots keep theirs.
+func (l *PantryLot) MoveTo(location *StorageLocation, shelfLife ShelfLife, now time.Time) {
+	wasFrozen := l.FrozenAt != nil
+	l.LocationID = &location.ID
+	if wasFrozen == location.IsFreezer() {
+		return
+	}
+
+	expiration := l.ExpirationDate
+	if location.IsFreezer() {
+		l.FrozenAt = &now
+		expiration = shelfLife.FreezeExpiration(expiration, now)
+	} else {
+		l.FrozenAt = nil
+		expiration = shelfLife.ThawExpiration(expiration, now)
+	}
+	if !expiration.Equal(l.ExpirationDate) {
+		l.ExpirationDate = expiration
+		l.ExpirationEstimated = true
+	}
+}
+
+// MoveLots moves the item's lot with the given ID, or all of its lots if lotID is nil, to a
+// storage location and returns the lots moved
+func (p *PantryItem) MoveLots(lotID *primitive.ObjectID, location *StorageLocation, shelfLife ShelfLife, now time.Time) ([]PantryLot, error) {
+	p.SyncLots()
+	if lotID != nil {
+		if _, err := p.Lot(*lotID); err != nil {
+			return nil, err
+		}
+	}
+
+	var moved []PantryLot
+	for i := range p.Lots {
+		lot := &p.Lots[i]
+		if lotID != nil && lot.ID != *lotID {
+			continue
+		}
+		lot.MoveTo(location, shelfLife, now)
+		moved = append(moved, *lot)
+	}
+
+	p.SyncLots()
+	p.UpdatedAt = now
+	return moved, nil
+}
+
+// FreezeExpiration returns when a lot expiring at expiration expires once frozen at now
+func (s ShelfLife) FreezeExpiration(expiration, now time.Time) time.Time {
+	if expiration.IsZero() {
+		if s.Days <= 0 {
+			return expiration
+		}
+		return now.AddDate(0, 0, s.FrozenDays())
+	}
+	return stretchRemaining(expiration, now, s.FreezerMultiplier)
+}
+
+// ThawExpiration returns when a frozen lot expiring at expiration expires once thawed at now
+func (s ShelfLife) ThawExpiration(expiration, now time.Time) time.Time {
+	if expiration.IsZero() || s.FreezerMultiplier <= 1 {
+		return expiration
+	}
+	return stretchRemaining(expiration, now, 1/s.FreezerMultiplier)
+}
+
+// stretchRemaining scales the time left until expiration by factor
+func stretchRemaining(expiration, now time.Time, factor float64) time.Time {
+	if !expiration.After(now) || factor <= 0 || factor == 1 {
+		return expiration
+	}
+	remaining := math.Min(float64(expiration.Sub(now))*factor, math.MaxInt64)
+	return now.Add(time.Duration(remaining))
+}
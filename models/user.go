@@ -19,4 +19,8 @@ type User struct {
 	GroupCode   string             `bson:"group_code" json:"group_code"`
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+
+	CalendarFeedToken   string               `bson:"calendar_feed_token,omitempty" json:"-"` // Secret for the personal ICS feed
+	ReminderPreferences *ReminderPreferences `bson:"reminder_preferences,omitempty" json:"reminder_preferences,omitempty"`
+	Notifications       NotificationSettings `bson:"notifications,omitempty" json:"notification_settings"`
 }
@@ -0,0 +1,204 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxWebhookAttempts is how many times a delivery is tried before it is dead-lettered
+const MaxWebhookAttempts = 8
+
+// MaxWebhooksPerGroup bounds the subscriptions a group can create
+const MaxWebhooksPerGroup = 10
+
+// WebhookDeliveryRetention is how long the delivery log is kept
+const WebhookDeliveryRetention = 30 * 24 * time.Hour
+
+// webhookBaseDelay and webhookMaxDelay shape the exponential backoff between attempts
+const (
+	webhookBaseDelay = 30 * time.Second
+	webhookMaxDelay  = 6 * time.Hour
+)
+
+// Webhook is a group's subscription to a set of event types, delivered to URL and signed
+// with Secret
+type Webhook struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GroupID     primitive.ObjectID `bson:"group_id" json:"group_id"`
+	URL         string             `bson:"url" json:"url"`
+	Secret      string             `bson:"secret" json:"-"`
+	Events      []string           `bson:"events" json:"events"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Active      bool               `bson:"active" json:"active"`
+	CreatedBy   primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// WebhookDeliveryStatus is where a delivery is in its lifecycle
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending    WebhookDeliveryStatus = "pending"    // Waiting for its next attempt
+	WebhookDeliveryDelivering WebhookDeliveryStatus = "delivering" // Claimed by a worker
+	WebhookDeliverySucceeded  WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryDead       WebhookDeliveryStatus = "dead" // Gave up after MaxWebhookAttempts
+)
+
+// WebhookAttempt is one try at delivering an event
+type WebhookAttempt struct {
+	At         time.Time `bson:"at" json:"at"`
+	StatusCode int       `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMS int64     `bson:"duration_ms" json:"duration_ms"`
+}
+
+// Succeeded checks if the receiver accepted the delivery
+func (a WebhookAttempt) Succeeded() bool {
+	return a.Error == "" && a.StatusCode >= 200 && a.StatusCode < 300
+}
+
+// WebhookDelivery is one event queued for one webhook. The payload is stored as sent, so a
+// redelivery carries exactly the same body.
+type WebhookDelivery struct {
+	ID            primitive.ObjectID    `bson:"_id,omitempty" json:"id"`
+	WebhookID     primitive.ObjectID    `bson:"webhook_id" json:"webhook_id"`
+	GroupID       primitive.ObjectID    `bson:"group_id" json:"group_id"`
+	EventID       primitive.ObjectID    `bson:"event_id" json:"event_id"`
+	EventType     string                `bson:"event_type" json:"event_type"`
+	Payload       string                `bson:"payload" json:"payload"`
+	Status        WebhookDeliveryStatus `bson:"status" json:"status"`
+	Attempts      []WebhookAttempt      `bson:"attempts" json:"attempts"`
+	NextAttemptAt *time.Time            `bson:"next_attempt_at,omitempty" json:"next_attempt_at,omitempty"`
+	LockedUntil   *time.Time            `bson:"locked_until,omitempty" json:"-"`
+	RedeliveryOf  *primitive.ObjectID   `bson:"redelivery_of,omitempty" json:"redelivery_of,omitempty"`
+	CreatedAt     time.Time             `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time             `bson:"updated_at" json:"updated_at"`
+	ExpiresAt     time.Time             `bson:"expires_at" json:"-"`
+}
+
+// NewWebhookSecret generates a random signing secret
+func NewWebhookSecret() (string, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// ValidateWebhookURL checks that a webhook URL is an absolute http or https URL
+func ValidateWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return errors.New("url must be an absolute URL")
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return errors.New("url must use http or https")
+	}
+	if parsed.User != nil {
+		return errors.New("url must not contain credentials")
+	}
+	return nil
+}
+
+// IsPrivateAddress checks if an address is on the server's own or a private network: a
+// loopback, private, link-local or unspecified address, which outbound requests must not reach
+func IsPrivateAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// Subscribes checks if the webhook wants events of a type
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, event := range w.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// NewWebhookDelivery queues an event for a webhook, due straight away
+func NewWebhookDelivery(webhook *Webhook, eventID primitive.ObjectID, eventType string, payload string, now time.Time) *WebhookDelivery {
+	return &WebhookDelivery{
+		WebhookID:     webhook.ID,
+		GroupID:       webhook.GroupID,
+		EventID:       eventID,
+		EventType:     eventType,
+		Payload:       payload,
+		Status:        WebhookDeliveryPending,
+		Attempts:      []WebhookAttempt{},
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		ExpiresAt:     now.Add(WebhookDeliveryRetention),
+	}
+}
+
+// Redeliver queues the same payload again as a new delivery, leaving this one's log intact
+func (d *WebhookDelivery) Redeliver(now time.Time) *WebhookDelivery {
+	originalID := d.ID
+	return &WebhookDelivery{
+		WebhookID:     d.WebhookID,
+		GroupID:       d.GroupID,
+		EventID:       d.EventID,
+		EventType:     d.EventType,
+		Payload:       d.Payload,
+		Status:        WebhookDeliveryPending,
+		Attempts:      []WebhookAttempt{},
+		NextAttemptAt: &now,
+		RedeliveryOf:  &originalID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		ExpiresAt:     now.Add(WebhookDeliveryRetention),
+	}
+}
+
+// WebhookRetryDelay returns how long to wait after the given number of failed attempts:
+// 30 seconds after the first, doubling each time up to six hours
+func WebhookRetryDelay(failedAttempts int) time.Duration {
+	delay := webhookBaseDelay
+	for i := 1; i < failedAttempts; i++ {
+		delay *= 2
+		if delay >= webhookMaxDelay {
+			return webhookMaxDelay
+		}
+	}
+	return delay
+}
+
+// RecordAttempt adds an attempt to the log and moves the delivery on: to succeeded, to
+// pending with a backed-off retry, or to dead once MaxWebhookAttempts have failed
+func (d *WebhookDelivery) RecordAttempt(attempt WebhookAttempt) {
+	d.Attempts = append(d.Attempts, attempt)
+	d.UpdatedAt = attempt.At
+	d.LockedUntil = nil
+
+	switch {
+	case attempt.Succeeded():
+		d.Status = WebhookDeliverySucceeded
+		d.NextAttemptAt = nil
+	case len(d.Attempts) >= MaxWebhookAttempts:
+		d.Status = WebhookDeliveryDead
+		d.NextAttemptAt = nil
+	default:
+		next := attempt.At.Add(WebhookRetryDelay(len(d.Attempts)))
+		d.Status = WebhookDeliveryPending
+		d.NextAttemptAt = &next
+	}
+}
+
+// DeadLetter records a final attempt and gives up on the delivery
+func (d *WebhookDelivery) DeadLetter(attempt WebhookAttempt) {
+	d.Attempts = append(d.Attempts, attempt)
+	d.UpdatedAt = attempt.At
+	d.LockedUntil = nil
+	d.Status = WebhookDeliveryDead
+	d.NextAttemptAt = nil
+}
@@ -0,0 +1,462 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestIsCompletionOnTime(t *testing.T) {
+	dueDate := time.Date(2025, 3, 12, 23, 59, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		dueDate     time.Time
+		completedAt time.Time
+		expected    bool
+	}{
+		{"before due day", dueDate, time.Date(2025, 3, 11, 10, 0, 0, 0, time.UTC), true},
+		{"last second of due day", dueDate, time.Date(2025, 3, 12, 23, 59, 59, 0, time.UTC), true},
+		{"day after due day", dueDate, time.Date(2025, 3, 13, 0, 0, 0, 0, time.UTC), false},
+		{"no due date", time.Time{}, time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := models.IsCompletionOnTime(tt.dueDate, tt.completedAt); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAchievementProgressUpdate(t *testing.T) {
+	// Wednesday 2025-03-12
+	wednesday := time.Date(2025, 3, 12, 10, 0, 0, 0, time.UTC)
+
+	completion := func(at, due time.Time) models.AchievementEvent {
+		return models.AchievementEvent{Type: models.AchievementEventChoreCompleted, OccurredAt: at, DueDate: due}
+	}
+	overdue := func(at time.Time) models.AchievementEvent {
+		return models.AchievementEvent{Type: models.AchievementEventChoreOverdue, OccurredAt: at}
+	}
+
+	tests := []struct {
+		name               string
+		events             []models.AchievementEvent
+		expectedCompleted  int
+		expectedOnTime     int
+		expectedBestOnTime int
+		expectedDaily      int
+		expectedBestDaily  int
+		expectedWeekActive int
+		expectedLastOnTime bool
+	}{
+		{
+			name:               "single on-time completion",
+			events:             []models.AchievementEvent{completion(wednesday, wednesday)},
+			expectedCompleted:  1,
+			expectedOnTime:     1,
+			expectedBestOnTime: 1,
+			expectedDaily:      1,
+			expectedBestDaily:  1,
+			expectedWeekActive: 1,
+			expectedLastOnTime: true,
+		},
+		{
+			name: "two completions on the same day count once for daily streak",
+			events: []models.AchievementEvent{
+				completion(wednesday, wednesday),
+				completion(wednesday.Add(time.Hour), wednesday),
+			},
+			expectedCompleted:  2,
+			expectedOnTime:     2,
+			expectedBestOnTime: 2,
+			expectedDaily:      1,
+			expectedBestDaily:  1,
+			expectedWeekActive: 1,
+			expectedLastOnTime: true,
+		},
+		{
+			name: "consecutive days extend the daily streak",
+			events: []models.AchievementEvent{
+				completion(wednesday, time.Time{}),
+				completion(wednesday.AddDate(0, 0, 1), time.Time{}),
+				completion(wednesday.AddDate(0, 0, 2), time.Time{}),
+			},
+			expectedCompleted:  3,
+			expectedOnTime:     3,
+			expectedBestOnTime: 3,
+			expectedDaily:      3,
+			expectedBestDaily:  3,
+			expectedWeekActive: 3,
+			expectedLastOnTime: true,
+		},
+		{
+			name: "a skipped day restarts the daily streak",
+			events: []models.AchievementEvent{
+				completion(wednesday, time.Time{}),
+				completion(wednesday.AddDate(0, 0, 1), time.Time{}),
+				completion(wednesday.AddDate(0, 0, 3), time.Time{}),
+			},
+			expectedCompleted:  3,
+			expectedOnTime:     3,
+			expectedBestOnTime: 3,
+			expectedDaily:      1,
+			expectedBestDaily:  2,
+			expectedWeekActive: 3,
+			expectedLastOnTime: true,
+		},
+		{
+			name: "a late completion breaks the on-time streak",
+			events: []models.AchievementEvent{
+				completion(wednesday, wednesday),
+				completion(wednesday.AddDate(0, 0, 2), wednesday),
+			},
+			expectedCompleted:  2,
+			expectedOnTime:     0,
+			expectedBestOnTime: 1,
+			expectedDaily:      1,
+			expectedBestDaily:  1,
+			expectedWeekActive: 2,
+			expectedLastOnTime: false,
+		},
+		{
+			name: "an overdue chore breaks the on-time streak but not the daily streak",
+			events: []models.AchievementEvent{
+				completion(wednesday, wednesday),
+				completion(wednesday.Add(time.Hour), wednesday),
+				overdue(wednesday.Add(2 * time.Hour)),
+			},
+			expectedCompleted:  2,
+			expectedOnTime:     0,
+			expectedBestOnTime: 2,
+			expectedDaily:      1,
+			expectedBestDaily:  1,
+			expectedWeekActive: 1,
+			expectedLastOnTime: false,
+		},
+		{
+			name: "a new week resets the active day count",
+			events: []models.AchievementEvent{
+				completion(wednesday, time.Time{}),
+				completion(wednesday.AddDate(0, 0, 4), time.Time{}), // Sunday
+				completion(wednesday.AddDate(0, 0, 5), time.Time{}), // Monday
+			},
+			expectedCompleted:  3,
+			expectedOnTime:     3,
+			expectedBestOnTime: 3,
+			expectedDaily:      2,
+			expectedBestDaily:  2,
+			expectedWeekActive: 1,
+			expectedLastOnTime: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groupID := primitive.NewObjectID()
+			stored := bson.M{}
+			var onTime bool
+			for _, event := range tt.events {
+				event.GroupID = groupID
+				var pipeline bson.A
+				pipeline, onTime = models.AchievementProgressUpdate(event, event.OccurredAt)
+				stored = applyPipeline(t, stored, pipeline)
+			}
+
+			var progress models.MemberProgress
+			raw, err := bson.Marshal(stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := bson.Unmarshal(raw, &progress); err != nil {
+				t.Fatal(err)
+			}
+
+			if progress.GroupID != groupID {
+				t.Errorf("Expected the group to be set, got %v", progress.GroupID)
+			}
+
+			if progress.ChoresCompleted != tt.expectedCompleted {
+				t.Errorf("Expected %d chores completed, got %d", tt.expectedCompleted, progress.ChoresCompleted)
+			}
+			if progress.OnTimeStreak != tt.expectedOnTime {
+				t.Errorf("Expected on-time streak %d, got %d", tt.expectedOnTime, progress.OnTimeStreak)
+			}
+			if progress.BestOnTimeStreak != tt.expectedBestOnTime {
+				t.Errorf("Expected best on-time streak %d, got %d", tt.expectedBestOnTime, progress.BestOnTimeStreak)
+			}
+			if progress.DailyStreak != tt.expectedDaily {
+				t.Errorf("Expected daily streak %d, got %d", tt.expectedDaily, progress.DailyStreak)
+			}
+			if progress.BestDailyStreak != tt.expectedBestDaily {
+				t.Errorf("Expected best daily streak %d, got %d", tt.expectedBestDaily, progress.BestDailyStreak)
+			}
+			if progress.WeekActiveDays != tt.expectedWeekActive {
+				t.Errorf("Expected %d active days this week, got %d", tt.expectedWeekActive, progress.WeekActiveDays)
+			}
+			if onTime != tt.expectedLastOnTime {
+				t.Errorf("Expected last event on time to be %v, got %v", tt.expectedLastOnTime, onTime)
+			}
+		})
+	}
+}
+
+func TestCurrentDailyStreak(t *testing.T) {
+	progress := models.MemberProgress{
+		DailyStreak:   4,
+		LastActiveDay: time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected int
+	}{
+		{"same day", time.Date(2025, 3, 12, 20, 0, 0, 0, time.UTC), 4},
+		{"next day keeps the streak alive", time.Date(2025, 3, 13, 20, 0, 0, 0, time.UTC), 4},
+		{"a full missed day ends the streak", time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := progress.CurrentDailyStreak(tt.now); got != tt.expected {
+				t.Errorf("Expected daily streak %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEvaluateAchievements(t *testing.T) {
+	now := time.Date(2025, 3, 12, 10, 0, 0, 0, time.UTC)
+	weekKey := "2025-03-10"
+
+	completed := models.AchievementEvent{
+		Type:       models.AchievementEventChoreCompleted,
+		UserID:     primitive.NewObjectID(),
+		GroupID:    primitive.NewObjectID(),
+		OccurredAt: now,
+	}
+	overdue := completed
+	overdue.Type = models.AchievementEventChoreOverdue
+
+	tests := []struct {
+		name     string
+		ctx      models.AchievementContext
+		earned   map[string]bool
+		expected []string
+	}{
+		{
+			name: "first completion",
+			ctx: models.AchievementContext{
+				Event:    completed,
+				Progress: models.MemberProgress{ChoresCompleted: 1, OnTimeStreak: 1, WeekActiveDays: 1},
+			},
+			expected: []string{models.AchievementFirstChore},
+		},
+		{
+			name: "first chore is not awarded twice",
+			ctx: models.AchievementContext{
+				Event:    completed,
+				Progress: models.MemberProgress{ChoresCompleted: 2, OnTimeStreak: 2, WeekActiveDays: 1},
+			},
+			earned:   map[string]bool{models.AchievementFirstChore: true},
+			expected: []string{},
+		},
+		{
+			name: "ten on time in a row",
+			ctx: models.AchievementContext{
+				Event:    completed,
+				Progress: models.MemberProgress{ChoresCompleted: 12, OnTimeStreak: 10, WeekActiveDays: 1},
+			},
+			earned:   map[string]bool{models.AchievementFirstChore: true},
+			expected: []string{models.AchievementOnTimeStreak10},
+		},
+		{
+			name: "fifty chores",
+			ctx: models.AchievementContext{
+				Event:    completed,
+				Progress: models.MemberProgress{ChoresCompleted: 50, WeekActiveDays: 1},
+			},
+			earned:   map[string]bool{models.AchievementFirstChore: true},
+			expected: []string{models.AchievementChores50},
+		},
+		{
+			name: "every day this week",
+			ctx: models.AchievementContext{
+				Event:    completed,
+				Progress: models.MemberProgress{ChoresCompleted: 7, WeekActiveDays: 7},
+			},
+			earned:   map[string]bool{models.AchievementFirstChore: true},
+			expected: []string{models.AchievementPerfectWeek},
+		},
+		{
+			name: "weekly achievement already earned this week",
+			ctx: models.AchievementContext{
+				Event:    completed,
+				Progress: models.MemberProgress{ChoresCompleted: 7, WeekActiveDays: 7},
+			},
+			earned: map[string]bool{
+				models.AchievementFirstChore:                                  true,
+				models.AchievementKey(models.AchievementPerfectWeek, weekKey): true,
+			},
+			expected: []string{},
+		},
+		{
+			name: "weekly achievement earned in an earlier week",
+			ctx: models.AchievementContext{
+				Event:    completed,
+				Progress: models.MemberProgress{ChoresCompleted: 7, WeekActiveDays: 7},
+			},
+			earned: map[string]bool{
+				models.AchievementFirstChore:                                       true,
+				models.AchievementKey(models.AchievementPerfectWeek, "2025-03-03"): true,
+			},
+			expected: []string{models.AchievementPerfectWeek},
+		},
+		{
+			name: "first to finish the week",
+			ctx: models.AchievementContext{
+				Event:             completed,
+				Progress:          models.MemberProgress{ChoresCompleted: 3, WeekActiveDays: 1},
+				FirstToFinishWeek: true,
+			},
+			earned:   map[string]bool{models.AchievementFirstChore: true},
+			expected: []string{models.AchievementFirstToFinishWeek},
+		},
+		{
+			name: "overdue events never award achievements",
+			ctx: models.AchievementContext{
+				Event:             overdue,
+				Progress:          models.MemberProgress{ChoresCompleted: 50, WeekActiveDays: 7},
+				FirstToFinishWeek: true,
+			},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			awarded := models.EvaluateAchievements(models.AchievementRules, tt.ctx, tt.earned)
+
+			if len(awarded) != len(tt.expected) {
+				t.Fatalf("Expected %d achievements, got %d: %+v", len(tt.expected), len(awarded), awarded)
+			}
+			for i, achievement := range awarded {
+				if achievement.AchievementID != tt.expected[i] {
+					t.Errorf("Expected achievement %s, got %s", tt.expected[i], achievement.AchievementID)
+				}
+				if achievement.UserID != tt.ctx.Event.UserID {
+					t.Errorf("Expected achievement to belong to the event user")
+				}
+			}
+		})
+	}
+}
+
+func TestAchievementPeriodKey(t *testing.T) {
+	sunday := time.Date(2025, 3, 16, 22, 0, 0, 0, time.UTC)
+
+	if key := models.AchievementPeriodKey(models.AchievementScopeWeekly, sunday); key != "2025-03-10" {
+		t.Errorf("Expected weekly key 2025-03-10, got %s", key)
+	}
+	if key := models.AchievementPeriodKey(models.AchievementScopeOnce, sunday); key != "" {
+		t.Errorf("Expected empty key for one-off achievements, got %s", key)
+	}
+}
+
+// applyPipeline runs the $set stages of an update pipeline over a stored document, evaluating
+// the expressions AchievementProgressUpdate uses the way MongoDB does
+func applyPipeline(t *testing.T, doc bson.M, pipeline bson.A) bson.M {
+	t.Helper()
+	for _, stage := range pipeline {
+		set, ok := stage.(bson.M)["$set"].(bson.M)
+		if !ok {
+			t.Fatalf("Expected a $set stage, got %v", stage)
+		}
+
+		// Every field of a stage is computed from the document as it was before the stage
+		next := bson.M{}
+		for field, value := range doc {
+			next[field] = value
+		}
+		for field, expr := range set {
+			if value := evalExpression(t, doc, expr); value != nil {
+				next[field] = value
+			} else {
+				delete(next, field)
+			}
+		}
+		doc = next
+	}
+	return doc
+}
+
+func evalExpression(t *testing.T, doc bson.M, expr interface{}) interface{} {
+	t.Helper()
+	if field, ok := expr.(string); ok && strings.HasPrefix(field, "$") {
+		return doc[strings.TrimPrefix(field, "$")]
+	}
+	operator, ok := expr.(bson.M)
+	if !ok {
+		return expr
+	}
+	if len(operator) != 1 {
+		t.Fatalf("Expected a single operator, got %v", operator)
+	}
+
+	for name, raw := range operator {
+		args := raw.(bson.A)
+		eval := func(i int) interface{} { return evalExpression(t, doc, args[i]) }
+		switch name {
+		case "$ifNull":
+			if value := eval(0); value != nil {
+				return value
+			}
+			return eval(1)
+		case "$add":
+			return eval(0).(int) + eval(1).(int)
+		case "$max":
+			if compareValues(eval(0), eval(1)) >= 0 {
+				return eval(0)
+			}
+			return eval(1)
+		case "$eq":
+			return compareValues(eval(0), eval(1)) == 0
+		case "$gt":
+			return compareValues(eval(0), eval(1)) > 0
+		case "$cond":
+			if eval(0).(bool) {
+				return eval(1)
+			}
+			return eval(2)
+		default:
+			t.Fatalf("Unexpected operator %s", name)
+		}
+	}
+	return nil
+}
+
+// compareValues orders null before any value, as MongoDB does
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	switch x := a.(type) {
+	case int:
+		return x - b.(int)
+	case time.Time:
+		return x.Compare(b.(time.Time))
+	}
+	return 0
+}
@@ -0,0 +1,139 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBucketStart(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("Time zone data not available: %v", err)
+	}
+
+	// Monday 2025-06-02 00:30 in London is still Sunday in UTC
+	ref := time.Date(2025, 6, 1, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		bucket   models.AnalyticsBucket
+		loc      *time.Location
+		expected time.Time
+	}{
+		{models.AnalyticsBucketDay, time.UTC, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{models.AnalyticsBucketDay, london, time.Date(2025, 6, 2, 0, 0, 0, 0, london)},
+		{models.AnalyticsBucketWeek, time.UTC, time.Date(2025, 5, 26, 0, 0, 0, 0, time.UTC)},
+		{models.AnalyticsBucketWeek, london, time.Date(2025, 6, 2, 0, 0, 0, 0, london)},
+		{models.AnalyticsBucketMonth, london, time.Date(2025, 6, 1, 0, 0, 0, 0, london)},
+	}
+
+	for _, tt := range tests {
+		got := models.BucketStart(tt.bucket, ref, tt.loc)
+		if !got.Equal(tt.expected) {
+			t.Errorf("%s in %s: expected %v, got %v", tt.bucket, tt.loc, tt.expected, got)
+		}
+	}
+}
+
+func TestAnalyticsWindow(t *testing.T) {
+	now := time.Date(2025, 6, 4, 15, 0, 0, 0, time.UTC) // Wednesday
+
+	start, end, err := models.AnalyticsWindow(models.AnalyticsBucketWeek, time.Time{}, now, time.UTC)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !end.Equal(time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected window to end after the current week, got %v", end)
+	}
+	if !start.Equal(end.AddDate(0, 0, -7*12)) {
+		t.Errorf("Expected 12 weeks by default, got %v", start)
+	}
+
+	start, end, err = models.AnalyticsWindow(models.AnalyticsBucketMonth, time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), now, time.UTC)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !start.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected January to June, got %v to %v", start, end)
+	}
+
+	if _, _, err := models.AnalyticsWindow(models.AnalyticsBucketDay, now, now.AddDate(0, 0, -3), time.UTC); err == nil {
+		t.Errorf("Expected an error when to is before from")
+	}
+	if _, _, err := models.AnalyticsWindow(models.AnalyticsBucketDay, now.AddDate(-2, 0, 0), now, time.UTC); err == nil {
+		t.Errorf("Expected an error for more than %d days", models.MaxAnalyticsBuckets)
+	}
+}
+
+func TestFillTrend(t *testing.T) {
+	from := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 21)
+
+	due := []models.AnalyticsTrendPoint{{PeriodStart: from.AddDate(0, 0, 7), Due: 4, CompletedOnTime: 3}}
+	completed := []models.AnalyticsTrendPoint{
+		{PeriodStart: from.AddDate(0, 0, 7), Completed: 3, Points: 9},
+		{PeriodStart: from.AddDate(0, 0, -7), Completed: 1, Points: 2}, // Outside the window
+	}
+
+	trend := models.FillTrend(models.AnalyticsBucketWeek, from, to, due, completed)
+	if len(trend) != 3 {
+		t.Fatalf("Expected 3 weeks, got %d", len(trend))
+	}
+	if trend[0].Due != 0 || trend[0].Completed != 0 {
+		t.Errorf("Expected an empty first week, got %+v", trend[0])
+	}
+	if trend[1].Due != 4 || trend[1].CompletedOnTime != 3 || trend[1].Completed != 3 || trend[1].Points != 9 {
+		t.Errorf("Expected merged second week, got %+v", trend[1])
+	}
+}
+
+func TestFinalizeMemberAnalytics(t *testing.T) {
+	alice := primitive.NewObjectID()
+	bob := primitive.NewObjectID()
+
+	members := []models.MemberAnalytics{
+		{UserID: bob, Name: "Bob", Assigned: 4, Completed: 2, CompletedOnTime: 1, Missed: 2, LatenessTotalMs: int64(6 * time.Hour / time.Millisecond)},
+		{UserID: alice, Name: "Alice", Assigned: 4, Completed: 4, CompletedOnTime: 4},
+	}
+	points := map[primitive.ObjectID]int{alice: 30, bob: 10}
+
+	members, summary := models.FinalizeMemberAnalytics(members, points)
+
+	if members[0].UserID != alice {
+		t.Errorf("Expected Alice first by completion rate")
+	}
+	if members[0].CompletionRate != 1 || members[0].PointsShare != 0.75 {
+		t.Errorf("Unexpected stats for Alice: %+v", members[0])
+	}
+	if members[1].CompletionRate != 0.5 || members[1].OnTimeRate != 0.5 || members[1].AverageLatenessHours != 6 {
+		t.Errorf("Unexpected stats for Bob: %+v", members[1])
+	}
+	if summary.Assigned != 8 || summary.Completed != 6 || summary.Points != 40 || summary.Missed != 2 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if summary.CompletionRate != 0.75 || summary.OnTimeRate != 0.833 {
+		t.Errorf("Expected rates 0.75 and 0.833, got %v and %v", summary.CompletionRate, summary.OnTimeRate)
+	}
+}
+
+func TestFinalizeSkippedChores(t *testing.T) {
+	chores := []models.SkippedRecurringChore{
+		{Title: "Bins", Instances: 4, Skipped: 1},
+		{Title: "Hoover", Instances: 4, Skipped: 3},
+		{Title: "Dishes", Instances: 10, Skipped: 3},
+		{Title: "Plants", Instances: 2, Skipped: 0},
+	}
+
+	result := models.FinalizeSkippedChores(chores, 2)
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 chores, got %d", len(result))
+	}
+	if result[0].Title != "Hoover" || result[0].SkipRate != 0.75 {
+		t.Errorf("Expected Hoover first with a 0.75 skip rate, got %+v", result[0])
+	}
+	if result[1].Title != "Dishes" {
+		t.Errorf("Expected Dishes second, got %s", result[1].Title)
+	}
+}
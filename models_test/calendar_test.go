@@ -0,0 +1,182 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestProjectRecurringChore(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+
+	rc := models.RecurringChore{
+		ID:             primitive.NewObjectID(),
+		Title:          "Take out trash",
+		MemberRotation: []primitive.ObjectID{alice, bob},
+		CurrentIndex:   1,
+		Frequency:      "weekly",
+		Points:         5,
+		NextAssignment: time.Date(2025, 3, 11, 9, 0, 0, 0, time.UTC),
+		IsActive:       true,
+	}
+
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 4, 9, 0, 0, 0, 0, time.UTC)
+	events := models.ProjectRecurringChore(rc, from, to, now)
+
+	expectedDue := []time.Time{
+		time.Date(2025, 3, 18, 23, 59, 0, 0, time.UTC),
+		time.Date(2025, 3, 25, 23, 59, 0, 0, time.UTC),
+		time.Date(2025, 4, 1, 23, 59, 0, 0, time.UTC),
+		time.Date(2025, 4, 8, 23, 59, 0, 0, time.UTC),
+	}
+	expectedAssignees := []primitive.ObjectID{bob, alice, bob, alice}
+
+	if len(events) != len(expectedDue) {
+		t.Fatalf("Expected %d projected occurrences, got %d", len(expectedDue), len(events))
+	}
+	for i, event := range events {
+		if !event.DueDate.Equal(expectedDue[i]) {
+			t.Errorf("Occurrence %d: expected due %v, got %v", i, expectedDue[i], event.DueDate)
+		}
+		if event.AssignedTo != expectedAssignees[i] {
+			t.Errorf("Occurrence %d: expected rotation to continue from the current index", i)
+		}
+		if !event.Projected || event.ChoreID != nil {
+			t.Errorf("Occurrence %d: expected a projected event without a chore ID", i)
+		}
+		if event.UID != models.RecurringCalendarUID(rc.ID, expectedDue[i]) {
+			t.Errorf("Occurrence %d: unexpected UID %s", i, event.UID)
+		}
+	}
+
+	if rc.CurrentIndex != 1 {
+		t.Errorf("Expected projection not to modify the recurring chore")
+	}
+
+	// Projecting again yields the same UIDs so calendar clients update rather than duplicate
+	again := models.ProjectRecurringChore(rc, from, to, now.Add(time.Hour))
+	for i := range again {
+		if again[i].UID != events[i].UID {
+			t.Errorf("Expected stable UID for occurrence %d", i)
+		}
+	}
+
+	rc.IsActive = false
+	if len(models.ProjectRecurringChore(rc, from, to, now)) != 0 {
+		t.Errorf("Expected no occurrences for an inactive recurring chore")
+	}
+}
+
+func TestProjectRecurringChoreOverdueAssignment(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	rc := models.RecurringChore{
+		ID:             primitive.NewObjectID(),
+		Frequency:      "daily",
+		NextAssignment: now.AddDate(0, 0, -3), // Scheduler has not run yet
+		IsActive:       true,
+	}
+
+	events := models.ProjectRecurringChore(rc, now.AddDate(0, 0, -7), now.AddDate(0, 0, 2), now)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 occurrences, got %d", len(events))
+	}
+	if events[0].DueDate.Day() != 10 || events[1].DueDate.Day() != 11 {
+		t.Errorf("Expected projection to start from now, got %v and %v", events[0].DueDate, events[1].DueDate)
+	}
+}
+
+func TestChoreCalendarEventKeepsProjectedUID(t *testing.T) {
+	rc := &models.RecurringChore{
+		ID:             primitive.NewObjectID(),
+		Frequency:      "daily",
+		MemberRotation: []primitive.ObjectID{primitive.NewObjectID()},
+		IsActive:       true,
+	}
+
+	chore := models.CreateChoreFromRecurring(rc)
+	chore.ID = primitive.NewObjectID()
+	projectedUID := models.RecurringCalendarUID(rc.ID, chore.DueDate)
+
+	if uid := models.ChoreCalendarEvent(chore).UID; uid != projectedUID {
+		t.Errorf("Expected the created chore to keep its projected UID %s, got %s", projectedUID, uid)
+	}
+
+	// Rescheduling the chore keeps the event
+	chore.DueDate = chore.DueDate.AddDate(0, 0, 2)
+	if uid := models.ChoreCalendarEvent(chore).UID; uid != projectedUID {
+		t.Errorf("Expected a rescheduled chore to keep UID %s, got %s", projectedUID, uid)
+	}
+
+	// Chores stored before the UID was kept derive it from their due date
+	chore.CalendarUID = ""
+	if uid := models.ChoreCalendarEvent(chore).UID; uid != models.RecurringCalendarUID(rc.ID, chore.DueDate) {
+		t.Errorf("Expected a UID derived from the recurring chore and due date, got %s", uid)
+	}
+}
+
+func TestMarshalICalendar(t *testing.T) {
+	stamp := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	chore := &models.Chore{
+		ID:          primitive.NewObjectID(),
+		Title:       "Clean kitchen; wipe counters, mop",
+		Description: strings.Repeat("Scrub the sink thoroughly. ", 5) + "\nThen mop",
+		Status:      models.ChoreStatusPending,
+		Points:      10,
+		DueDate:     time.Date(2025, 3, 12, 23, 59, 0, 0, time.UTC),
+		UpdatedAt:   stamp,
+	}
+
+	ics := models.MarshalICalendar("Cribb chores", []models.CalendarEvent{models.ChoreCalendarEvent(chore)}, stamp)
+
+	if !strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(ics, "END:VCALENDAR\r\n") {
+		t.Errorf("Expected a CRLF-delimited VCALENDAR")
+	}
+
+	for _, expected := range []string{
+		"UID:chore-" + chore.ID.Hex() + "@cribb\r\n",
+		"DTSTAMP:20250310T090000Z\r\n",
+		"DTSTART;VALUE=DATE:20250312\r\n",
+		"DTEND;VALUE=DATE:20250313\r\n",
+		`SUMMARY:Clean kitchen\; wipe counters\, mop` + "\r\n",
+		"STATUS:CONFIRMED\r\n",
+	} {
+		if !strings.Contains(ics, expected) {
+			t.Errorf("Expected output to contain %q", expected)
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Expected lines to be folded at 75 octets, got %d: %q", len(line), line)
+		}
+	}
+
+	// Unfolding must restore the escaped description
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	if !strings.Contains(unfolded, `DESCRIPTION:`+models.EscapeICalText(chore.Description+"\nPoints: 10")+"\r\n") {
+		t.Errorf("Expected folded description to unfold to the original text")
+	}
+}
+
+func TestEscapeICalText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"a,b;c", `a\,b\;c`},
+		{`back\slash`, `back\\slash`},
+		{"line\r\nbreak", `line\nbreak`},
+	}
+
+	for _, tt := range tests {
+		if got := models.EscapeICalText(tt.input); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{"no mentions", "Remember to buy bin bags", []string{}},
+		{"single mention", "@alice can you take this one?", []string{"alice"}},
+		{"mention mid sentence", "Thanks @Bob for the help", []string{"bob"}},
+		{"trailing punctuation", "Ask @carol.", []string{"carol"}},
+		{"email username", "cc @dave@example.com, please", []string{"dave@example.com"}},
+		{"duplicates removed", "@erin @ERIN and @frank", []string{"erin", "frank"}},
+		{"in parentheses", "(see @gina)", []string{"gina"}},
+		{"email address is not a mention", "mail me at henry@example.com", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := models.ParseMentions(tt.body)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestChoreCommentCanBeEditedBy(t *testing.T) {
+	authorID := primitive.NewObjectID()
+	chore := &models.Chore{ID: primitive.NewObjectID(), GroupID: primitive.NewObjectID(), Title: "Take out trash"}
+	comment := models.CreateChoreComment(chore, authorID, "alice", "Done soon", nil)
+
+	if comment.ChoreID == nil || *comment.ChoreID != chore.ID {
+		t.Errorf("Expected comment to reference the chore")
+	}
+	if comment.RecurringChoreID != nil {
+		t.Errorf("Expected recurring chore ID to be unset")
+	}
+	if comment.Mentions == nil {
+		t.Errorf("Expected mentions to be an empty slice, got nil")
+	}
+	if !comment.CanBeEditedBy(authorID) {
+		t.Errorf("Expected the author to be able to edit the comment")
+	}
+	if comment.CanBeEditedBy(primitive.NewObjectID()) {
+		t.Errorf("Expected other members not to be able to edit the comment")
+	}
+}
+
+func TestCreateCommentNotifications(t *testing.T) {
+	authorID := primitive.NewObjectID()
+	mentionedID := primitive.NewObjectID()
+	otherID := primitive.NewObjectID()
+
+	recurringChore := &models.RecurringChore{ID: primitive.NewObjectID(), GroupID: primitive.NewObjectID(), Title: "Vacuum"}
+	comment := models.CreateRecurringChoreComment(recurringChore, authorID, "alice", "@bob @alice", []primitive.ObjectID{mentionedID, authorID})
+	comment.ID = primitive.NewObjectID()
+
+	notifications := models.CreateCommentNotifications(comment)
+	if len(notifications) != 2 {
+		t.Fatalf("Expected 2 notifications, got %d", len(notifications))
+	}
+
+	groupWide := notifications[0]
+	if groupWide.Type != models.NotificationTypeChoreComment || groupWide.RecipientID != nil {
+		t.Errorf("Expected the first notification to be group-wide")
+	}
+	if !groupWide.IsVisibleTo(otherID) {
+		t.Errorf("Expected group-wide notification to be visible to every member")
+	}
+	if !groupWide.IsReadBy(authorID, models.NotificationSettings{}, false) || groupWide.IsReadBy(otherID, models.NotificationSettings{}, false) {
+		t.Errorf("Expected only the author to have read the notification")
+	}
+
+	mention := notifications[1]
+	if mention.Type != models.NotificationTypeChoreMention {
+		t.Errorf("Expected mention notification, got %s", mention.Type)
+	}
+	if mention.RecipientID == nil || *mention.RecipientID != mentionedID {
+		t.Errorf("Expected mention to be addressed to the mentioned member")
+	}
+	if mention.IsVisibleTo(otherID) {
+		t.Errorf("Expected mention not to be visible to other members")
+	}
+	if mention.Chore == nil || mention.Chore.CommentID == nil || *mention.Chore.CommentID != comment.ID {
+		t.Errorf("Expected mention to reference the comment")
+	}
+	if mention.Chore.RecurringChoreID == nil || *mention.Chore.RecurringChoreID != recurringChore.ID {
+		t.Errorf("Expected mention to reference the recurring chore")
+	}
+}
@@ -0,0 +1,238 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const rotaICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//Rota//EN\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:bins@example.com\r\n" +
+	"SUMMARY:Take out bins\r\n" +
+	"DTSTART;VALUE=DATE:20250303\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO\r\n" +
+	"ATTENDEE;CN=Alice Smith:mailto:alice@example.com\r\n" +
+	"ATTENDEE;CN=\"Bob\":mailto:bob@example.com\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:oven@example.com\r\n" +
+	"SUMMARY:Clean oven - Carol\r\n" +
+	"DESCRIPTION:Use the spray\\, not the cream\r\n" +
+	" \\nRinse well\r\n" +
+	"DTSTART;TZID=Europe/London:20250320T190000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:old@example.com\r\n" +
+	"SUMMARY:Defrost freezer\r\n" +
+	"DTSTART:20250101T100000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICalendar(t *testing.T) {
+	events, errs := models.ParseICalendar(strings.NewReader(rotaICS))
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+
+	bins := events[0]
+	if bins.Line != 4 || bins.UID != "bins@example.com" || !bins.AllDay {
+		t.Errorf("Unexpected first event: %+v", bins)
+	}
+	if bins.RRule == nil || bins.RRule.Freq != "WEEKLY" || bins.RRule.Interval != 1 {
+		t.Errorf("Expected weekly RRULE, got %+v", bins.RRule)
+	}
+	if len(bins.Attendees) != 2 || bins.Attendees[0].Email != "alice@example.com" || bins.Attendees[1].Name != "Bob" {
+		t.Errorf("Unexpected attendees: %+v", bins.Attendees)
+	}
+
+	oven := events[1]
+	if oven.Description != "Use the spray, not the cream\nRinse well" {
+		t.Errorf("Expected unfolded and unescaped description, got %q", oven.Description)
+	}
+	if oven.Start.Location().String() != "Europe/London" || oven.Start.Hour() != 19 {
+		t.Errorf("Expected start in Europe/London at 19:00, got %v", oven.Start)
+	}
+}
+
+func TestParseICalendarErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		ics          string
+		expectedLine int
+		contains     string
+	}{
+		{
+			name:         "missing colon",
+			ics:          "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY Bins\nDTSTART:20250301\nEND:VEVENT\nEND:VCALENDAR\n",
+			expectedLine: 3,
+			contains:     "NAME:VALUE",
+		},
+		{
+			name:         "bad DTSTART",
+			ics:          "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Bins\nDTSTART:2025-03-01\nEND:VEVENT\nEND:VCALENDAR\n",
+			expectedLine: 4,
+			contains:     "DTSTART",
+		},
+		{
+			name:         "bad RRULE",
+			ics:          "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Bins\nDTSTART:20250301\nRRULE:FREQ=FORTNIGHTLY\nEND:VEVENT\nEND:VCALENDAR\n",
+			expectedLine: 5,
+			contains:     "FREQ",
+		},
+		{
+			name:         "mismatched END",
+			ics:          "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Bins\nDTSTART:20250301\nEND:VTODO\nEND:VEVENT\nEND:VCALENDAR\n",
+			expectedLine: 5,
+			contains:     "does not match",
+		},
+		{
+			name:         "unclosed event",
+			ics:          "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Bins\nDTSTART:20250301\n",
+			expectedLine: 2,
+			contains:     "never closed",
+		},
+		{
+			name:         "missing summary",
+			ics:          "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20250301\nEND:VEVENT\nEND:VCALENDAR\n",
+			expectedLine: 2,
+			contains:     "SUMMARY",
+		},
+		{
+			name:         "not a calendar",
+			ics:          "hello: world\n",
+			expectedLine: 1,
+			contains:     "outside",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := models.ParseICalendar(strings.NewReader(tt.ics))
+			if len(errs) == 0 {
+				t.Fatalf("Expected a validation error")
+			}
+			if errs[0].Line != tt.expectedLine {
+				t.Errorf("Expected error on line %d, got %d (%s)", tt.expectedLine, errs[0].Line, errs[0].Message)
+			}
+			if !strings.Contains(errs[0].Message, tt.contains) {
+				t.Errorf("Expected error to mention %q, got %q", tt.contains, errs[0].Message)
+			}
+		})
+	}
+}
+
+func TestICalRecurrenceChoreFrequency(t *testing.T) {
+	tests := []struct {
+		rule      models.ICalRecurrence
+		expected  string
+		exactness bool
+	}{
+		{models.ICalRecurrence{Freq: "DAILY", Interval: 1}, "daily", true},
+		{models.ICalRecurrence{Freq: "WEEKLY", Interval: 1}, "weekly", true},
+		{models.ICalRecurrence{Freq: "WEEKLY", Interval: 2}, "biweekly", true},
+		{models.ICalRecurrence{Freq: "MONTHLY", Interval: 1}, "monthly", true},
+		{models.ICalRecurrence{Freq: "WEEKLY", Interval: 1, ByDay: []string{"MO", "TH"}}, "weekly", false},
+		{models.ICalRecurrence{Freq: "DAILY", Interval: 5}, "weekly", false},
+		{models.ICalRecurrence{Freq: "YEARLY", Interval: 1}, "monthly", false},
+	}
+
+	for _, tt := range tests {
+		frequency, exact := tt.rule.ChoreFrequency()
+		if frequency != tt.expected || exact != tt.exactness {
+			t.Errorf("%+v: expected %s (exact %v), got %s (exact %v)", tt.rule, tt.expected, tt.exactness, frequency, exact)
+		}
+	}
+}
+
+func TestBuildChoreImportItems(t *testing.T) {
+	events, errs := models.ParseICalendar(strings.NewReader(rotaICS))
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	alice := models.User{ID: primitive.NewObjectID(), Username: "alice@example.com", Name: "Alice Smith"}
+	bob := models.User{ID: primitive.NewObjectID(), Username: "bobby", Name: "Bob"}
+	carol := models.User{ID: primitive.NewObjectID(), Username: "carol", Name: "Carol"}
+	members := []models.User{alice, bob, carol}
+
+	now := time.Date(2025, 3, 12, 10, 0, 0, 0, time.UTC) // Wednesday
+	items := models.BuildChoreImportItems(events, members, alice, 3, now)
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %d", len(items))
+	}
+
+	bins := items[0]
+	if bins.Kind != models.ChoreImportKindRecurring || bins.Frequency != "weekly" {
+		t.Errorf("Expected weekly recurring chore, got %s %s", bins.Kind, bins.Frequency)
+	}
+	if !bins.DueDate.Equal(time.Date(2025, 3, 17, 23, 59, 0, 0, time.UTC)) {
+		t.Errorf("Expected first due date on the next Monday, got %v", bins.DueDate)
+	}
+	// The scheduler must create the following instance so that it falls due on the Monday after
+	if got := models.ProjectedDueDate(bins.Frequency, bins.NextAssignment); !got.Equal(time.Date(2025, 3, 24, 23, 59, 0, 0, time.UTC)) {
+		t.Errorf("Expected next instance due 2025-03-24, got %v", got)
+	}
+	if bins.AssignmentSource != models.ChoreImportAssignedByAttendee || len(bins.MemberRotation) != 2 ||
+		bins.MemberRotation[0] != alice.ID || bins.MemberRotation[1] != bob.ID {
+		t.Errorf("Expected rotation of the attendees, got %+v", bins.MemberRotation)
+	}
+	if bins.Points != 3 {
+		t.Errorf("Expected 3 points, got %d", bins.Points)
+	}
+
+	oven := items[1]
+	if oven.Kind != models.ChoreImportKindIndividual || oven.AssignedTo != carol.ID || oven.AssignmentSource != models.ChoreImportAssignedBySummary {
+		t.Errorf("Expected individual chore assigned to Carol from the summary, got %+v", oven)
+	}
+	if !oven.DueDate.Equal(time.Date(2025, 3, 20, 23, 59, 0, 0, time.UTC)) {
+		t.Errorf("Expected due at end of 2025-03-20, got %v", oven.DueDate)
+	}
+
+	if !items[2].Skipped {
+		t.Errorf("Expected past event to be skipped")
+	}
+
+	// Explicit mapping overrides the matched assignee
+	oven.AssignTo([]models.User{bob}, models.ChoreImportAssignedByMapping, alice)
+	if oven.AssignedTo != bob.ID || oven.AssignmentSource != models.ChoreImportAssignedByMapping {
+		t.Errorf("Expected mapping to assign Bob, got %+v", oven)
+	}
+}
+
+func TestBuildChoreImportItemsDefaultAssignment(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:a\nSUMMARY:Water plants\nDTSTART:20250401\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:b\nSUMMARY:Hoover\nDTSTART:20250401\nRRULE:FREQ=DAILY;INTERVAL=3;COUNT=10\nEND:VEVENT\nEND:VCALENDAR\n"
+	events, errs := models.ParseICalendar(strings.NewReader(ics))
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	importer := models.User{ID: primitive.NewObjectID(), Username: "dana"}
+	other := models.User{ID: primitive.NewObjectID(), Username: "eve"}
+	items := models.BuildChoreImportItems(events, []models.User{importer, other}, importer, 0, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	if items[0].AssignedTo != importer.ID || items[0].AssignmentSource != models.ChoreImportAssignedByDefault {
+		t.Errorf("Expected unmatched chore to be assigned to the importer")
+	}
+	if items[0].Points != 1 {
+		t.Errorf("Expected points to default to 1, got %d", items[0].Points)
+	}
+	if items[1].AssignmentSource != models.ChoreImportAssignedByRotation || len(items[1].MemberRotation) != 2 {
+		t.Errorf("Expected unmatched recurring chore to rotate over the group")
+	}
+	if len(items[1].Warnings) != 2 {
+		t.Errorf("Expected warnings for the approximate frequency and the COUNT, got %v", items[1].Warnings)
+	}
+}
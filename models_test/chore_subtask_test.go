@@ -0,0 +1,190 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBuildSubtasks(t *testing.T) {
+	subtasks, err := models.BuildSubtasks([]models.SubtaskSpec{
+		{Title: "Scrub toilet", Required: true},
+		{Title: "  Clean mirror  "},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(subtasks) != 2 {
+		t.Fatalf("Expected 2 subtasks, got %d", len(subtasks))
+	}
+	if subtasks[0].Position != 0 || subtasks[1].Position != 1 {
+		t.Errorf("Expected positions 0 and 1, got %d and %d", subtasks[0].Position, subtasks[1].Position)
+	}
+	if subtasks[1].Title != "Clean mirror" {
+		t.Errorf("Expected trimmed title, got %q", subtasks[1].Title)
+	}
+	if !subtasks[0].Required || subtasks[1].Required {
+		t.Errorf("Expected only the first subtask to be required")
+	}
+
+	// Reorder and keep progress of the existing subtask
+	userID := primitive.NewObjectID()
+	doneAt := time.Now()
+	subtasks[1].Done = true
+	subtasks[1].DoneBy = &userID
+	subtasks[1].DoneAt = &doneAt
+
+	reordered, err := models.BuildSubtasks([]models.SubtaskSpec{
+		{ID: subtasks[1].ID.Hex(), Title: "Clean mirror"},
+		{Title: "Empty bin"},
+	}, subtasks)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reordered[0].ID != subtasks[1].ID || reordered[0].Position != 0 {
+		t.Errorf("Expected existing subtask to move to position 0")
+	}
+	if !reordered[0].Done || reordered[0].DoneBy == nil || *reordered[0].DoneBy != userID {
+		t.Errorf("Expected existing subtask to keep its completion state")
+	}
+	if reordered[1].Done {
+		t.Errorf("Expected new subtask to be open")
+	}
+
+	errorCases := []struct {
+		name  string
+		specs []models.SubtaskSpec
+	}{
+		{"empty title", []models.SubtaskSpec{{Title: "   "}}},
+		{"invalid ID", []models.SubtaskSpec{{ID: "not-an-id", Title: "A"}}},
+		{"unknown ID", []models.SubtaskSpec{{ID: primitive.NewObjectID().Hex(), Title: "A"}}},
+		{"duplicate ID", []models.SubtaskSpec{
+			{ID: subtasks[0].ID.Hex(), Title: "A"},
+			{ID: subtasks[0].ID.Hex(), Title: "B"},
+		}},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := models.BuildSubtasks(tc.specs, subtasks); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestChoreEarnedPoints(t *testing.T) {
+	subtasks := func(done ...bool) []models.Subtask {
+		result := make([]models.Subtask, len(done))
+		for i, d := range done {
+			result[i] = models.Subtask{ID: primitive.NewObjectID(), Title: "Step", Done: d}
+		}
+		return result
+	}
+
+	tests := []struct {
+		name          string
+		points        int
+		partialPoints bool
+		subtasks      []models.Subtask
+		expected      int
+	}{
+		{"no subtasks", 10, true, nil, 10},
+		{"partial points disabled", 10, false, subtasks(true, false), 10},
+		{"all done", 10, true, subtasks(true, true, true), 10},
+		{"none done", 10, true, subtasks(false, false), 0},
+		{"half done", 10, true, subtasks(true, false), 5},
+		{"rounds to nearest", 10, true, subtasks(true, true, false), 7},
+		{"rounds down below half", 10, true, subtasks(true, false, false), 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chore := models.Chore{Points: tt.points, PartialPoints: tt.partialPoints, Subtasks: tt.subtasks}
+			if got := chore.EarnedPoints(); got != tt.expected {
+				t.Errorf("Expected %d points, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestChoreOpenRequiredSubtasks(t *testing.T) {
+	chore := models.Chore{
+		Subtasks: []models.Subtask{
+			{ID: primitive.NewObjectID(), Title: "Required done", Required: true, Done: true},
+			{ID: primitive.NewObjectID(), Title: "Required open", Required: true},
+			{ID: primitive.NewObjectID(), Title: "Optional open"},
+		},
+	}
+
+	open := chore.OpenRequiredSubtasks()
+	if len(open) != 1 || open[0].Title != "Required open" {
+		t.Errorf("Expected only the open required subtask, got %+v", open)
+	}
+
+	if chore.EffectiveSubtaskPolicy() != models.SubtaskPolicyBlock {
+		t.Errorf("Expected default policy to be block, got %s", chore.EffectiveSubtaskPolicy())
+	}
+	chore.SubtaskPolicy = models.SubtaskPolicyWarn
+	if chore.EffectiveSubtaskPolicy() != models.SubtaskPolicyWarn {
+		t.Errorf("Expected policy warn, got %s", chore.EffectiveSubtaskPolicy())
+	}
+
+	if chore.FindSubtask(chore.Subtasks[2].ID) != 2 {
+		t.Errorf("Expected to find subtask at index 2")
+	}
+	if chore.FindSubtask(primitive.NewObjectID()) != -1 {
+		t.Errorf("Expected unknown subtask to return -1")
+	}
+}
+
+func TestCreateChoreFromRecurringCopiesSubtasks(t *testing.T) {
+	userID := primitive.NewObjectID()
+	doneAt := time.Now()
+	recurringChore := models.CreateRecurringChore(
+		"Clean Bathroom",
+		"Weekly bathroom clean",
+		primitive.NewObjectID(),
+		[]primitive.ObjectID{userID},
+		"weekly",
+		10,
+	)
+	recurringChore.Subtasks = []models.Subtask{
+		{ID: primitive.NewObjectID(), Title: "Scrub toilet", Required: true, Position: 0, Done: true, DoneBy: &userID, DoneAt: &doneAt},
+		{ID: primitive.NewObjectID(), Title: "Clean mirror", Position: 1},
+	}
+	recurringChore.SubtaskPolicy = models.SubtaskPolicyWarn
+	recurringChore.PartialPoints = true
+
+	instances := []*models.Chore{
+		models.CreateChoreFromRecurring(recurringChore),
+		models.CreateChoreFromRecurringWithBaseDate(recurringChore, time.Now().Add(48*time.Hour)),
+	}
+
+	for _, chore := range instances {
+		if len(chore.Subtasks) != 2 {
+			t.Fatalf("Expected 2 subtasks, got %d", len(chore.Subtasks))
+		}
+		for i, subtask := range chore.Subtasks {
+			if subtask.Title != recurringChore.Subtasks[i].Title || subtask.Position != i {
+				t.Errorf("Expected subtask %d to keep title and order", i)
+			}
+			if subtask.Done || subtask.DoneBy != nil || subtask.DoneAt != nil {
+				t.Errorf("Expected subtask %d to be open on the new instance", i)
+			}
+		}
+		if !chore.Subtasks[0].Required {
+			t.Errorf("Expected required flag to be copied")
+		}
+		if chore.SubtaskPolicy != models.SubtaskPolicyWarn || !chore.PartialPoints {
+			t.Errorf("Expected subtask policy and partial points to be copied")
+		}
+	}
+
+	// Ticking an instance must not affect the recurring definition
+	instances[0].Subtasks[1].Done = true
+	if recurringChore.Subtasks[1].Done {
+		t.Errorf("Expected recurring chore subtasks to be independent of instances")
+	}
+}
@@ -0,0 +1,110 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCreateChoreTemplates(t *testing.T) {
+	predefined := models.CreatePredefinedChoreTemplate("Clean Bathroom", "Scrub everything", "weekly", 15)
+	if !predefined.IsPredefined() || predefined.IsCustom() {
+		t.Errorf("Expected predefined template type, got %s", predefined.Type)
+	}
+	if predefined.GroupID != nil || predefined.CreatedBy != nil {
+		t.Errorf("Expected predefined template to have no group or creator")
+	}
+	if !predefined.IsActive {
+		t.Errorf("Expected new template to be active")
+	}
+
+	groupID := primitive.NewObjectID()
+	userID := primitive.NewObjectID()
+	custom := models.CreateCustomChoreTemplate("Feed Cat", "", "daily", 2, groupID, userID)
+	if !custom.IsCustom() {
+		t.Errorf("Expected custom template type, got %s", custom.Type)
+	}
+	if custom.GroupID == nil || *custom.GroupID != groupID {
+		t.Errorf("Expected custom template to belong to group %s", groupID.Hex())
+	}
+	if custom.CreatedBy == nil || *custom.CreatedBy != userID {
+		t.Errorf("Expected custom template to be created by %s", userID.Hex())
+	}
+}
+
+func TestChoreTemplatePermissions(t *testing.T) {
+	groupID := primitive.NewObjectID()
+	otherGroupID := primitive.NewObjectID()
+	userID := primitive.NewObjectID()
+
+	predefined := models.CreatePredefinedChoreTemplate("Take Out Trash", "", "weekly", 5)
+	custom := models.CreateCustomChoreTemplate("Feed Cat", "", "daily", 2, groupID, userID)
+
+	tests := []struct {
+		name              string
+		template          *models.ChoreTemplate
+		groupID           primitive.ObjectID
+		expectedBelongs   bool
+		expectedDeletable bool
+	}{
+		{"predefined in any group", predefined, groupID, true, false},
+		{"custom in own group", custom, groupID, true, true},
+		{"custom in other group", custom, otherGroupID, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.template.BelongsToGroup(tt.groupID); got != tt.expectedBelongs {
+				t.Errorf("Expected BelongsToGroup %v, got %v", tt.expectedBelongs, got)
+			}
+			if got := tt.template.CanBeDeletedBy(userID, tt.groupID); got != tt.expectedDeletable {
+				t.Errorf("Expected CanBeDeletedBy %v, got %v", tt.expectedDeletable, got)
+			}
+		})
+	}
+}
+
+func TestIsValidChoreFrequency(t *testing.T) {
+	for _, frequency := range []string{"daily", "weekly", "biweekly", "monthly"} {
+		if !models.IsValidChoreFrequency(frequency) {
+			t.Errorf("Expected %s to be a valid frequency", frequency)
+		}
+	}
+	for _, frequency := range []string{"", "yearly", "Weekly"} {
+		if models.IsValidChoreFrequency(frequency) {
+			t.Errorf("Expected %q to be an invalid frequency", frequency)
+		}
+	}
+}
+
+func TestChoreTemplateToRecurringChore(t *testing.T) {
+	groupID := primitive.NewObjectID()
+	members := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID()}
+	template := models.CreatePredefinedChoreTemplate("Mop Floors", "Kitchen and bathroom", "biweekly", 10)
+
+	before := time.Now()
+	recurringChore := template.ToRecurringChore(groupID, members)
+
+	if recurringChore.Title != template.Title || recurringChore.Description != template.Description {
+		t.Errorf("Expected recurring chore to copy title and description from the template")
+	}
+	if recurringChore.Points != template.Points || recurringChore.Frequency != template.Frequency {
+		t.Errorf("Expected recurring chore to copy points and frequency from the template")
+	}
+	if recurringChore.GroupID != groupID {
+		t.Errorf("Expected recurring chore to belong to group %s", groupID.Hex())
+	}
+	if len(recurringChore.MemberRotation) != len(members) {
+		t.Errorf("Expected rotation of %d members, got %d", len(members), len(recurringChore.MemberRotation))
+	}
+	if !recurringChore.IsActive {
+		t.Errorf("Expected recurring chore to be active")
+	}
+
+	expectedNext := before.Add(14 * 24 * time.Hour)
+	if diff := recurringChore.NextAssignment.Sub(expectedNext); diff < 0 || diff > time.Minute {
+		t.Errorf("Expected next assignment around %v, got %v", expectedNext, recurringChore.NextAssignment)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -205,3 +206,42 @@ func TestCreateChoreFromRecurring(t *testing.T) {
 		t.Errorf("Expected due date around %v, got %v (diff: %v)", expectedDueDate, chore.DueDate, timeDiff)
 	}
 }
+
+func TestChoreCanBeArchived(t *testing.T) {
+	cutoff := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	before := cutoff.Add(-time.Hour)
+	after := cutoff.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		chore    models.Chore
+		expected bool
+	}{
+		{"completed before cutoff", models.Chore{Status: models.ChoreStatusCompleted, CompletedAt: &before}, true},
+		{"completed after cutoff", models.Chore{Status: models.ChoreStatusCompleted, CompletedAt: &after}, false},
+		{"pending", models.Chore{Status: models.ChoreStatusPending, UpdatedAt: before}, false},
+		{"already archived", models.Chore{Status: models.ChoreStatusCompleted, CompletedAt: &before, ArchivedAt: &before}, false},
+		{"restored after cutoff", models.Chore{Status: models.ChoreStatusCompleted, CompletedAt: &before, RestoredAt: &after}, false},
+		{"restored before cutoff", models.Chore{Status: models.ChoreStatusCompleted, CompletedAt: &before, RestoredAt: &before}, true},
+		{"legacy chore falls back to updated_at", models.Chore{Status: models.ChoreStatusCompleted, UpdatedAt: before}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.chore.CanBeArchived(cutoff); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNotArchived(t *testing.T) {
+	filter := models.NotArchived(bson.M{"group_id": "g"})
+	value, exists := filter["archived_at"]
+	if !exists || value != nil {
+		t.Errorf("Expected filter to require archived_at to be unset, got %v", filter)
+	}
+	if filter["group_id"] != "g" {
+		t.Errorf("Expected existing conditions to be kept")
+	}
+}
@@ -0,0 +1,138 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPeriodRange(t *testing.T) {
+	// Wednesday 2025-03-12 15:30 UTC
+	ref := time.Date(2025, 3, 12, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		period        models.LeaderboardPeriod
+		expectedStart time.Time
+		expectedEnd   time.Time
+	}{
+		{
+			name:          "week starts on Monday",
+			period:        models.LeaderboardPeriodWeek,
+			expectedStart: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC),
+			expectedEnd:   time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "month starts on the first",
+			period:        models.LeaderboardPeriodMonth,
+			expectedStart: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			expectedEnd:   time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "all time is unbounded",
+			period: models.LeaderboardPeriodAllTime,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := models.PeriodRange(tt.period, ref)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !start.Equal(tt.expectedStart) {
+				t.Errorf("Expected start %v, got %v", tt.expectedStart, start)
+			}
+			if !end.Equal(tt.expectedEnd) {
+				t.Errorf("Expected end %v, got %v", tt.expectedEnd, end)
+			}
+		})
+	}
+
+	// Sunday belongs to the week that started the previous Monday
+	sunday := time.Date(2025, 3, 16, 23, 0, 0, 0, time.UTC)
+	start, _, _ := models.PeriodRange(models.LeaderboardPeriodWeek, sunday)
+	if !start.Equal(time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Sunday to belong to week starting 2025-03-10, got %v", start)
+	}
+
+	if _, _, err := models.PeriodRange(models.LeaderboardPeriodCustom, ref); err == nil {
+		t.Errorf("Expected an error for custom period without explicit range")
+	}
+}
+
+func TestPreviousPeriodRange(t *testing.T) {
+	monthStart := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	monthEnd := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	start, end := models.PreviousPeriodRange(models.LeaderboardPeriodMonth, monthStart, monthEnd)
+	if !start.Equal(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(monthStart) {
+		t.Errorf("Expected previous month February 2025, got %v - %v", start, end)
+	}
+
+	customStart := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	customEnd := time.Date(2025, 3, 13, 0, 0, 0, 0, time.UTC)
+	start, end = models.PreviousPeriodRange(models.LeaderboardPeriodCustom, customStart, customEnd)
+	if !start.Equal(time.Date(2025, 3, 7, 0, 0, 0, 0, time.UTC)) || !end.Equal(customStart) {
+		t.Errorf("Expected previous custom range of equal length, got %v - %v", start, end)
+	}
+}
+
+func TestRankLeaderboard(t *testing.T) {
+	alice := models.LeaderboardEntry{UserID: primitive.NewObjectID(), Name: "Alice", Points: 20, ChoresCompleted: 2}
+	bob := models.LeaderboardEntry{UserID: primitive.NewObjectID(), Name: "Bob", Points: 30, ChoresCompleted: 3}
+	carol := models.LeaderboardEntry{UserID: primitive.NewObjectID(), Name: "Carol", Points: 20, ChoresCompleted: 4}
+	dave := models.LeaderboardEntry{UserID: primitive.NewObjectID(), Name: "Dave"}
+
+	entries := []models.LeaderboardEntry{alice, bob, carol, dave}
+	models.RankLeaderboard(entries)
+
+	expectedOrder := []string{"Bob", "Carol", "Alice", "Dave"}
+	expectedRanks := []int{1, 2, 2, 4}
+	for i, entry := range entries {
+		if entry.Name != expectedOrder[i] {
+			t.Errorf("Expected %s at position %d, got %s", expectedOrder[i], i, entry.Name)
+		}
+		if entry.Rank != expectedRanks[i] {
+			t.Errorf("Expected %s to have rank %d, got %d", entry.Name, expectedRanks[i], entry.Rank)
+		}
+	}
+
+	winner := models.LeaderboardWinner(entries)
+	if winner == nil || winner.Name != "Bob" {
+		t.Errorf("Expected Bob to be the winner")
+	}
+
+	if models.LeaderboardWinner([]models.LeaderboardEntry{dave}) != nil {
+		t.Errorf("Expected no winner when nobody scored")
+	}
+}
+
+func TestApplyRankChanges(t *testing.T) {
+	alice := primitive.NewObjectID()
+	bob := primitive.NewObjectID()
+	carol := primitive.NewObjectID()
+
+	previous := []models.LeaderboardEntry{
+		{UserID: alice, Rank: 1},
+		{UserID: bob, Rank: 2},
+	}
+	current := []models.LeaderboardEntry{
+		{UserID: bob, Rank: 1},
+		{UserID: alice, Rank: 2},
+		{UserID: carol, Rank: 3},
+	}
+
+	models.ApplyRankChanges(current, previous)
+
+	if current[0].RankChange != 1 || current[0].PreviousRank != 2 {
+		t.Errorf("Expected Bob to move up by 1 from rank 2, got change %d from %d", current[0].RankChange, current[0].PreviousRank)
+	}
+	if current[1].RankChange != -1 || current[1].PreviousRank != 1 {
+		t.Errorf("Expected Alice to move down by 1 from rank 1, got change %d from %d", current[1].RankChange, current[1].PreviousRank)
+	}
+	if current[2].RankChange != 0 || current[2].PreviousRank != 0 {
+		t.Errorf("Expected new entrant Carol to have no previous rank, got change %d from %d", current[2].RankChange, current[2].PreviousRank)
+	}
+}
@@ -0,0 +1,219 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNotificationTypeDomain(t *testing.T) {
+	tests := []struct {
+		notificationType models.NotificationType
+		expected         models.NotificationDomain
+	}{
+		{models.NotificationTypeChoreMention, models.NotificationDomainChore},
+		{models.NotificationTypeChoreOverdue, models.NotificationDomainChore},
+		{models.NotificationTypeLowStock, models.NotificationDomainPantry},
+		{models.NotificationTypeExpired, models.NotificationDomainPantry},
+		{models.NotificationTypeCartItemRemoved, models.NotificationDomainCart},
+		{"unknown", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.notificationType), func(t *testing.T) {
+			if got := tt.notificationType.Domain(); got != tt.expected {
+				t.Errorf("Expected domain %q, got %q", tt.expected, got)
+			}
+			if valid := tt.notificationType.IsValid(); valid != (tt.expected != "") {
+				t.Errorf("Expected IsValid %v, got %v", tt.expected != "", valid)
+			}
+		})
+	}
+}
+
+func TestNotificationIsReadBy(t *testing.T) {
+	userID := primitive.NewObjectID()
+	actorID := primitive.NewObjectID()
+	created := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+	before := created.Add(-time.Hour)
+	after := created.Add(time.Hour)
+
+	notification := &models.Notification{ActorID: &actorID, CreatedAt: created}
+
+	tests := []struct {
+		name       string
+		userID     primitive.ObjectID
+		settings   models.NotificationSettings
+		hasReceipt bool
+		expected   bool
+	}{
+		{"Unread", userID, models.NotificationSettings{}, false, false},
+		{"Receipt", userID, models.NotificationSettings{}, true, true},
+		{"Own action", actorID, models.NotificationSettings{}, false, true},
+		{"Read all after creation", userID, models.NotificationSettings{ReadAllAt: &after}, false, true},
+		{"Read all before creation", userID, models.NotificationSettings{ReadAllAt: &before}, false, false},
+		{"Read all at creation", userID, models.NotificationSettings{ReadAllAt: &created}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notification.IsReadBy(tt.userID, tt.settings, tt.hasReceipt); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNotificationIsVisibleTo(t *testing.T) {
+	userID := primitive.NewObjectID()
+	otherID := primitive.NewObjectID()
+
+	groupWide := models.CreatePantryNotification(primitive.NewObjectID(), primitive.NewObjectID(), "Milk", models.NotificationTypeLowStock, "Milk is running low")
+	if !groupWide.IsVisibleTo(userID) {
+		t.Errorf("Expected a group-wide notification to be visible")
+	}
+
+	personal := models.CreateChoreNotification(primitive.NewObjectID(), userID, models.NotificationTypeChoreDueSoon, models.ChoreNotificationPayload{}, "Chore due soon")
+	if !personal.IsVisibleTo(userID) {
+		t.Errorf("Expected a notification to be visible to its recipient")
+	}
+	if personal.IsVisibleTo(otherID) {
+		t.Errorf("Expected a notification to be hidden from other members")
+	}
+}
+
+func TestNotificationSettingsIsMuted(t *testing.T) {
+	settings := models.NotificationSettings{Muted: []models.NotificationType{models.NotificationTypeCartItemAdded}}
+
+	if !settings.IsMuted(models.NotificationTypeCartItemAdded) {
+		t.Errorf("Expected cart_item_added to be muted")
+	}
+	if settings.IsMuted(models.NotificationTypeLowStock) {
+		t.Errorf("Expected low_stock not to be muted")
+	}
+}
+
+func TestVisibleNotificationsFilter(t *testing.T) {
+	groupID := primitive.NewObjectID()
+	readAll := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+	user := &models.User{ID: primitive.NewObjectID()}
+
+	filter := models.VisibleNotifications(groupID, user)
+	if filter["group_id"] != groupID {
+		t.Errorf("Expected filter on group_id %v, got %v", groupID, filter["group_id"])
+	}
+	if _, ok := filter["type"]; ok {
+		t.Errorf("Expected no type filter without muted types")
+	}
+
+	user.Notifications = models.NotificationSettings{
+		ReadAllAt: &readAll,
+		Muted:     []models.NotificationType{models.NotificationTypeExpired},
+	}
+	accessible := models.AccessibleNotifications(groupID, user)
+	if _, ok := accessible["type"]; ok {
+		t.Errorf("Expected muted types to stay accessible, got %v", accessible["type"])
+	}
+	if accessible["group_id"] != groupID {
+		t.Errorf("Expected filter on group_id %v, got %v", groupID, accessible["group_id"])
+	}
+
+	filter = models.UnreadNotifications(groupID, user)
+
+	typeFilter, ok := filter["type"].(bson.M)
+	if !ok {
+		t.Fatalf("Expected a type filter, got %v", filter["type"])
+	}
+	muted, ok := typeFilter["$nin"].([]models.NotificationType)
+	if !ok || len(muted) != 1 || muted[0] != models.NotificationTypeExpired {
+		t.Errorf("Expected muted types to be excluded, got %v", typeFilter)
+	}
+	if actor, ok := filter["actor_id"].(bson.M); !ok || actor["$ne"] != user.ID {
+		t.Errorf("Expected the user's own notifications to be excluded, got %v", filter["actor_id"])
+	}
+	if created, ok := filter["created_at"].(bson.M); !ok || created["$gt"] != readAll {
+		t.Errorf("Expected notifications after the watermark, got %v", filter["created_at"])
+	}
+}
+
+func TestCreateCartNotification(t *testing.T) {
+	expires := time.Now().Add(24 * time.Hour)
+	activity := &models.ShoppingCartActivity{
+		ID:        primitive.NewObjectID(),
+		GroupID:   primitive.NewObjectID(),
+		UserID:    primitive.NewObjectID(),
+		UserName:  "alice",
+		ItemID:    primitive.NewObjectID(),
+		ItemName:  "Eggs",
+		Action:    models.CartActivityTypeDelete,
+		Quantity:  12,
+		ExpiresAt: expires,
+	}
+
+	notification := models.CreateCartNotification(activity)
+
+	if notification.Type != models.NotificationTypeCartItemRemoved {
+		t.Errorf("Expected type %q, got %q", models.NotificationTypeCartItemRemoved, notification.Type)
+	}
+	if notification.Domain != models.NotificationDomainCart {
+		t.Errorf("Expected domain %q, got %q", models.NotificationDomainCart, notification.Domain)
+	}
+	if notification.ActorID == nil || *notification.ActorID != activity.UserID {
+		t.Errorf("Expected the activity's user as actor")
+	}
+	if notification.RecipientID != nil {
+		t.Errorf("Expected a group-wide notification")
+	}
+	if notification.Cart == nil || notification.Cart.ActivityID != activity.ID || notification.Cart.Quantity != 12 {
+		t.Errorf("Expected the cart payload to describe the activity, got %+v", notification.Cart)
+	}
+	if notification.Chore != nil || notification.Pantry != nil {
+		t.Errorf("Expected only the cart payload to be set")
+	}
+	if !notification.ExpiresAt.Equal(expires) {
+		t.Errorf("Expected the notification to expire with the activity")
+	}
+}
+
+func TestCreatePantryNotification(t *testing.T) {
+	itemID := primitive.NewObjectID()
+	notification := models.CreatePantryNotification(primitive.NewObjectID(), itemID, "Milk", models.NotificationTypeExpiringSoon, "Milk expires soon")
+
+	if notification.Domain != models.NotificationDomainPantry {
+		t.Errorf("Expected domain %q, got %q", models.NotificationDomainPantry, notification.Domain)
+	}
+	if notification.Pantry == nil || notification.Pantry.ItemID != itemID || notification.Pantry.ItemName != "Milk" {
+		t.Errorf("Expected the pantry payload to describe the item, got %+v", notification.Pantry)
+	}
+	if notification.ActorID != nil {
+		t.Errorf("Expected a system notification without an actor")
+	}
+	if got := notification.ExpiresAt.Sub(notification.CreatedAt); got != models.NotificationRetention {
+		t.Errorf("Expected retention %v, got %v", models.NotificationRetention, got)
+	}
+}
+
+func TestPushRecipients(t *testing.T) {
+	actor := models.User{ID: primitive.NewObjectID()}
+	member := models.User{ID: primitive.NewObjectID()}
+	muting := models.User{
+		ID:            primitive.NewObjectID(),
+		Notifications: models.NotificationSettings{Muted: []models.NotificationType{models.NotificationTypeCartItemAdded}},
+	}
+	members := []models.User{actor, member, muting}
+
+	activity := &models.ShoppingCartActivity{GroupID: primitive.NewObjectID(), UserID: actor.ID, Action: models.CartActivityTypeAdd}
+	recipients := models.PushRecipients(models.CreateCartNotification(activity), members)
+	if len(recipients) != 1 || recipients[0] != member.ID {
+		t.Errorf("Expected only the member who neither acted nor muted, got %v", recipients)
+	}
+
+	personal := models.CreateChoreNotification(activity.GroupID, muting.ID, models.NotificationTypeChoreDueSoon, models.ChoreNotificationPayload{}, "Chore due soon")
+	recipients = models.PushRecipients(personal, members)
+	if len(recipients) != 1 || recipients[0] != muting.ID {
+		t.Errorf("Expected only the recipient, got %v", recipients)
+	}
+}
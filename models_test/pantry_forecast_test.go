@@ -0,0 +1,128 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"testing"
+	"time"
+)
+
+func useRecord(quantity float64, at time.Time) models.PantryHistory {
+	return models.PantryHistory{Action: models.ActionTypeUse, Quantity: quantity, CreatedAt: at}
+}
+
+func TestComputeConsumptionRate(t *testing.T) {
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days float64) time.Time {
+		return now.Add(-time.Duration(days * float64(24*time.Hour)))
+	}
+
+	steady := make([]models.PantryHistory, 0, 10)
+	for day := 0; day < 10; day++ {
+		steady = append(steady, useRecord(2, daysAgo(float64(day)+0.5)))
+	}
+
+	tests := []struct {
+		name     string
+		history  []models.PantryHistory
+		rate     float64
+		days     int
+		reliable bool
+	}{
+		{"No history", nil, 0, 0, false},
+		{"Steady use", steady, 2, 10, true},
+		{"Only today", []models.PantryHistory{useRecord(1, daysAgo(0.1)), useRecord(1, daysAgo(0.2))}, 2, 1, false},
+		{"Other actions are ignored", []models.PantryHistory{
+			{Action: models.ActionTypeAdd, Quantity: 10, CreatedAt: daysAgo(3)},
+			useRecord(1, daysAgo(0.5)),
+		}, 1, 1, false},
+		{"Outside the window is ignored", []models.PantryHistory{
+			useRecord(50, daysAgo(models.ForecastWindowDays+1)),
+			useRecord(1, daysAgo(0.5)),
+		}, 1, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate := models.ComputeConsumptionRate(tt.history, now)
+			if rate.DailyRate != tt.rate {
+				t.Errorf("Expected daily rate %v, got %v", tt.rate, rate.DailyRate)
+			}
+			if rate.Days != tt.days {
+				t.Errorf("Expected %d days of history, got %d", tt.days, rate.Days)
+			}
+			if rate.IsReliable() != tt.reliable {
+				t.Errorf("Expected reliable %v, got %v", tt.reliable, rate.IsReliable())
+			}
+		})
+	}
+}
+
+func TestComputeConsumptionRateFollowsRecentUse(t *testing.T) {
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+
+	var history []models.PantryHistory
+	for day := 0; day < 14; day++ {
+		quantity := 1.0
+		if day < 4 {
+			quantity = 3 // Used more over the last few days
+		}
+		history = append(history, useRecord(quantity, now.Add(-time.Duration(day)*24*time.Hour-time.Hour)))
+	}
+
+	rate := models.ComputeConsumptionRate(history, now)
+	average := (4*3.0 + 10*1.0) / 14
+	if rate.DailyRate <= average || rate.DailyRate >= 3 {
+		t.Errorf("Expected a rate between the average %.2f and the recent 3 a day, got %v", average, rate.DailyRate)
+	}
+	if !rate.LastUsedAt.Equal(now.Add(-time.Hour)) {
+		t.Errorf("Expected the last use an hour ago, got %v", rate.LastUsedAt)
+	}
+}
+
+func TestConsumptionRateForecast(t *testing.T) {
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	levels := models.StockLevels{LowStockThreshold: 1, ParLevel: 2}
+	rate := models.ConsumptionRate{DailyRate: 2, Days: 10, Uses: 10}
+	item := &models.PantryItem{Name: "milk", Quantity: 5, Unit: "l"}
+
+	forecast := rate.Forecast(item, levels, now)
+	if forecast.DaysLeft == nil || *forecast.DaysLeft != 2.5 {
+		t.Fatalf("Expected 2.5 days left, got %v", forecast.DaysLeft)
+	}
+	if !forecast.RunOutDate.Equal(now.Add(60 * time.Hour)) {
+		t.Errorf("Expected to run out in 60 hours, got %v", forecast.RunOutDate)
+	}
+	// A week at 2 a day plus the threshold, less what is left
+	if forecast.SuggestedQuantity != 10 {
+		t.Errorf("Expected to buy 10, got %v", forecast.SuggestedQuantity)
+	}
+	if !rate.RunsOutWithin(item.Quantity, models.ForecastWarningDays) {
+		t.Errorf("Expected the item to run out within %d days", models.ForecastWarningDays)
+	}
+	if rate.RunsOutWithin(20, models.ForecastWarningDays) {
+		t.Errorf("Expected 20 to last longer than %d days", models.ForecastWarningDays)
+	}
+
+	unreliable := models.ConsumptionRate{DailyRate: 2, Days: 1, Uses: 2}
+	forecast = unreliable.Forecast(&models.PantryItem{Quantity: 0.5}, levels, now)
+	if forecast.RunOutDate != nil || forecast.SuggestedQuantity != 1.5 {
+		t.Errorf("Expected no run-out date and a top-up to par without reliable history, got %v and %v", forecast.RunOutDate, forecast.SuggestedQuantity)
+	}
+}
+
+func TestRunOutMessage(t *testing.T) {
+	tests := []struct {
+		days     float64
+		expected string
+	}{
+		{0.3, "Will run out within a day"},
+		{1.2, "Will run out in about 1 day"},
+		{2.6, "Will run out in about 3 days"},
+	}
+
+	for _, tt := range tests {
+		if got := models.RunOutMessage(tt.days); got != tt.expected {
+			t.Errorf("Expected %q for %v days, got %q", tt.expected, tt.days, got)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"testing"
+)
+
+func TestPantryItemToItemUnit(t *testing.T) {
+	flour := models.PantryItem{Name: "Flour", Unit: "kg"}
+	eggs := models.PantryItem{Name: "Eggs", Unit: "pc"}
+	custom := models.PantryItem{Name: "Mystery powder", Unit: "g", Density: float(0.5)}
+
+	tests := []struct {
+		name     string
+		item     models.PantryItem
+		quantity float64
+		unit     string
+		expected float64
+		valid    bool
+	}{
+		{"Item unit", flour, 2, "", 2, true},
+		{"Grams into kilograms", flour, 500, "g", 0.5, true},
+		{"Cups through the typical density", flour, 4, "cups", 0.501567, true},
+		{"Millilitres through the item density", custom, 1, "ml", 0.5, true},
+		{"Dozen eggs", eggs, 1, "dozen", 12, true},
+		{"Eggs by weight", eggs, 100, "g", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.item.ToItemUnit(tt.quantity, tt.unit)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got error %v", tt.name, tt.valid, err)
+			continue
+		}
+		if tt.valid && got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
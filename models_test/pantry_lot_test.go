@@ -0,0 +1,153 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func lotItem(lots ...models.PantryLot) *models.PantryItem {
+	item := &models.PantryItem{Lots: []models.PantryLot{}}
+	for _, lot := range lots {
+		item.AddLot(lot)
+	}
+	return item
+}
+
+func TestPantryItemSyncLots(t *testing.T) {
+	now := time.Now()
+	later := models.NewPantryLot(2, now.AddDate(0, 0, 7), primitive.NewObjectID())
+	sooner := models.NewPantryLot(1, now.AddDate(0, 0, 2), primitive.NewObjectID())
+	undated := models.NewPantryLot(3, time.Time{}, primitive.NewObjectID())
+
+	item := lotItem(undated, later, sooner)
+
+	if item.Quantity != 6 {
+		t.Errorf("Expected quantity 6, got %v", item.Quantity)
+	}
+	if !item.ExpirationDate.Equal(sooner.ExpirationDate) {
+		t.Errorf("Expected the earliest expiration date %v, got %v", sooner.ExpirationDate, item.ExpirationDate)
+	}
+	expected := []primitive.ObjectID{sooner.ID, later.ID, undated.ID}
+	for i, lot := range item.Lots {
+		if lot.ID != expected[i] {
+			t.Errorf("Expected lot %d to be %s, got %s", i, expected[i].Hex(), lot.ID.Hex())
+		}
+	}
+}
+
+func TestPantryItemSyncLotsLegacy(t *testing.T) {
+	expiration := time.Now().AddDate(0, 0, 5)
+	item := models.PantryItem{ID: primitive.NewObjectID(), Quantity: 4, ExpirationDate: expiration}
+
+	item.SyncLots()
+
+	if len(item.Lots) != 1 {
+		t.Fatalf("Expected 1 lot, got %d", len(item.Lots))
+	}
+	if item.Lots[0].ID != item.ID || item.Lots[0].Quantity != 4 || !item.Lots[0].ExpirationDate.Equal(expiration) {
+		t.Errorf("Expected a lot keyed by the item holding its stock, got %+v", item.Lots[0])
+	}
+}
+
+func TestPantryItemConsumeLots(t *testing.T) {
+	now := time.Now()
+	sooner := models.NewPantryLot(1, now.AddDate(0, 0, 2), primitive.NewObjectID())
+	later := models.NewPantryLot(2, now.AddDate(0, 0, 7), primitive.NewObjectID())
+	undated := models.NewPantryLot(3, time.Time{}, primitive.NewObjectID())
+
+	tests := []struct {
+		name      string
+		quantity  float64
+		usages    []models.LotUsage
+		remaining float64
+		lots      int
+	}{
+		{"From the first-expiring lot", 0.5, []models.LotUsage{{LotID: sooner.ID, Quantity: 0.5}}, 5.5, 3},
+		{"Across lots", 2, []models.LotUsage{{LotID: sooner.ID, Quantity: 1}, {LotID: later.ID, Quantity: 1}}, 4, 2},
+		{"Everything", 6, []models.LotUsage{{LotID: sooner.ID, Quantity: 1}, {LotID: later.ID, Quantity: 2}, {LotID: undated.ID, Quantity: 3}}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := lotItem(undated, later, sooner)
+
+			usages, err := item.ConsumeLots(tt.quantity)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(usages) != len(tt.usages) {
+				t.Fatalf("Expected %d lots used, got %d", len(tt.usages), len(usages))
+			}
+			for i, usage := range usages {
+				if usage.LotID != tt.usages[i].LotID || usage.Quantity != tt.usages[i].Quantity {
+					t.Errorf("Expected %v from lot %s, got %v from %s", tt.usages[i].Quantity, tt.usages[i].LotID.Hex(), usage.Quantity, usage.LotID.Hex())
+				}
+			}
+			if item.Quantity != tt.remaining {
+				t.Errorf("Expected %v remaining, got %v", tt.remaining, item.Quantity)
+			}
+			if len(item.Lots) != tt.lots {
+				t.Errorf("Expected %d lots left, got %d", tt.lots, len(item.Lots))
+			}
+		})
+	}
+}
+
+func TestPantryItemConsumeLotsNotEnough(t *testing.T) {
+	item := lotItem(models.NewPantryLot(1, time.Time{}, primitive.NewObjectID()))
+
+	if _, err := item.ConsumeLots(1.5); !errors.Is(err, models.ErrNotEnoughQuantity) {
+		t.Errorf("Expected ErrNotEnoughQuantity, got %v", err)
+	}
+	if item.Quantity != 1 {
+		t.Errorf("Expected the quantity to be unchanged, got %v", item.Quantity)
+	}
+}
+
+func TestPantryItemSetQuantity(t *testing.T) {
+	now := time.Now()
+	addedBy := primitive.NewObjectID()
+	item := lotItem(
+		models.NewPantryLot(2, now.AddDate(0, 0, 2), addedBy),
+		models.NewPantryLot(2, now.AddDate(0, 0, 9), addedBy),
+	)
+
+	added, used, err := item.SetQuantity(5, models.NewPantryLot(0, now.AddDate(0, 0, 14), addedBy))
+	if err != nil || added == nil || added.Quantity != 1 || len(used) != 0 {
+		t.Errorf("Expected a new lot of 1, got %+v, %v, %v", added, used, err)
+	}
+
+	added, used, err = item.SetQuantity(2, models.NewPantryLot(0, time.Time{}, addedBy))
+	if err != nil || added != nil || len(used) != 2 {
+		t.Errorf("Expected 3 taken from the first two lots, got %+v, %v, %v", added, used, err)
+	}
+	if item.Quantity != 2 || !item.ExpirationDate.Equal(now.AddDate(0, 0, 9)) {
+		t.Errorf("Expected 2 left expiring with the second lot, got %v expiring %v", item.Quantity, item.ExpirationDate)
+	}
+}
+
+func TestPantryItemSetExpirationDate(t *testing.T) {
+	now := time.Now()
+	single := lotItem(models.NewPantryLot(1, now.AddDate(0, 0, 2), primitive.NewObjectID()))
+	if err := single.SetExpirationDate(now.AddDate(0, 0, 4)); err != nil {
+		t.Errorf("Expected a single lot to take the date, got %v", err)
+	}
+	if !single.Lots[0].ExpirationDate.Equal(now.AddDate(0, 0, 4)) {
+		t.Errorf("Expected the lot to expire %v, got %v", now.AddDate(0, 0, 4), single.Lots[0].ExpirationDate)
+	}
+
+	several := lotItem(
+		models.NewPantryLot(1, now.AddDate(0, 0, 2), primitive.NewObjectID()),
+		models.NewPantryLot(1, now.AddDate(0, 0, 5), primitive.NewObjectID()),
+	)
+	if err := several.SetExpirationDate(now.AddDate(0, 0, 2)); err != nil {
+		t.Errorf("Expected the current date to be accepted, got %v", err)
+	}
+	if err := several.SetExpirationDate(now.AddDate(0, 0, 3)); !errors.Is(err, models.ErrAmbiguousLot) {
+		t.Errorf("Expected ErrAmbiguousLot, got %v", err)
+	}
+}
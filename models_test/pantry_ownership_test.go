@@ -0,0 +1,125 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidateOwnership(t *testing.T) {
+	alice := primitive.NewObjectID()
+	bob := primitive.NewObjectID()
+
+	tests := []struct {
+		name   string
+		mode   models.OwnershipMode
+		owners []primitive.ObjectID
+		valid  bool
+	}{
+		{"Shared", models.OwnershipShared, nil, true},
+		{"Shared with owners", models.OwnershipShared, []primitive.ObjectID{alice}, false},
+		{"Personal", models.OwnershipPersonal, []primitive.ObjectID{alice}, true},
+		{"Personal without owner", models.OwnershipPersonal, nil, false},
+		{"Personal with two owners", models.OwnershipPersonal, []primitive.ObjectID{alice, bob}, false},
+		{"Split", models.OwnershipSplit, []primitive.ObjectID{alice, bob}, true},
+		{"Split with one owner", models.OwnershipSplit, []primitive.ObjectID{alice}, false},
+		{"Unknown mode", "borrowed", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := models.ValidateOwnership(tt.mode, tt.owners)
+			if (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestPantryItemPermissions(t *testing.T) {
+	alice := primitive.NewObjectID()
+	bob := primitive.NewObjectID()
+	carol := primitive.NewObjectID()
+
+	legacy := models.PantryItem{AddedBy: alice}
+	personal := models.PantryItem{AddedBy: alice}
+	if err := personal.SetOwnership(models.OwnershipPersonal, []primitive.ObjectID{alice}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	split := models.PantryItem{AddedBy: alice}
+	if err := split.SetOwnership(models.OwnershipSplit, []primitive.ObjectID{alice, bob}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		item      *models.PantryItem
+		user      primitive.ObjectID
+		canUse    bool
+		canDelete bool
+		canManage bool
+	}{
+		{"Anyone uses a legacy item", &legacy, bob, true, true, false},
+		{"The adder manages a shared item", &legacy, alice, true, true, true},
+		{"Owner of a personal item", &personal, alice, true, true, true},
+		{"Others and a personal item", &personal, bob, false, false, false},
+		{"Split owner", &split, bob, true, true, true},
+		{"Others and a split item", &split, carol, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.item.CanUse(tt.user); got != tt.canUse {
+				t.Errorf("Expected CanUse %v, got %v", tt.canUse, got)
+			}
+			if got := tt.item.CanDelete(tt.user); got != tt.canDelete {
+				t.Errorf("Expected CanDelete %v, got %v", tt.canDelete, got)
+			}
+			if got := tt.item.CanManageOwnership(tt.user); got != tt.canManage {
+				t.Errorf("Expected CanManageOwnership %v, got %v", tt.canManage, got)
+			}
+		})
+	}
+}
+
+func TestPantryItemAllowUser(t *testing.T) {
+	alice := primitive.NewObjectID()
+	bob := primitive.NewObjectID()
+	item := models.PantryItem{}
+	if err := item.SetOwnership(models.OwnershipPersonal, []primitive.ObjectID{alice}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	item.AllowUser(bob, true)
+	item.AllowUser(bob, true)
+	if !item.CanUse(bob) || len(item.AllowedUserIDs) != 1 {
+		t.Errorf("Expected bob to be allowed once, got %v", item.AllowedUserIDs)
+	}
+	if item.CanDelete(bob) {
+		t.Errorf("Expected permission to use not to allow deleting")
+	}
+
+	item.AllowUser(bob, false)
+	if item.CanUse(bob) {
+		t.Errorf("Expected bob's permission to be taken away")
+	}
+
+	item.AllowUser(bob, true)
+	if err := item.SetOwnership(models.OwnershipShared, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(item.AllowedUserIDs) != 0 {
+		t.Errorf("Expected permissions to be dropped on a shared item, got %v", item.AllowedUserIDs)
+	}
+}
+
+func TestSortOwners(t *testing.T) {
+	a, _ := primitive.ObjectIDFromHex("000000000000000000000001")
+	b, _ := primitive.ObjectIDFromHex("000000000000000000000002")
+
+	sorted := models.SortOwners([]primitive.ObjectID{b, a, b})
+	if len(sorted) != 2 || sorted[0] != a || sorted[1] != b {
+		t.Errorf("Expected [%s %s], got %v", a.Hex(), b.Hex(), sorted)
+	}
+}
@@ -0,0 +1,112 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"testing"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestResolveStockLevels(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     models.PantryItem
+		category *models.PantryCategory
+		expected models.StockLevels
+	}{
+		{
+			name:     "Defaults",
+			item:     models.PantryItem{},
+			category: &models.PantryCategory{},
+			expected: models.StockLevels{LowStockThreshold: 1, ParLevel: 2},
+		},
+		{
+			name:     "No category",
+			item:     models.PantryItem{},
+			category: nil,
+			expected: models.StockLevels{LowStockThreshold: 1, ParLevel: 2},
+		},
+		{
+			name:     "Category defaults",
+			item:     models.PantryItem{},
+			category: &models.PantryCategory{LowStockThreshold: float(500), ParLevel: float(2000)},
+			expected: models.StockLevels{LowStockThreshold: 500, ParLevel: 2000},
+		},
+		{
+			name:     "Item overrides category",
+			item:     models.PantryItem{LowStockThreshold: float(6), ParLevel: float(12)},
+			category: &models.PantryCategory{LowStockThreshold: float(500), ParLevel: float(2000)},
+			expected: models.StockLevels{LowStockThreshold: 6, ParLevel: 12},
+		},
+		{
+			name:     "Item threshold above the category par",
+			item:     models.PantryItem{LowStockThreshold: float(3)},
+			category: &models.PantryCategory{ParLevel: float(2)},
+			expected: models.StockLevels{LowStockThreshold: 3, ParLevel: 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := models.ResolveStockLevels(&tt.item, tt.category)
+			if got != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStockLevelsChecks(t *testing.T) {
+	levels := models.StockLevels{LowStockThreshold: 2, ParLevel: 6}
+
+	tests := []struct {
+		quantity     float64
+		lowStock     bool
+		needsRestock bool
+		suggested    float64
+	}{
+		{0, false, true, 6},
+		{1.5, true, true, 4.5},
+		{2, true, true, 4},
+		{3, false, false, 3},
+		{8, false, false, 0},
+	}
+
+	for _, tt := range tests {
+		if got := levels.IsLowStock(tt.quantity); got != tt.lowStock {
+			t.Errorf("Expected IsLowStock(%v) to be %v, got %v", tt.quantity, tt.lowStock, got)
+		}
+		if got := levels.NeedsRestock(tt.quantity); got != tt.needsRestock {
+			t.Errorf("Expected NeedsRestock(%v) to be %v, got %v", tt.quantity, tt.needsRestock, got)
+		}
+		if got := levels.SuggestedQuantity(tt.quantity); got != tt.suggested {
+			t.Errorf("Expected SuggestedQuantity(%v) to be %v, got %v", tt.quantity, tt.suggested, got)
+		}
+	}
+}
+
+func TestValidateStockLevels(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold *float64
+		par       *float64
+		valid     bool
+	}{
+		{"Neither", nil, nil, true},
+		{"Both", float(1), float(4), true},
+		{"Equal", float(2), float(2), true},
+		{"Only threshold", float(3), nil, true},
+		{"Negative threshold", float(-1), nil, false},
+		{"Negative par", nil, float(-1), false},
+		{"Par below threshold", float(5), float(2), false},
+	}
+
+	for _, tt := range tests {
+		err := models.ValidateStockLevels(tt.threshold, tt.par)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got error %v", tt.name, tt.valid, err)
+		}
+	}
+}
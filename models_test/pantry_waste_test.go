@@ -0,0 +1,190 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPantryItemDiscardLots(t *testing.T) {
+	now := time.Now()
+	expired := models.NewPantryLot(1, now.AddDate(0, 0, -2), primitive.NewObjectID())
+	alsoExpired := models.NewPantryLot(2, now.AddDate(0, 0, -1), primitive.NewObjectID())
+	fresh := models.NewPantryLot(3, now.AddDate(0, 0, 5), primitive.NewObjectID())
+
+	tests := []struct {
+		name      string
+		lots      []models.PantryLot
+		lotID     *primitive.ObjectID
+		quantity  float64
+		discarded map[primitive.ObjectID]float64
+		remaining float64
+	}{
+		{"Every expired lot", []models.PantryLot{expired, alsoExpired, fresh}, nil, 0,
+			map[primitive.ObjectID]float64{expired.ID: 1, alsoExpired.ID: 2}, 3},
+		{"Everything when nothing has expired", []models.PantryLot{fresh}, nil, 0,
+			map[primitive.ObjectID]float64{fresh.ID: 3}, 0},
+		{"Quantity from the first-expiring lots", []models.PantryLot{expired, alsoExpired, fresh}, nil, 2,
+			map[primitive.ObjectID]float64{expired.ID: 1, alsoExpired.ID: 1}, 4},
+		{"A whole lot", []models.PantryLot{expired, fresh}, &fresh.ID, 0,
+			map[primitive.ObjectID]float64{fresh.ID: 3}, 1},
+		{"Part of a lot", []models.PantryLot{expired, fresh}, &fresh.ID, 1.5,
+			map[primitive.ObjectID]float64{fresh.ID: 1.5}, 2.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := lotItem(tt.lots...)
+			usages, err := item.DiscardLots(tt.lotID, tt.quantity, now)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(usages) != len(tt.discarded) {
+				t.Fatalf("Expected %d lots discarded from, got %d", len(tt.discarded), len(usages))
+			}
+			for _, usage := range usages {
+				if usage.Quantity != tt.discarded[usage.LotID] {
+					t.Errorf("Expected %v discarded from lot %s, got %v", tt.discarded[usage.LotID], usage.LotID.Hex(), usage.Quantity)
+				}
+			}
+			if item.Quantity != tt.remaining {
+				t.Errorf("Expected %v remaining, got %v", tt.remaining, item.Quantity)
+			}
+		})
+	}
+}
+
+func TestPantryItemDiscardLotsErrors(t *testing.T) {
+	now := time.Now()
+	lot := models.NewPantryLot(1, now, primitive.NewObjectID())
+	unknown := primitive.NewObjectID()
+
+	if _, err := lotItem().DiscardLots(nil, 0, now); !errors.Is(err, models.ErrNothingToDiscard) {
+		t.Errorf("Expected ErrNothingToDiscard for an empty item, got %v", err)
+	}
+	if _, err := lotItem(lot).DiscardLots(&unknown, 0, now); !errors.Is(err, models.ErrLotNotFound) {
+		t.Errorf("Expected ErrLotNotFound, got %v", err)
+	}
+	if _, err := lotItem(lot).DiscardLots(&lot.ID, 2, now); !errors.Is(err, models.ErrNotEnoughQuantity) {
+		t.Errorf("Expected ErrNotEnoughQuantity, got %v", err)
+	}
+}
+
+func TestPantryItemDiscardValue(t *testing.T) {
+	item := &models.PantryItem{Quantity: 4}
+	if value := item.EstimateValue(2); value != nil {
+		t.Errorf("Expected no estimate without a remembered value, got %v", *value)
+	}
+
+	item.RememberValue(4, 6)
+	value := item.EstimateValue(3)
+	if value == nil || *value != 4.5 {
+		t.Errorf("Expected an estimate of 4.5, got %v", value)
+	}
+
+	lots := []models.LotUsage{{Quantity: 1}, {Quantity: 2}}
+	parts := models.SplitValue(value, lots)
+	if *parts[0] != 1.5 || *parts[1] != 3 {
+		t.Errorf("Expected the value split 1.5 and 3, got %v and %v", *parts[0], *parts[1])
+	}
+	if parts := models.SplitValue(nil, lots); parts[0] != nil || parts[1] != nil {
+		t.Errorf("Expected no values to split without a value")
+	}
+	if total := models.DiscardedQuantity(lots); total != 3 {
+		t.Errorf("Expected 3 discarded, got %v", total)
+	}
+}
+
+func TestBuildWasteReport(t *testing.T) {
+	from := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC) // A Monday
+	to := from.AddDate(0, 0, 14)
+	milk, bread := primitive.NewObjectID(), primitive.NewObjectID()
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+	dairy := primitive.NewObjectID()
+
+	discard := func(discardID primitive.ObjectID, itemID primitive.ObjectID, name string, userID primitive.ObjectID, reason models.DiscardReason, quantity, value float64, at time.Time) models.PantryHistory {
+		record := models.PantryHistory{
+			DiscardID: &discardID,
+			ItemID:    itemID,
+			ItemName:  name,
+			UserID:    userID,
+			Action:    models.ActionTypeDiscard,
+			Quantity:  quantity,
+			Reason:    reason,
+			Value:     &value,
+			CreatedAt: at,
+		}
+		if itemID == milk {
+			record.CategoryID = &dairy
+		}
+		return record
+	}
+
+	at := from.Add(36 * time.Hour)
+	twoLots := primitive.NewObjectID()
+	records := []models.PantryHistory{
+		// One discard of milk taken from two lots, written a moment apart
+		discard(twoLots, milk, "milk", alice, models.DiscardReasonExpired, 1, 2, at),
+		discard(twoLots, milk, "milk", alice, models.DiscardReasonExpired, 0.5, 1, at.Add(time.Millisecond)),
+		discard(primitive.NewObjectID(), milk, "milk", bob, models.DiscardReasonSpoiled, 1, 2, from.AddDate(0, 0, 8)),
+		discard(primitive.NewObjectID(), bread, "bread", bob, models.DiscardReasonExpired, 1, 1.5, from.AddDate(0, 0, 9)),
+		discard(primitive.NewObjectID(), bread, "bread", bob, models.DiscardReasonOther, 1, 1, to), // Outside the window
+		{ItemID: bread, UserID: bob, Action: models.ActionTypeUse, Quantity: 1, CreatedAt: at},
+	}
+
+	report := models.BuildWasteReport(records, map[primitive.ObjectID]string{dairy: "Dairy"}, models.AnalyticsBucketWeek, from, to, time.UTC)
+
+	if report.Summary.Discards != 3 || report.Summary.Value != 6.5 {
+		t.Errorf("Expected 3 discards worth 6.5, got %d worth %v", report.Summary.Discards, report.Summary.Value)
+	}
+
+	if len(report.Items) != 2 || report.Items[0].ItemID != milk {
+		t.Fatalf("Expected milk to lead 2 items, got %+v", report.Items)
+	}
+	if report.Items[0].Quantity != 2.5 || report.Items[0].Discards != 2 || report.Items[0].Value != 5 {
+		t.Errorf("Expected 2.5 milk in 2 discards worth 5, got %+v", report.Items[0])
+	}
+
+	if len(report.Categories) != 2 || report.Categories[0].Category != "Dairy" || report.Categories[1].Category != "Uncategorized" {
+		t.Errorf("Expected Dairy then Uncategorized, got %+v", report.Categories)
+	}
+
+	if len(report.Members) != 2 || report.Members[0].UserID != bob || report.Members[0].Discards != 2 {
+		t.Errorf("Expected bob to lead with 2 discards, got %+v", report.Members)
+	}
+
+	if len(report.Reasons) != 2 || report.Reasons[0].Reason != models.DiscardReasonExpired || report.Reasons[0].Value != 4.5 {
+		t.Errorf("Expected expired to lead worth 4.5, got %+v", report.Reasons)
+	}
+
+	if len(report.Trend) != 2 {
+		t.Fatalf("Expected 2 weeks of trend, got %d", len(report.Trend))
+	}
+	if report.Trend[0].Discards != 1 || report.Trend[0].Value != 3 {
+		t.Errorf("Expected 1 discard worth 3 in the first week, got %+v", report.Trend[0])
+	}
+	if report.Trend[1].Discards != 2 || report.Trend[1].Value != 3.5 {
+		t.Errorf("Expected 2 discards worth 3.5 in the second week, got %+v", report.Trend[1])
+	}
+}
+
+func TestBuildWasteReportSeparateDiscards(t *testing.T) {
+	from := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	at := from.Add(time.Hour)
+	itemID, userID := primitive.NewObjectID(), primitive.NewObjectID()
+	first, second := primitive.NewObjectID(), primitive.NewObjectID()
+
+	// Two quick discards of the same item by the same member stay apart
+	records := []models.PantryHistory{
+		{DiscardID: &first, ItemID: itemID, UserID: userID, Action: models.ActionTypeDiscard, Quantity: 1, CreatedAt: at},
+		{DiscardID: &second, ItemID: itemID, UserID: userID, Action: models.ActionTypeDiscard, Quantity: 1, CreatedAt: at},
+	}
+
+	report := models.BuildWasteReport(records, nil, models.AnalyticsBucketDay, from, from.AddDate(0, 0, 1), time.UTC)
+	if report.Summary.Discards != 2 {
+		t.Errorf("Expected 2 discards, got %d", report.Summary.Discards)
+	}
+}
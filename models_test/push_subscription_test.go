@@ -0,0 +1,91 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestValidatePushEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		valid    bool
+	}{
+		{"https://fcm.googleapis.com/fcm/send/abc", true},
+		{"http://localhost:8081/push/abc", false},
+		{"http://127.0.0.1:8081/push/abc", false},
+		{"https://localhost/push/abc", false},
+		{"https://127.0.0.1/push/abc", false},
+		{"https://10.0.0.5/push/abc", false},
+		{"https://169.254.169.254/latest", false},
+		{"https://[::1]/push/abc", false},
+		{"https://0.0.0.0/push/abc", false},
+		{"http://push.example.com/abc", false},
+		{"/push/abc", false},
+		{"not a url", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			err := models.ValidatePushEndpoint(tt.endpoint)
+			if (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestPushSubscriptionKeysDecode(t *testing.T) {
+	publicKey := make([]byte, 65)
+	publicKey[0] = 0x04
+	authSecret := make([]byte, 16)
+
+	tests := []struct {
+		name  string
+		keys  models.PushSubscriptionKeys
+		valid bool
+	}{
+		{"Unpadded", models.PushSubscriptionKeys{
+			P256dh: base64.RawURLEncoding.EncodeToString(publicKey),
+			Auth:   base64.RawURLEncoding.EncodeToString(authSecret),
+		}, true},
+		{"Padded", models.PushSubscriptionKeys{
+			P256dh: base64.URLEncoding.EncodeToString(publicKey),
+			Auth:   base64.URLEncoding.EncodeToString(authSecret),
+		}, true},
+		{"Compressed key", models.PushSubscriptionKeys{
+			P256dh: base64.RawURLEncoding.EncodeToString(publicKey[:33]),
+			Auth:   base64.RawURLEncoding.EncodeToString(authSecret),
+		}, false},
+		{"Short secret", models.PushSubscriptionKeys{
+			P256dh: base64.RawURLEncoding.EncodeToString(publicKey),
+			Auth:   base64.RawURLEncoding.EncodeToString(authSecret[:8]),
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.keys.Decode()
+			if (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestPushSubscriptionIsExpired(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	if (&models.PushSubscription{}).IsExpired(now) {
+		t.Errorf("Expected a subscription without expiration time not to expire")
+	}
+	if !(&models.PushSubscription{ExpiresAt: &past}).IsExpired(now) {
+		t.Errorf("Expected a subscription past its expiration time to be expired")
+	}
+	if (&models.PushSubscription{ExpiresAt: &future}).IsExpired(now) {
+		t.Errorf("Expected a subscription before its expiration time not to be expired")
+	}
+}
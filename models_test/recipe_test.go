@@ -0,0 +1,177 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func recipeItem(name string, quantity float64, unit string, expiration time.Time) models.PantryItem {
+	return models.PantryItem{
+		ID:             primitive.NewObjectID(),
+		Name:           name,
+		Quantity:       quantity,
+		Unit:           unit,
+		ExpirationDate: expiration,
+	}
+}
+
+func TestValidateRecipe(t *testing.T) {
+	flour := []models.RecipeIngredient{{Name: "flour", Quantity: 200, Unit: "g"}}
+
+	tests := []struct {
+		name        string
+		recipe      string
+		servings    int
+		ingredients []models.RecipeIngredient
+		valid       bool
+	}{
+		{"Valid recipe", "Pancakes", 2, flour, true},
+		{"Missing name", " ", 2, flour, false},
+		{"No servings", "Pancakes", 0, flour, false},
+		{"No ingredients", "Pancakes", 2, nil, false},
+		{"Unnamed ingredient", "Pancakes", 2, []models.RecipeIngredient{{Quantity: 1}}, false},
+		{"Zero quantity", "Pancakes", 2, []models.RecipeIngredient{{Name: "egg"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := models.ValidateRecipe(tt.recipe, tt.servings, tt.ingredients)
+			if (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestRecipeScaledIngredients(t *testing.T) {
+	recipe := models.Recipe{
+		Servings: 4,
+		Ingredients: []models.RecipeIngredient{
+			{Name: "flour", Quantity: 200, Unit: "g"},
+			{Name: "egg", Quantity: 3},
+		},
+	}
+
+	scaled := recipe.ScaledIngredients(2)
+	if scaled[0].Quantity != 100 || scaled[1].Quantity != 1.5 {
+		t.Errorf("Expected 100 g flour and 1.5 eggs, got %v and %v", scaled[0].Quantity, scaled[1].Quantity)
+	}
+	if recipe.Ingredients[0].Quantity != 200 {
+		t.Errorf("Expected the recipe to be left unchanged, got %v g flour", recipe.Ingredients[0].Quantity)
+	}
+
+	own := recipe.ScaledIngredients(0)
+	if own[0].Quantity != 200 {
+		t.Errorf("Expected the recipe's own servings without servings given, got %v g flour", own[0].Quantity)
+	}
+}
+
+func TestCombineIngredients(t *testing.T) {
+	productID := primitive.NewObjectID()
+
+	combined := models.CombineIngredients([]models.RecipeIngredient{
+		{Name: "Flour", Quantity: 200, Unit: "g"},
+		{Name: "milk", Quantity: 250, Unit: "ml"},
+		{Name: "flour", Quantity: 1, Unit: "kg"},
+		{Name: "milk", Quantity: 1, Unit: "can"},
+		{Name: "Oat drink", Quantity: 1, Unit: "l", ProductID: &productID},
+		{Name: "oat milk", Quantity: 500, Unit: "ml", ProductID: &productID},
+	})
+
+	if len(combined) != 4 {
+		t.Fatalf("Expected 4 ingredients, got %d: %+v", len(combined), combined)
+	}
+	if combined[0].Quantity != 1200 || combined[0].Unit != "g" {
+		t.Errorf("Expected 1200 g flour, got %v %s", combined[0].Quantity, combined[0].Unit)
+	}
+	if combined[1].Quantity != 250 || combined[2].Unit != "can" {
+		t.Errorf("Expected milk in cans to stay apart, got %+v", combined[1:3])
+	}
+	if combined[3].Quantity != 1.5 || combined[3].Unit != "l" {
+		t.Errorf("Expected 1.5 l of the linked product, got %v %s", combined[3].Quantity, combined[3].Unit)
+	}
+}
+
+func TestCheckCoverage(t *testing.T) {
+	now := time.Now()
+	soon := recipeItem("Milk", 0.5, "l", now.AddDate(0, 0, 1))
+	later := recipeItem("milk", 1, "l", now.AddDate(0, 0, 5))
+	eggs := recipeItem("egg", 2, "", time.Time{})
+	flour := recipeItem("flour", 1, "bag", time.Time{})
+
+	coverage := models.CheckCoverage([]models.RecipeIngredient{
+		{Name: "milk", Quantity: 800, Unit: "ml"},
+		{Name: "egg", Quantity: 3},
+		{Name: "flour", Quantity: 200, Unit: "g"},
+	}, []models.PantryItem{later, eggs, soon, flour})
+
+	milk := coverage[0]
+	if !milk.IsCovered() || milk.Available != 1500 {
+		t.Errorf("Expected milk covered with 1500 ml available, got %+v", milk)
+	}
+	if len(milk.Items) != 2 || milk.Items[0].ItemID != soon.ID || milk.Items[0].Quantity != 0.5 || milk.Items[1].Quantity != 0.3 {
+		t.Errorf("Expected all of the first-expiring milk then 0.3 l of the next, got %+v", milk.Items)
+	}
+
+	if coverage[1].Missing != 1 || coverage[1].Items[0].Quantity != 2 {
+		t.Errorf("Expected 1 egg missing after taking both, got %+v", coverage[1])
+	}
+
+	if coverage[2].Missing != 200 || coverage[2].Available != 0 {
+		t.Errorf("Expected flour in bags not to count towards grams, got %+v", coverage[2])
+	}
+
+	missing := models.MissingIngredients(coverage)
+	if len(missing) != 2 || missing[0].Quantity != 1 || missing[1].Quantity != 200 {
+		t.Errorf("Expected 1 egg and 200 g flour missing, got %+v", missing)
+	}
+}
+
+func TestCheckCoverageSharesStock(t *testing.T) {
+	butter := recipeItem("butter", 100, "g", time.Time{})
+
+	coverage := models.CheckCoverage([]models.RecipeIngredient{
+		{Name: "butter", Quantity: 80, Unit: "g"},
+		{Name: "butter", Quantity: 1, Unit: "tbsp"},
+	}, []models.PantryItem{butter})
+
+	if !coverage[0].IsCovered() {
+		t.Errorf("Expected the first use of butter covered, got %+v", coverage[0])
+	}
+	if coverage[1].Available >= 2 {
+		t.Errorf("Expected only the 20 g left to be available to the next ingredient, got %v tbsp", coverage[1].Available)
+	}
+}
+
+func TestPlannedIngredients(t *testing.T) {
+	recipe := models.Recipe{
+		ID:          primitive.NewObjectID(),
+		Servings:    2,
+		Ingredients: []models.RecipeIngredient{{Name: "rice", Quantity: 150, Unit: "g"}},
+	}
+	cooked := time.Now()
+
+	entries := []models.MealPlanEntry{
+		{RecipeID: recipe.ID, Servings: 2},
+		{RecipeID: recipe.ID, Servings: 4},
+		{RecipeID: recipe.ID, Servings: 2, CookedAt: &cooked},
+		{RecipeID: primitive.NewObjectID(), Servings: 2},
+	}
+
+	ingredients := models.PlannedIngredients(entries, map[primitive.ObjectID]models.Recipe{recipe.ID: recipe})
+	if len(ingredients) != 1 || ingredients[0].Quantity != 450 {
+		t.Errorf("Expected 450 g rice for the uncooked meals, got %+v", ingredients)
+	}
+}
+
+func TestWeekDates(t *testing.T) {
+	start := models.BucketStart(models.AnalyticsBucketWeek, time.Date(2024, 3, 7, 18, 0, 0, 0, time.UTC), time.UTC)
+
+	dates := models.WeekDates(start)
+	if len(dates) != 7 || dates[0] != "2024-03-04" || dates[6] != "2024-03-10" {
+		t.Errorf("Expected Monday 2024-03-04 to Sunday 2024-03-10, got %v", dates)
+	}
+}
@@ -0,0 +1,117 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReminderQuietHours(t *testing.T) {
+	tests := []struct {
+		start, end string
+		clock      string
+		expected   bool
+	}{
+		{"22:00", "07:00", "23:30", true},
+		{"22:00", "07:00", "06:59", true},
+		{"22:00", "07:00", "07:00", false},
+		{"22:00", "07:00", "12:00", false},
+		{"13:00", "14:00", "13:30", true},
+		{"13:00", "14:00", "14:30", false},
+		{"", "", "03:00", false},
+	}
+
+	for _, tt := range tests {
+		prefs := models.ReminderPreferences{QuietHoursStart: tt.start, QuietHoursEnd: tt.end}
+		clock, _ := time.Parse("15:04", tt.clock)
+		at := time.Date(2025, 6, 2, clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+		if got := prefs.InQuietHours(at, time.UTC); got != tt.expected {
+			t.Errorf("%s-%s at %s: expected %v, got %v", tt.start, tt.end, tt.clock, tt.expected, got)
+		}
+	}
+}
+
+func TestReminderQuietHoursTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("Time zone data not available: %v", err)
+	}
+
+	prefs := models.DefaultReminderPreferences()
+	// 14:00 UTC is 23:00 in Tokyo
+	at := time.Date(2025, 6, 2, 14, 0, 0, 0, time.UTC)
+	if prefs.InQuietHours(at, time.UTC) {
+		t.Errorf("Expected 14:00 UTC to be outside quiet hours in UTC")
+	}
+	if !prefs.InQuietHours(at, tokyo) {
+		t.Errorf("Expected 14:00 UTC to be inside quiet hours in Tokyo")
+	}
+}
+
+func TestReminderPreferencesValidate(t *testing.T) {
+	available := []models.ReminderChannel{models.ReminderChannelInApp}
+
+	tests := []struct {
+		name    string
+		modify  func(p *models.ReminderPreferences)
+		wantErr bool
+	}{
+		{"defaults", func(p *models.ReminderPreferences) {}, false},
+		{"no channels", func(p *models.ReminderPreferences) { p.Channels = nil }, false},
+		{"unknown channel", func(p *models.ReminderPreferences) { p.Channels = []models.ReminderChannel{"pigeon"} }, true},
+		{"negative lead", func(p *models.ReminderPreferences) { p.DueSoonHours = -1 }, true},
+		{"digest hour", func(p *models.ReminderPreferences) { p.DigestHour = 24 }, true},
+		{"half quiet hours", func(p *models.ReminderPreferences) { p.QuietHoursEnd = "" }, true},
+		{"bad clock", func(p *models.ReminderPreferences) { p.QuietHoursStart = "10pm" }, true},
+		{"no quiet hours", func(p *models.ReminderPreferences) { p.QuietHoursStart, p.QuietHoursEnd = "", "" }, false},
+		{"bad time zone", func(p *models.ReminderPreferences) { p.Timezone = "Mars/Olympus" }, true},
+	}
+
+	for _, tt := range tests {
+		prefs := models.DefaultReminderPreferences()
+		tt.modify(&prefs)
+		err := prefs.Validate(available)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestReminderKeys(t *testing.T) {
+	choreID := primitive.NewObjectID()
+	userID := primitive.NewObjectID()
+	due := time.Date(2025, 6, 2, 18, 0, 0, 0, time.UTC)
+
+	if models.DueSoonReminderKey(choreID, userID, due) == models.OverdueReminderKey(choreID, userID, due) {
+		t.Errorf("Expected due-soon and overdue keys to differ")
+	}
+	if models.DueSoonReminderKey(choreID, userID, due) == models.DueSoonReminderKey(choreID, userID, due.Add(time.Hour)) {
+		t.Errorf("Expected a rescheduled chore to get a new key")
+	}
+	if models.DailyDigestReminderKey(userID, due) != models.DailyDigestReminderKey(userID, due.Add(3*time.Hour)) {
+		t.Errorf("Expected one digest key per day")
+	}
+}
+
+func TestDigestSummary(t *testing.T) {
+	bins := models.Chore{Title: "Bins"}
+	dishes := models.Chore{Title: "Dishes"}
+
+	tests := []struct {
+		dueToday, overdue []models.Chore
+		expected          string
+	}{
+		{nil, nil, ""},
+		{[]models.Chore{bins}, nil, "1 chore due today: Bins"},
+		{[]models.Chore{bins, dishes}, []models.Chore{dishes}, "2 chores due today; 1 overdue: Dishes"},
+		{nil, []models.Chore{bins, dishes}, "2 overdue"},
+	}
+
+	for _, tt := range tests {
+		if got := models.DigestSummary(tt.dueToday, tt.overdue); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
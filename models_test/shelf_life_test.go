@@ -0,0 +1,105 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResolveShelfLife(t *testing.T) {
+	groupID := primitive.NewObjectID()
+	otherGroupID := primitive.NewObjectID()
+	categoryID := primitive.NewObjectID()
+	productID := primitive.NewObjectID()
+	days := 10
+
+	seededCategory := models.ShelfLifeRule{CategoryID: &categoryID, Days: 7, FreezerMultiplier: 4}
+	groupCategory := models.ShelfLifeRule{GroupID: &groupID, CategoryID: &categoryID, Days: 5}
+	otherCategory := models.ShelfLifeRule{GroupID: &otherGroupID, CategoryID: &categoryID, Days: 1}
+	seededProduct := models.ShelfLifeRule{ProductID: &productID, Days: 12, FreezerMultiplier: 2}
+	groupProduct := models.ShelfLifeRule{GroupID: &groupID, ProductID: &productID, Days: 3}
+
+	tests := []struct {
+		name     string
+		rules    []models.ShelfLifeRule
+		product  *models.Product
+		expected models.ShelfLife
+		found    bool
+	}{
+		{"Nothing known", nil, nil, models.ShelfLife{}, false},
+		{"Seeded category", []models.ShelfLifeRule{seededCategory}, nil, models.ShelfLife{Days: 7, FreezerMultiplier: 4}, true},
+		{"Group category overrides seeded", []models.ShelfLifeRule{seededCategory, groupCategory}, nil, models.ShelfLife{Days: 5, FreezerMultiplier: 4}, true},
+		{"Other group's rule ignored", []models.ShelfLifeRule{otherCategory}, nil, models.ShelfLife{}, false},
+		{"Catalog shelf life over category", []models.ShelfLifeRule{groupCategory}, &models.Product{ID: productID, ShelfLifeDays: &days}, models.ShelfLife{Days: 10, FreezerMultiplier: 1}, true},
+		{"Seeded product over catalog", []models.ShelfLifeRule{seededCategory, seededProduct}, &models.Product{ID: productID, ShelfLifeDays: &days}, models.ShelfLife{Days: 12, FreezerMultiplier: 2}, true},
+		{"Group product wins", []models.ShelfLifeRule{seededCategory, seededProduct, groupProduct}, &models.Product{ID: productID}, models.ShelfLife{Days: 3, FreezerMultiplier: 4}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shelfLife, found := models.ResolveShelfLife(tt.rules, groupID, categoryID, tt.product)
+			if found != tt.found {
+				t.Fatalf("Expected found %v, got %v", tt.found, found)
+			}
+			if shelfLife != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, shelfLife)
+			}
+		})
+	}
+}
+
+func TestValidateShelfLifeRule(t *testing.T) {
+	tests := []struct {
+		name       string
+		days       int
+		multiplier float64
+		valid      bool
+	}{
+		{"Days only", 7, 0, true},
+		{"With multiplier", 7, 4, true},
+		{"No days", 0, 0, false},
+		{"Multiplier below 1", 7, 0.5, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := models.ValidateShelfLifeRule(tt.days, tt.multiplier)
+			if (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestShelfLifeFrozenDays(t *testing.T) {
+	shelfLife := models.ShelfLife{Days: 3, FreezerMultiplier: 40}
+	if shelfLife.FrozenDays() != 120 {
+		t.Errorf("Expected 120 frozen days, got %d", shelfLife.FrozenDays())
+	}
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if expected := from.AddDate(0, 0, 3); !shelfLife.ExpirationFrom(from).Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, shelfLife.ExpirationFrom(from))
+	}
+}
+
+func TestPantryItemExpirationEstimated(t *testing.T) {
+	now := time.Now()
+	estimated := models.NewPantryLot(1, now.AddDate(0, 0, 2), primitive.NewObjectID())
+	estimated.ExpirationEstimated = true
+	confirmed := models.NewPantryLot(1, now.AddDate(0, 0, 5), primitive.NewObjectID())
+
+	item := lotItem(confirmed, estimated)
+	if !item.ExpirationEstimated {
+		t.Errorf("Expected the item to report the estimated date of its first-expiring lot")
+	}
+
+	single := lotItem(estimated)
+	if err := single.SetExpirationDate(now.AddDate(0, 0, 3)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if single.ExpirationEstimated || single.Lots[0].ExpirationEstimated {
+		t.Errorf("Expected a date set by hand to be confirmed")
+	}
+}
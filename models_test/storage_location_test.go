@@ -0,0 +1,144 @@
+package models_test
+
+import (
+	"cribb-backend/models"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func storageLocation(kind models.StorageKind) *models.StorageLocation {
+	location := models.CreateStorageLocation(primitive.NewObjectID(), string(kind), kind, primitive.NewObjectID())
+	location.ID = primitive.NewObjectID()
+	return location
+}
+
+func TestIsValidStorageKind(t *testing.T) {
+	tests := []struct {
+		kind  models.StorageKind
+		valid bool
+	}{
+		{models.StorageKindFridge, true},
+		{models.StorageKindFreezer, true},
+		{models.StorageKindCupboard, true},
+		{models.StorageKindShelf, true},
+		{"garage", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := models.IsValidStorageKind(tt.kind); got != tt.valid {
+			t.Errorf("Expected IsValidStorageKind(%q) to be %v, got %v", tt.kind, tt.valid, got)
+		}
+	}
+}
+
+func TestStorageLocationCanPlace(t *testing.T) {
+	owner, other := primitive.NewObjectID(), primitive.NewObjectID()
+	shared := storageLocation(models.StorageKindFridge)
+	personal := storageLocation(models.StorageKindShelf)
+	personal.OwnerID = &owner
+
+	tests := []struct {
+		name     string
+		location *models.StorageLocation
+		userID   primitive.ObjectID
+		expected bool
+	}{
+		{"shared location", shared, other, true},
+		{"own personal location", personal, owner, true},
+		{"someone else's personal location", personal, other, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.location.CanPlace(tt.userID); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestPantryLotMoveTo(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	shelfLife := models.ShelfLife{Days: 3, FreezerMultiplier: 40}
+	fridge := storageLocation(models.StorageKindFridge)
+	cupboard := storageLocation(models.StorageKindCupboard)
+	freezer := storageLocation(models.StorageKindFreezer)
+
+	tests := []struct {
+		name       string
+		expiration time.Time
+		from       *models.StorageLocation
+		to         *models.StorageLocation
+		expected   time.Time
+		estimated  bool
+	}{
+		{"Into the freezer stretches the time left", now.AddDate(0, 0, 2), fridge, freezer, now.AddDate(0, 0, 80), true},
+		{"Out of the freezer shrinks it", now.AddDate(0, 0, 80), freezer, fridge, now.AddDate(0, 0, 2), true},
+		{"Undated lot gets the frozen shelf life", time.Time{}, cupboard, freezer, now.AddDate(0, 0, 120), true},
+		{"Between unfrozen locations", now.AddDate(0, 0, 2), fridge, cupboard, now.AddDate(0, 0, 2), false},
+		{"Expired lot keeps its date", now.AddDate(0, 0, -1), fridge, freezer, now.AddDate(0, 0, -1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lot := models.NewPantryLot(1, tt.expiration, primitive.NewObjectID())
+			lot.PlaceIn(tt.from, now.AddDate(0, 0, -1))
+
+			lot.MoveTo(tt.to, shelfLife, now)
+
+			if lot.LocationID == nil || *lot.LocationID != tt.to.ID {
+				t.Errorf("Expected the lot to be in %s, got %v", tt.to.Name, lot.LocationID)
+			}
+			if (lot.FrozenAt != nil) != tt.to.IsFreezer() {
+				t.Errorf("Expected frozen %v, got frozen at %v", tt.to.IsFreezer(), lot.FrozenAt)
+			}
+			if !lot.ExpirationDate.Equal(tt.expected) {
+				t.Errorf("Expected expiration %v, got %v", tt.expected, lot.ExpirationDate)
+			}
+			if lot.ExpirationEstimated != tt.estimated {
+				t.Errorf("Expected estimated %v, got %v", tt.estimated, lot.ExpirationEstimated)
+			}
+		})
+	}
+}
+
+func TestPantryItemMoveLots(t *testing.T) {
+	now := time.Now()
+	fridge := storageLocation(models.StorageKindFridge)
+	freezer := storageLocation(models.StorageKindFreezer)
+
+	first := models.NewPantryLot(1, now.AddDate(0, 0, 2), primitive.NewObjectID())
+	first.PlaceIn(fridge, now)
+	second := models.NewPantryLot(2, now.AddDate(0, 0, 5), primitive.NewObjectID())
+	second.PlaceIn(fridge, now)
+	item := lotItem(first, second)
+
+	if item.LocationID == nil || *item.LocationID != fridge.ID {
+		t.Fatalf("Expected the item to be in the fridge, got %v", item.LocationID)
+	}
+
+	moved, err := item.MoveLots(&first.ID, freezer, models.ShelfLife{Days: 3, FreezerMultiplier: 4}, now)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(moved) != 1 || moved[0].ID != first.ID {
+		t.Errorf("Expected only the first lot to move, got %+v", moved)
+	}
+	if item.LocationID != nil {
+		t.Errorf("Expected no item location once its lots are apart, got %v", item.LocationID)
+	}
+
+	if _, err := item.MoveLots(nil, freezer, models.ShelfLife{}, now); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if item.LocationID == nil || *item.LocationID != freezer.ID {
+		t.Errorf("Expected the item to be in the freezer, got %v", item.LocationID)
+	}
+
+	missing := primitive.NewObjectID()
+	if _, err := item.MoveLots(&missing, fridge, models.ShelfLife{}, now); !errors.Is(err, models.ErrLotNotFound) {
+		t.Errorf("Expected ErrLotNotFound, got %v", err)
+	}
+}
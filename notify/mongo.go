@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"context"
+	"cribb-backend/models"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDeliveryLog keeps delivery records in the reminder_deliveries collection, whose
+// unique index on key makes claims atomic
+type MongoDeliveryLog struct {
+	collection *mongo.Collection
+}
+
+// NewMongoDeliveryLog creates a delivery log backed by the given database
+func NewMongoDeliveryLog(db *mongo.Database) *MongoDeliveryLog {
+	return &MongoDeliveryLog{collection: db.Collection("reminder_deliveries")}
+}
+
+// Claim inserts a delivery record for the message, returning false if one already exists
+func (l *MongoDeliveryLog) Claim(ctx context.Context, message Message, now time.Time) (bool, error) {
+	_, err := l.collection.InsertOne(ctx, models.ReminderDelivery{
+		Key:       message.Key,
+		Kind:      message.Kind,
+		UserID:    message.UserID,
+		ChoreID:   message.ChoreID,
+		Status:    models.ReminderDeliverySending,
+		Channels:  []models.ReminderChannel{},
+		CreatedAt: now,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// MarkSent records a claimed message as sent
+func (l *MongoDeliveryLog) MarkSent(ctx context.Context, key string, channels []models.ReminderChannel, now time.Time) error {
+	_, err := l.collection.UpdateOne(ctx,
+		bson.M{"key": key},
+		bson.M{"$set": bson.M{
+			"status":   models.ReminderDeliverySent,
+			"channels": channels,
+			"sent_at":  now,
+		}},
+	)
+	return err
+}
+
+// Release deletes an unsent claim
+func (l *MongoDeliveryLog) Release(ctx context.Context, key string) error {
+	_, err := l.collection.DeleteOne(ctx, bson.M{"key": key, "status": models.ReminderDeliverySending})
+	return err
+}
+
+// InAppNotifier posts reminders to the recipient's notification stream
+type InAppNotifier struct {
+	collection *mongo.Collection
+}
+
+// NewInAppNotifier creates an in-app notifier backed by the given database
+func NewInAppNotifier(db *mongo.Database) *InAppNotifier {
+	return &InAppNotifier{collection: db.Collection("notifications")}
+}
+
+// Channel implements Notifier
+func (n *InAppNotifier) Channel() models.ReminderChannel {
+	return models.ReminderChannelInApp
+}
+
+// Send implements Notifier
+func (n *InAppNotifier) Send(ctx context.Context, message Message) error {
+	_, err := n.collection.InsertOne(ctx, NewReminderNotification(message))
+	return err
+}
+
+// NewReminderNotification builds the notification shown for a reminder
+func NewReminderNotification(message Message) *models.Notification {
+	notificationType := models.NotificationTypeChoreDigest
+	switch message.Kind {
+	case models.ReminderKindDueSoon:
+		notificationType = models.NotificationTypeChoreDueSoon
+	case models.ReminderKindOverdue:
+		notificationType = models.NotificationTypeChoreOverdue
+	}
+
+	text := message.Title
+	if message.Body != "" {
+		text += ": " + message.Body
+	}
+
+	return models.CreateChoreNotification(
+		message.GroupID,
+		message.UserID,
+		notificationType,
+		models.ChoreNotificationPayload{ChoreID: message.ChoreID},
+		text,
+	)
+}
+
+// MongoPushSubscriptionStore keeps push subscriptions in the push_subscriptions collection
+type MongoPushSubscriptionStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoPushSubscriptionStore creates a subscription store backed by the given database
+func NewMongoPushSubscriptionStore(db *mongo.Database) *MongoPushSubscriptionStore {
+	return &MongoPushSubscriptionStore{collection: db.Collection("push_subscriptions")}
+}
+
+// ForUsers implements PushSubscriptionStore
+func (s *MongoPushSubscriptionStore) ForUsers(ctx context.Context, userIDs []primitive.ObjectID) ([]models.PushSubscription, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{"user_id": bson.M{"$in": userIDs}})
+	if err != nil {
+		return nil, err
+	}
+	var subscriptions []models.PushSubscription
+	if err = cursor.All(ctx, &subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// Remove implements PushSubscriptionStore
+func (s *MongoPushSubscriptionStore) Remove(ctx context.Context, endpoint string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"endpoint": endpoint})
+	return err
+}
+
+// Delivered implements PushSubscriptionStore
+func (s *MongoPushSubscriptionStore) Delivered(ctx context.Context, endpoint string, now time.Time) error {
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"endpoint": endpoint},
+		bson.M{"$set": bson.M{"failure_count": 0, "last_push_at": now}},
+	)
+	return err
+}
+
+// Failed implements PushSubscriptionStore
+func (s *MongoPushSubscriptionStore) Failed(ctx context.Context, endpoint string) (int, error) {
+	var subscription models.PushSubscription
+	err := s.collection.FindOneAndUpdate(ctx,
+		bson.M{"endpoint": endpoint},
+		bson.M{"$inc": bson.M{"failure_count": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&subscription)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return subscription.FailureCount, nil
+}
@@ -0,0 +1,135 @@
+// Package notify delivers reminders to users over whichever channels they prefer, and
+// pushes notifications to their browsers with Web Push
+package notify
+
+import (
+	"context"
+	"cribb-backend/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Message is a reminder addressed to a single user
+type Message struct {
+	Kind    models.ReminderKind
+	Key     string // Deduplication key, see models.DueSoonReminderKey and friends
+	UserID  primitive.ObjectID
+	GroupID primitive.ObjectID
+	ChoreID *primitive.ObjectID
+	Title   string
+	Body    string
+}
+
+// Notifier sends messages over one channel
+type Notifier interface {
+	Channel() models.ReminderChannel
+	Send(ctx context.Context, message Message) error
+}
+
+// DeliveryLog remembers which reminders have been sent. Claim must be atomic across
+// processes so that a reminder is only ever sent once.
+type DeliveryLog interface {
+	// Claim reserves the message's key, returning false when it was already claimed
+	Claim(ctx context.Context, message Message, now time.Time) (bool, error)
+	// MarkSent records the channels a claimed message went out on
+	MarkSent(ctx context.Context, key string, channels []models.ReminderChannel, now time.Time) error
+	// Release gives up a claim so that the message can be sent again later
+	Release(ctx context.Context, key string) error
+}
+
+// Outcome describes what happened to a dispatched message
+type Outcome string
+
+const (
+	OutcomeSent      Outcome = "sent"
+	OutcomeDuplicate Outcome = "duplicate" // Already sent earlier
+	OutcomeDeferred  Outcome = "deferred"  // In the user's quiet hours, try again later
+	OutcomeSkipped   Outcome = "skipped"   // The user turned this reminder or every channel off
+)
+
+// Dispatcher routes messages to the channels each user has chosen
+type Dispatcher struct {
+	log       DeliveryLog
+	notifiers map[models.ReminderChannel]Notifier
+}
+
+// NewDispatcher creates a dispatcher over the given channels
+func NewDispatcher(log DeliveryLog, notifiers ...Notifier) *Dispatcher {
+	d := &Dispatcher{log: log, notifiers: make(map[models.ReminderChannel]Notifier)}
+	for _, notifier := range notifiers {
+		d.notifiers[notifier.Channel()] = notifier
+	}
+	return d
+}
+
+// Channels returns the channels the dispatcher can deliver on
+func (d *Dispatcher) Channels() []models.ReminderChannel {
+	channels := make([]models.ReminderChannel, 0, len(d.notifiers))
+	for channel := range d.notifiers {
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// Dispatch sends a message on every channel the user prefers. Reminders falling in quiet
+// hours are deferred without being recorded, so the next run picks them up again.
+//
+// The key is claimed before any channel is called, which makes delivery at most once: if
+// the process dies mid-send the reminder is dropped rather than repeated. When every
+// channel fails the claim is released so that the next run retries.
+func (d *Dispatcher) Dispatch(ctx context.Context, message Message, prefs models.ReminderPreferences, loc *time.Location, now time.Time) (Outcome, error) {
+	if !prefs.Enabled(message.Kind) {
+		return OutcomeSkipped, nil
+	}
+
+	var notifiers []Notifier
+	for _, channel := range prefs.Channels {
+		if notifier, ok := d.notifiers[channel]; ok {
+			notifiers = append(notifiers, notifier)
+		}
+	}
+	if len(notifiers) == 0 {
+		return OutcomeSkipped, nil
+	}
+
+	if prefs.InQuietHours(now, loc) {
+		return OutcomeDeferred, nil
+	}
+
+	claimed, err := d.log.Claim(ctx, message, now)
+	if err != nil {
+		return "", fmt.Errorf("failed to claim reminder %s: %v", message.Key, err)
+	}
+	if !claimed {
+		return OutcomeDuplicate, nil
+	}
+
+	var sent []models.ReminderChannel
+	var failures []string
+	for _, notifier := range notifiers {
+		if err := notifier.Send(ctx, message); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", notifier.Channel(), err))
+			continue
+		}
+		sent = append(sent, notifier.Channel())
+	}
+
+	if len(sent) == 0 {
+		if err := d.log.Release(ctx, message.Key); err != nil {
+			return "", fmt.Errorf("failed to release reminder %s after %s: %v", message.Key, strings.Join(failures, "; "), err)
+		}
+		return "", errors.New(strings.Join(failures, "; "))
+	}
+
+	if err := d.log.MarkSent(ctx, message.Key, sent, now); err != nil {
+		return OutcomeSent, fmt.Errorf("reminder %s was sent but not recorded: %v", message.Key, err)
+	}
+	if len(failures) > 0 {
+		return OutcomeSent, fmt.Errorf("reminder %s partly failed: %s", message.Key, strings.Join(failures, "; "))
+	}
+	return OutcomeSent, nil
+}
@@ -0,0 +1,405 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"cribb-backend/models"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxPushPayload is the largest plaintext that fits a single aes128gcm record of the 4096
+// bytes push services must accept (RFC 8291, section 4)
+const MaxPushPayload = 3993
+
+// pushRecordSize is the aes128gcm record size announced in the content coding header
+const pushRecordSize = 4096
+
+// ErrSubscriptionGone is returned when the push service no longer knows a subscription
+var ErrSubscriptionGone = errors.New("push subscription has expired or was removed")
+
+// VAPIDKeys identify this server to push services (RFC 8292)
+type VAPIDKeys struct {
+	PublicKey  string // base64url uncompressed P-256 point, handed to browsers as applicationServerKey
+	Subject    string // mailto: or https: contact for the push service operator
+	privateKey *ecdsa.PrivateKey
+}
+
+// ParseVAPIDKeys loads a key pair in the base64url format used by the web-push tooling: the
+// uncompressed public point and the raw 32 byte private scalar
+func ParseVAPIDKeys(publicKey, privateKey, subject string) (*VAPIDKeys, error) {
+	publicBytes, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(publicKey, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID public key: %v", err)
+	}
+	privateBytes, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(privateKey, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %v", err)
+	}
+
+	key, err := ecdh.P256().NewPrivateKey(privateBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %v", err)
+	}
+	if !bytes.Equal(key.PublicKey().Bytes(), publicBytes) {
+		return nil, errors.New("VAPID public key does not match the private key")
+	}
+	if !strings.HasPrefix(subject, "mailto:") && !strings.HasPrefix(subject, "https://") {
+		return nil, errors.New("VAPID subject must be a mailto: or https: URL")
+	}
+
+	return &VAPIDKeys{
+		PublicKey: base64.RawURLEncoding.EncodeToString(publicBytes),
+		Subject:   subject,
+		privateKey: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(publicBytes[1:33]),
+				Y:     new(big.Int).SetBytes(publicBytes[33:]),
+			},
+			D: new(big.Int).SetBytes(privateBytes),
+		},
+	}, nil
+}
+
+// GenerateVAPIDKeys creates a new key pair, returning the base64url public and private keys
+func GenerateVAPIDKeys() (publicKey string, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		base64.RawURLEncoding.EncodeToString(key.Bytes()), nil
+}
+
+// authorization builds the VAPID Authorization header for an endpoint's push service
+func (k *VAPIDKeys) authorization(endpoint string, now time.Time) (string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.RegisteredClaims{
+		Audience:  jwt.ClaimStrings{parsed.Scheme + "://" + parsed.Host},
+		ExpiresAt: jwt.NewNumericDate(now.Add(12 * time.Hour)),
+		Subject:   k.Subject,
+	})
+	signed, err := token.SignedString(k.privateKey)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + signed + ", k=" + k.PublicKey, nil
+}
+
+// EncryptPushPayload encrypts a payload for a subscription with the aes128gcm content
+// coding, as described in RFC 8291. A fresh key pair and salt are used for every message.
+func EncryptPushPayload(keys models.PushSubscriptionKeys, plaintext []byte) ([]byte, error) {
+	if len(plaintext) > MaxPushPayload {
+		return nil, fmt.Errorf("push payload is %d bytes, the limit is %d", len(plaintext), MaxPushPayload)
+	}
+
+	userAgentPublic, authSecret, err := keys.Decode()
+	if err != nil {
+		return nil, err
+	}
+	userAgentKey, err := ecdh.P256().NewPublicKey(userAgentPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscription key: %v", err)
+	}
+
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := serverKey.ECDH(userAgentKey)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	serverPublic := serverKey.PublicKey().Bytes()
+
+	// Combine the shared secret with the authentication secret (RFC 8291, section 3.4)
+	keyInfo := append([]byte("WebPush: info\x00"), userAgentPublic...)
+	keyInfo = append(keyInfo, serverPublic...)
+	inputKey := hkdfExpand(hkdfExtract(authSecret, sharedSecret), keyInfo, 32)
+
+	// Derive the content encryption key and nonce (RFC 8188, section 2.2)
+	pseudoRandomKey := hkdfExtract(salt, inputKey)
+	contentKey := hkdfExpand(pseudoRandomKey, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdfExpand(pseudoRandomKey, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// A single record, so it ends with the last-record delimiter and no padding
+	record := append(append([]byte{}, plaintext...), 0x02)
+
+	header := make([]byte, 0, 21+len(serverPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, pushRecordSize)
+	header = append(header, byte(len(serverPublic)))
+	header = append(header, serverPublic...)
+
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+func hkdfExtract(salt, secret []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(secret)
+	return mac.Sum(nil)
+}
+
+// hkdfExpand is HKDF-Expand for outputs of at most one SHA-256 block
+func hkdfExpand(pseudoRandomKey, info []byte, length int) []byte {
+	mac := hmac.New(sha256.New, pseudoRandomKey)
+	mac.Write(info)
+	mac.Write([]byte{0x01})
+	return mac.Sum(nil)[:length]
+}
+
+// PushPayload is the JSON document the service worker receives
+type PushPayload struct {
+	NotificationID primitive.ObjectID        `json:"notification_id"`
+	GroupID        primitive.ObjectID        `json:"group_id"`
+	Type           models.NotificationType   `json:"type"`
+	Domain         models.NotificationDomain `json:"domain"`
+	Title          string                    `json:"title"`
+	Body           string                    `json:"body"`
+}
+
+// NewPushPayload describes a notification for the service worker, titled with the group name
+func NewPushPayload(notification *models.Notification, groupName string) PushPayload {
+	return PushPayload{
+		NotificationID: notification.ID,
+		GroupID:        notification.GroupID,
+		Type:           notification.Type,
+		Domain:         notification.Domain,
+		Title:          groupName,
+		Body:           notification.Message,
+	}
+}
+
+// PushSubscriptionStore looks up and maintains push subscriptions
+type PushSubscriptionStore interface {
+	// ForUsers returns every subscription belonging to the given users
+	ForUsers(ctx context.Context, userIDs []primitive.ObjectID) ([]models.PushSubscription, error)
+	// Remove deletes a subscription that can no longer be delivered to
+	Remove(ctx context.Context, endpoint string) error
+	// Delivered records a successful push, resetting the failure count
+	Delivered(ctx context.Context, endpoint string, now time.Time) error
+	// Failed records a failed push and returns how many have failed in a row
+	Failed(ctx context.Context, endpoint string) (int, error)
+}
+
+// PushResult counts what happened to the subscriptions a payload was pushed to
+type PushResult struct {
+	Delivered int
+	Removed   int
+	Failed    int
+}
+
+// WebPush delivers payloads to push services with VAPID authentication. Requests that fail
+// with a network error, 429 or 5xx are retried with exponential backoff; subscriptions the
+// push service reports as gone are removed.
+type WebPush struct {
+	keys   *VAPIDKeys
+	store  PushSubscriptionStore
+	client *http.Client
+
+	TTL         time.Duration // How long the push service should hold a message for an offline device
+	MaxAttempts int
+	RetryDelay  time.Duration // Delay before the first retry, doubled on each further one
+	MaxDelay    time.Duration // Upper bound on a retry delay, including Retry-After
+}
+
+// NewWebPush creates a sender with the default retry policy
+func NewWebPush(keys *VAPIDKeys, store PushSubscriptionStore, client *http.Client) *WebPush {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &WebPush{
+		keys:        keys,
+		store:       store,
+		client:      client,
+		TTL:         24 * time.Hour,
+		MaxAttempts: 3,
+		RetryDelay:  time.Second,
+		MaxDelay:    30 * time.Second,
+	}
+}
+
+// Keys returns the VAPID keys the sender authenticates with
+func (p *WebPush) Keys() *VAPIDKeys {
+	return p.keys
+}
+
+// Push sends a payload to every subscription of the given users. Only store errors are
+// returned; delivery failures are counted in the result.
+func (p *WebPush) Push(ctx context.Context, userIDs []primitive.ObjectID, payload PushPayload, now time.Time) (PushResult, error) {
+	var result PushResult
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return result, err
+	}
+	subscriptions, err := p.store.ForUsers(ctx, userIDs)
+	if err != nil {
+		return result, err
+	}
+
+	for _, subscription := range subscriptions {
+		if subscription.IsExpired(now) {
+			if err := p.store.Remove(ctx, subscription.Endpoint); err != nil {
+				return result, err
+			}
+			result.Removed++
+			continue
+		}
+
+		err := p.Send(ctx, subscription, body)
+		switch {
+		case err == nil:
+			result.Delivered++
+			if err := p.store.Delivered(ctx, subscription.Endpoint, now); err != nil {
+				return result, err
+			}
+		case errors.Is(err, ErrSubscriptionGone):
+			result.Removed++
+			if err := p.store.Remove(ctx, subscription.Endpoint); err != nil {
+				return result, err
+			}
+		default:
+			result.Failed++
+			failures, storeErr := p.store.Failed(ctx, subscription.Endpoint)
+			if storeErr != nil {
+				return result, storeErr
+			}
+			if failures >= models.MaxPushFailures {
+				if err := p.store.Remove(ctx, subscription.Endpoint); err != nil {
+					return result, err
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// Send encrypts a payload and posts it to one subscription, retrying transient failures.
+// It returns ErrSubscriptionGone when the subscription should be removed.
+func (p *WebPush) Send(ctx context.Context, subscription models.PushSubscription, payload []byte) error {
+	body, err := EncryptPushPayload(subscription.Keys, payload)
+	if err != nil {
+		return err
+	}
+
+	delay := p.RetryDelay
+	var lastErr error
+	for attempt := 1; attempt <= p.MaxAttempts; attempt++ {
+		retryAfter, err := p.post(ctx, subscription.Endpoint, body)
+		if err == nil {
+			return nil
+		}
+		var transient *transientPushError
+		if !errors.As(err, &transient) {
+			return err
+		}
+		lastErr = err
+
+		if attempt == p.MaxAttempts {
+			break
+		}
+		wait := delay
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		if wait > p.MaxDelay {
+			wait = p.MaxDelay
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+	return fmt.Errorf("push failed after %d attempts: %v", p.MaxAttempts, lastErr)
+}
+
+// transientPushError is a failure worth retrying
+type transientPushError struct {
+	err error
+}
+
+func (e *transientPushError) Error() string { return e.err.Error() }
+
+// post makes a single delivery attempt, returning the push service's Retry-After if any
+func (p *WebPush) post(ctx context.Context, endpoint string, body []byte) (time.Duration, error) {
+	authorization, err := p.keys.authorization(endpoint, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(p.TTL.Seconds())))
+	req.Header.Set("Urgency", "normal")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, &transientPushError{err: err}
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return 0, ErrSubscriptionGone
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		var retryAfter time.Duration
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return retryAfter, &transientPushError{err: fmt.Errorf("push service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))}
+	default:
+		return 0, fmt.Errorf("push service rejected the message with %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+}
@@ -0,0 +1,176 @@
+package notify_test
+
+import (
+	"context"
+	"cribb-backend/models"
+	"cribb-backend/notify"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryLog is a DeliveryLog kept in memory, standing in for the unique index
+type memoryLog struct {
+	claims map[string]models.ReminderDeliveryStatus
+}
+
+func newMemoryLog() *memoryLog {
+	return &memoryLog{claims: make(map[string]models.ReminderDeliveryStatus)}
+}
+
+func (l *memoryLog) Claim(ctx context.Context, message notify.Message, now time.Time) (bool, error) {
+	if _, ok := l.claims[message.Key]; ok {
+		return false, nil
+	}
+	l.claims[message.Key] = models.ReminderDeliverySending
+	return true, nil
+}
+
+func (l *memoryLog) MarkSent(ctx context.Context, key string, channels []models.ReminderChannel, now time.Time) error {
+	l.claims[key] = models.ReminderDeliverySent
+	return nil
+}
+
+func (l *memoryLog) Release(ctx context.Context, key string) error {
+	delete(l.claims, key)
+	return nil
+}
+
+type fakeNotifier struct {
+	channel models.ReminderChannel
+	err     error
+	sent    []notify.Message
+}
+
+func (n *fakeNotifier) Channel() models.ReminderChannel { return n.channel }
+
+func (n *fakeNotifier) Send(ctx context.Context, message notify.Message) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, message)
+	return nil
+}
+
+func newMessage() notify.Message {
+	choreID := primitive.NewObjectID()
+	userID := primitive.NewObjectID()
+	due := time.Date(2025, 6, 2, 18, 0, 0, 0, time.UTC)
+	return notify.Message{
+		Kind:    models.ReminderKindDueSoon,
+		Key:     models.DueSoonReminderKey(choreID, userID, due),
+		UserID:  userID,
+		ChoreID: &choreID,
+		Title:   "Chore due soon",
+	}
+}
+
+// noon is outside the default quiet hours
+var noon = time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+
+func TestDispatchSendsOnce(t *testing.T) {
+	log := newMemoryLog()
+	inApp := &fakeNotifier{channel: models.ReminderChannelInApp}
+	dispatcher := notify.NewDispatcher(log, inApp)
+	prefs := models.DefaultReminderPreferences()
+	message := newMessage()
+
+	outcome, err := dispatcher.Dispatch(context.Background(), message, prefs, time.UTC, noon)
+	if err != nil || outcome != notify.OutcomeSent {
+		t.Fatalf("Expected sent, got %s (%v)", outcome, err)
+	}
+
+	// A second run, or another instance, plans the same reminder
+	restarted := notify.NewDispatcher(log, inApp)
+	outcome, err = restarted.Dispatch(context.Background(), message, prefs, time.UTC, noon.Add(15*time.Minute))
+	if err != nil || outcome != notify.OutcomeDuplicate {
+		t.Fatalf("Expected duplicate, got %s (%v)", outcome, err)
+	}
+	if len(inApp.sent) != 1 {
+		t.Errorf("Expected 1 message, got %d", len(inApp.sent))
+	}
+}
+
+func TestDispatchQuietHours(t *testing.T) {
+	log := newMemoryLog()
+	inApp := &fakeNotifier{channel: models.ReminderChannelInApp}
+	dispatcher := notify.NewDispatcher(log, inApp)
+	prefs := models.DefaultReminderPreferences()
+	message := newMessage()
+
+	night := time.Date(2025, 6, 2, 23, 0, 0, 0, time.UTC)
+	outcome, _ := dispatcher.Dispatch(context.Background(), message, prefs, time.UTC, night)
+	if outcome != notify.OutcomeDeferred {
+		t.Fatalf("Expected deferred, got %s", outcome)
+	}
+	if len(log.claims) != 0 {
+		t.Errorf("Expected a deferred reminder not to be claimed")
+	}
+
+	morning := time.Date(2025, 6, 3, 7, 15, 0, 0, time.UTC)
+	outcome, _ = dispatcher.Dispatch(context.Background(), message, prefs, time.UTC, morning)
+	if outcome != notify.OutcomeSent {
+		t.Errorf("Expected the reminder to go out after quiet hours, got %s", outcome)
+	}
+}
+
+func TestDispatchChannelPreferences(t *testing.T) {
+	inApp := &fakeNotifier{channel: models.ReminderChannelInApp}
+	dispatcher := notify.NewDispatcher(newMemoryLog(), inApp)
+
+	prefs := models.DefaultReminderPreferences()
+	prefs.Channels = nil
+	if outcome, _ := dispatcher.Dispatch(context.Background(), newMessage(), prefs, time.UTC, noon); outcome != notify.OutcomeSkipped {
+		t.Errorf("Expected skipped without channels, got %s", outcome)
+	}
+
+	prefs = models.DefaultReminderPreferences()
+	prefs.DueSoonHours = 0
+	if outcome, _ := dispatcher.Dispatch(context.Background(), newMessage(), prefs, time.UTC, noon); outcome != notify.OutcomeSkipped {
+		t.Errorf("Expected skipped with due-soon reminders off, got %s", outcome)
+	}
+	if len(inApp.sent) != 0 {
+		t.Errorf("Expected nothing sent, got %d", len(inApp.sent))
+	}
+}
+
+func TestDispatchRetriesAfterFailure(t *testing.T) {
+	log := newMemoryLog()
+	inApp := &fakeNotifier{channel: models.ReminderChannelInApp, err: errors.New("unavailable")}
+	dispatcher := notify.NewDispatcher(log, inApp)
+	prefs := models.DefaultReminderPreferences()
+	message := newMessage()
+
+	if _, err := dispatcher.Dispatch(context.Background(), message, prefs, time.UTC, noon); err == nil {
+		t.Fatalf("Expected an error when every channel fails")
+	}
+	if _, ok := log.claims[message.Key]; ok {
+		t.Errorf("Expected the claim to be released")
+	}
+
+	inApp.err = nil
+	outcome, err := dispatcher.Dispatch(context.Background(), message, prefs, time.UTC, noon)
+	if err != nil || outcome != notify.OutcomeSent {
+		t.Errorf("Expected the retry to send, got %s (%v)", outcome, err)
+	}
+}
+
+func TestDispatchPartialFailure(t *testing.T) {
+	log := newMemoryLog()
+	inApp := &fakeNotifier{channel: models.ReminderChannelInApp}
+	broken := &fakeNotifier{channel: "broken", err: errors.New("unavailable")}
+	dispatcher := notify.NewDispatcher(log, inApp, broken)
+	prefs := models.DefaultReminderPreferences()
+	prefs.Channels = []models.ReminderChannel{models.ReminderChannelInApp, "broken"}
+	message := newMessage()
+
+	outcome, err := dispatcher.Dispatch(context.Background(), message, prefs, time.UTC, noon)
+	if outcome != notify.OutcomeSent || err == nil {
+		t.Fatalf("Expected sent with an error, got %s (%v)", outcome, err)
+	}
+	if log.claims[message.Key] != models.ReminderDeliverySent {
+		t.Errorf("Expected the reminder to be recorded as sent")
+	}
+}
@@ -0,0 +1,356 @@
+package notify_test
+
+import (
+	"context"
+	"cribb-backend/models"
+	"cribb-backend/notify"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// device is a browser's side of a subscription: the key pair and auth secret it keeps
+type device struct {
+	privateKey *ecdh.PrivateKey
+	authSecret []byte
+}
+
+func newDevice(t *testing.T) *device {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := make([]byte, 16)
+	rand.Read(secret)
+	return &device{privateKey: key, authSecret: secret}
+}
+
+func (d *device) keys() models.PushSubscriptionKeys {
+	return models.PushSubscriptionKeys{
+		P256dh: base64.RawURLEncoding.EncodeToString(d.privateKey.PublicKey().Bytes()),
+		Auth:   base64.RawURLEncoding.EncodeToString(d.authSecret),
+	}
+}
+
+func hmacSHA256(key []byte, parts ...[]byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return mac.Sum(nil)
+}
+
+// decrypt reverses the aes128gcm content coding the way a browser would
+func (d *device) decrypt(t *testing.T, body []byte) []byte {
+	t.Helper()
+	if len(body) < 21 {
+		t.Fatalf("Expected an aes128gcm header, got %d bytes", len(body))
+	}
+	salt := body[:16]
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != 4096 {
+		t.Errorf("Expected record size 4096, got %d", rs)
+	}
+	idLength := int(body[20])
+	serverPublic := body[21 : 21+idLength]
+	ciphertext := body[21+idLength:]
+
+	serverKey, err := ecdh.P256().NewPublicKey(serverPublic)
+	if err != nil {
+		t.Fatalf("Expected the key id to be the server's public key: %v", err)
+	}
+	shared, err := d.privateKey.ECDH(serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), d.privateKey.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, serverPublic...)
+	inputKey := hmacSHA256(hmacSHA256(d.authSecret, shared), keyInfo, []byte{1})
+	prk := hmacSHA256(salt, inputKey)
+	contentKey := hmacSHA256(prk, []byte("Content-Encoding: aes128gcm\x00"), []byte{1})[:16]
+	nonce := hmacSHA256(prk, []byte("Content-Encoding: nonce\x00"), []byte{1})[:12]
+
+	block, _ := aes.NewCipher(contentKey)
+	gcm, _ := cipher.NewGCM(block)
+	record, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Failed to decrypt push message: %v", err)
+	}
+	if len(record) == 0 || record[len(record)-1] != 0x02 {
+		t.Fatalf("Expected the last-record delimiter")
+	}
+	return record[:len(record)-1]
+}
+
+// fakePushService answers with the queued status codes, then 201
+type fakePushService struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (s *fakePushService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+	s.bodies = append(s.bodies, body)
+	status := http.StatusCreated
+	if len(s.statuses) > 0 {
+		status, s.statuses = s.statuses[0], s.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+// memoryStore is a PushSubscriptionStore kept in memory
+type memoryStore struct {
+	subscriptions map[string]*models.PushSubscription
+}
+
+func (s *memoryStore) ForUsers(ctx context.Context, userIDs []primitive.ObjectID) ([]models.PushSubscription, error) {
+	var found []models.PushSubscription
+	for _, subscription := range s.subscriptions {
+		for _, userID := range userIDs {
+			if subscription.UserID == userID {
+				found = append(found, *subscription)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (s *memoryStore) Remove(ctx context.Context, endpoint string) error {
+	delete(s.subscriptions, endpoint)
+	return nil
+}
+
+func (s *memoryStore) Delivered(ctx context.Context, endpoint string, now time.Time) error {
+	if subscription, ok := s.subscriptions[endpoint]; ok {
+		subscription.FailureCount = 0
+		subscription.LastPushAt = &now
+	}
+	return nil
+}
+
+func (s *memoryStore) Failed(ctx context.Context, endpoint string) (int, error) {
+	subscription, ok := s.subscriptions[endpoint]
+	if !ok {
+		return 0, nil
+	}
+	subscription.FailureCount++
+	return subscription.FailureCount, nil
+}
+
+type pushFixture struct {
+	service *fakePushService
+	server  *httptest.Server
+	store   *memoryStore
+	sender  *notify.WebPush
+	keys    *notify.VAPIDKeys
+	device  *device
+	userID  primitive.ObjectID
+}
+
+func newPushFixture(t *testing.T) *pushFixture {
+	publicKey, privateKey, err := notify.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := notify.ParseVAPIDKeys(publicKey, privateKey, "mailto:admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to parse generated keys: %v", err)
+	}
+
+	f := &pushFixture{
+		service: &fakePushService{},
+		keys:    keys,
+		device:  newDevice(t),
+		userID:  primitive.NewObjectID(),
+	}
+	f.server = httptest.NewServer(f.service)
+	t.Cleanup(f.server.Close)
+
+	endpoint := f.server.URL + "/push/device-1"
+	f.store = &memoryStore{subscriptions: map[string]*models.PushSubscription{
+		endpoint: {UserID: f.userID, Endpoint: endpoint, Keys: f.device.keys()},
+	}}
+	f.sender = notify.NewWebPush(keys, f.store, f.server.Client())
+	f.sender.RetryDelay = time.Millisecond
+	f.sender.MaxDelay = 5 * time.Millisecond
+	return f
+}
+
+func (f *pushFixture) payload() notify.PushPayload {
+	notification := models.CreatePantryNotification(primitive.NewObjectID(), primitive.NewObjectID(), "Milk", models.NotificationTypeLowStock, "Milk is running low")
+	return notify.NewPushPayload(notification, "Flat 4B")
+}
+
+func TestWebPushDelivers(t *testing.T) {
+	f := newPushFixture(t)
+
+	result, err := f.sender.Push(context.Background(), []primitive.ObjectID{f.userID}, f.payload(), time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Delivered != 1 || len(f.service.requests) != 1 {
+		t.Fatalf("Expected 1 delivery, got %+v with %d requests", result, len(f.service.requests))
+	}
+
+	req := f.service.requests[0]
+	if got := req.Header.Get("Content-Encoding"); got != "aes128gcm" {
+		t.Errorf("Expected Content-Encoding aes128gcm, got %q", got)
+	}
+	if got := req.Header.Get("TTL"); got != "86400" {
+		t.Errorf("Expected TTL 86400, got %q", got)
+	}
+
+	// The VAPID token is signed for the push service's origin with our key
+	authorization := req.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "vapid t=") || !strings.HasSuffix(authorization, ", k="+f.keys.PublicKey) {
+		t.Fatalf("Expected a vapid authorization, got %q", authorization)
+	}
+	token := strings.TrimSuffix(strings.TrimPrefix(authorization, "vapid t="), ", k="+f.keys.PublicKey)
+	publicBytes, _ := base64.RawURLEncoding.DecodeString(f.keys.PublicKey)
+	verifyKey := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(publicBytes[1:33]),
+		Y:     new(big.Int).SetBytes(publicBytes[33:]),
+	}
+	claims := &jwt.RegisteredClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return verifyKey, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected a valid VAPID token: %v", err)
+	}
+	if !claims.VerifyAudience(f.server.URL, true) {
+		t.Errorf("Expected audience %s, got %v", f.server.URL, claims.Audience)
+	}
+	if claims.Subject != "mailto:admin@example.com" {
+		t.Errorf("Expected subject mailto:admin@example.com, got %q", claims.Subject)
+	}
+
+	var received notify.PushPayload
+	if err := json.Unmarshal(f.device.decrypt(t, f.service.bodies[0]), &received); err != nil {
+		t.Fatalf("Expected a JSON payload: %v", err)
+	}
+	if received.Title != "Flat 4B" || received.Body != "Milk is running low" || received.Type != models.NotificationTypeLowStock {
+		t.Errorf("Unexpected payload %+v", received)
+	}
+}
+
+func TestWebPushRetriesTransientFailures(t *testing.T) {
+	f := newPushFixture(t)
+	f.service.statuses = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+
+	result, err := f.sender.Push(context.Background(), []primitive.ObjectID{f.userID}, f.payload(), time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Delivered != 1 || len(f.service.requests) != 3 {
+		t.Errorf("Expected delivery on the third attempt, got %+v with %d requests", result, len(f.service.requests))
+	}
+}
+
+func TestWebPushGivesUpAfterMaxAttempts(t *testing.T) {
+	f := newPushFixture(t)
+	f.service.statuses = []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}
+
+	result, _ := f.sender.Push(context.Background(), []primitive.ObjectID{f.userID}, f.payload(), time.Now())
+	if result.Failed != 1 || len(f.service.requests) != 3 {
+		t.Errorf("Expected 1 failure after 3 attempts, got %+v with %d requests", result, len(f.service.requests))
+	}
+	for _, subscription := range f.store.subscriptions {
+		if subscription.FailureCount != 1 {
+			t.Errorf("Expected the failure to be recorded, got %d", subscription.FailureCount)
+		}
+	}
+}
+
+func TestWebPushDoesNotRetryRejectedMessages(t *testing.T) {
+	f := newPushFixture(t)
+	f.service.statuses = []int{http.StatusBadRequest}
+
+	result, _ := f.sender.Push(context.Background(), []primitive.ObjectID{f.userID}, f.payload(), time.Now())
+	if result.Failed != 1 || len(f.service.requests) != 1 {
+		t.Errorf("Expected a single failed attempt, got %+v with %d requests", result, len(f.service.requests))
+	}
+}
+
+func TestWebPushRemovesExpiredSubscriptions(t *testing.T) {
+	t.Run("Gone", func(t *testing.T) {
+		f := newPushFixture(t)
+		f.service.statuses = []int{http.StatusGone}
+
+		result, _ := f.sender.Push(context.Background(), []primitive.ObjectID{f.userID}, f.payload(), time.Now())
+		if result.Removed != 1 || len(f.store.subscriptions) != 0 {
+			t.Errorf("Expected the subscription to be removed, got %+v", result)
+		}
+	})
+
+	t.Run("Expiration time passed", func(t *testing.T) {
+		f := newPushFixture(t)
+		expired := time.Now().Add(-time.Minute)
+		for _, subscription := range f.store.subscriptions {
+			subscription.ExpiresAt = &expired
+		}
+
+		result, _ := f.sender.Push(context.Background(), []primitive.ObjectID{f.userID}, f.payload(), time.Now())
+		if result.Removed != 1 || len(f.service.requests) != 0 {
+			t.Errorf("Expected the subscription to be removed without a request, got %+v with %d requests", result, len(f.service.requests))
+		}
+	})
+
+	t.Run("Too many failures", func(t *testing.T) {
+		f := newPushFixture(t)
+		f.service.statuses = []int{http.StatusBadRequest}
+		for _, subscription := range f.store.subscriptions {
+			subscription.FailureCount = models.MaxPushFailures - 1
+		}
+
+		f.sender.Push(context.Background(), []primitive.ObjectID{f.userID}, f.payload(), time.Now())
+		if len(f.store.subscriptions) != 0 {
+			t.Errorf("Expected the failing subscription to be removed")
+		}
+	})
+}
+
+func TestParseVAPIDKeysRejectsMismatchedPair(t *testing.T) {
+	publicKey, _, _ := notify.GenerateVAPIDKeys()
+	_, privateKey, _ := notify.GenerateVAPIDKeys()
+
+	if _, err := notify.ParseVAPIDKeys(publicKey, privateKey, "mailto:admin@example.com"); err == nil {
+		t.Errorf("Expected an error for keys from different pairs")
+	}
+}
+
+func TestEncryptPushPayloadLimit(t *testing.T) {
+	keys := newDevice(t).keys()
+
+	if _, err := notify.EncryptPushPayload(keys, make([]byte, notify.MaxPushPayload)); err != nil {
+		t.Errorf("Expected the largest payload to be accepted: %v", err)
+	}
+	if _, err := notify.EncryptPushPayload(keys, make([]byte, notify.MaxPushPayload+1)); err == nil {
+		t.Errorf("Expected an oversized payload to be rejected")
+	}
+}
@@ -0,0 +1,153 @@
+// query/cursor.go
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Cursor is the sort position of the last item on a page. Pages are keyset-paginated on
+// (sort field, _id), so items inserted or removed between requests never shift the pages.
+type Cursor struct {
+	Sort       string             // Sort field the cursor was issued for
+	Descending bool               // Sort direction the cursor was issued for
+	Value      interface{}        // Sort field value of the last item, nil if the field was unset
+	ID         primitive.ObjectID // _id of the last item
+}
+
+// cursorPayload is the encoded form of a cursor
+type cursorPayload struct {
+	Sort       string          `json:"s"`
+	Descending bool            `json:"d,omitempty"`
+	Value      json.RawMessage `json:"v"`
+	ID         string          `json:"id"`
+}
+
+var errInvalidCursor = errors.New("cursor is invalid")
+
+// Encode returns the opaque string sent to clients as next_cursor
+func (c *Cursor) Encode() string {
+	value, err := json.Marshal(c.Value)
+	if err != nil {
+		value = []byte("null")
+	}
+	payload, _ := json.Marshal(cursorPayload{
+		Sort:       c.Sort,
+		Descending: c.Descending,
+		Value:      value,
+		ID:         c.ID.Hex(),
+	})
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// DecodeCursor parses a cursor and checks it was issued for the requested sort order
+func DecodeCursor(encoded string, sortKey SortKey) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, errInvalidCursor
+	}
+	id, err := primitive.ObjectIDFromHex(payload.ID)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	if payload.Sort != sortKey.Field.Name || payload.Descending != sortKey.Descending {
+		return nil, fmt.Errorf("cursor was issued for a different sort order; start again without a cursor")
+	}
+
+	value, err := decodeCursorValue(payload.Value, sortKey.Field.Type)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	return &Cursor{Sort: payload.Sort, Descending: payload.Descending, Value: value, ID: id}, nil
+}
+
+// decodeCursorValue restores the typed sort value, since JSON loses the distinction
+// between strings, IDs and timestamps
+func decodeCursorValue(raw json.RawMessage, fieldType FieldType) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	switch fieldType {
+	case ObjectID:
+		var hex string
+		if err := json.Unmarshal(raw, &hex); err != nil {
+			return nil, err
+		}
+		return primitive.ObjectIDFromHex(hex)
+	case Time:
+		var t time.Time
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return nil, err
+		}
+		return t.UTC(), nil
+	case Number:
+		var number float64
+		err := json.Unmarshal(raw, &number)
+		return number, err
+	case Bool:
+		var value bool
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	default:
+		var value string
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	}
+}
+
+// cursorAt returns the cursor pointing at an item, reading its sort field the way the item
+// is stored
+func (q *Query) cursorAt(item interface{}) (*Cursor, error) {
+	doc, err := bson.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := fieldValue(doc, "_id").(primitive.ObjectID)
+	return &Cursor{
+		Sort:       q.Sort.Field.Name,
+		Descending: q.Sort.Descending,
+		Value:      fieldValue(doc, q.Sort.Field.path()),
+		ID:         id,
+	}, nil
+}
+
+// fieldValue reads a field from a document. Missing and null fields are returned as nil,
+// numbers as float64 and dates as UTC times, matching the values Parse produces.
+func fieldValue(doc bson.Raw, path string) interface{} {
+	raw, err := doc.LookupErr(strings.Split(path, ".")...)
+	if err != nil {
+		return nil
+	}
+
+	switch raw.Type {
+	case bsontype.String:
+		return raw.StringValue()
+	case bsontype.ObjectID:
+		return raw.ObjectID()
+	case bsontype.DateTime:
+		return raw.Time().UTC()
+	case bsontype.Double:
+		return raw.Double()
+	case bsontype.Int32:
+		return float64(raw.Int32())
+	case bsontype.Int64:
+		return float64(raw.Int64())
+	case bsontype.Boolean:
+		return raw.Boolean()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,99 @@
+// query/mongo.go
+package query
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Filter combines the handler's base filter (group scoping, archive state, ...) with the
+// parsed filter expressions and the cursor position
+func (q *Query) Filter(base bson.M) bson.M {
+	clauses := make([]bson.M, 0, 3)
+	if len(base) > 0 {
+		clauses = append(clauses, base)
+	}
+
+	conditions := bson.M{}
+	for _, condition := range q.Conditions {
+		path := condition.Field.path()
+		operators, ok := conditions[path].(bson.M)
+		if !ok {
+			operators = bson.M{}
+			conditions[path] = operators
+		}
+		operators["$"+string(condition.Op)] = condition.operand()
+	}
+	if len(conditions) > 0 {
+		clauses = append(clauses, conditions)
+	}
+
+	if q.After != nil {
+		clauses = append(clauses, q.afterCursor())
+	}
+
+	switch len(clauses) {
+	case 0:
+		return bson.M{}
+	case 1:
+		return clauses[0]
+	default:
+		return bson.M{"$and": clauses}
+	}
+}
+
+func (c Condition) operand() interface{} {
+	if c.Op == OpIn || c.Op == OpNin {
+		return c.Values
+	}
+	return c.Values[0]
+}
+
+// afterCursor matches the items that sort after the cursor. MongoDB orders null and missing
+// values before any other value, so they come first ascending and last descending.
+func (q *Query) afterCursor() bson.M {
+	path := q.Sort.Field.path()
+	value, id := q.After.Value, q.After.ID
+
+	if !q.Sort.Descending {
+		if value == nil {
+			return bson.M{"$or": bson.A{
+				bson.M{path: nil, "_id": bson.M{"$gt": id}},
+				bson.M{path: bson.M{"$ne": nil}},
+			}}
+		}
+		return bson.M{"$or": bson.A{
+			bson.M{path: bson.M{"$gt": value}},
+			bson.M{path: value, "_id": bson.M{"$gt": id}},
+		}}
+	}
+
+	if value == nil {
+		return bson.M{path: nil, "_id": bson.M{"$lt": id}}
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{path: bson.M{"$lt": value}},
+		bson.M{path: value, "_id": bson.M{"$lt": id}},
+		bson.M{path: nil},
+	}}
+}
+
+// SortDocument orders by the sort field, then by _id so the order is total
+func (q *Query) SortDocument() bson.D {
+	direction := 1
+	if q.Sort.Descending {
+		direction = -1
+	}
+	return bson.D{
+		{Key: q.Sort.Field.path(), Value: direction},
+		{Key: "_id", Value: direction},
+	}
+}
+
+// FindOptions sorts and fetches one item more than the page size, which tells Paginate
+// whether there is a next page
+func (q *Query) FindOptions() *options.FindOptions {
+	return options.Find().
+		SetSort(q.SortDocument()).
+		SetLimit(int64(q.Limit + 1))
+}
@@ -0,0 +1,38 @@
+// query/page.go
+package query
+
+// Page is the response envelope shared by all list endpoints
+type Page struct {
+	Data       interface{} `json:"data"`
+	NextCursor string      `json:"next_cursor"` // Empty on the last page
+	HasMore    bool        `json:"has_more"`
+	Limit      int         `json:"limit"`
+}
+
+// NewPage wraps a page of results
+func NewPage(data interface{}, nextCursor string, limit int) Page {
+	return Page{
+		Data:       data,
+		NextCursor: nextCursor,
+		HasMore:    nextCursor != "",
+		Limit:      limit,
+	}
+}
+
+// Paginate trims results fetched with FindOptions to the page size and returns the
+// next_cursor, which is empty when this is the last page
+func Paginate[T any](items []T, q *Query) ([]T, string, error) {
+	if items == nil {
+		items = make([]T, 0)
+	}
+	if len(items) <= q.Limit {
+		return items, "", nil
+	}
+
+	items = items[:q.Limit]
+	last, err := q.cursorAt(items[len(items)-1])
+	if err != nil {
+		return nil, "", err
+	}
+	return items, last.Encode(), nil
+}
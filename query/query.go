@@ -0,0 +1,316 @@
+// query/query.go
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reserved query parameters
+const (
+	ParamSort   = "sort"
+	ParamLimit  = "limit"
+	ParamCursor = "cursor"
+)
+
+// FieldType is how a field's query parameter values are parsed
+type FieldType int
+
+const (
+	String FieldType = iota
+	ObjectID
+	Time
+	Number
+	Bool
+)
+
+// Operator is a filter comparison, written as field[op]=value
+type Operator string
+
+const (
+	OpEq  Operator = "eq"
+	OpNe  Operator = "ne"
+	OpIn  Operator = "in"
+	OpNin Operator = "nin"
+	OpGt  Operator = "gt"
+	OpGte Operator = "gte"
+	OpLt  Operator = "lt"
+	OpLte Operator = "lte"
+)
+
+// Field whitelists a document field for filtering and/or sorting
+type Field struct {
+	Name   string    // Query parameter name
+	Path   string    // Document field, defaults to Name
+	Type   FieldType // How values are parsed
+	Filter bool      // Can be used in filter expressions
+	Sort   bool      // Can be used as a sort key
+	Values []string  // Allowed values for enumerated string fields
+}
+
+// Spec describes what a list endpoint can be filtered and sorted by
+type Spec struct {
+	Fields       []Field
+	DefaultSort  string // Sort key, prefixed with "-" for descending
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// SortKey orders results by a field; ties are broken by _id in the same direction
+type SortKey struct {
+	Field      Field
+	Descending bool
+}
+
+// Condition is a single parsed filter expression
+type Condition struct {
+	Field  Field
+	Op     Operator
+	Values []interface{}
+}
+
+// Query is a validated list request
+type Query struct {
+	Conditions []Condition
+	Sort       SortKey
+	Limit      int
+	After      *Cursor // Position to resume from, nil for the first page
+}
+
+// FieldError describes an invalid query parameter
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error lists every invalid parameter of a request
+type Error struct {
+	Errors []FieldError
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		messages = append(messages, fieldErr.Field+": "+fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *Error) add(field, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// path returns the document field a query field refers to
+func (f Field) path() string {
+	if f.Path != "" {
+		return f.Path
+	}
+	return f.Name
+}
+
+func (s Spec) field(name string) (Field, bool) {
+	for _, field := range s.Fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+// Parse validates list parameters against the spec. Filters are written as field=value,
+// field=a,b (any of) or field[op]=value; sort=field or sort=-field orders the results, limit
+// sets the page size and cursor resumes from a previous page's next_cursor. Parameters that
+// are not whitelisted are left for the handler, but operators on unknown fields are rejected.
+func Parse(values url.Values, spec Spec) (*Query, error) {
+	problems := &Error{}
+	q := &Query{Limit: spec.DefaultLimit}
+
+	params := make([]string, 0, len(values))
+	for param := range values {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+
+	for _, param := range params {
+		rawValues := values[param]
+		name, op, bracketed := splitParam(param)
+		if name == ParamSort || name == ParamLimit || name == ParamCursor {
+			continue
+		}
+
+		field, known := spec.field(name)
+		if !known || !field.Filter {
+			if bracketed {
+				problems.add(param, "%s cannot be filtered", name)
+			}
+			continue
+		}
+
+		for _, raw := range rawValues {
+			condition, err := parseCondition(field, op, raw)
+			if err != nil {
+				problems.add(param, "%v", err)
+				continue
+			}
+			q.Conditions = append(q.Conditions, condition)
+		}
+	}
+
+	sortParam := values.Get(ParamSort)
+	if sortParam == "" {
+		sortParam = spec.DefaultSort
+	}
+	sortKey, sortErr := parseSort(sortParam, spec)
+	if sortErr != nil {
+		problems.add(ParamSort, "%v", sortErr)
+	}
+	q.Sort = sortKey
+
+	if limitParam := values.Get(ParamLimit); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > spec.MaxLimit {
+			problems.add(ParamLimit, "limit must be between 1 and %d", spec.MaxLimit)
+		} else {
+			q.Limit = limit
+		}
+	}
+
+	if cursorParam := values.Get(ParamCursor); cursorParam != "" && sortErr == nil {
+		after, err := DecodeCursor(cursorParam, q.Sort)
+		if err != nil {
+			problems.add(ParamCursor, "%v", err)
+		} else {
+			q.After = after
+		}
+	}
+
+	if len(problems.Errors) > 0 {
+		return nil, problems
+	}
+	return q, nil
+}
+
+// splitParam splits "due_date[gte]" into its field name and operator
+func splitParam(param string) (string, Operator, bool) {
+	open := strings.Index(param, "[")
+	if open < 0 || !strings.HasSuffix(param, "]") {
+		return param, OpEq, false
+	}
+	return param[:open], Operator(param[open+1 : len(param)-1]), true
+}
+
+func parseSort(value string, spec Spec) (SortKey, error) {
+	descending := strings.HasPrefix(value, "-")
+	name := strings.TrimPrefix(value, "-")
+
+	field, known := spec.field(name)
+	if !known || !field.Sort {
+		return SortKey{}, fmt.Errorf("cannot sort by %q; use one of %s", name, strings.Join(spec.sortable(), ", "))
+	}
+	return SortKey{Field: field, Descending: descending}, nil
+}
+
+func (s Spec) sortable() []string {
+	names := make([]string, 0, len(s.Fields))
+	for _, field := range s.Fields {
+		if field.Sort {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+func parseCondition(field Field, op Operator, raw string) (Condition, error) {
+	parts := strings.Split(raw, ",")
+
+	switch op {
+	case OpEq:
+		if len(parts) > 1 {
+			op = OpIn
+		}
+	case OpNe:
+		if len(parts) > 1 {
+			op = OpNin
+		}
+	case OpIn, OpNin:
+	case OpGt, OpGte, OpLt, OpLte:
+		if field.Type == Bool {
+			return Condition{}, fmt.Errorf("%s does not support %s", field.Name, op)
+		}
+		if len(parts) > 1 {
+			return Condition{}, fmt.Errorf("%s takes a single value", op)
+		}
+	default:
+		return Condition{}, fmt.Errorf("unknown operator %q", op)
+	}
+
+	condition := Condition{Field: field, Op: op, Values: make([]interface{}, 0, len(parts))}
+	for _, part := range parts {
+		value, err := parseValue(field, strings.TrimSpace(part))
+		if err != nil {
+			return Condition{}, err
+		}
+		condition.Values = append(condition.Values, value)
+	}
+	return condition, nil
+}
+
+func parseValue(field Field, raw string) (interface{}, error) {
+	switch field.Type {
+	case ObjectID:
+		id, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid ID", raw)
+		}
+		return id, nil
+	case Time:
+		t, err := parseTime(raw)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	case Number:
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", raw)
+		}
+		return number, nil
+	case Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not true or false", raw)
+		}
+		return value, nil
+	default:
+		if len(field.Values) > 0 && !contains(field.Values, raw) {
+			return nil, fmt.Errorf("%q is not one of %s", raw, strings.Join(field.Values, ", "))
+		}
+		return raw, nil
+	}
+}
+
+// parseTime accepts RFC3339 timestamps or YYYY-MM-DD dates (midnight UTC)
+func parseTime(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date; use RFC3339 or YYYY-MM-DD", raw)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
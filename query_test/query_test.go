@@ -0,0 +1,266 @@
+package query_test
+
+import (
+	"cribb-backend/models"
+	"cribb-backend/query"
+	"cribb-backend/test"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var choreSpec = query.Spec{
+	Fields: []query.Field{
+		{Name: "status", Type: query.String, Filter: true, Values: []string{"pending", "completed", "overdue"}},
+		{Name: "assigned_to", Type: query.ObjectID, Filter: true},
+		{Name: "due_date", Type: query.Time, Filter: true, Sort: true},
+		{Name: "points", Type: query.Number, Filter: true, Sort: true},
+	},
+	DefaultSort:  "due_date",
+	DefaultLimit: 20,
+	MaxLimit:     100,
+}
+
+var pantrySpec = query.Spec{
+	Fields: []query.Field{
+		{Name: "expiration_date", Type: query.Time, Filter: true, Sort: true},
+		{Name: "name", Type: query.String, Sort: true},
+	},
+	DefaultSort:  "name",
+	DefaultLimit: 20,
+	MaxLimit:     100,
+}
+
+func mustParse(t *testing.T, rawQuery string, spec query.Spec) *query.Query {
+	t.Helper()
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := query.Parse(values, spec)
+	if err != nil {
+		t.Fatalf("Unexpected error for %q: %v", rawQuery, err)
+	}
+	return q
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name          string
+		rawQuery      string
+		expectedField string // Parameter named in the error, empty if valid
+	}{
+		{"defaults", "group_name=Home", ""},
+		{"any of", "status=pending,overdue", ""},
+		{"range", "due_date[gte]=2025-03-01&due_date[lt]=2025-04-01T00:00:00Z", ""},
+		{"descending sort", "sort=-points&limit=5", ""},
+		{"unknown sort", "sort=title", "sort"},
+		{"operator on unknown field", "title[eq]=Bins", "title[eq]"},
+		{"unknown operator", "points[like]=3", "points[like]"},
+		{"value not allowed", "status=done", "status"},
+		{"bad date", "due_date[gte]=yesterday", "due_date[gte]"},
+		{"bad id", "assigned_to=alice", "assigned_to"},
+		{"range with several values", "points[gt]=1,2", "points[gt]"},
+		{"limit too large", "limit=500", "limit"},
+		{"bad cursor", "cursor=not-a-cursor", "cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.rawQuery)
+			_, err := query.Parse(values, choreSpec)
+			if tt.expectedField == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			queryErr, ok := err.(*query.Error)
+			if !ok {
+				t.Fatalf("Expected a *query.Error, got %v", err)
+			}
+			if len(queryErr.Errors) != 1 || queryErr.Errors[0].Field != tt.expectedField {
+				t.Errorf("Expected an error for %s, got %+v", tt.expectedField, queryErr.Errors)
+			}
+		})
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	q := mustParse(t, "status=pending,overdue&group_name=Home", choreSpec)
+
+	if q.Limit != 20 {
+		t.Errorf("Expected default limit 20, got %d", q.Limit)
+	}
+	if q.Sort.Field.Name != "due_date" || q.Sort.Descending {
+		t.Errorf("Expected ascending due_date sort, got %+v", q.Sort)
+	}
+	if len(q.Conditions) != 1 || q.Conditions[0].Op != query.OpIn || len(q.Conditions[0].Values) != 2 {
+		t.Errorf("Expected a single in condition with 2 values, got %+v", q.Conditions)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	q := mustParse(t, "due_date[gte]=2025-03-01&due_date[lt]=2025-04-01&status=pending", choreSpec)
+	filter := q.Filter(bson.M{"group_id": "g1"})
+
+	clauses, ok := filter["$and"].([]bson.M)
+	if !ok || len(clauses) != 2 {
+		t.Fatalf("Expected the base filter and the conditions to be combined, got %v", filter)
+	}
+	conditions := clauses[1]
+	dueDate, ok := conditions["due_date"].(bson.M)
+	if !ok || len(dueDate) != 2 {
+		t.Errorf("Expected both due_date bounds on one field, got %v", conditions["due_date"])
+	}
+	if !dueDate["$gte"].(time.Time).Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected lower bound 2025-03-01, got %v", dueDate["$gte"])
+	}
+	if status, ok := conditions["status"].(bson.M); !ok || status["$eq"] != "pending" {
+		t.Errorf("Expected status filter, got %v", conditions["status"])
+	}
+
+	if plain := mustParse(t, "", choreSpec).Filter(bson.M{"group_id": "g1"}); plain["group_id"] != "g1" || len(plain) != 1 {
+		t.Errorf("Expected the base filter unchanged without conditions, got %v", plain)
+	}
+
+	sortDoc := mustParse(t, "sort=-points", choreSpec).SortDocument()
+	if len(sortDoc) != 2 || sortDoc[0].Key != "points" || sortDoc[0].Value != -1 || sortDoc[1].Key != "_id" {
+		t.Errorf("Expected sort by points then _id descending, got %v", sortDoc)
+	}
+}
+
+func TestFilterAfterCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+	expiry := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		sort     string
+		value    interface{}
+		expected bson.M
+	}{
+		{"ascending", "expiration_date", expiry, bson.M{"$or": bson.A{
+			bson.M{"expiration_date": bson.M{"$gt": expiry}},
+			bson.M{"expiration_date": expiry, "_id": bson.M{"$gt": id}},
+		}}},
+		// Unset values sort first ascending, so every set value comes after them
+		{"ascending from unset", "expiration_date", nil, bson.M{"$or": bson.A{
+			bson.M{"expiration_date": nil, "_id": bson.M{"$gt": id}},
+			bson.M{"expiration_date": bson.M{"$ne": nil}},
+		}}},
+		// Unset values sort last descending, after every set value
+		{"descending", "-expiration_date", expiry, bson.M{"$or": bson.A{
+			bson.M{"expiration_date": bson.M{"$lt": expiry}},
+			bson.M{"expiration_date": expiry, "_id": bson.M{"$lt": id}},
+			bson.M{"expiration_date": nil},
+		}}},
+		{"descending from unset", "-expiration_date", nil, bson.M{"expiration_date": nil, "_id": bson.M{"$lt": id}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := mustParse(t, "sort="+tt.sort, pantrySpec)
+			q.After = &query.Cursor{Sort: q.Sort.Field.Name, Descending: q.Sort.Descending, Value: tt.value, ID: id}
+
+			filter := q.Filter(bson.M{"group_id": "g1"})
+			expected := bson.M{"$and": []bson.M{{"group_id": "g1"}, tt.expected}}
+			if !reflect.DeepEqual(filter, expected) {
+				t.Errorf("Expected filter %v, got %v", expected, filter)
+			}
+		})
+	}
+}
+
+func TestFindOptions(t *testing.T) {
+	q := mustParse(t, "sort=-points&limit=5", choreSpec)
+	opts := q.FindOptions()
+
+	if opts.Limit == nil || *opts.Limit != 6 {
+		t.Errorf("Expected one item more than the page size, got %v", opts.Limit)
+	}
+	if !reflect.DeepEqual(opts.Sort, q.SortDocument()) {
+		t.Errorf("Expected the sort document, got %v", opts.Sort)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	base := time.Date(2025, 3, 1, 23, 59, 0, 0, time.UTC)
+	chores := make([]models.Chore, 0, 3)
+	for i := 0; i < 3; i++ {
+		chore := test.CreateTestChore()
+		chore.DueDate = base.AddDate(0, 0, i)
+		chores = append(chores, chore)
+	}
+
+	q := mustParse(t, "limit=2", choreSpec)
+	page, next, err := query.Paginate(chores, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || next == "" {
+		t.Fatalf("Expected 2 chores and a next cursor, got %d and %q", len(page), next)
+	}
+
+	// The next page resumes after the last chore returned
+	resumed := mustParse(t, "limit=2&cursor="+next, choreSpec)
+	if resumed.After.ID != page[1].ID || !resumed.After.Value.(time.Time).Equal(page[1].DueDate) {
+		t.Errorf("Expected the cursor at chore %s due %v, got %+v", page[1].ID.Hex(), page[1].DueDate, resumed.After)
+	}
+	expected := bson.M{"$and": []bson.M{
+		{"group_id": "g1"},
+		{"$or": bson.A{
+			bson.M{"due_date": bson.M{"$gt": page[1].DueDate}},
+			bson.M{"due_date": page[1].DueDate, "_id": bson.M{"$gt": page[1].ID}},
+		}},
+	}}
+	if filter := resumed.Filter(bson.M{"group_id": "g1"}); !reflect.DeepEqual(filter, expected) {
+		t.Errorf("Expected filter %v, got %v", expected, filter)
+	}
+
+	if page, next, _ := query.Paginate(chores[:2], q); len(page) != 2 || next != "" {
+		t.Errorf("Expected the last page without a cursor, got %d chores and %q", len(page), next)
+	}
+	if page, _, _ := query.Paginate([]models.Chore(nil), q); page == nil {
+		t.Errorf("Expected an empty page rather than nil")
+	}
+}
+
+func TestPaginateUnsetSortField(t *testing.T) {
+	items := []models.PantryItem{
+		{ID: primitive.NewObjectID(), Name: "Salt"},
+		{ID: primitive.NewObjectID(), Name: "Rice"},
+	}
+
+	q := mustParse(t, "sort=expiration_date&limit=1", pantrySpec)
+	_, next, err := query.Paginate(items, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An item without an expiry date leaves a cursor with no value, not the zero time
+	resumed := mustParse(t, "sort=expiration_date&cursor="+next, pantrySpec)
+	if resumed.After.Value != nil || resumed.After.ID != items[0].ID {
+		t.Errorf("Expected an unset cursor value at %s, got %+v", items[0].ID.Hex(), resumed.After)
+	}
+}
+
+func TestCursorMustMatchSort(t *testing.T) {
+	chores := []models.Chore{test.CreateTestChore(), test.CreateTestChore()}
+
+	q := mustParse(t, "limit=1", choreSpec)
+	_, next, err := query.Paginate(chores, q)
+	if err != nil || next == "" {
+		t.Fatalf("Expected a next cursor, got %q (%v)", next, err)
+	}
+
+	values := url.Values{"cursor": {next}, "sort": {"-due_date"}}
+	if _, err := query.Parse(values, choreSpec); err == nil {
+		t.Errorf("Expected a cursor issued for another sort order to be rejected")
+	}
+}
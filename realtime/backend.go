@@ -0,0 +1,166 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MemoryBackend delivers events within a single process. It suits one instance and tests.
+type MemoryBackend struct {
+	events chan Event
+}
+
+// NewMemoryBackend creates an in-process backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{events: make(chan Event, 1024)}
+}
+
+// Publish implements Backend
+func (b *MemoryBackend) Publish(ctx context.Context, event Event) error {
+	select {
+	case b.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run implements Backend
+func (b *MemoryBackend) Run(ctx context.Context, deliver func(Event)) error {
+	for {
+		select {
+		case event := <-b.events:
+			deliver(event)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// MongoEventsCollection is the capped collection the Mongo backend publishes through
+const MongoEventsCollection = "realtime_events"
+
+// mongoEventsSize caps the collection in bytes; old events are overwritten
+const mongoEventsSize = 16 << 20
+
+// MongoBackend fans events out across instances through a capped collection, which every
+// instance follows with a tailable cursor. It needs no replica set.
+type MongoBackend struct {
+	db *mongo.Database
+}
+
+// NewMongoBackend creates a backend on the given database
+func NewMongoBackend(db *mongo.Database) *MongoBackend {
+	return &MongoBackend{db: db}
+}
+
+// EnsureCollection creates the capped collection if it does not exist yet
+func (b *MongoBackend) EnsureCollection(ctx context.Context) error {
+	names, err := b.db.ListCollectionNames(ctx, bson.M{"name": MongoEventsCollection})
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		return nil
+	}
+	err = b.db.CreateCollection(ctx, MongoEventsCollection, options.CreateCollection().SetCapped(true).SetSizeInBytes(mongoEventsSize))
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Name == "NamespaceExists" {
+		return nil
+	}
+	return err
+}
+
+// Publish implements Backend
+func (b *MongoBackend) Publish(ctx context.Context, event Event) error {
+	_, err := b.db.Collection(MongoEventsCollection).InsertOne(ctx, event)
+	return err
+}
+
+// Run implements Backend. It follows events published from the moment it starts, in the
+// collection's insertion order: IDs minted on different instances are not strictly ordered,
+// so the cursor is positioned by skipping up to the last event seen rather than by ID.
+func (b *MongoBackend) Run(ctx context.Context, deliver func(Event)) error {
+	if err := b.EnsureCollection(ctx); err != nil {
+		return err
+	}
+	collection := b.db.Collection(MongoEventsCollection)
+
+	after, err := b.lastEventID(ctx)
+	if err != nil {
+		return err
+	}
+
+	for {
+		// The last event seen may have been overwritten while the cursor was down; carry
+		// on from the newest event rather than replaying the whole collection
+		if after != nil {
+			err := collection.FindOne(ctx, bson.M{"_id": *after}).Err()
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				log.Printf("Realtime events were overwritten before they could be delivered")
+				if after, err = b.lastEventID(ctx); err != nil {
+					return err
+				}
+			} else if err != nil {
+				return err
+			}
+		}
+
+		cursor, err := collection.Find(ctx, bson.M{},
+			options.Find().SetCursorType(options.TailableAwait).SetMaxAwaitTime(5*time.Second),
+		)
+		if err != nil {
+			return err
+		}
+
+		skipping := after != nil
+		for cursor.Next(ctx) {
+			var event Event
+			if err := cursor.Decode(&event); err != nil {
+				cursor.Close(ctx)
+				return err
+			}
+			if skipping {
+				skipping = event.ID != *after
+				continue
+			}
+			id := event.ID
+			after = &id
+			deliver(event)
+		}
+		err = cursor.Err()
+		cursor.Close(ctx)
+		if err != nil {
+			return err
+		}
+
+		// A tailable cursor on an empty collection dies straight away; wait and retry
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// lastEventID returns the ID of the newest event in the collection, nil if it is empty
+func (b *MongoBackend) lastEventID(ctx context.Context) (*primitive.ObjectID, error) {
+	var event Event
+	err := b.db.Collection(MongoEventsCollection).FindOne(ctx, bson.M{},
+		options.FindOne().SetSort(bson.D{{Key: "$natural", Value: -1}}),
+	).Decode(&event)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event.ID, nil
+}
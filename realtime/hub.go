@@ -0,0 +1,233 @@
+// Package realtime fans out group events to connected clients. Events are published through
+// a Backend so that every instance behind a load balancer sees them; each instance's Hub
+// keeps its own subscribers and a short history for clients resuming after a reconnect.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EventType names what happened in a group
+type EventType string
+
+const (
+	EventChoreCreated   EventType = "chore.created"
+	EventChoreUpdated   EventType = "chore.updated"
+	EventChoreCompleted EventType = "chore.completed"
+	EventChoreDeleted   EventType = "chore.deleted"
+	EventChoreOverdue   EventType = "chore.overdue"
+
+	EventPantryItemAdded     EventType = "pantry.item_added"
+	EventPantryItemUpdated   EventType = "pantry.item_updated"
+	EventPantryItemUsed      EventType = "pantry.item_used"
+	EventPantryItemDeleted   EventType = "pantry.item_deleted"
+	EventPantryItemMoved     EventType = "pantry.item_moved"
+	EventPantryItemDiscarded EventType = "pantry.item_discarded"
+	EventPantryLowStock      EventType = "pantry.low_stock"
+	EventPantryExpiring      EventType = "pantry.expiring"
+	EventPantryRunningOut    EventType = "pantry.running_out"
+
+	EventCartItemAdded   EventType = "cart.item_added"
+	EventCartItemUpdated EventType = "cart.item_updated"
+	EventCartItemRemoved EventType = "cart.item_removed"
+
+	// EventReset tells a resuming client that events were missed and it should refetch
+	EventReset EventType = "reset"
+)
+
+// EventTypes lists the group events clients and webhooks can receive
+var EventTypes = []EventType{
+	EventChoreCreated, EventChoreUpdated, EventChoreCompleted, EventChoreDeleted, EventChoreOverdue,
+	EventPantryItemAdded, EventPantryItemUpdated, EventPantryItemUsed, EventPantryItemDeleted,
+	EventPantryItemMoved, EventPantryItemDiscarded, EventPantryLowStock, EventPantryExpiring, EventPantryRunningOut,
+	EventCartItemAdded, EventCartItemUpdated, EventCartItemRemoved,
+}
+
+// IsValid checks if the event type is one of EventTypes
+func (t EventType) IsValid() bool {
+	for _, eventType := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultHistory is how many recent events a hub keeps per group for resuming clients
+const DefaultHistory = 256
+
+// subscriberBuffer is how many events may queue for a slow client before it is dropped
+const subscriberBuffer = 64
+
+// Event is a change broadcast to the members of a group. The ID orders events and is what
+// clients send back as Last-Event-ID.
+type Event struct {
+	ID        primitive.ObjectID  `bson:"_id" json:"id"`
+	GroupID   primitive.ObjectID  `bson:"group_id" json:"group_id"`
+	Type      EventType           `bson:"type" json:"type"`
+	ActorID   *primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	Data      json.RawMessage     `bson:"data" json:"data"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// NewEvent builds an event, encoding data as its JSON payload
+func NewEvent(groupID primitive.ObjectID, eventType EventType, actorID *primitive.ObjectID, data interface{}) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		ID:        primitive.NewObjectID(),
+		GroupID:   groupID,
+		Type:      eventType,
+		ActorID:   actorID,
+		Data:      payload,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// Backend carries published events to the hubs of every instance
+type Backend interface {
+	// Publish hands an event to every running instance, including this one
+	Publish(ctx context.Context, event Event) error
+	// Run passes every published event to deliver, in publication order, until ctx is done
+	Run(ctx context.Context, deliver func(Event)) error
+}
+
+// Hub keeps the subscribers of each group on this instance
+type Hub struct {
+	backend Backend
+	history int
+
+	mu     sync.Mutex
+	groups map[primitive.ObjectID]*groupStream
+}
+
+type groupStream struct {
+	subscribers map[*Subscription]struct{}
+	recent      []Event // Oldest first, at most history long
+}
+
+// Subscription is one client's stream of a group's events
+type Subscription struct {
+	hub     *Hub
+	groupID primitive.ObjectID
+	events  chan Event
+	once    sync.Once
+}
+
+// NewHub creates a hub on a backend, keeping history events per group for resuming
+func NewHub(backend Backend, history int) *Hub {
+	return &Hub{
+		backend: backend,
+		history: history,
+		groups:  make(map[primitive.ObjectID]*groupStream),
+	}
+}
+
+// Start receives events from the backend until ctx is done, restarting it after errors
+func (h *Hub) Start(ctx context.Context) {
+	go func() {
+		for {
+			err := h.backend.Run(ctx, h.deliver)
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Realtime backend stopped: %v; restarting", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+}
+
+// Publish broadcasts an event to the group's subscribers on every instance
+func (h *Hub) Publish(ctx context.Context, event Event) error {
+	return h.backend.Publish(ctx, event)
+}
+
+// Subscribe starts streaming a group's events. When lastEventID is set, the events after it
+// are returned for replay; resumed is false if the ID is no longer in the history, in which
+// case the client has missed events and should refetch.
+func (h *Hub) Subscribe(groupID primitive.ObjectID, lastEventID string) (subscription *Subscription, replay []Event, resumed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stream := h.stream(groupID)
+	subscription = &Subscription{hub: h, groupID: groupID, events: make(chan Event, subscriberBuffer)}
+	stream.subscribers[subscription] = struct{}{}
+
+	if lastEventID == "" {
+		return subscription, nil, true
+	}
+	for i, event := range stream.recent {
+		if event.ID.Hex() == lastEventID {
+			replay = append(replay, stream.recent[i+1:]...)
+			return subscription, replay, true
+		}
+	}
+	return subscription, nil, false
+}
+
+// Events returns the subscription's events. The channel is closed when the subscription
+// ends, including when the client fell too far behind.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.close()
+}
+
+// close must be called with the hub locked
+func (s *Subscription) close() {
+	s.once.Do(func() {
+		if stream, ok := s.hub.groups[s.groupID]; ok {
+			delete(stream.subscribers, s)
+		}
+		close(s.events)
+	})
+}
+
+// deliver records an event in the group's history and passes it to its subscribers.
+// Subscribers whose buffer is full are dropped so one slow client cannot stall the rest;
+// they reconnect and resume from their last event.
+func (h *Hub) deliver(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stream := h.stream(event.GroupID)
+	stream.recent = append(stream.recent, event)
+	if len(stream.recent) > h.history {
+		stream.recent = append([]Event(nil), stream.recent[len(stream.recent)-h.history:]...)
+	}
+
+	for subscription := range stream.subscribers {
+		select {
+		case subscription.events <- event:
+		default:
+			subscription.close()
+		}
+	}
+}
+
+// stream must be called with the hub locked
+func (h *Hub) stream(groupID primitive.ObjectID) *groupStream {
+	stream, ok := h.groups[groupID]
+	if !ok {
+		stream = &groupStream{subscribers: make(map[*Subscription]struct{})}
+		h.groups[groupID] = stream
+	}
+	return stream
+}
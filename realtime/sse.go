@@ -0,0 +1,86 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HeartbeatInterval keeps idle connections from being closed by proxies
+const HeartbeatInterval = 25 * time.Second
+
+// reconnectDelay is the retry interval sent to EventSource clients, in milliseconds
+const reconnectDelay = 3000
+
+// ServeGroup streams a group's events as Server-Sent Events until the client disconnects.
+// A client reconnecting with Last-Event-ID (or the last_event_id query parameter, for
+// clients that reconnect by hand) first receives the events it missed, or a reset event if
+// they are no longer available.
+func (h *Hub) ServeGroup(w http.ResponseWriter, r *http.Request, groupID primitive.ObjectID, heartbeat time.Duration) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	subscription, replay, resumed := h.Subscribe(groupID, lastEventID)
+	defer subscription.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Stop nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", reconnectDelay)
+	if !resumed {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", EventReset)
+	}
+	for _, event := range replay {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, open := <-subscription.Events():
+			if !open {
+				// Dropped for falling behind; the client reconnects and resumes
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID.Hex(), event.Type, data)
+	return err
+}
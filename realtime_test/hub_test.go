@@ -0,0 +1,198 @@
+package realtime_test
+
+import (
+	"bufio"
+	"context"
+	"cribb-backend/realtime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newHub(t *testing.T, history int) *realtime.Hub {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	hub := realtime.NewHub(realtime.NewMemoryBackend(), history)
+	hub.Start(ctx)
+	return hub
+}
+
+func publish(t *testing.T, hub *realtime.Hub, groupID primitive.ObjectID, eventType realtime.EventType) realtime.Event {
+	t.Helper()
+	event, err := realtime.NewEvent(groupID, eventType, nil, map[string]string{"name": "Milk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hub.Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func receive(t *testing.T, subscription *realtime.Subscription) realtime.Event {
+	t.Helper()
+	select {
+	case event, ok := <-subscription.Events():
+		if !ok {
+			t.Fatal("Expected an event, the subscription was closed")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an event")
+	}
+	return realtime.Event{}
+}
+
+func TestHubDeliversToGroup(t *testing.T) {
+	hub := newHub(t, realtime.DefaultHistory)
+	groupID := primitive.NewObjectID()
+	otherGroupID := primitive.NewObjectID()
+
+	subscription, _, _ := hub.Subscribe(groupID, "")
+	defer subscription.Close()
+	other, _, _ := hub.Subscribe(otherGroupID, "")
+	defer other.Close()
+
+	sent := publish(t, hub, groupID, realtime.EventCartItemAdded)
+
+	if got := receive(t, subscription); got.ID != sent.ID || got.Type != realtime.EventCartItemAdded {
+		t.Errorf("Expected event %s, got %s %s", sent.ID.Hex(), got.Type, got.ID.Hex())
+	}
+	select {
+	case event := <-other.Events():
+		t.Errorf("Expected no event for another group, got %s", event.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHubResume(t *testing.T) {
+	hub := newHub(t, 3)
+	groupID := primitive.NewObjectID()
+
+	// A first subscriber guarantees every event has been delivered to the hub
+	watcher, _, _ := hub.Subscribe(groupID, "")
+	defer watcher.Close()
+
+	var events []realtime.Event
+	for i := 0; i < 4; i++ {
+		events = append(events, publish(t, hub, groupID, realtime.EventChoreUpdated))
+		receive(t, watcher)
+	}
+
+	t.Run("From a recent event", func(t *testing.T) {
+		subscription, replay, resumed := hub.Subscribe(groupID, events[1].ID.Hex())
+		defer subscription.Close()
+		if !resumed || len(replay) != 2 || replay[0].ID != events[2].ID || replay[1].ID != events[3].ID {
+			t.Errorf("Expected to replay the last 2 events, got %d (resumed %v)", len(replay), resumed)
+		}
+	})
+
+	t.Run("From the latest event", func(t *testing.T) {
+		subscription, replay, resumed := hub.Subscribe(groupID, events[3].ID.Hex())
+		defer subscription.Close()
+		if !resumed || len(replay) != 0 {
+			t.Errorf("Expected nothing to replay, got %d (resumed %v)", len(replay), resumed)
+		}
+	})
+
+	t.Run("From an event no longer kept", func(t *testing.T) {
+		subscription, replay, resumed := hub.Subscribe(groupID, events[0].ID.Hex())
+		defer subscription.Close()
+		if resumed || len(replay) != 0 {
+			t.Errorf("Expected a reset, got %d events (resumed %v)", len(replay), resumed)
+		}
+	})
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	hub := newHub(t, realtime.DefaultHistory)
+	groupID := primitive.NewObjectID()
+
+	slow, _, _ := hub.Subscribe(groupID, "")
+	defer slow.Close()
+	watcher, _, _ := hub.Subscribe(groupID, "")
+	defer watcher.Close()
+
+	for i := 0; i < 100; i++ {
+		publish(t, hub, groupID, realtime.EventPantryItemUsed)
+		receive(t, watcher)
+	}
+
+	count := 0
+	for range slow.Events() {
+		count++
+	}
+	if count == 0 || count >= 100 {
+		t.Errorf("Expected the slow subscriber to be dropped after a full buffer, got %d events", count)
+	}
+}
+
+func TestServeGroup(t *testing.T) {
+	hub := newHub(t, realtime.DefaultHistory)
+	groupID := primitive.NewObjectID()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hub.ServeGroup(w, r, groupID, 20*time.Millisecond)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	req.Header.Set("Last-Event-ID", primitive.NewObjectID().Hex())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", got)
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	waitFor := func(prefix string) string {
+		t.Helper()
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("Stream ended before %q", prefix)
+				}
+				if strings.HasPrefix(line, prefix) {
+					return line
+				}
+			case <-timeout:
+				t.Fatalf("Timed out waiting for %q", prefix)
+			}
+		}
+	}
+
+	waitFor("retry: ")
+	// The unknown Last-Event-ID cannot be resumed from
+	waitFor("event: " + string(realtime.EventReset))
+	waitFor(": heartbeat")
+
+	sent := publish(t, hub, groupID, realtime.EventChoreCompleted)
+	if line := waitFor("id: "); line != "id: "+sent.ID.Hex() {
+		t.Errorf("Expected id %s, got %q", sent.ID.Hex(), line)
+	}
+	if line := waitFor("event: "); line != "event: "+string(realtime.EventChoreCompleted) {
+		t.Errorf("Expected a chore.completed event, got %q", line)
+	}
+	if line := waitFor("data: "); !strings.Contains(line, `"name":"Milk"`) {
+		t.Errorf("Expected the event data, got %q", line)
+	}
+}
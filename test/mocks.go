@@ -10,29 +10,29 @@ import (
 
 // TestDB provides a simplified interface for testing database operations
 type TestDB struct {
-	Users               []models.User
-	Groups              []models.Group
-	Chores              []models.Chore
-	RecurringChores     []models.RecurringChore
-	ChoreCompletions    []models.ChoreCompletion
-	PantryItems         []models.PantryItem         // Added for pantry tests
-	PantryNotifications []models.PantryNotification // Added for pantry tests
-	PantryHistory       []models.PantryHistory      // Added for pantry tests
-	ShoppingCartItems   []models.ShoppingCartItem
+	Users             []models.User
+	Groups            []models.Group
+	Chores            []models.Chore
+	RecurringChores   []models.RecurringChore
+	ChoreCompletions  []models.ChoreCompletion
+	PantryItems       []models.PantryItem // Added for pantry tests
+	Notifications     []models.Notification
+	PantryHistory     []models.PantryHistory // Added for pantry tests
+	ShoppingCartItems []models.ShoppingCartItem
 }
 
 // NewTestDB creates a new test database with some initial data
 func NewTestDB() *TestDB {
 	return &TestDB{
-		Users:               []models.User{},
-		Groups:              []models.Group{},
-		Chores:              []models.Chore{},
-		RecurringChores:     []models.RecurringChore{},
-		ChoreCompletions:    []models.ChoreCompletion{},
-		PantryItems:         []models.PantryItem{},
-		PantryNotifications: []models.PantryNotification{},
-		PantryHistory:       []models.PantryHistory{},
-		ShoppingCartItems:   []models.ShoppingCartItem{},
+		Users:             []models.User{},
+		Groups:            []models.Group{},
+		Chores:            []models.Chore{},
+		RecurringChores:   []models.RecurringChore{},
+		ChoreCompletions:  []models.ChoreCompletion{},
+		PantryItems:       []models.PantryItem{},
+		Notifications:     []models.Notification{},
+		PantryHistory:     []models.PantryHistory{},
+		ShoppingCartItems: []models.ShoppingCartItem{},
 	}
 }
 
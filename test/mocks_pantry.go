@@ -99,89 +99,31 @@ func (db *TestDB) GetPantryItemsByCategory(groupID primitive.ObjectID, category
 	return result
 }
 
-// AddPantryNotification adds a pantry notification to the test database
-func (db *TestDB) AddPantryNotification(notification models.PantryNotification) {
+// AddNotification adds a notification to the test database
+func (db *TestDB) AddNotification(notification models.Notification) {
 	// Initialize the slice if it's nil
-	if db.PantryNotifications == nil {
-		db.PantryNotifications = make([]models.PantryNotification, 0)
+	if db.Notifications == nil {
+		db.Notifications = make([]models.Notification, 0)
 	}
-	db.PantryNotifications = append(db.PantryNotifications, notification)
+	db.Notifications = append(db.Notifications, notification)
 }
 
-// UpdatePantryNotification updates a pantry notification in the test database
-func (db *TestDB) UpdatePantryNotification(notification models.PantryNotification) {
-	// Initialize the slice if it's nil
-	if db.PantryNotifications == nil {
-		db.PantryNotifications = make([]models.PantryNotification, 0)
-		db.AddPantryNotification(notification)
-		return
-	}
-
-	for i, existingNotification := range db.PantryNotifications {
-		if existingNotification.ID == notification.ID {
-			db.PantryNotifications[i] = notification
-			return
-		}
-	}
-	// If not found, add it
-	db.AddPantryNotification(notification)
-}
-
-// DeletePantryNotification deletes a pantry notification from the test database
-func (db *TestDB) DeletePantryNotification(id primitive.ObjectID) bool {
-	if db.PantryNotifications == nil {
-		return false
-	}
-
-	for i, notification := range db.PantryNotifications {
+// DeleteNotification deletes a notification from the test database
+func (db *TestDB) DeleteNotification(id primitive.ObjectID) bool {
+	for i, notification := range db.Notifications {
 		if notification.ID == id {
-			db.PantryNotifications = append(db.PantryNotifications[:i], db.PantryNotifications[i+1:]...)
+			db.Notifications = append(db.Notifications[:i], db.Notifications[i+1:]...)
 			return true
 		}
 	}
 	return false
 }
 
-// FindPantryNotificationByID finds a pantry notification by ID
-func (db *TestDB) FindPantryNotificationByID(id primitive.ObjectID) (models.PantryNotification, bool) {
-	if db.PantryNotifications == nil {
-		return models.PantryNotification{}, false
-	}
-
-	for _, notification := range db.PantryNotifications {
-		if notification.ID == id {
-			return notification, true
-		}
-	}
-	return models.PantryNotification{}, false
-}
-
-// GetPantryNotificationsByGroup gets all pantry notifications for a group
-func (db *TestDB) GetPantryNotificationsByGroup(groupID primitive.ObjectID) []models.PantryNotification {
-	var result []models.PantryNotification
-
-	if db.PantryNotifications == nil {
-		return result
-	}
-
-	for _, notification := range db.PantryNotifications {
-		if notification.GroupID == groupID {
-			result = append(result, notification)
-		}
-	}
-	return result
-}
-
-// GetPantryNotificationsByType gets all pantry notifications for a group of a specific type
-func (db *TestDB) GetPantryNotificationsByType(groupID primitive.ObjectID, notificationType models.NotificationType) []models.PantryNotification {
-	var result []models.PantryNotification
-
-	if db.PantryNotifications == nil {
-		return result
-	}
-
-	for _, notification := range db.PantryNotifications {
-		if notification.GroupID == groupID && notification.Type == notificationType {
+// GetPantryNotificationsByType gets the notifications of a type about a group's pantry items
+func (db *TestDB) GetPantryNotificationsByType(groupID primitive.ObjectID, notificationType models.NotificationType) []models.Notification {
+	var result []models.Notification
+	for _, notification := range db.Notifications {
+		if notification.GroupID == groupID && notification.Type == notificationType && notification.Pantry != nil {
 			result = append(result, notification)
 		}
 	}
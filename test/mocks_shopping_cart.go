@@ -16,7 +16,7 @@ type TestDB struct {
 	RecurringChores     []models.RecurringChore
 	ChoreCompletions    []models.ChoreCompletion
 	PantryItems         []models.PantryItem
-	PantryNotifications []models.PantryNotification
+	Notifications       []models.Notification
 	PantryHistory       []models.PantryHistory
 	ShoppingCartItems   []models.ShoppingCartItem  // Add this line
 }
@@ -0,0 +1,78 @@
+package units
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// densities are typical densities of pantry ingredients in grams per millilitre, as measured
+// with a scooped cup for dry goods
+var densities = map[string]float64{
+	"water":             1.0,
+	"milk":              1.03,
+	"buttermilk":        1.03,
+	"cream":             1.01,
+	"yogurt":            1.03,
+	"yoghurt":           1.03,
+	"butter":            0.96,
+	"oil":               0.92,
+	"olive oil":         0.91,
+	"vinegar":           1.01,
+	"juice":             1.04,
+	"soy sauce":         1.15,
+	"honey":             1.42,
+	"syrup":             1.33,
+	"maple syrup":       1.32,
+	"molasses":          1.41,
+	"flour":             0.53,
+	"whole wheat flour": 0.51,
+	"bread flour":       0.54,
+	"cornstarch":        0.54,
+	"cornmeal":          0.65,
+	"sugar":             0.85,
+	"brown sugar":       0.93,
+	"powdered sugar":    0.51,
+	"icing sugar":       0.51,
+	"salt":              1.22,
+	"baking soda":       0.93,
+	"baking powder":     0.81,
+	"cocoa":             0.42,
+	"rice":              0.78,
+	"oats":              0.38,
+	"rolled oats":       0.38,
+	"lentils":           0.81,
+	"peanut butter":     1.09,
+	"coffee":            0.36,
+}
+
+// densityKeys are the density entries, longest first so "brown sugar" wins over "sugar"
+var densityKeys = func() []string {
+	keys := make([]string, 0, len(densities))
+	for key := range densities {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}()
+
+// DensityFor looks up the typical density of an ingredient by name, in grams per millilitre.
+// The most specific known ingredient named as whole words wins, so "Organic Brown Sugar"
+// uses the density of brown sugar.
+func DensityFor(name string) (float64, bool) {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	padded := " " + strings.Join(words, " ") + " "
+	for _, key := range densityKeys {
+		if strings.Contains(padded, " "+key+" ") {
+			return densities[key], true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,195 @@
+// Package units normalizes units of measure and converts quantities between them.
+//
+// Every known unit belongs to a dimension (mass, volume or count) and has a factor to the
+// dimension's base unit: grams, millilitres or pieces. Units within a dimension convert
+// freely; mass and volume convert through a density in grams per millilitre. Units that are
+// not known, such as "can" or "bag", are kept as custom units that only match themselves.
+package units
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Dimension is what a unit measures
+type Dimension string
+
+const (
+	Mass   Dimension = "mass"   // Base unit g
+	Volume Dimension = "volume" // Base unit ml
+	Count  Dimension = "count"  // Base unit pc
+	Custom Dimension = "custom" // Only convertible to the same unit
+)
+
+// Unit is a canonical unit of measure
+type Unit struct {
+	Symbol    string    `json:"symbol"`
+	Dimension Dimension `json:"dimension"`
+	Factor    float64   `json:"factor"` // Base units in one of this unit; 1 for custom units
+}
+
+// ErrEmptyUnit is returned when no unit is given
+var ErrEmptyUnit = errors.New("unit is required")
+
+// ConversionError explains why a quantity cannot be converted between two units
+type ConversionError struct {
+	From         Unit
+	To           Unit
+	NeedsDensity bool // Mass and volume could convert with a density
+}
+
+func (e *ConversionError) Error() string {
+	if e.NeedsDensity {
+		return fmt.Sprintf("cannot convert %s to %s without a density for this item", e.From.Symbol, e.To.Symbol)
+	}
+	return fmt.Sprintf("cannot convert %s (%s) to %s (%s)", e.From.Symbol, e.From.Dimension, e.To.Symbol, e.To.Dimension)
+}
+
+// Known lists the canonical units, smallest first within each dimension
+var Known = []Unit{
+	{Symbol: "mg", Dimension: Mass, Factor: 0.001},
+	{Symbol: "g", Dimension: Mass, Factor: 1},
+	{Symbol: "oz", Dimension: Mass, Factor: 28.349523125},
+	{Symbol: "lb", Dimension: Mass, Factor: 453.59237},
+	{Symbol: "kg", Dimension: Mass, Factor: 1000},
+
+	{Symbol: "ml", Dimension: Volume, Factor: 1},
+	{Symbol: "tsp", Dimension: Volume, Factor: 4.92892159375},
+	{Symbol: "cl", Dimension: Volume, Factor: 10},
+	{Symbol: "tbsp", Dimension: Volume, Factor: 14.78676478125},
+	{Symbol: "fl oz", Dimension: Volume, Factor: 29.5735295625},
+	{Symbol: "dl", Dimension: Volume, Factor: 100},
+	{Symbol: "cup", Dimension: Volume, Factor: 236.5882365},
+	{Symbol: "pt", Dimension: Volume, Factor: 473.176473},
+	{Symbol: "qt", Dimension: Volume, Factor: 946.352946},
+	{Symbol: "l", Dimension: Volume, Factor: 1000},
+	{Symbol: "gal", Dimension: Volume, Factor: 3785.411784},
+
+	{Symbol: "pc", Dimension: Count, Factor: 1},
+	{Symbol: "pair", Dimension: Count, Factor: 2},
+	{Symbol: "dozen", Dimension: Count, Factor: 12},
+}
+
+// aliases maps the spellings people use to canonical symbols
+var aliases = map[string]string{
+	"mg": "mg", "milligram": "mg", "milligrams": "mg", "milligramme": "mg", "milligrammes": "mg",
+	"g": "g", "gr": "g", "gram": "g", "grams": "g", "gramme": "g", "grammes": "g",
+	"kg": "kg", "kgs": "kg", "kilo": "kg", "kilos": "kg", "kilogram": "kg", "kilograms": "kg",
+	"kilogramme": "kg", "kilogrammes": "kg",
+	"oz": "oz", "ounce": "oz", "ounces": "oz",
+	"lb": "lb", "lbs": "lb", "pound": "lb", "pounds": "lb",
+
+	"ml": "ml", "mls": "ml", "cc": "ml", "milliliter": "ml", "milliliters": "ml", "millilitre": "ml", "millilitres": "ml",
+	"cl": "cl", "centiliter": "cl", "centiliters": "cl", "centilitre": "cl", "centilitres": "cl",
+	"dl": "dl", "deciliter": "dl", "deciliters": "dl", "decilitre": "dl", "decilitres": "dl",
+	"l": "l", "ltr": "l", "liter": "l", "liters": "l", "litre": "l", "litres": "l",
+	"tsp": "tsp", "tsps": "tsp", "teaspoon": "tsp", "teaspoons": "tsp",
+	"tbsp": "tbsp", "tbsps": "tbsp", "tbs": "tbsp", "tablespoon": "tbsp", "tablespoons": "tbsp",
+	"fl oz": "fl oz", "floz": "fl oz", "fluid ounce": "fl oz", "fluid ounces": "fl oz",
+	"cup": "cup", "cups": "cup",
+	"pt": "pt", "pint": "pt", "pints": "pt",
+	"qt": "qt", "quart": "qt", "quarts": "qt",
+	"gal": "gal", "gallon": "gal", "gallons": "gal",
+
+	"pc": "pc", "pcs": "pc", "piece": "pc", "pieces": "pc", "each": "pc", "ea": "pc",
+	"unit": "pc", "units": "pc", "item": "pc", "items": "pc", "ct": "pc", "count": "pc",
+	"pair": "pair", "pairs": "pair",
+	"dozen": "dozen", "doz": "dozen", "dozens": "dozen",
+}
+
+var bySymbol = func() map[string]Unit {
+	units := make(map[string]Unit, len(Known))
+	for _, unit := range Known {
+		units[unit.Symbol] = unit
+	}
+	return units
+}()
+
+// Parse normalizes a unit as typed, ignoring case, dots and extra spaces. Unknown units are
+// returned as custom units in their singular form, so "Cans" and "can" are the same unit.
+func Parse(raw string) (Unit, error) {
+	key := strings.Join(strings.Fields(strings.ReplaceAll(strings.ToLower(raw), ".", "")), " ")
+	if key == "" {
+		return Unit{}, ErrEmptyUnit
+	}
+	if symbol, ok := aliases[key]; ok {
+		return bySymbol[symbol], nil
+	}
+	return Unit{Symbol: singular(key), Dimension: Custom, Factor: 1}, nil
+}
+
+// Normalize returns the canonical symbol of a unit as typed
+func Normalize(raw string) (string, error) {
+	unit, err := Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	return unit.Symbol, nil
+}
+
+// singular strips a simple English plural ending
+func singular(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "xes"):
+		return strings.TrimSuffix(word, "es")
+	case len(word) > 2 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
+
+// Compatible checks if quantities can be converted between two units. density is in grams
+// per millilitre, or 0 if unknown.
+func Compatible(from, to Unit, density float64) bool {
+	_, err := Convert(1, from, to, density)
+	return err == nil
+}
+
+// Convert converts a quantity between units. Mass and volume convert through density, in
+// grams per millilitre; pass 0 if it is unknown. Results are rounded to six decimal places so
+// round trips do not accumulate floating point noise.
+func Convert(quantity float64, from, to Unit, density float64) (float64, error) {
+	if from.Symbol == to.Symbol {
+		return quantity, nil
+	}
+	if from.Dimension == Custom || to.Dimension == Custom {
+		return 0, &ConversionError{From: from, To: to}
+	}
+
+	base := quantity * from.Factor
+	switch {
+	case from.Dimension == to.Dimension:
+	case from.Dimension == Volume && to.Dimension == Mass:
+		if density <= 0 {
+			return 0, &ConversionError{From: from, To: to, NeedsDensity: true}
+		}
+		base *= density
+	case from.Dimension == Mass && to.Dimension == Volume:
+		if density <= 0 {
+			return 0, &ConversionError{From: from, To: to, NeedsDensity: true}
+		}
+		base /= density
+	default:
+		return 0, &ConversionError{From: from, To: to}
+	}
+	return round(base / to.Factor), nil
+}
+
+// ConvertString parses both units and converts a quantity between them
+func ConvertString(quantity float64, from, to string, density float64) (float64, error) {
+	fromUnit, err := Parse(from)
+	if err != nil {
+		return 0, err
+	}
+	toUnit, err := Parse(to)
+	if err != nil {
+		return 0, err
+	}
+	return Convert(quantity, fromUnit, toUnit, density)
+}
+
+func round(quantity float64) float64 {
+	return math.Round(quantity*1e6) / 1e6
+}
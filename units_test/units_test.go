@@ -0,0 +1,128 @@
+package units_test
+
+import (
+	"cribb-backend/units"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw       string
+		symbol    string
+		dimension units.Dimension
+	}{
+		{"g", "g", units.Mass},
+		{"Grams", "g", units.Mass},
+		{" KG ", "kg", units.Mass},
+		{"lbs.", "lb", units.Mass},
+		{"Litres", "l", units.Volume},
+		{"fl. oz", "fl oz", units.Volume},
+		{"Tablespoons", "tbsp", units.Volume},
+		{"cups", "cup", units.Volume},
+		{"pcs", "pc", units.Count},
+		{"Dozen", "dozen", units.Count},
+		{"Cans", "can", units.Custom},
+		{"boxes", "box", units.Custom},
+		{"glass", "glass", units.Custom},
+	}
+
+	for _, tt := range tests {
+		unit, err := units.Parse(tt.raw)
+		if err != nil {
+			t.Errorf("Expected %q to parse, got %v", tt.raw, err)
+			continue
+		}
+		if unit.Symbol != tt.symbol || unit.Dimension != tt.dimension {
+			t.Errorf("Expected %q to be %s (%s), got %s (%s)", tt.raw, tt.symbol, tt.dimension, unit.Symbol, unit.Dimension)
+		}
+	}
+
+	if _, err := units.Parse("  "); !errors.Is(err, units.ErrEmptyUnit) {
+		t.Errorf("Expected ErrEmptyUnit for a blank unit, got %v", err)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		quantity float64
+		from     string
+		to       string
+		density  float64
+		expected float64
+	}{
+		{"Same unit", 3, "kg", "kg", 0, 3},
+		{"Grams to kilograms", 500, "g", "kg", 0, 0.5},
+		{"Pounds to grams", 1, "lb", "g", 0, 453.59237},
+		{"Cups to millilitres", 2, "cups", "ml", 0, 473.176473},
+		{"Tablespoons to teaspoons", 1, "tbsp", "tsp", 0, 3},
+		{"Dozen to pieces", 2, "dozen", "pcs", 0, 24},
+		{"Cups of flour to grams", 1, "cup", "g", 0.53, 125.391765},
+		{"Grams of water to litres", 1500, "g", "l", 1, 1.5},
+		{"Custom unit to itself", 2, "Cans", "can", 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := units.ConvertString(tt.quantity, tt.from, tt.to, tt.density)
+			if err != nil {
+				t.Fatalf("Expected a conversion, got %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestConvertIncompatible(t *testing.T) {
+	tests := []struct {
+		name         string
+		from         string
+		to           string
+		needsDensity bool
+	}{
+		{"Volume to mass without density", "cup", "g", true},
+		{"Mass to volume without density", "kg", "l", true},
+		{"Count to mass", "pcs", "kg", false},
+		{"Custom to mass", "can", "g", false},
+		{"Different custom units", "can", "bottle", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := units.ConvertString(1, tt.from, tt.to, 0)
+			var conversionErr *units.ConversionError
+			if !errors.As(err, &conversionErr) {
+				t.Fatalf("Expected a ConversionError, got %v", err)
+			}
+			if conversionErr.NeedsDensity != tt.needsDensity {
+				t.Errorf("Expected NeedsDensity %v, got %v (%s)", tt.needsDensity, conversionErr.NeedsDensity, err)
+			}
+		})
+	}
+}
+
+func TestDensityFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected float64
+		found    bool
+	}{
+		{"Flour", 0.53, true},
+		{"Organic Brown Sugar", 0.93, true},
+		{"sugar", 0.85, true},
+		{"Whole-Wheat Flour", 0.51, true},
+		{"Buttermilk", 1.03, true},
+		{"Peanut butter", 1.09, true},
+		{"Eggs", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, found := units.DensityFor(tt.name)
+		if got != tt.expected || found != tt.found {
+			t.Errorf("Expected %v (%v) for %q, got %v (%v)", tt.expected, tt.found, tt.name, got, found)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Package webhook signs and sends outbound webhook deliveries.
+//
+// Every request carries the event type, the delivery ID, a Unix timestamp and an
+// HMAC-SHA256 signature of "<timestamp>.<body>" keyed with the webhook's secret, so
+// receivers can check both the sender and the freshness of a request.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"cribb-backend/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	EventHeader     = "X-Cribb-Event"
+	DeliveryHeader  = "X-Cribb-Delivery"
+	TimestampHeader = "X-Cribb-Timestamp"
+	SignatureHeader = "X-Cribb-Signature"
+)
+
+// RequestTimeout bounds a single delivery attempt
+const RequestTimeout = 10 * time.Second
+
+// errPrivateAddress is returned when a URL resolves to a private network
+var errPrivateAddress = errors.New("URL resolves to a private or loopback address")
+
+// Sign returns the signature header value for a body sent at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header value in constant time
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// NewClient creates the HTTP client deliveries and Web Push messages are sent with. Unless
+// allowPrivate is set, connections to loopback, private and link-local addresses are refused
+// when dialing, so a webhook or push endpoint cannot be pointed at the server's own network,
+// even through DNS tricks.
+func NewClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || models.IsPrivateAddress(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &http.Client{
+		Timeout:   RequestTimeout,
+		Transport: transport,
+		// Redirects would skip the signature's intent and the address check's URL
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Send makes one delivery attempt and reports how it went
+func Send(ctx context.Context, client *http.Client, webhook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) models.WebhookAttempt {
+	attempt := models.WebhookAttempt{At: now}
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Cribb-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID.Hex())
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, body))
+
+	started := time.Now()
+	resp, err := client.Do(req)
+	attempt.DurationMS = time.Since(started).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	attempt.StatusCode = resp.StatusCode
+	if !attempt.Succeeded() {
+		attempt.Error = fmt.Sprintf("receiver returned %d", resp.StatusCode)
+	}
+	return attempt
+}
@@ -0,0 +1,115 @@
+package webhook_test
+
+import (
+	"context"
+	"cribb-backend/models"
+	"cribb-backend/webhook"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newDelivery(hook *models.Webhook, now time.Time) *models.WebhookDelivery {
+	delivery := models.NewWebhookDelivery(hook, primitive.NewObjectID(), "chore.completed", `{"type":"chore.completed"}`, now)
+	delivery.ID = primitive.NewObjectID()
+	return delivery
+}
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	signature := webhook.Sign("whsec_test", 1700000000, body)
+
+	if !webhook.Verify("whsec_test", 1700000000, body, signature) {
+		t.Errorf("Expected the signature to verify")
+	}
+	if webhook.Verify("whsec_other", 1700000000, body, signature) {
+		t.Errorf("Expected a different secret to fail")
+	}
+	if webhook.Verify("whsec_test", 1700000001, body, signature) {
+		t.Errorf("Expected a different timestamp to fail")
+	}
+	if webhook.Verify("whsec_test", 1700000000, []byte(`{"id":"2"}`), signature) {
+		t.Errorf("Expected a different body to fail")
+	}
+}
+
+func TestSend(t *testing.T) {
+	now := time.Now()
+	hook := &models.Webhook{ID: primitive.NewObjectID(), GroupID: primitive.NewObjectID(), Secret: "whsec_test"}
+
+	var received *http.Request
+	var receivedBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	hook.URL = server.URL
+
+	delivery := newDelivery(hook, now)
+	attempt := webhook.Send(context.Background(), webhook.NewClient(true), hook, delivery, now)
+
+	if !attempt.Succeeded() || attempt.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected a successful attempt, got %d %q", attempt.StatusCode, attempt.Error)
+	}
+	if string(receivedBody) != delivery.Payload {
+		t.Errorf("Expected the stored payload, got %q", receivedBody)
+	}
+	if got := received.Header.Get(webhook.EventHeader); got != "chore.completed" {
+		t.Errorf("Expected event header chore.completed, got %q", got)
+	}
+	if got := received.Header.Get(webhook.DeliveryHeader); got != delivery.ID.Hex() {
+		t.Errorf("Expected delivery header %s, got %q", delivery.ID.Hex(), got)
+	}
+	timestamp, err := strconv.ParseInt(received.Header.Get(webhook.TimestampHeader), 10, 64)
+	if err != nil || timestamp != now.Unix() {
+		t.Errorf("Expected timestamp %d, got %q", now.Unix(), received.Header.Get(webhook.TimestampHeader))
+	}
+	if !webhook.Verify(hook.Secret, timestamp, receivedBody, received.Header.Get(webhook.SignatureHeader)) {
+		t.Errorf("Expected a valid signature, got %q", received.Header.Get(webhook.SignatureHeader))
+	}
+}
+
+func TestSendReceiverError(t *testing.T) {
+	now := time.Now()
+	hook := &models.Webhook{ID: primitive.NewObjectID(), Secret: "whsec_test"}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com/", http.StatusFound)
+	}))
+	defer server.Close()
+	hook.URL = server.URL
+
+	attempt := webhook.Send(context.Background(), webhook.NewClient(true), hook, newDelivery(hook, now), now)
+
+	if attempt.Succeeded() || attempt.StatusCode != http.StatusFound || attempt.Error == "" {
+		t.Errorf("Expected a redirect to count as a failure, got %d %q", attempt.StatusCode, attempt.Error)
+	}
+}
+
+func TestSendBlocksPrivateAddresses(t *testing.T) {
+	now := time.Now()
+	hook := &models.Webhook{ID: primitive.NewObjectID(), Secret: "whsec_test"}
+
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+	hook.URL = server.URL
+
+	attempt := webhook.Send(context.Background(), webhook.NewClient(false), hook, newDelivery(hook, now), now)
+
+	if attempt.Succeeded() || attempt.Error == "" {
+		t.Errorf("Expected a loopback URL to be refused, got %d", attempt.StatusCode)
+	}
+	if called {
+		t.Errorf("Expected the request never to reach the server")
+	}
+}